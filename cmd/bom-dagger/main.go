@@ -1,232 +1,2077 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/nprimmer/bom-dagger/internal/cache"
 	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/graphfile"
+	"github.com/nprimmer/bom-dagger/internal/interrupt"
+	"github.com/nprimmer/bom-dagger/internal/logging"
+	"github.com/nprimmer/bom-dagger/internal/metadata"
+	"github.com/nprimmer/bom-dagger/internal/output"
+	"github.com/nprimmer/bom-dagger/internal/overlay"
 	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/plural"
+	"github.com/nprimmer/bom-dagger/internal/policy"
+	"github.com/nprimmer/bom-dagger/internal/probe"
 	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/server"
+	"github.com/nprimmer/bom-dagger/internal/testgen"
+	"github.com/nprimmer/bom-dagger/internal/warning"
 )
 
 // Version is set at build time via -ldflags
 var Version = "dev"
 
 func main() {
-	var (
-		inputFile   string
-		outputMode  string
-		showReverse bool
-		showGroups  bool
-		showStats   bool
-		showHelp    bool
-		showVersion bool
-	)
-
-	flag.StringVar(&inputFile, "input", "", "Path to CycloneDX SBOM file (JSON)")
-	flag.StringVar(&inputFile, "i", "", "Path to CycloneDX SBOM file (JSON) (shorthand)")
-	flag.StringVar(&outputMode, "output", "order", "Output mode: order, groups, dot")
-	flag.StringVar(&outputMode, "o", "order", "Output mode: order, groups, dot (shorthand)")
-	flag.BoolVar(&showReverse, "reverse", false, "Show reverse order (teardown sequence)")
-	flag.BoolVar(&showReverse, "r", false, "Show reverse order (teardown sequence) (shorthand)")
-	flag.BoolVar(&showGroups, "groups", false, "Show deployment groups (components that can be deployed in parallel)")
-	flag.BoolVar(&showGroups, "g", false, "Show deployment groups (shorthand)")
-	flag.BoolVar(&showStats, "stats", false, "Show graph statistics")
-	flag.BoolVar(&showStats, "s", false, "Show graph statistics (shorthand)")
-	flag.BoolVar(&showHelp, "help", false, "Show help message")
-	flag.BoolVar(&showHelp, "h", false, "Show help message (shorthand)")
-	flag.BoolVar(&showVersion, "version", false, "Show version information")
-	flag.BoolVar(&showVersion, "v", false, "Show version information (shorthand)")
-
-	flag.Parse()
-
-	if showVersion {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// The streams of the current run; run sets them
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// exitCode unwinds a run to its status; see exit
+type exitCode int
+
+// exit ends the run with status code. Unlike os.Exit, deferred functions
+// still run, and tests calling run get the status back.
+func exit(code int) {
+	panic(exitCode(code))
+}
+
+// run runs bom-dagger with args, reading stdin from in and writing stdout to
+// out and stderr to errOut, and returns the exit status. Runs share the
+// package's streams, logger and style, so they must not overlap.
+func run(args []string, in io.Reader, out, errOut io.Writer) (status int) {
+	stdin, stdout, stderr = in, out, errOut
+	logger = logging.New(stderr, logging.LevelWarn, logging.FormatText)
+	style = output.Style{}
+	defer func() {
+		if r := recover(); r != nil {
+			code, ok := r.(exitCode)
+			if !ok {
+				panic(r)
+			}
+			status = int(code)
+		}
+	}()
+	runCommand(args)
+	return 0
+}
+
+// runCommand is run without the exit status, which it reports through exit
+func runCommand(args []string) {
+	if len(args) > 0 && args[0] == "serve" {
+		runServe(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "schema" {
+		if _, err := stdout.Write(plan.Schema); err != nil {
+			failf("writing schema: %v", err)
+		}
+		return
+	}
+
+	o := parseOptions(args)
+	if o.runStandalone() {
+		return
+	}
+	s := o.validate()
+
+	// An interrupt, or running past --run-timeout, ends the run with a report of
+	// the phase it hit; only the probe and exec phases wind down through the
+	// context
+	interrupted := interrupt.New()
+	interrupted.Report = func(err error) { logger.Errorf("%v", err) }
+	defer interrupted.Notify()()
+	defer interrupted.Deadline(o.runTimeout)()
+
+	in := o.load(s, interrupted)
+	o.prepare(s, in, interrupted)
+	interrupted.Enter("planning")
+	o.runMode(s, in, interrupted)
+}
+
+// runStandalone handles the options that need no SBOM: --version,
+// --generate, -o list and --help. It reports whether the run is done.
+func (o *options) runStandalone() bool {
+	if o.showVersion {
 		printVersion()
-		os.Exit(0)
+		exit(0)
 	}
 
-	if showHelp || inputFile == "" {
+	if o.generate != "" {
+		opts, err := testgen.ParseOptions(o.generate)
+		if err != nil {
+			fatalf("invalid --generate: %v", err)
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(testgen.GenerateBOM(opts)); err != nil {
+			failf("writing generated SBOM: %v", err)
+		}
+		return true
+	}
+
+	if o.outputMode == "list" {
+		if err := output.List(stdout); err != nil {
+			failf("listing output modes: %v", err)
+		}
+		return true
+	}
+
+	noInput := len(o.inputFiles.files) == 0 && o.fromGraph == ""
+	if o.showHelp || noInput {
 		printUsage()
-		if inputFile == "" && !showHelp {
-			os.Exit(1)
+		if noInput && !o.showHelp {
+			exit(1)
+		}
+		exit(0)
+	}
+	return false
+}
+
+// input is the graph a run plans, with what it was built from and what
+// prepare found in it
+type input struct {
+	parser           *parser.Parser
+	bom              *sbom.CycloneDX
+	inputs           []plan.ProvenanceInput
+	graph            *dag.Graph
+	cyclic           bool // Only with -o prometheus; see build
+	parseWarnings    []warning.Warning
+	metadataWarnings []warning.Warning
+	graphOptions     string // The build options, as keyed by the cache and hashed by --freeze
+
+	noDependencies bool
+	conflicts      []dag.Conflict
+	unreachable    []string
+	pruned         []string
+}
+
+// load parses the SBOMs and builds the graph, or loads it from the cache or
+// --from-graph
+func (o *options) load(s *settings, interrupted *interrupt.Handler) *input {
+	interrupted.Enter("parsing")
+	in := &input{parser: parser.New()}
+	in.parser.Logger = logger
+	in.parser.Strict = o.strictParse
+	in.parser.Format = s.format
+
+	inputValues, fromDir, err := expandInputDirs(o.inputFiles.files, o.namespaced, o.recursive)
+	if err != nil {
+		failf("reading input: %v", err)
+	}
+	inputPaths := inputValues
+	var namespaces []string
+	if o.namespaced {
+		if inputPaths, namespaces, err = splitNamespaces(inputValues); err != nil {
+			fatalf("invalid --namespace-inputs: %v", err)
+		}
+	}
+	if stdinInputs := countOf(inputPaths, stdinPath); stdinInputs > 1 {
+		fatalf("standard input (-i %s) can only be read once", stdinPath)
+	} else if stdinInputs == 1 && o.cacheDir != "" {
+		// The cache key hashes the inputs before reading them
+		logger.Infof("not caching a graph read from standard input")
+		o.cacheDir = ""
+	}
+
+	in.graphOptions = fmt.Sprintf("strict-parse=%t infer-from=%s synthesize-missing=%t dedupe-by=%s dedupe-version-tolerant=%t include-excluded=%t runtime-only=%t namespaces=%s bom-dir=%s",
+		o.strictParse, s.inferFrom, o.synthesize, o.dedupeBy, o.dedupeLoose, o.inclExcl, o.runtimeOnly, strings.Join(namespaces, ","), o.bomDir)
+	buildOpts := dag.BuildOptions{
+		InferFrom:             s.inferFrom,
+		SynthesizeMissing:     o.synthesize,
+		DedupeByPurl:          o.dedupeBy == "purl",
+		DedupeVersionTolerant: o.dedupeLoose,
+		IncludeExcluded:       o.inclExcl,
+		Env:                   o.env,
+		Logger:                logger,
+	}
+	if o.runtimeOnly {
+		buildOpts.EdgeFilter = dag.RuntimeOnly
+	}
+
+	// A cached graph replaces parsing and building; everything that shapes
+	// the graph is part of the key
+	var cacheKey string
+	loaded := false
+	switch {
+	case o.fromGraph != "":
+		o.loadGraphFile(s, in, buildOpts, interrupted)
+		loaded = true
+	case o.cacheDir != "":
+		if cacheKey, err = o.cacheKey(inputPaths, in.graphOptions); err != nil {
+			failf("reading input: %v", err)
+		}
+		loaded = o.loadCached(s, in, cacheKey, buildOpts, interrupted)
+	}
+
+	if !loaded {
+		interrupted.Enter("parsing")
+		o.parseInputs(in, inputPaths, namespaces, fromDir)
+		if o.bomDir != "" {
+			registry, err := in.parser.LoadRegistry(o.bomDir)
+			if err != nil {
+				failf("loading --bom-dir: %v", err)
+			}
+			logger.Infof("loaded %d linked %s from %s", registry.Len(), plural.Noun(registry.Len(), "SBOM"), o.bomDir)
+			unresolved, linkWarnings := parser.ResolveBOMLinks(in.bom, registry)
+			for _, w := range linkWarnings {
+				o.logParseWarning(w)
+				in.parseWarnings = append(in.parseWarnings, w)
+			}
+			buildOpts.ReportedRefs = unresolved
+		}
+		checkStrict(o.strict, s.warnPolicy, in.parseWarnings)
+		o.applyLocalFiles(in)
+
+		interrupted.Enter("building the graph")
+		o.build(s, in, buildOpts)
+		if o.cacheDir != "" && !in.cyclic {
+			entry := cache.NewEntry(in.bom, in.graph)
+			entry.Inputs = in.inputs
+			entry.ParseWarnings = in.parseWarnings
+			entry.MetadataWarnings = in.metadataWarnings
+			if err := cache.Store(o.cacheDir, cacheKey, entry); err != nil {
+				logger.Warnf("writing cache: %v", err)
+			}
+		}
+	}
+
+	for _, w := range in.graph.Warnings {
+		logger.Warn(w)
+	}
+	return in
+}
+
+// logParseWarning logs a parse warning; --strict makes them fatal, so
+// --quiet must not hide them
+func (o *options) logParseWarning(w warning.Warning) {
+	if o.strict {
+		logger.Error(w)
+		return
+	}
+	logger.Warn(w)
+}
+
+// replayWarnings logs the warnings recorded with a saved graph, as parsing
+// would have
+func (o *options) replayWarnings(s *settings, in *input, parseWarnings, metadataWarnings []warning.Warning) {
+	for _, w := range parseWarnings {
+		o.logParseWarning(w)
+	}
+	checkStrict(o.strict, s.warnPolicy, parseWarnings)
+	for _, w := range metadataWarnings {
+		logger.Warn(w)
+	}
+	in.parseWarnings = parseWarnings
+}
+
+// loadCached loads the graph from the cache entry under key and reports
+// whether there was a usable one
+func (o *options) loadCached(s *settings, in *input, key string, buildOpts dag.BuildOptions, interrupted *interrupt.Handler) bool {
+	cached, err := cache.Load(o.cacheDir, key)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Infof("ignoring cache entry %s: %v", cache.Path(o.cacheDir, key), err)
 		}
-		os.Exit(0)
+		return false
+	}
+	o.replayWarnings(s, in, cached.ParseWarnings, cached.MetadataWarnings)
+	interrupted.Enter("building the graph")
+	if in.graph, err = dag.FromSnapshot(cached.Graph, buildOpts); err != nil {
+		logger.Infof("ignoring cache entry %s: %v", cache.Path(o.cacheDir, key), err)
+		return false
 	}
+	in.bom, in.inputs, in.metadataWarnings = cached.BOM, cached.Inputs, cached.MetadataWarnings
+	logger.Infof("loaded the graph from %s", cache.Path(o.cacheDir, key))
+	return true
+}
 
-	// Parse the SBOM file
-	p := parser.New()
-	bom, err := p.ParseFile(inputFile)
+// loadGraphFile loads the graph written to --from-graph
+func (o *options) loadGraphFile(s *settings, in *input, buildOpts dag.BuildOptions, interrupted *interrupt.Handler) {
+	file, err := graphfile.Load(o.fromGraph)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing SBOM: %v\n", err)
-		os.Exit(1)
+		failf("loading graph: %v", err)
+	}
+	o.replayWarnings(s, in, file.ParseWarnings, file.MetadataWarnings)
+	// --env filtered the graph when it was written
+	if o.env != "" && o.env != file.Provenance.Env {
+		fatalf("--env %s does not match the graph, written for %q", o.env, file.Provenance.Env)
+	}
+	o.env = file.Provenance.Env
+	interrupted.Enter("building the graph")
+	if in.graph, err = dag.FromSnapshot(file.Graph, buildOpts); err != nil {
+		failf("loading graph: %v", err)
+	}
+	in.bom, in.inputs, in.metadataWarnings = file.BOM, file.Provenance.Inputs, file.MetadataWarnings
+	logger.Infof("loaded the graph from %s", o.fromGraph)
+}
+
+// cacheKey hashes the inputs, the local files and linked SBOMs that shape the
+// graph, and graphOptions
+func (o *options) cacheKey(inputPaths []string, graphOptions string) (string, error) {
+	files := append([]string(nil), inputPaths...)
+	for _, file := range []string{o.overlayFile, o.metaFile} {
+		if file != "" {
+			files = append(files, file)
+		}
 	}
+	if o.bomDir != "" {
+		// The linked documents shape the graph as much as the inputs
+		linked, _ := filepath.Glob(filepath.Join(o.bomDir, "*.json"))
+		sort.Strings(linked)
+		files = append(files, linked...)
+	}
+	return cache.Key(files, Version, o.formatFlag, graphOptions, "env="+o.env)
+}
 
-	// Get component map
-	componentMap := p.GetComponentMap(bom)
+// parseInputs parses every input into in.bom, merging several, and records
+// their provenance and warnings
+func (o *options) parseInputs(in *input, inputPaths, namespaces []string, fromDir map[string]bool) {
+	boms := make([]*sbom.CycloneDX, 0, len(inputPaths))
+	for i, inputFile := range inputPaths {
+		bom, warnings, digest, err := parseInput(in.parser, inputFile)
+		if err != nil && fromDir[inputFile] && !o.strict {
+			// One broken SBOM of a directory does not hold up the others
+			w := warning.New(warning.SkippedInput, "skipping %s: %v", inputFile, err)
+			logger.Warn(w)
+			in.parseWarnings = append(in.parseWarnings, w)
+			continue
+		}
+		if err != nil {
+			if len(inputPaths) > 1 {
+				failf("parsing SBOM: %s: %v", inputFile, err)
+			}
+			failf("parsing SBOM: %v", err)
+		}
+		for _, w := range warnings {
+			if len(inputPaths) > 1 {
+				w.Message = inputFile + ": " + w.Message
+			}
+			o.logParseWarning(w)
+			in.parseWarnings = append(in.parseWarnings, w)
+		}
+		input := plan.NewProvenanceInput(inputFile, bom)
+		input.SHA256 = digest
+		if inputFile == stdinPath {
+			input.Path = "stdin"
+		}
+		in.inputs = append(in.inputs, input)
+		if namespaces != nil {
+			parser.Namespace(bom, namespaces[i])
+		}
+		boms = append(boms, bom)
+	}
+	if len(boms) == 0 {
+		failf("parsing SBOM: none of the %d files could be parsed", len(inputPaths))
+	}
+	in.bom = boms[0]
+	if len(boms) > 1 {
+		in.bom = parser.Merge(boms...)
+		logger.Infof("merged %d SBOMs", len(boms))
+	}
+}
 
-	// Build the DAG
-	graph := dag.New()
-	if err := graph.BuildFromSBOM(bom, componentMap); err != nil {
-		fmt.Fprintf(os.Stderr, "Error building DAG: %v\n", err)
-		os.Exit(1)
+// applyLocalFiles applies the --overlay ordering tweaks and --metadata
+// properties to the SBOM before the graph is built
+func (o *options) applyLocalFiles(in *input) {
+	if o.overlayFile != "" {
+		ov, err := overlay.LoadFile(o.overlayFile)
+		if err != nil {
+			failf("loading overlay: %v", err)
+		}
+		if err := ov.Apply(in.bom); err != nil {
+			failf("applying overlay: %v", err)
+		}
 	}
 
-	// Show statistics if requested
-	if showStats {
-		printStatistics(graph, bom)
-		fmt.Println()
+	if o.metaFile != "" {
+		meta, err := metadata.LoadFile(o.metaFile)
+		if err != nil {
+			failf("loading metadata: %v", err)
+		}
+		for _, w := range meta.Apply(in.bom) {
+			logger.Warn(w)
+			in.metadataWarnings = append(in.metadataWarnings, w)
+		}
+	}
+}
+
+// build builds the graph of in.bom
+func (o *options) build(s *settings, in *input, buildOpts dag.BuildOptions) {
+	in.graph = dag.New()
+	componentMap := in.parser.GetComponentMap(in.bom)
+	if err := in.graph.BuildFromSBOMWithOptions(in.bom, componentMap, buildOpts); err != nil {
+		// A cycle is one of the metrics -o prometheus reports, so the
+		// dashboards still get fresh values
+		if s.writer.Name() != "prometheus" || !in.graph.Metrics().Cycles {
+			failf("building DAG: %v", err)
+		}
+		logger.Warn(warning.New(warning.Cycle, "building DAG: %v", err))
+		in.cyclic = true
+	}
+	in.graph.DuplicateServices = duplicateServiceRefs(in.bom, componentMap, in.parser.GetServiceMap(in.bom))
+}
+
+// prepare configures the graph, checks it against the warning policy and
+// applies --reachable-only, --prune-leaves-of-type and --redact
+func (o *options) prepare(s *settings, in *input, interrupted *interrupt.Handler) {
+	graph := in.graph
+	graph.OwnerProperty = o.ownerProp
+	graph.LinkType = o.linkType
+	var err error
+	if graph.Pins, err = dag.ParsePins(o.pinFlag); err != nil {
+		fatalf("invalid --pin: %v", err)
+	}
+	if err := graph.CheckPins(); err != nil {
+		fatalf("%v", err)
+	}
+
+	// Without a single edge every component lands in step 1, which looks
+	// like a plan but only reflects missing data
+	in.noDependencies = graph.GetNodeCount() > 1 && graph.GetEdgeCount() == 0
+	if in.noDependencies {
+		if o.requireDeps {
+			fatalf("no dependency information found in the SBOM (--require-dependencies)")
+		}
+		logger.Warn(warning.New(warning.NoDependencies, "no dependency information found; all %d components treated as independent", graph.GetNodeCount()))
+	}
+
+	in.conflicts = dag.FindVersionConflicts(graph)
+	for _, conflict := range in.conflicts {
+		logger.Warn(warning.New(warning.VersionConflict, "version conflict: %s", conflict))
+	}
+	if o.failOnConfl && len(in.conflicts) > 0 {
+		fatalf("found %d version %s (--fail-on-conflict)", len(in.conflicts), plural.Noun(len(in.conflicts), "conflict"))
+	}
+	// Nothing is planned, run or written once the SBOM breaks the warning policy
+	checkFailOn()
+
+	if o.reachable {
+		if len(s.targets) == 0 {
+			if in.bom.Metadata == nil || in.bom.Metadata.Component == nil || in.bom.Metadata.Component.BOMRef == "" {
+				fatalf("--reachable-only needs a metadata component with a bom-ref, or --target")
+			}
+			s.targets = []string{in.bom.Metadata.Component.BOMRef}
+		}
+		if in.unreachable, err = graph.KeepReachable(s.targets); err != nil {
+			fatalf("invalid --target: %v", err)
+		}
+		logger.Infof("dropped %d unreachable %s", len(in.unreachable), plural.Noun(len(in.unreachable), "component"))
+	}
+
+	if len(s.pruneLeaves) > 0 {
+		in.pruned = graph.PruneLeaves(s.pruneLeaves)
+		logger.Infof("pruned %d leaf %s of type %s", len(in.pruned), plural.Noun(len(in.pruned), "component"), strings.Join(s.pruneLeaves, ", "))
+	}
+
+	if o.redact {
+		in.graph = redactGraph(graph, o.redactSalt, o.redactMap, interrupted)
+	}
+}
+
+// runMode runs the one mode the options select: a check, a query, a probe,
+// --exec or, by default, writing the plan
+func (o *options) runMode(s *settings, in *input, interrupted *interrupt.Handler) {
+	graph := in.graph
+	asJSON := s.writer.Name() == "json"
+
+	// Show statistics if requested. JSON plans carry them instead, so the
+	// output stays valid JSON.
+	statsInPlan := o.showStats && asJSON && !o.runExec && o.why == "" && o.removal == "" && o.depsOf == "" && o.rdepsOf == "" && o.search == "" && o.checkPlan == "" && o.compareFile == "" && o.policyFile == "" && o.verifyFile == "" && !o.suggestEdges
+	if o.showStats && !statsInPlan {
+		printStatistics(in, o.byEcosystem, o.chokepoints)
+		fmt.Fprintln(stdout)
 	}
 
-	// Handle different output modes
-	if showGroups || outputMode == "groups" {
-		printDeploymentGroups(graph)
-	} else if outputMode == "dot" {
-		printDotFormat(graph)
+	if o.requireDone {
+		completeness := graph.Completeness()
+		if notComplete := append(completeness.Incomplete, completeness.Unknown...); len(notComplete) > 0 {
+			sort.Strings(notComplete)
+			fatalf("dependencies are not declared complete for: %s", strings.Join(notComplete, ", "))
+		}
+	}
+
+	if o.freezeFile != "" || o.verifyFile != "" {
+		lock := newLock(graph, in.inputs, o.env, o.timestamp, in.graphOptions, "env="+o.env,
+			fmt.Sprintf("reachable-only=%t target=%s prune-leaves=%s", o.reachable, strings.Join(s.targets, ","), strings.Join(s.pruneLeaves, ",")))
+		if o.verifyFile != "" {
+			if !runVerifyFrozen(lock, o.verifyFile) {
+				exit(1)
+			}
+			return
+		}
+		if err := output.WriteFileAtomic(o.freezeFile, lock.Write); err != nil {
+			failf("writing lock: %v", err)
+		}
+		logger.Infof("froze the plan to %s", o.freezeFile)
+	}
+
+	if o.runProbe {
+		interrupted.EnterCancelable("probing endpoints")
+		ok := runEndpointProbe(interrupted.Context(), graph, probe.Options{Timeout: o.timeout, Concurrency: o.probeLimit})
+		interrupted.Exit()
+		if !ok && o.failOnProbe {
+			exit(1)
+		}
+		return
+	}
+
+	// Prompts go to stderr so the plan on stdout stays clean
+	var prompter *plan.Prompter
+	if o.interactive {
+		prompter = plan.NewPrompter(stdin, stderr)
+		prompter.AssumeYes = o.assumeYes
+		if o.sessionLog != "" {
+			f, err := os.OpenFile(o.sessionLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+			if err != nil {
+				failf("opening session log: %v", err)
+			}
+			defer f.Close()
+			prompter.Log = f
+		}
+	}
+
+	ok := true
+	switch {
+	case o.runExec:
+		p := selectPlan(graph, s.selection, o.showReverse)
+		opts := plan.ExecuteOptions{MaxParallel: o.maxParallel, ContinueOnError: o.continueErr}
+		commandOpts := plan.CommandOptions{RetryDelay: o.retryDelay, HealthTimeout: o.healthWait}
+		interrupted.EnterCancelable("running commands")
+		ok = runPlanExec(interrupted.Context(), graph, p, opts, commandOpts, o.retries, o.showReverse, prompter)
+		interrupted.Exit()
+	case o.why != "":
+		printWhy(graph, o.why, o.maxPaths)
+	case o.removal != "":
+		printRemovalImpact(graph, o.removal, asJSON)
+	case o.depsOf != "":
+		printNeighborhood(graph, o.depsOf, o.hops(), dag.DirectionDependencies, asJSON)
+	case o.rdepsOf != "":
+		printNeighborhood(graph, o.rdepsOf, o.hops(), dag.DirectionDependents, asJSON)
+	case o.search != "":
+		ok = printSearch(graph, o.search, o.caseSens, asJSON) || !o.failIfEmpty
+	case o.checkPlan != "":
+		ok = runPlanCheck(graph, o.checkPlan)
+	case o.compareFile != "":
+		ok = runGroupCheck(graph, o.compareFile, o.throughGroup)
+	case s.rules != nil:
+		ok = runPolicyCheck(graph, s.rules, o.policyFile)
+	case o.suggestEdges:
+		printEdgeSuggestions(graph)
+	default:
+		o.writePlan(s, in, statsInPlan, prompter, interrupted)
+	}
+	if !ok {
+		exit(1)
+	}
+}
+
+// writePlan writes the selected output, the teardown plan with --reverse
+func (o *options) writePlan(s *settings, in *input, statsInPlan bool, prompter *plan.Prompter, interrupted *interrupt.Handler) {
+	graph, writer := in.graph, s.writer
+	var deployment *plan.Plan
+	if in.cyclic {
+		deployment = withProvenance(&plan.Plan{SchemaVersion: plan.SchemaVersion}, in.inputs, o.env, o.timestamp)
 	} else {
-		// Default: show deployment order
-		if showReverse {
-			printReverseOrder(graph)
+		deployment = withProvenance(selectPlan(graph, s.selection, o.showReverse), in.inputs, o.env, o.timestamp)
+	}
+	if statsInPlan {
+		deployment.Stats = planStats(in, o.byEcosystem, o.chokepoints)
+	}
+	if o.window > 0 {
+		splitWindows(deployment, graph, o.window, o.defaultDur, logger)
+	}
+	opts := o.outputOptions(s, in)
+	if prompter != nil {
+		// Each group is shown as soon as it is confirmed, so nothing is
+		// buffered
+		opts.Pause = prompter.Pause
+		if err := writer.Write(stdout, deployment, graph, opts); err != nil {
+			failf("writing %s output: %v", writer.Name(), err)
+		}
+		checkFailOn()
+		return
+	}
+	// Render into a buffer and write it in one go, so an interrupt never
+	// leaves truncated JSON or YAML behind
+	interrupted.Enter("writing output")
+	var rendered bytes.Buffer
+	if err := writer.Write(&rendered, deployment, graph, opts); err != nil {
+		failf("writing %s output: %v", writer.Name(), err)
+	}
+	if o.validateSelf {
+		if err := plan.Validate(rendered.Bytes()); err != nil {
+			fatalf("--validate-self: %v", err)
+		}
+	}
+	if err := interrupted.Critical(func() error {
+		if o.toStdout() {
+			_, err := rendered.WriteTo(stdout)
+			return err
+		}
+		return output.WriteFileAtomic(o.outputFile, func(w io.Writer) error {
+			_, err := rendered.WriteTo(w)
+			return err
+		})
+	}); err != nil {
+		failf("writing %s output: %v", writer.Name(), err)
+	}
+	// The output formats warn about what they leave out
+	checkFailOn()
+}
+
+// outputOptions returns the options of the output writers
+func (o *options) outputOptions(s *settings, in *input) output.Options {
+	opts := output.Options{
+		Label:            s.label,
+		ClusterBy:        s.clusterBy,
+		Direction:        s.direction,
+		TreeFrom:         o.treeFrom,
+		TreeDepth:        o.treeDepth,
+		ServiceKind:      o.serviceKind,
+		K8sAnnotation:    o.k8sAnnot,
+		DefaultDuration:  o.defaultDur,
+		GanttMaxTasks:    o.ganttMax,
+		Describe:         o.describe,
+		Numbering:        s.numbering,
+		ShowBlockers:     o.blockers,
+		ShowTrust:        o.showTrust,
+		DOTLegend:        o.dotLegend,
+		ByOwner:          o.byOwner,
+		Flat:             o.flat,
+		DescribeWidth:    describeWidth(),
+		DescribeMax:      o.describeMax,
+		MaxLabelLen:      o.maxLabelLen,
+		Teardown:         o.showReverse,
+		Banner:           !o.showStats, // the stats already carry the banner
+		Style:            style,
+		ParseWarnings:    in.parseWarnings,
+		MetadataWarnings: in.metadataWarnings,
+		Logger:           logger,
+	}
+	if !o.redact {
+		// The metadata names what --redact hides
+		opts.BOM = in.bom
+	}
+	if o.baseline != "" {
+		var err error
+		if opts.Baseline, err = in.parser.ParseFile(o.baseline); err != nil {
+			failf("parsing --baseline: %v", err)
+		}
+	}
+	return opts
+}
+
+// checkStrict fails the run on parse warnings with --strict, except those
+// --suppress acknowledges
+func checkStrict(strict bool, policy warning.Policy, parseWarnings []warning.Warning) {
+	if n := len(policy.Unsuppressed(parseWarnings)); strict && n > 0 {
+		fatalf("SBOM has %d parse %s (--strict)", n, plural.Noun(n, "warning"))
+	}
+}
+
+// checkFailOn fails the run once a warning with a --fail-on code was reported
+func checkFailOn() {
+	failures := logger.Failures()
+	if len(failures) == 0 {
+		return
+	}
+	var codes []string
+	for _, w := range failures {
+		if !slices.Contains(codes, string(w.Code)) {
+			codes = append(codes, string(w.Code))
+		}
+	}
+	fatalf("%d %s with --fail-on codes: %s", len(failures), plural.Noun(len(failures), "warning"), strings.Join(codes, ", "))
+}
+
+// logger reports warnings and errors on stderr; main replaces it once the
+// logging flags are parsed
+var logger = logging.New(os.Stderr, logging.LevelWarn, logging.FormatText)
+
+// style colors human-readable output on stdout; main sets it once the color
+// flags are parsed
+var style output.Style
+
+// envPrefix prefixes the environment variables that provide flag defaults
+const envPrefix = "BOM_DAGGER_"
+
+// envName returns the environment variable for a flag, e.g.
+// BOM_DAGGER_SORT_WITHIN_GROUP for --sort-within-group
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnvDefaults sets every flag of fs from its BOM_DAGGER_<NAME> variable
+// before parsing, so explicit flags win; shorthands use the long form's one
+func applyEnvDefaults(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || len(f.Name) == 1 {
+			return
+		}
+		name := envName(f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		set := f.Value.Set
+		if d, ok := f.Value.(interface{ SetDefault(string) error }); ok {
+			set = d.SetDefault
+		}
+		if setErr := set(value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %v", value, name, setErr)
+		}
+	})
+	return err
+}
+
+// flagGiven reports whether any of the named flags was set on the command
+// line or through its environment variable
+func flagGiven(fs *flag.FlagSet, names ...string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		if slices.Contains(names, f.Name) {
+			given = true
+		}
+	})
+	for _, name := range names {
+		if _, ok := os.LookupEnv(envName(name)); ok && len(name) > 1 { // shorthands have no variable
+			given = true
+		}
+	}
+	return given
+}
+
+// fileList is a repeatable flag collecting file paths. A value from the
+// environment is a default that the first command-line value replaces.
+type fileList struct {
+	files     []string
+	isDefault bool
+}
+
+func (l *fileList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(l.files, ",")
+}
+
+func (l *fileList) Set(value string) error {
+	if l.isDefault {
+		l.files, l.isDefault = nil, false
+	}
+	l.files = append(l.files, value)
+	return nil
+}
+
+// SetDefault sets the value from the environment (see applyEnvDefaults)
+func (l *fileList) SetDefault(value string) error {
+	l.files, l.isDefault = []string{value}, true
+	return nil
+}
+
+// sbomExtensions are the file extensions read from an -i directory
+var sbomExtensions = []string{".json"}
+
+// expandInputDirs turns file:// URLs into paths and expands -i directories
+// into their sorted SBOM files; fromDir records which files came from one
+func expandInputDirs(values []string, namespaced, recursive bool) (expanded []string, fromDir map[string]bool, err error) {
+	fromDir = make(map[string]bool)
+	for _, value := range values {
+		path, prefix := value, ""
+		if name, rest, ok := strings.Cut(value, "="); namespaced && ok && name != "" && !strings.ContainsAny(name, `/\`) {
+			path, prefix = rest, name+"="
+		}
+		if path, err = parser.FilePath(path); err != nil {
+			return nil, nil, err
+		}
+		value = prefix + path
+		info, err := os.Stat(path)
+		if path == stdinPath || err != nil || !info.IsDir() {
+			expanded = append(expanded, value)
+			continue
+		}
+		if path != value {
+			return nil, nil, fmt.Errorf("%s: a directory cannot be given a namespace; its files are namespaced by name", value)
+		}
+		files, err := sbomFiles(path, recursive)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(files) == 0 {
+			return nil, nil, fmt.Errorf("no SBOM files (%s) in %s", strings.Join(sbomExtensions, ", "), path)
+		}
+		for _, file := range files {
+			fromDir[file] = true
+		}
+		expanded = append(expanded, files...)
+	}
+	return expanded, fromDir, nil
+}
+
+// sbomFiles lists the files of dir with an SBOM extension, sorted
+func sbomFiles(dir string, recursive bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if slices.Contains(sbomExtensions, strings.ToLower(filepath.Ext(path))) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// parseInput parses the SBOM at path, or standard input for stdinPath, and
+// returns the hex SHA-256 digest of everything read for the provenance
+func parseInput(p *parser.Parser, path string) (*sbom.CycloneDX, []parser.Warning, string, error) {
+	reader := stdin
+	if path != stdinPath {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+	hash := sha256.New()
+	tee := io.TeeReader(reader, hash)
+	bom, warnings, err := p.ParseWithWarnings(tee)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	// The decoder may stop before trailing whitespace
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return bom, warnings, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// stdinPath is the input path reading standard input
+const stdinPath = "-"
+
+// countOf returns how many values equal value
+func countOf(values []string, value string) int {
+	n := 0
+	for _, v := range values {
+		if v == value {
+			n++
+		}
+	}
+	return n
+}
+
+// splitNamespaces splits --namespace-inputs inputs into paths and the
+// namespaces their refs are prefixed with: name from name=path, or the file
+// name without its extension. Namespaces must be unique.
+func splitNamespaces(values []string) (paths, namespaces []string, err error) {
+	seen := make(map[string]string)
+	for _, value := range values {
+		path, namespace := value, ""
+		if name, rest, ok := strings.Cut(value, "="); ok && name != "" && !strings.ContainsAny(name, `/\`) {
+			path, namespace = rest, name
+		} else if path == stdinPath {
+			namespace = "stdin"
 		} else {
-			printDeploymentOrder(graph)
+			base := filepath.Base(path)
+			namespace = strings.TrimSuffix(base, filepath.Ext(base))
+		}
+		if err := parser.ValidateNamespace(namespace); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", value, err)
+		}
+		if other, dup := seen[namespace]; dup {
+			return nil, nil, fmt.Errorf("%s and %s share the namespace %q; name them with -i name=path", other, path, namespace)
+		}
+		seen[namespace] = path
+		paths = append(paths, path)
+		namespaces = append(namespaces, namespace)
+	}
+	return paths, namespaces, nil
+}
+
+// parseFlags applies environment defaults and parses args into fs, reporting
+// errors with the valid options; -o dot, -o=dot and --output=dot are equivalent
+func parseFlags(fs *flag.FlagSet, args []string) {
+	if err := applyEnvDefaults(fs); err != nil {
+		fatalf("%v", err)
+	}
+	fs.Init(fs.Name(), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		fatalf("%s", flagError(fs, err))
+	}
+}
+
+// flagError rewrites a flag parse error into a message naming the option the
+// way it is documented, suggesting the closest valid option for unknown ones
+// and listing all of them
+func flagError(fs *flag.FlagSet, err error) string {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			names = append(names, optionName(f.Name))
+		}
+	})
+	// Shorthands first, then long options, each alphabetically
+	sort.Slice(names, func(i, j int) bool {
+		if short := len(names[i]) == 2; short != (len(names[j]) == 2) {
+			return short
+		}
+		return names[i] < names[j]
+	})
+	valid := "Valid options: " + strings.Join(names, ", ")
+
+	message := err.Error()
+	if unknown, ok := strings.CutPrefix(message, "flag provided but not defined: -"); ok {
+		unknown = strings.TrimLeft(unknown, "-")
+		message = fmt.Sprintf("unknown option %s", optionName(unknown))
+		if suggestion := closestFlag(fs, unknown); suggestion != "" {
+			message += fmt.Sprintf(" (did you mean %s?)", optionName(suggestion))
+		}
+	} else if name, ok := strings.CutPrefix(message, "flag needs an argument: -"); ok {
+		message = fmt.Sprintf("option %s needs a value", optionName(name))
+	} else if m := invalidFlagValue.FindStringSubmatch(message); m != nil {
+		message = fmt.Sprintf("invalid value %s for %s: %s", m[1], optionName(m[2]), m[3])
+	}
+	return message + "\n" + valid
+}
+
+// hiddenFlags are developer options left out of the help and option lists
+var hiddenFlags = map[string]bool{
+	"generate": true, // synthetic SBOMs for benchmarks, see internal/testgen
+}
+
+// invalidFlagValue matches the flag package's error for unparseable values
+var invalidFlagValue = regexp.MustCompile(`^invalid (?:boolean )?value (".*") for (?:flag )?-([^:]+): (.*)$`)
+
+// optionName formats a flag name as documented: -x for shorthands, --name otherwise
+func optionName(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// closestFlag returns the registered long flag nearest to name by edit
+// distance, or "" when none is close enough to be a plausible typo
+func closestFlag(fs *flag.FlagSet, name string) string {
+	best, bestDistance := "", 3
+	fs.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) == 1 || hiddenFlags[f.Name] {
+			return
 		}
+		if d := editDistance(name, f.Name); d < bestDistance {
+			best, bestDistance = f.Name, d
+		}
+	})
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
 	}
+	return prev[len(b)]
+}
+
+// fatalf logs an error and exits with status 1
+func fatalf(format string, args ...any) {
+	logger.Errorf(format, args...)
+	exit(1)
+}
+
+// failf logs what failed, as in "Error parsing SBOM: ...", and exits with
+// status 1
+func failf(format string, args ...any) {
+	logger.Failf(format, args...)
+	exit(1)
 }
 
 func printVersion() {
-	fmt.Printf("bom-dagger version %s\n", Version)
-	fmt.Printf("Go version: %s\n", runtime.Version())
-	fmt.Printf("OS/Arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(stdout, "bom-dagger version %s\n", Version)
+	fmt.Fprintf(stdout, "Go version: %s\n", runtime.Version())
+	fmt.Fprintf(stdout, "OS/Arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
 }
 
 func printUsage() {
-	fmt.Printf("bom-dagger %s - Creates a DAG for deployment order from a CycloneDX SBOM\n", Version)
-	fmt.Println()
-	fmt.Println("Usage: bom-dagger -i <sbom-file> [options]")
-	fmt.Println()
-	fmt.Println("Options:")
-	fmt.Println("  -i, --input <file>     Path to CycloneDX SBOM file (JSON)")
-	fmt.Println("  -o, --output <mode>    Output mode: order (default), groups, dot")
-	fmt.Println("  -r, --reverse          Show reverse order (teardown sequence)")
-	fmt.Println("  -g, --groups           Show deployment groups (parallel deployment)")
-	fmt.Println("  -s, --stats            Show graph statistics")
-	fmt.Println("  -h, --help             Show this help message")
-	fmt.Println()
-	fmt.Println("Examples:")
-	fmt.Println("  bom-dagger -i sbom.json                    # Show deployment order")
-	fmt.Println("  bom-dagger -i sbom.json -r                 # Show teardown order")
-	fmt.Println("  bom-dagger -i sbom.json -g                 # Show parallel groups")
-	fmt.Println("  bom-dagger -i sbom.json -o dot > graph.dot # Generate DOT format")
+	fmt.Fprintf(stdout, "bom-dagger %s - Creates a DAG for deployment order from a CycloneDX SBOM\n", Version)
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "Usage: bom-dagger -i <sbom-file> [options]")
+	fmt.Fprintln(stdout, "       bom-dagger serve [--listen <addr>] [--max-body <bytes>] [--timeout <duration>]")
+	fmt.Fprintln(stdout, "       bom-dagger schema    Print the JSON Schema of -o json plans")
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "Options:")
+	fmt.Fprintln(stdout, "  -i, --input <file>          Path to CycloneDX SBOM file (JSON), or a directory")
+	fmt.Fprintln(stdout, "                              of them; repeat to merge; - reads standard input")
+	fmt.Fprintln(stdout, "      --recursive             Also read SBOMs in subdirectories of an -i directory")
+	fmt.Fprintln(stdout, "      --format <format>       Input format: auto (default), cyclonedx-json,")
+	fmt.Fprintln(stdout, "                              cyclonedx-xml, spdx-json, cyclonedx-protobuf")
+	fmt.Fprintln(stdout, "      --quiet                 Only log errors")
+	fmt.Fprintln(stdout, "      --verbose               Also log progress of each phase")
+	fmt.Fprintln(stdout, "      --log-format <format>   Log lines on stderr as text (default) or json")
+	fmt.Fprintln(stdout, "      --run-timeout <d>       Give up when the run takes longer, naming the phase")
+	fmt.Fprintln(stdout, "                              it was in; exit status 124 (default: no limit)")
+	fmt.Fprintln(stdout, "      --color <when>          Color output: auto (default), always or never")
+	fmt.Fprintln(stdout, "      --no-color              Never color output")
+	fmt.Fprintln(stdout, "      --strict                Treat SBOM parse warnings as errors")
+	fmt.Fprintln(stdout, "      --strict-parse          Reject unknown fields and mistyped or missing refs,")
+	fmt.Fprintln(stdout, "                              dependsOn lists and names, with their JSON paths")
+	fmt.Fprintln(stdout, "      --suppress <codes>      Do not report warnings with these codes, e.g. W001,W003")
+	fmt.Fprintln(stdout, "      --fail-on <codes>       Fail when a warning with one of these codes is reported")
+	fmt.Fprintln(stdout, "  -o, --output <mode>         Output mode: order (default), groups, dot, json,")
+	fmt.Fprintln(stdout, "                              markdown, terraform, tree, mermaid, backstage,")
+	fmt.Fprintln(stdout, "                              schedule, schedule-json, gantt, shell, adjacency,")
+	fmt.Fprintln(stdout, "                              k8s-patch, images, spdx, prometheus,")
+	fmt.Fprintln(stdout, "                              release-report, release-report-json, graph;")
+	fmt.Fprintln(stdout, "                              -o list describes each mode")
+	fmt.Fprintln(stdout, "  -f, --output-file <file>    Write the output to a file, replaced atomically;")
+	fmt.Fprintln(stdout, "                              without -o the mode follows the extension: .dot,")
+	fmt.Fprintln(stdout, "                              .json, .md, .mmd or .prom; - writes to stdout")
+	fmt.Fprintln(stdout, "  -r, --reverse               Show reverse order (teardown sequence)")
+	fmt.Fprintln(stdout, "  -g, --groups                Show deployment groups (parallel deployment)")
+	fmt.Fprintln(stdout, "  -s, --stats                 Show graph statistics")
+	fmt.Fprintln(stdout, "      --stats-by-ecosystem    Break per-step counts down by purl ecosystem")
+	fmt.Fprintln(stdout, "      --chokepoints <n>       With --stats, rank the n components blocking the")
+	fmt.Fprintln(stdout, "                              most of the plan if they fail")
+	fmt.Fprintln(stdout, "      --runtime-only          Ignore build-time dependencies")
+	fmt.Fprintln(stdout, "      --include-excluded      Keep components with scope excluded (not shipped)")
+	fmt.Fprintln(stdout, "      --env <name>            Leave out components whose bom-dagger:envs property")
+	fmt.Fprintln(stdout, "                              does not list this environment")
+	fmt.Fprintln(stdout, "      --steps <range>         Only show steps in a range, e.g. 2, 1-3 or 4-")
+	fmt.Fprintln(stdout, "      --top <n>               Only show the first N components of the order")
+	fmt.Fprintln(stdout, "      --sort-within-group <s> Order components within a step by type, name or ref")
+	fmt.Fprintln(stdout, "                              (default: priority, then name, then ref)")
+	fmt.Fprintln(stdout, "      --type-order <types>    Type precedence for sorting by type (default")
+	fmt.Fprintln(stdout, "                              data,platform,container,application,library)")
+	fmt.Fprintln(stdout, "      --shuffle-seed <n>      Order components within a step randomly from a seed")
+	fmt.Fprintln(stdout, "      --pin <pins>            Place components early or late, e.g. monitor=late")
+	fmt.Fprintln(stdout, "      --link-type <type>      External reference type linked from markdown and")
+	fmt.Fprintln(stdout, "                              dot output (default documentation)")
+	fmt.Fprintln(stdout, "      --owner-property <key>  Property naming a component's team (default")
+	fmt.Fprintln(stdout, "                              bom-dagger:team, then the supplier name)")
+	fmt.Fprintln(stdout, "      --owner <team>          Only show the components a team owns")
+	fmt.Fprintln(stdout, "      --by-owner              Split each deployment group by owner")
+	fmt.Fprintln(stdout, "      --numbering <n>         Number the order by group (default) or sequence")
+	fmt.Fprintln(stdout, "      --show-blockers         List what each order entry waits for, with steps")
+	fmt.Fprintln(stdout, "      --show-trust            Mark services crossing a trust boundary")
+	fmt.Fprintln(stdout, "      --describe              Print publishers and descriptions under order and")
+	fmt.Fprintln(stdout, "                              groups entries")
+	fmt.Fprintln(stdout, "      --describe-max <n>      Truncate longer descriptions (default 300, 0 = never)")
+	fmt.Fprintln(stdout, "      --max-label-len <n>     Shorten longer names and refs in text, dot, mermaid")
+	fmt.Fprintln(stdout, "                              and other output for people (default 80, 0 = never)")
+	fmt.Fprintln(stdout, "      --direction <dir>       Adjacency output: deps, dependents or both (default)")
+	fmt.Fprintln(stdout, "      --from <ref>            Root the tree output at a specific component")
+	fmt.Fprintln(stdout, "      --depth <n>             Limit the depth of the tree output")
+	fmt.Fprintln(stdout, "      --cluster-by <mode>     Cluster dot and mermaid output: group or step")
+	fmt.Fprintln(stdout, "      --dot-legend            Append a legend of node and edge styles to dot output")
+	fmt.Fprintln(stdout, "      --label-template <tmpl> Node labels for dot, mermaid and tree output, using")
+	fmt.Fprintln(stdout, "                              .Name .Version .Ref .Type .Purl and .Prop \"key\"")
+	fmt.Fprintln(stdout, "      --default-duration <d>  Schedule and window duration without a property")
+	fmt.Fprintln(stdout, "                              (default 1m)")
+	fmt.Fprintln(stdout, "      --window <d>            Split the order and json output into maintenance")
+	fmt.Fprintln(stdout, "                              windows of this length, e.g. 2h")
+	fmt.Fprintln(stdout, "      --gantt-max-tasks <n>   Maximum tasks in -o gantt output (default 200)")
+	fmt.Fprintln(stdout, "      --k8s-annotation <key>  Annotation set to the wave by -o k8s-patch")
+	fmt.Fprintln(stdout, "      --flat                  List each -o images image once, first needed first")
+	fmt.Fprintln(stdout, "      --backstage-service-kind <kind>")
+	fmt.Fprintln(stdout, "                              Backstage kind for services: component, api")
+	fmt.Fprintln(stdout, "      --redact                Replace identifying data with stable pseudonyms")
+	fmt.Fprintln(stdout, "      --redact-salt <salt>    Salt used to derive --redact pseudonyms")
+	fmt.Fprintln(stdout, "      --redact-map <file>     Write the pseudonym mapping to a file")
+	fmt.Fprintln(stdout, "      --require-complete      Fail unless compositions declare dependencies complete")
+	fmt.Fprintln(stdout, "      --require-dependencies  Fail when the SBOM declares no dependencies at all")
+	fmt.Fprintln(stdout, "      --fail-on-conflict      Fail when a component appears at several versions")
+	fmt.Fprintln(stdout, "      --overlay <file>        Apply extra dependencies and exclusions")
+	fmt.Fprintln(stdout, "      --reachable-only        Drop components the metadata component never reaches")
+	fmt.Fprintln(stdout, "      --target <refs>         Comma-separated refs --reachable-only starts from")
+	fmt.Fprintln(stdout, "      --metadata <file>       Merge properties keyed by bom-ref, purl or name")
+	fmt.Fprintln(stdout, "      --bom-dir <dir>         Resolve BOM-Link dependencies into these SBOMs")
+	fmt.Fprintln(stdout, "      --prune-leaves-of-type <types>")
+	fmt.Fprintln(stdout, "                              Remove unused components of these types, e.g.")
+	fmt.Fprintln(stdout, "                              library,file,operating-system")
+	fmt.Fprintln(stdout, "      --dedupe-by purl        Unify components sharing a purl under one ref")
+	fmt.Fprintln(stdout, "      --dedupe-version-tolerant")
+	fmt.Fprintln(stdout, "                              Keep the first version of a purl on conflicts")
+	fmt.Fprintln(stdout, "      --namespace-inputs      Prefix each input's refs with its name (-i name=path)")
+	fmt.Fprintln(stdout, "      --synthesize-missing    Add placeholders for dependencies not in the SBOM")
+	fmt.Fprintln(stdout, "      --infer-from <source>   Infer dependencies from nesting, compositions (their")
+	fmt.Fprintln(stdout, "                              assemblies) or both")
+	fmt.Fprintln(stdout, "      --infer-from-nesting    Same as --infer-from both")
+	fmt.Fprintln(stdout, "      --cache-dir <dir>       Reuse graphs built from unchanged inputs and options")
+	fmt.Fprintln(stdout, "      --from-graph <file>     Load a graph written by -o graph instead of an SBOM")
+	fmt.Fprintln(stdout, "      --exec                  Run each component's bom-dagger:deploy-command in")
+	fmt.Fprintln(stdout, "                              plan order (teardown commands with --reverse)")
+	fmt.Fprintln(stdout, "      --max-parallel <n>      Maximum --exec commands at once within a step")
+	fmt.Fprintln(stdout, "      --continue-on-error     Keep running --exec commands after one fails")
+	fmt.Fprintln(stdout, "      --interactive           Show the order or groups one group at a time; with")
+	fmt.Fprintln(stdout, "                              --exec, ask y/n/skip before each group")
+	fmt.Fprintln(stdout, "      --assume-yes            Answer every --interactive prompt with yes")
+	fmt.Fprintln(stdout, "      --session-log <file>    Append each --interactive answer, timestamped")
+	fmt.Fprintln(stdout, "      --retries <n>           Further attempts of a failed --exec command (default 0)")
+	fmt.Fprintln(stdout, "      --retry-delay <d>       Wait between --exec attempts (default 5s)")
+	fmt.Fprintln(stdout, "      --health-timeout <d>    How long a health command may keep failing (default 5m)")
+	fmt.Fprintln(stdout, "      --probe                 HTTP GET each service endpoint and report status")
+	fmt.Fprintln(stdout, "      --timeout <duration>    Timeout for each endpoint probe (default 5s)")
+	fmt.Fprintln(stdout, "      --probe-concurrency <n> Maximum concurrent endpoint probes (default 8)")
+	fmt.Fprintln(stdout, "      --fail-on-probe         Exit non-zero when any endpoint probe fails")
+	fmt.Fprintln(stdout, "      --timestamp <time>      Generation time in the provenance of json and")
+	fmt.Fprintln(stdout, "                              markdown plans and spdx documents (default")
+	fmt.Fprintln(stdout, "                              SOURCE_DATE_EPOCH or now)")
+	fmt.Fprintln(stdout, "      --why <from>:<to>       Print the dependency paths that order from after to")
+	fmt.Fprintln(stdout, "      --max-paths <n>         Maximum paths printed by --why (default 10, 0 = all)")
+	fmt.Fprintln(stdout, "      --deps <ref>            List what a component depends on, with the hops to")
+	fmt.Fprintln(stdout, "                              each (limit with --depth)")
+	fmt.Fprintln(stdout, "      --rdeps <ref>           List what depends on a component, with the hops to")
+	fmt.Fprintln(stdout, "                              each (limit with --depth)")
+	fmt.Fprintln(stdout, "      --simulate-removal <ref> Report what removing a component would break")
+	fmt.Fprintln(stdout, "      --search <pattern>      List components whose ref or name matches a glob")
+	fmt.Fprintln(stdout, "                              (or re:<regexp>), with step and edge counts")
+	fmt.Fprintln(stdout, "      --case-sensitive        Match --search patterns case-sensitively")
+	fmt.Fprintln(stdout, "      --fail-if-empty         Exit non-zero when --search matches nothing")
+	fmt.Fprintln(stdout, "      --check-plan <file>     Compare against a previously exported JSON plan")
+	fmt.Fprintln(stdout, "      --baseline <file>       SBOM of the last deployment, for -o release-report")
+	fmt.Fprintln(stdout, "      --compare-groups <file> Fail when a component changed group since a JSON plan")
+	fmt.Fprintln(stdout, "      --through-group <n>     Only check groups 1 to n with --compare-groups")
+	fmt.Fprintln(stdout, "      --policy <file>         Fail when the graph breaks a rule of a policy file")
+	fmt.Fprintln(stdout, "      --suggest-edges         Print likely missing dependencies as an overlay")
+	fmt.Fprintln(stdout, "                              snippet, without applying them")
+	fmt.Fprintln(stdout, "      --freeze <file>         Write the plan and its fingerprints to a lock file")
+	fmt.Fprintln(stdout, "      --verify-frozen <file>  Fail when the plan drifted from a lock file")
+	fmt.Fprintln(stdout, "      --validate-self         Check the JSON plan against the embedded schema")
+	fmt.Fprintln(stdout, "  -h, --help                  Show this help message")
+	fmt.Fprintln(stdout, "  -v, --version               Show version information")
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "Every option can also be set with a BOM_DAGGER_<NAME> environment variable,")
+	fmt.Fprintln(stdout, "e.g. BOM_DAGGER_INPUT=sbom.json or BOM_DAGGER_SORT_WITHIN_GROUP=type;")
+	fmt.Fprintln(stdout, "options given on the command line take precedence.")
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "Examples:")
+	fmt.Fprintln(stdout, "  bom-dagger -i sbom.json                         # Show deployment order")
+	fmt.Fprintln(stdout, "  bom-dagger -i sbom.json -r                      # Show teardown order")
+	fmt.Fprintln(stdout, "  bom-dagger -i sbom.json -g                      # Show parallel groups")
+	fmt.Fprintln(stdout, "  bom-dagger -i sbom.json -o dot > graph.dot      # Generate DOT format")
+	fmt.Fprintln(stdout, "  bom-dagger -i sbom.json --check-plan plan.json  # Verify a deployed plan")
+	fmt.Fprintln(stdout, "  bom-dagger -i sbom.json --verify-frozen plan.lock # Verify an approved plan")
+}
+
+// isTerminal reports whether r is a terminal, which --interactive needs to
+// read answers from
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
-func printStatistics(graph *dag.Graph, bom *sbom.CycloneDX) {
-	fmt.Println("=== Graph Statistics ===")
-	fmt.Printf("Total Components: %d\n", graph.GetNodeCount())
-	fmt.Printf("Total Dependencies: %d\n", graph.GetEdgeCount())
-	fmt.Printf("Root Components: %d\n", len(graph.Roots))
-	fmt.Printf("SBOM Format: %s %s\n", bom.BOMFormat, bom.SpecVersion)
+// describeWidth is the width --describe wraps descriptions at: the terminal
+// width the shell exports in COLUMNS, or output.DefaultDescribeWidth
+func describeWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return output.DefaultDescribeWidth
 }
 
-func printDeploymentOrder(graph *dag.Graph) {
-	order, err := graph.TopologicalSort()
+// runServe runs the HTTP API until interrupted
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := fs.String("listen", ":8080", "Address to listen on")
+	maxBody := fs.Int64("max-body", server.DefaultMaxBodyBytes, "Maximum SBOM request body size in bytes")
+	timeout := fs.Duration("timeout", server.DefaultTimeout, "Per-request processing timeout")
+	parseFlags(fs, args)
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           server.New(server.Options{MaxBodyBytes: *maxBody, Timeout: *timeout}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(stderr, "bom-dagger %s listening on %s\n", Version, *listen)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		failf("running server: %v", err)
+	}
+}
+
+// printStatistics prints --stats of the graph and what the run removed from
+// or found in it
+func printStatistics(in *input, byEcosystem bool, chokepoints int) {
+	graph, bom := in.graph, in.bom
+	fmt.Fprintln(stdout, "=== Graph Statistics ===")
+	counts := graph.NodeCounts()
+	fmt.Fprintf(stdout, "Total Components: %d\n", counts.Components)
+	if counts.Services > 0 {
+		fmt.Fprintf(stdout, "Total Services: %d\n", counts.Services)
+	}
+	if counts.Nested > 0 {
+		fmt.Fprintf(stdout, "Nested Components: %d (declared inside other components, %d top-level)\n", counts.Nested, counts.TopLevel())
+	}
+	if counts.DuplicateServices > 0 {
+		fmt.Fprintf(stdout, "Duplicate Services: %d (bom-ref shared with a component or another service, planned once)\n", counts.DuplicateServices)
+	}
+	fmt.Fprintf(stdout, "Total Dependencies: %d\n", graph.GetEdgeCount())
+	if inferred := graph.GetInferredEdgeCount(); inferred > 0 {
+		fmt.Fprintf(stdout, "Inferred Dependencies: %d\n", inferred)
+	}
+	fmt.Fprintf(stdout, "Root Components: %d\n", len(graph.Roots))
+	if synthetic := graph.SyntheticCount(); synthetic > 0 {
+		fmt.Fprintf(stdout, "Synthetic Components: %d (external placeholders)\n", synthetic)
+	}
+	if excluded := graph.ExcludedCount(); excluded > 0 {
+		fmt.Fprintf(stdout, "Excluded Components: %d (scope excluded, see --include-excluded)\n", excluded)
+	}
+	if envExcluded := graph.EnvExcludedCount(); envExcluded > 0 {
+		fmt.Fprintf(stdout, "Other-Environment Components: %d (bom-dagger:envs does not list the --env)\n", envExcluded)
+	}
+	if unreachable := len(in.unreachable); unreachable > 0 {
+		fmt.Fprintf(stdout, "Unreachable Components: %d (dropped by --reachable-only)\n", unreachable)
+	}
+	if pruned := len(in.pruned); pruned > 0 {
+		fmt.Fprintf(stdout, "Pruned Components: %d (leaves of --prune-leaves-of-type)\n", pruned)
+	}
+	if conflicts := len(in.conflicts); conflicts > 0 {
+		fmt.Fprintf(stdout, "Version Conflicts: %d (same component at several versions, listed in warnings)\n", conflicts)
+	}
+	fmt.Fprintf(stdout, "SBOM Format: %s %s\n", bom.BOMFormat, bom.SpecVersion)
+	if specWarnings := specVersionWarnings(in.parseWarnings); len(specWarnings) > 0 {
+		fmt.Fprintf(stdout, "Spec Version Mismatches: %d (features newer than the declared specVersion)\n", len(specWarnings))
+		for _, warning := range specWarnings {
+			fmt.Fprintf(stdout, "  %s\n", warning)
+		}
+	}
+	if bom.Metadata != nil {
+		if tools := bom.Metadata.Tools; len(tools) > 0 {
+			names := make([]string, len(tools))
+			for i, tool := range tools {
+				names[i] = tool.String()
+			}
+			fmt.Fprintf(stdout, "SBOM Tools: %s\n", strings.Join(names, ", "))
+		}
+		if lifecycles := bom.Metadata.Lifecycles; len(lifecycles) > 0 {
+			phases := make([]string, len(lifecycles))
+			for i, lifecycle := range lifecycles {
+				phases[i] = lifecycle.String()
+			}
+			fmt.Fprintf(stdout, "SBOM Lifecycles: %s\n", strings.Join(phases, ", "))
+		}
+	}
+	printGroupCounts(graph.GroupCounts())
+	if stats, err := plan.NewStats(graph, byEcosystem); err == nil {
+		printLevelStats(stats.Levels, byEcosystem)
+	}
+	if chokepoints > 0 {
+		if reports, err := graph.Chokepoints(chokepoints); err == nil {
+			printChokepoints(reports, graph.GetNodeCount())
+		}
+	}
+
+	completeness := graph.Completeness()
+	if completeness.Referenced() > 0 {
+		fmt.Fprintf(stdout, "Dependency Completeness: %d complete, %d incomplete, %d unknown\n",
+			len(completeness.Complete), len(completeness.Incomplete), len(completeness.Unknown))
+		output.WriteCompletenessBanner(stdout, completeness, style)
+	}
+}
+
+// planStats returns the statistics a JSON plan carries with --stats
+func planStats(in *input, byEcosystem bool, chokepoints int) *plan.Stats {
+	stats, err := plan.NewStats(in.graph, byEcosystem)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error computing deployment order: %v\n", err)
-		os.Exit(1)
+		failf("computing statistics: %v", err)
+	}
+	stats.Unreachable = len(in.unreachable)
+	stats.Pruned = len(in.pruned)
+	stats.VersionConflicts = len(in.conflicts)
+	stats.NoDependencies = in.noDependencies
+	if chokepoints > 0 {
+		if stats.Chokepoints, err = in.graph.Chokepoints(chokepoints); err != nil {
+			failf("computing chokepoints: %v", err)
+		}
+	}
+	return stats
+}
+
+// duplicateServiceRefs returns the sorted refs of services declared more than
+// once, or also declared by a component. The graph keeps one node per ref, so
+// without this they would silently vanish from the counts.
+func duplicateServiceRefs(bom *sbom.CycloneDX, componentMap map[string]*sbom.Component, serviceMap map[string]*sbom.Service) []string {
+	declared := make(map[string]int, len(serviceMap))
+	for _, service := range bom.Services {
+		declared[service.BOMRef]++
+	}
+	var refs []string
+	for ref := range serviceMap {
+		if _, ok := componentMap[ref]; ok || declared[ref] > 1 {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// specVersionWarnings picks the spec version mismatches out of the parse
+// warnings
+func specVersionWarnings(warnings []warning.Warning) []string {
+	var mismatches []string
+	for _, w := range warnings {
+		if w.Code.SpecVersionMismatch() {
+			mismatches = append(mismatches, w.String())
+		}
 	}
+	return mismatches
+}
 
-	fmt.Println("=== Deployment Order ===")
-	fmt.Println("Deploy components in this sequence:")
-	fmt.Println()
+// printGroupCounts prints a table of components per CycloneDX group when any
+// component declares one
+func printGroupCounts(counts []dag.GroupCount) {
+	if len(counts) == 0 || (len(counts) == 1 && counts[0].Group == "") {
+		return
+	}
 
-	currentStep := 0
-	for _, item := range order {
-		if item.Step != currentStep {
-			if currentStep > 0 {
-				fmt.Println()
+	width := len("(ungrouped)")
+	for _, c := range counts {
+		width = max(width, len(c.Group))
+	}
+	fmt.Fprintln(stdout, "Components by Group:")
+	for _, c := range counts {
+		group := c.Group
+		if group == "" {
+			group = "(ungrouped)"
+		}
+		fmt.Fprintf(stdout, "  %-*s  %d\n", width, group, c.Count)
+	}
+}
+
+// printLevelStats prints a table of how many components and services each
+// deployment step holds, with a column per purl ecosystem when requested
+func printLevelStats(levels []plan.LevelStats, byEcosystem bool) {
+	if len(levels) == 0 {
+		return
+	}
+
+	var ecosystems []string
+	if byEcosystem {
+		seen := make(map[string]bool)
+		for _, level := range levels {
+			for ecosystem := range level.Ecosystems {
+				if !seen[ecosystem] && ecosystem != plan.NoEcosystem {
+					seen[ecosystem] = true
+					ecosystems = append(ecosystems, ecosystem)
+				}
 			}
-			currentStep = item.Step
-			fmt.Printf("Step %d:\n", currentStep)
 		}
-		fmt.Printf("  - %s (ref: %s)\n", item.Component, item.BOMRef)
+		sort.Strings(ecosystems)
+		ecosystems = append(ecosystems, plan.NoEcosystem)
+	}
+
+	fmt.Fprintln(stdout, "Components by Step:")
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "  Step\tTotal\tComponents\tServices")
+	for _, ecosystem := range ecosystems {
+		fmt.Fprint(tw, "\t"+ecosystem)
+	}
+	fmt.Fprintln(tw)
+	for _, level := range levels {
+		fmt.Fprintf(tw, "  %d\t%d\t%d\t%d", level.Step, level.Total, level.Components, level.Services)
+		for _, ecosystem := range ecosystems {
+			fmt.Fprintf(tw, "\t%d", level.Ecosystems[ecosystem])
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
+
+// printChokepoints prints the ranked --chokepoints table, with the share of
+// the n nodes each one blocks
+func printChokepoints(reports []dag.ChokepointReport, n int) {
+	if len(reports) == 0 {
+		fmt.Fprintln(stdout, "Chokepoints: none (nothing depends on another component)")
+		return
 	}
+	fmt.Fprintln(stdout, "Chokepoints:")
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Rank\tComponent\tRef\tStep\tBlocked")
+	for i, report := range reports {
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%d\t%d (%.0f%%)\n", i+1, report.Name, report.Ref, report.Step, report.Blocked, 100*float64(report.Blocked)/float64(n))
+	}
+	tw.Flush()
+}
+
+// planSelection holds the flags that shape the printed plan
+type planSelection struct {
+	steps     plan.StepRange
+	top       int
+	sortBy    plan.SortBy
+	typeOrder []string
+	shuffle   bool  // --shuffle-seed was given
+	seed      int64 // --shuffle-seed
+	owner     string
 }
 
-func printReverseOrder(graph *dag.Graph) {
-	order, err := graph.ReverseTopologicalSort()
+// selectPlan computes the deployment plan, or the teardown plan with reverse,
+// orders components within each step and applies the --owner, --steps and
+// --top filters. Filtering happens on the plan so statistics stay global.
+func selectPlan(graph *dag.Graph, selection planSelection, reverse bool) *plan.Plan {
+	build := plan.New
+	if reverse {
+		build = plan.NewTeardown
+	}
+	p, err := build(graph)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error computing reverse order: %v\n", err)
-		os.Exit(1)
+		failf("computing deployment plan: %v", err)
+	}
+	switch {
+	case selection.shuffle:
+		p = p.ShuffleWithinSteps(selection.seed)
+	case selection.sortBy != "":
+		p = p.SortWithinSteps(selection.sortBy, selection.typeOrder)
+	}
+	if selection.owner != "" {
+		owned := p.ForOwner(selection.owner)
+		if len(owned.Steps) == 0 {
+			fatalf("no components owned by %q (owners: %s)", selection.owner, strings.Join(p.Owners(), ", "))
+		}
+		p = owned
 	}
 
-	fmt.Println("=== Teardown Order ===")
-	fmt.Println("Remove/stop components in this sequence:")
-	fmt.Println()
+	p, err = p.Select(selection.steps, selection.top)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	return p
+}
 
-	currentStep := 0
-	for _, item := range order {
-		if item.Step != currentStep {
-			if currentStep > 0 {
-				fmt.Println()
+// splitWindows sets the durations of the plan's components, from their
+// bom-dagger:duration or def, and splits it into maintenance windows of
+// length window, warning about every step too long to fit one
+func splitWindows(p *plan.Plan, g *dag.Graph, window, def time.Duration, logger *logging.Logger) {
+	durations, err := dag.PropertyDurations(g, def)
+	if err != nil {
+		failf("reading durations: %v", err)
+	}
+	p.SetDurations(func(ref string) time.Duration {
+		if node, ok := g.Node(ref); ok {
+			return durations(node)
+		}
+		return def
+	})
+	if p.Windows, err = p.SplitWindows(window); err != nil {
+		failf("splitting into windows: %v", err)
+	}
+	for _, w := range p.Windows {
+		if w.Oversized {
+			logger.Warn(warning.New(warning.OversizedStep, "step %d takes %s, longer than the %s window; it gets a window of its own",
+				w.Steps[0], w.Duration, window))
+		}
+	}
+}
+
+// withProvenance records the tool, inputs, environment and generation time on
+// a plan. The time comes from --timestamp, then SOURCE_DATE_EPOCH, then the
+// clock.
+func withProvenance(p *plan.Plan, inputs []plan.ProvenanceInput, env, timestamp string) *plan.Plan {
+	generatedAt, err := plan.GenerationTime(timestamp, os.Getenv("SOURCE_DATE_EPOCH"), time.Now())
+	if err != nil {
+		fatalf("%v", err)
+	}
+	p.Provenance = &plan.Provenance{
+		Tool:        "bom-dagger",
+		ToolVersion: Version,
+		GeneratedAt: generatedAt.Format(time.RFC3339),
+		Inputs:      inputs,
+		Env:         env,
+	}
+	return p
+}
+
+// runPlanExec runs every component's deploy (or teardown) command step by
+// step and reports whether all succeeded, summarizing a failed run
+func runPlanExec(ctx context.Context, graph *dag.Graph, p *plan.Plan, opts plan.ExecuteOptions, commandOpts plan.CommandOptions, retries int, teardown bool, prompter *plan.Prompter) bool {
+	property, verb, question := dag.PropertyDeployCommand, "Deploying", "Deploy"
+	if teardown {
+		property, verb, question = dag.PropertyTeardownCommand, "Tearing down", "Tear down"
+	}
+	// Health commands check a deployed component, so teardowns skip them
+	commands, warnings := plan.Commands(graph, property, !teardown, retries)
+	for _, w := range warnings {
+		logger.Warn(w)
+	}
+	checkFailOn()
+
+	var mu sync.Mutex
+	printf := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(stdout, format, args...)
+	}
+	completed := make(map[string]bool)
+
+	opts.OnGroupStart = func(ctx context.Context, step plan.Step) error {
+		printf("=== Group %d ===\n", step.Step)
+		if prompter == nil {
+			return nil
+		}
+		// An interrupt must not wait for the answer
+		type answer struct {
+			reply plan.Reply
+			err   error
+		}
+		answered := make(chan answer, 1)
+		go func() {
+			reply, err := prompter.Confirm(step.Step, fmt.Sprintf("%s group %d (%d %s)?", question, step.Step, len(step.Components), plural.Noun(len(step.Components), "component")))
+			answered <- answer{reply, err}
+		}()
+		var reply plan.Reply
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case a := <-answered:
+			reply, err = a.reply, a.err
+		}
+		switch {
+		case err != nil:
+			return fmt.Errorf("%w: %v", plan.ErrStop, err)
+		case reply == plan.ReplyNo:
+			return fmt.Errorf("%w by the operator", plan.ErrStop)
+		case reply == plan.ReplySkip:
+			printf("Group %d skipped\n", step.Step)
+			return plan.ErrSkipGroup
+		}
+		return nil
+	}
+	opts.OnComponent = func(ctx context.Context, step plan.Step, entry plan.Entry) error {
+		command, ok := commands[entry.BOMRef]
+		if !ok {
+			logger.Infof("no %s property on %s; skipping", property, entry.BOMRef)
+		} else {
+			printf("%s %s (ref: %s)\n", verb, entry.Name, entry.BOMRef)
+			componentOpts := commandOpts
+			componentOpts.OnRetry = func(attempt int, err error, output []byte) {
+				logger.Warnf("%s failed (attempt %d of %d): %v\n%s", entry.BOMRef, attempt, command.Retries+1, err, output)
+			}
+			output, err := plan.RunCommand(ctx, command, componentOpts)
+			if err != nil {
+				logger.Errorf("%s failed: %v\n%s", entry.BOMRef, err, output)
+				return err
 			}
-			currentStep = item.Step
-			fmt.Printf("Step %d:\n", currentStep)
+			logger.Infof("%s finished:\n%s", entry.BOMRef, output)
+		}
+		mu.Lock()
+		completed[entry.BOMRef] = true
+		mu.Unlock()
+		return nil
+	}
+	opts.OnGroupEnd = func(ctx context.Context, step plan.Step, err error) {
+		if err != nil {
+			printf("Group %d failed\n", step.Step)
 		}
-		fmt.Printf("  - %s (ref: %s)\n", item.Component, item.BOMRef)
 	}
+
+	err := p.Execute(ctx, opts)
+	if err == nil {
+		return true
+	}
+	if failed := plan.ComponentErrors(err); len(failed) > 0 {
+		refs := make([]string, len(failed))
+		for i, componentErr := range failed {
+			refs[i] = componentErr.BOMRef
+		}
+		logger.Errorf("%d %s failed: %s", len(failed), plural.Noun(len(failed), "component"), strings.Join(refs, ", "))
+	} else if ctx.Err() == nil { // an interrupt is reported by the caller
+		logger.Errorf("%v", err)
+	}
+
+	summary := p.Summarize(completed, err)
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "=== Exec Summary ===")
+	for _, line := range []struct {
+		label string
+		refs  []string
+	}{
+		{"Completed", summary.Completed},
+		{"Failed", summary.Failed},
+		{"Not started", summary.NotStarted},
+	} {
+		fmt.Fprintf(stdout, "%s: %d", line.label, len(line.refs))
+		if len(line.refs) > 0 {
+			fmt.Fprintf(stdout, " (%s)", strings.Join(line.refs, ", "))
+		}
+		fmt.Fprintln(stdout)
+	}
+	return false
 }
 
-func printDeploymentGroups(graph *dag.Graph) {
-	groups, err := graph.GetDeploymentGroups()
+// printWhy explains why one component is deployed after another by printing
+// the dependency paths between them
+func printWhy(graph *dag.Graph, spec string, maxPaths int) {
+	from, to, err := splitWhy(graph, spec)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error computing deployment groups: %v\n", err)
-		os.Exit(1)
+		fatalf("invalid --why: %v", err)
+	}
+	if from == to {
+		fmt.Fprintf(stdout, "%s and %s are the same component\n", from, to)
+		return
+	}
+
+	// Ask for one path more than the limit to know whether there are more
+	limit := maxPaths
+	if limit > 0 {
+		limit++
+	}
+	paths, err := graph.Paths(from, to, limit)
+	if err != nil {
+		failf("finding paths: %v", err)
+	}
+	if len(paths) == 0 {
+		if reverse, _ := graph.Paths(to, from, 1); len(reverse) > 0 {
+			fmt.Fprintf(stdout, "%s does not depend on %s; %s depends on %s, so %s comes first (see --why %s:%s)\n",
+				from, to, to, from, from, to, from)
+			return
+		}
+		fmt.Fprintf(stdout, "%s does not depend on %s; their relative order is incidental\n", from, to)
+		return
+	}
+
+	truncated := maxPaths > 0 && len(paths) > maxPaths
+	if truncated {
+		paths = paths[:maxPaths]
+	}
+	fmt.Fprintf(stdout, "%s comes after %s because of %d dependency %s:\n", from, to, len(paths), plural.Noun(len(paths), "path"))
+	for _, path := range paths {
+		fmt.Fprintf(stdout, "  %s\n", strings.Join(path, " -> "))
+	}
+	if truncated {
+		fmt.Fprintf(stdout, "(more paths exist; raise --max-paths to see them)\n")
 	}
+}
 
-	fmt.Println("=== Deployment Groups ===")
-	fmt.Println("Components in the same group can be deployed in parallel:")
-	fmt.Println()
+// neighbor is a node listed by --deps or --rdeps
+type neighbor struct {
+	Ref   string `json:"ref"`
+	Name  string `json:"name"`
+	Depth int    `json:"depth"` // Edges between the node and the queried one
+}
 
-	for i, group := range groups {
-		fmt.Printf("Group %d (can deploy in parallel):\n", i+1)
-		for _, component := range group {
-			fmt.Printf("  - %s\n", component)
+// printNeighborhood lists the nodes within depth edges of ref in direction
+// dir, closest first, as text or, with -o json, as a JSON array. A negative
+// depth follows the edges to the end.
+func printNeighborhood(graph *dag.Graph, ref string, depth int, dir dag.Direction, asJSON bool) {
+	option, title, one := "--deps", "Dependencies", "dependency"
+	if dir == dag.DirectionDependents {
+		option, title, one = "--rdeps", "Dependents", "dependent"
+	}
+	levels, err := graph.NeighborhoodLevels(ref, depth, dir)
+	if err != nil {
+		fatalf("invalid %s: %v", option, err)
+	}
+	neighbors := []neighbor{}
+	for d, level := range levels {
+		for _, node := range level {
+			neighbors = append(neighbors, neighbor{Ref: node.ID, Name: node.Name(), Depth: d})
 		}
-		if i < len(groups)-1 {
-			fmt.Println("    ↓")
+	}
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(neighbors); err != nil {
+			failf("writing %s: %v", strings.ToLower(title), err)
 		}
+		return
+	}
+
+	fmt.Fprintf(stdout, "=== %s of %s ===\n", title, ref)
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Depth\tComponent\tRef")
+	for _, n := range neighbors {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", n.Depth, n.Name, n.Ref)
+	}
+	tw.Flush()
+	fmt.Fprintln(stdout)
+	found, noun := len(neighbors)-1, strings.ToLower(title)
+	if found == 1 {
+		noun = one
+	}
+	if depth >= 0 {
+		fmt.Fprintf(stdout, "%d %s within %d %s\n", found, noun, depth, plural.Noun(depth, "hop"))
+	} else {
+		fmt.Fprintf(stdout, "%d %s\n", found, noun)
 	}
 }
 
-func printDotFormat(graph *dag.Graph) {
-	fmt.Println("digraph dependencies {")
-	fmt.Println("  rankdir=BT;")
-	fmt.Println("  node [shape=box];")
-	fmt.Println()
+// printRemovalImpact reports what removing ref would break, as text or, with
+// -o json, as a JSON object
+func printRemovalImpact(graph *dag.Graph, ref string, asJSON bool) {
+	impact, err := graph.SimulateRemoval(ref)
+	if err != nil {
+		fatalf("invalid --simulate-removal: %v", err)
+	}
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(impact); err != nil {
+			failf("writing removal impact: %v", err)
+		}
+		return
+	}
 
-	// Print all nodes
-	for id, node := range graph.Nodes {
-		var label string
-		if node.Component != nil {
-			label = fmt.Sprintf("%s\\n%s", node.Component.Name, node.Component.Version)
-		} else if node.Service != nil {
-			label = fmt.Sprintf("%s\\n%s", node.Service.Name, node.Service.Version)
-			if node.Service.Version == "" {
-				label = node.Service.Name
+	fmt.Fprintf(stdout, "=== Removing %s ===\n", ref)
+	fmt.Fprintln(stdout)
+	fmt.Fprintf(stdout, "Would lose a dependency: %d\n", len(impact.Dependents))
+	for _, dependent := range impact.Dependents {
+		fmt.Fprintf(stdout, "  %s\n", dependent)
+	}
+	fmt.Fprintf(stdout, "Would become unreachable: %d\n", len(impact.Unreachable))
+	for _, unreachable := range impact.Unreachable {
+		fmt.Fprintf(stdout, "  %s\n", unreachable)
+	}
+	fmt.Fprintf(stdout, "Dangling dependency entries: %d\n", len(impact.Dangling))
+	for _, edge := range impact.Dangling {
+		fmt.Fprintf(stdout, "  %s -> %s\n", edge.From, edge.To)
+	}
+}
+
+// printSearch lists the nodes matching pattern, as text or, with -o json, as
+// a JSON array, and reports whether anything matched
+func printSearch(graph *dag.Graph, pattern string, caseSensitive, asJSON bool) bool {
+	re, err := dag.NewSearchPattern(pattern, caseSensitive)
+	if err != nil {
+		fatalf("invalid --search: %v", err)
+	}
+	matches, err := graph.Search(re)
+	if err != nil {
+		fatalf("searching: %v", err)
+	}
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(matches); err != nil {
+			failf("writing search results: %v", err)
+		}
+	} else {
+		for _, match := range matches {
+			kind := match.Kind
+			if match.Type != "" {
+				kind = match.Type
+			}
+			version := ""
+			if match.Version != "" {
+				version = " " + match.Version
 			}
+			dependencies := "dependencies"
+			if match.Dependencies == 1 {
+				dependencies = "dependency"
+			}
+			fmt.Fprintf(stdout, "%s%s (ref: %s, %s): step %d, %d %s, %d %s\n", match.Name, version, match.Ref, kind, match.Step,
+				match.Dependencies, dependencies, match.Dependents, plural.Noun(match.Dependents, "dependent"))
+		}
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(stderr, "no matches for %q\n", pattern)
+		return false
+	}
+	return true
+}
+
+// splitWhy splits a --why value into two refs. Refs may contain colons
+// themselves (purls, URNs), so the value is split at the one colon that
+// leaves a known ref on both sides.
+func splitWhy(graph *dag.Graph, spec string) (string, string, error) {
+	var from, to string
+	matches := 0
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != ':' {
+			continue
+		}
+		_, fromOK := graph.Node(spec[:i])
+		_, toOK := graph.Node(spec[i+1:])
+		if fromOK && toOK {
+			from, to = spec[:i], spec[i+1:]
+			matches++
+		}
+	}
+	switch {
+	case matches == 1:
+		return from, to, nil
+	case matches > 1:
+		return "", "", fmt.Errorf("%q can be split into known refs in more than one way", spec)
+	case !strings.Contains(spec, ":"):
+		return "", "", fmt.Errorf("expected from-ref:to-ref, got %q", spec)
+	}
+	return "", "", fmt.Errorf("%q does not name two known refs separated by ':'", spec)
+}
+
+// runPlanCheck compares the computed plan with a previously exported one and
+// reports whether they match
+func runPlanCheck(graph *dag.Graph, planFile string) bool {
+	expected, err := plan.LoadFile(planFile)
+	if err != nil {
+		failf("loading plan: %v", err)
+	}
+
+	actual, err := plan.New(graph)
+	if err != nil {
+		failf("computing deployment plan: %v", err)
+	}
+
+	diff := plan.Compare(expected, actual)
+
+	fmt.Fprintln(stdout, "=== Plan Check ===")
+	fmt.Fprintf(stdout, "Comparing against %s:\n", planFile)
+	fmt.Fprintln(stdout)
+	diff.Write(stdout)
+
+	return diff.Empty()
+}
+
+// runGroupCheck compares the group membership of the plan of graph with the
+// plan in planFile, up to group through, and reports whether nothing moved
+func runGroupCheck(graph *dag.Graph, planFile string, through int) bool {
+	expected, err := plan.LoadFile(planFile)
+	if err != nil {
+		failf("loading plan: %v", err)
+	}
+	actual, err := plan.New(graph)
+	if err != nil {
+		failf("computing deployment plan: %v", err)
+	}
+	moves, ignored := plan.CompareGroups(expected, actual, through)
+
+	fmt.Fprintln(stdout, "=== Group Check ===")
+	if through > 0 {
+		fmt.Fprintf(stdout, "Comparing groups 1-%d against %s:\n", through, planFile)
+	} else {
+		fmt.Fprintf(stdout, "Comparing every group against %s:\n", planFile)
+	}
+	fmt.Fprintln(stdout)
+	if len(moves) == 0 {
+		fmt.Fprintln(stdout, "Groups match.")
+	}
+	for _, move := range moves {
+		fmt.Fprintln(stdout, "  "+move.String())
+	}
+	if len(moves) > 0 {
+		fmt.Fprintln(stdout)
+		fmt.Fprintf(stdout, "%d %s moved\n", len(moves), plural.Noun(len(moves), "component"))
+	}
+	if ignored > 0 {
+		fmt.Fprintf(stdout, "%d %s after group %d not checked\n", ignored, plural.Noun(ignored, "change"), through)
+	}
+	return len(moves) == 0
+}
+
+// printEdgeSuggestions prints the dependencies the heuristics of
+// dag.SuggestEdges guess are missing, then the same edges as an overlay
+// snippet. The snippet is only printed, never applied.
+func printEdgeSuggestions(graph *dag.Graph) {
+	suggestions := graph.SuggestEdges()
+
+	fmt.Fprintln(stdout, "=== Suggested Dependencies ===")
+	fmt.Fprintln(stdout, "These are guesses from endpoints, properties and groups; none of them is applied.")
+	fmt.Fprintln(stdout, "Review each one and copy those that hold into an --overlay file.")
+	fmt.Fprintln(stdout)
+	if len(suggestions) == 0 {
+		fmt.Fprintln(stdout, "No dependencies to suggest.")
+		return
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Confidence\tDependency\tReason")
+	var additions []sbom.Dependency
+	for _, suggestion := range suggestions {
+		fmt.Fprintf(tw, "  %s\t%s -> %s\t%s\n", suggestion.Confidence, suggestion.From, suggestion.To, suggestion.Reason)
+		if n := len(additions); n > 0 && additions[n-1].Ref == suggestion.From {
+			additions[n-1].DependsOn = append(additions[n-1].DependsOn, suggestion.To)
 		} else {
-			label = id
+			additions = append(additions, sbom.Dependency{Ref: suggestion.From, DependsOn: []string{suggestion.To}})
+		}
+	}
+	tw.Flush()
+	fmt.Fprintln(stdout)
+	fmt.Fprintf(stdout, "%d %s. Overlay snippet:\n", len(suggestions), plural.Noun(len(suggestions), "suggestion"))
+	snippet := &overlay.Overlay{AddDependencies: additions}
+	if err := snippet.Write(stdout); err != nil {
+		failf("writing overlay snippet: %v", err)
+	}
+}
+
+// runPolicyCheck evaluates the rules of policyFile against the graph and
+// reports whether none of them is violated
+func runPolicyCheck(graph *dag.Graph, rules *policy.Policy, policyFile string) bool {
+	findings, err := rules.Evaluate(graph)
+	if err != nil {
+		failf("checking policy: %v", err)
+	}
+
+	fmt.Fprintln(stdout, "=== Policy Check ===")
+	fmt.Fprintf(stdout, "Checking %d %s from %s:\n", len(rules.Rules), plural.Noun(len(rules.Rules), "rule"), policyFile)
+	fmt.Fprintln(stdout)
+	if len(findings) == 0 {
+		fmt.Fprintln(stdout, "No violations.")
+		return true
+	}
+	for _, finding := range findings {
+		fmt.Fprintln(stdout, "  "+finding.String())
+	}
+	fmt.Fprintln(stdout)
+	fmt.Fprintf(stdout, "%d %s\n", len(findings), plural.Noun(len(findings), "violation"))
+	return false
+}
+
+// newLock freezes the full plan of graph, ignoring --steps and --top, with
+// the fingerprint of the graph and the hash of options
+func newLock(graph *dag.Graph, inputs []plan.ProvenanceInput, env, timestamp string, options ...string) *plan.Lock {
+	p, err := plan.New(graph)
+	if err != nil {
+		failf("computing deployment plan: %v", err)
+	}
+	return plan.NewLock(withProvenance(p, inputs, env, timestamp), graph.Fingerprint(), plan.OptionsHash(options...))
+}
+
+// runVerifyFrozen compares current with the lock in lockFile and reports
+// whether the plan is still the one that was frozen
+func runVerifyFrozen(current *plan.Lock, lockFile string) bool {
+	frozen, err := plan.LoadLockFile(lockFile)
+	if err != nil {
+		failf("loading lock: %v", err)
+	}
+	drift := frozen.Drift(current)
+
+	fmt.Fprintln(stdout, "=== Frozen Plan Check ===")
+	if frozen.Provenance != nil {
+		fmt.Fprintf(stdout, "Verifying against %s (frozen %s):\n", lockFile, frozen.Provenance.GeneratedAt)
+	} else {
+		fmt.Fprintf(stdout, "Verifying against %s:\n", lockFile)
+	}
+	fmt.Fprintln(stdout)
+	if len(drift) == 0 {
+		fmt.Fprintln(stdout, "Plan matches the frozen plan.")
+		return true
+	}
+	for _, line := range drift {
+		fmt.Fprintln(stdout, "  "+line)
+	}
+	return false
+}
+
+// runEndpointProbe probes every service endpoint in deployment order and
+// reports whether all of them responded successfully
+func runEndpointProbe(ctx context.Context, graph *dag.Graph, opts probe.Options) bool {
+	p, err := plan.New(graph)
+	if err != nil {
+		failf("computing deployment plan: %v", err)
+	}
+
+	var targets []probe.Target
+	for _, step := range p.Steps {
+		for _, entry := range step.Components {
+			for _, endpoint := range entry.Endpoints {
+				targets = append(targets, probe.Target{Step: step.Step, BOMRef: entry.BOMRef, Service: entry.Name, Endpoint: endpoint})
+			}
 		}
-		fmt.Printf("  \"%s\" [label=\"%s\"];\n", id, label)
 	}
-	fmt.Println()
 
-	// Print all edges
-	for _, node := range graph.Nodes {
-		for _, dep := range node.Dependencies {
-			fmt.Printf("  \"%s\" -> \"%s\";\n", node.ID, dep.ID)
+	fmt.Fprintln(stdout, "=== Endpoint Probe ===")
+	if len(targets) == 0 {
+		fmt.Fprintln(stdout, "No service endpoints found.")
+		return true
+	}
+	fmt.Fprintln(stdout)
+
+	results := probe.Run(ctx, targets, opts)
+	probe.WriteReport(stdout, results)
+
+	failed := 0
+	for _, result := range results {
+		if !result.OK() {
+			failed++
 		}
 	}
+	fmt.Fprintln(stdout)
+	fmt.Fprintf(stdout, "%d of %d endpoints healthy\n", len(results)-failed, len(results))
+
+	return failed == 0
+}
 
-	fmt.Println("}")
+// redactGraph replaces the graph with a pseudonymized copy, optionally saving
+// the mapping so the output can be de-referenced internally
+func redactGraph(graph *dag.Graph, salt, mapFile string, interrupted *interrupt.Handler) *dag.Graph {
+	redacted, entries := dag.Redact(graph, salt)
+	if mapFile == "" {
+		return redacted
+	}
+
+	err := interrupted.Critical(func() error {
+		return output.WriteFileAtomic(mapFile, func(w io.Writer) error {
+			return dag.WriteRedactionMap(w, entries)
+		})
+	})
+	if err != nil {
+		failf("writing redaction map: %v", err)
+	}
+	return redacted
 }
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/ident"
+	"github.com/nprimmer/bom-dagger/internal/logging"
+	"github.com/nprimmer/bom-dagger/internal/output"
+	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/policy"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// options holds the command line of a run, one field per flag
+type options struct {
+	flags *flag.FlagSet // For the flags whose absence means something
+
+	inputFiles   fileList
+	namespaced   bool
+	recursive    bool
+	outputMode   string
+	showReverse  bool
+	showGroups   bool
+	showStats    bool
+	showHelp     bool
+	showVersion  bool
+	runtimeOnly  bool
+	checkPlan    string
+	baseline     string
+	compareFile  string
+	throughGroup int
+	policyFile   string
+	suggestEdges bool
+	freezeFile   string
+	verifyFile   string
+	inferNested  bool
+	inferFlag    string
+	overlayFile  string
+	treeFrom     string
+	treeDepth    int
+	depsOf       string
+	rdepsOf      string
+	runProbe     bool
+	failOnProbe  bool
+	timeout      time.Duration
+	runTimeout   time.Duration
+	probeLimit   int
+	serviceKind  string
+	stepsFlag    string
+	top          int
+	defaultDur   time.Duration
+	window       time.Duration
+	pinFlag      string
+	redact       bool
+	redactSalt   string
+	redactMap    string
+	requireDone  bool
+	formatFlag   string
+	labelFlag    string
+	quiet        bool
+	strict       bool
+	clusterFlag  string
+	verbose      bool
+	logFormat    string
+	colorFlag    string
+	noColor      bool
+	sortFlag     string
+	numbering    string
+	typeOrder    string
+	shuffleSeed  int64
+	synthesize   bool
+	runExec      bool
+	retries      int
+	retryDelay   time.Duration
+	healthWait   time.Duration
+	maxParallel  int
+	continueErr  bool
+	direction    string
+	pruneTypes   string
+	metaFile     string
+	bomDir       string
+	why          string
+	maxPaths     int
+	removal      string
+	search       string
+	caseSens     bool
+	failIfEmpty  bool
+	k8sAnnot     string
+	dedupeBy     string
+	dedupeLoose  bool
+	generate     string
+	inclExcl     bool
+	timestamp    string
+	strictParse  bool
+	failOnConfl  bool
+	ganttMax     int
+	describe     bool
+	describeMax  int
+	maxLabelLen  int
+	byEcosystem  bool
+	chokepoints  int
+	outputFile   string
+	blockers     bool
+	showTrust    bool
+	dotLegend    bool
+	validateSelf bool
+	reachable    bool
+	requireDeps  bool
+	ownerProp    string
+	linkType     string
+	owner        string
+	byOwner      bool
+	targetFlag   string
+	cacheDir     string
+	fromGraph    string
+	env          string
+	interactive  bool
+	assumeYes    bool
+	sessionLog   string
+	flat         bool
+	suppress     string
+	failOn       string
+}
+
+// parseOptions registers every flag and parses args into the options
+func parseOptions(args []string) *options {
+	o := &options{}
+	flags := flag.NewFlagSet("bom-dagger", flag.ContinueOnError)
+	flags.Var(&o.inputFiles, "input", "Path to CycloneDX SBOM file (JSON), directory of them, or - for standard input; repeat to merge several SBOMs")
+	flags.Var(&o.inputFiles, "i", "Path to CycloneDX SBOM file (JSON) (shorthand)")
+	flags.StringVar(&o.formatFlag, "format", "auto", "Input format: auto, cyclonedx-json, cyclonedx-xml, spdx-json, cyclonedx-protobuf")
+	flags.BoolVar(&o.quiet, "quiet", false, "Only log errors")
+	flags.BoolVar(&o.verbose, "verbose", false, "Also log progress of each phase")
+	flags.StringVar(&o.logFormat, "log-format", "text", "Format of log lines on stderr: text or json")
+	flags.StringVar(&o.colorFlag, "color", "auto", "Color the order, groups and warnings: auto (terminals unless NO_COLOR is set), always or never")
+	flags.BoolVar(&o.noColor, "no-color", false, "Never color output (same as --color=never)")
+	flags.BoolVar(&o.strict, "strict", false, "Treat SBOM parse warnings as errors")
+	flags.StringVar(&o.suppress, "suppress", "", "Comma-separated warning codes not to report, e.g. W001,W003 (see the README for the codes)")
+	flags.StringVar(&o.failOn, "fail-on", "", "Comma-separated warning codes that fail the run when reported, e.g. W004")
+	flags.BoolVar(&o.strictParse, "strict-parse", false, "Reject fields CycloneDX does not define and mistyped or missing dependency refs, dependsOn lists and names")
+	flags.StringVar(&o.outputMode, "output", "order", "Output mode: "+strings.Join(output.Names(), ", ")+", or list to describe them")
+	flags.StringVar(&o.outputMode, "o", "order", "Output mode (shorthand)")
+	flags.StringVar(&o.outputFile, "output-file", "", "Write the output to a file, replaced atomically; without -o the mode follows the extension (.dot, .json, .md, .mmd, .prom); - is stdout")
+	flags.StringVar(&o.outputFile, "f", "", "Write the output to a file (shorthand)")
+	flags.BoolVar(&o.showReverse, "reverse", false, "Show reverse order (teardown sequence)")
+	flags.BoolVar(&o.showReverse, "r", false, "Show reverse order (teardown sequence) (shorthand)")
+	flags.BoolVar(&o.showGroups, "groups", false, "Show deployment groups (components that can be deployed in parallel)")
+	flags.BoolVar(&o.showGroups, "g", false, "Show deployment groups (shorthand)")
+	flags.BoolVar(&o.showStats, "stats", false, "Show graph statistics")
+	flags.BoolVar(&o.showStats, "s", false, "Show graph statistics (shorthand)")
+	flags.BoolVar(&o.byEcosystem, "stats-by-ecosystem", false, "Break the per-step counts of --stats down by purl ecosystem (npm, maven, docker, ...)")
+	flags.IntVar(&o.chokepoints, "chokepoints", 0, "With --stats, rank the n components whose failure would block the most of the plan")
+	flags.BoolVar(&o.runtimeOnly, "runtime-only", false, "Drop edges to build-time dependencies (scope excluded or bom-dagger:dep-kind=build)")
+	flags.BoolVar(&o.inclExcl, "include-excluded", false, "Keep components with scope excluded (not shipped) instead of leaving them out of the plan")
+	flags.StringVar(&o.env, "env", "", "Plan for a deployment environment: leave out components whose bom-dagger:envs property does not list it")
+	flags.StringVar(&o.stepsFlag, "steps", "", "Only show the given range of steps, e.g. 2, 1-3 or 4-")
+	flags.IntVar(&o.top, "top", 0, "Only show the first N components of the order (0 = all)")
+	flags.StringVar(&o.sortFlag, "sort-within-group", "", "Order of components within a step: type, name or ref (default: priority, then name, then ref)")
+	flags.StringVar(&o.numbering, "numbering", "group", "Numbers shown by the order output: group (steps by deployment group) or sequence (components 1..N)")
+	flags.StringVar(&o.typeOrder, "type-order", strings.Join(plan.DefaultTypeOrder, ","), "Component type precedence for --sort-within-group type")
+	flags.Int64Var(&o.shuffleSeed, "shuffle-seed", 0, "Order components within a step randomly from this seed, e.g. to test that a deployment needs no more order than its dependencies")
+	flags.StringVar(&o.linkType, "link-type", dag.LinkTypeDocumentation, "External reference type linked next to each component in -o markdown and dot, e.g. website or vcs")
+	flags.StringVar(&o.ownerProp, "owner-property", dag.PropertyTeam, "Property naming the team that owns a component; the supplier or provider name is used without it")
+	flags.StringVar(&o.owner, "owner", "", "Only show the components owned by this team, keeping their step numbers")
+	flags.BoolVar(&o.byOwner, "by-owner", false, "Split each group of the groups output by owner")
+	flags.BoolVar(&o.blockers, "show-blockers", false, "List under each entry of the order output the direct dependencies it waits for, with their steps")
+	flags.BoolVar(&o.showTrust, "show-trust", false, "Mark services declaring x-trust-boundary in the order and groups output and fill them in red in dot")
+	flags.BoolVar(&o.describe, "describe", false, "Print component publishers and descriptions under each entry of the order and groups output")
+	flags.IntVar(&o.describeMax, "describe-max", output.DefaultDescribeMax, "Truncate descriptions longer than this many characters (0 = never)")
+	flags.IntVar(&o.maxLabelLen, "max-label-len", ident.DefaultMaxLabelLen, "Shorten names and refs longer than this many characters in output for people, keeping them unique with a hash (0 = never)")
+	flags.BoolVar(&o.validateSelf, "validate-self", false, "Check the JSON plan against the embedded schema before writing it (for development)")
+	flags.BoolVar(&o.dotLegend, "dot-legend", false, "Append a legend explaining the node and edge styles to dot output")
+	flags.StringVar(&o.clusterFlag, "cluster-by", "", "Cluster dot and mermaid output by CycloneDX group or deployment step: group or step")
+	flags.StringVar(&o.direction, "direction", "both", "Adjacency output: deps (edges), dependents (inverted map) or both")
+	flags.StringVar(&o.treeFrom, "from", "", "Root the tree output at the given bom-ref")
+	flags.IntVar(&o.treeDepth, "depth", 0, "Limit the depth of the tree output (0 = unlimited) and of --deps and --rdeps (unlimited unless given; 0 = the component alone)")
+	flags.StringVar(&o.pinFlag, "pin", "", "Comma-separated ref=early|normal|late pins placing components as early or late as the graph allows, e.g. monitor=late")
+	flags.StringVar(&o.labelFlag, "label-template", "", "Go template for node labels in dot, mermaid and tree output, e.g. '{{.Name}} ({{.Prop \"team\"}})'")
+	flags.DurationVar(&o.defaultDur, "default-duration", time.Minute, "Deploy duration for components without a bom-dagger:duration property")
+	flags.DurationVar(&o.window, "window", 0, "Split the order and json output into maintenance windows of this length, e.g. 2h, by bom-dagger:duration")
+	flags.IntVar(&o.ganttMax, "gantt-max-tasks", dag.DefaultGanttMaxTasks, "Maximum number of tasks in -o gantt output (0 = all)")
+	flags.StringVar(&o.k8sAnnot, "k8s-annotation", dag.DefaultK8sAnnotation, "Annotation set to the deployment wave by -o k8s-patch, e.g. argocd.argoproj.io/sync-wave")
+	flags.BoolVar(&o.flat, "flat", false, "List each image of -o images once, in the order it is first needed, instead of per group")
+	flags.StringVar(&o.serviceKind, "backstage-service-kind", "component", "Backstage entity kind for services: component or api")
+	flags.BoolVar(&o.runExec, "exec", false, "Run the bom-dagger:deploy-command of every component in plan order (teardown commands with --reverse)")
+	flags.IntVar(&o.maxParallel, "max-parallel", 0, "Maximum number of --exec commands running at once within a step (0 = all)")
+	flags.BoolVar(&o.continueErr, "continue-on-error", false, "Keep running --exec commands after one fails")
+	flags.BoolVar(&o.interactive, "interactive", false, "Show the order or groups one group at a time, waiting for Enter; with --exec, ask y/n/skip before each group")
+	flags.BoolVar(&o.assumeYes, "assume-yes", false, "Answer every --interactive prompt with yes, so it runs without a terminal")
+	flags.StringVar(&o.sessionLog, "session-log", "", "Append a timestamped line per --interactive answer to this file")
+	flags.IntVar(&o.retries, "retries", 0, "Further attempts of a failed --exec command without a bom-dagger:retries property")
+	flags.DurationVar(&o.retryDelay, "retry-delay", 5*time.Second, "Wait between attempts of a failed --exec command")
+	flags.DurationVar(&o.healthWait, "health-timeout", plan.DefaultHealthTimeout, "How long a bom-dagger:health-command may keep failing after --exec deployed a component")
+	flags.DurationVar(&o.runTimeout, "run-timeout", 0, "Give up when parsing, building and writing the plan take longer, naming the phase; exit status 124 (0 = no limit)")
+	flags.BoolVar(&o.runProbe, "probe", false, "HTTP GET every service endpoint and report status per service")
+	flags.BoolVar(&o.failOnProbe, "fail-on-probe", false, "Exit non-zero when any endpoint probe fails")
+	flags.DurationVar(&o.timeout, "timeout", 5*time.Second, "Timeout for each endpoint probe")
+	flags.IntVar(&o.probeLimit, "probe-concurrency", 8, "Maximum number of concurrent endpoint probes")
+	flags.BoolVar(&o.redact, "redact", false, "Replace names, versions, purls, descriptions and endpoints with stable pseudonyms")
+	flags.StringVar(&o.redactSalt, "redact-salt", "", "Salt used to derive --redact pseudonyms")
+	flags.StringVar(&o.redactMap, "redact-map", "", "Write the --redact pseudonym mapping to a file")
+	flags.BoolVar(&o.requireDone, "require-complete", false, "Fail when any ref named by a composition is not declared complete")
+	flags.BoolVar(&o.requireDeps, "require-dependencies", false, "Fail when the SBOM has several components but no dependency between them")
+	flags.BoolVar(&o.failOnConfl, "fail-on-conflict", false, "Fail when the same component appears at more than one version under different refs")
+	flags.StringVar(&o.overlayFile, "overlay", "", "Apply an overlay file of extra dependencies and exclusions")
+	flags.StringVar(&o.pruneTypes, "prune-leaves-of-type", "", "Repeatedly remove components of these comma-separated types that nothing depends on, e.g. library,file,operating-system")
+	flags.BoolVar(&o.reachable, "reachable-only", false, "Drop components not reachable through dependencies from the metadata component (or the --target refs)")
+	flags.StringVar(&o.targetFlag, "target", "", "Comma-separated bom-refs --reachable-only starts from instead of the metadata component")
+	flags.StringVar(&o.metaFile, "metadata", "", "Merge properties from a YAML or JSON file keyed by bom-ref, purl or name onto components")
+	flags.StringVar(&o.bomDir, "bom-dir", "", "Resolve BOM-Link (urn:cdx:) dependencies into the SBOMs of this directory, importing the linked components")
+	flags.StringVar(&o.dedupeBy, "dedupe-by", "", "Unify components that describe the same artifact under different refs: purl")
+	flags.BoolVar(&o.dedupeLoose, "dedupe-version-tolerant", false, "Keep the first version instead of failing when --dedupe-by finds conflicting versions")
+	flags.BoolVar(&o.recursive, "recursive", false, "Also read the SBOMs in subdirectories of an -i directory")
+	flags.BoolVar(&o.namespaced, "namespace-inputs", false, "Prefix the refs of every input with a namespace (its file name, or name from -i name=path) so inputs reusing refs stay separate")
+	flags.BoolVar(&o.synthesize, "synthesize-missing", false, "Add placeholder nodes for dependency targets missing from the SBOM instead of skipping them")
+	flags.StringVar(&o.inferFlag, "infer-from", "", "Infer dependencies from component nesting, composition assemblies or both: nesting, compositions or both")
+	flags.BoolVar(&o.inferNested, "infer-from-nesting", false, "Infer dependencies from component nesting and compositions (same as --infer-from both)")
+	flags.StringVar(&o.cacheDir, "cache-dir", "", "Keep built graphs in this directory and reuse them while the inputs and build options are unchanged")
+	flags.StringVar(&o.fromGraph, "from-graph", "", "Load a graph written by -o graph instead of parsing and building from SBOMs")
+	flags.StringVar(&o.generate, "generate", "", "Write a synthetic SBOM for benchmarks and exit, e.g. nodes=10000,density=3,cycles=0,services=0.1,depth=2,seed=1")
+	flags.StringVar(&o.timestamp, "timestamp", "", "Generation time recorded in the provenance of -o json and markdown and in -o spdx (RFC 3339; default SOURCE_DATE_EPOCH or now)")
+	flags.StringVar(&o.why, "why", "", "Explain why one component comes after another: print the dependency paths from-ref:to-ref")
+	flags.IntVar(&o.maxPaths, "max-paths", 10, "Maximum number of paths printed by --why (0 = all)")
+	flags.StringVar(&o.depsOf, "deps", "", "List what a component depends on, directly or transitively, with the number of hops to each (see --depth)")
+	flags.StringVar(&o.rdepsOf, "rdeps", "", "List what depends on a component, directly or transitively, i.e. what its failure affects, with the number of hops to each (see --depth)")
+	flags.StringVar(&o.removal, "simulate-removal", "", "Report what removing a component would break, without changing anything: dependents, unreachable components and dangling dependencies")
+	flags.StringVar(&o.search, "search", "", "List the components and services whose ref or name matches a glob, or a regular expression after re:, with their step and edge counts")
+	flags.BoolVar(&o.caseSens, "case-sensitive", false, "Match --search patterns case-sensitively")
+	flags.BoolVar(&o.failIfEmpty, "fail-if-empty", false, "Exit non-zero when --search matches nothing")
+	flags.StringVar(&o.checkPlan, "check-plan", "", "Compare the computed plan against a previously exported JSON plan")
+	flags.StringVar(&o.baseline, "baseline", "", "The SBOM of the last deployment, compared with the input by -o release-report and release-report-json")
+	flags.StringVar(&o.compareFile, "compare-groups", "", "Fail when a component moved to another group than in this JSON plan, ignoring order within groups")
+	flags.IntVar(&o.throughGroup, "through-group", 0, "Only check the groups up to this one with --compare-groups (0 = every group)")
+	flags.BoolVar(&o.suggestEdges, "suggest-edges", false, "Print dependencies the SBOM may be missing, guessed from endpoints, properties and groups, as an overlay snippet; nothing is applied")
+	flags.StringVar(&o.policyFile, "policy", "", "Check the graph and its deployment groups against the deny rules of a YAML or JSON policy file and fail on violations")
+	flags.StringVar(&o.freezeFile, "freeze", "", "Write the plan with the fingerprints of the graph and options to this lock file for approval")
+	flags.StringVar(&o.verifyFile, "verify-frozen", "", "Verify that the SBOM and options still give the plan frozen in this lock file")
+	flags.BoolVar(&o.showHelp, "help", false, "Show help message")
+	flags.BoolVar(&o.showHelp, "h", false, "Show help message (shorthand)")
+	flags.BoolVar(&o.showVersion, "version", false, "Show version information")
+	flags.BoolVar(&o.showVersion, "v", false, "Show version information (shorthand)")
+
+	parseFlags(flags, args)
+	o.flags = flags
+	return o
+}
+
+// settings are the options checked and parsed into what the run uses
+type settings struct {
+	writer      output.Writer
+	selection   planSelection
+	numbering   output.Numbering
+	direction   dag.AdjacencyDirection
+	clusterBy   dag.ClusterBy
+	label       *dag.LabelTemplate
+	format      parser.Format
+	inferFrom   dag.InferSource
+	warnPolicy  warning.Policy
+	rules       *policy.Policy
+	pruneLeaves []string
+	targets     []string
+}
+
+// validate sets up logging and checks the options, ending the run on the
+// first invalid one
+func (o *options) validate() *settings {
+	s := &settings{warnPolicy: o.setUpLogging()}
+	s.writer = o.outputWriter()
+	o.checkOutputOptions(s.writer.Name())
+	o.checkModes()
+	o.checkCounts()
+
+	var err error
+	s.selection = o.planSelection()
+	if s.numbering, err = output.ParseNumbering(o.numbering); err != nil {
+		fatalf("invalid --numbering: %v", err)
+	}
+	if o.policyFile != "" {
+		if s.rules, err = policy.LoadFile(o.policyFile); err != nil {
+			failf("loading policy: %v", err)
+		}
+	}
+	if o.pruneTypes != "" {
+		for _, t := range strings.Split(o.pruneTypes, ",") {
+			if t = strings.TrimSpace(t); t == "" {
+				fatalf("invalid --prune-leaves-of-type %q: empty type", o.pruneTypes)
+			}
+			s.pruneLeaves = append(s.pruneLeaves, t)
+		}
+	}
+	if o.targetFlag != "" {
+		if !o.reachable {
+			fatalf("--target requires --reachable-only")
+		}
+		for _, ref := range strings.Split(o.targetFlag, ",") {
+			if ref = strings.TrimSpace(ref); ref == "" {
+				fatalf("invalid --target %q: empty ref", o.targetFlag)
+			}
+			s.targets = append(s.targets, ref)
+		}
+	}
+	if o.dedupeBy != "" && o.dedupeBy != "purl" {
+		fatalf("invalid --dedupe-by %q (expected purl)", o.dedupeBy)
+	}
+	if s.direction, err = dag.ParseAdjacencyDirection(o.direction); err != nil {
+		fatalf("invalid --direction: %v", err)
+	}
+	if s.clusterBy, err = dag.ParseClusterBy(o.clusterFlag); err != nil {
+		fatalf("invalid --cluster-by: %v", err)
+	}
+	if o.labelFlag != "" {
+		if s.label, err = dag.ParseLabelTemplate(o.labelFlag); err != nil {
+			fatalf("%v", err)
+		}
+	}
+	if s.inferFrom, err = dag.ParseInferSource(o.inferFlag); err != nil {
+		fatalf("invalid --infer-from: %v", err)
+	}
+	if o.inferNested {
+		if s.inferFrom != dag.InferNone && s.inferFrom != dag.InferBoth {
+			fatalf("--infer-from-nesting is --infer-from both; it conflicts with --infer-from %s", s.inferFrom)
+		}
+		s.inferFrom = dag.InferBoth
+	}
+	if s.format, err = parser.ParseFormat(o.formatFlag); err != nil {
+		fatalf("%v", err)
+	}
+	return s
+}
+
+// setUpLogging points the logger and style at the run's streams and returns
+// the warning policy of --suppress and --fail-on
+func (o *options) setUpLogging() warning.Policy {
+	format, err := logging.ParseFormat(o.logFormat)
+	if err != nil {
+		fatalf("invalid --log-format: %v", err)
+	}
+	if o.quiet && o.verbose {
+		fatalf("--quiet and --verbose are mutually exclusive")
+	}
+	level := logging.LevelWarn
+	if o.quiet {
+		level = logging.LevelError
+	} else if o.verbose {
+		level = logging.LevelInfo
+	}
+	logger = logging.New(stderr, level, format)
+
+	var warnPolicy warning.Policy
+	if warnPolicy.Suppress, err = warning.ParseCodes(o.suppress); err != nil {
+		fatalf("invalid --suppress: %v", err)
+	}
+	if warnPolicy.FailOn, err = warning.ParseCodes(o.failOn); err != nil {
+		fatalf("invalid --fail-on: %v", err)
+	}
+	logger.SetPolicy(warnPolicy)
+
+	colorMode, err := output.ParseColorMode(o.colorFlag)
+	if err != nil {
+		fatalf("invalid --color: %v", err)
+	}
+	if o.noColor {
+		colorMode = output.ColorNever
+	}
+	logger.SetColor(colorMode.Enabled(stderr))
+	style.Color = colorMode.Enabled(stdout) && o.toStdout()
+	return warnPolicy
+}
+
+// toStdout reports whether the output goes to standard output
+func (o *options) toStdout() bool {
+	return o.outputFile == "" || o.outputFile == "-"
+}
+
+// outputWriter returns the writer of -o, -g or the --output-file extension
+func (o *options) outputWriter() output.Writer {
+	var err error
+	if o.showGroups {
+		o.outputMode = "groups"
+	} else if !o.toStdout() && !flagGiven(o.flags, "output", "o") {
+		if o.outputMode, err = output.ModeForFile(o.outputFile); err != nil {
+			fatalf("invalid --output-file: %v", err)
+		}
+	}
+	writer, err := output.Lookup(o.outputMode)
+	if err != nil {
+		fatalf("invalid --output: %v", err)
+	}
+	return writer
+}
+
+// checkOutputOptions rejects options that do not apply to the output mode
+func (o *options) checkOutputOptions(mode string) {
+	if o.byOwner && mode != "groups" {
+		fatalf("--by-owner only applies to the groups output (-g or -o groups)")
+	}
+	if o.dotLegend && mode != "dot" {
+		fatalf("--dot-legend only applies to dot output (-o dot)")
+	}
+	if o.flat && mode != "images" {
+		fatalf("--flat only applies to images output (-o images)")
+	}
+	if o.showReverse && !o.runExec && !output.SupportsTeardown(mode) {
+		fatalf("--reverse does not apply to %s output (use order, groups, json, markdown or shell)", mode)
+	}
+	if o.window < 0 {
+		fatalf("--window must not be negative")
+	}
+	if o.window > 0 && mode != "order" && mode != "json" {
+		fatalf("--window only applies to the order and json output")
+	}
+	if o.validateSelf && mode != "json" {
+		fatalf("--validate-self only applies to JSON output (-o json)")
+	}
+	releaseReport := mode == "release-report" || mode == "release-report-json"
+	if releaseReport && o.baseline == "" {
+		fatalf("-o %s needs the SBOM of the last deployment (--baseline)", mode)
+	} else if !releaseReport && o.baseline != "" {
+		fatalf("--baseline only applies to the release report (-o release-report or release-report-json)")
+	}
+	if o.interactive {
+		if !o.runExec && mode != "order" && mode != "groups" {
+			fatalf("--interactive only applies to the order and groups output and to --exec")
+		}
+		if !o.runExec && !o.toStdout() {
+			fatalf("--interactive writes to the terminal; it does not apply to --output-file")
+		}
+		if !o.assumeYes && !isTerminal(stdin) {
+			fatalf("--interactive reads answers from a terminal, but standard input is not one; use --assume-yes to run unattended")
+		}
+	} else if o.assumeYes || o.sessionLog != "" {
+		fatalf("--assume-yes and --session-log require --interactive")
+	}
+}
+
+// checkModes rejects options that conflict with each other
+func (o *options) checkModes() {
+	if o.freezeFile != "" && o.verifyFile != "" {
+		fatalf("--freeze and --verify-frozen are mutually exclusive")
+	}
+	if o.depsOf != "" && o.rdepsOf != "" {
+		fatalf("--deps and --rdeps are mutually exclusive")
+	}
+	if o.fromGraph != "" {
+		// The graph is already built; these shape the build
+		for _, option := range []struct {
+			name string
+			set  bool
+		}{{"--input", len(o.inputFiles.files) > 0}, {"--overlay", o.overlayFile != ""}, {"--metadata", o.metaFile != ""}, {"--bom-dir", o.bomDir != ""}, {"--namespace-inputs", o.namespaced}} {
+			if option.set {
+				fatalf("%s does not apply to --from-graph; use it when writing the graph", option.name)
+			}
+		}
+	}
+}
+
+// checkCounts rejects negative counts and limits
+func (o *options) checkCounts() {
+	for _, option := range []struct {
+		name  string
+		value int
+	}{
+		{"--top", o.top}, {"--through-group", o.throughGroup}, {"--chokepoints", o.chokepoints}, {"--max-paths", o.maxPaths},
+		{"--depth", o.treeDepth}, {"--gantt-max-tasks", o.ganttMax}, {"--describe-max", o.describeMax},
+	} {
+		if option.value < 0 {
+			fatalf("%s must not be negative", option.name)
+		}
+	}
+	if o.maxLabelLen < 0 || o.maxLabelLen > 0 && o.maxLabelLen < ident.MinMaxLabelLen {
+		fatalf("--max-label-len must be 0 (never shorten) or at least %d", ident.MinMaxLabelLen)
+	}
+	if o.maxParallel < 0 {
+		fatalf("--max-parallel must not be negative")
+	}
+	if o.runExec && o.retries < 0 {
+		fatalf("--retries must not be negative")
+	}
+}
+
+// planSelection parses the options that shape the printed plan
+func (o *options) planSelection() planSelection {
+	selection := planSelection{top: o.top, owner: o.owner}
+	var err error
+	if o.stepsFlag != "" {
+		if selection.steps, err = plan.ParseStepRange(o.stepsFlag); err != nil {
+			fatalf("%v", err)
+		}
+	}
+	if o.sortFlag != "" {
+		if selection.sortBy, err = plan.ParseSortBy(o.sortFlag); err != nil {
+			fatalf("invalid --sort-within-group: %v", err)
+		}
+	}
+	if selection.typeOrder, err = plan.ParseTypeOrder(o.typeOrder); err != nil {
+		fatalf("invalid --type-order: %v", err)
+	}
+	if flagGiven(o.flags, "shuffle-seed") {
+		if selection.sortBy != "" {
+			fatalf("--shuffle-seed and --sort-within-group %s are mutually exclusive", selection.sortBy)
+		}
+		selection.shuffle, selection.seed = true, o.shuffleSeed
+	}
+	return selection
+}
+
+// hops is the depth of --deps and --rdeps: unlike the tree, an explicit
+// --depth 0 means the component alone
+func (o *options) hops() int {
+	if flagGiven(o.flags, "depth") {
+		return o.treeDepth
+	}
+	return -1
+}
@@ -0,0 +1,151 @@
+// Package cache stores built graphs keyed by the inputs and graph options;
+// anything that does not decode cleanly is a miss
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/output"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// FormatVersion is bumped whenever Entry or dag.Snapshot change shape, so
+// old entries are ignored instead of being decoded wrongly
+const FormatVersion = 11
+
+// ErrMismatch reports an entry written for another key or format version
+var ErrMismatch = errors.New("cache entry does not match")
+
+// Entry is everything a run needs from parsing and building, so that a hit
+// can skip both
+type Entry struct {
+	// BOM is the merged SBOM without its components, services, dependencies
+	// and compositions, which the graph already holds
+	BOM *sbom.CycloneDX
+	// Inputs is the provenance of the input files
+	Inputs []plan.ProvenanceInput
+	// ParseWarnings and MetadataWarnings are replayed on a hit, so a cached
+	// run logs and fails (--strict) like a fresh one
+	ParseWarnings    []warning.Warning
+	MetadataWarnings []warning.Warning
+	Graph            *dag.Snapshot
+}
+
+// header precedes the Entry in a cache file
+type header struct {
+	Format int
+	Key    string
+}
+
+// NewEntry returns an Entry for a BOM and the graph built from it
+func NewEntry(bom *sbom.CycloneDX, g *dag.Graph) *Entry {
+	return &Entry{
+		BOM: &sbom.CycloneDX{
+			BOMFormat:    bom.BOMFormat,
+			SpecVersion:  bom.SpecVersion,
+			SerialNumber: bom.SerialNumber,
+			Version:      bom.Version,
+			Metadata:     bom.Metadata,
+		},
+		Graph: g.Snapshot(),
+	}
+}
+
+// Key hashes the contents and paths of files, which must all exist, together
+// with options, a canonical description of the settings the entry depends on.
+// Paths are part of the key because the provenance records them.
+func Key(files []string, options ...string) (string, error) {
+	h := sha256.New()
+	writeField(h, fmt.Sprint(FormatVersion))
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		info, err := f.Stat()
+		if err == nil {
+			writeField(h, path)
+			binary.Write(h, binary.BigEndian, info.Size())
+			_, err = io.Copy(h, f)
+		}
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	for _, option := range options {
+		writeField(h, option)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeField writes s length-prefixed, so that consecutive fields cannot run
+// into each other
+func writeField(h hash.Hash, s string) {
+	binary.Write(h, binary.BigEndian, int64(len(s)))
+	io.WriteString(h, s)
+}
+
+// Path returns the file of the entry for key in dir
+func Path(dir, key string) string {
+	return filepath.Join(dir, key+".gob")
+}
+
+// Load reads the entry for key from dir. A missing entry is an error
+// satisfying errors.Is(err, fs.ErrNotExist); any other error means the entry
+// is unusable and should be replaced.
+func Load(dir, key string) (*Entry, error) {
+	f, err := os.Open(Path(dir, key))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(f)
+	var h header
+	if err := dec.Decode(&h); err != nil {
+		return nil, fmt.Errorf("decoding cache header: %w", err)
+	}
+	if h.Format != FormatVersion || h.Key != key {
+		return nil, fmt.Errorf("%w: format %d, key %s", ErrMismatch, h.Format, h.Key)
+	}
+	var entry Entry
+	if err := dec.Decode(&entry); err != nil {
+		return nil, fmt.Errorf("decoding cache entry: %w", err)
+	}
+	if entry.BOM == nil {
+		return nil, fmt.Errorf("decoding cache entry: no BOM")
+	}
+	if entry.Graph == nil {
+		// gob leaves out the snapshot of an empty graph
+		entry.Graph = &dag.Snapshot{}
+	}
+	return &entry, nil
+}
+
+// Store writes the entry for key to dir, creating dir when needed. The file
+// is replaced atomically, so concurrent runs never read a partial entry.
+func Store(dir, key string, entry *Entry) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return output.WriteFileAtomic(Path(dir, key), func(w io.Writer) error {
+		enc := gob.NewEncoder(w)
+		if err := enc.Encode(header{Format: FormatVersion, Key: key}); err != nil {
+			return err
+		}
+		return enc.Encode(entry)
+	})
+}
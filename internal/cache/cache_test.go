@@ -0,0 +1,260 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/testgen"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+const fixture = "microservices-1.6.json"
+
+// build parses and builds path the way the CLI does on a cache miss
+func build(tb testing.TB, path string) *Entry {
+	tb.Helper()
+	p := parser.New()
+	bom, err := p.ParseFile(path)
+	if err != nil {
+		tb.Fatalf("ParseFile failed: %v", err)
+	}
+	g := dag.New()
+	if err := g.BuildFromSBOM(bom, p.GetComponentMap(bom)); err != nil {
+		tb.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	entry := NewEntry(bom, g)
+	entry.Inputs = []plan.ProvenanceInput{plan.NewProvenanceInput(path, bom)}
+	return entry
+}
+
+// copyFixture copies a fixture into a temporary directory, so tests can
+// change it
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", "sboms", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStoreAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	input := copyFixture(t, fixture)
+	key, err := Key([]string{input}, "runtime-only=false")
+	if err != nil {
+		t.Fatalf("Key failed: %v", err)
+	}
+
+	if _, err := Load(dir, key); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Expected a miss on an empty cache, got %v", err)
+	}
+
+	entry := build(t, input)
+	entry.ParseWarnings = []warning.Warning{warning.New(warning.MissingVersion, "component x has no version")}
+	if err := Store(dir, key, entry); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	loaded, err := Load(dir, key)
+	if err != nil {
+		t.Fatalf("Expected a hit, got %v", err)
+	}
+	if loaded.BOM.SerialNumber != entry.BOM.SerialNumber || loaded.BOM.Metadata.Timestamp != entry.BOM.Metadata.Timestamp {
+		t.Errorf("Loaded BOM differs:\n%+v\nwant\n%+v", loaded.BOM, entry.BOM)
+	}
+	if loaded.Inputs[0].Path != input || !reflect.DeepEqual(loaded.Inputs[0].Tools, entry.Inputs[0].Tools) {
+		t.Errorf("Loaded provenance differs:\n%+v\nwant\n%+v", loaded.Inputs, entry.Inputs)
+	}
+	if !reflect.DeepEqual(loaded.ParseWarnings, entry.ParseWarnings) {
+		t.Errorf("Expected parse warnings %v, got %v", entry.ParseWarnings, loaded.ParseWarnings)
+	}
+	if len(loaded.BOM.Components) != 0 {
+		t.Errorf("Expected the cached BOM to leave components to the graph, got %d", len(loaded.BOM.Components))
+	}
+
+	g, err := dag.FromSnapshot(loaded.Graph, dag.BuildOptions{})
+	if err != nil {
+		t.Fatalf("FromSnapshot failed: %v", err)
+	}
+	fresh, err := dag.FromSnapshot(entry.Graph, dag.BuildOptions{})
+	if err != nil {
+		t.Fatalf("FromSnapshot failed: %v", err)
+	}
+	got, _ := g.TopologicalSort()
+	want, _ := fresh.TopologicalSort()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Cached graph sorts differently:\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestKey(t *testing.T) {
+	input := copyFixture(t, fixture)
+	key := func(files []string, options ...string) string {
+		t.Helper()
+		k, err := Key(files, options...)
+		if err != nil {
+			t.Fatalf("Key failed: %v", err)
+		}
+		return k
+	}
+
+	base := key([]string{input}, "dev", "runtime-only=false")
+	if again := key([]string{input}, "dev", "runtime-only=false"); again != base {
+		t.Error("Expected the same key for the same inputs and options")
+	}
+	if key([]string{input}, "dev", "runtime-only=true") == base {
+		t.Error("Expected a changed option to change the key")
+	}
+	if key([]string{input}, "dev", "runtime-only=", "false") == base {
+		t.Error("Expected options to be kept apart")
+	}
+	if key([]string{input}, "v1.2.0", "runtime-only=false") == base {
+		t.Error("Expected another version to change the key")
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(input, append(data, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if key([]string{input}, "dev", "runtime-only=false") == base {
+		t.Error("Expected a changed input to change the key")
+	}
+
+	if _, err := Key([]string{filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Error("Expected an error for a missing input")
+	}
+}
+
+func TestLoadCorrupted(t *testing.T) {
+	input := copyFixture(t, fixture)
+	entry := build(t, input)
+
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, dir, key string)
+	}{
+		{"truncated", func(t *testing.T, dir, key string) {
+			data, err := os.ReadFile(Path(dir, key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(Path(dir, key), data[:len(data)/2], 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"garbage", func(t *testing.T, dir, key string) {
+			if err := os.WriteFile(Path(dir, key), []byte(`{"not": "gob"}`), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"empty", func(t *testing.T, dir, key string) {
+			if err := os.WriteFile(Path(dir, key), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"other key", func(t *testing.T, dir, key string) {
+			// An entry renamed to another key must not be trusted
+			if err := Store(dir, "other", entry); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Rename(Path(dir, "other"), Path(dir, key)); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			key, err := Key([]string{input})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Store(dir, key, entry); err != nil {
+				t.Fatal(err)
+			}
+			tt.corrupt(t, dir, key)
+
+			_, err = Load(dir, key)
+			if err == nil {
+				t.Fatal("Expected an error for a corrupted entry")
+			}
+			if errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Expected a corrupted entry to be told apart from a miss, got %v", err)
+			}
+
+			// Storing again replaces the bad entry
+			if err := Store(dir, key, entry); err != nil {
+				t.Fatalf("Store failed: %v", err)
+			}
+			if _, err := Load(dir, key); err != nil {
+				t.Errorf("Expected a hit after replacing the entry, got %v", err)
+			}
+		})
+	}
+}
+
+// benchmarkInput writes a large generated SBOM and returns its path
+func benchmarkInput(b *testing.B) string {
+	b.Helper()
+	data, err := json.Marshal(testgen.GenerateBOM(testgen.Options{Nodes: 20000, EdgeDensity: 3, Seed: 1}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	path := filepath.Join(b.TempDir(), "large.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// BenchmarkParseAndBuild is the cost of a miss; compare BenchmarkLoad, the
+// cost of a hit, on the same input
+func BenchmarkParseAndBuild(b *testing.B) {
+	input := benchmarkInput(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		build(b, input)
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	input := benchmarkInput(b)
+	dir := b.TempDir()
+	key, err := Key([]string{input})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := Store(dir, key, build(b, input)); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// A hit still hashes the input to find the entry
+		if _, err := Key([]string{input}); err != nil {
+			b.Fatal(err)
+		}
+		entry, err := Load(dir, key)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := dag.FromSnapshot(entry.Graph, dag.BuildOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
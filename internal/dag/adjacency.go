@@ -0,0 +1,113 @@
+package dag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// AdjacencyDirection selects which side of the adjacency WriteAdjacency emits
+type AdjacencyDirection string
+
+// Supported values for AdjacencyDirection and the --direction flag
+const (
+	AdjacencyDeps       AdjacencyDirection = "deps"       // edges list only
+	AdjacencyDependents AdjacencyDirection = "dependents" // dependents map only
+	AdjacencyBoth       AdjacencyDirection = "both"
+)
+
+// ParseAdjacencyDirection validates a --direction value
+func ParseAdjacencyDirection(s string) (AdjacencyDirection, error) {
+	switch AdjacencyDirection(s) {
+	case AdjacencyDeps, AdjacencyDependents, AdjacencyBoth:
+		return AdjacencyDirection(s), nil
+	}
+	return "", fmt.Errorf("unknown direction %q (expected deps, dependents or both)", s)
+}
+
+// AdjacencyOptions controls WriteAdjacency
+type AdjacencyOptions struct {
+	// Direction defaults to AdjacencyBoth
+	Direction AdjacencyDirection
+}
+
+// Adjacency is the JSON document written by WriteAdjacency
+type Adjacency struct {
+	Nodes []AdjacencyNode `json:"nodes"`
+	// Edges point from a node to a node it depends on
+	Edges []AdjacencyEdge `json:"edges,omitempty"`
+	// Dependents maps every ref to the refs that depend on it
+	Dependents map[string][]string `json:"dependents,omitempty"`
+}
+
+// AdjacencyNode describes one node of the graph
+type AdjacencyNode struct {
+	Ref       string   `json:"ref"`
+	Name      string   `json:"name"`
+	Version   string   `json:"version,omitempty"`
+	Kind      string   `json:"kind"`
+	Synthetic bool     `json:"synthetic,omitempty"`
+	Aliases   []string `json:"aliases,omitempty"` // Refs unified into this node by purl
+}
+
+// AdjacencyEdge is a dependency edge: From depends on To
+type AdjacencyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// WriteAdjacency writes the raw graph as JSON for external analysis. Nodes are
+// sorted by ref, edges by from and then to, and dependents lists by ref, so
+// the output is reproducible.
+func WriteAdjacency(w io.Writer, g *Graph, opts AdjacencyOptions) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	direction := opts.Direction
+	if direction == "" {
+		direction = AdjacencyBoth
+	}
+	if _, err := ParseAdjacencyDirection(string(direction)); err != nil {
+		return err
+	}
+
+	doc := Adjacency{Nodes: []AdjacencyNode{}}
+	withEdges := direction != AdjacencyDependents
+	withDependents := direction != AdjacencyDeps
+	if withEdges {
+		doc.Edges = []AdjacencyEdge{}
+	}
+	if withDependents {
+		doc.Dependents = make(map[string][]string, len(g.Nodes))
+	}
+
+	for _, node := range g.sortedNodes() {
+		doc.Nodes = append(doc.Nodes, AdjacencyNode{
+			Ref:       node.ID,
+			Name:      node.Name(),
+			Version:   node.Version(),
+			Kind:      node.Kind(),
+			Synthetic: node.Synthetic,
+			Aliases:   node.Aliases,
+		})
+		if withDependents {
+			dependents := make([]string, 0, len(node.Dependents))
+			for _, dependent := range node.Dependents {
+				dependents = append(dependents, dependent.ID)
+			}
+			sort.Strings(dependents)
+			doc.Dependents[node.ID] = dependents
+		}
+	}
+
+	if withEdges {
+		for _, edge := range g.edges() {
+			doc.Edges = append(doc.Edges, AdjacencyEdge(edge))
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
@@ -0,0 +1,150 @@
+package dag
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// graphFromAdjacency rebuilds a graph from WriteAdjacency output, using the
+// edges list when present and the dependents map otherwise
+func graphFromAdjacency(t *testing.T, data []byte) *Graph {
+	t.Helper()
+
+	var doc Adjacency
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Failed to decode adjacency JSON: %v", err)
+	}
+
+	g := New()
+	for _, node := range doc.Nodes {
+		var err error
+		if node.Kind == "service" {
+			_, err = g.AddService(&sbom.Service{BOMRef: node.Ref, Name: node.Name, Version: node.Version})
+		} else {
+			_, err = g.AddComponent(&sbom.Component{BOMRef: node.Ref, Name: node.Name, Version: node.Version})
+		}
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", node.Ref, err)
+		}
+	}
+
+	addEdge := func(from, to string) {
+		if err := g.AddDependency(from, to); err != nil {
+			t.Fatalf("AddDependency(%s, %s) failed: %v", from, to, err)
+		}
+	}
+	if doc.Edges != nil {
+		for _, edge := range doc.Edges {
+			addEdge(edge.From, edge.To)
+		}
+	} else {
+		for ref, dependents := range doc.Dependents {
+			for _, dependent := range dependents {
+				addEdge(dependent, ref)
+			}
+		}
+	}
+	return g
+}
+
+func TestWriteAdjacencyRoundTrip(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+
+	for _, direction := range []AdjacencyDirection{AdjacencyDeps, AdjacencyDependents, AdjacencyBoth} {
+		var buf bytes.Buffer
+		if err := WriteAdjacency(&buf, g, AdjacencyOptions{Direction: direction}); err != nil {
+			t.Fatalf("WriteAdjacency(%s) failed: %v", direction, err)
+		}
+
+		rebuilt := graphFromAdjacency(t, buf.Bytes())
+		if len(rebuilt.Nodes) != len(g.Nodes) {
+			t.Errorf("%s: expected %d nodes, got %d", direction, len(g.Nodes), len(rebuilt.Nodes))
+		}
+		if rebuilt.GetEdgeCount() != g.GetEdgeCount() {
+			t.Errorf("%s: expected %d edges, got %d", direction, g.GetEdgeCount(), rebuilt.GetEdgeCount())
+		}
+
+		// Writing the rebuilt graph must reproduce the document byte for byte
+		var again bytes.Buffer
+		if err := WriteAdjacency(&again, rebuilt, AdjacencyOptions{Direction: direction}); err != nil {
+			t.Fatalf("WriteAdjacency(%s) failed: %v", direction, err)
+		}
+		if again.String() != buf.String() {
+			t.Errorf("%s: output is not reproducible\nFirst:\n%s\nSecond:\n%s", direction, buf.String(), again.String())
+		}
+	}
+}
+
+func TestWriteAdjacency(t *testing.T) {
+	g := labelGraph(t)
+
+	var buf bytes.Buffer
+	if err := WriteAdjacency(&buf, g, AdjacencyOptions{}); err != nil {
+		t.Fatalf("WriteAdjacency failed: %v", err)
+	}
+	want := `{
+  "nodes": [
+    {
+      "ref": "lib",
+      "name": "say \"hi\" \u003cb\u003e\u0026\\ #1",
+      "version": "1.2.3",
+      "kind": "component"
+    },
+    {
+      "ref": "svc",
+      "name": "Service",
+      "version": "2.0",
+      "kind": "service"
+    }
+  ],
+  "edges": [
+    {
+      "from": "lib",
+      "to": "svc"
+    }
+  ],
+  "dependents": {
+    "lib": [],
+    "svc": [
+      "lib"
+    ]
+  }
+}
+`
+	if buf.String() != want {
+		t.Errorf("Unexpected adjacency\nGot:\n%s\nWant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteAdjacency(&buf, g, AdjacencyOptions{Direction: AdjacencyDeps}); err != nil {
+		t.Fatalf("WriteAdjacency failed: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte(`"dependents"`)) {
+		t.Errorf("Expected no dependents map for direction deps, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteAdjacency(&buf, g, AdjacencyOptions{Direction: AdjacencyDependents}); err != nil {
+		t.Fatalf("WriteAdjacency failed: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte(`"edges"`)) {
+		t.Errorf("Expected no edges for direction dependents, got:\n%s", buf.String())
+	}
+}
+
+func TestParseAdjacencyDirection(t *testing.T) {
+	for _, valid := range []string{"deps", "dependents", "both"} {
+		if _, err := ParseAdjacencyDirection(valid); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", valid, err)
+		}
+	}
+	if _, err := ParseAdjacencyDirection("up"); err == nil {
+		t.Error("Expected an error for an unknown direction")
+	}
+	if err := WriteAdjacency(&bytes.Buffer{}, New(), AdjacencyOptions{Direction: "up"}); err == nil {
+		t.Error("Expected WriteAdjacency to reject an unknown direction")
+	}
+}
@@ -0,0 +1,202 @@
+package dag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/ident"
+)
+
+// BackstageOptions controls WriteBackstage
+type BackstageOptions struct {
+	// ServiceKind selects the entity kind for CycloneDX services: "Component"
+	// (default) or "API". API entities cannot declare dependsOn in Backstage,
+	// so Components depending on them list them under consumesApis instead.
+	ServiceKind string
+	// Owner is written to spec.owner (default "unknown")
+	Owner string
+	// Lifecycle is written to spec.lifecycle (default "production")
+	Lifecycle string
+}
+
+// backstageTypes maps CycloneDX component types to Backstage kind and spec.type
+var backstageTypes = map[string][2]string{
+	"application":      {"Component", "service"},
+	"framework":        {"Component", "library"},
+	"library":          {"Component", "library"},
+	"file":             {"Component", "other"},
+	"container":        {"Resource", "container"},
+	"platform":         {"Resource", "platform"},
+	"operating-system": {"Resource", "operating-system"},
+	"device":           {"Resource", "device"},
+	"device-driver":    {"Resource", "device-driver"},
+	"firmware":         {"Resource", "firmware"},
+	"data":             {"Resource", "database"},
+}
+
+type backstageEntity struct {
+	node *Node
+	kind string
+	typ  string
+	name string
+}
+
+// ref returns the entity reference used in relations, e.g. "component:api"
+func (e *backstageEntity) ref() string {
+	return strings.ToLower(e.kind) + ":" + e.name
+}
+
+// WriteBackstage writes a multi-document catalog-info YAML stream with one
+// entity per node and dependsOn relations mirroring the graph
+func WriteBackstage(w io.Writer, g *Graph, opts BackstageOptions) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	serviceKind := "Component"
+	switch strings.ToLower(opts.ServiceKind) {
+	case "", "component":
+	case "api":
+		serviceKind = "API"
+	default:
+		return fmt.Errorf("unsupported Backstage service kind %q (expected component or api)", opts.ServiceKind)
+	}
+	owner := opts.Owner
+	if owner == "" {
+		owner = "unknown"
+	}
+	lifecycle := opts.Lifecycle
+	if lifecycle == "" {
+		lifecycle = "production"
+	}
+
+	// Assign entity names in a stable order, unique per kind
+	entities := make(map[string]*backstageEntity)
+	namers := make(map[string]*ident.Namer)
+	nodes := g.sortedNodes()
+	for _, node := range nodes {
+		entity := &backstageEntity{node: node, kind: "Component", typ: "other"}
+		if node.Service != nil {
+			entity.kind = serviceKind
+			entity.typ = "service"
+			if serviceKind == "API" {
+				entity.typ = "other"
+			}
+		} else if node.Component != nil {
+			if mapped, ok := backstageTypes[node.Component.Type]; ok {
+				entity.kind, entity.typ = mapped[0], mapped[1]
+			}
+		}
+
+		if namers[entity.kind] == nil {
+			namers[entity.kind] = ident.NewNamer(ident.DNS1123)
+		}
+		entity.name = namers[entity.kind].NameFor(node.ID, node.Name())
+		entities[node.ID] = entity
+	}
+
+	var b strings.Builder
+	for _, node := range nodes {
+		entity := entities[node.ID]
+
+		var dependsOn, consumesAPIs []string
+		for _, dep := range node.Dependencies {
+			target := entities[dep.ID]
+			if target.kind == "API" {
+				consumesAPIs = append(consumesAPIs, target.name)
+			} else {
+				dependsOn = append(dependsOn, target.ref())
+			}
+		}
+		sort.Strings(dependsOn)
+		sort.Strings(consumesAPIs)
+
+		b.WriteString("---\n")
+		b.WriteString("apiVersion: backstage.io/v1alpha1\n")
+		fmt.Fprintf(&b, "kind: %s\n", entity.kind)
+		b.WriteString("metadata:\n")
+		fmt.Fprintf(&b, "  name: %s\n", yamlScalar(entity.name))
+		fmt.Fprintf(&b, "  title: %s\n", yamlScalar(node.Name()))
+		if description := nodeDescription(node); description != "" {
+			fmt.Fprintf(&b, "  description: %s\n", yamlScalar(description))
+		}
+		b.WriteString("  annotations:\n")
+		fmt.Fprintf(&b, "    bom-dagger/bom-ref: %s\n", yamlScalar(node.ID))
+		if version := node.Version(); version != "" {
+			fmt.Fprintf(&b, "    bom-dagger/version: %s\n", yamlScalar(version))
+		}
+		b.WriteString("spec:\n")
+		fmt.Fprintf(&b, "  type: %s\n", yamlScalar(entity.typ))
+		if entity.kind != "Resource" {
+			fmt.Fprintf(&b, "  lifecycle: %s\n", yamlScalar(lifecycle))
+		}
+		fmt.Fprintf(&b, "  owner: %s\n", yamlScalar(owner))
+		if entity.kind == "API" {
+			fmt.Fprintf(&b, "  definition: %s\n", yamlScalar(apiDefinition(node)))
+		} else {
+			writeYAMLList(&b, "dependsOn", dependsOn)
+			if entity.kind == "Component" {
+				writeYAMLList(&b, "consumesApis", consumesAPIs)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeYAMLList(b *strings.Builder, key string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "  %s:\n", key)
+	for _, item := range items {
+		fmt.Fprintf(b, "    - %s\n", yamlScalar(item))
+	}
+}
+
+// apiDefinition describes a service for the required API definition field
+func apiDefinition(node *Node) string {
+	if len(node.Service.Endpoints) == 0 {
+		return node.Name()
+	}
+	return strings.Join(node.Service.Endpoints, "\n")
+}
+
+func nodeDescription(node *Node) string {
+	if node.Component != nil {
+		return node.Component.Description
+	}
+	if node.Service != nil {
+		return node.Service.Description
+	}
+	return ""
+}
+
+func nodeList(g *Graph) []*Node {
+	nodes := make([]*Node, 0, len(g.Nodes))
+	for _, node := range g.Nodes {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+var yamlPlain = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9 _./():-]*$`)
+
+// yamlScalar returns s as a YAML scalar, quoting it when a plain scalar
+// would be ambiguous or invalid
+func yamlScalar(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return fmt.Sprintf("%q", s)
+	}
+	if yamlPlain.MatchString(s) && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, ":") && !strings.Contains(s, ": ") {
+		return s
+	}
+	// A JSON string is a valid YAML double-quoted scalar
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
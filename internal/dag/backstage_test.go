@@ -0,0 +1,60 @@
+package dag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteBackstage(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+
+	tests := []struct {
+		golden string
+		opts   BackstageOptions
+	}{
+		{"backstage-microservices.yaml", BackstageOptions{}},
+		{"backstage-microservices-api.yaml", BackstageOptions{ServiceKind: "api"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteBackstage(&buf, g, tt.opts); err != nil {
+				t.Fatalf("WriteBackstage failed: %v", err)
+			}
+			assertGolden(t, tt.golden, buf.Bytes())
+		})
+	}
+}
+
+func TestWriteBackstageInvalidServiceKind(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+
+	var buf bytes.Buffer
+	err := WriteBackstage(&buf, g, BackstageOptions{ServiceKind: "system"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported Backstage service kind") {
+		t.Errorf("Expected unsupported service kind error, got %v", err)
+	}
+}
+
+func TestYAMLScalar(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"web-frontend", "web-frontend"},
+		{"Web Frontend", "Web Frontend"},
+		{"yes", `"yes"`},
+		{"15.2", `"15.2"`},
+		{"OAuth2/OpenID: service", `"OAuth2/OpenID: service"`},
+		{"component:web-frontend", "component:web-frontend"},
+		{"", `""`},
+	}
+
+	for _, tt := range tests {
+		if got := yamlScalar(tt.in); got != tt.want {
+			t.Errorf("yamlScalar(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
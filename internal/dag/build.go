@@ -0,0 +1,189 @@
+package dag
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/nprimmer/bom-dagger/internal/plural"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// parallelThreshold is the number of dependency entries below which the
+// edges are built sequentially; goroutine overhead dominates for small SBOMs
+const parallelThreshold = 1024
+
+// resolvedEdge is a dependency entry resolved to nodes and their owning shards
+type resolvedEdge struct {
+	from, to           *Node
+	fromShard, toShard int
+}
+
+// buildEdges resolves dependency entries to edges and links them; chunks are
+// resolved in parallel, then each worker links the edges of its own nodes
+func (g *Graph) buildEdges(dependencies []sbom.Dependency, opts BuildOptions) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers == 1 || len(dependencies) < parallelThreshold {
+		edges, warnings := g.resolveEdges(dependencies, opts, nil)
+		g.Warnings = append(g.Warnings, warnings...)
+		linkEdges(edges)
+		return
+	}
+
+	// Assign every node to a shard up front; lookups are read-only afterwards
+	shards := make(map[*Node]int, len(g.Nodes))
+	i := 0
+	for _, node := range g.Nodes {
+		shards[node] = i % workers
+		i++
+	}
+
+	// Phase 1: resolve chunks of dependency entries concurrently, bucketing
+	// each chunk's edges by the shard owning their source and their target
+	chunkSize := (len(dependencies) + workers - 1) / workers
+	outgoing := make([][][]resolvedEdge, workers) // [chunk][shard of from]
+	incoming := make([][][]resolvedEdge, workers) // [chunk][shard of to]
+	chunkWarnings := make([][]warning.Warning, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * chunkSize
+		if start >= len(dependencies) {
+			break
+		}
+		end := min(start+chunkSize, len(dependencies))
+		wg.Add(1)
+		go func(w int, chunk []sbom.Dependency) {
+			defer wg.Done()
+			var edges []resolvedEdge
+			edges, chunkWarnings[w] = g.resolveEdges(chunk, opts, shards)
+			outgoing[w], incoming[w] = make([][]resolvedEdge, workers), make([][]resolvedEdge, workers)
+			for _, edge := range edges {
+				outgoing[w][edge.fromShard] = append(outgoing[w][edge.fromShard], edge)
+				incoming[w][edge.toShard] = append(incoming[w][edge.toShard], edge)
+			}
+		}(w, dependencies[start:end])
+	}
+	wg.Wait()
+	for w := range chunkWarnings {
+		g.Warnings = append(g.Warnings, chunkWarnings[w]...)
+	}
+
+	// Phase 2: each worker links the edges touching the nodes it owns, in
+	// chunk order
+	for shard := 0; shard < workers; shard++ {
+		wg.Add(1)
+		go func(shard int) {
+			defer wg.Done()
+			for chunk := range outgoing {
+				if outgoing[chunk] == nil {
+					continue
+				}
+				for _, edge := range outgoing[chunk][shard] {
+					edge.from.Dependencies = append(edge.from.Dependencies, edge.to)
+				}
+				for _, edge := range incoming[chunk][shard] {
+					edge.to.Dependents = append(edge.to.Dependents, edge.from)
+				}
+			}
+		}(shard)
+	}
+	wg.Wait()
+}
+
+// normalizeDependencies merges dependency entries that share a ref into the
+// first of them and removes repeated targets, keeping the order in which refs
+// and targets first appear. A note is returned for every ref that changed.
+func normalizeDependencies(dependencies []sbom.Dependency) ([]sbom.Dependency, []warning.Warning) {
+	type merged struct {
+		index   int
+		entries int
+		targets int
+		seen    map[string]bool
+	}
+
+	var result []sbom.Dependency
+	var order []string
+	byRef := make(map[string]*merged)
+	for _, dep := range dependencies {
+		m, ok := byRef[dep.Ref]
+		if !ok {
+			m = &merged{index: len(result), seen: make(map[string]bool)}
+			byRef[dep.Ref] = m
+			order = append(order, dep.Ref)
+			result = append(result, sbom.Dependency{Ref: dep.Ref})
+		}
+		m.entries++
+
+		entry := &result[m.index]
+		for _, target := range dep.DependsOn {
+			m.targets++
+			if !m.seen[target] {
+				m.seen[target] = true
+				entry.DependsOn = append(entry.DependsOn, target)
+			}
+		}
+	}
+
+	var notes []warning.Warning
+	for _, ref := range order {
+		m := byRef[ref]
+		unique := len(result[m.index].DependsOn)
+		switch {
+		case m.entries > 1:
+			notes = append(notes, warning.New(warning.DuplicateDependency, "merged %d dependency entries for %s (%d unique of %d listed dependencies)", m.entries, ref, unique, m.targets))
+		case unique < m.targets:
+			removed := m.targets - unique
+			notes = append(notes, warning.New(warning.DuplicateDependency, "removed %d %s from the dependsOn list of %s", removed, plural.Noun(removed, "duplicate"), ref))
+		}
+	}
+
+	return result, notes
+}
+
+// resolveEdges looks up the nodes for each dependency entry, skipping
+// unknown refs and applying the edge filter
+func (g *Graph) resolveEdges(dependencies []sbom.Dependency, opts BuildOptions, shards map[*Node]int) ([]resolvedEdge, []warning.Warning) {
+	var edges []resolvedEdge
+	var warnings []warning.Warning
+
+	for _, dep := range dependencies {
+		node, exists := g.Nodes[dep.Ref]
+		if !exists {
+			// Skip dependencies for components not in our map
+			continue
+		}
+
+		for _, depRef := range dep.DependsOn {
+			depNode, exists := g.Nodes[depRef]
+			if !exists {
+				// Skip missing dependencies
+				continue
+			}
+
+			if !opts.keepEdge(node, depNode) {
+				warnings = append(warnings, warning.New(warning.FilteredEdge, "dropped edge %s -> %s (filtered)", node.ID, depNode.ID))
+				continue
+			}
+
+			edges = append(edges, resolvedEdge{
+				from:      node,
+				to:        depNode,
+				fromShard: shards[node],
+				toShard:   shards[depNode],
+			})
+		}
+	}
+
+	return edges, warnings
+}
+
+// linkEdges appends edges to the nodes they connect
+func linkEdges(edges []resolvedEdge) {
+	for _, edge := range edges {
+		edge.from.Dependencies = append(edge.from.Dependencies, edge.to)
+		edge.to.Dependents = append(edge.to.Dependents, edge.from)
+	}
+}
@@ -0,0 +1,175 @@
+package dag
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/testgen"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// generateBOM creates an acyclic SBOM where each node depends on up to
+// maxDeps earlier nodes, with occasional dangling refs and duplicate entries
+func generateBOM(r *rand.Rand, nodes, maxDeps int) (*sbom.CycloneDX, map[string]*sbom.Component) {
+	bom := &sbom.CycloneDX{BOMFormat: "CycloneDX", SpecVersion: "1.6"}
+	for i := 0; i < nodes; i++ {
+		ref := fmt.Sprintf("node-%d", i)
+		if i%10 == 9 {
+			bom.Services = append(bom.Services, sbom.Service{BOMRef: ref, Name: ref})
+		} else {
+			bom.Components = append(bom.Components, sbom.Component{BOMRef: ref, Name: ref, Version: "1.0"})
+		}
+	}
+
+	for i := 1; i < nodes; i++ {
+		dep := sbom.Dependency{Ref: fmt.Sprintf("node-%d", i)}
+		for d := r.Intn(maxDeps + 1); d > 0; d-- {
+			dep.DependsOn = append(dep.DependsOn, fmt.Sprintf("node-%d", r.Intn(i)))
+		}
+		if r.Intn(20) == 0 {
+			dep.DependsOn = append(dep.DependsOn, "missing-ref")
+		}
+		bom.Dependencies = append(bom.Dependencies, dep)
+		if r.Intn(50) == 0 {
+			bom.Dependencies = append(bom.Dependencies, dep)
+		}
+	}
+
+	componentMap := make(map[string]*sbom.Component)
+	for i := range bom.Components {
+		componentMap[bom.Components[i].BOMRef] = &bom.Components[i]
+	}
+	return bom, componentMap
+}
+
+func TestBuildEdgesParallelMatchesSequential(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	// Drops a deterministic subset of edges to exercise filter warnings
+	filter := func(from, to *Node) bool {
+		return (len(from.ID)+len(to.ID))%7 != 0
+	}
+
+	for iteration := 0; iteration < 25; iteration++ {
+		nodes := 1 + r.Intn(4000)
+		bom, componentMap := generateBOM(r, nodes, 1+r.Intn(8))
+		opts := BuildOptions{EdgeFilter: filter}
+
+		sequential := New()
+		opts.Workers = 1
+		if err := sequential.BuildFromSBOMWithOptions(bom, componentMap, opts); err != nil {
+			t.Fatalf("Sequential build failed: %v", err)
+		}
+
+		parallel := New()
+		opts.Workers = 2 + r.Intn(7)
+		if err := parallel.BuildFromSBOMWithOptions(bom, componentMap, opts); err != nil {
+			t.Fatalf("Parallel build failed: %v", err)
+		}
+
+		if len(sequential.Nodes) != len(parallel.Nodes) {
+			t.Fatalf("Node count mismatch: %d vs %d", len(sequential.Nodes), len(parallel.Nodes))
+		}
+		for id, node := range sequential.Nodes {
+			other := parallel.Nodes[id]
+			if !reflect.DeepEqual(nodeIDs(node.Dependencies), nodeIDs(other.Dependencies)) {
+				t.Fatalf("Dependencies of %s differ (nodes=%d workers=%d)", id, nodes, opts.Workers)
+			}
+			if !reflect.DeepEqual(nodeIDs(node.Dependents), nodeIDs(other.Dependents)) {
+				t.Fatalf("Dependents of %s differ (nodes=%d workers=%d)", id, nodes, opts.Workers)
+			}
+		}
+		if !reflect.DeepEqual(sequential.Warnings, parallel.Warnings) {
+			t.Fatalf("Warnings differ (nodes=%d workers=%d)", nodes, opts.Workers)
+		}
+
+		seqRoots, parRoots := nodeIDs(sequential.Roots), nodeIDs(parallel.Roots)
+		sort.Strings(seqRoots)
+		sort.Strings(parRoots)
+		if !reflect.DeepEqual(seqRoots, parRoots) {
+			t.Fatalf("Roots differ (nodes=%d workers=%d)", nodes, opts.Workers)
+		}
+	}
+}
+
+func TestBuildMergesDuplicateDependencyEntries(t *testing.T) {
+	g := loadFixture(t, "duplicate-deps-1.6.json")
+
+	if count := g.GetEdgeCount(); count != 5 {
+		t.Errorf("Expected 5 edges, got %d", count)
+	}
+	want := []string{"db", "cache", "queue", "auth"}
+	if got := nodeIDs(g.Nodes["app"].Dependencies); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected app dependencies %v in first-seen order, got %v", want, got)
+	}
+	if got := nodeIDs(g.Nodes["db"].Dependents); !reflect.DeepEqual(got, []string{"app", "queue"}) {
+		t.Errorf("Expected db dependents [app queue], got %v", got)
+	}
+
+	wantWarnings := []warning.Warning{{Code: warning.DuplicateDependency, Message: "merged 3 dependency entries for app (4 unique of 6 listed dependencies)"}}
+	if !reflect.DeepEqual(g.Warnings, wantWarnings) {
+		t.Errorf("Expected warnings %v, got %v", wantWarnings, g.Warnings)
+	}
+
+	order, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+	steps := make(map[string]int)
+	for _, entry := range order {
+		steps[entry.BOMRef] = entry.Step
+	}
+	wantSteps := map[string]int{"db": 1, "cache": 1, "auth": 1, "queue": 2, "app": 3}
+	if !reflect.DeepEqual(steps, wantSteps) {
+		t.Errorf("Expected steps %v, got %v", wantSteps, steps)
+	}
+}
+
+func TestNormalizeDependencies(t *testing.T) {
+	deps, notes := normalizeDependencies([]sbom.Dependency{
+		{Ref: "a", DependsOn: []string{"b", "b", "c"}},
+		{Ref: "b"},
+		{Ref: "c", DependsOn: []string{"d"}},
+	})
+
+	want := []sbom.Dependency{
+		{Ref: "a", DependsOn: []string{"b", "c"}},
+		{Ref: "b"},
+		{Ref: "c", DependsOn: []string{"d"}},
+	}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("Expected %v, got %v", want, deps)
+	}
+	if !reflect.DeepEqual(notes, []warning.Warning{warning.New(warning.DuplicateDependency, "removed 1 duplicate from the dependsOn list of a")}) {
+		t.Errorf("Unexpected notes: %v", notes)
+	}
+}
+
+func BenchmarkBuildFromSBOM(b *testing.B) {
+	for _, nodes := range []int{1000, 10000, 100000} {
+		// Roughly 10 dependencies per node
+		bom := testgen.GenerateBOM(testgen.Options{Nodes: nodes, EdgeDensity: 10, ServiceRatio: 0.1, Seed: 42})
+		componentMap := parser.New().GetComponentMap(bom)
+
+		for _, workers := range []int{1, 0} {
+			name := fmt.Sprintf("%dk/sequential", nodes/1000)
+			if workers == 0 {
+				name = fmt.Sprintf("%dk/parallel", nodes/1000)
+			}
+			b.Run(name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					g := New()
+					if err := g.BuildFromSBOMWithOptions(bom, componentMap, BuildOptions{Workers: workers}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package dag
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// ChokepointReport is a node whose failure during a deployment would block
+// the nodes depending on it, directly or transitively
+type ChokepointReport struct {
+	Ref     string `json:"ref"`
+	Name    string `json:"name"`
+	Step    int    `json:"step"`    // Deployment step (Kahn level)
+	Blocked int    `json:"blocked"` // Nodes depending on it, directly or transitively
+}
+
+// Chokepoints returns the topN nodes (all when topN <= 0) with the most
+// transitive dependents; ties go to the earlier step, then the lower ref
+func (g *Graph) Chokepoints(topN int) ([]ChokepointReport, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	steps, err := g.levels()
+	if err != nil {
+		return nil, err
+	}
+	// Dependents come before their dependencies in teardown order
+	teardown, err := g.teardownLevels()
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[*Node]int, len(g.Nodes))
+	step := make(map[*Node]int, len(g.Nodes))
+	for i, level := range steps {
+		for _, node := range level {
+			step[node] = i + 1
+			index[node] = len(index)
+		}
+	}
+
+	words := (len(index) + 63) / 64
+	sets := make(map[*Node][]uint64, len(g.Nodes))
+	unread := make(map[*Node]int, len(g.Nodes))
+	var reports []ChokepointReport
+	for _, level := range teardown {
+		for _, node := range level {
+			unread[node] = len(node.Dependencies)
+			if len(node.Dependents) == 0 {
+				continue
+			}
+			set := make([]uint64, words)
+			for _, dependent := range node.Dependents {
+				i := index[dependent]
+				set[i/64] |= 1 << (i % 64)
+				for w, word := range sets[dependent] {
+					set[w] |= word
+				}
+				if unread[dependent]--; unread[dependent] == 0 {
+					delete(sets, dependent)
+				}
+			}
+			blocked := 0
+			for _, word := range set {
+				blocked += bits.OnesCount64(word)
+			}
+			if unread[node] > 0 {
+				sets[node] = set
+			}
+			reports = append(reports, ChokepointReport{Ref: node.ID, Name: getNodeName(node), Step: step[node], Blocked: blocked})
+		}
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		a, b := reports[i], reports[j]
+		if a.Blocked != b.Blocked {
+			return a.Blocked > b.Blocked
+		}
+		if a.Step != b.Step {
+			return a.Step < b.Step
+		}
+		return a.Ref < b.Ref
+	})
+	if topN > 0 && len(reports) > topN {
+		reports = reports[:topN]
+	}
+	return reports, nil
+}
@@ -0,0 +1,76 @@
+package dag
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/testgen"
+)
+
+func TestChokepoints(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+	got, err := g.Chokepoints(5)
+	if err != nil {
+		t.Fatalf("Chokepoints failed: %v", err)
+	}
+	want := []ChokepointReport{
+		{Ref: "postgres-primary", Name: "PostgreSQL Primary", Step: 1, Blocked: 10},
+		{Ref: "redis-master", Name: "Redis Master", Step: 1, Blocked: 10},
+		{Ref: "zookeeper", Name: "Apache Zookeeper", Step: 1, Blocked: 10},
+		{Ref: "kafka", Name: "Apache Kafka", Step: 2, Blocked: 9},
+		{Ref: "elasticsearch", Name: "Elasticsearch", Step: 1, Blocked: 8},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	all, err := g.Chokepoints(0)
+	if err != nil {
+		t.Fatalf("Chokepoints failed: %v", err)
+	}
+	for _, report := range all {
+		if report.Blocked == 0 {
+			t.Errorf("Expected nodes blocking nothing to be left out, got %+v", report)
+		}
+	}
+	if last := all[len(all)-1]; last.Ref != "prometheus" || last.Blocked != 1 {
+		t.Errorf("Expected prometheus to block the least, got %+v", last)
+	}
+}
+
+// TestChokepointsMatchWalk checks the bitsets against a walk of the
+// dependents of every node of generated graphs
+func TestChokepointsMatchWalk(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for iteration := 0; iteration < 20; iteration++ {
+		opts := testgen.Options{Nodes: 1 + r.Intn(300), EdgeDensity: r.Float64() * 3, Seed: r.Int63()}
+		g, err := generatedGraph(t, opts)
+		if err != nil {
+			t.Fatalf("Build failed for %+v: %v", opts, err)
+		}
+		reports, err := g.Chokepoints(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]int, len(reports))
+		for _, report := range reports {
+			got[report.Ref] = report.Blocked
+		}
+		for ref, node := range g.Nodes {
+			seen := make(map[*Node]bool)
+			stack := append([]*Node(nil), node.Dependents...)
+			for len(stack) > 0 {
+				next := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if !seen[next] {
+					seen[next] = true
+					stack = append(stack, next.Dependents...)
+				}
+			}
+			if got[ref] != len(seen) {
+				t.Errorf("%s: expected %d blocked, got %d (%+v)", ref, len(seen), got[ref], opts)
+			}
+		}
+	}
+}
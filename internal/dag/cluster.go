@@ -0,0 +1,110 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ClusterBy selects how the DOT and Mermaid writers cluster nodes
+type ClusterBy string
+
+// Supported values for ClusterBy and the --cluster-by flag
+const (
+	ClusterNone  ClusterBy = ""
+	ClusterGroup ClusterBy = "group" // CycloneDX group (namespace) of each node
+	ClusterStep  ClusterBy = "step"  // Deployment step of each node
+)
+
+// UngroupedCluster labels the cluster of nodes without a group
+const UngroupedCluster = "ungrouped"
+
+// ParseClusterBy validates a --cluster-by value. Clustering by group and by
+// step are mutually exclusive because a node can only be in one cluster.
+func ParseClusterBy(s string) (ClusterBy, error) {
+	switch ClusterBy(s) {
+	case ClusterNone, ClusterGroup, ClusterStep:
+		return ClusterBy(s), nil
+	}
+	if strings.Contains(s, ",") {
+		return "", fmt.Errorf("cannot cluster by %q: group and step clustering are mutually exclusive", s)
+	}
+	return "", fmt.Errorf("unknown cluster mode %q (expected group or step)", s)
+}
+
+// cluster is a labelled set of nodes sorted by ID. key is the group name
+// for group clusters and empty otherwise.
+type cluster struct {
+	key   string
+	label string
+	nodes []*Node
+}
+
+// clusters partitions the graph. Group clusters are sorted by name with the
+// ungrouped cluster last; step clusters are in deployment order.
+func (g *Graph) clusters(by ClusterBy) ([]cluster, error) {
+	switch by {
+	case ClusterGroup:
+		byGroup := make(map[string][]*Node)
+		for _, node := range g.Nodes {
+			byGroup[node.Group()] = append(byGroup[node.Group()], node)
+		}
+		names := make([]string, 0, len(byGroup))
+		for name := range byGroup {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		var result []cluster
+		for _, name := range names {
+			result = append(result, cluster{key: name, label: name, nodes: sortedByID(byGroup[name])})
+		}
+		if ungrouped, ok := byGroup[""]; ok {
+			result = append(result, cluster{label: UngroupedCluster, nodes: sortedByID(ungrouped)})
+		}
+		return result, nil
+
+	case ClusterStep:
+		order, err := g.topologicalSort()
+		if err != nil {
+			return nil, err
+		}
+		var result []cluster
+		for _, entry := range order {
+			if len(result) < entry.Step {
+				result = append(result, cluster{label: fmt.Sprintf("Step %d", entry.Step)})
+			}
+			c := &result[entry.Step-1]
+			c.nodes = append(c.nodes, g.Nodes[entry.BOMRef])
+		}
+		for i := range result {
+			result[i].nodes = sortedByID(result[i].nodes)
+		}
+		return result, nil
+
+	default:
+		return []cluster{{nodes: g.sortedNodes()}}, nil
+	}
+}
+
+// GroupCount is the number of nodes in one CycloneDX group
+type GroupCount struct {
+	Group string // Empty for nodes without a group
+	Count int
+}
+
+// GroupCounts returns the number of nodes per group, sorted by group name
+// with ungrouped nodes last
+func (g *Graph) GroupCounts() []GroupCount {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	clusters, _ := g.clusters(ClusterGroup)
+	counts := make([]GroupCount, len(clusters))
+	for i, c := range clusters {
+		counts[i] = GroupCount{Group: c.key, Count: len(c.nodes)}
+	}
+	return counts
+}
@@ -0,0 +1,75 @@
+package dag
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseClusterBy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ClusterBy
+		wantErr string
+	}{
+		{value: "", want: ClusterNone},
+		{value: "group", want: ClusterGroup},
+		{value: "step", want: ClusterStep},
+		{value: "group,step", wantErr: "mutually exclusive"},
+		{value: "team", wantErr: "unknown cluster mode"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseClusterBy(tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseClusterBy(%q): expected error containing %q, got %v", tt.value, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseClusterBy(%q) = %q, %v; expected %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestGroupCounts(t *testing.T) {
+	g := loadFixture(t, "groups-1.6.json")
+
+	want := []GroupCount{
+		{Group: "com.example.payments", Count: 2},
+		{Group: "com.example.platform", Count: 2},
+		{Group: "org.acme", Count: 1},
+		{Group: "", Count: 2},
+	}
+	if got := g.GroupCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestWriteClustered(t *testing.T) {
+	g := loadFixture(t, "groups-1.6.json")
+
+	var buf bytes.Buffer
+	if err := WriteDOTWithOptions(&buf, g, DOTOptions{ClusterBy: ClusterGroup}); err != nil {
+		t.Fatalf("WriteDOTWithOptions failed: %v", err)
+	}
+	assertGolden(t, "dot-groups.dot", buf.Bytes())
+
+	buf.Reset()
+	if err := WriteMermaid(&buf, g, MermaidOptions{ClusterBy: ClusterGroup}); err != nil {
+		t.Fatalf("WriteMermaid failed: %v", err)
+	}
+	assertGolden(t, "mermaid-groups.mmd", buf.Bytes())
+
+	buf.Reset()
+	if err := WriteDOTWithOptions(&buf, g, DOTOptions{ClusterBy: ClusterStep}); err != nil {
+		t.Fatalf("WriteDOTWithOptions failed: %v", err)
+	}
+	for _, want := range []string{"label=\"Step 1\";\n    \"acme-sdk\"", "label=\"Step 5\";\n    \"web\""} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in step clusters, got:\n%s", want, buf.String())
+		}
+	}
+}
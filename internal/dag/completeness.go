@@ -0,0 +1,86 @@
+package dag
+
+import (
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// Composition aggregate values that describe how complete the declared
+// dependency information is. Any value starting with "incomplete" (such as
+// incomplete_first_party_only) counts as incomplete.
+const (
+	AggregateComplete     = "complete"
+	AggregateIncomplete   = "incomplete"
+	AggregateUnknown      = "unknown"
+	AggregateNotSpecified = "not_specified"
+)
+
+// Completeness groups the bom-refs named by compositions by their aggregate
+type Completeness struct {
+	Complete   []string
+	Incomplete []string
+	Unknown    []string // unknown, not_specified or unrecognized aggregates
+}
+
+// Referenced returns the number of nodes covered by a composition
+func (c Completeness) Referenced() int {
+	return len(c.Complete) + len(c.Incomplete) + len(c.Unknown)
+}
+
+// applyCompositions records each composition's aggregate on the nodes it
+// names; a non-complete aggregate wins, then the first in document order
+func (g *Graph) applyCompositions(compositions []sbom.Composition) {
+	for _, composition := range compositions {
+		aggregate := composition.Aggregate
+		if aggregate == "" {
+			aggregate = AggregateNotSpecified
+		}
+		refs := append(append([]string{}, composition.Dependencies...), composition.Assemblies...)
+		for _, ref := range refs {
+			node, exists := g.Nodes[ref]
+			if !exists {
+				continue
+			}
+			if node.Completeness == "" || node.Completeness == AggregateComplete {
+				node.Completeness = aggregate
+			}
+		}
+	}
+}
+
+// Completeness summarizes the declared completeness of every node named by a
+// composition. Refs are sorted within each group.
+func (g *Graph) Completeness() Completeness {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var c Completeness
+	for _, node := range g.sortedNodes() {
+		switch completenessClass(node.Completeness) {
+		case AggregateComplete:
+			c.Complete = append(c.Complete, node.ID)
+		case AggregateIncomplete:
+			c.Incomplete = append(c.Incomplete, node.ID)
+		case AggregateUnknown:
+			c.Unknown = append(c.Unknown, node.ID)
+		}
+	}
+	return c
+}
+
+// completenessClass reduces an aggregate to AggregateComplete,
+// AggregateIncomplete or AggregateUnknown, or "" when no composition names
+// the node
+func completenessClass(aggregate string) string {
+	switch {
+	case aggregate == "":
+		return ""
+	case aggregate == AggregateComplete:
+		return AggregateComplete
+	case strings.HasPrefix(aggregate, AggregateIncomplete):
+		return AggregateIncomplete
+	default:
+		return AggregateUnknown
+	}
+}
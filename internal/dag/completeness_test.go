@@ -0,0 +1,90 @@
+package dag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompleteness(t *testing.T) {
+	g := loadFixture(t, "compositions-1.6.json")
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"web", AggregateComplete},
+		{"db", AggregateComplete},
+		{"api", AggregateUnknown}, // complete, then unknown: non-complete wins
+		{"sdk", "incomplete_third_party_only"},
+		{"worker", AggregateIncomplete}, // named in assemblies
+		{"queue", ""},                   // not named by any composition
+	}
+	for _, tt := range tests {
+		if got := g.Nodes[tt.ref].Completeness; got != tt.want {
+			t.Errorf("Expected %s completeness %q, got %q", tt.ref, tt.want, got)
+		}
+	}
+
+	c := g.Completeness()
+	if strings.Join(c.Complete, ",") != "db,web" {
+		t.Errorf("Unexpected complete refs: %v", c.Complete)
+	}
+	if strings.Join(c.Incomplete, ",") != "sdk,worker" {
+		t.Errorf("Unexpected incomplete refs: %v", c.Incomplete)
+	}
+	if strings.Join(c.Unknown, ",") != "api" {
+		t.Errorf("Unexpected unknown refs: %v", c.Unknown)
+	}
+	if c.Referenced() != 5 {
+		t.Errorf("Expected 5 referenced nodes, got %d", c.Referenced())
+	}
+}
+
+func TestCompletenessWithoutCompositions(t *testing.T) {
+	g := loadFixture(t, "diamond-1.6.json")
+	if c := g.Completeness(); c.Referenced() != 0 {
+		t.Errorf("Expected no referenced nodes, got %+v", c)
+	}
+}
+
+func TestWriteDOTCompleteness(t *testing.T) {
+	g := loadFixture(t, "compositions-1.6.json")
+
+	var buf bytes.Buffer
+	if err := WriteDOTWithOptions(&buf, g, DOTOptions{Legend: true}); err != nil {
+		t.Fatalf("WriteDOTWithOptions failed: %v", err)
+	}
+	assertGolden(t, "dot-compositions.dot", buf.Bytes())
+
+	// api is complete and unknown, so unknown wins; sdk is a leaf
+	for _, want := range []string{
+		`"api" -> "db" [style=dotted];`,
+		`"web" -> "api";`,
+		`"worker" -> "queue" [style=dashed];`,
+		`subgraph "cluster_legend"`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %s in output, got:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "legend:trust") {
+		t.Error("Expected no trust boundary legend without ShowTrust")
+	}
+
+	buf.Reset()
+	if err := WriteDOTWithOptions(&buf, g, DOTOptions{Legend: true, ShowTrust: true}); err != nil {
+		t.Fatalf("WriteDOTWithOptions failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"legend:trust" [label="crosses a trust boundary"`+trustBoundaryStyle+"];") {
+		t.Errorf("Expected the trust boundary legend with ShowTrust, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteDOT(&buf, g); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "legend") {
+		t.Errorf("Expected no legend by default, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,112 @@
+package dag
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// Run with -race to check that readers and writers are synchronized
+func TestGraphConcurrentReads(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+
+	var want bytes.Buffer
+	if err := WriteDOT(&want, g); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3*8)
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			order, err := g.TopologicalSort()
+			if err == nil && len(order) != len(g.Nodes) {
+				err = fmt.Errorf("expected %d entries in the order, got %d", len(g.Nodes), len(order))
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := g.GetDeploymentGroups()
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			err := WriteDOT(&buf, g)
+			if err == nil && buf.String() != want.String() {
+				err = fmt.Errorf("concurrent WriteDOT produced different output")
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestGraphConcurrentMutation(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+	existing := sortedByID(nodeList(g))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// New nodes only depend on existing ones, so no cycle can form
+		for i := 0; i < 50; i++ {
+			ref := fmt.Sprintf("sidecar-%d", i)
+			if _, err := g.AddComponent(&sbom.Component{BOMRef: ref, Name: ref}); err != nil {
+				t.Errorf("AddComponent failed: %v", err)
+				return
+			}
+			if err := g.AddDependency(ref, existing[i%len(existing)].ID); err != nil {
+				t.Errorf("AddDependency failed: %v", err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := g.TopologicalSort(); err != nil {
+					t.Errorf("TopologicalSort failed: %v", err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := g.GetDeploymentGroups(); err != nil {
+					t.Errorf("GetDeploymentGroups failed: %v", err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := WriteDOT(&bytes.Buffer{}, g); err != nil {
+					t.Errorf("WriteDOT failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := g.GetNodeCount(), len(existing)+50; got != want {
+		t.Errorf("Expected %d nodes, got %d", want, got)
+	}
+}
@@ -0,0 +1,94 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Conflict is a set of nodes that describe the same logical component at
+// different versions under different bom-refs, which usually means a
+// packaging mistake in a merged SBOM
+type Conflict struct {
+	// Identity is the purl without version, qualifiers and subpath, or
+	// group/name for nodes without a purl
+	Identity string
+	Members  []ConflictMember // Sorted by version, then bom-ref
+}
+
+// ConflictMember is one node of a Conflict
+type ConflictMember struct {
+	BOMRef  string
+	Version string
+}
+
+func (c Conflict) String() string {
+	members := make([]string, len(c.Members))
+	for i, m := range c.Members {
+		members[i] = fmt.Sprintf("%s (ref %s)", m.Version, m.BOMRef)
+	}
+	return fmt.Sprintf("%s has %d versions: %s", c.Identity, c.versionCount(), strings.Join(members, ", "))
+}
+
+func (c Conflict) versionCount() int {
+	versions := make(map[string]bool)
+	for _, m := range c.Members {
+		versions[m.Version] = true
+	}
+	return len(versions)
+}
+
+// FindVersionConflicts groups the nodes of g by identity (see Conflict) and
+// returns the groups declaring more than one version, sorted by identity.
+// Nodes without a version are not compared.
+func FindVersionConflicts(g *Graph) []Conflict {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	byIdentity := make(map[string][]ConflictMember)
+	for _, node := range g.Nodes {
+		version := node.Version()
+		if version == "" || node.Synthetic {
+			continue
+		}
+		identity := versionlessIdentity(node)
+		byIdentity[identity] = append(byIdentity[identity], ConflictMember{BOMRef: node.ID, Version: version})
+	}
+
+	var conflicts []Conflict
+	for identity, members := range byIdentity {
+		conflict := Conflict{Identity: identity, Members: members}
+		if conflict.versionCount() < 2 {
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool {
+			if members[i].Version != members[j].Version {
+				return members[i].Version < members[j].Version
+			}
+			return members[i].BOMRef < members[j].BOMRef
+		})
+		conflicts = append(conflicts, conflict)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Identity < conflicts[j].Identity
+	})
+	return conflicts
+}
+
+// versionlessIdentity returns the node's purl without its version,
+// qualifiers and subpath, or group/name when it has no purl
+func versionlessIdentity(node *Node) string {
+	if node.Component != nil && node.Component.Purl != "" {
+		purl := node.Component.Purl
+		purl, _, _ = strings.Cut(purl, "#")
+		purl, _, _ = strings.Cut(purl, "?")
+		if at := strings.LastIndex(purl, "@"); at > strings.LastIndex(purl, "/") {
+			purl = purl[:at]
+		}
+		return purl
+	}
+	if group := node.Group(); group != "" {
+		return group + "/" + node.Name()
+	}
+	return node.Name()
+}
@@ -0,0 +1,70 @@
+package dag
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestFindVersionConflicts(t *testing.T) {
+	g := loadFixture(t, "version-conflict-1.6.json")
+
+	conflicts := FindVersionConflicts(g)
+	want := []Conflict{
+		{Identity: "com.example/billing-client", Members: []ConflictMember{{BOMRef: "client-api", Version: "1.2.0"}, {BOMRef: "client-worker", Version: "1.3.0"}}},
+		{Identity: "pkg:docker/library/postgres", Members: []ConflictMember{{BOMRef: "postgres-primary", Version: "15"}, {BOMRef: "postgres-reporting", Version: "16"}}},
+	}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Fatalf("Expected conflicts %+v, got %+v", want, conflicts)
+	}
+	if got := conflicts[1].String(); got != "pkg:docker/library/postgres has 2 versions: 15 (ref postgres-primary), 16 (ref postgres-reporting)" {
+		t.Errorf("Unexpected description: %s", got)
+	}
+}
+
+func TestFindVersionConflictsIdentity(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []sbom.Component
+		conflicts  int
+	}{
+		{"same version twice", []sbom.Component{
+			{BOMRef: "a", Name: "redis", Version: "7", Purl: "pkg:docker/redis@7"},
+			{BOMRef: "b", Name: "redis", Version: "7", Purl: "pkg:docker/redis@7"},
+		}, 0},
+		{"purl wins over name", []sbom.Component{
+			{BOMRef: "a", Name: "redis", Version: "7", Purl: "pkg:docker/redis@7"},
+			{BOMRef: "b", Name: "redis", Version: "6", Purl: "pkg:docker/valkey@6"},
+		}, 0},
+		{"different groups", []sbom.Component{
+			{BOMRef: "a", Group: "org.one", Name: "client", Version: "1"},
+			{BOMRef: "b", Group: "org.two", Name: "client", Version: "2"},
+		}, 0},
+		{"missing version", []sbom.Component{
+			{BOMRef: "a", Name: "client", Version: "1"},
+			{BOMRef: "b", Name: "client"},
+		}, 0},
+		{"scoped npm purl", []sbom.Component{
+			{BOMRef: "a", Name: "core", Version: "1.0.0", Purl: "pkg:npm/%40angular/core@1.0.0"},
+			{BOMRef: "b", Name: "core", Version: "2.0.0", Purl: "pkg:npm/%40angular/core@2.0.0#src"},
+		}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bom := &sbom.CycloneDX{BOMFormat: "CycloneDX", SpecVersion: "1.6", Components: tt.components}
+			componentMap := make(map[string]*sbom.Component)
+			for i := range bom.Components {
+				componentMap[bom.Components[i].BOMRef] = &bom.Components[i]
+			}
+			g := New()
+			if err := g.BuildFromSBOM(bom, componentMap); err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+			if got := FindVersionConflicts(g); len(got) != tt.conflicts {
+				t.Errorf("Expected %d conflicts, got %+v", tt.conflicts, got)
+			}
+		})
+	}
+}
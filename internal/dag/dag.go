@@ -2,8 +2,13 @@ package dag
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 
+	"github.com/nprimmer/bom-dagger/internal/logging"
+	"github.com/nprimmer/bom-dagger/internal/plural"
 	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
 )
 
 // Node represents a node in the DAG
@@ -13,24 +18,201 @@ type Node struct {
 	Service      *sbom.Service // For CycloneDX 1.6 services
 	Dependencies []*Node
 	Dependents   []*Node
+
+	// Completeness is the composition aggregate declared for this node
+	// ("complete", "incomplete", ...), or empty when no composition names it
+	Completeness string
+
+	// Synthetic marks a placeholder created for a dependency target that is
+	// not declared in the SBOM (see BuildOptions.SynthesizeMissing)
+	Synthetic bool
+
+	// Aliases are the refs of components unified into this node because they
+	// share its purl (see BuildOptions.DedupeByPurl)
+	Aliases []string
+
+	// Nested marks a component the SBOM declares inside another component
+	// rather than in its top-level components list or as the metadata
+	// component
+	Nested bool
 }
 
-// Graph represents the dependency DAG
+// Graph represents the dependency DAG. Its methods are safe for concurrent
+// use; Nodes and Roots are not guarded once exported.
 type Graph struct {
-	Nodes map[string]*Node
-	Roots []*Node // Components with no dependencies
+	// Nodes maps each ref to its node.
+	//
+	// Deprecated: use Node, NodesSorted and Edges.
+	Nodes    map[string]*Node
+	Roots    []*Node           // Components with no dependencies
+	Warnings []warning.Warning // Non-fatal issues encountered while building the graph
+	Excluded []string          // Refs of scope "excluded" components left out of the graph, sorted
+
+	// EnvExcluded are the refs of nodes left out because they are not
+	// deployed to BuildOptions.Env, sorted
+	EnvExcluded []string
+
+	// Unresolved are the dependsOn targets that name no component or service
+	// and were skipped, sorted. With SynthesizeMissing they become
+	// placeholder nodes instead.
+	Unresolved []string
+
+	// DuplicateServices are the refs of services declared more than once,
+	// or also declared by a component, sorted. Each ref is planned as a
+	// single node.
+	DuplicateServices []string
+
+	// DeferCycleChecks disables cycle detection in AddDependency so that many
+	// edges can be added cheaply; call CheckCycles once they are in place.
+	DeferCycleChecks bool
+
+	// LessWithinLevel breaks ties within each level of the plan and never moves
+	// a node to another level. Nil uses ByPriority.
+	LessWithinLevel func(a, b *Node) bool
+
+	// Pins override the bom-dagger:phase property of the nodes they name
+	// (see PropertyPhase); check them with CheckPins
+	Pins map[string]Phase
+
+	// OwnerProperty is the property plans read each node's owner from; see
+	// Node.Owner. Empty uses PropertyTeam.
+	OwnerProperty string
+
+	// LinkType is the external reference type plans and DOT graphs link each
+	// node to; see Node.Links. Empty uses LinkTypeDocumentation.
+	LinkType string
+
+	inferred map[Edge]bool // Edges derived from nesting/compositions rather than declared
+
+	// buildOpts and compositions are kept from the build for ApplyDelta, as
+	// are the unresolved dependsOn targets of each ref, in document order
+	buildOpts    BuildOptions
+	compositions []sbom.Composition
+	unresolvedBy map[string][]string
+
+	mu sync.RWMutex
+}
+
+// Edge is a dependency edge: From depends on To
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// InferSource is what BuildOptions.InferFrom derives edges from
+type InferSource string
+
+// Sources accepted by --infer-from
+const (
+	InferNone         InferSource = ""
+	InferNesting      InferSource = "nesting"      // Parents depend on their nested components
+	InferCompositions InferSource = "compositions" // Parents depend on the assemblies compositions list
+	InferBoth         InferSource = "both"
+)
+
+// ParseInferSource validates an --infer-from value
+func ParseInferSource(s string) (InferSource, error) {
+	switch InferSource(s) {
+	case InferNone, InferNesting, InferCompositions, InferBoth:
+		return InferSource(s), nil
+	}
+	return "", fmt.Errorf("unknown inference source %q (expected compositions, nesting or both)", s)
+}
+
+func (s InferSource) nesting() bool      { return s == InferNesting || s == InferBoth }
+func (s InferSource) compositions() bool { return s == InferCompositions || s == InferBoth }
+
+// BuildOptions configures how an SBOM is translated into a graph
+type BuildOptions struct {
+	// EdgeFilter is called for every resolved dependency edge (from depends on to).
+	// Returning false drops the edge; dropped edges are recorded in Warnings.
+	EdgeFilter func(from, to *Node) bool
+
+	// InferFrom derives parent -> child edges from component nesting,
+	// compositions or both; explicit edges take precedence
+	InferFrom InferSource
+
+	// Workers builds the edges of large SBOMs in parallel; zero uses GOMAXPROCS.
+	// EdgeFilter must then be safe for concurrent use.
+	Workers int
+
+	// SynthesizeMissing creates a placeholder node, named after its ref, for
+	// every dependsOn target that is not a component or service, instead of
+	// dropping the edges to it
+	SynthesizeMissing bool
+
+	// ReportedRefs are unknown dependsOn targets already reported elsewhere,
+	// such as BOM-Links --bom-dir could not resolve; they are handled like
+	// any unknown ref but without a dangling ref warning
+	ReportedRefs map[string]bool
+
+	// DedupeByPurl unifies components sharing a purl into the first ref, the
+	// others becoming Aliases; differing versions fail unless tolerant
+	DedupeByPurl          bool
+	DedupeVersionTolerant bool
+
+	// IncludeExcluded keeps components with scope "excluded" (not shipped)
+	// in the graph. By default they are dropped and every dependent is
+	// linked to their dependencies instead, so ordering through them holds.
+	IncludeExcluded bool
+
+	// Env leaves out nodes whose bom-dagger:envs property does not list it,
+	// like excluded components. Empty keeps every node.
+	Env string
+
+	// Logger receives progress messages (nil = discard)
+	Logger *logging.Logger
+}
+
+// Property names understood by bom-dagger
+const (
+	PropertyDepKind         = "bom-dagger:dep-kind"
+	PropertyDeployCommand   = "bom-dagger:deploy-command"
+	PropertyTeardownCommand = "bom-dagger:teardown-command"
+	PropertyHealthCommand   = "bom-dagger:health-command"
+	PropertyRetries         = "bom-dagger:retries"
+	PropertyEnvs            = "bom-dagger:envs"
+	PropertyNamespace       = "bom-dagger:namespace" // Set by parser.Namespace
+)
+
+// RuntimeOnly is an EdgeFilter that drops edges to build-time dependencies:
+// components with scope "excluded", which it only sees with IncludeExcluded,
+// or marked with bom-dagger:dep-kind=build
+func RuntimeOnly(from, to *Node) bool {
+	if to.Component != nil && to.Component.Scope == ScopeExcluded {
+		return false
+	}
+	if kind, ok := to.Property(PropertyDepKind); ok && kind == "build" {
+		return false
+	}
+	return true
 }
 
 // New creates a new Graph
 func New() *Graph {
 	return &Graph{
-		Nodes: make(map[string]*Node),
-		Roots: []*Node{},
+		Nodes:    make(map[string]*Node),
+		Roots:    []*Node{},
+		inferred: make(map[Edge]bool),
 	}
 }
 
 // BuildFromSBOM builds a DAG from a CycloneDX SBOM
 func (g *Graph) BuildFromSBOM(bom *sbom.CycloneDX, componentMap map[string]*sbom.Component) error {
+	return g.BuildFromSBOMWithOptions(bom, componentMap, BuildOptions{})
+}
+
+// BuildFromSBOMWithOptions builds a DAG from a CycloneDX SBOM using the given options
+func (g *Graph) BuildFromSBOMWithOptions(bom *sbom.CycloneDX, componentMap map[string]*sbom.Component, opts BuildOptions) error {
+	if bom == nil {
+		return fmt.Errorf("cannot build graph from a nil SBOM")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ensureNodes()
+	g.buildOpts = opts
+
 	// Create nodes for all components
 	for ref, component := range componentMap {
 		node := &Node{
@@ -41,6 +223,11 @@ func (g *Graph) BuildFromSBOM(bom *sbom.CycloneDX, componentMap map[string]*sbom
 		}
 		g.Nodes[ref] = node
 	}
+	for ref := range nestedRefs(bom) {
+		if node, ok := g.Nodes[ref]; ok {
+			node.Nested = true
+		}
+	}
 
 	// Create nodes for all services (CycloneDX 1.6)
 	for i := range bom.Services {
@@ -56,27 +243,41 @@ func (g *Graph) BuildFromSBOM(bom *sbom.CycloneDX, componentMap map[string]*sbom
 		}
 	}
 
-	// Build dependency relationships
-	for _, dep := range bom.Dependencies {
-		node, exists := g.Nodes[dep.Ref]
-		if !exists {
-			// Skip dependencies for components not in our map
-			continue
+	dependencies, compositions := bom.Dependencies, bom.Compositions
+	var aliases map[string]string
+	if opts.DedupeByPurl {
+		var err error
+		if aliases, err = g.dedupeByPurl(bom, opts); err != nil {
+			return err
 		}
+		dependencies = rewriteDependencies(dependencies, aliases)
+		compositions = rewriteCompositions(compositions, aliases)
+	}
 
-		for _, depRef := range dep.DependsOn {
-			depNode, exists := g.Nodes[depRef]
-			if !exists {
-				// Skip missing dependencies
-				continue
-			}
+	// Build dependency relationships
+	dependencies, notes := normalizeDependencies(dependencies)
+	g.Warnings = append(g.Warnings, notes...)
+	g.resolveMissing(dependencies, opts)
+	g.buildEdges(dependencies, opts)
+	g.applyCompositions(compositions)
+	g.compositions = compositions
+
+	if opts.InferFrom != InferNone {
+		g.inferEdges(bom, aliases, opts)
+	}
 
-			// Add edge from node to dependency
-			node.Dependencies = append(node.Dependencies, depNode)
-			// Add reverse edge for dependents
-			depNode.Dependents = append(depNode.Dependents, node)
+	if !opts.IncludeExcluded {
+		g.Excluded = g.contractExcluded(opts)
+		if len(g.Excluded) > 0 {
+			opts.Logger.Infof("left out %d components with scope excluded", len(g.Excluded))
 		}
 	}
+	if opts.Env != "" {
+		g.EnvExcluded = g.contractOtherEnvs(opts)
+		opts.Logger.Infof("left out %d components not deployed to %s", len(g.EnvExcluded), opts.Env)
+	}
+
+	g.checkPriorities()
 
 	// Identify root nodes (components with no dependencies)
 	for _, node := range g.Nodes {
@@ -85,12 +286,248 @@ func (g *Graph) BuildFromSBOM(bom *sbom.CycloneDX, componentMap map[string]*sbom
 		}
 	}
 
-	// Check for cycles
-	if g.hasCycle() {
-		return fmt.Errorf("dependency graph contains cycles")
+	if opts.Logger.Enabled(logging.LevelInfo) {
+		opts.Logger.Infof("built %d edges between %d nodes", g.edgeCount(), len(g.Nodes))
+		if inferred := len(g.inferred); inferred > 0 {
+			opts.Logger.Infof("inferred %d of those edges from nesting and compositions", inferred)
+		}
+		opts.Logger.Infof("detected %d roots", len(g.Roots))
 	}
 
-	return nil
+	return g.checkCycles()
+}
+
+// resolveMissing handles dependsOn targets of known nodes that are not in the
+// graph: each one gets a synthetic placeholder node with SynthesizeMissing and
+// is reported as skipped otherwise
+func (g *Graph) resolveMissing(dependencies []sbom.Dependency, opts BuildOptions) {
+	handled := make(map[string]bool)
+	for _, dep := range dependencies {
+		if _, exists := g.Nodes[dep.Ref]; !exists {
+			continue
+		}
+		for _, target := range dep.DependsOn {
+			if _, exists := g.Nodes[target]; exists {
+				continue
+			}
+			if !opts.SynthesizeMissing {
+				g.addUnresolved(dep.Ref, target)
+			}
+			if handled[target] {
+				continue
+			}
+			handled[target] = true
+			if opts.SynthesizeMissing {
+				g.Nodes[target] = &Node{ID: target, Dependencies: []*Node{}, Dependents: []*Node{}, Synthetic: true}
+				opts.Logger.Infof("synthesized placeholder for unknown ref %s (needed by %s)", target, dep.Ref)
+			} else {
+				if !opts.ReportedRefs[target] {
+					g.Warnings = append(g.Warnings, warning.New(warning.DanglingRef, "skipped unknown ref %s (needed by %s)", target, dep.Ref))
+				}
+				g.Unresolved = append(g.Unresolved, target)
+			}
+		}
+	}
+	sort.Strings(g.Unresolved)
+}
+
+// SyntheticCount returns the number of placeholder nodes in the graph
+func (g *Graph) SyntheticCount() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	count := 0
+	for _, node := range g.Nodes {
+		if node.Synthetic {
+			count++
+		}
+	}
+	return count
+}
+
+// NodeCounts breaks the nodes of a graph down by kind
+type NodeCounts struct {
+	Components int // Component nodes, external placeholders included
+	Services   int
+	Nested     int // Components declared inside another component
+
+	// DuplicateServices are services sharing a bom-ref with a component or
+	// another service; see Graph.DuplicateServices
+	DuplicateServices int
+}
+
+// TopLevel returns the number of components not nested in another one
+func (c NodeCounts) TopLevel() int {
+	return c.Components - c.Nested
+}
+
+// NodeCounts counts the components and services of the graph, and the
+// components declared nested
+func (g *Graph) NodeCounts() NodeCounts {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	counts := NodeCounts{DuplicateServices: len(g.DuplicateServices)}
+	for _, node := range g.Nodes {
+		if node.Service != nil {
+			counts.Services++
+			continue
+		}
+		counts.Components++
+		if node.Nested {
+			counts.Nested++
+		}
+	}
+	return counts
+}
+
+// ensureNodes initializes the node map of a zero-value Graph
+func (g *Graph) ensureNodes() {
+	if g.Nodes == nil {
+		g.Nodes = make(map[string]*Node)
+	}
+}
+
+// inferEdges adds the parent -> child edges of opts.InferFrom that are not
+// already declared, with a warning counting each source's edges
+func (g *Graph) inferEdges(bom *sbom.CycloneDX, aliases map[string]string, opts BuildOptions) {
+	if g.inferred == nil {
+		g.inferred = make(map[Edge]bool)
+	}
+
+	if opts.InferFrom.nesting() {
+		var edges []Edge
+		for i := range bom.Components {
+			edges = appendNestingEdges(edges, &bom.Components[i])
+		}
+		if bom.Metadata != nil && bom.Metadata.Component != nil {
+			edges = appendNestingEdges(edges, bom.Metadata.Component)
+		}
+		added := g.addInferredEdges(edges, aliases, opts)
+		g.Warnings = append(g.Warnings, warning.New(warning.InferredEdges, "inferred %d %s from component nesting", added, plural.Noun(added, "edge")))
+	}
+	if opts.InferFrom.compositions() {
+		added := g.addInferredEdges(assemblyEdges(bom), aliases, opts)
+		g.Warnings = append(g.Warnings, warning.New(warning.InferredEdges, "inferred %d %s from composition assemblies", added, plural.Noun(added, "edge")))
+	}
+}
+
+// assemblyEdges returns an edge from every ref listed in the assemblies of a
+// composition to its parent: the component it is nested in, or the metadata
+// component for top-level refs
+func assemblyEdges(bom *sbom.CycloneDX) []Edge {
+	parents := make(map[string]string)
+	var walk func(parent string, components []sbom.Component)
+	walk = func(parent string, components []sbom.Component) {
+		for i := range components {
+			child := &components[i]
+			if parent != "" && child.BOMRef != "" {
+				parents[child.BOMRef] = parent
+			}
+			walk(child.BOMRef, child.Components)
+		}
+	}
+
+	subject := ""
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		subject = bom.Metadata.Component.BOMRef
+		walk(subject, bom.Metadata.Component.Components)
+	}
+	walk("", bom.Components)
+
+	var edges []Edge
+	for _, composition := range bom.Compositions {
+		for _, ref := range composition.Assemblies {
+			parent, ok := parents[ref]
+			if !ok {
+				parent = subject
+			}
+			if parent != "" && parent != ref {
+				edges = append(edges, Edge{From: parent, To: ref})
+			}
+		}
+	}
+	return edges
+}
+
+// addInferredEdges adds the edges that are not in the graph yet and returns
+// how many it added
+func (g *Graph) addInferredEdges(edges []Edge, aliases map[string]string, opts BuildOptions) int {
+	added := 0
+	for _, edge := range edges {
+		edge = Edge{From: canonicalRef(aliases, edge.From), To: canonicalRef(aliases, edge.To)}
+		if edge.From == edge.To {
+			continue
+		}
+		node, fromExists := g.Nodes[edge.From]
+		depNode, toExists := g.Nodes[edge.To]
+		if !fromExists || !toExists || g.hasEdge(node, depNode) {
+			continue
+		}
+		if !opts.keepEdge(node, depNode) {
+			g.Warnings = append(g.Warnings, warning.New(warning.FilteredEdge, "dropped edge %s -> %s (filtered)", node.ID, depNode.ID))
+			continue
+		}
+
+		node.Dependencies = append(node.Dependencies, depNode)
+		depNode.Dependents = append(depNode.Dependents, node)
+		g.inferred[edge] = true
+		added++
+	}
+	return added
+}
+
+// nestedRefs returns the refs of the components declared inside another
+// component, the metadata component included
+func nestedRefs(bom *sbom.CycloneDX) map[string]bool {
+	nested := make(map[string]bool)
+	var stack []*sbom.Component
+	for i := range bom.Components {
+		stack = append(stack, &bom.Components[i])
+	}
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		stack = append(stack, bom.Metadata.Component)
+	}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for i := range current.Components {
+			if child := &current.Components[i]; child.BOMRef != "" {
+				nested[child.BOMRef] = true
+			}
+			stack = append(stack, &current.Components[i])
+		}
+	}
+	return nested
+}
+
+// appendNestingEdges recursively collects parent -> nested component edges
+func appendNestingEdges(edges []Edge, component *sbom.Component) []Edge {
+	for i := range component.Components {
+		child := &component.Components[i]
+		if component.BOMRef != "" && child.BOMRef != "" {
+			edges = append(edges, Edge{From: component.BOMRef, To: child.BOMRef})
+		}
+		edges = appendNestingEdges(edges, child)
+	}
+	return edges
+}
+
+// hasEdge reports whether node already depends on dep
+func (g *Graph) hasEdge(node, dep *Node) bool {
+	for _, existing := range node.Dependencies {
+		if existing == dep {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInferred reports whether the edge from -> to was inferred rather than declared
+func (g *Graph) IsInferred(from, to string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.inferred[Edge{From: from, To: to}]
 }
 
 // hasCycle detects if the graph has any cycles using DFS
@@ -127,16 +564,162 @@ func (g *Graph) hasCycleDFS(nodeID string, visited, recStack map[string]bool) bo
 	return false
 }
 
+// FindCycle returns the bom-refs along one dependency cycle, starting and
+// ending with the same ref, or nil if the graph is acyclic. Nodes are visited
+// in bom-ref order so the reported cycle is deterministic.
+func (g *Graph) FindCycle() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[*Node]int, len(g.Nodes))
+	var path []*Node
+
+	var visit func(node *Node) []string
+	visit = func(node *Node) []string {
+		state[node] = inProgress
+		path = append(path, node)
+		for _, dep := range sortedByID(node.Dependencies) {
+			switch state[dep] {
+			case inProgress:
+				// dep is on the current path; the cycle runs from it back to itself
+				var cycle []string
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == dep {
+						for _, n := range path[i:] {
+							cycle = append(cycle, n.ID)
+						}
+						break
+					}
+				}
+				return append(cycle, dep.ID)
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = done
+		return nil
+	}
+
+	for _, node := range g.sortedNodes() {
+		if state[node] == unvisited {
+			if cycle := visit(node); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// UnresolvedRefs returns the sorted, de-duplicated refs used in the SBOM's
+// dependencies that do not match any node in the graph. Such entries are
+// skipped when the graph is built.
+func (g *Graph) UnresolvedRefs(bom *sbom.CycloneDX) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var refs []string
+	add := func(ref string) {
+		if _, exists := g.Nodes[ref]; !exists && !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	for _, dep := range bom.Dependencies {
+		add(dep.Ref)
+		for _, ref := range dep.DependsOn {
+			add(ref)
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// Property returns the value of the named property on the node's component or service
+func (n *Node) Property(name string) (string, bool) {
+	var props []sbom.Property
+	if n.Component != nil {
+		props = n.Component.Properties
+	} else if n.Service != nil {
+		props = n.Service.Properties
+	}
+	for _, prop := range props {
+		if prop.Name == name {
+			return prop.Value, true
+		}
+	}
+	return "", false
+}
+
 // GetNodeCount returns the number of nodes in the graph
 func (g *Graph) GetNodeCount() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 	return len(g.Nodes)
 }
 
 // GetEdgeCount returns the number of edges in the graph
 func (g *Graph) GetEdgeCount() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.edgeCount()
+}
+
+func (g *Graph) edgeCount() int {
 	count := 0
 	for _, node := range g.Nodes {
 		count += len(node.Dependencies)
 	}
 	return count
 }
+
+// Node returns the node with the given ref
+func (g *Graph) Node(ref string) (*Node, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	node, ok := g.Nodes[ref]
+	return node, ok
+}
+
+// NodesSorted returns the nodes sorted by ID
+func (g *Graph) NodesSorted() []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.sortedNodes()
+}
+
+func (g *Graph) sortedNodes() []*Node {
+	return sortedByID(nodeList(g))
+}
+
+// Edges returns every edge sorted by From, then To
+func (g *Graph) Edges() []Edge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.edges()
+}
+
+func (g *Graph) edges() []Edge {
+	edges := make([]Edge, 0, g.edgeCount())
+	for _, node := range g.sortedNodes() {
+		for _, dep := range sortedByID(node.Dependencies) {
+			edges = append(edges, Edge{From: node.ID, To: dep.ID})
+		}
+	}
+	return edges
+}
+
+// GetInferredEdgeCount returns the number of edges that were inferred
+func (g *Graph) GetInferredEdgeCount() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.inferred)
+}
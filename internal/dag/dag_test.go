@@ -1,9 +1,17 @@
 package dag
 
 import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
+	"github.com/nprimmer/bom-dagger/internal/parser"
 	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
 )
 
 func TestNewGraph(t *testing.T) {
@@ -74,6 +82,26 @@ func TestBuildFromSBOM(t *testing.T) {
 	}
 }
 
+func TestBuildFromSBOMZeroValueGraph(t *testing.T) {
+	bom := &sbom.CycloneDX{
+		Components:   []sbom.Component{{BOMRef: "a", Name: "A"}, {BOMRef: "b", Name: "B"}},
+		Dependencies: []sbom.Dependency{{Ref: "a", DependsOn: []string{"b"}}},
+	}
+	componentMap := map[string]*sbom.Component{"a": &bom.Components[0], "b": &bom.Components[1]}
+
+	var g Graph
+	if err := g.BuildFromSBOMWithOptions(bom, componentMap, BuildOptions{InferFrom: InferBoth}); err != nil {
+		t.Fatalf("BuildFromSBOM on a zero-value Graph failed: %v", err)
+	}
+	if g.GetEdgeCount() != 1 {
+		t.Errorf("Expected 1 edge, got %d", g.GetEdgeCount())
+	}
+
+	if err := New().BuildFromSBOM(nil, nil); err == nil {
+		t.Error("Expected error for nil SBOM")
+	}
+}
+
 func TestCycleDetection(t *testing.T) {
 	// Create a SBOM with a cycle
 	bom := &sbom.CycloneDX{
@@ -106,6 +134,95 @@ func TestCycleDetection(t *testing.T) {
 	if err.Error() != "dependency graph contains cycles" {
 		t.Errorf("Expected cycle error message, got: %v", err)
 	}
+
+	if cycle := strings.Join(g.FindCycle(), " -> "); cycle != "comp-a -> comp-b -> comp-c -> comp-a" {
+		t.Errorf("Unexpected cycle path: %s", cycle)
+	}
+}
+
+func TestFindCycleAcyclic(t *testing.T) {
+	g := loadFixture(t, "diamond-1.6.json")
+	if cycle := g.FindCycle(); cycle != nil {
+		t.Errorf("Expected no cycle, got %v", cycle)
+	}
+}
+
+func TestUnresolvedRefs(t *testing.T) {
+	bom := &sbom.CycloneDX{
+		Components: []sbom.Component{{BOMRef: "a", Name: "A"}},
+		Dependencies: []sbom.Dependency{
+			{Ref: "a", DependsOn: []string{"missing-b", "missing-a"}},
+			{Ref: "ghost", DependsOn: []string{"a", "missing-a"}},
+		},
+	}
+	g := New()
+	if err := g.BuildFromSBOM(bom, map[string]*sbom.Component{"a": &bom.Components[0]}); err != nil {
+		t.Fatal(err)
+	}
+
+	if refs := strings.Join(g.UnresolvedRefs(bom), ","); refs != "ghost,missing-a,missing-b" {
+		t.Errorf("Unexpected unresolved refs: %s", refs)
+	}
+}
+
+func TestSynthesizeMissing(t *testing.T) {
+	p := parser.New()
+	bom, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", "missing-ref-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	skipped := New()
+	if err := skipped.BuildFromSBOM(bom, p.GetComponentMap(bom)); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	if _, exists := skipped.Nodes["managed-postgres"]; exists {
+		t.Error("Expected the unknown ref to be skipped by default")
+	}
+	want := []warning.Warning{{Code: warning.DanglingRef, Message: "skipped unknown ref managed-postgres (needed by app)"}}
+	if !reflect.DeepEqual(skipped.Warnings, want) {
+		t.Errorf("Expected warnings %v, got %v", want, skipped.Warnings)
+	}
+	if strings.Join(skipped.Unresolved, ",") != "managed-postgres" {
+		t.Errorf("Expected managed-postgres to be unresolved, got %v", skipped.Unresolved)
+	}
+
+	// A ref reported elsewhere is skipped without a second warning
+	reported := New()
+	if err := reported.BuildFromSBOMWithOptions(bom, p.GetComponentMap(bom), BuildOptions{ReportedRefs: map[string]bool{"managed-postgres": true}}); err != nil {
+		t.Fatalf("BuildFromSBOMWithOptions failed: %v", err)
+	}
+	if len(reported.Warnings) != 0 || strings.Join(reported.Unresolved, ",") != "managed-postgres" {
+		t.Errorf("Expected managed-postgres unresolved without a warning, got %v (unresolved %v)", reported.Warnings, reported.Unresolved)
+	}
+
+	g := New()
+	if err := g.BuildFromSBOMWithOptions(bom, p.GetComponentMap(bom), BuildOptions{SynthesizeMissing: true}); err != nil {
+		t.Fatalf("BuildFromSBOMWithOptions failed: %v", err)
+	}
+	node, exists := g.Nodes["managed-postgres"]
+	if !exists || !node.Synthetic {
+		t.Fatal("Expected a synthetic placeholder node")
+	}
+	if node.Name() != "managed-postgres" || len(node.Dependents) != 2 {
+		t.Errorf("Expected placeholder named after its ref with 2 dependents, got %q with %d", node.Name(), len(node.Dependents))
+	}
+	if g.SyntheticCount() != 1 || len(g.Warnings) != 0 || len(g.Unresolved) != 0 {
+		t.Errorf("Expected 1 synthetic node and no warnings, got %d and %v (unresolved %v)", g.SyntheticCount(), g.Warnings, g.Unresolved)
+	}
+
+	order, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+	for _, entry := range order {
+		if entry.BOMRef == "managed-postgres" && entry.Step != 1 {
+			t.Errorf("Expected the placeholder in step 1, got step %d", entry.Step)
+		}
+		if entry.BOMRef == "app" && entry.Step != 3 {
+			t.Errorf("Expected app in step 3, got step %d", entry.Step)
+		}
+	}
 }
 
 func TestGetNodeCount(t *testing.T) {
@@ -119,6 +236,37 @@ func TestGetNodeCount(t *testing.T) {
 	}
 }
 
+func TestNodeCounts(t *testing.T) {
+	bom := &sbom.CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.6",
+		Metadata:    &sbom.Metadata{Component: &sbom.Component{BOMRef: "root", Name: "root", Components: []sbom.Component{{BOMRef: "plugin", Name: "plugin"}}}},
+		Components: []sbom.Component{
+			{BOMRef: "app", Name: "app", Components: []sbom.Component{{BOMRef: "lib", Name: "lib"}}},
+		},
+		Services: []sbom.Service{{BOMRef: "queue", Name: "queue"}},
+	}
+	g := New()
+	if err := g.BuildFromSBOM(bom, parser.New().GetComponentMap(bom)); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	counts := g.NodeCounts()
+	if want := (NodeCounts{Components: 4, Services: 1, Nested: 2}); counts != want {
+		t.Errorf("Expected %+v, got %+v", want, counts)
+	}
+	if counts.TopLevel() != 2 {
+		t.Errorf("Expected 2 top-level components, got %d", counts.TopLevel())
+	}
+
+	restored, err := FromSnapshot(g.Snapshot(), BuildOptions{})
+	if err != nil {
+		t.Fatalf("FromSnapshot failed: %v", err)
+	}
+	if got := restored.NodeCounts(); got != counts {
+		t.Errorf("Expected the snapshot to keep %+v, got %+v", counts, got)
+	}
+}
+
 func TestGetEdgeCount(t *testing.T) {
 	g := New()
 	nodeA := &Node{ID: "a"}
@@ -136,3 +284,327 @@ func TestGetEdgeCount(t *testing.T) {
 		t.Errorf("Expected 3 edges, got %d", g.GetEdgeCount())
 	}
 }
+
+func TestNodesSortedAndEdges(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+
+	nodes := g.NodesSorted()
+	if len(nodes) != g.GetNodeCount() {
+		t.Fatalf("Expected %d nodes, got %d", g.GetNodeCount(), len(nodes))
+	}
+	for i := 1; i < len(nodes); i++ {
+		if nodes[i-1].ID >= nodes[i].ID {
+			t.Errorf("Expected nodes sorted by ID, got %s before %s", nodes[i-1].ID, nodes[i].ID)
+		}
+	}
+
+	edges := g.Edges()
+	if len(edges) != g.GetEdgeCount() {
+		t.Fatalf("Expected %d edges, got %d", g.GetEdgeCount(), len(edges))
+	}
+	for i := 1; i < len(edges); i++ {
+		a, b := edges[i-1], edges[i]
+		if a.From > b.From || (a.From == b.From && a.To >= b.To) {
+			t.Errorf("Expected edges sorted by from and then to, got %v before %v", a, b)
+		}
+	}
+	for _, edge := range edges {
+		from, ok := g.Node(edge.From)
+		if !ok || !g.hasEdge(from, g.Nodes[edge.To]) {
+			t.Errorf("Edges returned %v, which is not in the graph", edge)
+		}
+	}
+
+	if node, ok := g.Node("kafka"); !ok || node.ID != "kafka" {
+		t.Errorf("Expected Node to find kafka, got %v, %v", node, ok)
+	}
+	if node, ok := g.Node("missing"); ok || node != nil {
+		t.Errorf("Expected Node to miss an unknown ref, got %v, %v", node, ok)
+	}
+}
+
+// TestWritersIgnoreDependencyOrder writes graphs that differ only in the
+// order nodes list their dependencies, which map iteration and delta
+// application can change
+func TestWritersIgnoreDependencyOrder(t *testing.T) {
+	build := func(reverse bool) *Graph {
+		g := New()
+		a, b, c, d := &Node{ID: "a"}, &Node{ID: "b"}, &Node{ID: "c"}, &Node{ID: "d"}
+		a.Dependencies = []*Node{b, c, d}
+		b.Dependencies = []*Node{c, d}
+		if reverse {
+			a.Dependencies = []*Node{d, c, b}
+			b.Dependencies = []*Node{d, c}
+		}
+		for _, node := range []*Node{a, b, c, d} {
+			g.Nodes[node.ID] = node
+		}
+		return g
+	}
+	writers := map[string]func(io.Writer, *Graph) error{
+		"dot": WriteDOT,
+		"mermaid": func(w io.Writer, g *Graph) error {
+			return WriteMermaid(w, g, MermaidOptions{})
+		},
+		"adjacency": func(w io.Writer, g *Graph) error {
+			return WriteAdjacency(w, g, AdjacencyOptions{})
+		},
+	}
+	for name, write := range writers {
+		var forward, reverse bytes.Buffer
+		if err := write(&forward, build(false)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := write(&reverse, build(true)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if forward.String() != reverse.String() {
+			t.Errorf("%s: expected the same output for either dependency order\nForward:\n%s\nReverse:\n%s", name, forward.String(), reverse.String())
+		}
+	}
+}
+
+func TestBuildFromSBOMWithEdgeFilter(t *testing.T) {
+	bom := &sbom.CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.6",
+		Components: []sbom.Component{
+			{BOMRef: "app", Name: "App", Version: "1.0"},
+			{BOMRef: "api", Name: "API", Version: "1.0"},
+			{BOMRef: "db", Name: "Database", Version: "1.0"},
+			{BOMRef: "compiler", Name: "Compiler", Version: "1.0", Properties: []sbom.Property{
+				{Name: PropertyDepKind, Value: "build"},
+			}},
+			{BOMRef: "toolchain", Name: "Toolchain", Version: "1.0"},
+			{BOMRef: "fixtures", Name: "Fixtures", Version: "1.0", Scope: "excluded"},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "app", DependsOn: []string{"api", "compiler"}},
+			{Ref: "api", DependsOn: []string{"db", "fixtures"}},
+			{Ref: "compiler", DependsOn: []string{"toolchain"}},
+			{Ref: "fixtures", DependsOn: []string{"db"}},
+		},
+	}
+
+	componentMap := make(map[string]*sbom.Component)
+	for i := range bom.Components {
+		componentMap[bom.Components[i].BOMRef] = &bom.Components[i]
+	}
+
+	// Keep the excluded fixtures so the filter is what drops them
+	full := New()
+	if err := full.BuildFromSBOMWithOptions(bom, componentMap, BuildOptions{IncludeExcluded: true}); err != nil {
+		t.Fatalf("BuildFromSBOMWithOptions failed: %v", err)
+	}
+	fullGroups, err := full.GetDeploymentGroups()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroups failed: %v", err)
+	}
+
+	runtime := New()
+	if err := runtime.BuildFromSBOMWithOptions(bom, componentMap, BuildOptions{EdgeFilter: RuntimeOnly, IncludeExcluded: true}); err != nil {
+		t.Fatalf("BuildFromSBOMWithOptions failed: %v", err)
+	}
+	runtimeGroups, err := runtime.GetDeploymentGroups()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroups failed: %v", err)
+	}
+
+	// Dropping app -> compiler and api -> fixtures shortens the plan
+	if len(fullGroups) != 4 {
+		t.Errorf("Expected 4 groups without filter, got %d", len(fullGroups))
+	}
+	if len(runtimeGroups) != 3 {
+		t.Errorf("Expected 3 groups with runtime-only filter, got %d", len(runtimeGroups))
+	}
+
+	if full.GetEdgeCount()-runtime.GetEdgeCount() != 2 {
+		t.Errorf("Expected 2 edges dropped, got %d", full.GetEdgeCount()-runtime.GetEdgeCount())
+	}
+	if len(runtime.Warnings) != 2 || runtime.Warnings[0].Code != warning.FilteredEdge {
+		t.Errorf("Expected 2 warnings for dropped edges, got %d: %v", len(runtime.Warnings), runtime.Warnings)
+	}
+	if len(full.Warnings) != 0 {
+		t.Errorf("Expected no warnings without filter, got %v", full.Warnings)
+	}
+
+	// Runtime dependencies must still be ordered
+	order, err := runtime.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+	steps := make(map[string]int)
+	for _, item := range order {
+		steps[item.BOMRef] = item.Step
+	}
+	if steps["app"] <= steps["api"] || steps["api"] <= steps["db"] {
+		t.Errorf("Runtime ordering not preserved: %v", steps)
+	}
+	if steps["compiler"] <= steps["toolchain"] {
+		t.Errorf("Edges from build-time components should be kept: %v", steps)
+	}
+}
+
+func TestNodeProperty(t *testing.T) {
+	node := &Node{
+		ID: "svc",
+		Service: &sbom.Service{Name: "Service", Properties: []sbom.Property{
+			{Name: "team", Value: "payments"},
+		}},
+	}
+
+	if value, ok := node.Property("team"); !ok || value != "payments" {
+		t.Errorf("Expected team=payments, got %q (found=%v)", value, ok)
+	}
+	if _, ok := node.Property("missing"); ok {
+		t.Error("Expected missing property to be absent")
+	}
+	if _, ok := (&Node{ID: "bare"}).Property("team"); ok {
+		t.Error("Expected node without component or service to have no properties")
+	}
+}
+
+func TestBuildFromSBOMInferFromNesting(t *testing.T) {
+	bom, err := parser.New().ParseFile(filepath.Join("..", "..", "testdata", "sboms", "nested-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	// Simulate an SBOM that ships components without a dependencies section
+	bom.Dependencies = nil
+	componentMap := parser.New().GetComponentMap(bom)
+
+	flat := New()
+	if err := flat.BuildFromSBOM(bom, componentMap); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	groups, err := flat.GetDeploymentGroups()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroups failed: %v", err)
+	}
+	if len(groups) != 1 {
+		t.Errorf("Expected a single flat group without inference, got %d", len(groups))
+	}
+
+	inferred := New()
+	if err := inferred.BuildFromSBOMWithOptions(bom, componentMap, BuildOptions{InferFrom: InferBoth}); err != nil {
+		t.Fatalf("BuildFromSBOMWithOptions failed: %v", err)
+	}
+	groups, err = inferred.GetDeploymentGroups()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroups failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Errorf("Expected 2 groups with nesting inference, got %d: %v", len(groups), groups)
+	}
+
+	// main-app -> embedded-lib, module-a -> submodule-a1/a2, module-b -> submodule-b1
+	if inferred.GetInferredEdgeCount() != 4 {
+		t.Errorf("Expected 4 inferred edges, got %d", inferred.GetInferredEdgeCount())
+	}
+	if inferred.GetEdgeCount() != inferred.GetInferredEdgeCount() {
+		t.Errorf("Expected every edge to be inferred, got %d edges", inferred.GetEdgeCount())
+	}
+	if !inferred.IsInferred("module-a", "submodule-a2") {
+		t.Error("Expected module-a -> submodule-a2 to be marked inferred")
+	}
+}
+
+func TestInferFromSources(t *testing.T) {
+	// Nesting puts docs under platform, but the composition assembles only
+	// auth and billing into it, plus gateway into the shop
+	tests := []struct {
+		source   InferSource
+		edges    []Edge
+		warnings []string
+	}{
+		{InferNone, nil, nil},
+		{InferNesting, []Edge{{"platform", "billing"}, {"platform", "docs"}},
+			[]string{"inferred 2 edges from component nesting"}},
+		{InferCompositions, []Edge{{"platform", "billing"}, {"shop", "gateway"}},
+			[]string{"inferred 2 edges from composition assemblies"}},
+		{InferBoth, []Edge{{"platform", "billing"}, {"platform", "docs"}, {"shop", "gateway"}},
+			[]string{"inferred 2 edges from component nesting", "inferred 1 edge from composition assemblies"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.source), func(t *testing.T) {
+			bom, err := parser.New().ParseFile(filepath.Join("..", "..", "testdata", "sboms", "assemblies-1.6.json"))
+			if err != nil {
+				t.Fatalf("ParseFile failed: %v", err)
+			}
+			g := New()
+			if err := g.BuildFromSBOMWithOptions(bom, parser.New().GetComponentMap(bom), BuildOptions{InferFrom: tt.source}); err != nil {
+				t.Fatalf("BuildFromSBOMWithOptions failed: %v", err)
+			}
+
+			var edges []Edge
+			for edge := range g.inferred {
+				edges = append(edges, edge)
+			}
+			sort.Slice(edges, func(i, j int) bool {
+				return edges[i].From+" "+edges[i].To < edges[j].From+" "+edges[j].To
+			})
+			if !reflect.DeepEqual(edges, tt.edges) {
+				t.Errorf("Expected inferred edges %v, got %v", tt.edges, edges)
+			}
+			// The declared platform -> auth is never inferred again
+			if g.IsInferred("platform", "auth") || g.GetEdgeCount() != len(tt.edges)+1 {
+				t.Errorf("Expected the declared edge once, got %d edges", g.GetEdgeCount())
+			}
+
+			var warnings []string
+			for _, w := range g.Warnings {
+				if w.Code == warning.InferredEdges {
+					warnings = append(warnings, w.Message)
+				}
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("Expected warnings %q, got %q", tt.warnings, warnings)
+			}
+		})
+	}
+
+	if _, err := ParseInferSource("assemblies"); err == nil {
+		t.Error("Expected an error for an unknown source")
+	}
+}
+
+func TestInferFromNestingKeepsExplicitEdges(t *testing.T) {
+	bom := &sbom.CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.6",
+		Metadata: &sbom.Metadata{
+			Component: &sbom.Component{BOMRef: "product", Name: "Product"},
+		},
+		Components: []sbom.Component{
+			{BOMRef: "parent", Name: "Parent", Components: []sbom.Component{
+				{BOMRef: "child", Name: "Child"},
+			}},
+			{BOMRef: "standalone", Name: "Standalone"},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "parent", DependsOn: []string{"child"}},
+		},
+		Compositions: []sbom.Composition{
+			{Aggregate: "complete", Assemblies: []string{"standalone", "child"}},
+		},
+	}
+	componentMap := parser.New().GetComponentMap(bom)
+
+	g := New()
+	if err := g.BuildFromSBOMWithOptions(bom, componentMap, BuildOptions{InferFrom: InferBoth}); err != nil {
+		t.Fatalf("BuildFromSBOMWithOptions failed: %v", err)
+	}
+
+	if len(g.Nodes["parent"].Dependencies) != 1 {
+		t.Errorf("Expected explicit edge not to be duplicated, got %d dependencies", len(g.Nodes["parent"].Dependencies))
+	}
+	if g.IsInferred("parent", "child") {
+		t.Error("Explicit edge should not be marked inferred")
+	}
+	if !g.IsInferred("product", "standalone") {
+		t.Error("Expected top-level composition assembly to be assembled into the metadata component")
+	}
+	if g.GetInferredEdgeCount() != 1 {
+		t.Errorf("Expected 1 inferred edge, got %d", g.GetInferredEdgeCount())
+	}
+}
@@ -0,0 +1,122 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nprimmer/bom-dagger/internal/logging"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// dedupeByPurl unifies component nodes sharing a purl into the first ref in
+// document order and returns every alias's canonical ref
+func (g *Graph) dedupeByPurl(bom *sbom.CycloneDX, opts BuildOptions) (map[string]string, error) {
+	canonical := make(map[string]*Node) // purl -> canonical node
+	aliases := make(map[string]string)
+
+	var components []*sbom.Component
+	var walk func(list []sbom.Component)
+	walk = func(list []sbom.Component) {
+		for i := range list {
+			components = append(components, &list[i])
+			walk(list[i].Components)
+		}
+	}
+	walk(bom.Components)
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		components = append(components, bom.Metadata.Component)
+		walk(bom.Metadata.Component.Components)
+	}
+
+	for _, component := range components {
+		if component.Purl == "" || component.BOMRef == "" {
+			continue
+		}
+		node, exists := g.Nodes[component.BOMRef]
+		if !exists {
+			continue // already unified, or a repeated ref
+		}
+		first, seen := canonical[component.Purl]
+		if !seen {
+			canonical[component.Purl] = node
+			continue
+		}
+		if first == node {
+			continue
+		}
+
+		if kept, other := first.Version(), component.Version; kept != "" && other != "" && kept != other {
+			conflict := fmt.Sprintf("purl %s has conflicting versions: %s (ref %s) and %s (ref %s)",
+				component.Purl, kept, first.ID, other, component.BOMRef)
+			if !opts.DedupeVersionTolerant {
+				return nil, fmt.Errorf("%s", conflict)
+			}
+			g.Warnings = append(g.Warnings, warning.New(warning.VersionConflict, "%s; keeping %s", conflict, kept))
+		}
+
+		aliases[component.BOMRef] = first.ID
+		first.Aliases = append(first.Aliases, component.BOMRef)
+		delete(g.Nodes, component.BOMRef)
+	}
+
+	for _, node := range canonical {
+		sort.Strings(node.Aliases)
+	}
+	if opts.Logger.Enabled(logging.LevelInfo) && len(aliases) > 0 {
+		opts.Logger.Infof("unified %d components that share a purl with another component", len(aliases))
+	}
+	return aliases, nil
+}
+
+// canonicalRef returns the ref a unified alias was merged into, or ref itself
+func canonicalRef(aliases map[string]string, ref string) string {
+	if canonical, ok := aliases[ref]; ok {
+		return canonical
+	}
+	return ref
+}
+
+// rewriteDependencies redirects dependency entries and targets from aliases to
+// their canonical refs, dropping edges that now point at their own node. The
+// input is not modified.
+func rewriteDependencies(dependencies []sbom.Dependency, aliases map[string]string) []sbom.Dependency {
+	if len(aliases) == 0 {
+		return dependencies
+	}
+	result := make([]sbom.Dependency, 0, len(dependencies))
+	for _, dep := range dependencies {
+		rewritten := sbom.Dependency{Ref: canonicalRef(aliases, dep.Ref)}
+		for _, target := range dep.DependsOn {
+			if target = canonicalRef(aliases, target); target != rewritten.Ref {
+				rewritten.DependsOn = append(rewritten.DependsOn, target)
+			}
+		}
+		result = append(result, rewritten)
+	}
+	return result
+}
+
+// rewriteCompositions redirects the refs listed by compositions from aliases
+// to their canonical refs. The input is not modified.
+func rewriteCompositions(compositions []sbom.Composition, aliases map[string]string) []sbom.Composition {
+	if len(aliases) == 0 {
+		return compositions
+	}
+	rewrite := func(refs []string) []string {
+		var result []string
+		for _, ref := range refs {
+			result = append(result, canonicalRef(aliases, ref))
+		}
+		return result
+	}
+	result := make([]sbom.Composition, 0, len(compositions))
+	for _, composition := range compositions {
+		result = append(result, sbom.Composition{
+			Aggregate:    composition.Aggregate,
+			Assemblies:   rewrite(composition.Assemblies),
+			Dependencies: rewrite(composition.Dependencies),
+		})
+	}
+	return result
+}
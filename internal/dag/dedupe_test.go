@@ -0,0 +1,108 @@
+package dag
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// mergedPostgresBOM merges two SBOMs that describe the same postgres
+// container under different refs
+func mergedPostgresBOM(t *testing.T) *sbom.CycloneDX {
+	t.Helper()
+
+	p := parser.New()
+	var boms []*sbom.CycloneDX
+	for _, name := range []string{"dedupe-shop-1.6.json", "dedupe-migrator-1.6.json"} {
+		bom, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", name))
+		if err != nil {
+			t.Fatalf("ParseFile(%s) failed: %v", name, err)
+		}
+		boms = append(boms, bom)
+	}
+	return parser.Merge(boms...)
+}
+
+func TestDedupeByPurl(t *testing.T) {
+	bom := mergedPostgresBOM(t)
+
+	g := New()
+	if err := g.BuildFromSBOMWithOptions(bom, parser.New().GetComponentMap(bom), BuildOptions{DedupeByPurl: true}); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if len(g.Nodes) != 5 {
+		t.Errorf("Expected 5 nodes after unifying postgres, got %d", len(g.Nodes))
+	}
+	if _, exists := g.Nodes["docker.io/library/postgres:15.4"]; exists {
+		t.Error("Expected the alias ref to be removed from the graph")
+	}
+	postgres := g.Nodes["pg-container"]
+	if want := []string{"docker.io/library/postgres:15.4"}; !reflect.DeepEqual(postgres.Aliases, want) {
+		t.Errorf("Expected aliases %v, got %v", want, postgres.Aliases)
+	}
+
+	var dependents []string
+	for _, node := range sortedByID(postgres.Dependents) {
+		dependents = append(dependents, node.ID)
+	}
+	if want := []string{"migrator", "shop"}; !reflect.DeepEqual(dependents, want) {
+		t.Errorf("Expected both SBOMs' components to depend on postgres, got %v", dependents)
+	}
+}
+
+func TestDedupeByPurlDisabled(t *testing.T) {
+	bom := mergedPostgresBOM(t)
+
+	g := New()
+	if err := g.BuildFromSBOM(bom, parser.New().GetComponentMap(bom)); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(g.Nodes) != 6 {
+		t.Errorf("Expected postgres twice without deduplication, got %d nodes", len(g.Nodes))
+	}
+}
+
+func TestDedupeByPurlVersionConflict(t *testing.T) {
+	newBOM := func() *sbom.CycloneDX {
+		return &sbom.CycloneDX{
+			Components: []sbom.Component{
+				{BOMRef: "app", Name: "App"},
+				{BOMRef: "pg-a", Name: "postgres", Version: "15.4", Purl: "pkg:docker/library/postgres"},
+				{BOMRef: "pg-b", Name: "postgres", Version: "15.5", Purl: "pkg:docker/library/postgres"},
+			},
+			Dependencies: []sbom.Dependency{
+				{Ref: "app", DependsOn: []string{"pg-b"}},
+				{Ref: "pg-b", DependsOn: []string{"pg-a"}},
+			},
+		}
+	}
+
+	bom := newBOM()
+	err := New().BuildFromSBOMWithOptions(bom, parser.New().GetComponentMap(bom), BuildOptions{DedupeByPurl: true})
+	if err == nil || !strings.Contains(err.Error(), "conflicting versions: 15.4 (ref pg-a) and 15.5 (ref pg-b)") {
+		t.Fatalf("Expected a version conflict error, got %v", err)
+	}
+
+	bom = newBOM()
+	g := New()
+	opts := BuildOptions{DedupeByPurl: true, DedupeVersionTolerant: true}
+	if err := g.BuildFromSBOMWithOptions(bom, parser.New().GetComponentMap(bom), opts); err != nil {
+		t.Fatalf("Expected tolerant build to succeed, got %v", err)
+	}
+	if len(g.Nodes) != 2 || g.Nodes["pg-a"].Version() != "15.4" {
+		t.Errorf("Expected pg-b unified into pg-a at 15.4, got %d nodes", len(g.Nodes))
+	}
+	// The edge between the two refs collapses instead of becoming a self-loop
+	if len(g.Nodes["pg-a"].Dependencies) != 0 || len(g.Nodes["app"].Dependencies) != 1 {
+		t.Errorf("Unexpected edges after unification: %v", g.Nodes["app"].Dependencies)
+	}
+	if len(g.Warnings) == 0 || g.Warnings[0].Code != warning.VersionConflict || !strings.Contains(g.Warnings[0].Message, "keeping 15.4") {
+		t.Errorf("Expected a version conflict warning, got %v", g.Warnings)
+	}
+}
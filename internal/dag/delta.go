@@ -0,0 +1,229 @@
+package dag
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// ErrDeltaUnsupported is returned by ApplyDelta for graphs whose build needs
+// a pass over the whole SBOM, which a delta cannot provide
+var ErrDeltaUnsupported = errors.New("delta cannot be applied incrementally")
+
+// ApplyDelta updates the graph in place with an SBOM delta, as a full rebuild
+// would. InferFrom, DedupeByPurl, SynthesizeMissing and excluded components
+// return ErrDeltaUnsupported; a new cycle keeps the delta and wraps ErrCycle.
+func (g *Graph) ApplyDelta(delta sbom.BOMDelta) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ensureNodes()
+
+	if err := g.checkDelta(delta); err != nil {
+		return err
+	}
+
+	// The dependents of a removed node keep naming it, so it becomes one of
+	// their unresolved targets
+	for _, ref := range delta.RemovedComponents {
+		if node, ok := g.Nodes[ref]; ok && node.Component != nil {
+			g.removeDeltaNode(node)
+		}
+	}
+	for _, ref := range delta.RemovedServices {
+		if node, ok := g.Nodes[ref]; ok && node.Service != nil {
+			g.removeDeltaNode(node)
+		}
+	}
+
+	added := 0
+	for i := range delta.Components {
+		component := &delta.Components[i]
+		if node, ok := g.Nodes[component.BOMRef]; ok {
+			node.Component, node.Service = component, nil
+		} else {
+			g.insertNode(&Node{ID: component.BOMRef, Component: component})
+			added++
+		}
+		g.checkPriority(g.Nodes[component.BOMRef])
+		g.checkPhase(g.Nodes[component.BOMRef])
+	}
+	for i := range delta.Services {
+		service := &delta.Services[i]
+		if node, ok := g.Nodes[service.BOMRef]; ok && node.Component == nil {
+			node.Service = service
+		} else if !ok {
+			g.insertNode(&Node{ID: service.BOMRef, Service: service})
+			added++
+		}
+		g.checkPriority(g.Nodes[service.BOMRef])
+		g.checkPhase(g.Nodes[service.BOMRef])
+	}
+
+	// Replace the outgoing edges of every listed ref; a new cycle must run
+	// through one of the new edges, so only their sources are searched
+	var sources []string
+	for _, ref := range delta.RemovedDependencies {
+		delete(g.unresolvedBy, ref)
+		if node, ok := g.Nodes[ref]; ok {
+			g.unlinkDependencies(node)
+		}
+	}
+	dependencies, notes := normalizeDependencies(delta.Dependencies)
+	g.Warnings = append(g.Warnings, notes...)
+	for _, dep := range dependencies {
+		delete(g.unresolvedBy, dep.Ref)
+		if node, ok := g.Nodes[dep.Ref]; ok {
+			g.unlinkDependencies(node)
+			for _, target := range dep.DependsOn {
+				if _, exists := g.Nodes[target]; !exists {
+					g.addUnresolved(dep.Ref, target)
+				}
+			}
+		}
+	}
+	g.refreshUnresolved()
+	edges, warnings := g.resolveEdges(dependencies, g.buildOpts, nil)
+	g.Warnings = append(g.Warnings, warnings...)
+	linkEdges(edges)
+	for _, dep := range dependencies {
+		if node, ok := g.Nodes[dep.Ref]; ok {
+			if len(node.Dependencies) > 0 {
+				g.removeRoot(node)
+				sources = append(sources, node.ID)
+			} else if !containsNode(g.Roots, node) {
+				g.Roots = append(g.Roots, node)
+			}
+		}
+	}
+
+	if delta.CompositionsChanged {
+		g.compositions = delta.Compositions
+	}
+	if delta.CompositionsChanged || (added > 0 && len(g.compositions) > 0) {
+		for _, node := range g.Nodes {
+			node.Completeness = ""
+		}
+		g.applyCompositions(g.compositions)
+	}
+
+	visited := make(map[string]bool)
+	recStack := make(map[string]bool)
+	for _, id := range sources {
+		if g.hasCycleDFS(id, visited, recStack) {
+			return fmt.Errorf("%w: the delta makes %s part of a cycle", ErrCycle, id)
+		}
+	}
+	return nil
+}
+
+// checkDelta reports whether the delta can be applied to the graph
+func (g *Graph) checkDelta(delta sbom.BOMDelta) error {
+	opts := g.buildOpts
+	switch {
+	case opts.InferFrom != InferNone:
+		return fmt.Errorf("%w: the graph infers edges from %s", ErrDeltaUnsupported, opts.InferFrom)
+	case opts.DedupeByPurl:
+		return fmt.Errorf("%w: the graph unifies components by purl", ErrDeltaUnsupported)
+	case opts.SynthesizeMissing:
+		return fmt.Errorf("%w: the graph synthesizes missing dependencies", ErrDeltaUnsupported)
+	case opts.Env != "":
+		return fmt.Errorf("%w: the graph is planned for environment %s", ErrDeltaUnsupported, opts.Env)
+	}
+	if opts.IncludeExcluded {
+		return nil
+	}
+	if len(g.Excluded) > 0 {
+		return fmt.Errorf("%w: the graph left out components with scope excluded", ErrDeltaUnsupported)
+	}
+	for _, component := range delta.Components {
+		if component.Scope == ScopeExcluded {
+			return fmt.Errorf("%w: %s has scope excluded", ErrDeltaUnsupported, component.BOMRef)
+		}
+	}
+	return nil
+}
+
+// removeDeltaNode removes a node deleted by a delta, recording it as an
+// unresolved target of its dependents
+func (g *Graph) removeDeltaNode(node *Node) {
+	for _, dependent := range node.Dependents {
+		if dependent != node {
+			g.addUnresolved(dependent.ID, node.ID)
+		}
+	}
+	delete(g.unresolvedBy, node.ID)
+	g.removeNode(node.ID)
+}
+
+// addUnresolved records target as a dependsOn target of ref that names no
+// node
+func (g *Graph) addUnresolved(ref, target string) {
+	if g.unresolvedBy == nil {
+		g.unresolvedBy = make(map[string][]string)
+	}
+	g.unresolvedBy[ref] = append(g.unresolvedBy[ref], target)
+}
+
+// refreshUnresolved recomputes Unresolved and the dangling ref warnings from
+// the unresolved targets recorded per ref
+func (g *Graph) refreshUnresolved() {
+	warnings := make([]warning.Warning, 0, len(g.Warnings))
+	for _, w := range g.Warnings {
+		if w.Code != warning.DanglingRef {
+			warnings = append(warnings, w)
+		}
+	}
+	g.Warnings = warnings
+	g.Unresolved = nil
+
+	seen := make(map[string]bool)
+	refs := make([]string, 0, len(g.unresolvedBy))
+	for ref := range g.unresolvedBy {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	for _, ref := range refs {
+		for _, target := range g.unresolvedBy[ref] {
+			if seen[target] {
+				continue
+			}
+			seen[target] = true
+			if !g.buildOpts.ReportedRefs[target] {
+				g.Warnings = append(g.Warnings, warning.New(warning.DanglingRef, "skipped unknown ref %s (needed by %s)", target, ref))
+			}
+			g.Unresolved = append(g.Unresolved, target)
+		}
+	}
+	sort.Strings(g.Unresolved)
+}
+
+// insertNode adds a node without edges, which makes it a root
+func (g *Graph) insertNode(node *Node) {
+	node.Dependencies = []*Node{}
+	node.Dependents = []*Node{}
+	g.Nodes[node.ID] = node
+	g.Roots = append(g.Roots, node)
+}
+
+// unlinkDependencies removes every outgoing edge of node
+func (g *Graph) unlinkDependencies(node *Node) {
+	for _, dep := range node.Dependencies {
+		dep.Dependents = removeNodeFrom(dep.Dependents, node)
+	}
+	node.Dependencies = []*Node{}
+	if !containsNode(g.Roots, node) {
+		g.Roots = append(g.Roots, node)
+	}
+}
+
+func containsNode(nodes []*Node, target *Node) bool {
+	for _, n := range nodes {
+		if n == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package dag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/testgen"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// diffGraphs is DiffNodes plus the bookkeeping ApplyDelta keeps up to date
+// alongside nodes and edges: reverse edges, roots, completeness and
+// unresolved refs
+func diffGraphs(a, b *Graph) []string {
+	diff := DiffNodes(a, b)
+	for _, node := range sortedByID(nodeList(a)) {
+		other, ok := b.Nodes[node.ID]
+		if !ok {
+			continue
+		}
+		if got, want := nodeIDs(sortedByID(other.Dependents)), nodeIDs(sortedByID(node.Dependents)); !reflect.DeepEqual(got, want) {
+			diff = append(diff, fmt.Sprintf("~ node %s: dependents %v -> %v", node.ID, want, got))
+		}
+		if node.Completeness != other.Completeness {
+			diff = append(diff, fmt.Sprintf("~ node %s: completeness %q -> %q", node.ID, node.Completeness, other.Completeness))
+		}
+	}
+	if got, want := nodeIDs(sortedByID(b.Roots)), nodeIDs(sortedByID(a.Roots)); !reflect.DeepEqual(got, want) {
+		diff = append(diff, fmt.Sprintf("~ roots %v -> %v", want, got))
+	}
+	if !reflect.DeepEqual(a.Unresolved, b.Unresolved) {
+		diff = append(diff, fmt.Sprintf("~ unresolved %v -> %v", a.Unresolved, b.Unresolved))
+	}
+	return diff
+}
+
+// copyBOM returns a deep copy of bom
+func copyBOM(t testing.TB, bom *sbom.CycloneDX) *sbom.CycloneDX {
+	t.Helper()
+	data, err := json.Marshal(bom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c sbom.CycloneDX
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	return &c
+}
+
+// changeBOM returns a copy of a testgen SBOM in which about fraction of the
+// nodes were removed, added, given a new version or new dependencies. New
+// dependencies point at lower indexes, so the result stays acyclic.
+func changeBOM(t testing.TB, bom *sbom.CycloneDX, fraction float64, seed int64) *sbom.CycloneDX {
+	t.Helper()
+	r := rand.New(rand.NewSource(seed))
+	changed := copyBOM(t, bom)
+	n := len(bom.Components) + len(bom.Services)
+	changes := max(1, int(float64(n)*fraction))
+
+	removed := make(map[string]bool)
+	for i := 0; i < changes/4; i++ {
+		removed[testgen.Ref(r.Intn(n))] = true
+	}
+	var components []sbom.Component
+	for _, c := range changed.Components {
+		if !removed[c.BOMRef] {
+			components = append(components, c)
+		}
+	}
+	for i := 0; i < changes/4; i++ {
+		c := &components[r.Intn(len(components))]
+		c.Version += "-patched"
+	}
+	for i := 0; i < changes/4; i++ {
+		ref := fmt.Sprintf("added-%d", i)
+		components = append(components, sbom.Component{Type: "library", BOMRef: ref, Name: ref, Version: "1.0.0"})
+		changed.Dependencies = append(changed.Dependencies, sbom.Dependency{Ref: ref, DependsOn: []string{testgen.Ref(r.Intn(n))}})
+		changed.Dependencies = append(changed.Dependencies, sbom.Dependency{Ref: testgen.Ref(n - 1 - r.Intn(n/2)), DependsOn: []string{ref}})
+	}
+	changed.Components = components
+	for i := 0; i < changes/4; i++ {
+		index := 1 + r.Intn(n-1)
+		dep := &changed.Dependencies[index]
+		dep.DependsOn = []string{testgen.Ref(r.Intn(index))}
+	}
+	return changed
+}
+
+func buildFrom(t testing.TB, bom *sbom.CycloneDX, opts BuildOptions) *Graph {
+	t.Helper()
+	g := New()
+	if err := g.BuildFromSBOMWithOptions(bom, parser.New().GetComponentMap(bom), opts); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	return g
+}
+
+func TestApplyDeltaMatchesRebuild(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		old := testgen.GenerateBOM(testgen.Options{Nodes: 2000, EdgeDensity: 2, ServiceRatio: 0.1, Seed: seed})
+		updated := changeBOM(t, old, 0.05, seed)
+
+		g := buildFrom(t, old, BuildOptions{})
+		delta := sbom.ComputeDelta(old, updated)
+		if delta.Empty() {
+			t.Fatal("Expected a non-empty delta")
+		}
+		if err := g.ApplyDelta(delta); err != nil {
+			t.Fatalf("seed %d: ApplyDelta failed: %v", seed, err)
+		}
+
+		rebuilt := buildFrom(t, updated, BuildOptions{})
+		if diff := diffGraphs(rebuilt, g); diff != nil {
+			t.Fatalf("seed %d: graph after ApplyDelta differs from a rebuild:\n%s", seed, strings.Join(diff, "\n"))
+		}
+		if got, want := stepsByRef(t, g), stepsByRef(t, rebuilt); !reflect.DeepEqual(got, want) {
+			t.Errorf("seed %d: steps after ApplyDelta differ from a rebuild", seed)
+		}
+
+		// Applying the reverse delta restores the original graph
+		if err := g.ApplyDelta(sbom.ComputeDelta(updated, old)); err != nil {
+			t.Fatalf("seed %d: reverse ApplyDelta failed: %v", seed, err)
+		}
+		if diff := diffGraphs(buildFrom(t, old, BuildOptions{}), g); diff != nil {
+			t.Errorf("seed %d: reverse delta did not restore the original graph:\n%s", seed, strings.Join(diff, "\n"))
+		}
+	}
+}
+
+func TestApplyDeltaResolvesNewTargets(t *testing.T) {
+	old := &sbom.CycloneDX{
+		BOMFormat: "CycloneDX", SpecVersion: "1.6",
+		Components: []sbom.Component{{BOMRef: "app", Name: "App", Version: "1"}},
+		Dependencies: []sbom.Dependency{
+			{Ref: "app", DependsOn: []string{"db"}},
+			{Ref: "cache", DependsOn: []string{"db"}},
+		},
+		Compositions: []sbom.Composition{{Aggregate: "incomplete", Assemblies: []string{"db"}}},
+	}
+	updated := copyBOM(t, old)
+	updated.Components = append(updated.Components, sbom.Component{BOMRef: "db", Name: "Database", Version: "15"})
+	updated.Services = []sbom.Service{{BOMRef: "cache", Name: "Cache"}}
+
+	delta := sbom.ComputeDelta(old, updated)
+	var refs []string
+	for _, dep := range delta.Dependencies {
+		refs = append(refs, dep.Ref)
+	}
+	if !reflect.DeepEqual(refs, []string{"app", "cache"}) {
+		t.Errorf("Expected the unchanged entries naming new nodes in the delta, got %v", refs)
+	}
+
+	g := buildFrom(t, old, BuildOptions{})
+	if err := g.ApplyDelta(delta); err != nil {
+		t.Fatalf("ApplyDelta failed: %v", err)
+	}
+	if diff := diffGraphs(buildFrom(t, updated, BuildOptions{}), g); diff != nil {
+		t.Errorf("Graph after ApplyDelta differs from a rebuild:\n%s", strings.Join(diff, "\n"))
+	}
+	if g.Nodes["db"].Completeness != AggregateIncomplete {
+		t.Errorf("Expected the new node to pick up its composition, got %q", g.Nodes["db"].Completeness)
+	}
+}
+
+func TestApplyDeltaUnresolved(t *testing.T) {
+	old := &sbom.CycloneDX{
+		BOMFormat: "CycloneDX", SpecVersion: "1.6",
+		Components: []sbom.Component{
+			{BOMRef: "app", Name: "App", Version: "1"},
+			{BOMRef: "api", Name: "API", Version: "1"},
+			{BOMRef: "db", Name: "Database", Version: "15"},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "app", DependsOn: []string{"api", "queue"}},
+			{Ref: "api", DependsOn: []string{"db", "vault"}},
+		},
+	}
+	// The queue now exists, the api no longer needs the vault, and the
+	// database is gone while the api still names it
+	updated := copyBOM(t, old)
+	updated.Components = append(updated.Components[:2], sbom.Component{BOMRef: "queue", Name: "Queue", Version: "3"})
+	updated.Dependencies[1].DependsOn = []string{"db"}
+
+	g := buildFrom(t, old, BuildOptions{})
+	if !reflect.DeepEqual(g.Unresolved, []string{"queue", "vault"}) {
+		t.Fatalf("Expected queue and vault unresolved, got %v", g.Unresolved)
+	}
+	if err := g.ApplyDelta(sbom.ComputeDelta(old, updated)); err != nil {
+		t.Fatalf("ApplyDelta failed: %v", err)
+	}
+	if !reflect.DeepEqual(g.Unresolved, []string{"db"}) {
+		t.Errorf("Expected only db unresolved, got %v", g.Unresolved)
+	}
+	var dangling []string
+	for _, w := range g.Warnings {
+		if w.Code == warning.DanglingRef {
+			dangling = append(dangling, w.Message)
+		}
+	}
+	if !reflect.DeepEqual(dangling, []string{"skipped unknown ref db (needed by api)"}) {
+		t.Errorf("Expected one dangling ref warning for db, got %v", dangling)
+	}
+
+	// Snapshots keep what ApplyDelta needs
+	restored, err := FromSnapshot(g.Snapshot(), BuildOptions{})
+	if err != nil {
+		t.Fatalf("FromSnapshot failed: %v", err)
+	}
+	if err := restored.ApplyDelta(sbom.ComputeDelta(updated, old)); err != nil {
+		t.Fatalf("ApplyDelta failed: %v", err)
+	}
+	if !reflect.DeepEqual(restored.Unresolved, []string{"queue", "vault"}) {
+		t.Errorf("Expected the reverse delta to restore the unresolved refs, got %v", restored.Unresolved)
+	}
+}
+
+func TestApplyDeltaCycle(t *testing.T) {
+	old := testgen.GenerateBOM(testgen.Options{Nodes: 50, EdgeDensity: 2, Seed: 3})
+	updated := copyBOM(t, old)
+	// node-0 is depended on by later nodes; make it depend on the last one
+	updated.Dependencies[0].DependsOn = []string{testgen.Ref(49)}
+	updated.Dependencies[49].DependsOn = []string{testgen.Ref(10)}
+	updated.Dependencies[10].DependsOn = []string{testgen.Ref(0)}
+
+	g := buildFrom(t, old, BuildOptions{})
+	err := g.ApplyDelta(sbom.ComputeDelta(old, updated))
+	if !errors.Is(err, ErrCycle) {
+		t.Fatalf("Expected ErrCycle, got %v", err)
+	}
+}
+
+func TestApplyDeltaUnsupported(t *testing.T) {
+	bom := testgen.GenerateBOM(testgen.Options{Nodes: 20, EdgeDensity: 1, Seed: 1})
+	updated := changeBOM(t, bom, 0.5, 1)
+
+	for name, opts := range map[string]BuildOptions{
+		"nesting":    {InferFrom: InferBoth},
+		"dedupe":     {DedupeByPurl: true},
+		"synthesize": {SynthesizeMissing: true},
+		"env":        {Env: "prod"},
+	} {
+		g := buildFrom(t, bom, opts)
+		before, err := FromSnapshot(g.Snapshot(), opts)
+		if err != nil {
+			t.Fatalf("%s: FromSnapshot failed: %v", name, err)
+		}
+		if err := g.ApplyDelta(sbom.ComputeDelta(bom, updated)); !errors.Is(err, ErrDeltaUnsupported) {
+			t.Errorf("%s: expected ErrDeltaUnsupported, got %v", name, err)
+		}
+		if diff := diffGraphs(before, g); diff != nil {
+			t.Errorf("%s: expected the graph to be left unchanged, got:\n%s", name, strings.Join(diff, "\n"))
+		}
+	}
+
+	excluded := copyBOM(t, updated)
+	excluded.Components[0].Scope = ScopeExcluded
+	g := buildFrom(t, bom, BuildOptions{})
+	if err := g.ApplyDelta(sbom.ComputeDelta(bom, excluded)); !errors.Is(err, ErrDeltaUnsupported) {
+		t.Errorf("Expected ErrDeltaUnsupported for an excluded component, got %v", err)
+	}
+	g = buildFrom(t, bom, BuildOptions{IncludeExcluded: true})
+	if err := g.ApplyDelta(sbom.ComputeDelta(bom, excluded)); err != nil {
+		t.Errorf("Expected excluded components to be fine with IncludeExcluded, got %v", err)
+	}
+}
+
+// BenchmarkApplyDelta compares rebuilding a 100k-node graph with applying a
+// delta touching 1% of it. Each iteration applies the delta and its reverse,
+// so it is reported per pair.
+func BenchmarkApplyDelta(b *testing.B) {
+	old := testgen.GenerateBOM(testgen.Options{Nodes: 100000, EdgeDensity: 3, ServiceRatio: 0.1, Seed: 42})
+	updated := changeBOM(b, old, 0.01, 42)
+	forward, backward := sbom.ComputeDelta(old, updated), sbom.ComputeDelta(updated, old)
+	componentMap := parser.New().GetComponentMap(updated)
+
+	b.Run("rebuild", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			g := New()
+			if err := g.BuildFromSBOM(updated, componentMap); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("apply", func(b *testing.B) {
+		g := buildFrom(b, old, BuildOptions{})
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := g.ApplyDelta(forward); err != nil {
+				b.Fatal(err)
+			}
+			if err := g.ApplyDelta(backward); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("compute", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sbom.ComputeDelta(old, updated)
+		}
+	})
+}
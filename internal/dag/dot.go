@@ -0,0 +1,142 @@
+package dag
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/ident"
+)
+
+// DOTOptions controls WriteDOTWithOptions
+type DOTOptions struct {
+	// Label renders node labels; nil uses the name and version
+	Label *LabelTemplate
+	// ClusterBy wraps nodes in a cluster subgraph per group or step
+	ClusterBy ClusterBy
+	// ShowTrust fills services crossing a trust boundary in red
+	ShowTrust bool
+	// Legend appends a cluster explaining the node and edge styles
+	Legend bool
+	// MaxLabelLen shortens node IDs and the names, versions and refs in
+	// labels longer than this many characters (0 = never; see ident.Shorten)
+	MaxLabelLen int
+}
+
+// WriteDOT writes the graph in Graphviz DOT format, sorted by bom-ref, with
+// placeholders and incomplete or unknown compositions dashed or dotted
+func WriteDOT(w io.Writer, g *Graph) error {
+	return WriteDOTWithOptions(w, g, DOTOptions{})
+}
+
+// WriteDOTWithOptions is WriteDOT with configurable node labels
+func WriteDOTWithOptions(w io.Writer, g *Graph, opts DOTOptions) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=BT;\n")
+	b.WriteString("  node [shape=box];\n\n")
+
+	clusters, err := g.clusters(opts.ClusterBy)
+	if err != nil {
+		return err
+	}
+	for i, c := range clusters {
+		indent := "  "
+		if opts.ClusterBy != ClusterNone {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "  subgraph \"cluster_%d\" {\n", i+1)
+			fmt.Fprintf(&b, "    label=\"%s\";\n", ident.Sanitize(ident.Shorten(c.label, opts.MaxLabelLen), ident.DOT))
+			indent = "    "
+		}
+		for _, node := range c.nodes {
+			label, err := nodeLabel(opts.Label, node, nameAndVersion, opts.MaxLabelLen)
+			if err != nil {
+				return err
+			}
+			style := ""
+			if node.Synthetic {
+				style = ", style=dashed"
+			}
+			if opts.ShowTrust && node.CrossesTrustBoundary() {
+				style += trustBoundaryStyle
+			}
+			// DOT takes a single URL per node, so only the first link
+			if links := node.Links(g.LinkType); len(links) > 0 {
+				style += fmt.Sprintf(", URL=\"%s\"", ident.Sanitize(links[0], ident.DOT))
+			}
+			fmt.Fprintf(&b, "%s\"%s\" [label=\"%s\"%s];\n", indent, dotID(node.ID, opts), ident.Sanitize(label, ident.DOT), style)
+		}
+		if opts.ClusterBy != ClusterNone {
+			b.WriteString("  }\n")
+		}
+	}
+	b.WriteString("\n")
+
+	for _, edge := range g.edges() {
+		style := completenessEdgeStyles[completenessClass(g.Nodes[edge.From].Completeness)]
+		fmt.Fprintf(&b, "  \"%s\" -> \"%s\"%s;\n", dotID(edge.From, opts), dotID(edge.To, opts), style)
+	}
+	if opts.Legend {
+		writeDOTLegend(&b, opts)
+	}
+	b.WriteString("}\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// dotID is the quoted DOT ID of a node. Shortening keeps IDs unique, as the
+// hash suffix tells long refs with a common prefix apart.
+func dotID(ref string, opts DOTOptions) string {
+	return ident.Sanitize(ident.Shorten(ref, opts.MaxLabelLen), ident.DOT)
+}
+
+// nameAndVersion is the default graph label: the name, with the version or
+// the external marker of a synthetic node on a second line
+func nameAndVersion(data LabelData) string {
+	if data.node.Synthetic {
+		return data.Name + "\n(external)"
+	}
+	if data.Version != "" {
+		return data.Name + "\n" + data.Version
+	}
+	return data.Name
+}
+
+// completenessEdgeStyles style the edges of a node by the completeness its
+// compositions declare
+var completenessEdgeStyles = map[string]string{
+	AggregateIncomplete: " [style=dashed]",
+	AggregateUnknown:    " [style=dotted]",
+}
+
+// writeDOTLegend writes a cluster with one sample node per node style and one
+// sample edge per edge style
+func writeDOTLegend(b *strings.Builder, opts DOTOptions) {
+	b.WriteString("\n  subgraph \"cluster_legend\" {\n")
+	b.WriteString("    label=\"Legend\";\n")
+	b.WriteString("    \"legend:component\" [label=\"component or service\"];\n")
+	b.WriteString("    \"legend:external\" [label=\"external placeholder\", style=dashed];\n")
+	if opts.ShowTrust {
+		fmt.Fprintf(b, "    \"legend:trust\" [label=\"crosses a trust boundary\"%s];\n", trustBoundaryStyle)
+	}
+	for _, edge := range []struct{ class, label string }{
+		{AggregateComplete, "dependencies complete or not declared"},
+		{AggregateIncomplete, "dependencies incomplete"},
+		{AggregateUnknown, "dependencies unknown"},
+	} {
+		fmt.Fprintf(b, "    \"legend:%s\" [label=\"%s\", shape=plaintext];\n", edge.class, edge.label)
+		fmt.Fprintf(b, "    \"legend:%s:to\" [label=\"\", shape=point];\n", edge.class)
+		fmt.Fprintf(b, "    \"legend:%s\" -> \"legend:%s:to\"%s;\n", edge.class, edge.class, completenessEdgeStyles[edge.class])
+	}
+	b.WriteString("  }\n")
+}
+
+// trustBoundaryStyle fills the services crossing a trust boundary with
+// DOTOptions.ShowTrust
+const trustBoundaryStyle = `, style=filled, fillcolor="#f4cccc", color="#cc0000"`
@@ -1,6 +1,7 @@
 package dag
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/nprimmer/bom-dagger/internal/sbom"
@@ -161,31 +162,23 @@ func TestEdgeCases(t *testing.T) {
 		g := New()
 
 		// Create diamond: A -> B,C -> D
-		compA := &sbom.Component{Name: "A", Version: "1.0"}
-		compB := &sbom.Component{Name: "B", Version: "1.0"}
-		compC := &sbom.Component{Name: "C", Version: "1.0"}
-		compD := &sbom.Component{Name: "D", Version: "1.0"}
-
-		nodeA := &Node{ID: "a", Component: compA}
-		nodeB := &Node{ID: "b", Component: compB}
-		nodeC := &Node{ID: "c", Component: compC}
-		nodeD := &Node{ID: "d", Component: compD}
-
-		// A depends on B and C
-		nodeA.Dependencies = []*Node{nodeB, nodeC}
-		nodeB.Dependents = []*Node{nodeA}
-		nodeC.Dependents = []*Node{nodeA}
-
-		// B and C both depend on D
-		nodeB.Dependencies = []*Node{nodeD}
-		nodeC.Dependencies = []*Node{nodeD}
-		nodeD.Dependents = []*Node{nodeB, nodeC}
-
-		g.Nodes["a"] = nodeA
-		g.Nodes["b"] = nodeB
-		g.Nodes["c"] = nodeC
-		g.Nodes["d"] = nodeD
-		g.Roots = []*Node{nodeD}
+		for _, name := range []string{"A", "B", "C", "D"} {
+			comp := &sbom.Component{BOMRef: strings.ToLower(name), Name: name, Version: "1.0"}
+			if _, err := g.AddComponent(comp); err != nil {
+				t.Fatalf("AddComponent failed: %v", err)
+			}
+		}
+
+		// A depends on B and C; B and C both depend on D
+		for _, edge := range []Edge{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}} {
+			if err := g.AddDependency(edge.From, edge.To); err != nil {
+				t.Fatalf("AddDependency failed: %v", err)
+			}
+		}
+
+		if len(g.Roots) != 1 || g.Roots[0].ID != "d" {
+			t.Errorf("Expected d as the only root, got %v", g.Roots)
+		}
 
 		order, err := g.TopologicalSort()
 		if err != nil {
@@ -0,0 +1,148 @@
+package dag
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// Equal reports whether two graphs have the same refs, with the same kind,
+// name and version, and the same edges; see DiffNodes for what differs
+func Equal(a, b *Graph) bool {
+	return len(DiffNodes(a, b)) == 0
+}
+
+// DiffNodes describes how b differs from a, one sorted line per difference:
+// refs only in one graph, refs whose kind, name or version changed, and
+// edges only in one graph. It returns nil when the graphs are Equal.
+func DiffNodes(a, b *Graph) []string {
+	if a == b {
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var diff []string
+	for ref, node := range a.Nodes {
+		other, ok := b.Nodes[ref]
+		if !ok {
+			diff = append(diff, fmt.Sprintf("- node %s", ref))
+			continue
+		}
+		if node.Kind() != other.Kind() {
+			diff = append(diff, fmt.Sprintf("~ node %s: kind %s -> %s", ref, node.Kind(), other.Kind()))
+		}
+		if node.Name() != other.Name() {
+			diff = append(diff, fmt.Sprintf("~ node %s: name %q -> %q", ref, node.Name(), other.Name()))
+		}
+		if node.Version() != other.Version() {
+			diff = append(diff, fmt.Sprintf("~ node %s: version %q -> %q", ref, node.Version(), other.Version()))
+		}
+	}
+	for ref := range b.Nodes {
+		if _, ok := a.Nodes[ref]; !ok {
+			diff = append(diff, fmt.Sprintf("+ node %s", ref))
+		}
+	}
+
+	aEdges, bEdges := edgeSet(a), edgeSet(b)
+	for edge := range aEdges {
+		if !bEdges[edge] {
+			diff = append(diff, fmt.Sprintf("- edge %s -> %s", edge.From, edge.To))
+		}
+	}
+	for edge := range bEdges {
+		if !aEdges[edge] {
+			diff = append(diff, fmt.Sprintf("+ edge %s -> %s", edge.From, edge.To))
+		}
+	}
+
+	sort.Strings(diff)
+	return diff
+}
+
+// Fingerprint returns a "sha256:" digest of what Equal compares plus each
+// node's type, scope and properties, commands included
+func (g *Graph) Fingerprint() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	h := sha256.New()
+	write := func(fields ...string) {
+		for _, field := range fields {
+			fmt.Fprintf(h, "%d:%s", len(field), field)
+		}
+	}
+	for _, node := range g.sortedNodes() {
+		write("node", node.ID, node.Kind(), componentType(node), componentScope(node), node.Name(), node.Version())
+		for _, prop := range sortedProperties(node) {
+			write("property", prop.Name, prop.Value)
+		}
+	}
+	edges := make([]Edge, 0, len(g.Nodes))
+	for edge := range edgeSet(g) {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	for _, edge := range edges {
+		write("edge", edge.From, edge.To)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// edgeSet returns the dependency edges of g; g.mu is held
+func edgeSet(g *Graph) map[Edge]bool {
+	edges := make(map[Edge]bool)
+	for _, node := range g.Nodes {
+		for _, dep := range node.Dependencies {
+			edges[Edge{From: node.ID, To: dep.ID}] = true
+		}
+	}
+	return edges
+}
+
+// nodeType returns the CycloneDX type of the node's component
+func componentType(n *Node) string {
+	if n.Component != nil {
+		return n.Component.Type
+	}
+	return ""
+}
+
+// nodeScope returns the scope of the node's component
+func componentScope(n *Node) string {
+	if n.Component != nil {
+		return n.Component.Scope
+	}
+	return ""
+}
+
+// nodeProperties returns the properties of the node's component or service,
+// sorted by name and value
+func sortedProperties(n *Node) []sbom.Property {
+	var props []sbom.Property
+	if n.Component != nil {
+		props = n.Component.Properties
+	} else if n.Service != nil {
+		props = n.Service.Properties
+	}
+	props = slices.Clone(props)
+	sort.Slice(props, func(i, j int) bool {
+		if props[i].Name != props[j].Name {
+			return props[i].Name < props[j].Name
+		}
+		return props[i].Value < props[j].Value
+	})
+	return props
+}
@@ -0,0 +1,111 @@
+package dag
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// diamond builds app -> api-a, api-b -> db, adding nodes and edges in the
+// given order; change, when set, edits the graph before it is returned
+func diamond(t *testing.T, reverse bool, change func(g *Graph)) *Graph {
+	t.Helper()
+	g := New()
+	components := []*sbom.Component{
+		{BOMRef: "app", Name: "App", Version: "1.0"},
+		{BOMRef: "api-a", Name: "API A", Version: "1.0"},
+		{BOMRef: "api-b", Name: "API B", Version: "2.0"},
+		{BOMRef: "db", Name: "Database", Version: "15"},
+	}
+	edges := []Edge{{"app", "api-a"}, {"app", "api-b"}, {"api-a", "db"}, {"api-b", "db"}}
+	if reverse {
+		for i, j := 0, len(components)-1; i < j; i, j = i+1, j-1 {
+			components[i], components[j] = components[j], components[i]
+		}
+		for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+			edges[i], edges[j] = edges[j], edges[i]
+		}
+	}
+	for _, c := range components {
+		if _, err := g.AddComponent(c); err != nil {
+			t.Fatalf("AddComponent failed: %v", err)
+		}
+	}
+	for _, e := range edges {
+		if err := g.AddDependency(e.From, e.To); err != nil {
+			t.Fatalf("AddDependency failed: %v", err)
+		}
+	}
+	if change != nil {
+		change(g)
+	}
+	return g
+}
+
+func TestEqual(t *testing.T) {
+	base := diamond(t, false, nil)
+
+	tests := []struct {
+		name  string
+		other *Graph
+		want  []string
+	}{
+		{"itself", base, nil},
+		{"same graph built in another order", diamond(t, true, nil), nil},
+		{"one extra edge", diamond(t, false, func(g *Graph) {
+			_ = g.AddDependency("app", "db")
+		}), []string{"+ edge app -> db"}},
+		{"one edge fewer", diamond(t, false, func(g *Graph) {
+			g.Nodes["api-b"].Dependencies = nil
+			g.Nodes["db"].Dependents = g.Nodes["db"].Dependents[:1]
+		}), []string{"- edge api-b -> db"}},
+		{"one version bumped", diamond(t, false, func(g *Graph) {
+			g.Nodes["db"].Component = &sbom.Component{BOMRef: "db", Name: "Database", Version: "16"}
+		}), []string{`~ node db: version "15" -> "16"`}},
+		{"renamed", diamond(t, false, func(g *Graph) {
+			g.Nodes["app"].Component = &sbom.Component{BOMRef: "app", Name: "Application", Version: "1.0"}
+		}), []string{`~ node app: name "App" -> "Application"`}},
+		{"component became a service", diamond(t, false, func(g *Graph) {
+			g.Nodes["db"].Component = nil
+			g.Nodes["db"].Service = &sbom.Service{BOMRef: "db", Name: "Database", Version: "15"}
+		}), []string{"~ node db: kind component -> service"}},
+		{"node removed", diamond(t, false, func(g *Graph) {
+			_ = g.RemoveNode("app")
+		}), []string{"- edge app -> api-a", "- edge app -> api-b", "- node app"}},
+		{"isolated node added", diamond(t, false, func(g *Graph) {
+			_, _ = g.AddComponent(&sbom.Component{BOMRef: "cache", Name: "Cache"})
+		}), []string{"+ node cache"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffNodes(base, tt.other)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffNodes() = %q, want %q", got, tt.want)
+			}
+			if equal := Equal(base, tt.other); equal != (tt.want == nil) {
+				t.Errorf("Equal() = %t, want %t", equal, tt.want == nil)
+			}
+			if Equal(tt.other, base) != Equal(base, tt.other) {
+				t.Error("Expected Equal to be symmetric")
+			}
+			if same := base.Fingerprint() == tt.other.Fingerprint(); same != (tt.want == nil) {
+				t.Errorf("Fingerprints match = %t, want %t", same, tt.want == nil)
+			}
+		})
+	}
+}
+
+func TestEqualIgnoresComponentIdentity(t *testing.T) {
+	// Separately parsed copies share no pointers
+	a, b := loadFixture(t, "microservices-1.6.json"), loadFixture(t, "microservices-1.6.json")
+	if diff := DiffNodes(a, b); diff != nil {
+		t.Errorf("Expected equal graphs, got %q", diff)
+	}
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Errorf("Expected equal fingerprints, got %s and %s", a.Fingerprint(), b.Fingerprint())
+	}
+	if Equal(a, loadFixture(t, "diamond-1.6.json")) {
+		t.Error("Expected different fixtures to differ")
+	}
+}
@@ -0,0 +1,118 @@
+package dag
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// ScopeExcluded is the CycloneDX scope of components that are not shipped
+const ScopeExcluded = "excluded"
+
+// contractExcluded removes the components with scope "excluded" (see
+// contract) and returns their refs, sorted
+func (g *Graph) contractExcluded(opts BuildOptions) []string {
+	return g.contract(opts, isExcluded)
+}
+
+// isExcluded reports whether node is a component with scope "excluded"
+func isExcluded(node *Node) bool {
+	return node.Component != nil && node.Component.Scope == ScopeExcluded
+}
+
+// keepEdge reports whether opts.EdgeFilter keeps the edge from -> to. Edges
+// into excluded components that contractExcluded will remove are kept, so
+// the filter judges the edges bridging them instead.
+func (opts BuildOptions) keepEdge(from, to *Node) bool {
+	if opts.EdgeFilter == nil || (!opts.IncludeExcluded && isExcluded(to)) {
+		return true
+	}
+	return opts.EdgeFilter(from, to)
+}
+
+// contractOtherEnvs removes the nodes not deployed to opts.Env (see InEnv and
+// contract) and returns their refs, sorted
+func (g *Graph) contractOtherEnvs(opts BuildOptions) []string {
+	return g.contract(opts, func(node *Node) bool {
+		return !node.InEnv(opts.Env)
+	})
+}
+
+// InEnv reports whether the node is deployed to env: nodes without a
+// bom-dagger:envs property are deployed everywhere, the others only to the
+// comma-separated environments it lists (compared case-insensitively)
+func (n *Node) InEnv(env string) bool {
+	envs, ok := n.Property(PropertyEnvs)
+	if !ok {
+		return true
+	}
+	for _, e := range strings.Split(envs, ",") {
+		if strings.EqualFold(strings.TrimSpace(e), env) {
+			return true
+		}
+	}
+	return false
+}
+
+// contract removes the nodes matching drop, linking their dependents to their
+// dependencies, and returns the removed refs, sorted
+func (g *Graph) contract(opts BuildOptions, drop func(*Node) bool) []string {
+	var excluded []string
+	for ref, node := range g.Nodes {
+		if drop(node) {
+			excluded = append(excluded, ref)
+		}
+	}
+	sort.Strings(excluded)
+
+	for _, ref := range excluded {
+		node := g.Nodes[ref]
+		for _, dependent := range node.Dependents {
+			for _, dep := range node.Dependencies {
+				if dependent == dep || g.hasEdge(dependent, dep) {
+					continue
+				}
+				if !opts.keepEdge(dependent, dep) {
+					g.Warnings = append(g.Warnings, warning.New(warning.FilteredEdge, "dropped edge %s -> %s (filtered)", dependent.ID, dep.ID))
+					continue
+				}
+				dependent.Dependencies = append(dependent.Dependencies, dep)
+				dep.Dependents = append(dep.Dependents, dependent)
+				if g.inferred[Edge{From: dependent.ID, To: ref}] || g.inferred[Edge{From: ref, To: dep.ID}] {
+					g.inferred[Edge{From: dependent.ID, To: dep.ID}] = true
+				}
+			}
+		}
+
+		for _, dep := range node.Dependencies {
+			dep.Dependents = removeNodeFrom(dep.Dependents, node)
+		}
+		for _, dependent := range node.Dependents {
+			dependent.Dependencies = removeNodeFrom(dependent.Dependencies, node)
+		}
+		delete(g.Nodes, ref)
+		for edge := range g.inferred {
+			if edge.From == ref || edge.To == ref {
+				delete(g.inferred, edge)
+			}
+		}
+	}
+	return excluded
+}
+
+// ExcludedCount returns the number of components with scope "excluded" that
+// were left out of the graph (see BuildOptions.IncludeExcluded)
+func (g *Graph) ExcludedCount() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.Excluded)
+}
+
+// EnvExcludedCount returns the number of nodes left out of the graph because
+// they are not deployed to BuildOptions.Env
+func (g *Graph) EnvExcludedCount() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.EnvExcluded)
+}
@@ -0,0 +1,172 @@
+package dag
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestBuildContractsExcludedComponents(t *testing.T) {
+	g := loadFixture(t, "excluded-chain-1.6.json")
+
+	if want := []string{"gateway-mock", "seed-data", "seed-loader"}; !reflect.DeepEqual(g.Excluded, want) {
+		t.Errorf("Expected excluded %v, got %v", want, g.Excluded)
+	}
+	if g.ExcludedCount() != 3 || g.GetNodeCount() != 4 {
+		t.Errorf("Expected 3 excluded and 4 remaining nodes, got %d and %d", g.ExcludedCount(), g.GetNodeCount())
+	}
+
+	// Contracted edges keep the endpoints ordered
+	wantDeps := map[string][]string{"app": {"auth"}, "auth": {"db"}, "worker": {"db"}, "db": {}}
+	for ref, want := range wantDeps {
+		if got := nodeIDs(g.Nodes[ref].Dependencies); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %s to depend on %v, got %v", ref, want, got)
+		}
+	}
+	if got := nodeIDs(sortedByID(g.Nodes["db"].Dependents)); !reflect.DeepEqual(got, []string{"auth", "worker"}) {
+		t.Errorf("Expected db dependents [auth worker], got %v", got)
+	}
+
+	order, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+	steps := make(map[string]int)
+	for _, entry := range order {
+		steps[entry.BOMRef] = entry.Step
+	}
+	if want := map[string]int{"db": 1, "auth": 2, "worker": 2, "app": 3}; !reflect.DeepEqual(steps, want) {
+		t.Errorf("Expected steps %v, got %v", want, steps)
+	}
+	if len(g.Roots) != 1 || g.Roots[0].ID != "db" {
+		t.Errorf("Expected db as the only root, got %v", nodeIDs(g.Roots))
+	}
+}
+
+func TestBuildRuntimeOnlyContractsFirst(t *testing.T) {
+	p := parser.New()
+	bom, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", "excluded-chain-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	// The filter sees the contracted edges, not those into excluded components
+	g := New()
+	if err := g.BuildFromSBOMWithOptions(bom, p.GetComponentMap(bom), BuildOptions{EdgeFilter: RuntimeOnly}); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	wantDeps := map[string][]string{"app": {"auth"}, "auth": {"db"}, "worker": {"db"}, "db": {}}
+	for ref, want := range wantDeps {
+		if got := nodeIDs(g.Nodes[ref].Dependencies); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %s to depend on %v, got %v", ref, want, got)
+		}
+	}
+	if len(g.Warnings) != 0 {
+		t.Errorf("Expected no filtered edges, got %v", g.Warnings)
+	}
+}
+
+func TestBuildIncludeExcluded(t *testing.T) {
+	p := parser.New()
+	bom, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", "excluded-chain-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	g := New()
+	if err := g.BuildFromSBOMWithOptions(bom, p.GetComponentMap(bom), BuildOptions{IncludeExcluded: true}); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if g.GetNodeCount() != 7 || g.ExcludedCount() != 0 {
+		t.Errorf("Expected all 7 components and none excluded, got %d and %d", g.GetNodeCount(), g.ExcludedCount())
+	}
+	if got := nodeIDs(g.Nodes["app"].Dependencies); !reflect.DeepEqual(got, []string{"gateway-mock"}) {
+		t.Errorf("Expected app to depend on gateway-mock, got %v", got)
+	}
+}
+
+// buildEnv builds the envs fixture for one deployment environment
+func buildEnv(t *testing.T, env string) *Graph {
+	t.Helper()
+	p := parser.New()
+	bom, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", "envs-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	g := New()
+	if err := g.BuildFromSBOMWithOptions(bom, p.GetComponentMap(bom), BuildOptions{Env: env}); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	return g
+}
+
+func TestBuildEnv(t *testing.T) {
+	tests := []struct {
+		env      string
+		excluded []string
+		steps    map[string]int
+	}{
+		{"", nil, map[string]int{
+			"db": 1, "queue": 1, "api": 2, "analytics-pipeline": 3, "cdn": 3, "smoke-tests": 3, "dashboard": 4, "web": 4,
+		}},
+		{"prod", []string{"smoke-tests"}, map[string]int{
+			"db": 1, "queue": 1, "api": 2, "analytics-pipeline": 3, "cdn": 3, "dashboard": 4, "web": 4,
+		}},
+		// Staging skips the CDN, so the web frontend follows the API directly
+		{"staging", []string{"analytics-pipeline", "cdn", "dashboard"}, map[string]int{
+			"db": 1, "queue": 1, "api": 2, "smoke-tests": 3, "web": 3,
+		}},
+		// Environments are matched case-insensitively; the queue lists neither
+		{"DEV", []string{"analytics-pipeline", "cdn", "dashboard", "queue", "smoke-tests"}, map[string]int{
+			"db": 1, "api": 2, "web": 3,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			g := buildEnv(t, tt.env)
+			if !reflect.DeepEqual(g.EnvExcluded, tt.excluded) {
+				t.Errorf("Expected %v left out, got %v", tt.excluded, g.EnvExcluded)
+			}
+			if g.EnvExcludedCount() != len(tt.excluded) {
+				t.Errorf("Expected EnvExcludedCount %d, got %d", len(tt.excluded), g.EnvExcludedCount())
+			}
+			order, err := g.TopologicalSort()
+			if err != nil {
+				t.Fatalf("TopologicalSort failed: %v", err)
+			}
+			steps := make(map[string]int)
+			for _, entry := range order {
+				steps[entry.BOMRef] = entry.Step
+			}
+			if !reflect.DeepEqual(steps, tt.steps) {
+				t.Errorf("Expected steps %v, got %v", tt.steps, steps)
+			}
+		})
+	}
+}
+
+func TestInEnv(t *testing.T) {
+	node := func(envs string) *Node {
+		return &Node{Component: &sbom.Component{Properties: []sbom.Property{{Name: PropertyEnvs, Value: envs}}}}
+	}
+	tests := []struct {
+		node *Node
+		env  string
+		want bool
+	}{
+		{&Node{Component: &sbom.Component{}}, "prod", true},
+		{node("staging, prod"), "prod", true},
+		{node("staging,prod"), "Staging", true},
+		{node("staging"), "prod", false},
+		{node(""), "prod", false},
+		{&Node{Service: &sbom.Service{Properties: []sbom.Property{{Name: PropertyEnvs, Value: "prod"}}}}, "staging", false},
+	}
+	for i, tt := range tests {
+		if got := tt.node.InEnv(tt.env); got != tt.want {
+			t.Errorf("Case %d: InEnv(%q) = %t, want %t", i, tt.env, got, tt.want)
+		}
+	}
+}
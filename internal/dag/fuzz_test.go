@@ -0,0 +1,68 @@
+package dag
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/testgen"
+)
+
+func FuzzBuildFromSBOM(f *testing.F) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "testdata", "sboms", "*.json"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data, false)
+		f.Add(data, true)
+	}
+	generated, err := json.Marshal(testgen.GenerateBOM(testgen.Options{Nodes: 50, EdgeDensity: 2, ServiceRatio: 0.1, NestingDepth: 2, Seed: 1}))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(generated, true)
+
+	f.Fuzz(func(t *testing.T, data []byte, infer bool) {
+		p := parser.New()
+		p.MaxSize = 1 << 20
+		bom, err := p.Parse(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		g := New()
+		opts := BuildOptions{EdgeFilter: RuntimeOnly}
+		if infer {
+			opts.InferFrom = InferBoth
+		}
+		if err := g.BuildFromSBOMWithOptions(bom, p.GetComponentMap(bom), opts); err != nil {
+			return
+		}
+
+		order, err := g.TopologicalSort()
+		if err != nil {
+			t.Fatalf("TopologicalSort failed on an acyclic graph: %v", err)
+		}
+		if len(order) != len(g.Nodes) {
+			t.Fatalf("Expected %d nodes in order, got %d", len(g.Nodes), len(order))
+		}
+		var buf bytes.Buffer
+		if err := WriteTree(&buf, g, TreeOptions{}); err != nil {
+			t.Fatalf("WriteTree failed: %v", err)
+		}
+		if err := WriteBackstage(&buf, g, BackstageOptions{}); err != nil {
+			t.Fatalf("WriteBackstage failed: %v", err)
+		}
+		if _, err := WriteTerraform(&buf, g); err != nil {
+			t.Fatalf("WriteTerraform failed: %v", err)
+		}
+	})
+}
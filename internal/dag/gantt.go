@@ -0,0 +1,89 @@
+package dag
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DefaultGanttMaxTasks is the number of tasks WriteGantt writes by default;
+// Mermaid becomes unreadable, and slow to render, well before a few hundred
+const DefaultGanttMaxTasks = 200
+
+// GanttOptions controls WriteGantt
+type GanttOptions struct {
+	// MaxTasks caps the number of tasks (0 = unlimited). Tasks are kept in
+	// step order, then schedule order, and the rest are left out.
+	MaxTasks int
+}
+
+// WriteGantt writes a schedule as a Mermaid gantt with a section per step
+// and returns how many tasks MaxTasks left out
+func WriteGantt(w io.Writer, g *Graph, items []ScheduledItem, opts GanttOptions) (int, error) {
+	g.mu.RLock()
+	order, err := g.topologicalSort()
+	g.mu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+	steps := make(map[string]int, len(order))
+	for _, entry := range order {
+		steps[entry.BOMRef] = entry.Step
+	}
+
+	tasks := append([]ScheduledItem(nil), items...)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return steps[tasks[i].BOMRef] < steps[tasks[j].BOMRef]
+	})
+	omitted := 0
+	if opts.MaxTasks > 0 && len(tasks) > opts.MaxTasks {
+		omitted = len(tasks) - opts.MaxTasks
+		tasks = tasks[:opts.MaxTasks]
+	}
+
+	var b strings.Builder
+	b.WriteString("gantt\n")
+	b.WriteString("    title Deployment Schedule\n")
+	b.WriteString("    dateFormat X\n")
+	b.WriteString("    axisFormat %H:%M\n")
+	section := 0
+	for i, task := range tasks {
+		if step := steps[task.BOMRef]; step != section {
+			section = step
+			fmt.Fprintf(&b, "    section Step %d\n", step)
+		}
+		var tags []string
+		if task.Critical {
+			tags = append(tags, "crit")
+		}
+		if task.Duration == 0 {
+			tags = append(tags, "milestone")
+		}
+		tags = append(tags, fmt.Sprintf("t%d", i+1))
+		fmt.Fprintf(&b, "    %s :%s, %d, %ds\n", ganttTaskName(task.Name), strings.Join(tags, ", "),
+			int64(task.Start.Seconds()), int64(task.Duration.Seconds()))
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return omitted, err
+}
+
+var ganttReplacer = strings.NewReplacer(
+	":", "-", // ends the task name
+	"%%", "%", // starts a comment
+	"#", "", // starts an entity code
+	";", ",",
+	"\r\n", " ",
+	"\n", " ",
+	"\r", " ",
+)
+
+// ganttTaskName makes a name safe to use as a Mermaid gantt task name, which
+// ends at the first colon and may not span lines
+func ganttTaskName(name string) string {
+	if name = strings.TrimSpace(ganttReplacer.Replace(name)); name == "" {
+		return "(unnamed)"
+	}
+	return name
+}
@@ -0,0 +1,57 @@
+package dag
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/parser"
+)
+
+var update = flag.Bool("update", false, "Update golden files")
+
+// assertGolden compares got against testdata/golden/<name>, ignoring the
+// difference between LF and CRLF line endings, rewriting the file when
+// -update is set
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("..", "..", "testdata", "golden", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	// Checkouts with core.autocrlf turn the golden files' line endings into CRLF
+	if normalizeNewlines(string(got)) != normalizeNewlines(string(want)) {
+		t.Errorf("Output does not match %s\nGot:\n%s\nWant:\n%s", name, got, want)
+	}
+}
+
+// loadFixture builds a graph from one of the SBOMs in testdata/sboms
+func loadFixture(t *testing.T, name string) *Graph {
+	t.Helper()
+
+	p := parser.New()
+	bom, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", name))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	g := New()
+	if err := g.BuildFromSBOM(bom, p.GetComponentMap(bom)); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	return g
+}
+
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
@@ -0,0 +1,100 @@
+package dag
+
+import (
+	"net/url"
+	"strings"
+)
+
+// PropertyImage names the container image a component runs, for components
+// without a docker or oci purl or to override the one derived from it
+const PropertyImage = "bom-dagger:image"
+
+// Image returns the container image reference of the node: its
+// bom-dagger:image property, or the reference derived from a docker or oci
+// purl. It returns "" for nodes without an image.
+func (n *Node) Image() string {
+	if image, ok := n.Property(PropertyImage); ok && strings.TrimSpace(image) != "" {
+		return strings.TrimSpace(image)
+	}
+	if n.Component == nil {
+		return ""
+	}
+	image, _ := ImageFromPurl(n.Component.Purl)
+	return image
+}
+
+// ImageFromPurl converts a docker or oci purl into a pullable image reference,
+// e.g. pkg:docker/team/api@1.2 -> team/api:1.2; false for other purls
+func ImageFromPurl(purl string) (string, bool) {
+	rest, ok := strings.CutPrefix(purl, "pkg:")
+	if !ok {
+		return "", false
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, rawQualifiers, _ := strings.Cut(rest, "?")
+	qualifiers, err := url.ParseQuery(rawQualifiers)
+	if err != nil {
+		return "", false
+	}
+	var version string
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		rest, version = rest[:i], rest[i+1:]
+		if version, err = url.PathUnescape(version); err != nil {
+			return "", false
+		}
+	}
+	segments := strings.Split(strings.Trim(rest, "/"), "/")
+	for i, segment := range segments {
+		if segments[i], err = url.PathUnescape(segment); err != nil {
+			return "", false
+		}
+	}
+	if len(segments) < 2 || segments[len(segments)-1] == "" {
+		return "", false
+	}
+
+	repositoryURL := qualifiers.Get("repository_url")
+	if _, after, ok := strings.Cut(repositoryURL, "://"); ok {
+		repositoryURL = after
+	}
+	repositoryURL = strings.TrimSuffix(repositoryURL, "/")
+
+	var repository string
+	switch strings.ToLower(segments[0]) {
+	case "docker":
+		repository = strings.Join(segments[1:], "/")
+		if repositoryURL != "" {
+			repository = repositoryURL + "/" + repository
+		}
+	case "oci":
+		repository = segments[len(segments)-1]
+		if repositoryURL != "" {
+			repository = repositoryURL
+		}
+	default:
+		return "", false
+	}
+
+	tag, digest := qualifiers.Get("tag"), qualifiers.Get("digest")
+	if isDigest(version) {
+		digest = version
+	} else if version != "" {
+		tag = version
+	}
+	image := repository
+	if tag != "" {
+		image += ":" + tag
+	}
+	if digest != "" {
+		image += "@" + digest
+	}
+	return image, true
+}
+
+// isDigest reports whether version is a content digest such as sha256:ab12
+func isDigest(version string) bool {
+	algorithm, hex, ok := strings.Cut(version, ":")
+	return ok && hex != "" && algorithm != "" && strings.IndexFunc(algorithm, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) < 0
+}
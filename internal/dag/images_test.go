@@ -0,0 +1,58 @@
+package dag
+
+import (
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestImageFromPurl(t *testing.T) {
+	tests := []struct {
+		purl string
+		want string
+	}{
+		{"pkg:docker/library/nginx@1.25", "library/nginx:1.25"},
+		{"pkg:docker/nginx", "nginx"},
+		{"pkg:docker/team/api@2.0?repository_url=registry.local:5000", "registry.local:5000/team/api:2.0"},
+		{"pkg:docker/team/api@2.0?repository_url=https%3A%2F%2Fregistry.local%3A5000%2F", "registry.local:5000/team/api:2.0"},
+		{"pkg:docker/registry.local%3A5000/team/api@2.0", "registry.local:5000/team/api:2.0"},
+		{"pkg:docker/team/api@sha256%3Aabc123?repository_url=gcr.io", "gcr.io/team/api@sha256:abc123"},
+		{"pkg:docker/team/api@sha256:abc123?tag=2.0", "team/api:2.0@sha256:abc123"},
+		{"pkg:docker/team/api@2.0?digest=sha256%3Aabc123", "team/api:2.0@sha256:abc123"},
+		{"pkg:oci/debian@sha256%3A244fd47e07d1?repository_url=docker.io/library/debian&arch=amd64&tag=latest", "docker.io/library/debian:latest@sha256:244fd47e07d1"},
+		{"pkg:oci/static@sha256%3Aabc123", "static@sha256:abc123"},
+		{"pkg:OCI/static?tag=v1#sub/path", "static:v1"},
+		{"pkg:npm/left-pad@1.3.0", ""},
+		{"pkg:docker/", ""},
+		{"docker/nginx@1.25", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, ok := ImageFromPurl(tt.purl)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("ImageFromPurl(%q) = %q, %t, want %q", tt.purl, got, ok, tt.want)
+		}
+	}
+}
+
+func TestNodeImage(t *testing.T) {
+	property := func(value string) []sbom.Property {
+		return []sbom.Property{{Name: PropertyImage, Value: value}}
+	}
+	tests := []struct {
+		name string
+		node *Node
+		want string
+	}{
+		{"purl", &Node{Component: &sbom.Component{Purl: "pkg:docker/team/api@2.0"}}, "team/api:2.0"},
+		{"property wins", &Node{Component: &sbom.Component{Purl: "pkg:docker/team/api@2.0", Properties: property(" ghcr.io/acme/api:2.0 ")}}, "ghcr.io/acme/api:2.0"},
+		{"blank property", &Node{Component: &sbom.Component{Purl: "pkg:docker/team/api@2.0", Properties: property(" ")}}, "team/api:2.0"},
+		{"service", &Node{Service: &sbom.Service{Properties: property("acme/gateway:1")}}, "acme/gateway:1"},
+		{"no image", &Node{Component: &sbom.Component{Purl: "pkg:maven/org.acme/lib@1.0"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.node.Image(); got != tt.want {
+			t.Errorf("%s: Image() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package dag
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Properties naming the Kubernetes resource that deploys a component
+const (
+	PropertyK8sKind      = "bom-dagger:k8s-kind"
+	PropertyK8sName      = "bom-dagger:k8s-name"
+	PropertyK8sNamespace = "bom-dagger:k8s-namespace" // optional
+)
+
+// DefaultK8sAnnotation is the annotation WriteK8sPatches sets to the wave number
+const DefaultK8sAnnotation = "bom-dagger.io/wave"
+
+// K8sPatchOptions controls WriteK8sPatches
+type K8sPatchOptions struct {
+	// Annotation is the annotation key set to the wave (default
+	// DefaultK8sAnnotation), e.g. argocd.argoproj.io/sync-wave
+	Annotation string
+}
+
+// K8sPatch is one kustomize patches entry: a target resource and an inline
+// JSON 6902 patch
+type K8sPatch struct {
+	Target K8sTarget `json:"target"`
+	Patch  string    `json:"patch"`
+}
+
+// K8sTarget selects the resource a K8sPatch applies to
+type K8sTarget struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// k8sPatchOp is a JSON 6902 operation
+type k8sPatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+// WriteK8sPatches writes kustomize patches annotating each node's manifest
+// with its wave and returns the sorted refs lacking the k8s properties
+func WriteK8sPatches(w io.Writer, g *Graph, opts K8sPatchOptions) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	annotation := opts.Annotation
+	if annotation == "" {
+		annotation = DefaultK8sAnnotation
+	}
+
+	order, err := g.topologicalSort()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].Step != order[j].Step {
+			return order[i].Step < order[j].Step
+		}
+		return order[i].BOMRef < order[j].BOMRef
+	})
+
+	patches := []K8sPatch{}
+	var skipped []string
+	for _, entry := range order {
+		node := g.Nodes[entry.BOMRef]
+		kind, hasKind := node.Property(PropertyK8sKind)
+		name, hasName := node.Property(PropertyK8sName)
+		if !hasKind || !hasName || kind == "" || name == "" {
+			skipped = append(skipped, node.ID)
+			continue
+		}
+		namespace, _ := node.Property(PropertyK8sNamespace)
+
+		ops, err := json.Marshal([]k8sPatchOp{{
+			Op:    "add",
+			Path:  "/metadata/annotations/" + jsonPointerEscape(annotation),
+			Value: strconv.Itoa(entry.Step),
+		}})
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, K8sPatch{
+			Target: K8sTarget{Kind: kind, Name: name, Namespace: namespace},
+			Patch:  string(ops),
+		})
+	}
+	sort.Strings(skipped)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return skipped, encoder.Encode(patches)
+}
+
+// jsonPointerEscape escapes a key for use as a JSON Pointer (RFC 6901) token
+func jsonPointerEscape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,86 @@
+package dag
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestWriteK8sPatches(t *testing.T) {
+	g := loadFixture(t, "k8s-1.6.json")
+
+	var buf bytes.Buffer
+	skipped, err := WriteK8sPatches(&buf, g, K8sPatchOptions{})
+	if err != nil {
+		t.Fatalf("WriteK8sPatches failed: %v", err)
+	}
+	if want := []string{"client-lib", "migrations"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("Expected skipped %v, got %v", want, skipped)
+	}
+
+	var patches []K8sPatch
+	if err := json.Unmarshal(buf.Bytes(), &patches); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	groups, err := g.GetDeploymentGroupNodes()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroupNodes failed: %v", err)
+	}
+	wave := make(map[string]string)
+	for i, group := range groups {
+		for _, node := range group {
+			if name, ok := node.Property(PropertyK8sName); ok {
+				wave[name] = fmt.Sprint(i + 1)
+			}
+		}
+	}
+
+	if len(patches) != 4 {
+		t.Fatalf("Expected 4 patches, got %d", len(patches))
+	}
+	for _, patch := range patches {
+		var ops []k8sPatchOp
+		if err := json.Unmarshal([]byte(patch.Patch), &ops); err != nil {
+			t.Fatalf("Patch for %s is not valid JSON: %v", patch.Target.Name, err)
+		}
+		want := []k8sPatchOp{{Op: "add", Path: "/metadata/annotations/bom-dagger.io~1wave", Value: wave[patch.Target.Name]}}
+		if !reflect.DeepEqual(ops, want) {
+			t.Errorf("Unexpected patch for %s\nGot:  %v\nWant: %v", patch.Target.Name, ops, want)
+		}
+	}
+
+	if patches[0].Target != (K8sTarget{Kind: "StatefulSet", Name: "postgres", Namespace: "data"}) {
+		t.Errorf("Expected the first patch to target the database, got %+v", patches[0].Target)
+	}
+	if last := patches[len(patches)-1].Target; last != (K8sTarget{Kind: "Deployment", Name: "web"}) {
+		t.Errorf("Expected the last patch to target web without a namespace, got %+v", last)
+	}
+}
+
+func TestWriteK8sPatchesAnnotation(t *testing.T) {
+	g := loadFixture(t, "k8s-1.6.json")
+
+	var buf bytes.Buffer
+	if _, err := WriteK8sPatches(&buf, g, K8sPatchOptions{Annotation: "argocd.argoproj.io/sync-wave"}); err != nil {
+		t.Fatalf("WriteK8sPatches failed: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`/metadata/annotations/argocd.argoproj.io~1sync-wave`)) {
+		t.Errorf("Expected the custom annotation in the patch path, got:\n%s", buf.String())
+	}
+}
+
+func TestJSONPointerEscape(t *testing.T) {
+	tests := map[string]string{
+		"wave":               "wave",
+		"bom-dagger.io/wave": "bom-dagger.io~1wave",
+		"a~b/c":              "a~0b~1c",
+	}
+	for in, want := range tests {
+		if got := jsonPointerEscape(in); got != want {
+			t.Errorf("jsonPointerEscape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
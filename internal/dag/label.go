@@ -0,0 +1,89 @@
+package dag
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/nprimmer/bom-dagger/internal/ident"
+)
+
+// LabelTemplate renders a node label from a Go text/template. Writers escape
+// the rendered text for their output format, so templates produce plain text.
+type LabelTemplate struct {
+	tmpl *template.Template
+}
+
+// LabelData is the value a label template is executed against
+type LabelData struct {
+	Name    string
+	Version string
+	Ref     string
+	Type    string // Component type such as "library", or "service"
+	Purl    string
+
+	node *Node
+}
+
+// Prop returns the value of the named property, or "" when it is absent
+func (d LabelData) Prop(key string) string {
+	if d.node == nil {
+		return ""
+	}
+	value, _ := d.node.Property(key)
+	return value
+}
+
+// ParseLabelTemplate parses text as a label template. Besides syntax errors,
+// references to fields that LabelData does not have are reported here rather
+// than when the first node is rendered.
+func ParseLabelTemplate(text string) (*LabelTemplate, error) {
+	tmpl, err := template.New("label").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label template: %w", err)
+	}
+	if err := tmpl.Execute(io.Discard, LabelData{}); err != nil {
+		return nil, fmt.Errorf("invalid label template: %w", err)
+	}
+	return &LabelTemplate{tmpl: tmpl}, nil
+}
+
+// Label renders the template for node
+func (t *LabelTemplate) Label(node *Node) (string, error) {
+	return t.label(newLabelData(node, 0))
+}
+
+func (t *LabelTemplate) label(data LabelData) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering label for %s: %w", data.node.ID, err)
+	}
+	return b.String(), nil
+}
+
+// newLabelData describes node for a label, with the name, version, ref and
+// purl shortened to maxLen characters by ident.Shorten (0 = never)
+func newLabelData(node *Node, maxLen int) LabelData {
+	data := LabelData{
+		Name:    ident.Shorten(node.Name(), maxLen),
+		Version: ident.Shorten(node.Version(), maxLen),
+		Ref:     ident.Shorten(node.ID, maxLen),
+		Type:    nodeType(node),
+		node:    node,
+	}
+	if node.Component != nil {
+		data.Purl = ident.Shorten(node.Component.Purl, maxLen)
+	}
+	return data
+}
+
+// nodeLabel renders node with t, or returns fallback of its label data when
+// t is nil. Names, versions, refs and purls are shortened to maxLen.
+func nodeLabel(t *LabelTemplate, node *Node, fallback func(LabelData) string, maxLen int) (string, error) {
+	data := newLabelData(node, maxLen)
+	if t == nil {
+		return fallback(data), nil
+	}
+	return t.label(data)
+}
@@ -0,0 +1,234 @@
+package dag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// labelGraph returns a library with an awkward name that depends on a service
+func labelGraph(t *testing.T) *Graph {
+	t.Helper()
+
+	g := New()
+	if _, err := g.AddComponent(&sbom.Component{
+		BOMRef:     "lib",
+		Name:       `say "hi" <b>&\ #1`,
+		Version:    "1.2.3",
+		Type:       "library",
+		Purl:       "pkg:golang/example.com/lib@1.2.3",
+		Properties: []sbom.Property{{Name: "team", Value: "platform"}},
+	}); err != nil {
+		t.Fatalf("AddComponent failed: %v", err)
+	}
+	if _, err := g.AddService(&sbom.Service{BOMRef: "svc", Name: "Service", Version: "2.0"}); err != nil {
+		t.Fatalf("AddService failed: %v", err)
+	}
+	if err := g.AddDependency("lib", "svc"); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+	return g
+}
+
+func TestLabelTemplateFields(t *testing.T) {
+	g := labelGraph(t)
+
+	tests := []struct {
+		template string
+		ref      string
+		want     string
+	}{
+		{"{{.Name}}", "svc", "Service"},
+		{"{{.Version}}", "lib", "1.2.3"},
+		{"{{.Ref}}", "lib", "lib"},
+		{"{{.Type}}", "lib", "library"},
+		{"{{.Type}}", "svc", "service"},
+		{"{{.Purl}}", "lib", "pkg:golang/example.com/lib@1.2.3"},
+		{`{{.Prop "team"}}`, "lib", "platform"},
+		{`{{.Prop "team"}}`, "svc", ""},
+		{`{{with .Prop "team"}}{{.}}/{{end}}{{.Ref}}`, "svc", "svc"},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := ParseLabelTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("ParseLabelTemplate(%q) failed: %v", tt.template, err)
+		}
+		got, err := tmpl.Label(g.Nodes[tt.ref])
+		if err != nil {
+			t.Fatalf("Label failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("%s on %s: expected %q, got %q", tt.template, tt.ref, tt.want, got)
+		}
+	}
+}
+
+func TestParseLabelTemplateErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{{.Name", "label:1: unclosed action"},
+		{"{{.Name}}\n{{.Owner}}", "label:2:2"},
+		{"{{nosuchfunc .Name}}", `function "nosuchfunc" not defined`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseLabelTemplate(tt.template)
+		if err == nil {
+			t.Errorf("Expected error for %q", tt.template)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+		}
+	}
+}
+
+func TestWriteDOTLabelTemplate(t *testing.T) {
+	g := labelGraph(t)
+	tmpl, err := ParseLabelTemplate("{{.Name}}\n{{.Purl}}")
+	if err != nil {
+		t.Fatalf("ParseLabelTemplate failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDOTWithOptions(&buf, g, DOTOptions{Label: tmpl}); err != nil {
+		t.Fatalf("WriteDOTWithOptions failed: %v", err)
+	}
+	want := `  "lib" [label="say \"hi\" <b>&\\ #1\npkg:golang/example.com/lib@1.2.3"];`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected escaped label %s, got:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteDOTDefaultLabel(t *testing.T) {
+	g := labelGraph(t)
+
+	var buf bytes.Buffer
+	if err := WriteDOT(&buf, g); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+	for _, want := range []string{`"svc" [label="Service\n2.0"];`, `"lib" -> "svc";`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %s in output, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestWriteDOTShowTrust(t *testing.T) {
+	g := loadFixture(t, "trust-boundary-1.6.json")
+
+	var buf bytes.Buffer
+	if err := WriteDOTWithOptions(&buf, g, DOTOptions{ShowTrust: true}); err != nil {
+		t.Fatalf("WriteDOTWithOptions failed: %v", err)
+	}
+	for _, want := range []string{
+		`"payments" [label="Payment Gateway\n2024-06"` + trustBoundaryStyle + "];",
+		`"geoip" [label="GeoIP Lookup"` + trustBoundaryStyle + "];",
+		`"orders-api" [label="Orders API\n3.2.0"];`,
+		`"inventory" [label="Inventory Service\n1.8.0"];`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %s in output, got:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := WriteDOT(&buf, g); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "fillcolor") {
+		t.Errorf("Expected no trust styling without ShowTrust, got:\n%s", buf.String())
+	}
+}
+
+// TestWindowsPathNames checks refs and names built from Windows paths, with
+// both separators, in the DOT and Mermaid output
+func TestWindowsPathNames(t *testing.T) {
+	g := New()
+	if _, err := g.AddComponent(&sbom.Component{BOMRef: `file:C:\sboms/app.json`, Name: `C:\sboms/app\`, Version: "1.0"}); err != nil {
+		t.Fatalf("AddComponent failed: %v", err)
+	}
+	if _, err := g.AddComponent(&sbom.Component{BOMRef: `\\server\share\lib`, Name: "lib"}); err != nil {
+		t.Fatalf("AddComponent failed: %v", err)
+	}
+	if err := g.AddDependency(`file:C:\sboms/app.json`, `\\server\share\lib`); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDOT(&buf, g); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+	for _, want := range []string{
+		`"file:C:\\sboms/app.json" [label="C:\\sboms/app\\\n1.0"];`,
+		`"\\\\server\\share\\lib" [label="lib"];`,
+		`"file:C:\\sboms/app.json" -> "\\\\server\\share\\lib";`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %s in the DOT output, got:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := WriteMermaid(&buf, g, MermaidOptions{}); err != nil {
+		t.Fatalf("WriteMermaid failed: %v", err)
+	}
+	want := "flowchart BT\n" +
+		"    server_share_lib[\"lib\"]\n" +
+		"    file_C_sboms_app_json[\"C:\\sboms/app\\<br/>1.0\"]\n" +
+		"    file_C_sboms_app_json --> server_share_lib\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected Mermaid output\nGot:\n%s\nWant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	g := labelGraph(t)
+
+	var buf bytes.Buffer
+	if err := WriteMermaid(&buf, g, MermaidOptions{}); err != nil {
+		t.Fatalf("WriteMermaid failed: %v", err)
+	}
+	want := "flowchart BT\n" +
+		"    lib[\"say #quot;hi#quot; #lt;b#gt;#amp;\\ #35;1<br/>1.2.3\"]\n" +
+		"    svc[\"Service<br/>2.0\"]\n" +
+		"    lib --> svc\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected Mermaid output\nGot:\n%s\nWant:\n%s", buf.String(), want)
+	}
+
+	tmpl, err := ParseLabelTemplate(`{{.Ref}} ({{.Type}})`)
+	if err != nil {
+		t.Fatalf("ParseLabelTemplate failed: %v", err)
+	}
+	buf.Reset()
+	if err := WriteMermaid(&buf, g, MermaidOptions{Label: tmpl}); err != nil {
+		t.Fatalf("WriteMermaid failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `svc["svc (service)"]`) {
+		t.Errorf("Expected templated label, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteTreeLabelTemplate(t *testing.T) {
+	g := labelGraph(t)
+	tmpl, err := ParseLabelTemplate("{{.Name}}\n{{.Version}}")
+	if err != nil {
+		t.Fatalf("ParseLabelTemplate failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTree(&buf, g, TreeOptions{Label: tmpl}); err != nil {
+		t.Fatalf("WriteTree failed: %v", err)
+	}
+	want := "Service 2.0\n" +
+		"└── say \"hi\" <b>&\\ #1 1.2.3\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected tree\nGot:\n%s\nWant:\n%s", buf.String(), want)
+	}
+}
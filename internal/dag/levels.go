@@ -0,0 +1,119 @@
+package dag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// levels runs Kahn's algorithm and returns the nodes of each level, ordered
+// by LessWithinLevel, with nodes pinned late moved by pinLate
+func (g *Graph) levels() ([][]*Node, error) {
+	levels, err := g.kahn(dependencies, dependents)
+	if err != nil {
+		return nil, err
+	}
+	return g.pinLate(levels), nil
+}
+
+// teardownLevels runs Kahn's algorithm over the reversed edges, which is not
+// the deployment levels reversed; pins are ignored
+func (g *Graph) teardownLevels() ([][]*Node, error) {
+	return g.kahn(dependents, dependencies)
+}
+
+func dependencies(node *Node) []*Node { return node.Dependencies }
+func dependents(node *Node) []*Node   { return node.Dependents }
+
+// kahn levels the graph where before(n) lists the nodes that must come
+// before n and after(n) the nodes waiting for it
+func (g *Graph) kahn(before, after func(*Node) []*Node) ([][]*Node, error) {
+	// Remaining predecessors per node, keyed by pointer to skip hashing refs
+	inDegree := make(map[*Node]int, len(g.Nodes))
+	var current, next []*Node
+	for _, node := range g.Nodes {
+		inDegree[node] = len(before(node))
+		if inDegree[node] == 0 {
+			current = append(current, node)
+		}
+	}
+
+	all := make([]*Node, 0, len(g.Nodes))
+	var levels [][]*Node
+	for len(current) > 0 {
+		g.sortLevel(current)
+		start := len(all)
+		all = append(all, current...)
+		levels = append(levels, all[start:len(all):len(all)])
+
+		next = next[:0]
+		for _, node := range current {
+			// Reduce in-degree for the nodes waiting on this one
+			for _, waiting := range after(node) {
+				inDegree[waiting]--
+				if inDegree[waiting] == 0 {
+					next = append(next, waiting)
+				}
+			}
+		}
+		current, next = next, current
+	}
+
+	// Check if all nodes were processed
+	if len(all) != len(g.Nodes) {
+		return nil, fmt.Errorf("cycle detected in dependency graph")
+	}
+	return levels, nil
+}
+
+// LevelStats counts the nodes of one deployment level, i.e. how wide that
+// wave of the deployment is
+type LevelStats struct {
+	Level      int            // 1-based, the deployment step
+	Components int            // Component nodes, including external placeholders
+	Services   int            // Service nodes
+	Ecosystems map[string]int // Nodes per purl type (see Node.Ecosystem); "" counts nodes without a purl
+}
+
+// Total returns the number of nodes in the level
+func (s LevelStats) Total() int {
+	return s.Components + s.Services
+}
+
+// LevelBreakdown returns the number of components and services, and of nodes
+// per purl ecosystem, in every deployment level
+func (g *Graph) LevelBreakdown() ([]LevelStats, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	levels, err := g.levels()
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]LevelStats, len(levels))
+	for i, level := range levels {
+		stats[i] = LevelStats{Level: i + 1, Ecosystems: make(map[string]int)}
+		for _, node := range level {
+			if node.Service != nil {
+				stats[i].Services++
+			} else {
+				stats[i].Components++
+			}
+			stats[i].Ecosystems[node.Ecosystem()]++
+		}
+	}
+	return stats, nil
+}
+
+// Ecosystem returns the package-URL type of the node's component, such as npm
+// or docker, or "" when it has no purl
+func (n *Node) Ecosystem() string {
+	if n.Component == nil {
+		return ""
+	}
+	rest, ok := strings.CutPrefix(n.Component.Purl, "pkg:")
+	if !ok {
+		return ""
+	}
+	ecosystem, _, _ := strings.Cut(strings.TrimLeft(rest, "/"), "/")
+	return strings.ToLower(ecosystem)
+}
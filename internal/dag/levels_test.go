@@ -0,0 +1,167 @@
+package dag
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestLevelBreakdown(t *testing.T) {
+	stats, err := loadFixture(t, "microservices-1.6.json").LevelBreakdown()
+	if err != nil {
+		t.Fatalf("LevelBreakdown failed: %v", err)
+	}
+
+	want := []LevelStats{
+		{Level: 1, Components: 6, Ecosystems: map[string]int{"docker": 2, "": 4}},
+		{Level: 2, Components: 5, Services: 3, Ecosystems: map[string]int{"docker": 1, "": 7}},
+		{Level: 3, Services: 3, Ecosystems: map[string]int{"": 3}},
+		{Level: 4, Services: 3, Ecosystems: map[string]int{"": 3}},
+		{Level: 5, Services: 1, Ecosystems: map[string]int{"": 1}},
+		{Level: 6, Components: 2, Ecosystems: map[string]int{"npm": 1, "": 1}},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("LevelBreakdown() =\n%+v\nwant\n%+v", stats, want)
+	}
+
+	// The levels are the deployment groups
+	groups, err := loadFixture(t, "microservices-1.6.json").GetDeploymentGroups()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroups failed: %v", err)
+	}
+	for i, group := range groups {
+		if len(group) != stats[i].Total() {
+			t.Errorf("Level %d: %d nodes, but group %d has %d", i+1, stats[i].Total(), i+1, len(group))
+		}
+	}
+}
+
+func TestLevelsDoNotShareCapacity(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+	levels, err := g.levels()
+	if err != nil {
+		t.Fatalf("levels failed: %v", err)
+	}
+	want := make([][]string, len(levels))
+	for i, level := range levels {
+		want[i] = nodeIDs(level)
+	}
+
+	// The levels are windows of one queue; growing one must not write over
+	// the level after it
+	for i := range levels {
+		levels[i] = append(levels[i], &Node{ID: "appended"})
+	}
+	for i, level := range levels {
+		if got := nodeIDs(level[:len(level)-1]); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("Level %d changed after appending to the others: %v, want %v", i+1, got, want[i])
+		}
+	}
+}
+
+// fanOutGraph has one root with n dependents, each with a dependent of its
+// own: three levels of 1, n and n nodes
+func fanOutGraph(tb testing.TB, n int) *Graph {
+	tb.Helper()
+
+	bom := &sbom.CycloneDX{Components: []sbom.Component{{BOMRef: "root", Name: "Root"}}}
+	for i := 0; i < n; i++ {
+		mid, top := fmt.Sprintf("mid-%05d", i), fmt.Sprintf("top-%05d", i)
+		bom.Components = append(bom.Components, sbom.Component{BOMRef: mid, Name: mid}, sbom.Component{BOMRef: top, Name: top})
+		bom.Dependencies = append(bom.Dependencies,
+			sbom.Dependency{Ref: mid, DependsOn: []string{"root"}},
+			sbom.Dependency{Ref: top, DependsOn: []string{mid}})
+	}
+	componentMap := make(map[string]*sbom.Component, len(bom.Components))
+	for i := range bom.Components {
+		componentMap[bom.Components[i].BOMRef] = &bom.Components[i]
+	}
+	g := New()
+	if err := g.BuildFromSBOM(bom, componentMap); err != nil {
+		tb.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	return g
+}
+
+// TestLevelsFanOut guards the queue handling against a wide level: the
+// dependents queued while iterating one level must not show up in it, and
+// every level must hold exactly its own nodes
+func TestLevelsFanOut(t *testing.T) {
+	const n = 5000
+	levels, err := fanOutGraph(t, n).levels()
+	if err != nil {
+		t.Fatalf("levels failed: %v", err)
+	}
+	if len(levels) != 3 || len(levels[0]) != 1 || len(levels[1]) != n || len(levels[2]) != n {
+		t.Fatalf("Expected levels of 1, %d and %d nodes, got %d levels", n, n, len(levels))
+	}
+	if levels[0][0].ID != "root" {
+		t.Errorf("Expected root alone in level 1, got %s", levels[0][0].ID)
+	}
+	for i, prefix := range []string{"mid", "top"} {
+		for j, node := range levels[i+1] {
+			if want := fmt.Sprintf("%s-%05d", prefix, j); node.ID != want {
+				t.Fatalf("Level %d holds %s at %d, want %s", i+2, node.ID, j, want)
+			}
+		}
+	}
+}
+
+func TestLevelsAllocationsScaleLinearly(t *testing.T) {
+	allocated := func(n int) uint64 {
+		g := fanOutGraph(t, n)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if _, err := g.levels(); err != nil {
+			t.Fatal(err)
+		}
+		runtime.ReadMemStats(&after)
+		return after.TotalAlloc - before.TotalAlloc
+	}
+	// Ten times the nodes and edges should allocate about ten times as much;
+	// copying the queue per level would make it a hundred
+	small, large := allocated(1000), allocated(10000)
+	if large > 20*small {
+		t.Errorf("Expected allocations linear in the fan-out, got %d bytes for 1000 and %d for 10000", small, large)
+	}
+}
+
+func BenchmarkLevelsFanOut(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		g := fanOutGraph(b, n)
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := g.levels(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestEcosystem(t *testing.T) {
+	tests := []struct {
+		purl string
+		want string
+	}{
+		{"pkg:npm/%40angular/core@17.0.0", "npm"},
+		{"pkg:docker/library/postgres@15", "docker"},
+		{"pkg:/Maven/org.example/lib@1.0", "maven"},
+		{"pkg:generic", "generic"},
+		{"", ""},
+		{"not-a-purl", ""},
+	}
+	for _, tt := range tests {
+		node := &Node{Component: &sbom.Component{Purl: tt.purl}}
+		if got := node.Ecosystem(); got != tt.want {
+			t.Errorf("Ecosystem() for %q = %q, want %q", tt.purl, got, tt.want)
+		}
+	}
+	if got := (&Node{Service: &sbom.Service{}}).Ecosystem(); got != "" {
+		t.Errorf("Expected no ecosystem for a service, got %q", got)
+	}
+}
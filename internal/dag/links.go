@@ -0,0 +1,29 @@
+package dag
+
+import "github.com/nprimmer/bom-dagger/internal/sbom"
+
+// LinkTypeDocumentation is the default external reference type linked from
+// plans, typically a README or runbook; see Node.Links
+const LinkTypeDocumentation = "documentation"
+
+// Links returns the URLs of the node's external references of type linkType
+// (LinkTypeDocumentation when empty), in declaration order
+func (n *Node) Links(linkType string) []string {
+	if linkType == "" {
+		linkType = LinkTypeDocumentation
+	}
+	var refs []sbom.ExternalReference
+	switch {
+	case n.Component != nil:
+		refs = n.Component.ExternalReferences
+	case n.Service != nil:
+		refs = n.Service.ExternalReferences
+	}
+	var links []string
+	for _, ref := range refs {
+		if ref.Type == linkType && ref.URL != "" {
+			links = append(links, ref.URL)
+		}
+	}
+	return links
+}
@@ -0,0 +1,61 @@
+package dag
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	g := loadFixture(t, "links-1.6.json")
+
+	tests := []struct {
+		ref      string
+		linkType string
+		want     []string
+	}{
+		{"checkout", "", []string{"https://wiki.example.com/runbooks/checkout", "https://wiki.example.com/checkout/README"}},
+		{"checkout", "documentation", []string{"https://wiki.example.com/runbooks/checkout", "https://wiki.example.com/checkout/README"}},
+		{"checkout", "vcs", []string{"https://git.example.com/shop/checkout"}},
+		{"checkout", "website", []string{"https://shop.example.com"}},
+		{"checkout", "issue-tracker", nil},
+		{"payments", "", []string{"https://wiki.example.com/runbooks/payments"}},
+		{"orders-db", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref+"/"+tt.linkType, func(t *testing.T) {
+			if got := g.Nodes[tt.ref].Links(tt.linkType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Links(%q) = %q, want %q", tt.linkType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteDOTLinks(t *testing.T) {
+	g := loadFixture(t, "links-1.6.json")
+
+	tests := []struct {
+		linkType string
+		want     string
+	}{
+		{"", `"checkout" [label="Checkout\n3.1.0", URL="https://wiki.example.com/runbooks/checkout"];`},
+		{"vcs", `"checkout" [label="Checkout\n3.1.0", URL="https://git.example.com/shop/checkout"];`},
+		{"website", `"checkout" [label="Checkout\n3.1.0", URL="https://shop.example.com"];`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.linkType, func(t *testing.T) {
+			g.LinkType = tt.linkType
+			var buf bytes.Buffer
+			if err := WriteDOT(&buf, g); err != nil {
+				t.Fatalf("WriteDOT failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("Expected %s in output, got:\n%s", tt.want, buf.String())
+			}
+			if strings.Count(buf.String(), "URL=") != map[string]int{"": 2, "vcs": 1, "website": 1}[tt.linkType] {
+				t.Errorf("Expected only nodes with %q references to link, got:\n%s", tt.linkType, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package dag
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/ident"
+)
+
+// MermaidOptions controls WriteMermaid
+type MermaidOptions struct {
+	// Label renders node labels; nil uses the name and version
+	Label *LabelTemplate
+	// ClusterBy wraps nodes in a subgraph per group or step
+	ClusterBy ClusterBy
+	// MaxLabelLen shortens node IDs and the names, versions and refs in
+	// labels longer than this many characters (0 = never; see ident.Shorten)
+	MaxLabelLen int
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, node IDs sanitized by
+// ident.MermaidID and clusters numbered c1, c2, ...
+func WriteMermaid(w io.Writer, g *Graph, opts MermaidOptions) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var b strings.Builder
+	b.WriteString("flowchart BT\n")
+
+	clusters, err := g.clusters(opts.ClusterBy)
+	if err != nil {
+		return err
+	}
+
+	nodes := g.sortedNodes()
+	namer := ident.NewNamer(ident.MermaidID)
+	if opts.ClusterBy != ClusterNone {
+		for i := range clusters {
+			namer.Reserve(fmt.Sprintf("c%d", i+1))
+		}
+	}
+	ids := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		ids[node.ID] = namer.NameFor(node.ID, ident.Shorten(node.ID, opts.MaxLabelLen))
+	}
+	for i, c := range clusters {
+		indent := "    "
+		if opts.ClusterBy != ClusterNone {
+			fmt.Fprintf(&b, "    subgraph c%d[\"%s\"]\n", i+1, mermaidEscape(ident.Shorten(c.label, opts.MaxLabelLen)))
+			indent = "        "
+		}
+		for _, node := range c.nodes {
+			label, err := nodeLabel(opts.Label, node, nameAndVersion, opts.MaxLabelLen)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "%s%s[\"%s\"]\n", indent, ids[node.ID], mermaidEscape(label))
+		}
+		if opts.ClusterBy != ClusterNone {
+			b.WriteString("    end\n")
+		}
+	}
+
+	for _, edge := range g.edges() {
+		fmt.Fprintf(&b, "    %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+var mermaidEscaper = strings.NewReplacer(
+	"#", "#35;",
+	`"`, "#quot;",
+	"&", "#amp;",
+	"<", "#lt;",
+	">", "#gt;",
+	"\r\n", "<br/>",
+	"\n", "<br/>",
+	"\r", "<br/>",
+)
+
+// mermaidEscape replaces characters that end or alter a quoted Mermaid label
+// with entity codes and line breaks with <br/>
+func mermaidEscape(s string) string {
+	return mermaidEscaper.Replace(s)
+}
@@ -0,0 +1,35 @@
+package dag
+
+// Metrics are counts describing the health of a graph, for tracking SBOMs
+// over time
+type Metrics struct {
+	Components   int  // Nodes, services and external placeholders included
+	Edges        int  // Dependency edges
+	Groups       int  // Deployment groups; 0 when the graph has a cycle
+	MaxDepth     int  // Edges on the longest dependency chain; 0 when the graph has a cycle
+	DanglingRefs int  // dependsOn targets naming no component or service, skipped or synthesized
+	Cycles       bool // Whether the graph has a cycle
+}
+
+// Metrics computes the graph's Metrics. Unlike planning it also works on a
+// graph left with a cycle by a failed build, which it reports.
+func (g *Graph) Metrics() Metrics {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	m := Metrics{Components: len(g.Nodes), DanglingRefs: len(g.Unresolved)}
+	for _, node := range g.Nodes {
+		m.Edges += len(node.Dependencies)
+		if node.Synthetic {
+			m.DanglingRefs++
+		}
+	}
+	levels, err := g.levels()
+	if err != nil {
+		m.Cycles = true
+		return m
+	}
+	m.Groups = len(levels)
+	m.MaxDepth = max(len(levels)-1, 0)
+	return m
+}
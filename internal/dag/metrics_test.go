@@ -0,0 +1,40 @@
+package dag
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/parser"
+)
+
+func TestMetrics(t *testing.T) {
+	build := func(t *testing.T, name string, opts BuildOptions) *Graph {
+		p := parser.New()
+		bom, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", name))
+		if err != nil {
+			t.Fatalf("ParseFile failed: %v", err)
+		}
+		g := New()
+		// The cycle fixture fails to build but leaves a graph to measure
+		_ = g.BuildFromSBOMWithOptions(bom, p.GetComponentMap(bom), opts)
+		return g
+	}
+
+	tests := []struct {
+		fixture string
+		opts    BuildOptions
+		want    Metrics
+	}{
+		{"diamond-1.6.json", BuildOptions{}, Metrics{Components: 4, Edges: 4, Groups: 3, MaxDepth: 2}},
+		{"missing-ref-1.6.json", BuildOptions{}, Metrics{Components: 3, Edges: 2, Groups: 3, MaxDepth: 2, DanglingRefs: 1}},
+		{"missing-ref-1.6.json", BuildOptions{SynthesizeMissing: true}, Metrics{Components: 4, Edges: 4, Groups: 3, MaxDepth: 2, DanglingRefs: 1}},
+		{"cycle-1.6.json", BuildOptions{}, Metrics{Components: 3, Edges: 3, Cycles: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			if got := build(t, tt.fixture, tt.opts).Metrics(); got != tt.want {
+				t.Errorf("Metrics() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
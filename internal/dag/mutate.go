@@ -0,0 +1,161 @@
+package dag
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// Errors returned by the graph mutation API. Use errors.Is to test for them.
+var (
+	ErrMissingRef    = errors.New("missing bom-ref")
+	ErrDuplicateNode = errors.New("node already exists")
+	ErrUnknownNode   = errors.New("unknown node")
+	ErrDuplicateEdge = errors.New("dependency already exists")
+	ErrCycle         = errors.New("dependency would introduce a cycle")
+)
+
+// AddComponent adds a node for a component keyed by its bom-ref
+func (g *Graph) AddComponent(c *sbom.Component) (*Node, error) {
+	if c == nil || c.BOMRef == "" {
+		return nil, ErrMissingRef
+	}
+	return g.addNode(&Node{ID: c.BOMRef, Component: c})
+}
+
+// AddService adds a node for a service keyed by its bom-ref
+func (g *Graph) AddService(s *sbom.Service) (*Node, error) {
+	if s == nil || s.BOMRef == "" {
+		return nil, ErrMissingRef
+	}
+	return g.addNode(&Node{ID: s.BOMRef, Service: s})
+}
+
+func (g *Graph) addNode(node *Node) (*Node, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.Nodes[node.ID]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateNode, node.ID)
+	}
+
+	node.Dependencies = []*Node{}
+	node.Dependents = []*Node{}
+	g.ensureNodes()
+	g.Nodes[node.ID] = node
+	g.Roots = append(g.Roots, node)
+	return node, nil
+}
+
+// AddDependency records that fromRef depends on toRef. Unless
+// DeferCycleChecks is set, edges that would introduce a cycle are refused.
+func (g *Graph) AddDependency(fromRef, toRef string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	node, exists := g.Nodes[fromRef]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownNode, fromRef)
+	}
+	depNode, exists := g.Nodes[toRef]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownNode, toRef)
+	}
+	if g.hasEdge(node, depNode) {
+		return fmt.Errorf("%w: %s -> %s", ErrDuplicateEdge, fromRef, toRef)
+	}
+	if !g.DeferCycleChecks && (node == depNode || g.dependsOn(depNode, node)) {
+		return fmt.Errorf("%w: %s -> %s", ErrCycle, fromRef, toRef)
+	}
+
+	if len(node.Dependencies) == 0 {
+		g.removeRoot(node)
+	}
+	node.Dependencies = append(node.Dependencies, depNode)
+	depNode.Dependents = append(depNode.Dependents, node)
+	return nil
+}
+
+// RemoveNode removes a node and detaches all of its edges. Dependents left
+// without dependencies become roots.
+func (g *Graph) RemoveNode(ref string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.removeNode(ref)
+}
+
+func (g *Graph) removeNode(ref string) error {
+	node, exists := g.Nodes[ref]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownNode, ref)
+	}
+
+	for _, dep := range node.Dependencies {
+		dep.Dependents = removeNodeFrom(dep.Dependents, node)
+	}
+	for _, dependent := range node.Dependents {
+		dependent.Dependencies = removeNodeFrom(dependent.Dependencies, node)
+		if len(dependent.Dependencies) == 0 && dependent != node {
+			g.Roots = append(g.Roots, dependent)
+		}
+	}
+
+	g.removeRoot(node)
+	delete(g.Nodes, ref)
+	for edge := range g.inferred {
+		if edge.From == ref || edge.To == ref {
+			delete(g.inferred, edge)
+		}
+	}
+	return nil
+}
+
+// CheckCycles returns an error if the graph contains a cycle. Call it after
+// adding edges with DeferCycleChecks set.
+func (g *Graph) CheckCycles() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.checkCycles()
+}
+
+func (g *Graph) checkCycles() error {
+	if g.hasCycle() {
+		return fmt.Errorf("dependency graph contains cycles")
+	}
+	return nil
+}
+
+// dependsOn reports whether from transitively depends on to
+func (g *Graph) dependsOn(from, to *Node) bool {
+	visited := make(map[*Node]bool)
+	stack := []*Node{from}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if current == to {
+			return true
+		}
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		stack = append(stack, current.Dependencies...)
+	}
+	return false
+}
+
+func (g *Graph) removeRoot(node *Node) {
+	g.Roots = removeNodeFrom(g.Roots, node)
+}
+
+// removeNodeFrom returns nodes without any occurrence of target
+func removeNodeFrom(nodes []*Node, target *Node) []*Node {
+	result := nodes[:0]
+	for _, n := range nodes {
+		if n != target {
+			result = append(result, n)
+		}
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+package dag
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func rootIDs(g *Graph) map[string]bool {
+	ids := make(map[string]bool)
+	for _, root := range g.Roots {
+		ids[root.ID] = true
+	}
+	return ids
+}
+
+func TestAddComponentAndService(t *testing.T) {
+	g := New()
+
+	node, err := g.AddComponent(&sbom.Component{BOMRef: "comp", Name: "Component"})
+	if err != nil {
+		t.Fatalf("AddComponent failed: %v", err)
+	}
+	if node.Component == nil || g.Nodes["comp"] != node {
+		t.Error("Component node not registered")
+	}
+
+	svc, err := g.AddService(&sbom.Service{BOMRef: "svc", Name: "Service"})
+	if err != nil {
+		t.Fatalf("AddService failed: %v", err)
+	}
+	if svc.Service == nil || svc.Kind() != "service" {
+		t.Error("Service node not registered as a service")
+	}
+
+	if len(g.Roots) != 2 {
+		t.Errorf("Expected new nodes to be roots, got %d roots", len(g.Roots))
+	}
+
+	if _, err := g.AddComponent(&sbom.Component{BOMRef: "comp", Name: "Again"}); !errors.Is(err, ErrDuplicateNode) {
+		t.Errorf("Expected ErrDuplicateNode, got %v", err)
+	}
+	if _, err := g.AddService(&sbom.Service{BOMRef: "comp", Name: "Clash"}); !errors.Is(err, ErrDuplicateNode) {
+		t.Errorf("Expected ErrDuplicateNode for service clashing with component, got %v", err)
+	}
+	if _, err := g.AddComponent(&sbom.Component{Name: "No Ref"}); !errors.Is(err, ErrMissingRef) {
+		t.Errorf("Expected ErrMissingRef, got %v", err)
+	}
+	if _, err := g.AddService(nil); !errors.Is(err, ErrMissingRef) {
+		t.Errorf("Expected ErrMissingRef for nil service, got %v", err)
+	}
+}
+
+func TestAddDependency(t *testing.T) {
+	g := New()
+	for _, ref := range []string{"a", "b", "c"} {
+		if _, err := g.AddComponent(&sbom.Component{BOMRef: ref, Name: ref}); err != nil {
+			t.Fatalf("AddComponent failed: %v", err)
+		}
+	}
+
+	if err := g.AddDependency("a", "b"); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+	if err := g.AddDependency("b", "c"); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+
+	if len(g.Nodes["b"].Dependents) != 1 || g.Nodes["b"].Dependents[0].ID != "a" {
+		t.Error("Reverse edge not maintained")
+	}
+	if roots := rootIDs(g); len(roots) != 1 || !roots["c"] {
+		t.Errorf("Expected c as the only root, got %v", roots)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     error
+	}{
+		{"duplicate edge", "a", "b", ErrDuplicateEdge},
+		{"unknown from", "missing", "a", ErrUnknownNode},
+		{"unknown to", "a", "missing", ErrUnknownNode},
+		{"self edge", "a", "a", ErrCycle},
+		{"transitive cycle", "c", "a", ErrCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := g.AddDependency(tt.from, tt.to); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if g.GetEdgeCount() != 2 {
+		t.Errorf("Rejected edges must not be added, got %d edges", g.GetEdgeCount())
+	}
+}
+
+func TestAddDependencyDeferredCycleCheck(t *testing.T) {
+	g := New()
+	g.DeferCycleChecks = true
+	for _, ref := range []string{"a", "b"} {
+		if _, err := g.AddComponent(&sbom.Component{BOMRef: ref, Name: ref}); err != nil {
+			t.Fatalf("AddComponent failed: %v", err)
+		}
+	}
+
+	if err := g.AddDependency("a", "b"); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+	if err := g.AddDependency("b", "a"); err != nil {
+		t.Fatalf("Deferred cycle check should accept the edge, got %v", err)
+	}
+	if err := g.CheckCycles(); err == nil {
+		t.Error("Expected CheckCycles to report the cycle")
+	}
+}
+
+func TestRemoveNode(t *testing.T) {
+	g := createTestGraph()
+
+	// cache depends on mq; app depends on cache and db
+	if err := g.RemoveNode("mq"); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if _, exists := g.Nodes["mq"]; exists {
+		t.Error("mq should have been removed")
+	}
+	if len(g.Nodes["cache"].Dependencies) != 0 {
+		t.Error("cache should have lost its dependency on mq")
+	}
+	if roots := rootIDs(g); len(roots) != 2 || !roots["db"] || !roots["cache"] {
+		t.Errorf("Expected db and cache as roots, got %v", roots)
+	}
+
+	if err := g.RemoveNode("cache"); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if len(g.Nodes["app"].Dependencies) != 1 || g.Nodes["app"].Dependencies[0].ID != "db" {
+		t.Error("app should only depend on db")
+	}
+	if roots := rootIDs(g); len(roots) != 1 || !roots["db"] {
+		t.Errorf("Expected db as the only root, got %v", roots)
+	}
+
+	if err := g.RemoveNode("cache"); !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expected ErrUnknownNode, got %v", err)
+	}
+
+	order, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+	if len(order) != 2 {
+		t.Errorf("Expected 2 items after removals, got %d", len(order))
+	}
+}
@@ -0,0 +1,63 @@
+package dag
+
+import "fmt"
+
+// Direction selects the edges Neighborhood follows
+type Direction int
+
+const (
+	// DirectionDependencies follows the edges to what a node depends on
+	DirectionDependencies Direction = iota
+	// DirectionDependents follows the edges to what depends on a node, i.e.
+	// what a failure of the node would affect
+	DirectionDependents
+)
+
+// Neighborhood returns the nodes within depth edges of ref in direction dir,
+// closest first; a negative depth follows the edges to the end
+func (g *Graph) Neighborhood(ref string, depth int, dir Direction) ([]*Node, error) {
+	levels, err := g.NeighborhoodLevels(ref, depth, dir)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []*Node
+	for _, level := range levels {
+		nodes = append(nodes, level...)
+	}
+	return nodes, nil
+}
+
+// NeighborhoodLevels is Neighborhood with the nodes split by depth: level i
+// holds the nodes first reached after i edges, sorted by ID
+func (g *Graph) NeighborhoodLevels(ref string, depth int, dir Direction) ([][]*Node, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	start, ok := g.Nodes[ref]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownNode, ref)
+	}
+
+	seen := map[*Node]bool{start: true}
+	levels := [][]*Node{{start}}
+	for d := 1; depth < 0 || d <= depth; d++ {
+		var next []*Node
+		for _, node := range levels[len(levels)-1] {
+			neighbors := node.Dependencies
+			if dir == DirectionDependents {
+				neighbors = node.Dependents
+			}
+			for _, neighbor := range neighbors {
+				if !seen[neighbor] {
+					seen[neighbor] = true
+					next = append(next, neighbor)
+				}
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		levels = append(levels, sortedByID(next))
+	}
+	return levels, nil
+}
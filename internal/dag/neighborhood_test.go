@@ -0,0 +1,87 @@
+package dag
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNeighborhoodLevels(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+	ids := func(levels [][]*Node) [][]string {
+		var out [][]string
+		for _, level := range levels {
+			var refs []string
+			for _, node := range level {
+				refs = append(refs, node.ID)
+			}
+			out = append(out, refs)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		ref   string
+		depth int
+		dir   Direction
+		want  [][]string
+	}{
+		{"node itself", "order-service", 0, DirectionDependencies, [][]string{{"order-service"}}},
+		{"direct dependencies", "order-service", 1, DirectionDependencies, [][]string{
+			{"order-service"},
+			{"kafka", "notification-service", "payment-service", "postgres-primary"},
+		}},
+		{"every dependency", "order-service", -1, DirectionDependencies, [][]string{
+			{"order-service"},
+			{"kafka", "notification-service", "payment-service", "postgres-primary"},
+			{"redis-master", "zookeeper"},
+		}},
+		{"depth past the end", "order-service", 10, DirectionDependencies, [][]string{
+			{"order-service"},
+			{"kafka", "notification-service", "payment-service", "postgres-primary"},
+			{"redis-master", "zookeeper"},
+		}},
+		// api-gateway is two hops away through order-service and three
+		// through user-service, and is listed once at two
+		{"dependents", "kafka", 3, DirectionDependents, [][]string{
+			{"kafka"},
+			{"analytics-service", "notification-service", "order-service", "payment-service"},
+			{"api-gateway", "recommendation-service", "user-service"},
+			{"frontend-mobile", "frontend-web"},
+		}},
+		{"two hops of dependents", "kafka", 2, DirectionDependents, [][]string{
+			{"kafka"},
+			{"analytics-service", "notification-service", "order-service", "payment-service"},
+			{"api-gateway", "recommendation-service", "user-service"},
+		}},
+		{"leaf", "zookeeper", -1, DirectionDependencies, [][]string{{"zookeeper"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels, err := g.NeighborhoodLevels(tt.ref, tt.depth, tt.dir)
+			if err != nil {
+				t.Fatalf("NeighborhoodLevels failed: %v", err)
+			}
+			if got := ids(levels); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+
+			nodes, err := g.Neighborhood(tt.ref, tt.depth, tt.dir)
+			if err != nil {
+				t.Fatalf("Neighborhood failed: %v", err)
+			}
+			var flat []*Node
+			for _, level := range levels {
+				flat = append(flat, level...)
+			}
+			if !reflect.DeepEqual(nodes, flat) {
+				t.Errorf("Expected Neighborhood to list the levels in order, got %v", nodes)
+			}
+		})
+	}
+
+	if _, err := g.Neighborhood("missing", 1, DirectionDependencies); !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expected ErrUnknownNode, got %v", err)
+	}
+}
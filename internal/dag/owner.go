@@ -0,0 +1,30 @@
+package dag
+
+import "strings"
+
+// PropertyTeam names the team owning a node. It is the default owner
+// property; see Node.Owner.
+const PropertyTeam = "bom-dagger:team"
+
+// Unowned is the owner of nodes with neither an owner property nor a
+// supplier
+const Unowned = "unowned"
+
+// Owner returns who owns the node: the value of property (PropertyTeam when
+// empty), else the name of the component's supplier or the service's
+// provider, else Unowned
+func (n *Node) Owner(property string) string {
+	if property == "" {
+		property = PropertyTeam
+	}
+	if owner, ok := n.Property(property); ok && strings.TrimSpace(owner) != "" {
+		return strings.TrimSpace(owner)
+	}
+	if n.Component != nil && n.Component.Supplier != nil && n.Component.Supplier.Name != "" {
+		return n.Component.Supplier.Name
+	}
+	if n.Service != nil && n.Service.Provider != nil && n.Service.Provider.Name != "" {
+		return n.Service.Provider.Name
+	}
+	return Unowned
+}
@@ -0,0 +1,39 @@
+package dag
+
+import (
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestOwner(t *testing.T) {
+	team := func(name, value string) []sbom.Property {
+		return []sbom.Property{{Name: name, Value: value}}
+	}
+	tests := []struct {
+		name     string
+		node     *Node
+		property string
+		want     string
+	}{
+		{"team property", &Node{Component: &sbom.Component{Properties: team(PropertyTeam, "payments-team")}}, "", "payments-team"},
+		{"property wins over the supplier", &Node{Component: &sbom.Component{
+			Supplier: &sbom.Supplier{Name: "Acme"}, Properties: team(PropertyTeam, "payments-team")}}, "", "payments-team"},
+		{"custom property", &Node{Component: &sbom.Component{Properties: team("owner", " platform ")}}, "owner", "platform"},
+		{"other property ignored", &Node{Component: &sbom.Component{Properties: team(PropertyTeam, "payments-team")}}, "owner", Unowned},
+		{"blank property falls back", &Node{Component: &sbom.Component{
+			Supplier: &sbom.Supplier{Name: "Acme"}, Properties: team(PropertyTeam, " ")}}, "", "Acme"},
+		{"supplier", &Node{Component: &sbom.Component{Supplier: &sbom.Supplier{Name: "Acme"}}}, "", "Acme"},
+		{"service provider", &Node{Service: &sbom.Service{Provider: &sbom.Supplier{Name: "Acme Payments"}}}, "", "Acme Payments"},
+		{"nameless supplier", &Node{Component: &sbom.Component{Supplier: &sbom.Supplier{URL: []string{"https://acme.example"}}}}, "", Unowned},
+		{"nothing", &Node{Component: &sbom.Component{}}, "", Unowned},
+		{"synthetic", &Node{Synthetic: true}, "", Unowned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.Owner(tt.property); got != tt.want {
+				t.Errorf("Owner(%q) = %q, want %q", tt.property, got, tt.want)
+			}
+		})
+	}
+}
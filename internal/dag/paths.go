@@ -0,0 +1,49 @@
+package dag
+
+import "fmt"
+
+// Paths returns the dependency paths from one node to another in bom-ref
+// order, at most limit when above zero; none means their order is incidental
+func (g *Graph) Paths(from, to string, limit int) ([][]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	start, ok := g.Nodes[from]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownNode, from)
+	}
+	target, ok := g.Nodes[to]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownNode, to)
+	}
+
+	var paths [][]string
+	var path []string
+	onPath := make(map[*Node]bool)
+
+	var visit func(node *Node) bool
+	visit = func(node *Node) bool {
+		path = append(path, node.ID)
+		defer func() { path = path[:len(path)-1] }()
+
+		if node == target {
+			paths = append(paths, append([]string{}, path...))
+			return limit > 0 && len(paths) >= limit
+		}
+
+		onPath[node] = true
+		defer delete(onPath, node)
+		for _, dep := range sortedByID(node.Dependencies) {
+			if onPath[dep] {
+				continue
+			}
+			if visit(dep) {
+				return true
+			}
+		}
+		return false
+	}
+	visit(start)
+
+	return paths, nil
+}
@@ -0,0 +1,49 @@
+package dag
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	g := loadFixture(t, "diamond-1.6.json")
+
+	tests := []struct {
+		name  string
+		from  string
+		to    string
+		limit int
+		want  [][]string
+	}{
+		{"diamond", "app", "db", 0, [][]string{{"app", "api-a", "db"}, {"app", "api-b", "db"}}},
+		{"limited", "app", "db", 1, [][]string{{"app", "api-a", "db"}}},
+		{"direct", "api-a", "db", 0, [][]string{{"api-a", "db"}}},
+		{"reverse direction", "db", "app", 0, nil},
+		{"siblings", "api-a", "api-b", 0, nil},
+		{"same node", "db", "db", 0, [][]string{{"db"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := g.Paths(tt.from, tt.to, tt.limit)
+			if err != nil {
+				t.Fatalf("Paths failed: %v", err)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, paths)
+			}
+		})
+	}
+}
+
+func TestPathsUnknownRef(t *testing.T) {
+	g := loadFixture(t, "diamond-1.6.json")
+
+	if _, err := g.Paths("app", "nope", 0); !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expected ErrUnknownNode for the target, got %v", err)
+	}
+	if _, err := g.Paths("nope", "db", 0); !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expected ErrUnknownNode for the source, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package dag
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// PropertyPhase pins a node to the start or the end of the deployment when
+// the graph leaves it slack, e.g. monitors that alert on everything and must
+// go last although nothing depends on them
+const PropertyPhase = "bom-dagger:phase"
+
+// Phase is where a node is placed among the levels it can legally occupy
+type Phase string
+
+// Phases accepted by bom-dagger:phase and --pin
+const (
+	PhaseEarly  Phase = "early"  // The earliest level, after its dependencies; where every node goes unpinned
+	PhaseNormal Phase = "normal" // No pin
+	PhaseLate   Phase = "late"   // The latest level, just before its first dependent, or the last level
+)
+
+// ParsePhase validates a phase name
+func ParsePhase(s string) (Phase, error) {
+	switch Phase(s) {
+	case PhaseEarly, PhaseNormal, PhaseLate:
+		return Phase(s), nil
+	}
+	return "", fmt.Errorf("unknown phase %q (expected early, normal or late)", s)
+}
+
+// phase returns the node's pin: Graph.Pins, then its bom-dagger:phase
+// property, then PhaseNormal. Invalid property values count as normal.
+func (g *Graph) phase(node *Node) Phase {
+	if phase, ok := g.Pins[node.ID]; ok {
+		return phase
+	}
+	if value, ok := node.Property(PropertyPhase); ok {
+		if phase, err := ParsePhase(value); err == nil {
+			return phase
+		}
+	}
+	return PhaseNormal
+}
+
+// CheckPins reports pins naming no node, and late pins that an early pinned
+// node depends on; placement gives way to the early pin
+func (g *Graph) CheckPins() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var unknown []string
+	for ref := range g.Pins {
+		if _, ok := g.Nodes[ref]; !ok {
+			unknown = append(unknown, ref)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return fmt.Errorf("pinned refs not in the graph: %s", strings.Join(unknown, ", "))
+	}
+
+	var conflicts []string
+	for _, node := range g.sortedNodes() {
+		if g.phase(node) != PhaseLate {
+			continue
+		}
+		for _, dependent := range sortedByID(node.Dependents) {
+			if g.phase(dependent) == PhaseEarly {
+				conflicts = append(conflicts, fmt.Sprintf("%s is pinned late but %s, pinned early, depends on it", node.ID, dependent.ID))
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("conflicting pins: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// pinLate moves every node pinned late to the level just before its
+// earliest dependent, or the last level; no level empties
+func (g *Graph) pinLate(levels [][]*Node) [][]*Node {
+	if len(g.Pins) == 0 && !g.hasPhaseProperty() {
+		return levels
+	}
+	placed := make(map[*Node]int, len(g.Nodes))
+	moved := false
+	for i := len(levels) - 1; i >= 0; i-- {
+		for _, node := range levels[i] {
+			placed[node] = i
+			if g.phase(node) != PhaseLate {
+				continue
+			}
+			latest := len(levels) - 1
+			for _, dependent := range node.Dependents {
+				latest = min(latest, placed[dependent]-1)
+			}
+			if latest != i {
+				placed[node] = latest
+				moved = true
+			}
+		}
+	}
+	if !moved {
+		return levels
+	}
+
+	pinned := make([][]*Node, len(levels))
+	for _, level := range levels {
+		for _, node := range level {
+			pinned[placed[node]] = append(pinned[placed[node]], node)
+		}
+	}
+	for _, level := range pinned {
+		g.sortLevel(level)
+	}
+	return pinned
+}
+
+// hasPhaseProperty reports whether any node declares bom-dagger:phase
+func (g *Graph) hasPhaseProperty() bool {
+	for _, node := range g.Nodes {
+		if _, ok := node.Property(PropertyPhase); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPhase records a warning for a bom-dagger:phase that is not a phase;
+// the node is not pinned
+func (g *Graph) checkPhase(node *Node) {
+	value, ok := node.Property(PropertyPhase)
+	if !ok {
+		return
+	}
+	if _, err := ParsePhase(value); err != nil {
+		g.Warnings = append(g.Warnings, warning.New(warning.InvalidPhase, "invalid %s %q on %s (expected early, normal or late); not pinned", PropertyPhase, value, node.ID))
+	}
+}
+
+// ParsePins parses a comma-separated list of ref=phase pins, as --pin takes
+// them
+func ParsePins(s string) (map[string]Phase, error) {
+	pins := make(map[string]Phase)
+	for _, value := range strings.Split(s, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		ref, name, ok := strings.Cut(value, "=")
+		if !ok || ref == "" {
+			return nil, fmt.Errorf("invalid pin %q (expected ref=early|normal|late)", value)
+		}
+		phase, err := ParsePhase(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pin %q: %w", value, err)
+		}
+		pins[ref] = phase
+	}
+	return pins, nil
+}
@@ -0,0 +1,222 @@
+package dag
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/testgen"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// checkLevels fails unless every node is in exactly one step, every edge
+// points to an earlier step and no step is empty
+func checkLevels(t *testing.T, g *Graph) map[string]int {
+	t.Helper()
+	groups, err := g.GetDeploymentGroupNodes()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroupNodes failed: %v", err)
+	}
+	steps := make(map[string]int, len(g.Nodes))
+	for i, group := range groups {
+		if len(group) == 0 {
+			t.Errorf("Step %d is empty", i+1)
+		}
+		for _, node := range group {
+			if _, ok := steps[node.ID]; ok {
+				t.Errorf("%s is in several steps", node.ID)
+			}
+			steps[node.ID] = i + 1
+		}
+	}
+	if len(steps) != len(g.Nodes) {
+		t.Errorf("Expected %d nodes in the steps, got %d", len(g.Nodes), len(steps))
+	}
+	for _, node := range g.Nodes {
+		for _, dep := range node.Dependencies {
+			if steps[dep.ID] >= steps[node.ID] {
+				t.Errorf("Edge %s -> %s not satisfied: steps %d and %d", node.ID, dep.ID, steps[node.ID], steps[dep.ID])
+			}
+		}
+	}
+	return steps
+}
+
+// pinnedGraph is db <- api <- app, with a monitor, a metrics exporter the
+// monitor depends on and a cache app depends on, all free to move
+func pinnedGraph(t *testing.T, phases map[string]string) *Graph {
+	t.Helper()
+	bom := &sbom.CycloneDX{BOMFormat: "CycloneDX", SpecVersion: "1.6"}
+	for _, ref := range []string{"db", "api", "app", "monitor", "exporter", "cache"} {
+		component := sbom.Component{BOMRef: ref, Name: ref, Type: "application"}
+		if phase, ok := phases[ref]; ok {
+			component.Properties = []sbom.Property{{Name: PropertyPhase, Value: phase}}
+		}
+		bom.Components = append(bom.Components, component)
+	}
+	bom.Dependencies = []sbom.Dependency{
+		{Ref: "api", DependsOn: []string{"db"}},
+		{Ref: "app", DependsOn: []string{"api", "cache"}},
+		{Ref: "monitor", DependsOn: []string{"exporter"}},
+	}
+	componentMap := make(map[string]*sbom.Component)
+	for i := range bom.Components {
+		componentMap[bom.Components[i].BOMRef] = &bom.Components[i]
+	}
+	g := New()
+	if err := g.BuildFromSBOM(bom, componentMap); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	return g
+}
+
+func TestPinLate(t *testing.T) {
+	tests := []struct {
+		name   string
+		phases map[string]string
+		pins   map[string]Phase
+		want   map[string]int
+	}{
+		{
+			name: "unpinned",
+			want: map[string]int{"db": 1, "cache": 1, "exporter": 1, "api": 2, "monitor": 2, "app": 3},
+		},
+		{
+			name:   "late node without dependents goes last",
+			phases: map[string]string{"monitor": "late"},
+			want:   map[string]int{"db": 1, "cache": 1, "exporter": 1, "api": 2, "monitor": 3, "app": 3},
+		},
+		{
+			name:   "late node goes just before its dependent",
+			phases: map[string]string{"cache": "late"},
+			want:   map[string]int{"db": 1, "cache": 2, "exporter": 1, "api": 2, "monitor": 2, "app": 3},
+		},
+		{
+			name:   "late chain moves together",
+			phases: map[string]string{"monitor": "late", "exporter": "late"},
+			want:   map[string]int{"db": 1, "cache": 1, "exporter": 2, "api": 2, "monitor": 3, "app": 3},
+		},
+		{
+			name:   "late dependency held by an unpinned dependent",
+			phases: map[string]string{"exporter": "late"},
+			want:   map[string]int{"db": 1, "cache": 1, "exporter": 1, "api": 2, "monitor": 2, "app": 3},
+		},
+		{
+			name:   "early is the earliest level",
+			phases: map[string]string{"monitor": "early", "cache": "normal"},
+			want:   map[string]int{"db": 1, "cache": 1, "exporter": 1, "api": 2, "monitor": 2, "app": 3},
+		},
+		{
+			name:   "pins override the property",
+			phases: map[string]string{"monitor": "late"},
+			pins:   map[string]Phase{"monitor": PhaseNormal, "cache": PhaseLate},
+			want:   map[string]int{"db": 1, "cache": 2, "exporter": 1, "api": 2, "monitor": 2, "app": 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := pinnedGraph(t, tt.phases)
+			g.Pins = tt.pins
+			if err := g.CheckPins(); err != nil {
+				t.Fatalf("CheckPins failed: %v", err)
+			}
+			if steps := checkLevels(t, g); !reflect.DeepEqual(steps, tt.want) {
+				t.Errorf("Expected steps %v, got %v", tt.want, steps)
+			}
+		})
+	}
+}
+
+func TestPinsOrderWithinLevel(t *testing.T) {
+	g := pinnedGraph(t, map[string]string{"monitor": "late"})
+	groups, err := g.GetDeploymentGroups()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroups failed: %v", err)
+	}
+	if want := []string{"app", "monitor"}; !reflect.DeepEqual(groups[2], want) {
+		t.Errorf("Expected the last step sorted by name, %v, got %v", want, groups[2])
+	}
+}
+
+func TestCheckPins(t *testing.T) {
+	g := pinnedGraph(t, map[string]string{"exporter": "late", "monitor": "early"})
+	err := g.CheckPins()
+	if err == nil || !strings.Contains(err.Error(), "exporter is pinned late but monitor, pinned early, depends on it") {
+		t.Errorf("Expected a conflict naming exporter and monitor, got %v", err)
+	}
+	// The late pin gives way, so the plan stays valid
+	checkLevels(t, g)
+
+	g = pinnedGraph(t, nil)
+	g.Pins = map[string]Phase{"nope": PhaseLate, "db": PhaseEarly, "gone": PhaseEarly}
+	if err := g.CheckPins(); err == nil || err.Error() != "pinned refs not in the graph: gone, nope" {
+		t.Errorf("Expected unknown refs to be named, got %v", err)
+	}
+}
+
+func TestInvalidPhaseWarns(t *testing.T) {
+	g := pinnedGraph(t, map[string]string{"monitor": "last"})
+	want := warning.New(warning.InvalidPhase, `invalid bom-dagger:phase "last" on monitor (expected early, normal or late); not pinned`)
+	if !slices.Contains(g.Warnings, want) {
+		t.Errorf("Expected warning %q, got %v", want, g.Warnings)
+	}
+	if steps := checkLevels(t, g); steps["monitor"] != 2 {
+		t.Errorf("Expected monitor unpinned in step 2, got %d", steps["monitor"])
+	}
+}
+
+func TestParsePins(t *testing.T) {
+	pins, err := ParsePins("monitor=late, db=early")
+	if err != nil {
+		t.Fatalf("ParsePins failed: %v", err)
+	}
+	if want := map[string]Phase{"monitor": PhaseLate, "db": PhaseEarly}; !reflect.DeepEqual(pins, want) {
+		t.Errorf("Expected %v, got %v", want, pins)
+	}
+	for _, value := range []string{"monitor", "=late", "monitor=last"} {
+		if _, err := ParsePins(value); err == nil {
+			t.Errorf("ParsePins(%q): expected an error", value)
+		}
+	}
+}
+
+// TestPinLateProperties pins random nodes of generated graphs late and
+// checks every plan still satisfies every edge with the same number of steps
+func TestPinLateProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for iteration := 0; iteration < 20; iteration++ {
+		opts := testgen.Options{Nodes: 1 + r.Intn(500), EdgeDensity: r.Float64() * 3, Seed: r.Int63()}
+		g, err := generatedGraph(t, opts)
+		if err != nil {
+			t.Fatalf("Build failed for %+v: %v", opts, err)
+		}
+		unpinned, err := g.GetDeploymentGroupNodes()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g.Pins = make(map[string]Phase)
+		for ref := range g.Nodes {
+			if r.Intn(4) == 0 {
+				g.Pins[ref] = PhaseLate
+			}
+		}
+		steps := checkLevels(t, g)
+		if groups, _ := g.GetDeploymentGroupNodes(); len(groups) != len(unpinned) {
+			t.Errorf("Expected %d steps, got %d (%+v)", len(unpinned), len(groups), opts)
+		}
+		for ref := range g.Pins {
+			node := g.Nodes[ref]
+			latest := len(unpinned)
+			for _, dependent := range node.Dependents {
+				latest = min(latest, steps[dependent.ID]-1)
+			}
+			if steps[ref] != latest {
+				t.Errorf("Expected late %s in step %d, got %d (%+v)", ref, latest, steps[ref], opts)
+			}
+		}
+	}
+}
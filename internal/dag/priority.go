@@ -0,0 +1,70 @@
+package dag
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// PropertyPriority is a soft ordering preference among nodes that can be
+// deployed in parallel: lower values are listed, and started, first. It never
+// changes which step a node is in. Nodes without it have priority 0.
+const PropertyPriority = "bom-dagger:priority"
+
+// Priority returns the node's bom-dagger:priority, or 0 when it is absent or
+// not an integer
+func (n *Node) Priority() int {
+	value, ok := n.Property(PropertyPriority)
+	if !ok {
+		return 0
+	}
+	priority, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
+// ByPriority orders nodes by bom-dagger:priority, then name, then bom-ref. It
+// is the default Graph.LessWithinLevel.
+func ByPriority(a, b *Node) bool {
+	if pa, pb := a.Priority(), b.Priority(); pa != pb {
+		return pa < pb
+	}
+	if na, nb := a.Name(), b.Name(); na != nb {
+		return na < nb
+	}
+	return a.ID < b.ID
+}
+
+// sortLevel orders the nodes of one Kahn level with LessWithinLevel
+func (g *Graph) sortLevel(level []*Node) {
+	less := g.LessWithinLevel
+	if less == nil {
+		less = ByPriority
+	}
+	sort.SliceStable(level, func(i, j int) bool {
+		return less(level[i], level[j])
+	})
+}
+
+// checkPriorities records a warning for every bom-dagger:priority that is not
+// an integer, and every bom-dagger:phase that is not a phase; those nodes
+// fall back to the default priority and are not pinned
+func (g *Graph) checkPriorities() {
+	for _, node := range g.sortedNodes() {
+		g.checkPriority(node)
+		g.checkPhase(node)
+	}
+}
+
+func (g *Graph) checkPriority(node *Node) {
+	value, ok := node.Property(PropertyPriority)
+	if !ok {
+		return
+	}
+	if _, err := strconv.Atoi(value); err != nil {
+		g.Warnings = append(g.Warnings, warning.New(warning.InvalidPriority, "invalid %s %q on %s (expected an integer); using default ordering", PropertyPriority, value, node.ID))
+	}
+}
@@ -0,0 +1,117 @@
+package dag
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/testgen"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+func TestTopologicalSortByPriority(t *testing.T) {
+	g := loadFixture(t, "priority-1.6.json")
+
+	order, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+	var refs []string
+	for _, entry := range order {
+		refs = append(refs, entry.BOMRef)
+	}
+	// migrate (-10) first, then the unprioritized nodes by name, then admin (5)
+	if want := []string{"db", "migrate", "api", "worker", "admin"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("Expected order %v, got %v", want, refs)
+	}
+
+	groups, err := g.GetDeploymentGroups()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroups failed: %v", err)
+	}
+	want := [][]string{{"Database (15.0)"}, {"Schema Migration (2.0.0)", "API (2.0.0)", "Background Worker (2.0.0)", "Admin Console (1.4.0)"}}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("Expected groups %v, got %v", want, groups)
+	}
+}
+
+func TestInvalidPriorityWarns(t *testing.T) {
+	bom := &sbom.CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.6",
+		Components: []sbom.Component{
+			{BOMRef: "a", Name: "b-second", Properties: []sbom.Property{{Name: PropertyPriority, Value: "soon"}}},
+			{BOMRef: "b", Name: "a-first"},
+		},
+	}
+	componentMap := map[string]*sbom.Component{"a": &bom.Components[0], "b": &bom.Components[1]}
+
+	g := New()
+	if err := g.BuildFromSBOM(bom, componentMap); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	want := warning.New(warning.InvalidPriority, `invalid bom-dagger:priority "soon" on a (expected an integer); using default ordering`)
+	if !reflect.DeepEqual(g.Warnings, []warning.Warning{want}) {
+		t.Errorf("Expected warning %q, got %v", want, g.Warnings)
+	}
+	if g.Nodes["a"].Priority() != 0 {
+		t.Errorf("Expected invalid priority to fall back to 0, got %d", g.Nodes["a"].Priority())
+	}
+
+	order, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+	if order[0].BOMRef != "b" || order[1].BOMRef != "a" {
+		t.Errorf("Expected default ordering by name, got %v", order)
+	}
+}
+
+// TestLessWithinLevelKeepsMembership checks that a comparator only reorders
+// nodes within a step: every node stays in the same step whatever the order
+func TestLessWithinLevelKeepsMembership(t *testing.T) {
+	comparators := map[string]func(a, b *Node) bool{
+		"by-priority": ByPriority,
+		"reverse-ref": func(a, b *Node) bool { return a.ID > b.ID },
+		"by-fan-out":  func(a, b *Node) bool { return len(a.Dependents) > len(b.Dependents) },
+	}
+
+	for seed := int64(1); seed <= 5; seed++ {
+		g, err := generatedGraph(t, testgen.Options{Nodes: 300, EdgeDensity: 1.5, Seed: seed})
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		baseline := stepsByRef(t, g)
+
+		for name, less := range comparators {
+			g.LessWithinLevel = less
+			if got := stepsByRef(t, g); !reflect.DeepEqual(got, baseline) {
+				t.Errorf("seed %d, %s: comparator moved nodes between steps", seed, name)
+			}
+
+			order, err := g.TopologicalSort()
+			if err != nil {
+				t.Fatalf("TopologicalSort failed: %v", err)
+			}
+			for i := 1; i < len(order); i++ {
+				prev, cur := order[i-1], order[i]
+				if prev.Step == cur.Step && less(g.Nodes[cur.BOMRef], g.Nodes[prev.BOMRef]) {
+					t.Errorf("seed %d, %s: %s listed before %s", seed, name, prev.BOMRef, cur.BOMRef)
+				}
+			}
+		}
+	}
+}
+
+func stepsByRef(t *testing.T, g *Graph) map[string]int {
+	t.Helper()
+	order, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+	steps := make(map[string]int, len(order))
+	for _, entry := range order {
+		steps[entry.BOMRef] = entry.Step
+	}
+	return steps
+}
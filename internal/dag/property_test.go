@@ -0,0 +1,98 @@
+package dag
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/testgen"
+)
+
+// generatedGraph builds a graph from a testgen SBOM
+func generatedGraph(tb testing.TB, opts testgen.Options) (*Graph, error) {
+	tb.Helper()
+
+	bom := testgen.GenerateBOM(opts)
+	g := New()
+	return g, g.BuildFromSBOM(bom, parser.New().GetComponentMap(bom))
+}
+
+func TestTopologicalSortProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for iteration := 0; iteration < 30; iteration++ {
+		opts := testgen.Options{
+			Nodes:        1 + r.Intn(2000),
+			EdgeDensity:  r.Float64() * 5,
+			ServiceRatio: r.Float64() * 0.3,
+			NestingDepth: r.Intn(4),
+			Seed:         r.Int63(),
+		}
+		g, err := generatedGraph(t, opts)
+		if err != nil {
+			t.Fatalf("Build failed for %+v: %v", opts, err)
+		}
+
+		order, err := g.TopologicalSort()
+		if err != nil {
+			t.Fatalf("TopologicalSort failed for %+v: %v", opts, err)
+		}
+		if len(order) != opts.Nodes {
+			t.Fatalf("Expected order length %d, got %d (%+v)", opts.Nodes, len(order), opts)
+		}
+
+		step := make(map[string]int, len(order))
+		for _, entry := range order {
+			step[entry.BOMRef] = entry.Step
+		}
+		for _, node := range g.Nodes {
+			for _, dep := range node.Dependencies {
+				if step[dep.ID] >= step[node.ID] {
+					t.Fatalf("Edge %s -> %s not satisfied: steps %d and %d (%+v)", node.ID, dep.ID, step[node.ID], step[dep.ID], opts)
+				}
+			}
+		}
+
+		refs := make([]string, len(order))
+		for i, entry := range order {
+			refs[i] = entry.BOMRef
+		}
+		if err := ValidOrder(g, refs); err != nil {
+			t.Fatalf("Invalid deterministic order (%+v): %v", opts, err)
+		}
+		random, err := g.RandomTopologicalOrder(rand.New(rand.NewSource(opts.Seed)))
+		if err != nil {
+			t.Fatalf("RandomTopologicalOrder failed for %+v: %v", opts, err)
+		}
+		if err := ValidOrder(g, random); err != nil {
+			t.Fatalf("Invalid random order (%+v): %v", opts, err)
+		}
+	}
+}
+
+func TestTopologicalSortInjectedCycles(t *testing.T) {
+	for seed := int64(0); seed < 10; seed++ {
+		_, err := generatedGraph(t, testgen.Options{Nodes: 200, EdgeDensity: 2, Cycles: 1, Seed: seed})
+		if err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Errorf("Seed %d: expected a cycle error, got %v", seed, err)
+		}
+	}
+}
+
+func BenchmarkTopologicalSort(b *testing.B) {
+	for _, nodes := range []int{1000, 10000, 100000} {
+		g, err := generatedGraph(b, testgen.Options{Nodes: nodes, EdgeDensity: 3, ServiceRatio: 0.1, Seed: 42})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("%dk", nodes/1000), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := g.TopologicalSort(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package dag
+
+import "sort"
+
+// PruneLeaves repeatedly removes nodes nothing depends on whose type is in
+// types ("service" for services) and returns the removed refs, sorted
+func (g *Graph) PruneLeaves(types []string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	prunable := make(map[string]bool, len(types))
+	for _, t := range types {
+		prunable[t] = true
+	}
+
+	var removed []string
+	candidates := nodeList(g)
+	for len(candidates) > 0 {
+		var next []*Node
+		for _, node := range sortedByID(candidates) {
+			if _, exists := g.Nodes[node.ID]; !exists || len(node.Dependents) > 0 || !prunable[nodeType(node)] {
+				continue
+			}
+			// Only the dependencies of a removed node can become new leaves
+			next = append(next, node.Dependencies...)
+			_ = g.removeNode(node.ID)
+			removed = append(removed, node.ID)
+		}
+		candidates = next
+	}
+
+	sort.Strings(removed)
+	return removed
+}
+
+// nodeType returns the component type of a node, or "service" for services
+func nodeType(node *Node) string {
+	if node.Service != nil {
+		return "service"
+	}
+	if node.Component != nil {
+		return node.Component.Type
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package dag
+
+import (
+	"strings"
+	"testing"
+)
+
+var pruneTypes = []string{"library", "file", "operating-system"}
+
+func TestPruneLeaves(t *testing.T) {
+	g := loadFixture(t, "prune-leaves-1.6.json")
+	before, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+	if len(before) != 20 {
+		t.Fatalf("Expected 20 nodes before pruning, got %d", len(before))
+	}
+
+	removed := g.PruneLeaves(pruneTypes)
+	if len(removed) != 15 {
+		t.Errorf("Expected 15 pruned nodes, got %d: %v", len(removed), removed)
+	}
+
+	after, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+
+	// grpc-lib is a library but api depends on it, so it must survive, and
+	// every surviving node stays in its step, keeping the relative order
+	beforeSteps := make(map[string]int)
+	for _, entry := range before {
+		beforeSteps[entry.BOMRef] = entry.Step
+	}
+	want := map[string]int{"db": 1, "grpc-lib": 1, "api": 2, "worker": 2, "web": 3}
+	if len(after) != len(want) {
+		t.Errorf("Expected %d nodes after pruning, got %d", len(want), len(after))
+	}
+	for _, entry := range after {
+		if entry.Step != want[entry.BOMRef] {
+			t.Errorf("Expected %s in step %d, got %d", entry.BOMRef, want[entry.BOMRef], entry.Step)
+		}
+		if entry.Step != beforeSteps[entry.BOMRef] {
+			t.Errorf("Pruning moved %s from step %d to %d", entry.BOMRef, beforeSteps[entry.BOMRef], entry.Step)
+		}
+	}
+
+	var roots []string
+	for _, root := range sortedByID(g.Roots) {
+		roots = append(roots, root.ID)
+	}
+	if strings.Join(roots, ",") != "db,grpc-lib" {
+		t.Errorf("Expected roots db and grpc-lib, got %v", roots)
+	}
+	if edges := g.GetEdgeCount(); edges != 4 {
+		t.Errorf("Expected 4 edges after pruning, got %d", edges)
+	}
+}
+
+func TestPruneLeavesKeepsDependencies(t *testing.T) {
+	g := loadFixture(t, "prune-leaves-1.6.json")
+
+	// Applications are never leaves of the given types, so nothing under
+	// them may go, while the unused OS packages still do
+	removed := g.PruneLeaves([]string{"library", "data"})
+	for _, ref := range removed {
+		if ref == "db" || ref == "grpc-lib" {
+			t.Errorf("Pruned %s even though an application depends on it", ref)
+		}
+	}
+	if _, ok := g.Nodes["debian"]; !ok {
+		t.Error("Expected the operating-system node to stay when its type is not pruned")
+	}
+
+	if removed := g.PruneLeaves(nil); len(removed) != 0 {
+		t.Errorf("Expected no types to prune nothing, got %v", removed)
+	}
+}
@@ -0,0 +1,44 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+)
+
+// KeepReachable removes the nodes not reachable from refs through their
+// dependencies and returns the sorted refs it removed
+func (g *Graph) KeepReachable(refs []string) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	reachable := make(map[string]bool, len(g.Nodes))
+	var stack []*Node
+	for _, ref := range refs {
+		node, exists := g.Nodes[ref]
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownNode, ref)
+		}
+		stack = append(stack, node)
+	}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[node.ID] {
+			continue
+		}
+		reachable[node.ID] = true
+		stack = append(stack, node.Dependencies...)
+	}
+
+	var removed []string
+	for ref := range g.Nodes {
+		if !reachable[ref] {
+			removed = append(removed, ref)
+		}
+	}
+	sort.Strings(removed)
+	for _, ref := range removed {
+		_ = g.removeNode(ref)
+	}
+	return removed, nil
+}
@@ -0,0 +1,68 @@
+package dag
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestKeepReachable(t *testing.T) {
+	g := loadFixture(t, "reachable-noise-1.6.json")
+	before, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+
+	removed, err := g.KeepReachable([]string{"app"})
+	if err != nil {
+		t.Fatalf("KeepReachable failed: %v", err)
+	}
+	// The isolated components and the migration-tool chain are noise
+	want := []string{"left-pad", "migration-tool", "readme", "yaml-parser"}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("KeepReachable() removed %v, want %v", removed, want)
+	}
+
+	after, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort failed: %v", err)
+	}
+	if len(after) != len(before)-len(want) {
+		t.Errorf("Expected %d nodes after KeepReachable, got %d", len(before)-len(want), len(after))
+	}
+	beforeSteps := make(map[string]int)
+	for _, entry := range before {
+		beforeSteps[entry.BOMRef] = entry.Step
+	}
+	for _, entry := range after {
+		if entry.Step != beforeSteps[entry.BOMRef] {
+			t.Errorf("KeepReachable moved %s from step %d to %d", entry.BOMRef, beforeSteps[entry.BOMRef], entry.Step)
+		}
+	}
+
+	var roots []string
+	for _, root := range sortedByID(g.Roots) {
+		roots = append(roots, root.ID)
+	}
+	if want := []string{"db-driver", "logging"}; !reflect.DeepEqual(roots, want) {
+		t.Errorf("Expected roots %v, got %v", want, roots)
+	}
+}
+
+func TestKeepReachableTargets(t *testing.T) {
+	g := loadFixture(t, "reachable-noise-1.6.json")
+	removed, err := g.KeepReachable([]string{"migration-tool", "web-framework"})
+	if err != nil {
+		t.Fatalf("KeepReachable failed: %v", err)
+	}
+	if want := []string{"app", "db-driver", "left-pad", "readme"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("KeepReachable() removed %v, want %v", removed, want)
+	}
+	if g.GetNodeCount() != 4 || g.GetEdgeCount() != 2 {
+		t.Errorf("Expected 4 nodes and 2 edges, got %d and %d", g.GetNodeCount(), g.GetEdgeCount())
+	}
+
+	if _, err := g.KeepReachable([]string{"nope"}); !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expected ErrUnknownNode for an unknown ref, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package dag
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// RedactionEntry maps a pseudonym back to the node it replaced
+type RedactionEntry struct {
+	Pseudonym string `json:"pseudonym"`
+	BOMRef    string `json:"ref"`
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+}
+
+// redactedProperties are kept because they only affect ordering and timing
+var redactedProperties = map[string]bool{
+	PropertyDepKind:  true,
+	PropertyDuration: true,
+}
+
+// Redact returns a copy of the graph with every node renamed to a pseudonym
+// salted by salt, keeping only types, scopes and edges, and the mapping back
+func Redact(g *Graph, salt string) (*Graph, []RedactionEntry) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	type keyed struct {
+		node *Node
+		key  string
+	}
+
+	var components, services []keyed
+	for _, node := range g.Nodes {
+		mac := hmac.New(sha256.New, []byte(salt))
+		mac.Write([]byte(node.ID))
+		k := keyed{node: node, key: hex.EncodeToString(mac.Sum(nil))}
+		if node.Service != nil {
+			services = append(services, k)
+		} else {
+			components = append(components, k)
+		}
+	}
+
+	pseudonyms := make(map[string]string, len(g.Nodes))
+	assign := func(prefix string, nodes []keyed) {
+		sort.Slice(nodes, func(i, j int) bool {
+			if nodes[i].key != nodes[j].key {
+				return nodes[i].key < nodes[j].key
+			}
+			return nodes[i].node.ID < nodes[j].node.ID
+		})
+		width := len(strconv.Itoa(len(nodes)))
+		if width < 3 {
+			width = 3
+		}
+		for i, k := range nodes {
+			pseudonyms[k.node.ID] = fmt.Sprintf("%s-%0*d", prefix, width, i+1)
+		}
+	}
+	assign("component", components)
+	assign("service", services)
+
+	redacted := New()
+	entries := make([]RedactionEntry, 0, len(g.Nodes))
+	for id, node := range g.Nodes {
+		pseudonym := pseudonyms[id]
+		copied := &Node{ID: pseudonym, Dependencies: []*Node{}, Dependents: []*Node{}, Completeness: node.Completeness, Synthetic: node.Synthetic, Nested: node.Nested}
+		if node.Service != nil {
+			copied.Service = redactService(node.Service, pseudonym)
+		} else if !node.Synthetic {
+			copied.Component = redactComponent(node.Component, pseudonym)
+		}
+		redacted.Nodes[pseudonym] = copied
+		entries = append(entries, RedactionEntry{
+			Pseudonym: pseudonym,
+			BOMRef:    id,
+			Name:      node.Name(),
+			Version:   node.Version(),
+		})
+	}
+
+	for id, node := range g.Nodes {
+		copied := redacted.Nodes[pseudonyms[id]]
+		for _, dep := range node.Dependencies {
+			depCopy := redacted.Nodes[pseudonyms[dep.ID]]
+			copied.Dependencies = append(copied.Dependencies, depCopy)
+			depCopy.Dependents = append(depCopy.Dependents, copied)
+			if g.inferred[Edge{From: id, To: dep.ID}] {
+				redacted.inferred[Edge{From: copied.ID, To: depCopy.ID}] = true
+			}
+		}
+	}
+	for _, node := range redacted.Nodes {
+		if len(node.Dependencies) == 0 {
+			redacted.Roots = append(redacted.Roots, node)
+		}
+	}
+	redacted.DeferCycleChecks = g.DeferCycleChecks
+	for _, ref := range g.DuplicateServices {
+		redacted.DuplicateServices = append(redacted.DuplicateServices, pseudonyms[ref])
+	}
+	sort.Strings(redacted.DuplicateServices)
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Pseudonym < entries[j].Pseudonym
+	})
+	return redacted, entries
+}
+
+func redactComponent(c *sbom.Component, pseudonym string) *sbom.Component {
+	redacted := &sbom.Component{BOMRef: pseudonym, Name: pseudonym}
+	if c != nil {
+		redacted.Type = c.Type
+		redacted.Scope = c.Scope
+		redacted.Properties = redactProperties(c.Properties)
+	}
+	return redacted
+}
+
+func redactService(s *sbom.Service, pseudonym string) *sbom.Service {
+	redacted := &sbom.Service{
+		BOMRef:     pseudonym,
+		Name:       pseudonym,
+		Properties: redactProperties(s.Properties),
+	}
+	for i := range s.Endpoints {
+		redacted.Endpoints = append(redacted.Endpoints, fmt.Sprintf("https://%s.invalid/endpoint-%d", pseudonym, i+1))
+	}
+	return redacted
+}
+
+func redactProperties(props []sbom.Property) []sbom.Property {
+	var kept []sbom.Property
+	for _, prop := range props {
+		if redactedProperties[prop.Name] {
+			kept = append(kept, prop)
+		}
+	}
+	return kept
+}
+
+// WriteRedactionMap writes the pseudonym mapping returned by Redact as
+// indented JSON for internal de-referencing
+func WriteRedactionMap(w io.Writer, entries []RedactionEntry) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
@@ -0,0 +1,129 @@
+package dag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedactPreservesStructure(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+	redacted, entries := Redact(g, "s3cret")
+
+	if redacted.GetNodeCount() != g.GetNodeCount() || redacted.GetEdgeCount() != g.GetEdgeCount() {
+		t.Fatalf("Expected %d nodes and %d edges, got %d and %d",
+			g.GetNodeCount(), g.GetEdgeCount(), redacted.GetNodeCount(), redacted.GetEdgeCount())
+	}
+	if len(redacted.Roots) != len(g.Roots) {
+		t.Errorf("Expected %d roots, got %d", len(g.Roots), len(redacted.Roots))
+	}
+	if len(entries) != g.GetNodeCount() {
+		t.Fatalf("Expected %d mapping entries, got %d", g.GetNodeCount(), len(entries))
+	}
+
+	pseudonyms := make(map[string]string)
+	for _, entry := range entries {
+		pseudonyms[entry.BOMRef] = entry.Pseudonym
+	}
+
+	// Every edge maps onto an edge between the corresponding pseudonyms
+	for id, node := range g.Nodes {
+		copied := redacted.Nodes[pseudonyms[id]]
+		if copied == nil {
+			t.Fatalf("Missing redacted node for %s", id)
+		}
+		if copied.Kind() != node.Kind() {
+			t.Errorf("Expected %s to stay a %s, got %s", id, node.Kind(), copied.Kind())
+		}
+		if node.Component != nil && copied.Component.Type != node.Component.Type {
+			t.Errorf("Expected type %s for %s, got %s", node.Component.Type, id, copied.Component.Type)
+		}
+		for _, dep := range node.Dependencies {
+			if !redacted.hasEdge(copied, redacted.Nodes[pseudonyms[dep.ID]]) {
+				t.Errorf("Missing redacted edge for %s -> %s", id, dep.ID)
+			}
+		}
+	}
+
+	// Group membership is unchanged
+	original, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, err := redacted.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	redactedStep := make(map[string]int)
+	for _, item := range steps {
+		redactedStep[item.BOMRef] = item.Step
+	}
+	for _, item := range original {
+		if redactedStep[pseudonyms[item.BOMRef]] != item.Step {
+			t.Errorf("Expected %s in step %d, got %d", item.BOMRef, item.Step, redactedStep[pseudonyms[item.BOMRef]])
+		}
+	}
+}
+
+func TestRedactRemovesIdentifyingData(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+	redacted, _ := Redact(g, "s3cret")
+
+	var buf bytes.Buffer
+	if err := WriteBackstage(&buf, redacted, BackstageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteTree(&buf, redacted, TreeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, leak := range []string{"PostgreSQL", "postgres", "api.example.com", "Kong", "15.2", "pkg:"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("Redacted output leaks %q", leak)
+		}
+	}
+	for _, want := range []string{"component-001", "service-001"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected redacted output to contain %q", want)
+		}
+	}
+
+	for _, node := range redacted.Nodes {
+		if node.Service == nil {
+			continue
+		}
+		for _, endpoint := range node.Service.Endpoints {
+			if !strings.HasPrefix(endpoint, "https://"+node.ID+".invalid/") {
+				t.Errorf("Expected placeholder endpoint for %s, got %s", node.ID, endpoint)
+			}
+		}
+	}
+}
+
+func TestRedactDeterminism(t *testing.T) {
+	mapping := func(salt string) string {
+		_, entries := Redact(loadFixture(t, "microservices-1.6.json"), salt)
+		var pairs []string
+		for _, entry := range entries {
+			pairs = append(pairs, entry.Pseudonym+"="+entry.BOMRef)
+		}
+		return strings.Join(pairs, ",")
+	}
+
+	first := mapping("salt-a")
+	if second := mapping("salt-a"); first != second {
+		t.Errorf("Expected identical mappings for the same salt\nFirst:  %s\nSecond: %s", first, second)
+	}
+	if other := mapping("salt-b"); first == other {
+		t.Error("Expected a different salt to produce a different mapping")
+	}
+
+	var buf bytes.Buffer
+	_, entries := Redact(loadFixture(t, "microservices-1.6.json"), "salt-a")
+	if err := WriteRedactionMap(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"pseudonym": "component-001"`) {
+		t.Errorf("Unexpected redaction map: %s", buf.String())
+	}
+}
@@ -0,0 +1,76 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RemovalImpact is what removing one node from the graph would break; see
+// SimulateRemoval
+type RemovalImpact struct {
+	Ref string `json:"ref"`
+
+	// Dependents are the refs of the nodes that would lose a dependency
+	Dependents []string `json:"dependents"`
+
+	// Unreachable are the refs of the nodes only reached through Ref, which
+	// nothing would lead to anymore
+	Unreachable []string `json:"unreachable"`
+
+	// Dangling are the declared dependencies on Ref that the SBOM's
+	// dependency entries would still list. Edges inferred from nesting or
+	// compositions are not entries and are left out.
+	Dangling []Edge `json:"dangling"`
+}
+
+// SimulateRemoval reports what removing ref, without relinking its
+// dependents, would do to the graph; the graph is not modified
+func (g *Graph) SimulateRemoval(ref string) (*RemovalImpact, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	removed, exists := g.Nodes[ref]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownNode, ref)
+	}
+
+	impact := &RemovalImpact{
+		Ref:         ref,
+		Dependents:  []string{},
+		Unreachable: []string{},
+		Dangling:    []Edge{},
+	}
+	for _, dependent := range removed.Dependents {
+		impact.Dependents = append(impact.Dependents, dependent.ID)
+		if edge := (Edge{From: dependent.ID, To: ref}); !g.inferred[edge] {
+			impact.Dangling = append(impact.Dangling, edge)
+		}
+	}
+	sort.Strings(impact.Dependents)
+	sort.Slice(impact.Dangling, func(i, j int) bool { return impact.Dangling[i].From < impact.Dangling[j].From })
+
+	// Walk from the entry points as if ref were gone
+	reachable := map[string]bool{ref: true}
+	var stack []*Node
+	for _, node := range g.Nodes {
+		if len(node.Dependents) == 0 && node != removed {
+			stack = append(stack, node)
+		}
+	}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[node.ID] {
+			continue
+		}
+		reachable[node.ID] = true
+		stack = append(stack, node.Dependencies...)
+	}
+	for id := range g.Nodes {
+		if !reachable[id] {
+			impact.Unreachable = append(impact.Unreachable, id)
+		}
+	}
+	sort.Strings(impact.Unreachable)
+	return impact, nil
+}
@@ -0,0 +1,70 @@
+package dag
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestSimulateRemoval(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+	fingerprint := g.Fingerprint()
+
+	impact, err := g.SimulateRemoval("postgres-primary")
+	if err != nil {
+		t.Fatalf("SimulateRemoval failed: %v", err)
+	}
+	want := []string{"auth-service", "order-service", "payment-service", "postgres-replica", "user-service"}
+	if !reflect.DeepEqual(impact.Dependents, want) {
+		t.Errorf("Expected dependents %v, got %v", want, impact.Dependents)
+	}
+	if len(impact.Unreachable) != 0 {
+		t.Errorf("Expected nothing to become unreachable, got %v", impact.Unreachable)
+	}
+	if len(impact.Dangling) != len(want) || impact.Dangling[0] != (Edge{From: "auth-service", To: "postgres-primary"}) {
+		t.Errorf("Expected a dangling entry per dependent, got %v", impact.Dangling)
+	}
+
+	// Zookeeper is only reached through Kafka
+	impact, err = g.SimulateRemoval("kafka")
+	if err != nil {
+		t.Fatalf("SimulateRemoval failed: %v", err)
+	}
+	if want := []string{"zookeeper"}; !reflect.DeepEqual(impact.Unreachable, want) {
+		t.Errorf("Expected unreachable %v, got %v", want, impact.Unreachable)
+	}
+
+	if g.Fingerprint() != fingerprint || g.GetNodeCount() != 23 {
+		t.Error("Expected SimulateRemoval to leave the graph unchanged")
+	}
+
+	if _, err := g.SimulateRemoval("missing"); !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expected ErrUnknownNode, got %v", err)
+	}
+}
+
+func TestSimulateRemovalInferredEdges(t *testing.T) {
+	g := New()
+	for _, ref := range []string{"app", "lib"} {
+		if _, err := g.AddComponent(&sbom.Component{BOMRef: ref, Name: ref}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.AddDependency("app", "lib"); err != nil {
+		t.Fatal(err)
+	}
+	g.inferred[Edge{From: "app", To: "lib"}] = true
+
+	impact, err := g.SimulateRemoval("lib")
+	if err != nil {
+		t.Fatalf("SimulateRemoval failed: %v", err)
+	}
+	if want := []string{"app"}; !reflect.DeepEqual(impact.Dependents, want) {
+		t.Errorf("Expected dependents %v, got %v", want, impact.Dependents)
+	}
+	if len(impact.Dangling) != 0 {
+		t.Errorf("Expected inferred edges not to dangle, got %v", impact.Dangling)
+	}
+}
@@ -0,0 +1,206 @@
+package dag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PropertyDuration declares how long a component takes to deploy, as a Go
+// duration string such as "90s" or "5m"
+const PropertyDuration = "bom-dagger:duration"
+
+// ScheduledItem is the earliest-start schedule entry for one node
+type ScheduledItem struct {
+	BOMRef   string
+	Name     string
+	Start    time.Duration // Earliest start, relative to the start of the deployment
+	Finish   time.Duration // Start + Duration
+	Duration time.Duration
+	Critical bool // On a longest path: starting it later delays the whole deployment
+}
+
+// Schedule computes the earliest start and finish of every node with
+// unlimited parallelism; durations is called with the graph read-locked
+func (g *Graph) Schedule(durations func(*Node) time.Duration) ([]ScheduledItem, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	order, err := g.topologicalSort()
+	if err != nil {
+		return nil, err
+	}
+
+	// Dependencies always precede their dependents in the order, so a single
+	// pass computes the longest path to every node
+	finish := make(map[string]time.Duration, len(order))
+	items := make([]ScheduledItem, 0, len(order))
+	for _, entry := range order {
+		node := g.Nodes[entry.BOMRef]
+		duration := durations(node)
+		if duration < 0 {
+			return nil, fmt.Errorf("negative duration %s for %s", duration, node.ID)
+		}
+
+		var start time.Duration
+		for _, dep := range node.Dependencies {
+			if finish[dep.ID] > start {
+				start = finish[dep.ID]
+			}
+		}
+		finish[node.ID] = start + duration
+
+		items = append(items, ScheduledItem{
+			BOMRef:   node.ID,
+			Name:     node.Name(),
+			Start:    start,
+			Finish:   start + duration,
+			Duration: duration,
+		})
+	}
+
+	// Walking the order backwards, the latest a node can finish without
+	// delaying the deployment is the earliest latest-start of its dependents;
+	// nodes without slack are critical
+	var makespan time.Duration
+	for _, item := range items {
+		makespan = max(makespan, item.Finish)
+	}
+	latestStart := make(map[string]time.Duration, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		node := g.Nodes[items[i].BOMRef]
+		latestFinish := makespan
+		for _, dependent := range node.Dependents {
+			latestFinish = min(latestFinish, latestStart[dependent.ID])
+		}
+		latestStart[node.ID] = latestFinish - items[i].Duration
+		items[i].Critical = latestStart[node.ID] == items[i].Start
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Start != items[j].Start {
+			return items[i].Start < items[j].Start
+		}
+		if items[i].Finish != items[j].Finish {
+			return items[i].Finish < items[j].Finish
+		}
+		return items[i].BOMRef < items[j].BOMRef
+	})
+
+	return items, nil
+}
+
+// PropertyDurations returns a duration function reading the
+// bom-dagger:duration property of each node, falling back to def when it is
+// absent. Every node is validated up front so malformed values are reported.
+func PropertyDurations(g *Graph, def time.Duration) (func(*Node) time.Duration, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	durations := make(map[string]time.Duration, len(g.Nodes))
+	for _, node := range g.sortedNodes() {
+		value, ok := node.Property(PropertyDuration)
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q on %s: %w", PropertyDuration, value, node.ID, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("invalid %s %q on %s: must not be negative", PropertyDuration, value, node.ID)
+		}
+		durations[node.ID] = d
+	}
+
+	return func(n *Node) time.Duration {
+		if d, ok := durations[n.ID]; ok {
+			return d
+		}
+		return def
+	}, nil
+}
+
+// ganttWidth is the number of columns used for the timeline bars
+const ganttWidth = 40
+
+// WriteSchedule writes the schedule as a text table with a Gantt-style bar
+// per item, scaled to the total duration of the deployment
+func WriteSchedule(w io.Writer, items []ScheduledItem) error {
+	var makespan time.Duration
+	nameWidth := len("COMPONENT")
+	for _, item := range items {
+		if item.Finish > makespan {
+			makespan = item.Finish
+		}
+		if len(item.Name) > nameWidth {
+			nameWidth = len(item.Name)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-*s  %10s  %10s  %s\n", nameWidth, "COMPONENT", "START", "FINISH", "TIMELINE")
+	for _, item := range items {
+		fmt.Fprintf(&b, "%-*s  %10s  %10s  |%s|\n", nameWidth, item.Name, item.Start, item.Finish, ganttBar(item, makespan))
+	}
+	fmt.Fprintf(&b, "\nTotal duration: %s\n", makespan)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ganttBar renders the span of an item as '#' within a ganttWidth timeline.
+// Zero-duration items are drawn as a single '|' at their start.
+func ganttBar(item ScheduledItem, makespan time.Duration) string {
+	bar := []byte(strings.Repeat(" ", ganttWidth))
+	if makespan == 0 {
+		return string(bar)
+	}
+
+	start := int(int64(item.Start) * ganttWidth / int64(makespan))
+	end := int(int64(item.Finish) * ganttWidth / int64(makespan))
+	if item.Duration == 0 {
+		if start == ganttWidth {
+			start--
+		}
+		bar[start] = '|'
+		return string(bar)
+	}
+	if end == start {
+		end = start + 1
+	}
+	for i := start; i < end; i++ {
+		bar[i] = '#'
+	}
+	return string(bar)
+}
+
+type scheduleJSON struct {
+	BOMRef   string `json:"ref"`
+	Name     string `json:"name"`
+	Start    string `json:"start"`
+	Finish   string `json:"finish"`
+	Duration string `json:"duration"`
+}
+
+// WriteScheduleJSON writes the schedule as indented JSON with durations
+// formatted as Go duration strings
+func WriteScheduleJSON(w io.Writer, items []ScheduledItem) error {
+	out := make([]scheduleJSON, len(items))
+	for i, item := range items {
+		out[i] = scheduleJSON{
+			BOMRef:   item.BOMRef,
+			Name:     item.Name,
+			Start:    item.Start.String(),
+			Finish:   item.Finish.String(),
+			Duration: item.Duration.String(),
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
@@ -0,0 +1,179 @@
+package dag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestSchedule(t *testing.T) {
+	g := loadFixture(t, "diamond-1.6.json")
+	durations := map[string]time.Duration{
+		"db":    5 * time.Minute,
+		"api-a": 10 * time.Minute,
+		"api-b": 2 * time.Minute,
+		"app":   time.Minute,
+	}
+
+	items, err := g.Schedule(func(n *Node) time.Duration { return durations[n.ID] })
+	if err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+
+	want := []ScheduledItem{
+		{BOMRef: "db", Name: "Database", Start: 0, Finish: 5 * time.Minute, Duration: 5 * time.Minute, Critical: true},
+		{BOMRef: "api-b", Name: "API B", Start: 5 * time.Minute, Finish: 7 * time.Minute, Duration: 2 * time.Minute},
+		{BOMRef: "api-a", Name: "API A", Start: 5 * time.Minute, Finish: 15 * time.Minute, Duration: 10 * time.Minute, Critical: true},
+		{BOMRef: "app", Name: "Application", Start: 15 * time.Minute, Finish: 16 * time.Minute, Duration: time.Minute, Critical: true},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("Expected %d items, got %d", len(want), len(items))
+	}
+	for i := range want {
+		if items[i] != want[i] {
+			t.Errorf("Item %d: expected %+v, got %+v", i, want[i], items[i])
+		}
+	}
+}
+
+func TestScheduleTiesAndZeroDurations(t *testing.T) {
+	g := loadFixture(t, "diamond-1.6.json")
+
+	// Equal durations tie api-a and api-b; they are ordered by bom-ref
+	items, err := g.Schedule(func(n *Node) time.Duration {
+		if n.ID == "db" {
+			return 0
+		}
+		return time.Minute
+	})
+	if err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+
+	var refs []string
+	for _, item := range items {
+		refs = append(refs, item.BOMRef)
+	}
+	if strings.Join(refs, ",") != "db,api-a,api-b,app" {
+		t.Errorf("Unexpected order: %v", refs)
+	}
+	if items[1].Start != 0 {
+		t.Errorf("Expected api-a to start immediately after a zero-duration db, got %s", items[1].Start)
+	}
+	if items[3].Start != time.Minute || items[3].Finish != 2*time.Minute {
+		t.Errorf("Unexpected app schedule: %+v", items[3])
+	}
+
+	if _, err := g.Schedule(func(*Node) time.Duration { return -time.Second }); err == nil {
+		t.Error("Expected error for negative duration")
+	}
+}
+
+func TestPropertyDurations(t *testing.T) {
+	g := New()
+	for _, c := range []sbom.Component{
+		{BOMRef: "fast", Name: "Fast", Properties: []sbom.Property{{Name: PropertyDuration, Value: "30s"}}},
+		{BOMRef: "default", Name: "Default"},
+	} {
+		if _, err := g.AddComponent(&c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	durations, err := PropertyDurations(g, time.Minute)
+	if err != nil {
+		t.Fatalf("PropertyDurations failed: %v", err)
+	}
+	if d := durations(g.Nodes["fast"]); d != 30*time.Second {
+		t.Errorf("Expected 30s from property, got %s", d)
+	}
+	if d := durations(g.Nodes["default"]); d != time.Minute {
+		t.Errorf("Expected default 1m, got %s", d)
+	}
+
+	bad := sbom.Component{BOMRef: "bad", Name: "Bad", Properties: []sbom.Property{{Name: PropertyDuration, Value: "soon"}}}
+	if _, err := g.AddComponent(&bad); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PropertyDurations(g, time.Minute); err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("Expected error naming the invalid node, got %v", err)
+	}
+}
+
+func TestWriteSchedule(t *testing.T) {
+	items := []ScheduledItem{
+		{BOMRef: "db", Name: "Database", Start: 0, Finish: 10 * time.Minute, Duration: 10 * time.Minute},
+		{BOMRef: "app", Name: "App", Start: 10 * time.Minute, Finish: 20 * time.Minute, Duration: 10 * time.Minute},
+		{BOMRef: "done", Name: "Done", Start: 20 * time.Minute, Finish: 20 * time.Minute},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSchedule(&buf, items); err != nil {
+		t.Fatalf("WriteSchedule failed: %v", err)
+	}
+
+	half := strings.Repeat("#", 20)
+	blank := strings.Repeat(" ", 20)
+	want := "COMPONENT       START      FINISH  TIMELINE\n" +
+		"Database           0s       10m0s  |" + half + blank + "|\n" +
+		"App             10m0s       20m0s  |" + blank + half + "|\n" +
+		"Done            20m0s       20m0s  |" + strings.Repeat(" ", 39) + "||\n" +
+		"\nTotal duration: 20m0s\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected schedule\nGot:\n%s\nWant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteScheduleJSON(&buf, items[:1]); err != nil {
+		t.Fatalf("WriteScheduleJSON failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"finish": "10m0s"`) {
+		t.Errorf("Expected duration strings in JSON, got: %s", buf.String())
+	}
+}
+
+func TestWriteGantt(t *testing.T) {
+	g := loadFixture(t, "diamond-durations-1.6.json")
+	durations, err := PropertyDurations(g, time.Minute)
+	if err != nil {
+		t.Fatalf("PropertyDurations failed: %v", err)
+	}
+	items, err := g.Schedule(durations)
+	if err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	omitted, err := WriteGantt(&buf, g, items, GanttOptions{})
+	if err != nil || omitted != 0 {
+		t.Fatalf("WriteGantt failed: %v (%d omitted)", err, omitted)
+	}
+	assertGolden(t, "gantt-diamond.mmd", buf.Bytes())
+
+	buf.Reset()
+	omitted, err = WriteGantt(&buf, g, items, GanttOptions{MaxTasks: 2})
+	if err != nil {
+		t.Fatalf("WriteGantt failed: %v", err)
+	}
+	if omitted != 3 || strings.Count(buf.String(), ", t") != 2 || strings.Contains(buf.String(), "Step 3") {
+		t.Errorf("Expected the first 2 tasks and 3 left out, got %d left out:\n%s", omitted, buf.String())
+	}
+}
+
+func TestGanttTaskName(t *testing.T) {
+	tests := map[string]string{
+		"Database":               "Database",
+		"Migrations: schema v42": "Migrations- schema v42",
+		"%% not a comment":       "% not a comment",
+		"multi\nline; #1":        "multi line, 1",
+		"  ":                     "(unnamed)",
+	}
+	for name, want := range tests {
+		if got := ganttTaskName(name); got != want {
+			t.Errorf("ganttTaskName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package dag
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// SearchMatch is a node found by Search, with where it sits in the plan
+type SearchMatch struct {
+	Ref          string `json:"ref"`
+	Name         string `json:"name"`
+	Version      string `json:"version,omitempty"`
+	Kind         string `json:"kind"`
+	Type         string `json:"type,omitempty"` // CycloneDX component type; empty for services
+	Step         int    `json:"step"`           // Deployment step (Kahn level)
+	Dependencies int    `json:"dependencies"`   // Direct dependencies
+	Dependents   int    `json:"dependents"`     // Nodes depending on it directly
+}
+
+// NewSearchPattern compiles a whole-string glob, or a re: regular expression,
+// matching without case unless caseSensitive is set
+func NewSearchPattern(pattern string, caseSensitive bool) (*regexp.Regexp, error) {
+	var expr string
+	if re, ok := strings.CutPrefix(pattern, "re:"); ok {
+		expr = re
+	} else {
+		var b strings.Builder
+		b.WriteString("^")
+		for _, r := range pattern {
+			switch r {
+			case '*':
+				b.WriteString(".*")
+			case '?':
+				b.WriteString(".")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		b.WriteString("$")
+		expr = b.String()
+	}
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		// Name the problem without the flags and anchors added to the pattern
+		var parseErr *syntax.Error
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("invalid regular expression %q: %s", strings.TrimPrefix(pattern, "re:"), parseErr.Code)
+		}
+		return nil, fmt.Errorf("invalid regular expression %q: %w", strings.TrimPrefix(pattern, "re:"), err)
+	}
+	return re, nil
+}
+
+// Search returns the nodes whose bom-ref or name matches pattern (see
+// NewSearchPattern), sorted by step and then by ref
+func (g *Graph) Search(pattern *regexp.Regexp) ([]SearchMatch, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	levels, err := g.levels()
+	if err != nil {
+		return nil, err
+	}
+	matches := []SearchMatch{}
+	for i, level := range levels {
+		for _, node := range sortedByID(level) {
+			if !pattern.MatchString(node.ID) && !pattern.MatchString(getNodeName(node)) {
+				continue
+			}
+			match := SearchMatch{
+				Ref:          node.ID,
+				Name:         getNodeName(node),
+				Version:      getNodeVersion(node),
+				Kind:         node.Kind(),
+				Step:         i + 1,
+				Dependencies: len(node.Dependencies),
+				Dependents:   len(node.Dependents),
+			}
+			if node.Component != nil {
+				match.Type = node.Component.Type
+			}
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
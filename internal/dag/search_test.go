@@ -0,0 +1,75 @@
+package dag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+	tests := []struct {
+		name          string
+		pattern       string
+		caseSensitive bool
+		want          []string
+	}{
+		{name: "glob on refs", pattern: "redis*", want: []string{"redis-master", "redis-slave"}},
+		{name: "glob ignores case", pattern: "REDIS-?????", want: []string{"redis-slave"}},
+		{name: "glob on names", pattern: "apache *", want: []string{"zookeeper", "kafka"}},
+		{name: "glob matches whole strings", pattern: "redis"},
+		{name: "case-sensitive glob", pattern: "Redis*", caseSensitive: true, want: []string{"redis-master", "redis-slave"}},
+		{name: "case-sensitive glob without match", pattern: "REDIS*", caseSensitive: true},
+		{name: "regex matches anywhere", pattern: "re:^postgres|mongo", want: []string{"mongodb", "postgres-primary", "postgres-replica"}},
+		{name: "no matches", pattern: "re:^nothing$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := NewSearchPattern(tt.pattern, tt.caseSensitive)
+			if err != nil {
+				t.Fatalf("NewSearchPattern failed: %v", err)
+			}
+			matches, err := g.Search(re)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			var refs []string
+			for _, match := range matches {
+				refs = append(refs, match.Ref)
+			}
+			if !reflect.DeepEqual(refs, tt.want) {
+				t.Errorf("Expected matches %v, got %v", tt.want, refs)
+			}
+		})
+	}
+}
+
+func TestSearchMatchDetails(t *testing.T) {
+	g := loadFixture(t, "microservices-1.6.json")
+	re, err := NewSearchPattern("redis-master", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := g.Search(re)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	want := []SearchMatch{{
+		Ref: "redis-master", Name: "Redis Master", Version: "7.2.0", Kind: "component", Type: "library",
+		Step: 1, Dependencies: 0, Dependents: len(g.Nodes["redis-master"].Dependents),
+	}}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("Expected %+v, got %+v", want, matches)
+	}
+}
+
+func TestNewSearchPatternInvalid(t *testing.T) {
+	_, err := NewSearchPattern("re:(redis", false)
+	if err == nil || !strings.Contains(err.Error(), `invalid regular expression "(redis": missing closing )`) {
+		t.Errorf("Expected a readable error, got %v", err)
+	}
+	// Regex syntax in a glob is literal
+	if _, err := NewSearchPattern("(redis*", false); err != nil {
+		t.Errorf("Expected a glob to accept any characters, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// Snapshot is a plain copy of a built graph that encoding/gob can serialize,
+// so a graph can be cached between runs instead of being rebuilt. Nodes refer
+// to each other by ref.
+type Snapshot struct {
+	Nodes             []SnapshotNode
+	Roots             []string
+	Warnings          []warning.Warning
+	Excluded          []string
+	EnvExcluded       []string
+	Unresolved        []string
+	UnresolvedBy      map[string][]string // Unresolved targets per ref, for ApplyDelta
+	DuplicateServices []string
+	Inferred          []Edge
+	Compositions      []sbom.Composition
+}
+
+// SnapshotNode is a Node with its edges as refs, in their original order
+type SnapshotNode struct {
+	ID           string
+	Component    *sbom.Component
+	Service      *sbom.Service
+	Dependencies []string
+	Dependents   []string
+	Completeness string
+	Synthetic    bool
+	Aliases      []string
+	Nested       bool
+}
+
+// Snapshot copies the graph into a Snapshot. Nodes and inferred edges are
+// sorted, so equal graphs give equal snapshots.
+func (g *Graph) Snapshot() *Snapshot {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	s := &Snapshot{
+		Nodes:             make([]SnapshotNode, 0, len(g.Nodes)),
+		Warnings:          g.Warnings,
+		Excluded:          g.Excluded,
+		EnvExcluded:       g.EnvExcluded,
+		Unresolved:        g.Unresolved,
+		UnresolvedBy:      g.unresolvedBy,
+		DuplicateServices: g.DuplicateServices,
+		Compositions:      g.compositions,
+	}
+	for _, node := range g.sortedNodes() {
+		s.Nodes = append(s.Nodes, SnapshotNode{
+			ID:           node.ID,
+			Component:    node.Component,
+			Service:      node.Service,
+			Dependencies: nodeIDs(node.Dependencies),
+			Dependents:   nodeIDs(node.Dependents),
+			Completeness: node.Completeness,
+			Synthetic:    node.Synthetic,
+			Aliases:      node.Aliases,
+			Nested:       node.Nested,
+		})
+	}
+	s.Roots = nodeIDs(g.Roots)
+	for edge := range g.inferred {
+		s.Inferred = append(s.Inferred, edge)
+	}
+	sort.Slice(s.Inferred, func(i, j int) bool {
+		if s.Inferred[i].From != s.Inferred[j].From {
+			return s.Inferred[i].From < s.Inferred[j].From
+		}
+		return s.Inferred[i].To < s.Inferred[j].To
+	})
+	return s
+}
+
+// FromSnapshot rebuilds the graph a Snapshot was taken of. opts are the
+// options the graph was built with; they are kept for ApplyDelta. It returns
+// an error when the snapshot refers to a node it does not contain.
+func FromSnapshot(s *Snapshot, opts BuildOptions) (*Graph, error) {
+	g := New()
+	g.Warnings = s.Warnings
+	g.Excluded = s.Excluded
+	g.EnvExcluded = s.EnvExcluded
+	g.Unresolved = s.Unresolved
+	g.unresolvedBy = s.UnresolvedBy
+	g.DuplicateServices = s.DuplicateServices
+	g.buildOpts = opts
+	g.compositions = s.Compositions
+
+	for i := range s.Nodes {
+		n := &s.Nodes[i]
+		if _, exists := g.Nodes[n.ID]; exists {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateNode, n.ID)
+		}
+		g.Nodes[n.ID] = &Node{
+			ID:           n.ID,
+			Component:    n.Component,
+			Service:      n.Service,
+			Completeness: n.Completeness,
+			Synthetic:    n.Synthetic,
+			Aliases:      n.Aliases,
+			Nested:       n.Nested,
+		}
+	}
+
+	lookup := func(refs []string) ([]*Node, error) {
+		nodes := make([]*Node, len(refs))
+		for i, ref := range refs {
+			node, exists := g.Nodes[ref]
+			if !exists {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownNode, ref)
+			}
+			nodes[i] = node
+		}
+		return nodes, nil
+	}
+	var err error
+	for i := range s.Nodes {
+		node := g.Nodes[s.Nodes[i].ID]
+		if node.Dependencies, err = lookup(s.Nodes[i].Dependencies); err != nil {
+			return nil, err
+		}
+		if node.Dependents, err = lookup(s.Nodes[i].Dependents); err != nil {
+			return nil, err
+		}
+	}
+	if g.Roots, err = lookup(s.Roots); err != nil {
+		return nil, err
+	}
+	for _, edge := range s.Inferred {
+		g.inferred[edge] = true
+	}
+	return g, nil
+}
+
+// nodeIDs returns the refs of nodes, in order
+func nodeIDs(nodes []*Node) []string {
+	ids := make([]string, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID
+	}
+	return ids
+}
@@ -0,0 +1,71 @@
+package dag
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/parser"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	for _, fixture := range []string{"microservices-1.6.json", "nested-1.6.json", "dedupe-shop-1.6.json", "missing-ref-1.6.json"} {
+		t.Run(fixture, func(t *testing.T) {
+			g := loadFixture(t, fixture)
+			restored, err := FromSnapshot(g.Snapshot(), BuildOptions{})
+			if err != nil {
+				t.Fatalf("FromSnapshot failed: %v", err)
+			}
+
+			want, err := g.TopologicalSort()
+			if err != nil {
+				t.Fatalf("TopologicalSort failed: %v", err)
+			}
+			got, err := restored.TopologicalSort()
+			if err != nil {
+				t.Fatalf("TopologicalSort of the restored graph failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Restored order differs:\n%+v\nwant\n%+v", got, want)
+			}
+			if !reflect.DeepEqual(restored.Snapshot(), g.Snapshot()) {
+				t.Error("Snapshot of the restored graph differs")
+			}
+			if diff := DiffNodes(g, restored); diff != nil {
+				t.Errorf("Restored graph differs: %q", diff)
+			}
+		})
+	}
+}
+
+func TestFromSnapshotUnknownRef(t *testing.T) {
+	s := loadFixture(t, "simple-1.6.json").Snapshot()
+	s.Nodes[0].Dependencies = append(s.Nodes[0].Dependencies, "missing")
+	if _, err := FromSnapshot(s, BuildOptions{}); !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expected ErrUnknownNode, got %v", err)
+	}
+}
+
+func TestSnapshotKeepsInferredEdges(t *testing.T) {
+	p := parser.New()
+	bom, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", "nested-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	g := New()
+	if err := g.BuildFromSBOMWithOptions(bom, p.GetComponentMap(bom), BuildOptions{InferFrom: InferBoth}); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+
+	restored, err := FromSnapshot(g.Snapshot(), BuildOptions{InferFrom: InferBoth})
+	if err != nil {
+		t.Fatalf("FromSnapshot failed: %v", err)
+	}
+	if got, want := restored.GetInferredEdgeCount(), g.GetInferredEdgeCount(); got != want || want == 0 {
+		t.Errorf("Expected %d inferred edges, got %d", want, got)
+	}
+	if !reflect.DeepEqual(restored.Snapshot(), g.Snapshot()) {
+		t.Error("Snapshot of the restored graph differs")
+	}
+}
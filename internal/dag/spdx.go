@@ -0,0 +1,194 @@
+package dag
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/ident"
+)
+
+// SPDX constants used by WriteSPDX
+const (
+	SPDXVersion         = "SPDX-2.3"
+	SPDXDocumentID      = "SPDXRef-DOCUMENT"
+	SPDXNoAssertion     = "NOASSERTION"
+	SPDXRelDescribes    = "DESCRIBES"
+	SPDXRelDependsOn    = "DEPENDS_ON"
+	SPDXRefTypeBOMRef   = "bom-ref" // OTHER external reference carrying the node's bom-ref
+	SPDXServiceComment  = "CycloneDX service"
+	spdxNamespacePrefix = "https://github.com/nprimmer/bom-dagger/spdxdocs/"
+)
+
+// SPDXOptions controls WriteSPDX
+type SPDXOptions struct {
+	// Name is the document name (default "bom-dagger")
+	Name string
+	// Namespace is the document namespace URI. Empty derives one from Name
+	// and the graph's Fingerprint, so identical graphs share it.
+	Namespace string
+	// Created is the RFC 3339 creation time (default now)
+	Created string
+	// Creators are written to creationInfo.creators (default "Tool: bom-dagger")
+	Creators []string
+}
+
+// SPDXDocument is the subset of an SPDX 2.3 JSON document written by WriteSPDX
+type SPDXDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      SPDXCreationInfo   `json:"creationInfo"`
+	Packages          []SPDXPackage      `json:"packages"`
+	Relationships     []SPDXRelationship `json:"relationships"`
+}
+
+// SPDXCreationInfo records who created an SPDX document and when
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+// SPDXPackage is one node of the graph
+type SPDXPackage struct {
+	SPDXID                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	Comment               string            `json:"comment,omitempty"`
+	ExternalRefs          []SPDXExternalRef `json:"externalRefs,omitempty"`
+}
+
+// SPDXExternalRef points from a package to an identifier outside the document
+type SPDXExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+// SPDXRelationship relates two elements of the document
+type SPDXRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxPurposes maps CycloneDX component types to SPDX primaryPackagePurpose
+var spdxPurposes = map[string]string{
+	"application":      "APPLICATION",
+	"framework":        "FRAMEWORK",
+	"library":          "LIBRARY",
+	"container":        "CONTAINER",
+	"platform":         "OTHER",
+	"operating-system": "OPERATING-SYSTEM",
+	"device":           "DEVICE",
+	"device-driver":    "DEVICE",
+	"firmware":         "FIRMWARE",
+	"file":             "FILE",
+	"data":             "OTHER",
+}
+
+// WriteSPDX writes the graph as an SPDX 2.3 JSON document with a package per
+// node and a DEPENDS_ON relationship per edge
+func WriteSPDX(w io.Writer, g *Graph, opts SPDXOptions) error {
+	name := opts.Name
+	if name == "" {
+		name = "bom-dagger"
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		fingerprint := strings.TrimPrefix(g.Fingerprint(), "sha256:")
+		namespace = spdxNamespacePrefix + ident.Sanitize(name, ident.SPDXID) + "-" + fingerprint[:32]
+	}
+	created := opts.Created
+	if created == "" {
+		created = time.Now().UTC().Format(time.RFC3339)
+	}
+	creators := opts.Creators
+	if len(creators) == 0 {
+		creators = []string{"Tool: bom-dagger"}
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	nodes := g.sortedNodes()
+	namer := ident.NewNamer(ident.SPDXID)
+	namer.Reserve(strings.TrimPrefix(SPDXDocumentID, "SPDXRef-"))
+	ids := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		ids[node.ID] = "SPDXRef-" + namer.Name(node.ID)
+	}
+
+	doc := SPDXDocument{
+		SPDXVersion:       SPDXVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            SPDXDocumentID,
+		Name:              name,
+		DocumentNamespace: namespace,
+		CreationInfo:      SPDXCreationInfo{Created: created, Creators: creators},
+		Packages:          make([]SPDXPackage, 0, len(nodes)),
+		Relationships:     []SPDXRelationship{},
+	}
+
+	var dependsOn []SPDXRelationship
+	for _, node := range nodes {
+		pkg := SPDXPackage{
+			SPDXID:           ids[node.ID],
+			Name:             node.Name(),
+			VersionInfo:      node.Version(),
+			DownloadLocation: SPDXNoAssertion,
+		}
+		if pkg.Name == "" {
+			pkg.Name = node.ID
+		}
+		switch {
+		case node.Service != nil:
+			pkg.PrimaryPackagePurpose = "OTHER"
+			pkg.Comment = SPDXServiceComment
+		case node.Component != nil:
+			pkg.PrimaryPackagePurpose = spdxPurposes[node.Component.Type]
+			if node.Component.Purl != "" {
+				pkg.ExternalRefs = append(pkg.ExternalRefs, SPDXExternalRef{
+					ReferenceCategory: "PACKAGE-MANAGER",
+					ReferenceType:     "purl",
+					ReferenceLocator:  node.Component.Purl,
+				})
+			}
+		}
+		pkg.ExternalRefs = append(pkg.ExternalRefs, SPDXExternalRef{
+			ReferenceCategory: "OTHER",
+			ReferenceType:     SPDXRefTypeBOMRef,
+			ReferenceLocator:  node.ID,
+		})
+		doc.Packages = append(doc.Packages, pkg)
+
+		if len(node.Dependents) == 0 {
+			doc.Relationships = append(doc.Relationships, SPDXRelationship{
+				SPDXElementID:      SPDXDocumentID,
+				RelationshipType:   SPDXRelDescribes,
+				RelatedSPDXElement: pkg.SPDXID,
+			})
+		}
+		for _, dep := range sortedByID(node.Dependencies) {
+			dependsOn = append(dependsOn, SPDXRelationship{
+				SPDXElementID:      pkg.SPDXID,
+				RelationshipType:   SPDXRelDependsOn,
+				RelatedSPDXElement: ids[dep.ID],
+			})
+		}
+	}
+	doc.Relationships = append(doc.Relationships, dependsOn...)
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,112 @@
+package dag
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestWriteSPDX(t *testing.T) {
+	g := loadFixture(t, "diamond-1.6.json")
+
+	var buf bytes.Buffer
+	opts := SPDXOptions{Name: "diamond", Created: "2024-01-15T10:00:00Z", Creators: []string{"Tool: bom-dagger-test"}}
+	if err := WriteSPDX(&buf, g, opts); err != nil {
+		t.Fatalf("WriteSPDX failed: %v", err)
+	}
+	assertGolden(t, "spdx-diamond.json", buf.Bytes())
+}
+
+func TestWriteSPDXNamespace(t *testing.T) {
+	namespace := func(g *Graph) string {
+		var buf bytes.Buffer
+		if err := WriteSPDX(&buf, g, SPDXOptions{}); err != nil {
+			t.Fatalf("WriteSPDX failed: %v", err)
+		}
+		var doc SPDXDocument
+		if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		return doc.DocumentNamespace
+	}
+
+	diamond := namespace(loadFixture(t, "diamond-1.6.json"))
+	if !strings.HasPrefix(diamond, "https://github.com/nprimmer/bom-dagger/spdxdocs/bom-dagger-") {
+		t.Errorf("Expected a namespace under the bom-dagger spdxdocs URL, got %q", diamond)
+	}
+	if again := namespace(loadFixture(t, "diamond-1.6.json")); again != diamond {
+		t.Errorf("Expected identical graphs to share a namespace, got %q and %q", diamond, again)
+	}
+	if simple := namespace(loadFixture(t, "simple-1.6.json")); simple == diamond {
+		t.Errorf("Expected different graphs to get different namespaces, both got %q", diamond)
+	}
+}
+
+// TestSPDXRoundTrip reads the written documents back and checks that they
+// describe the same graph. SPDX has no place for component types, scopes
+// and properties, so only the structure is compared. There is no SPDX parser yet, so readSPDX stands in
+// for it; once one exists this test should go through it instead.
+func TestSPDXRoundTrip(t *testing.T) {
+	for _, fixture := range []string{"diamond-1.6.json", "simple-1.6.json", "microservices-1.6.json", "nested-1.6.json"} {
+		t.Run(fixture, func(t *testing.T) {
+			g := loadFixture(t, fixture)
+
+			var buf bytes.Buffer
+			if err := WriteSPDX(&buf, g, SPDXOptions{}); err != nil {
+				t.Fatalf("WriteSPDX failed: %v", err)
+			}
+			roundTripped := readSPDX(t, buf.Bytes())
+
+			if diff := DiffNodes(g, roundTripped); diff != nil {
+				t.Errorf("Graph changed:\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+// readSPDX builds a graph from an SPDX document written by WriteSPDX, using
+// the bom-ref external references to restore the original refs
+func readSPDX(t *testing.T, data []byte) *Graph {
+	t.Helper()
+
+	var doc SPDXDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Invalid SPDX JSON: %v", err)
+	}
+	bom := &sbom.CycloneDX{BOMFormat: "CycloneDX", SpecVersion: "1.6"}
+	refs := make(map[string]string)
+	for _, pkg := range doc.Packages {
+		for _, ref := range pkg.ExternalRefs {
+			if ref.ReferenceType == SPDXRefTypeBOMRef {
+				refs[pkg.SPDXID] = ref.ReferenceLocator
+			}
+		}
+		if pkg.Comment == SPDXServiceComment {
+			bom.Services = append(bom.Services, sbom.Service{BOMRef: refs[pkg.SPDXID], Name: pkg.Name, Version: pkg.VersionInfo})
+		} else {
+			bom.Components = append(bom.Components, sbom.Component{BOMRef: refs[pkg.SPDXID], Name: pkg.Name, Version: pkg.VersionInfo})
+		}
+	}
+	dependsOn := make(map[string][]string)
+	for _, rel := range doc.Relationships {
+		if rel.RelationshipType == SPDXRelDependsOn {
+			dependsOn[refs[rel.SPDXElementID]] = append(dependsOn[refs[rel.SPDXElementID]], refs[rel.RelatedSPDXElement])
+		}
+	}
+	for ref, deps := range dependsOn {
+		bom.Dependencies = append(bom.Dependencies, sbom.Dependency{Ref: ref, DependsOn: deps})
+	}
+
+	componentMap := make(map[string]*sbom.Component)
+	for i := range bom.Components {
+		componentMap[bom.Components[i].BOMRef] = &bom.Components[i]
+	}
+	g := New()
+	if err := g.BuildFromSBOM(bom, componentMap); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	return g
+}
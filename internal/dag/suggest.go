@@ -0,0 +1,279 @@
+package dag
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// Confidence is how likely a suggested edge is to be a real dependency
+type Confidence string
+
+// Confidence levels, from the most to the least likely
+const (
+	ConfidenceHigh   Confidence = "high"
+	ConfidenceMedium Confidence = "medium"
+	ConfidenceLow    Confidence = "low"
+)
+
+func (c Confidence) rank() int {
+	switch c {
+	case ConfidenceHigh:
+		return 0
+	case ConfidenceMedium:
+		return 1
+	}
+	return 2
+}
+
+// EdgeSuggestion is a dependency the SBOM does not declare but its metadata
+// hints at: From probably depends on To
+type EdgeSuggestion struct {
+	From       string     `json:"from"`
+	To         string     `json:"to"`
+	Confidence Confidence `json:"confidence"`
+	Reason     string     `json:"reason"`
+}
+
+func (s EdgeSuggestion) String() string {
+	return fmt.Sprintf("%s -> %s: %s", s.From, s.To, s.Reason)
+}
+
+// minSuggestName is the shortest ref or name the heuristics match, so that
+// names such as "db" or "ui" do not match every other word
+const minSuggestName = 3
+
+// SuggestEdges guesses missing dependencies from endpoints, properties and
+// groups, leaving out existing and cycle-closing edges
+func (g *Graph) SuggestEdges() []EdgeSuggestion {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	nodes := g.sortedNodes()
+	names := suggestNames(nodes)
+	var all []EdgeSuggestion
+	all = append(all, endpointSuggestions(nodes, names)...)
+	all = append(all, propertySuggestions(nodes, names)...)
+	all = append(all, groupSuggestions(nodes)...)
+
+	best := make(map[Edge]EdgeSuggestion)
+	var order []Edge
+	for _, suggestion := range all {
+		edge := Edge{From: suggestion.From, To: suggestion.To}
+		from, to := g.Nodes[edge.From], g.Nodes[edge.To]
+		if from == to || g.hasEdge(from, to) || reaches(to, from) {
+			continue
+		}
+		kept, seen := best[edge]
+		if !seen {
+			order = append(order, edge)
+		}
+		if !seen || suggestion.Confidence.rank() < kept.Confidence.rank() {
+			best[edge] = suggestion
+		}
+	}
+
+	suggestions := make([]EdgeSuggestion, 0, len(order))
+	for _, edge := range order {
+		suggestions = append(suggestions, best[edge])
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].From != suggestions[j].From {
+			return suggestions[i].From < suggestions[j].From
+		}
+		return suggestions[i].To < suggestions[j].To
+	})
+	return suggestions
+}
+
+// reaches reports whether to can be reached from from by following
+// dependencies
+func reaches(from, to *Node) bool {
+	seen := make(map[*Node]bool)
+	stack := []*Node{from}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node == to {
+			return true
+		}
+		if seen[node] {
+			continue
+		}
+		seen[node] = true
+		stack = append(stack, node.Dependencies...)
+	}
+	return false
+}
+
+// suggestNames maps the normalized ref and name of every node to the refs
+// of the nodes carrying it. Refs and names shorter than minSuggestName are
+// left out.
+func suggestNames(nodes []*Node) map[string][]string {
+	names := make(map[string][]string)
+	for _, node := range nodes {
+		for _, name := range []string{normalizeName(node.ID), normalizeName(node.Name())} {
+			if len(name) < minSuggestName || contains(names[name], node.ID) {
+				continue
+			}
+			names[name] = append(names[name], node.ID)
+		}
+	}
+	return names
+}
+
+func contains(refs []string, ref string) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeName lowercases s and joins its words with hyphens, so that
+// "Order Service", "order_service" and "order-service" compare equal
+func normalizeName(s string) string {
+	return strings.Join(nameWords(s), "-")
+}
+
+// nameWords splits s into lowercase words at anything that is not a letter
+// or a digit
+func nameWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	})
+}
+
+// endpointSuggestions suggests that a service depends on the nodes named by
+// its endpoint hosts, with high confidence for the first host label
+func endpointSuggestions(nodes []*Node, names map[string][]string) []EdgeSuggestion {
+	var suggestions []EdgeSuggestion
+	for _, node := range nodes {
+		if node.Service == nil {
+			continue
+		}
+		for _, endpoint := range node.Service.Endpoints {
+			host := endpointHost(endpoint)
+			for i, label := range strings.Split(host, ".") {
+				confidence := ConfidenceHigh
+				if i > 0 {
+					confidence = ConfidenceMedium
+				}
+				for _, ref := range names[normalizeName(label)] {
+					if ref == node.ID {
+						continue
+					}
+					suggestions = append(suggestions, EdgeSuggestion{
+						From:       node.ID,
+						To:         ref,
+						Confidence: confidence,
+						Reason:     fmt.Sprintf("endpoint %s names %s", endpoint, ref),
+					})
+				}
+			}
+		}
+	}
+	return suggestions
+}
+
+// endpointHost returns the lowercase host of an endpoint URL, which may
+// lack a scheme
+func endpointHost(endpoint string) string {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "//" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// propertySuggestions suggests that a node depends on the nodes its property
+// values name, leaving out the bom-dagger: properties
+func propertySuggestions(nodes []*Node, names map[string][]string) []EdgeSuggestion {
+	var suggestions []EdgeSuggestion
+	for _, node := range nodes {
+		for _, prop := range nodeProperties(node) {
+			if strings.HasPrefix(prop.Name, "bom-dagger:") {
+				continue
+			}
+			matched := make(map[string]bool)
+			suggest := func(ref string, confidence Confidence) {
+				if ref == node.ID || matched[ref] {
+					return
+				}
+				matched[ref] = true
+				suggestions = append(suggestions, EdgeSuggestion{
+					From:       node.ID,
+					To:         ref,
+					Confidence: confidence,
+					Reason:     fmt.Sprintf("property %s mentions %s", prop.Name, ref),
+				})
+			}
+			// Whole tokens, keeping hyphenated names together
+			for _, token := range strings.FieldsFunc(strings.ToLower(prop.Value), func(r rune) bool {
+				return !('a' <= r && r <= 'z' || '0' <= r && r <= '9' || r == '-' || r == '_')
+			}) {
+				for _, ref := range names[normalizeName(token)] {
+					suggest(ref, ConfidenceMedium)
+				}
+			}
+			// Names of several words, spelled out with spaces
+			text := " " + strings.Join(nameWords(prop.Value), " ") + " "
+			for name, refs := range names {
+				if !strings.Contains(name, "-") {
+					continue
+				}
+				if strings.Contains(text, " "+strings.ReplaceAll(name, "-", " ")+" ") {
+					for _, ref := range refs {
+						suggest(ref, ConfidenceLow)
+					}
+				}
+			}
+		}
+	}
+	return suggestions
+}
+
+func nodeProperties(n *Node) []sbom.Property {
+	if n.Component != nil {
+		return n.Component.Properties
+	}
+	if n.Service != nil {
+		return n.Service.Properties
+	}
+	return nil
+}
+
+// groupSuggestions suggests, with low confidence, that a node depends on the
+// node of its group whose name its own extends, as kafka-connect does kafka
+func groupSuggestions(nodes []*Node) []EdgeSuggestion {
+	var suggestions []EdgeSuggestion
+	for _, node := range nodes {
+		group := node.Group()
+		if group == "" {
+			continue
+		}
+		name := normalizeName(node.Name())
+		for _, base := range nodes {
+			baseName := normalizeName(base.Name())
+			if base == node || base.Group() != group || len(baseName) < minSuggestName {
+				continue
+			}
+			if strings.HasPrefix(name, baseName+"-") {
+				suggestions = append(suggestions, EdgeSuggestion{
+					From:       node.ID,
+					To:         base.ID,
+					Confidence: ConfidenceLow,
+					Reason:     fmt.Sprintf("%s extends the name of %s in group %s", node.Name(), base.Name(), group),
+				})
+			}
+		}
+	}
+	return suggestions
+}
@@ -0,0 +1,106 @@
+package dag
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func serviceNode(ref, name string, endpoints ...string) *Node {
+	return &Node{ID: ref, Service: &sbom.Service{BOMRef: ref, Name: name, Endpoints: endpoints}}
+}
+
+func componentNode(ref, group, name string, props ...sbom.Property) *Node {
+	return &Node{ID: ref, Component: &sbom.Component{BOMRef: ref, Group: group, Name: name, Properties: props}}
+}
+
+func TestEndpointSuggestions(t *testing.T) {
+	nodes := []*Node{
+		serviceNode("gateway", "Gateway", "http://orders:8080/v1", "https://api.inventory.internal", "gateway.internal", "https://db.example.com"),
+		serviceNode("orders", "Order Service"),
+		serviceNode("inventory", "Inventory"),
+		componentNode("db", "", "db"),
+	}
+	got := endpointSuggestions(nodes, suggestNames(nodes))
+	want := []EdgeSuggestion{
+		{From: "gateway", To: "orders", Confidence: ConfidenceHigh, Reason: "endpoint http://orders:8080/v1 names orders"},
+		{From: "gateway", To: "inventory", Confidence: ConfidenceMedium, Reason: "endpoint https://api.inventory.internal names inventory"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestPropertySuggestions(t *testing.T) {
+	nodes := []*Node{
+		componentNode("orders", "", "Order Service",
+			sbom.Property{Name: "db.url", Value: "jdbc:postgresql://postgres-primary:5432/orders"},
+			sbom.Property{Name: "notes", Value: "Caches lookups in Redis Cache"},
+			sbom.Property{Name: PropertyDeployCommand, Value: "deploy kafka"}),
+		componentNode("postgres-primary", "", "PostgreSQL Primary"),
+		componentNode("cache-7", "", "Redis Cache"),
+		componentNode("kafka", "", "Kafka"),
+	}
+	got := propertySuggestions(nodes, suggestNames(nodes))
+	want := []EdgeSuggestion{
+		{From: "orders", To: "postgres-primary", Confidence: ConfidenceMedium, Reason: "property db.url mentions postgres-primary"},
+		{From: "orders", To: "cache-7", Confidence: ConfidenceLow, Reason: "property notes mentions cache-7"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestGroupSuggestions(t *testing.T) {
+	nodes := []*Node{
+		componentNode("kafka", "org.apache", "kafka"),
+		componentNode("kafka-connect", "org.apache", "kafka-connect"),
+		componentNode("kafka-ui", "com.provectus", "kafka-ui"),
+		componentNode("kafkacat", "org.apache", "kafkacat"),
+	}
+	got := groupSuggestions(nodes)
+	want := []EdgeSuggestion{
+		{From: "kafka-connect", To: "kafka", Confidence: ConfidenceLow, Reason: "kafka-connect extends the name of kafka in group org.apache"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestSuggestEdges(t *testing.T) {
+	g := loadFixture(t, "suggest-edges-1.6.json")
+	got := g.SuggestEdges()
+	want := []EdgeSuggestion{
+		{From: "api-gateway", To: "inventory", Confidence: ConfidenceMedium, Reason: "endpoint https://api.inventory.internal/ names inventory"},
+		{From: "inventory", To: "redis-cache", Confidence: ConfidenceLow, Reason: "property notes mentions redis-cache"},
+		{From: "kafka-connect", To: "kafka", Confidence: ConfidenceLow, Reason: "kafka-connect extends the name of kafka in group org.apache"},
+		{From: "orders", To: "postgres-primary", Confidence: ConfidenceMedium, Reason: "property db.url mentions postgres-primary"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestSuggestEdgesSkipsCyclesAndKeepsTheBestMatch(t *testing.T) {
+	g := New()
+	gateway := serviceNode("gateway", "Gateway", "http://orders:8080", "http://billing")
+	orders := componentNode("orders", "", "Orders", sbom.Property{Name: "upstream", Value: "gateway"})
+	billing := componentNode("billing", "", "Billing")
+	gateway.Service.Properties = []sbom.Property{{Name: "billing", Value: "billing"}}
+	for _, node := range []*Node{gateway, orders, billing} {
+		g.Nodes[node.ID] = node
+	}
+	// orders already depends on the gateway, so the endpoint match would
+	// close a cycle
+	orders.Dependencies = []*Node{gateway}
+	gateway.Dependents = []*Node{orders}
+
+	got := g.SuggestEdges()
+	want := []EdgeSuggestion{
+		{From: "gateway", To: "billing", Confidence: ConfidenceHigh, Reason: "endpoint http://billing names billing"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,109 @@
+package dag
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/ident"
+)
+
+// PropertyTFModule holds the Terraform module source for a component
+const PropertyTFModule = "bom-dagger:tf-module"
+
+// WriteTerraform writes a module block per node with bom-dagger:tf-module and
+// returns how many nodes lack the property
+func WriteTerraform(w io.Writer, g *Graph) (int, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	nodes := g.sortedNodes()
+
+	// Assign unique identifiers in a stable order
+	moduleNames := make(map[string]string)
+	namer := ident.NewNamer(ident.HCL)
+	skipped := 0
+	for _, node := range nodes {
+		if _, ok := node.Property(PropertyTFModule); !ok {
+			skipped++
+			continue
+		}
+		moduleNames[node.ID] = namer.NameFor(node.ID, node.Name())
+	}
+
+	first := true
+	for _, node := range nodes {
+		name, ok := moduleNames[node.ID]
+		if !ok {
+			continue
+		}
+		source, _ := node.Property(PropertyTFModule)
+
+		var dependsOn []string
+		for _, dep := range node.Dependencies {
+			if depName, ok := moduleNames[dep.ID]; ok {
+				dependsOn = append(dependsOn, "module."+depName)
+			}
+		}
+		sort.Strings(dependsOn)
+
+		if !first {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return skipped, err
+			}
+		}
+		first = false
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "# %s\n", hclComment(fmt.Sprintf("%s (ref: %s)", node.Name(), node.ID)))
+		fmt.Fprintf(&b, "module \"%s\" {\n", name)
+		fmt.Fprintf(&b, "  source = %s\n", hclString(source))
+		if len(dependsOn) > 0 {
+			fmt.Fprintf(&b, "\n  depends_on = [%s]\n", strings.Join(dependsOn, ", "))
+		}
+		b.WriteString("}\n")
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return skipped, err
+		}
+	}
+
+	return skipped, nil
+}
+
+// hclString quotes s as an HCL string literal, escaping template sequences
+func hclString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '$', '%':
+			// "${" and "%{" start template interpolations; double the sigil to escape
+			if i+1 < len(s) && s[i+1] == '{' {
+				b.WriteByte(c)
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// hclComment strips line breaks so text can be used in a single-line comment
+func hclComment(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
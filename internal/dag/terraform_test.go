@@ -0,0 +1,68 @@
+package dag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestWriteTerraform(t *testing.T) {
+	g := loadFixture(t, "terraform-1.6.json")
+
+	var buf bytes.Buffer
+	skipped, err := WriteTerraform(&buf, g)
+	if err != nil {
+		t.Fatalf("WriteTerraform failed: %v", err)
+	}
+	if skipped != 1 {
+		t.Errorf("Expected 1 skipped component, got %d", skipped)
+	}
+
+	assertGolden(t, "terraform-1.6.tf", buf.Bytes())
+}
+
+func TestHCLString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"./modules/web", `"./modules/web"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\modules`, `"C:\\modules"`},
+		{"ref=${version}", `"ref=$${version}"`},
+		{"%{if x}", `"%%{if x}"`},
+		{"cost $5 or 50%", `"cost $5 or 50%"`},
+		{"line\nbreak", `"line\nbreak"`},
+	}
+
+	for _, tt := range tests {
+		if got := hclString(tt.in); got != tt.want {
+			t.Errorf("hclString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteTerraformDuplicateNames(t *testing.T) {
+	g := New()
+	for _, c := range []struct{ id, name string }{{"a", "Shared Name"}, {"b", "Shared/Name"}} {
+		g.Nodes[c.id] = &Node{ID: c.id, Component: &sbom.Component{
+			Name:       c.name,
+			Properties: []sbom.Property{{Name: PropertyTFModule, Value: "./" + c.id}},
+		}}
+	}
+	g.Nodes["b"].Dependencies = []*Node{g.Nodes["a"]}
+
+	var buf bytes.Buffer
+	if _, err := WriteTerraform(&buf, g); err != nil {
+		t.Fatalf("WriteTerraform failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{`module "shared_name" {`, `module "shared_name_2" {`, `depends_on = [module.shared_name]`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
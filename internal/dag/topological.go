@@ -2,157 +2,192 @@ package dag
 
 import (
 	"fmt"
+	"math/rand"
 )
 
-// DeploymentOrder represents a deployment step
+// DeploymentOrder is one component of the deployment order
 type DeploymentOrder struct {
+	// Sequence numbers the components 1..N in order; it is distinct per
+	// component
+	Sequence int
+	// Group is the 1-based Kahn level of the component; components of a
+	// group can be deployed in parallel
+	Group int
+	// Step is Group, kept for compatibility
 	Step      int
 	Component string
 	BOMRef    string
 }
 
 // TopologicalSort performs a topological sort using Kahn's algorithm
-// Returns the deployment order (components with no dependencies first)
+// Returns the deployment order (components with no dependencies first); within
+// a group, nodes are ordered by LessWithinLevel
 func (g *Graph) TopologicalSort() ([]DeploymentOrder, error) {
-	// Create a copy of in-degrees
-	inDegree := make(map[string]int)
-	for id, node := range g.Nodes {
-		inDegree[id] = len(node.Dependencies)
-	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.topologicalSort()
+}
 
-	// Queue for nodes with no dependencies
-	queue := []*Node{}
-	for _, node := range g.Nodes {
-		if inDegree[node.ID] == 0 {
-			queue = append(queue, node)
-		}
+func (g *Graph) topologicalSort() ([]DeploymentOrder, error) {
+	levels, err := g.levels()
+	if err != nil {
+		return nil, err
 	}
+	return deploymentOrder(levels), nil
+}
 
-	var result []DeploymentOrder
-	step := 1
-
-	for len(queue) > 0 {
-		// Process all nodes at the current level
-		levelSize := len(queue)
-		levelNodes := queue[:levelSize]
-		queue = queue[levelSize:]
-
-		// Add all nodes at this level to the result
-		for _, node := range levelNodes {
-			name := getNodeName(node)
+// deploymentOrder numbers the nodes of levels in order; Group is the 1-based
+// level
+func deploymentOrder(levels [][]*Node) []DeploymentOrder {
+	n := 0
+	for _, level := range levels {
+		n += len(level)
+	}
+	result := make([]DeploymentOrder, 0, n)
+	for i, level := range levels {
+		for _, node := range level {
 			result = append(result, DeploymentOrder{
-				Step:      step,
-				Component: name,
+				Sequence:  len(result) + 1,
+				Group:     i + 1,
+				Step:      i + 1,
+				Component: getNodeName(node),
 				BOMRef:    node.ID,
 			})
-
-			// Reduce in-degree for dependent nodes
-			for _, dependent := range node.Dependents {
-				inDegree[dependent.ID]--
-				if inDegree[dependent.ID] == 0 {
-					queue = append(queue, dependent)
-				}
-			}
 		}
-
-		step++
-	}
-
-	// Check if all nodes were processed
-	if len(result) != len(g.Nodes) {
-		return nil, fmt.Errorf("cycle detected in dependency graph")
 	}
+	return result
+}
 
-	return result, nil
+// GetDeploymentGroupNodes returns the nodes grouped by deployment order, in
+// fresh slices ordered by LessWithinLevel
+func (g *Graph) GetDeploymentGroupNodes() ([][]*Node, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.levels()
 }
 
-// GetDeploymentGroups returns components grouped by deployment order
-// Components in the same group can be deployed in parallel
+// GetDeploymentGroups returns components grouped by deployment order as
+// "name (version)" strings
 func (g *Graph) GetDeploymentGroups() ([][]string, error) {
-	// Create a copy of in-degrees
-	inDegree := make(map[string]int)
-	for id, node := range g.Nodes {
-		inDegree[id] = len(node.Dependencies)
-	}
-
-	// Queue for nodes with no dependencies
-	queue := []*Node{}
-	for _, node := range g.Nodes {
-		if inDegree[node.ID] == 0 {
-			queue = append(queue, node)
-		}
+	levels, err := g.GetDeploymentGroupNodes()
+	if err != nil {
+		return nil, err
 	}
+	return displayGroups(levels), nil
+}
 
-	var groups [][]string
-	processedCount := 0
+// GetTeardownGroupNodes returns the nodes grouped by teardown order, nodes
+// nothing depends on first
+func (g *Graph) GetTeardownGroupNodes() ([][]*Node, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.teardownLevels()
+}
 
-	for len(queue) > 0 {
-		// Process all nodes at the current level
-		levelSize := len(queue)
-		levelNodes := queue[:levelSize]
-		queue = queue[levelSize:]
+// GetTeardownGroups returns components grouped by teardown order as display
+// strings, like GetDeploymentGroups
+func (g *Graph) GetTeardownGroups() ([][]string, error) {
+	levels, err := g.GetTeardownGroupNodes()
+	if err != nil {
+		return nil, err
+	}
+	return displayGroups(levels), nil
+}
 
-		// Create a group for this level
-		group := make([]string, 0, levelSize)
-		for _, node := range levelNodes {
+// displayGroups names the nodes of each level "name (version)", or just the
+// name without a version
+func displayGroups(levels [][]*Node) [][]string {
+	groups := make([][]string, 0, len(levels))
+	for _, level := range levels {
+		group := make([]string, 0, len(level))
+		for _, node := range level {
 			name := getNodeName(node)
-			version := getNodeVersion(node)
-			if version != "" {
+			if version := getNodeVersion(node); version != "" {
 				group = append(group, fmt.Sprintf("%s (%s)", name, version))
 			} else {
 				group = append(group, name)
 			}
-			processedCount++
-
-			// Reduce in-degree for dependent nodes
-			for _, dependent := range node.Dependents {
-				inDegree[dependent.ID]--
-				if inDegree[dependent.ID] == 0 {
-					queue = append(queue, dependent)
-				}
-			}
 		}
-
 		groups = append(groups, group)
 	}
-
-	// Check if all nodes were processed
-	if processedCount != len(g.Nodes) {
-		return nil, fmt.Errorf("cycle detected in dependency graph")
-	}
-
-	return groups, nil
+	return groups
 }
 
-// ReverseTopologicalSort returns the reverse deployment order (teardown order)
+// ReverseTopologicalSort returns the teardown order, grouped by
+// GetTeardownGroupNodes rather than the deployment groups renumbered
 func (g *Graph) ReverseTopologicalSort() ([]DeploymentOrder, error) {
-	order, err := g.TopologicalSort()
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	levels, err := g.teardownLevels()
 	if err != nil {
 		return nil, err
 	}
+	return deploymentOrder(levels), nil
+}
+
+// Name returns the display name of the node's component or service
+func (n *Node) Name() string {
+	return getNodeName(n)
+}
+
+// Version returns the version of the node's component or service
+func (n *Node) Version() string {
+	return getNodeVersion(n)
+}
+
+// Group returns the group (namespace) of the node's component or service
+func (n *Node) Group() string {
+	if n.Component != nil {
+		return n.Component.Group
+	}
+	if n.Service != nil {
+		return n.Service.Group
+	}
+	return ""
+}
 
-	// Reverse the order
-	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
-		order[i], order[j] = order[j], order[i]
+// Description returns the description of the node's component or service
+func (n *Node) Description() string {
+	if n.Component != nil {
+		return n.Component.Description
+	}
+	if n.Service != nil {
+		return n.Service.Description
 	}
+	return ""
+}
 
-	// Update step numbers
-	for i := range order {
-		order[i].Step = i + 1
+// Kind returns "service" for service nodes and "component" otherwise
+func (n *Node) Kind() string {
+	if n.Service != nil {
+		return "service"
 	}
+	return "component"
+}
 
-	return order, nil
+// CrossesTrustBoundary reports whether the node is a service declaring
+// x-trust-boundary: true
+func (n *Node) CrossesTrustBoundary() bool {
+	return n.Service != nil && n.Service.XTrustBoundary != nil && *n.Service.XTrustBoundary
 }
 
 // Helper functions to get node name and version for both components and services
 func getNodeName(node *Node) string {
-	if node.Component != nil {
-		return node.Component.Name
+	var name string
+	switch {
+	case node.Component != nil:
+		name = node.Component.Name
+	case node.Service != nil:
+		name = node.Service.Name
+	default:
+		return node.ID
 	}
-	if node.Service != nil {
-		return node.Service.Name
+	// Nodes from namespaced inputs show their namespace
+	if namespace, ok := node.Property(PropertyNamespace); ok && namespace != "" {
+		return namespace + "/" + name
 	}
-	return node.ID
+	return name
 }
 
 func getNodeVersion(node *Node) string {
@@ -164,3 +199,71 @@ func getNodeVersion(node *Node) string {
 	}
 	return ""
 }
+
+// RandomTopologicalOrder returns the refs in a random valid order drawn from
+// r, dependencies first; levels, priorities and pins are ignored
+func (g *Graph) RandomTopologicalOrder(r *rand.Rand) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	// Start from the sorted nodes so the order depends only on the seed
+	inDegree := make(map[*Node]int, len(g.Nodes))
+	var ready []*Node
+	for _, node := range g.sortedNodes() {
+		inDegree[node] = len(node.Dependencies)
+		if inDegree[node] == 0 {
+			ready = append(ready, node)
+		}
+	}
+
+	order := make([]string, 0, len(g.Nodes))
+	for len(ready) > 0 {
+		i := r.Intn(len(ready))
+		node := ready[i]
+		ready[i] = ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		order = append(order, node.ID)
+
+		for _, dependent := range node.Dependents {
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) != len(g.Nodes) {
+		return nil, fmt.Errorf("cycle detected in dependency graph")
+	}
+	return order, nil
+}
+
+// ValidOrder checks that order lists every node of g exactly once and each
+// node after all of its dependencies. The error names the first problem
+// found: an unknown or repeated ref, a missing node or a violated edge.
+func ValidOrder(g *Graph, order []string) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	position := make(map[string]int, len(order))
+	for i, ref := range order {
+		if _, ok := g.Nodes[ref]; !ok {
+			return fmt.Errorf("%s is not in the graph", ref)
+		}
+		if _, seen := position[ref]; seen {
+			return fmt.Errorf("%s is listed more than once", ref)
+		}
+		position[ref] = i
+	}
+	for _, node := range g.sortedNodes() {
+		if _, ok := position[node.ID]; !ok {
+			return fmt.Errorf("%s is missing from the order", node.ID)
+		}
+	}
+	for _, edge := range g.edges() {
+		if position[edge.To] > position[edge.From] {
+			return fmt.Errorf("%s comes before its dependency %s", edge.From, edge.To)
+		}
+	}
+	return nil
+}
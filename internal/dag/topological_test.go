@@ -1,6 +1,9 @@
 package dag
 
 import (
+	"math/rand"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/nprimmer/bom-dagger/internal/sbom"
@@ -9,33 +12,24 @@ import (
 func createTestGraph() *Graph {
 	g := New()
 
-	// Create components
-	compA := &sbom.Component{Name: "App", Version: "1.0"}
-	compB := &sbom.Component{Name: "Database", Version: "2.0"}
-	compC := &sbom.Component{Name: "Cache", Version: "1.5"}
-	compD := &sbom.Component{Name: "MessageQueue", Version: "3.0"}
-
 	// Create nodes
-	nodeA := &Node{ID: "app", Component: compA}
-	nodeB := &Node{ID: "db", Component: compB}
-	nodeC := &Node{ID: "cache", Component: compC}
-	nodeD := &Node{ID: "mq", Component: compD}
+	for _, c := range []*sbom.Component{
+		{BOMRef: "app", Name: "App", Version: "1.0"},
+		{BOMRef: "db", Name: "Database", Version: "2.0"},
+		{BOMRef: "cache", Name: "Cache", Version: "1.5"},
+		{BOMRef: "mq", Name: "MessageQueue", Version: "3.0"},
+	} {
+		if _, err := g.AddComponent(c); err != nil {
+			panic(err)
+		}
+	}
 
 	// Set up dependencies: App depends on DB and Cache, Cache depends on MQ
-	nodeA.Dependencies = []*Node{nodeB, nodeC}
-	nodeB.Dependents = []*Node{nodeA}
-	nodeC.Dependencies = []*Node{nodeD}
-	nodeC.Dependents = []*Node{nodeA}
-	nodeD.Dependents = []*Node{nodeC}
-
-	// Add to graph
-	g.Nodes["app"] = nodeA
-	g.Nodes["db"] = nodeB
-	g.Nodes["cache"] = nodeC
-	g.Nodes["mq"] = nodeD
-
-	// Set roots (nodes with no dependencies)
-	g.Roots = []*Node{nodeB, nodeD}
+	for _, edge := range []Edge{{"app", "db"}, {"app", "cache"}, {"cache", "mq"}} {
+		if err := g.AddDependency(edge.From, edge.To); err != nil {
+			panic(err)
+		}
+	}
 
 	return g
 }
@@ -87,7 +81,7 @@ func TestReverseTopologicalSort(t *testing.T) {
 	// Create a map to track teardown order
 	orderMap := make(map[string]int)
 	for _, item := range order {
-		orderMap[item.BOMRef] = item.Step
+		orderMap[item.BOMRef] = item.Sequence
 	}
 
 	// Verify reverse order
@@ -104,6 +98,63 @@ func TestReverseTopologicalSort(t *testing.T) {
 	}
 }
 
+// assertSequence checks that the Sequence numbers of order are 1..N in
+// order, that every dependency has a lower number than its dependents
+// (higher with teardown) and that Step is Group
+func assertSequence(t *testing.T, g *Graph, order []DeploymentOrder, teardown bool) {
+	t.Helper()
+	if len(order) != g.GetNodeCount() {
+		t.Fatalf("Expected %d entries, got %d", g.GetNodeCount(), len(order))
+	}
+	sequence := make(map[string]int, len(order))
+	for i, item := range order {
+		if item.Sequence != i+1 {
+			t.Errorf("Expected %s at position %d to have sequence %d, got %d", item.BOMRef, i, i+1, item.Sequence)
+		}
+		if item.Step != item.Group {
+			t.Errorf("Expected the step of %s to be its group %d, got %d", item.BOMRef, item.Group, item.Step)
+		}
+		if i > 0 && item.Group < order[i-1].Group {
+			t.Errorf("Expected groups not to decrease, got %d after %d", item.Group, order[i-1].Group)
+		}
+		sequence[item.BOMRef] = item.Sequence
+	}
+	for _, node := range g.Nodes {
+		for _, dep := range node.Dependencies {
+			if before := sequence[dep.ID] < sequence[node.ID]; before == teardown {
+				t.Errorf("Sequence of %s (%d) is inconsistent with its dependency %s (%d)", node.ID, sequence[node.ID], dep.ID, sequence[dep.ID])
+			}
+		}
+	}
+}
+
+func TestTopologicalSortNumbering(t *testing.T) {
+	for _, name := range []string{"diamond-1.6.json", "microservices-1.6.json"} {
+		t.Run(name, func(t *testing.T) {
+			g := loadFixture(t, name)
+			order, err := g.TopologicalSort()
+			if err != nil {
+				t.Fatalf("TopologicalSort failed: %v", err)
+			}
+			assertSequence(t, g, order, false)
+
+			levels, _ := g.GetDeploymentGroupNodes()
+			if last := order[len(order)-1].Group; last != len(levels) {
+				t.Errorf("Expected the last group to be %d, got %d", len(levels), last)
+			}
+
+			reversed, err := g.ReverseTopologicalSort()
+			if err != nil {
+				t.Fatalf("ReverseTopologicalSort failed: %v", err)
+			}
+			assertSequence(t, g, reversed, true)
+			if reversed[0].Group != 1 || reversed[len(reversed)-1].Group != len(levels) {
+				t.Errorf("Expected teardown groups 1..%d, got %d..%d", len(levels), reversed[0].Group, reversed[len(reversed)-1].Group)
+			}
+		})
+	}
+}
+
 func TestGetDeploymentGroups(t *testing.T) {
 	g := createTestGraph()
 
@@ -136,6 +187,144 @@ func TestGetDeploymentGroups(t *testing.T) {
 	}
 }
 
+// TestTeardownGroups adds a worker on the database of the diamond. The worker
+// deploys with the APIs but nothing depends on it, so it is torn down in the
+// first group with the application rather than after it.
+func TestTeardownGroups(t *testing.T) {
+	g := loadFixture(t, "diamond-1.6.json")
+	if _, err := g.AddComponent(&sbom.Component{BOMRef: "worker", Name: "Worker", Type: "application"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddDependency("worker", "db"); err != nil {
+		t.Fatal(err)
+	}
+
+	refs := func(levels [][]*Node) [][]string {
+		var groups [][]string
+		for _, level := range levels {
+			var group []string
+			for _, node := range level {
+				group = append(group, node.ID)
+			}
+			groups = append(groups, group)
+		}
+		return groups
+	}
+	deployment, err := g.GetDeploymentGroupNodes()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroupNodes failed: %v", err)
+	}
+	if want := [][]string{{"db"}, {"api-a", "api-b", "worker"}, {"app"}}; !reflect.DeepEqual(refs(deployment), want) {
+		t.Fatalf("Expected deployment groups %v, got %v", want, refs(deployment))
+	}
+	teardown, err := g.GetTeardownGroupNodes()
+	if err != nil {
+		t.Fatalf("GetTeardownGroupNodes failed: %v", err)
+	}
+	if want := [][]string{{"app", "worker"}, {"api-a", "api-b"}, {"db"}}; !reflect.DeepEqual(refs(teardown), want) {
+		t.Errorf("Expected teardown groups %v, got %v", want, refs(teardown))
+	}
+
+	groups, err := g.GetTeardownGroups()
+	if err != nil {
+		t.Fatalf("GetTeardownGroups failed: %v", err)
+	}
+	if want := []string{"Application (1.0.0)", "Worker"}; !reflect.DeepEqual(groups[0], want) {
+		t.Errorf("Expected the first teardown group %v, got %v", want, groups[0])
+	}
+
+	order, err := g.ReverseTopologicalSort()
+	if err != nil {
+		t.Fatalf("ReverseTopologicalSort failed: %v", err)
+	}
+	assertSequence(t, g, order, true)
+	for _, item := range order {
+		if item.BOMRef == "worker" && item.Group != 1 {
+			t.Errorf("Expected worker in teardown group 1, got %d", item.Group)
+		}
+	}
+}
+
+func TestGetDeploymentGroupNodes(t *testing.T) {
+	g := createTestGraph()
+
+	groups, err := g.GetDeploymentGroupNodes()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroupNodes failed: %v", err)
+	}
+	var refs [][]string
+	seen := make(map[*Node]bool)
+	for _, group := range groups {
+		refs = append(refs, nodeIDs(group))
+		for _, node := range group {
+			// The groups hold the graph's own nodes, each exactly once
+			if g.Nodes[node.ID] != node {
+				t.Errorf("Expected %s to be the graph's node", node.ID)
+			}
+			if seen[node] {
+				t.Errorf("Expected %s in one group only", node.ID)
+			}
+			seen[node] = true
+		}
+	}
+	if len(seen) != len(g.Nodes) {
+		t.Errorf("Expected every node in a group, got %d of %d", len(seen), len(g.Nodes))
+	}
+	if want := [][]string{{"db", "mq"}, {"cache"}, {"app"}}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("Expected groups %v, got %v", want, refs)
+	}
+
+	// The string form renders the same groups
+	labels, err := g.GetDeploymentGroups()
+	if err != nil {
+		t.Fatalf("GetDeploymentGroups failed: %v", err)
+	}
+	for i, group := range groups {
+		for j, node := range group {
+			want := node.Name()
+			if node.Version() != "" {
+				want += " (" + node.Version() + ")"
+			}
+			if labels[i][j] != want {
+				t.Errorf("Group %d: expected %q, got %q", i+1, want, labels[i][j])
+			}
+		}
+	}
+
+	// Reordering a returned group leaves the next call alone
+	groups[0][0], groups[0][1] = groups[0][1], groups[0][0]
+	again, _ := g.GetDeploymentGroupNodes()
+	if again[0][0].ID != "db" {
+		t.Errorf("Expected fresh groups on every call, got %s first", again[0][0].ID)
+	}
+}
+
+func TestOlderSpecVersionsDeployInSameOrder(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"diamond-1.4.json", "diamond-1.6.json"},
+		{"microservices-1.5.json", "microservices-1.6.json"},
+	} {
+		t.Run(pair[0], func(t *testing.T) {
+			older, current := loadFixture(t, pair[0]), loadFixture(t, pair[1])
+			if diff := DiffNodes(current, older); diff != nil {
+				t.Errorf("Expected the same graph, got %q", diff)
+			}
+
+			want, err := current.GetDeploymentGroups()
+			if err != nil {
+				t.Fatalf("GetDeploymentGroups failed: %v", err)
+			}
+			got, err := older.GetDeploymentGroups()
+			if err != nil {
+				t.Fatalf("GetDeploymentGroups failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Deployment groups differ:\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
+
 func TestTopologicalSortWithCycle(t *testing.T) {
 	g := New()
 
@@ -160,3 +349,60 @@ func TestTopologicalSortWithCycle(t *testing.T) {
 		t.Error("Expected error for cyclic graph, but got none")
 	}
 }
+
+func TestRandomTopologicalOrder(t *testing.T) {
+	g := createTestGraph()
+
+	// App comes last; the database can go anywhere before it
+	seen := make(map[string]bool)
+	for seed := int64(0); seed < 50; seed++ {
+		order, err := g.RandomTopologicalOrder(rand.New(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatalf("RandomTopologicalOrder failed: %v", err)
+		}
+		if err := ValidOrder(g, order); err != nil {
+			t.Fatalf("Seed %d gave an invalid order %v: %v", seed, order, err)
+		}
+		again, _ := g.RandomTopologicalOrder(rand.New(rand.NewSource(seed)))
+		if !reflect.DeepEqual(order, again) {
+			t.Errorf("Seed %d gave %v, then %v", seed, order, again)
+		}
+		seen[strings.Join(order, " ")] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected all 3 valid orders, got %v", seen)
+	}
+
+	g.DeferCycleChecks = true
+	if err := g.AddDependency("mq", "app"); err != nil {
+		t.Fatalf("AddDependency failed: %v", err)
+	}
+	if _, err := g.RandomTopologicalOrder(rand.New(rand.NewSource(1))); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+}
+
+func TestValidOrder(t *testing.T) {
+	g := createTestGraph()
+
+	tests := []struct {
+		order []string
+		want  string
+	}{
+		{[]string{"mq", "db", "cache", "app"}, ""},
+		{[]string{"db", "mq", "cache", "app"}, ""},
+		{[]string{"db", "cache", "mq", "app"}, "cache comes before its dependency mq"},
+		{[]string{"mq", "cache", "app"}, "db is missing from the order"},
+		{[]string{"mq", "db", "cache", "cache", "app"}, "cache is listed more than once"},
+		{[]string{"mq", "db", "cache", "web", "app"}, "web is not in the graph"},
+	}
+	for _, tt := range tests {
+		err := ValidOrder(g, tt.order)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("Expected %v to be valid, got %v", tt.order, err)
+		case tt.want != "" && (err == nil || err.Error() != tt.want):
+			t.Errorf("Expected %v to fail with %q, got %v", tt.order, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package dag
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// TreeOptions controls WriteTree
+type TreeOptions struct {
+	// From roots the tree at a single node instead of every graph root
+	From string
+	// MaxDepth limits how many levels below the root are printed (0 = unlimited)
+	MaxDepth int
+	// Label renders node labels; nil uses the name and bom-ref. Line breaks
+	// in rendered labels are replaced with spaces.
+	Label *LabelTemplate
+	// MaxLabelLen shortens the names and refs in labels longer than this
+	// many characters (0 = never; see ident.Shorten)
+	MaxLabelLen int
+}
+
+// WriteTree prints the graph as a tree from each root through its
+// dependents, marking nodes already printed with "…"
+func WriteTree(w io.Writer, g *Graph, opts TreeOptions) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var roots []*Node
+	if opts.From != "" {
+		node, ok := g.Nodes[opts.From]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownNode, opts.From)
+		}
+		roots = []*Node{node}
+	} else {
+		roots = sortedByID(g.Roots)
+	}
+
+	tw := &treeWriter{w: w, opts: opts, printed: make(map[string]bool)}
+	for _, root := range roots {
+		tw.write(root, "", "", 0)
+	}
+	return tw.err
+}
+
+type treeWriter struct {
+	w       io.Writer
+	opts    TreeOptions
+	printed map[string]bool
+	err     error
+}
+
+func (tw *treeWriter) write(node *Node, prefix, childPrefix string, depth int) {
+	if tw.err != nil {
+		return
+	}
+
+	label, err := nodeLabel(tw.opts.Label, node, treeLabel, tw.opts.MaxLabelLen)
+	if err != nil {
+		tw.err = err
+		return
+	}
+	label = treeLineBreaks.Replace(label)
+	if tw.printed[node.ID] {
+		_, tw.err = fmt.Fprintf(tw.w, "%s%s …\n", prefix, label)
+		return
+	}
+	tw.printed[node.ID] = true
+	if _, tw.err = fmt.Fprintf(tw.w, "%s%s\n", prefix, label); tw.err != nil {
+		return
+	}
+
+	if tw.opts.MaxDepth > 0 && depth >= tw.opts.MaxDepth {
+		return
+	}
+
+	children := sortedByID(node.Dependents)
+	for i, child := range children {
+		if i == len(children)-1 {
+			tw.write(child, childPrefix+"└── ", childPrefix+"    ", depth+1)
+		} else {
+			tw.write(child, childPrefix+"├── ", childPrefix+"│   ", depth+1)
+		}
+	}
+}
+
+// treeLabel is the default tree label
+func treeLabel(data LabelData) string {
+	if data.node.Synthetic {
+		return fmt.Sprintf("%s (ref: %s) (external)", data.Name, data.Ref)
+	}
+	return fmt.Sprintf("%s (ref: %s)", data.Name, data.Ref)
+}
+
+var treeLineBreaks = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// sortedByID returns a copy of nodes sorted by ID
+func sortedByID(nodes []*Node) []*Node {
+	sorted := append([]*Node{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
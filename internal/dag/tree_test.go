@@ -0,0 +1,78 @@
+package dag
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWriteTree(t *testing.T) {
+	g := loadFixture(t, "diamond-1.6.json")
+
+	tests := []struct {
+		name string
+		opts TreeOptions
+		want string
+	}{
+		{
+			name: "all roots",
+			want: "Database (ref: db)\n" +
+				"├── API A (ref: api-a)\n" +
+				"│   └── Application (ref: app)\n" +
+				"└── API B (ref: api-b)\n" +
+				"    └── Application (ref: app) …\n",
+		},
+		{
+			name: "from node",
+			opts: TreeOptions{From: "api-b"},
+			want: "API B (ref: api-b)\n" +
+				"└── Application (ref: app)\n",
+		},
+		{
+			name: "depth limit",
+			opts: TreeOptions{MaxDepth: 1},
+			want: "Database (ref: db)\n" +
+				"├── API A (ref: api-a)\n" +
+				"└── API B (ref: api-b)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteTree(&buf, g, tt.opts); err != nil {
+				t.Fatalf("WriteTree failed: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Unexpected tree\nGot:\n%s\nWant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTreeUnknownRoot(t *testing.T) {
+	g := loadFixture(t, "diamond-1.6.json")
+
+	var buf bytes.Buffer
+	if err := WriteTree(&buf, g, TreeOptions{From: "nope"}); !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expected ErrUnknownNode, got %v", err)
+	}
+}
+
+func TestWriteTreeMultipleRoots(t *testing.T) {
+	g := createTestGraph()
+
+	var buf bytes.Buffer
+	if err := WriteTree(&buf, g, TreeOptions{}); err != nil {
+		t.Fatalf("WriteTree failed: %v", err)
+	}
+
+	want := "Database (ref: db)\n" +
+		"└── App (ref: app)\n" +
+		"MessageQueue (ref: mq)\n" +
+		"└── Cache (ref: cache)\n" +
+		"    └── App (ref: app) …\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected tree\nGot:\n%s\nWant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,133 @@
+// Package graphfile saves a built graph for --from-graph; unlike a cache
+// entry, a file this build cannot read is an error
+package graphfile
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/output"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// FormatVersion is bumped whenever File or dag.Snapshot change shape; see
+// also cache.FormatVersion
+const FormatVersion = 4
+
+// magic starts every graph file, so other files are told apart from
+// corrupted ones
+const magic = "bom-dagger graph"
+
+var (
+	// ErrNotGraphFile reports a file that was not written by -o graph
+	ErrNotGraphFile = errors.New("not a bom-dagger graph file")
+	// ErrNewerFormat reports a graph file written by a newer bom-dagger
+	ErrNewerFormat = errors.New("graph file was written by a newer bom-dagger")
+	// ErrOlderFormat reports a graph file in a format this build no longer
+	// reads
+	ErrOlderFormat = errors.New("graph file was written by an older bom-dagger")
+)
+
+// File is a graph with everything a run needs from parsing and building it
+type File struct {
+	// BOM is the merged SBOM without its components, services, dependencies
+	// and compositions, which the graph already holds
+	BOM *sbom.CycloneDX
+	// Provenance is that of the run that wrote the file; its inputs are the
+	// SBOMs the graph was built from
+	Provenance *plan.Provenance
+	// ParseWarnings and MetadataWarnings are replayed on loading, so a run
+	// from the file logs and fails (--strict) like one from the SBOMs
+	ParseWarnings    []warning.Warning
+	MetadataWarnings []warning.Warning
+	Graph            *dag.Snapshot
+}
+
+// header precedes the File
+type header struct {
+	Magic  string
+	Format int
+}
+
+func init() {
+	output.Register(output.Func("graph", "The graph itself, in a binary format that --from-graph loads", writeGraph))
+}
+
+// writeGraph writes the graph as it would be planned, after --reachable-only,
+// --prune-leaves and --redact
+func writeGraph(w io.Writer, p *plan.Plan, g *dag.Graph, opts output.Options) error {
+	f := &File{
+		BOM:              &sbom.CycloneDX{},
+		Provenance:       p.Provenance,
+		ParseWarnings:    opts.ParseWarnings,
+		MetadataWarnings: opts.MetadataWarnings,
+		Graph:            g.Snapshot(),
+	}
+	if bom := opts.BOM; bom != nil {
+		f.BOM = &sbom.CycloneDX{
+			BOMFormat:    bom.BOMFormat,
+			SpecVersion:  bom.SpecVersion,
+			SerialNumber: bom.SerialNumber,
+			Version:      bom.Version,
+			Metadata:     bom.Metadata,
+		}
+	}
+	return Write(w, f)
+}
+
+// Write encodes f to w
+func Write(w io.Writer, f *File) error {
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(header{Magic: magic, Format: FormatVersion}); err != nil {
+		return err
+	}
+	return enc.Encode(f)
+}
+
+// Read decodes a File from r. A file in another format version is an error
+// wrapping ErrNewerFormat or ErrOlderFormat; the header is checked before
+// the rest is decoded, so such files never decode wrongly.
+func Read(r io.Reader) (*File, error) {
+	dec := gob.NewDecoder(r)
+	var h header
+	if err := dec.Decode(&h); err != nil || h.Magic != magic {
+		return nil, ErrNotGraphFile
+	}
+	switch {
+	case h.Format > FormatVersion:
+		return nil, fmt.Errorf("%w (format %d, this build reads format %d); upgrade bom-dagger", ErrNewerFormat, h.Format, FormatVersion)
+	case h.Format < FormatVersion:
+		return nil, fmt.Errorf("%w (format %d, this build reads format %d); write it again with -o graph", ErrOlderFormat, h.Format, FormatVersion)
+	}
+	var f File
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("decoding graph file: %w", err)
+	}
+	if f.BOM == nil {
+		f.BOM = &sbom.CycloneDX{}
+	}
+	if f.Provenance == nil {
+		f.Provenance = &plan.Provenance{}
+	}
+	if f.Graph == nil {
+		// gob leaves out the snapshot of an empty graph
+		f.Graph = &dag.Snapshot{}
+	}
+	return &f, nil
+}
+
+// Load reads the graph file at path
+func Load(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
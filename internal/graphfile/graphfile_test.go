@@ -0,0 +1,182 @@
+package graphfile
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/output"
+	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/testgen"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// build parses and builds path the way the CLI does without --from-graph
+func build(tb testing.TB, path string) *dag.Graph {
+	tb.Helper()
+	p := parser.New()
+	bom, err := p.ParseFile(path)
+	if err != nil {
+		tb.Fatalf("ParseFile failed: %v", err)
+	}
+	g := dag.New()
+	if err := g.BuildFromSBOM(bom, p.GetComponentMap(bom)); err != nil {
+		tb.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	return g
+}
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join("..", "..", "testdata", "sboms", "microservices-1.6.json")
+	bom, err := parser.New().ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	g := build(t, path)
+	prov := &plan.Provenance{Tool: "bom-dagger", Inputs: []plan.ProvenanceInput{plan.NewProvenanceInput(path, bom)}, Env: "prod"}
+
+	w, err := output.Lookup("graph")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	var buf bytes.Buffer
+	opts := output.Options{
+		BOM:              bom,
+		ParseWarnings:    []warning.Warning{warning.New(warning.UnknownField, "parse warning")},
+		MetadataWarnings: []warning.Warning{warning.New(warning.UnmatchedMetadata, "metadata warning")},
+	}
+	if err := w.Write(&buf, &plan.Plan{Provenance: prov}, g, opts); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	f, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	loaded, err := dag.FromSnapshot(f.Graph, dag.BuildOptions{})
+	if err != nil {
+		t.Fatalf("FromSnapshot failed: %v", err)
+	}
+	if loaded.GetNodeCount() != g.GetNodeCount() || loaded.GetEdgeCount() != g.GetEdgeCount() {
+		t.Errorf("Expected %d nodes and %d edges, got %d and %d",
+			g.GetNodeCount(), g.GetEdgeCount(), loaded.GetNodeCount(), loaded.GetEdgeCount())
+	}
+	if loaded.Fingerprint() != g.Fingerprint() {
+		t.Errorf("Fingerprint changed: %s, want %s", loaded.Fingerprint(), g.Fingerprint())
+	}
+	if f.BOM.SerialNumber != bom.SerialNumber || f.BOM.Metadata == nil || len(f.BOM.Components) != 0 {
+		t.Errorf("Expected the BOM metadata without components, got %+v", f.BOM)
+	}
+	if f.Provenance.Env != "prod" || len(f.Provenance.Inputs) != 1 || f.Provenance.Inputs[0].Path != path {
+		t.Errorf("Expected the provenance back, got %+v", f.Provenance)
+	}
+	if !reflect.DeepEqual(f.ParseWarnings, opts.ParseWarnings) || !reflect.DeepEqual(f.MetadataWarnings, opts.MetadataWarnings) {
+		t.Errorf("Expected the warnings back, got %q and %q", f.ParseWarnings, f.MetadataWarnings)
+	}
+}
+
+func TestRoundTripEmptyGraph(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, &File{Graph: dag.New().Snapshot()}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	f, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if f.BOM == nil || f.Provenance == nil || f.Graph == nil {
+		t.Fatalf("Expected non-nil BOM, provenance and graph, got %+v", f)
+	}
+	g, err := dag.FromSnapshot(f.Graph, dag.BuildOptions{})
+	if err != nil || g.GetNodeCount() != 0 {
+		t.Errorf("Expected an empty graph, got %v nodes, error %v", g.GetNodeCount(), err)
+	}
+}
+
+func TestReadOtherFormats(t *testing.T) {
+	encode := func(h header) []byte {
+		var buf bytes.Buffer
+		enc := gob.NewEncoder(&buf)
+		if err := enc.Encode(h); err != nil {
+			t.Fatal(err)
+		}
+		// Whatever a newer format holds, it is not decoded
+		if err := enc.Encode(struct{ Future []int }{[]int{1}}); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"newer format", encode(header{Magic: magic, Format: FormatVersion + 1}), ErrNewerFormat},
+		{"older format", encode(header{Magic: magic, Format: FormatVersion - 1}), ErrOlderFormat},
+		{"other gob", encode(header{Magic: "something else", Format: FormatVersion}), ErrNotGraphFile},
+		{"json", []byte(`{"bomFormat": "CycloneDX"}`), ErrNotGraphFile},
+		{"empty", nil, ErrNotGraphFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// benchmarkInput writes a large generated SBOM and returns its path
+func benchmarkInput(b *testing.B) string {
+	b.Helper()
+	data, err := json.Marshal(testgen.GenerateBOM(testgen.Options{Nodes: 20000, EdgeDensity: 3, Seed: 1}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	path := filepath.Join(b.TempDir(), "large.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// BenchmarkParseAndBuild is the cost of planning from the SBOM; compare
+// BenchmarkLoad, the cost with --from-graph, on the same input
+func BenchmarkParseAndBuild(b *testing.B) {
+	input := benchmarkInput(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		build(b, input)
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	input := benchmarkInput(b)
+	path := filepath.Join(b.TempDir(), "large.graph")
+	var buf bytes.Buffer
+	if err := Write(&buf, &File{Graph: build(b, input).Snapshot()}); err != nil {
+		b.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f, err := Load(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := dag.FromSnapshot(f.Graph, dag.BuildOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
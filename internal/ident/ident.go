@@ -0,0 +1,278 @@
+// Package ident turns bom-refs and names into identifiers for the output
+// formats
+package ident
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Style is a set of identifier rules
+type Style int
+
+const (
+	// DOT is the contents of a double-quoted Graphviz ID. Every string is
+	// kept, with backslashes, double quotes and line breaks escaped.
+	DOT Style = iota
+	// MermaidID is a Mermaid flowchart node ID: ASCII letters, digits and
+	// underscores, starting with a letter and never a flowchart keyword
+	MermaidID
+	// DNS1123 is a DNS-1123 label as used for Kubernetes and Backstage
+	// names: at most 63 lower-case letters, digits and hyphens, starting and
+	// ending with a letter or digit
+	DNS1123
+	// YAMLKey is a plain YAML mapping key such as a CI job name: ASCII
+	// letters, digits, underscores and hyphens, starting with a letter or
+	// underscore and never a YAML 1.1 boolean or null
+	YAMLKey
+	// Shell is a word that needs no quoting in POSIX shells and is also a
+	// portable file name: at most 200 ASCII letters, digits, dots,
+	// underscores and hyphens, not starting with a dot or hyphen
+	Shell
+	// HCL is a Terraform identifier: lower-case letters, digits, underscores
+	// and hyphens, starting with a letter or underscore
+	HCL
+	// SPDXID is the part of an SPDX element ID after "SPDXRef-": ASCII
+	// letters, digits, dots and hyphens
+	SPDXID
+)
+
+// Unnamed replaces refs that have nothing left after sanitization
+const Unnamed = "unnamed"
+
+var styleNames = map[Style]string{
+	DOT:       "dot",
+	MermaidID: "mermaid-id",
+	DNS1123:   "dns-1123",
+	YAMLKey:   "yaml-key",
+	Shell:     "shell",
+	HCL:       "hcl",
+	SPDXID:    "spdx-id",
+}
+
+func (s Style) String() string {
+	if name, ok := styleNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Style(%d)", int(s))
+}
+
+// rules describe the restricted styles; DOT escapes instead
+type rules struct {
+	valid    func(r rune) bool // Runes kept; the rest become sep
+	sep      rune              // Replaces invalid runes
+	collapse bool              // Runs of sep become one
+	lower    bool              // Fold to lower case first
+	trim     string            // Trimmed from both ends
+	mayStart func(r rune) bool // Runes an identifier may start with (nil = any)
+	prefix   string            // Prepended when the first rune may not start an identifier
+	reserved map[string]bool   // Words that get sep appended, compared in lower case
+	maxLen   int               // 0 = unlimited
+	suffix   string            // Separates a Namer's collision suffix
+}
+
+func isLetter(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') }
+func isDigit(r rune) bool  { return r >= '0' && r <= '9' }
+
+var styleRules = map[Style]rules{
+	MermaidID: {
+		valid:    func(r rune) bool { return isLetter(r) || isDigit(r) || r == '_' },
+		sep:      '_',
+		trim:     "_",
+		prefix:   "n_",
+		mayStart: isLetter,
+		suffix:   "_",
+		// Keywords that end or redirect a flowchart statement
+		reserved: map[string]bool{"end": true, "graph": true, "flowchart": true, "subgraph": true, "click": true,
+			"style": true, "class": true, "classdef": true, "linkstyle": true, "direction": true, "default": true},
+		collapse: true,
+	},
+	DNS1123: {
+		valid:    func(r rune) bool { return (r >= 'a' && r <= 'z') || isDigit(r) || r == '-' },
+		sep:      '-',
+		lower:    true,
+		trim:     "-",
+		maxLen:   63,
+		suffix:   "-",
+		collapse: true,
+	},
+	YAMLKey: {
+		valid:    func(r rune) bool { return isLetter(r) || isDigit(r) || r == '_' || r == '-' },
+		sep:      '_',
+		trim:     "_-",
+		prefix:   "_",
+		mayStart: func(r rune) bool { return isLetter(r) || r == '_' },
+		reserved: map[string]bool{"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+			"y": true, "n": true, "null": true},
+		suffix:   "_",
+		collapse: true,
+	},
+	Shell: {
+		valid:    func(r rune) bool { return isLetter(r) || isDigit(r) || r == '.' || r == '_' || r == '-' },
+		sep:      '_',
+		maxLen:   200,
+		prefix:   "_",
+		mayStart: func(r rune) bool { return r != '.' && r != '-' },
+		suffix:   "-",
+	},
+	HCL: {
+		valid:    func(r rune) bool { return (r >= 'a' && r <= 'z') || isDigit(r) || r == '_' || r == '-' },
+		sep:      '_',
+		lower:    true,
+		trim:     "_-",
+		prefix:   "_",
+		mayStart: func(r rune) bool { return isLetter(r) || r == '_' },
+		suffix:   "_",
+		collapse: true,
+	},
+	SPDXID: {
+		valid:    func(r rune) bool { return isLetter(r) || isDigit(r) || r == '.' || r == '-' },
+		sep:      '-',
+		trim:     "-",
+		suffix:   "-",
+		collapse: true,
+	},
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// Sanitize converts ref into an identifier of the given style. Different
+// refs can give the same identifier; use a Namer to keep them apart.
+func Sanitize(ref string, style Style) string {
+	if style == DOT {
+		return dotEscaper.Replace(ref)
+	}
+	r, ok := styleRules[style]
+	if !ok {
+		panic("ident: unknown style " + style.String())
+	}
+
+	if r.lower {
+		ref = strings.ToLower(ref)
+	}
+	var b strings.Builder
+	lastSep := false
+	for _, c := range ref {
+		if !r.valid(c) {
+			c = r.sep
+		}
+		if c == r.sep && lastSep && r.collapse {
+			continue
+		}
+		lastSep = c == r.sep
+		b.WriteRune(c)
+	}
+
+	id := strings.Trim(b.String(), r.trim)
+	if id == "" {
+		id = Unnamed
+	}
+	if r.mayStart != nil && !r.mayStart(rune(id[0])) {
+		id = r.prefix + id
+	}
+	if r.reserved[strings.ToLower(id)] {
+		id += string(r.sep)
+	}
+	return r.truncate(id, 0)
+}
+
+// truncate shortens id so that a suffix of suffixLen bytes still fits within
+// the style's length limit. Sanitized identifiers are ASCII.
+func (r rules) truncate(id string, suffixLen int) string {
+	if r.maxLen == 0 || len(id)+suffixLen <= r.maxLen {
+		return id
+	}
+	return strings.TrimRight(id[:r.maxLen-suffixLen], r.trim)
+}
+
+// Namer hands out unique identifiers of one style, suffixing taken ones with
+// _2, _3 (-2, -3 for DNS1123, Shell and SPDXID)
+type Namer struct {
+	style Style
+	names map[string]string // By ref
+	used  map[string]bool
+}
+
+// NewNamer returns a Namer for style
+func NewNamer(style Style) *Namer {
+	return &Namer{style: style, names: make(map[string]string), used: make(map[string]bool)}
+}
+
+// Name returns the identifier of ref, the same one every time ref is named
+func (n *Namer) Name(ref string) string {
+	return n.NameFor(ref, ref)
+}
+
+// NameFor is Name for identifiers derived from something other than the ref,
+// such as a display name: s is sanitized and ref keeps the result stable
+func (n *Namer) NameFor(ref, s string) string {
+	if name, ok := n.names[ref]; ok {
+		return name
+	}
+	base := Sanitize(s, n.style)
+	name := base
+	for i := 2; n.used[name]; i++ {
+		name = n.suffixed(base, i)
+	}
+	n.used[name] = true
+	n.names[ref] = name
+	return name
+}
+
+// Reserve marks id as taken, for identifiers a writer assigns itself
+func (n *Namer) Reserve(id string) {
+	n.used[id] = true
+}
+
+// suffixed appends the i-th collision suffix to base, shortening base when
+// the style limits the length
+func (n *Namer) suffixed(base string, i int) string {
+	r, ok := styleRules[n.style]
+	if !ok {
+		// DOT escaping is one-to-one, so distinct refs never collide
+		return fmt.Sprintf("%s-%d", base, i)
+	}
+	suffix := fmt.Sprintf("%s%d", r.suffix, i)
+	return r.truncate(base, len(suffix)) + suffix
+}
+
+const (
+	// DefaultMaxLabelLen is the length names and refs are shortened to in
+	// output meant for people
+	DefaultMaxLabelLen = 80
+	// MinMaxLabelLen is the shortest length Shorten accepts: a few
+	// characters of the original, the ellipsis and the hash
+	MinMaxLabelLen = 16
+
+	// labelHashLen is the number of hex digits of the hash suffix
+	labelHashLen = 8
+)
+
+// Shorten cuts s to maxLen runes, ending in "…" and a hash of s; maxLen <= 0
+// never shortens and smaller ones are raised to MinMaxLabelLen
+func Shorten(s string, maxLen int) string {
+	if maxLen <= 0 || utf8.RuneCountInString(s) <= maxLen {
+		return s
+	}
+	maxLen = max(maxLen, MinMaxLabelLen)
+	sum := sha256.Sum256([]byte(s))
+	suffix := "…" + hex.EncodeToString(sum[:])[:labelHashLen]
+	runes := []rune(s)
+	return string(runes[:maxLen-utf8.RuneCountInString(suffix)]) + suffix
+}
+
+// ShortenLines is Shorten applied to each line of s, for labels that put
+// the name and the version on lines of their own
+func ShortenLines(s string, maxLen int) string {
+	if maxLen <= 0 || utf8.RuneCountInString(s) <= maxLen {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = Shorten(line, maxLen)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,168 @@
+package ident
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitize(t *testing.T) {
+	long := strings.Repeat("component-", 30)
+
+	tests := []struct {
+		ref   string
+		style Style
+		want  string
+	}{
+		// DOT keeps everything and escapes
+		{`say "hi"`, DOT, `say \"hi\"`},
+		{`C:\path`, DOT, `C:\\path`},
+		{"two\r\nlines\n", DOT, `two\nlines\n`},
+		{"pkg:npm/%40scope/name@1.0", DOT, "pkg:npm/%40scope/name@1.0"},
+		{"🚀 rocket", DOT, "🚀 rocket"},
+
+		{"pkg:npm/%40scope/name@1.0", MermaidID, "pkg_npm_40scope_name_1_0"},
+		{`say "hi" --> x`, MermaidID, "say_hi_x"},
+		{"urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79", MermaidID, "urn_uuid_3e671687_395b_41f5_a30f_a58921a69b79"},
+		{"42", MermaidID, "n_42"},
+		{"_private", MermaidID, "private"},
+		{"end", MermaidID, "end_"},
+		{"Graph", MermaidID, "Graph_"},
+		{"🚀", MermaidID, "unnamed"},
+		{"", MermaidID, "unnamed"},
+
+		{"Web Frontend", DNS1123, "web-frontend"},
+		{"PostgreSQL Primary", DNS1123, "postgresql-primary"},
+		{"--Leading/Trailing--", DNS1123, "leading-trailing"},
+		{"lib.core_v2", DNS1123, "lib-core-v2"},
+		{"!!!", DNS1123, "unnamed"},
+		{"Ünïcode 🚀", DNS1123, "n-code"},
+		{strings.Repeat("a", 70), DNS1123, strings.Repeat("a", 63)},
+		{long, DNS1123, strings.TrimSuffix(long[:63], "-")},
+
+		{"deploy web", YAMLKey, "deploy_web"},
+		{"yes", YAMLKey, "yes_"},
+		{"NULL", YAMLKey, "NULL_"},
+		{"3rd-party", YAMLKey, "_3rd-party"},
+		{"a: b", YAMLKey, "a_b"},
+
+		{"pkg:npm/%40scope/name@1.0", Shell, "pkg_npm__40scope_name_1.0"},
+		{"../../etc/passwd", Shell, "_.._.._etc_passwd"},
+		{"-rf", Shell, "_-rf"},
+		{"$(rm -rf /)", Shell, "__rm_-rf___"},
+		{"it's", Shell, "it_s"},
+		{long, Shell, long[:200]},
+
+		{"Web Frontend", HCL, "web_frontend"},
+		{`API "Core" Service`, HCL, "api_core_service"},
+		{"3rd-party DB", HCL, "_3rd-party_db"},
+		{"VPC / Network", HCL, "vpc_network"},
+		{"already_valid-name", HCL, "already_valid-name"},
+		{"!!!", HCL, "unnamed"},
+		{"Ünïcode", HCL, "n_code"},
+
+		{"pkg:npm/lodash@4.17.21", SPDXID, "pkg-npm-lodash-4.17.21"},
+		{"postgres-primary", SPDXID, "postgres-primary"},
+		{"--a b--", SPDXID, "a-b"},
+		{"!!!", SPDXID, "unnamed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%.20s", tt.style, tt.ref), func(t *testing.T) {
+			if got := Sanitize(tt.ref, tt.style); got != tt.want {
+				t.Errorf("Sanitize(%q, %s) = %q, want %q", tt.ref, tt.style, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamer(t *testing.T) {
+	long := strings.Repeat("x", 300)
+
+	tests := []struct {
+		style Style
+		refs  []string
+		want  []string
+	}{
+		{MermaidID, []string{"a/b", "a:b", "a b", "a/b"}, []string{"a_b", "a_b_2", "a_b_3", "a_b"}},
+		// A ref that sanitizes to an earlier suffixed name is suffixed in turn
+		{MermaidID, []string{"a b", "a/b", "a_b_2"}, []string{"a_b", "a_b_2", "a_b_2_2"}},
+		{DNS1123, []string{long, long + "y"}, []string{strings.Repeat("x", 63), strings.Repeat("x", 61) + "-2"}},
+		{Shell, []string{"a/b", "a_b"}, []string{"a_b", "a_b-2"}},
+		{HCL, []string{"DB", "db"}, []string{"db", "db_2"}},
+		{SPDXID, []string{"a/b", "a:b"}, []string{"a-b", "a-b-2"}},
+		{DOT, []string{`"`, `\"`}, []string{`\"`, `\\\"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style.String(), func(t *testing.T) {
+			namer := NewNamer(tt.style)
+			for i, ref := range tt.refs {
+				if got := namer.Name(ref); got != tt.want[i] {
+					t.Errorf("Name(%q) = %q, want %q", ref, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNamerReserve(t *testing.T) {
+	namer := NewNamer(MermaidID)
+	namer.Reserve("c1")
+	if got := namer.Name("c1"); got != "c1_2" {
+		t.Errorf("Expected a reserved identifier to be suffixed, got %q", got)
+	}
+}
+
+func TestNamerNameFor(t *testing.T) {
+	namer := NewNamer(DNS1123)
+	if got := namer.NameFor("pkg:a", "Web"); got != "web" {
+		t.Errorf("NameFor() = %q, want web", got)
+	}
+	if got := namer.NameFor("pkg:b", "web"); got != "web-2" {
+		t.Errorf("Expected the same name for another ref to be suffixed, got %q", got)
+	}
+	if got := namer.NameFor("pkg:a", "ignored"); got != "web" {
+		t.Errorf("Expected a named ref to keep its identifier, got %q", got)
+	}
+}
+
+func TestShorten(t *testing.T) {
+	long := "pkg:maven/org.example/app@1.0?" + strings.Repeat("classifier=x&", 120)
+	other := long + "type=jar"
+
+	short := Shorten(long, 40)
+	if n := utf8.RuneCountInString(short); n != 40 {
+		t.Errorf("Expected 40 characters, got %d: %q", n, short)
+	}
+	if !strings.HasPrefix(short, "pkg:maven/org.example/app@1.0?") || !strings.Contains(short, "…") {
+		t.Errorf("Expected the start of the ref and an ellipsis, got %q", short)
+	}
+	if Shorten(long, 40) != short {
+		t.Error("Expected shortening to be stable")
+	}
+	if Shorten(other, 40) == short {
+		t.Errorf("Expected refs sharing a prefix to stay apart, both gave %q", short)
+	}
+
+	for _, tt := range []struct {
+		s      string
+		maxLen int
+		want   int
+	}{
+		{"short", 40, 5},
+		{long, 0, utf8.RuneCountInString(long)},
+		{long, 3, MinMaxLabelLen},
+		{strings.Repeat("ü", 100), 20, 20},
+	} {
+		if got := utf8.RuneCountInString(Shorten(tt.s, tt.maxLen)); got != tt.want {
+			t.Errorf("Shorten(%.10q, %d): expected %d characters, got %d", tt.s, tt.maxLen, tt.want, got)
+		}
+	}
+
+	lines := strings.Split(ShortenLines(long+"\n1.0", 40), "\n")
+	if len(lines) != 2 || lines[0] != short || lines[1] != "1.0" {
+		t.Errorf("Expected each line to be shortened on its own, got %q", lines)
+	}
+}
@@ -0,0 +1,192 @@
+// Package interrupt turns SIGINT, SIGTERM and --run-timeout into context
+// cancellation, remembering the phase that was interrupted
+package interrupt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// TimeoutExitCode is the status of a run that exceeded its deadline, as
+// timeout(1) reports it
+const TimeoutExitCode = 124
+
+// Error reports an interrupted run
+type Error struct {
+	Phase   string
+	Signal  os.Signal     // Nil when the deadline passed
+	Timeout time.Duration // The deadline that passed, if any
+}
+
+func (e *Error) Error() string {
+	if e.Signal == nil {
+		return fmt.Sprintf("timed out during %s (exceeded --run-timeout %v)", e.Phase, e.Timeout)
+	}
+	return fmt.Sprintf("interrupted during %s (%v)", e.Phase, e.Signal)
+}
+
+// ExitCode is the shell convention for a process killed by the signal:
+// 130 for SIGINT, 143 for SIGTERM, and TimeoutExitCode past the deadline
+func (e *Error) ExitCode() int {
+	if e.Signal == nil {
+		return TimeoutExitCode
+	}
+	return ExitCode(e.Signal)
+}
+
+// ExitCode returns 128 plus the signal number, or 1 for signals without one
+func ExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// Handler tracks the phase of a run: an interrupt exits at once, or in a
+// phase entered with EnterCancelable cancels Context for the caller to Exit
+type Handler struct {
+	// Report receives the *Error of an interrupt before the process exits
+	// (nil = print it to stderr)
+	Report func(err error)
+	// exit ends the process; tests replace it
+	exit func(code int)
+
+	mu         sync.Mutex // Held while interrupting and by Critical
+	phase      string
+	cancelable bool
+	err        *Error
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// New returns a Handler in the phase "startup". Call Notify to install it
+// for SIGINT and SIGTERM.
+func New() *Handler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Handler{phase: "startup", exit: os.Exit, ctx: ctx, cancel: cancel}
+}
+
+// Context is cancelled by the first interrupt
+func (h *Handler) Context() context.Context {
+	return h.ctx
+}
+
+// Enter starts a phase that cannot stop halfway, such as parsing: an
+// interrupt reports the phase and exits
+func (h *Handler) Enter(phase string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.phase, h.cancelable = phase, false
+}
+
+// EnterCancelable starts a phase that watches Context, such as running
+// deploy commands: an interrupt cancels Context and leaves the exit to Exit
+func (h *Handler) EnterCancelable(phase string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.phase, h.cancelable = phase, true
+}
+
+// Notify delivers SIGINT and SIGTERM to Interrupt until stop is called
+func (h *Handler) Notify() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				h.Interrupt(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// Deadline interrupts the run once d has passed, as a signal would, with an
+// *Error naming the phase that ran out of time. It returns a function
+// disarming the deadline; d <= 0 sets none.
+func (h *Handler) Deadline(d time.Duration) (stop func()) {
+	if d <= 0 {
+		return func() {}
+	}
+	timer := time.AfterFunc(d, func() { h.interrupt(&Error{Timeout: d}) })
+	return func() { timer.Stop() }
+}
+
+// Interrupt handles a signal. The first one cancels Context, and outside a
+// cancelable phase reports and exits once any Critical section has finished.
+// A second signal always exits.
+func (h *Handler) Interrupt(sig os.Signal) {
+	h.interrupt(&Error{Signal: sig})
+}
+
+// interrupt handles a signal or the deadline, reported as err once its phase
+// is filled in
+func (h *Handler) interrupt(err *Error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.err != nil {
+		h.abort()
+		return
+	}
+	err.Phase = h.phase
+	h.err = err
+	h.cancel()
+	if !h.cancelable {
+		h.abort()
+	}
+}
+
+// Err returns the *Error of the interrupt, or nil when there was none
+func (h *Handler) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.err == nil {
+		return nil
+	}
+	return h.err
+}
+
+// Exit reports the interrupt and exits when there was one, and returns
+// otherwise
+func (h *Handler) Exit() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.err != nil {
+		h.abort()
+	}
+}
+
+// Critical runs fn, such as writing the final output, without letting an
+// interrupt end the process halfway through it. It skips fn and returns the
+// interrupt's *Error when the run was already interrupted.
+func (h *Handler) Critical(fn func() error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.err != nil {
+		return h.err
+	}
+	return fn()
+}
+
+// abort reports the interrupt and exits; h.mu is held
+func (h *Handler) abort() {
+	if h.Report != nil {
+		h.Report(h.err)
+	} else {
+		fmt.Fprintf(os.Stderr, "%v\n", h.err)
+	}
+	h.exit(h.err.ExitCode())
+}
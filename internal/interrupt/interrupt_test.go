@@ -0,0 +1,156 @@
+package interrupt
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testHandler returns a Handler that records exits and reports instead of
+// ending the test binary
+func testHandler() (h *Handler, exits *[]int, reports *[]string) {
+	h = New()
+	exits, reports = new([]int), new([]string)
+	h.exit = func(code int) { *exits = append(*exits, code) }
+	h.Report = func(err error) { *reports = append(*reports, err.Error()) }
+	return h, exits, reports
+}
+
+func TestExitCode(t *testing.T) {
+	if got := ExitCode(syscall.SIGINT); got != 130 {
+		t.Errorf("ExitCode(SIGINT) = %d, want 130", got)
+	}
+	if got := ExitCode(syscall.SIGTERM); got != 143 {
+		t.Errorf("ExitCode(SIGTERM) = %d, want 143", got)
+	}
+}
+
+func TestInterruptExitsOutsideCancelablePhases(t *testing.T) {
+	h, exits, reports := testHandler()
+	h.Enter("parsing")
+	h.Interrupt(syscall.SIGTERM)
+
+	if len(*exits) != 1 || (*exits)[0] != 143 {
+		t.Errorf("Expected exit 143, got %v", *exits)
+	}
+	if len(*reports) != 1 || (*reports)[0] != "interrupted during parsing (terminated)" {
+		t.Errorf("Expected the phase to be reported, got %q", *reports)
+	}
+	if h.Context().Err() == nil {
+		t.Error("Expected the context to be cancelled")
+	}
+}
+
+func TestInterruptCancelsCancelablePhases(t *testing.T) {
+	h, exits, _ := testHandler()
+	h.EnterCancelable("running commands")
+	h.Interrupt(os.Interrupt)
+
+	if len(*exits) != 0 {
+		t.Fatalf("Expected no exit before Exit, got %v", *exits)
+	}
+	select {
+	case <-h.Context().Done():
+	default:
+		t.Fatal("Expected the context to be cancelled")
+	}
+	var interrupted *Error
+	if !errors.As(h.Err(), &interrupted) || interrupted.Phase != "running commands" || interrupted.ExitCode() != 130 {
+		t.Errorf("Unexpected Err(): %v", h.Err())
+	}
+
+	h.Exit()
+	if len(*exits) != 1 || (*exits)[0] != 130 {
+		t.Errorf("Expected Exit to exit 130, got %v", *exits)
+	}
+
+	// A second signal does not wait for the phase to wind down
+	h.Interrupt(os.Interrupt)
+	if len(*exits) != 2 {
+		t.Errorf("Expected a second signal to exit, got %v", *exits)
+	}
+}
+
+func TestExitWithoutInterrupt(t *testing.T) {
+	h, exits, _ := testHandler()
+	h.Exit()
+	if len(*exits) != 0 || h.Err() != nil {
+		t.Errorf("Expected no exit and no error, got %v and %v", *exits, h.Err())
+	}
+}
+
+func TestCriticalDelaysInterrupt(t *testing.T) {
+	h, exits, _ := testHandler()
+	h.Enter("writing output")
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	var exitsDuringCritical int
+	go func() {
+		defer close(done)
+		_ = h.Critical(func() error {
+			close(started)
+			<-release
+			exitsDuringCritical = len(*exits)
+			return nil
+		})
+	}()
+	<-started
+
+	interruptDone := make(chan struct{})
+	go func() {
+		h.Interrupt(syscall.SIGTERM)
+		close(interruptDone)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	<-done
+	<-interruptDone
+
+	if exitsDuringCritical != 0 {
+		t.Error("Expected the interrupt to wait for the critical section")
+	}
+	if len(*exits) != 1 {
+		t.Errorf("Expected an exit after the critical section, got %v", *exits)
+	}
+
+	ran := false
+	if err := h.Critical(func() error { ran = true; return nil }); err == nil || ran {
+		t.Error("Expected Critical to be skipped after an interrupt")
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	h, _, reports := testHandler()
+	codes := make(chan int, 1)
+	h.exit = func(code int) { codes <- code }
+	h.Enter("building the graph")
+	defer h.Deadline(time.Millisecond)()
+
+	select {
+	case code := <-codes:
+		if code != TimeoutExitCode {
+			t.Errorf("Expected exit %d, got %d", TimeoutExitCode, code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the deadline to exit")
+	}
+	if want := "timed out during building the graph (exceeded --run-timeout 1ms)"; len(*reports) != 1 || (*reports)[0] != want {
+		t.Errorf("Expected %q, got %q", want, *reports)
+	}
+	if h.Context().Err() == nil {
+		t.Error("Expected the context to be cancelled")
+	}
+}
+
+func TestDeadlineStopped(t *testing.T) {
+	h, exits, _ := testHandler()
+	h.Deadline(10 * time.Millisecond)()
+	h.Deadline(0)()
+	time.Sleep(30 * time.Millisecond)
+	if h.Err() != nil || len(*exits) != 0 {
+		t.Errorf("Expected a stopped deadline not to fire, got %v and %v", h.Err(), *exits)
+	}
+}
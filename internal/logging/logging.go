@@ -0,0 +1,205 @@
+// Package logging is a small leveled logger for diagnostics written to
+// stderr, either as human-readable lines or as JSON lines for log collectors.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// Level is the most verbose kind of message a Logger writes
+type Level int
+
+// Levels in increasing verbosity
+const (
+	LevelError Level = iota // Errors only (--quiet)
+	LevelWarn               // Warnings and errors (default)
+	LevelInfo               // Phase logs, warnings and errors (--verbose)
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelError:
+		return "error"
+	case LevelWarn:
+		return "warn"
+	default:
+		return "info"
+	}
+}
+
+// Format is the encoding of log lines
+type Format string
+
+// Supported values for the --log-format flag
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat validates a log format name
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case FormatText, FormatJSON:
+		return Format(s), nil
+	}
+	return "", fmt.Errorf("unknown log format %q (expected text or json)", s)
+}
+
+// textPrefixes are prepended to messages in the text format
+var textPrefixes = map[Level]string{
+	LevelError: "Error: ",
+	LevelWarn:  "Warning: ",
+	LevelInfo:  "Info: ",
+}
+
+// textColors are the ANSI colors of levels in the text format with SetColor
+var textColors = map[Level]string{
+	LevelWarn: "\x1b[33m", // yellow
+}
+
+// Logger writes leveled messages. A nil *Logger discards everything, so
+// packages can accept an optional logger without checking for nil.
+type Logger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  Level
+	format Format
+	color  bool
+	now    func() time.Time
+
+	policy   warning.Policy
+	failures []warning.Warning
+}
+
+// New returns a logger writing messages up to level to w
+func New(w io.Writer, level Level, format Format) *Logger {
+	return &Logger{w: w, level: level, format: format, now: time.Now}
+}
+
+// SetColor makes the text format color warnings for a terminal. Call it
+// before the logger is shared.
+func (l *Logger) SetColor(color bool) {
+	l.color = color
+}
+
+// SetPolicy makes Warn drop the warnings policy suppresses and remember the
+// ones it fails on. Call it before the logger is shared.
+func (l *Logger) SetPolicy(policy warning.Policy) {
+	l.policy = policy
+}
+
+// Enabled reports whether messages at level are written
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && level <= l.level
+}
+
+// Errorf logs an error
+func (l *Logger) Errorf(format string, args ...any) {
+	l.log(LevelError, format, args...)
+}
+
+// Failf logs an error naming what failed, such as "parsing SBOM: ...",
+// which the text format words as "Error parsing SBOM: ..."
+func (l *Logger) Failf(format string, args ...any) {
+	l.writePrefixed(LevelError, "", "Error ", fmt.Sprintf(format, args...))
+}
+
+// Error logs a coded warning that fails the run, such as a parse warning
+// with --strict, at error level so that --quiet still shows it
+func (l *Logger) Error(w warning.Warning) {
+	if l == nil || l.policy.Suppressed(w.Code) {
+		return
+	}
+	l.write(LevelError, string(w.Code), w.Message)
+}
+
+// Warnf logs a warning
+func (l *Logger) Warnf(format string, args ...any) {
+	l.log(LevelWarn, format, args...)
+}
+
+// Warn logs a coded warning unless the policy suppresses it. The text
+// format shows the code in brackets before the message; the JSON format has
+// it in a field of its own.
+func (l *Logger) Warn(w warning.Warning) {
+	if l == nil || l.policy.Suppressed(w.Code) {
+		return
+	}
+	if l.policy.Fails(w.Code) {
+		l.mu.Lock()
+		l.failures = append(l.failures, w)
+		l.mu.Unlock()
+	}
+	l.write(LevelWarn, string(w.Code), w.Message)
+}
+
+// Failures returns the warnings logged so far whose codes the policy fails
+// on, even those --quiet did not show
+func (l *Logger) Failures() []warning.Warning {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]warning.Warning(nil), l.failures...)
+}
+
+// Infof logs a progress message shown only in verbose mode
+func (l *Logger) Infof(format string, args ...any) {
+	l.log(LevelInfo, format, args...)
+}
+
+type jsonLine struct {
+	Time  string `json:"time"`
+	Level string `json:"level"`
+	Code  string `json:"code,omitempty"`
+	Msg   string `json:"msg"`
+}
+
+func (l *Logger) log(level Level, format string, args ...any) {
+	l.write(level, "", fmt.Sprintf(format, args...))
+}
+
+// write writes one message, with the code of a warning or ""
+func (l *Logger) write(level Level, code, msg string) {
+	l.writePrefixed(level, code, textPrefixes[level], msg)
+}
+
+// writePrefixed writes one message, starting with prefix in the text format
+func (l *Logger) writePrefixed(level Level, code, prefix, msg string) {
+	if !l.Enabled(level) {
+		return
+	}
+	msg = strings.TrimSuffix(msg, "\n")
+
+	var line []byte
+	if l.format == FormatJSON {
+		line, _ = json.Marshal(jsonLine{
+			Time:  l.now().UTC().Format(time.RFC3339Nano),
+			Level: level.String(),
+			Code:  code,
+			Msg:   msg,
+		})
+		line = append(line, '\n')
+	} else {
+		if code != "" {
+			msg = "[" + code + "] " + msg
+		}
+		text := prefix + msg
+		if color, ok := textColors[level]; ok && l.color {
+			text = color + text + "\x1b[0m"
+		}
+		line = []byte(text + "\n")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.w.Write(line)
+}
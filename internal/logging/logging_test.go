@@ -0,0 +1,174 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  string
+	}{
+		{LevelError, "Error: e\n"},
+		{LevelWarn, "Error: e\nWarning: w\n"},
+		{LevelInfo, "Error: e\nWarning: w\nInfo: i\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		l := New(&buf, tt.level, FormatText)
+		l.Errorf("e")
+		l.Warnf("w")
+		l.Infof("i")
+		if buf.String() != tt.want {
+			t.Errorf("Level %s: expected %q, got %q", tt.level, tt.want, buf.String())
+		}
+	}
+}
+
+func TestFailures(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, LevelError, FormatText)
+	l.SetPolicy(warning.Policy{Suppress: []warning.Code{warning.DanglingRef}})
+	l.Failf("parsing SBOM: %s", "bad JSON")
+	l.Error(warning.New(warning.MissingVersion, "no version"))
+	l.Error(warning.New(warning.DanglingRef, "suppressed"))
+
+	want := "Error parsing SBOM: bad JSON\nError: [W013_MISSING_VERSION] no version\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	l = New(&buf, LevelError, FormatJSON)
+	l.Failf("parsing SBOM: %s", "bad JSON")
+	var line jsonLine
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil || line.Level != "error" || line.Msg != "parsing SBOM: bad JSON" {
+		t.Errorf("Unexpected JSON line %q: %v", buf.String(), err)
+	}
+}
+
+func TestColor(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, LevelInfo, FormatText)
+	l.SetColor(true)
+	l.Errorf("e")
+	l.Warnf("w")
+	l.Infof("i")
+	if want := "Error: e\n\x1b[33mWarning: w\x1b[0m\nInfo: i\n"; buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+
+	// JSON lines are for machines and never colored
+	buf.Reset()
+	l = New(&buf, LevelWarn, FormatJSON)
+	l.SetColor(true)
+	l.Warnf("w")
+	if strings.Contains(buf.String(), "\x1b") {
+		t.Errorf("Expected no escape sequences in JSON, got %q", buf.String())
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, LevelInfo, FormatJSON)
+	l.now = func() time.Time { return time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC) }
+	l.Infof("parsed %d components", 3)
+	l.Warnf("quote \" and newline\nkept\n")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 JSON lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var first, second map[string]string
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Invalid JSON line %q: %v", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("Invalid JSON line %q: %v", lines[1], err)
+	}
+	if first["time"] != "2024-01-15T10:00:00Z" || first["level"] != "info" || first["msg"] != "parsed 3 components" {
+		t.Errorf("Unexpected first line: %v", first)
+	}
+	if second["level"] != "warn" || second["msg"] != "quote \" and newline\nkept" {
+		t.Errorf("Unexpected second line: %v", second)
+	}
+}
+
+func TestWarnPolicy(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, LevelWarn, FormatText)
+	l.SetPolicy(warning.Policy{
+		Suppress: []warning.Code{warning.DanglingRef},
+		FailOn:   []warning.Code{warning.DanglingRef, warning.VersionConflict},
+	})
+	l.Warn(warning.New(warning.DanglingRef, "suppressed"))
+	l.Warn(warning.New(warning.VersionConflict, "conflict"))
+	l.Warn(warning.New(warning.MissingVersion, "no version"))
+
+	want := "Warning: [W004_VERSION_CONFLICT] conflict\nWarning: [W013_MISSING_VERSION] no version\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+	// Suppression wins over --fail-on
+	if failures := l.Failures(); len(failures) != 1 || failures[0].Code != warning.VersionConflict {
+		t.Errorf("Expected the version conflict to fail, got %v", failures)
+	}
+
+	// Failures are recorded even when warnings are not shown
+	quiet := New(&buf, LevelError, FormatText)
+	quiet.SetPolicy(warning.Policy{FailOn: []warning.Code{warning.Cycle}})
+	quiet.Warn(warning.New(warning.Cycle, "cycle"))
+	if len(quiet.Failures()) != 1 {
+		t.Errorf("Expected a failure from a quiet logger, got %v", quiet.Failures())
+	}
+}
+
+func TestWarnJSONCode(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, LevelWarn, FormatJSON)
+	l.Warn(warning.New(warning.DuplicateBOMRef, "twice"))
+	l.Warnf("uncoded")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	var coded, uncoded map[string]string
+	if err := json.Unmarshal([]byte(lines[0]), &coded); err != nil {
+		t.Fatalf("Invalid JSON line %q: %v", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &uncoded); err != nil {
+		t.Fatalf("Invalid JSON line %q: %v", lines[1], err)
+	}
+	if coded["code"] != "W003_DUPLICATE_BOMREF" || coded["msg"] != "twice" {
+		t.Errorf("Unexpected coded line: %v", coded)
+	}
+	if _, ok := uncoded["code"]; ok {
+		t.Errorf("Expected no code field on an uncoded line, got %v", uncoded)
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var l *Logger
+	if l.Enabled(LevelError) {
+		t.Error("Expected a nil logger to be disabled")
+	}
+	l.Errorf("discarded")
+	l.Infof("discarded")
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"text", "json"} {
+		if f, err := ParseFormat(s); err != nil || string(f) != s {
+			t.Errorf("ParseFormat(%q) = %q, %v", s, f, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
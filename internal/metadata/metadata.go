@@ -0,0 +1,121 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// Metadata holds properties kept outside the SBOM, keyed by bom-ref, purl or
+// name, in that precedence; it also wins over the SBOM's own properties
+type Metadata map[string]map[string]string
+
+// Load reads metadata from a reader. Documents starting with "{" are decoded
+// as JSON, anything else as YAML.
+func Load(r io.Reader) (Metadata, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	var m Metadata
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &m); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata: %w", err)
+		}
+	} else if m, err = parseYAML(data); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	if m == nil {
+		m = Metadata{}
+	}
+	return m, nil
+}
+
+// LoadFile reads metadata from a file path
+func LoadFile(filePath string) (Metadata, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metadata: %w", err)
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// Apply merges the metadata onto every matching component and service of the
+// SBOM and warns about entries that matched nothing
+func (m Metadata) Apply(bom *sbom.CycloneDX) []warning.Warning {
+	used := make(map[string]bool, len(m))
+	apply := func(ref, purl, name string, properties *[]sbom.Property) {
+		// Lowest precedence first so later keys overwrite earlier ones
+		for _, key := range []string{name, purl, ref} {
+			entry, ok := m[key]
+			if key == "" || !ok {
+				continue
+			}
+			used[key] = true
+			setProperties(properties, entry)
+		}
+	}
+
+	stack := make([]*sbom.Component, 0, len(bom.Components)+1)
+	for i := range bom.Components {
+		stack = append(stack, &bom.Components[i])
+	}
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		stack = append(stack, bom.Metadata.Component)
+	}
+	for len(stack) > 0 {
+		component := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		apply(component.BOMRef, component.Purl, component.Name, &component.Properties)
+		for i := range component.Components {
+			stack = append(stack, &component.Components[i])
+		}
+	}
+	for i := range bom.Services {
+		service := &bom.Services[i]
+		apply(service.BOMRef, "", service.Name, &service.Properties)
+	}
+
+	var warnings []warning.Warning
+	for _, key := range sortedKeys(m) {
+		if !used[key] {
+			warnings = append(warnings, warning.New(warning.UnmatchedMetadata, "metadata entry %q matches no bom-ref, purl or name", key))
+		}
+	}
+	return warnings
+}
+
+// setProperties sets each property, replacing an existing one of the same name
+func setProperties(properties *[]sbom.Property, entry map[string]string) {
+	for _, name := range sortedKeys(entry) {
+		value := entry[name]
+		replaced := false
+		for i := range *properties {
+			if (*properties)[i].Name == name {
+				(*properties)[i].Value = value
+				replaced = true
+			}
+		}
+		if !replaced {
+			*properties = append(*properties, sbom.Property{Name: name, Value: value})
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,155 @@
+package metadata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+func TestLoadYAML(t *testing.T) {
+	doc := `---
+# Operational metadata for the payments stack
+api:
+  owner: team-payments   # inline comment
+  bom-dagger:deploy-command: "kubectl apply -f 'api.yaml' && echo \"done\""
+  slack: '#payments'
+"pkg:npm/left-pad@1.3.0":
+  note: 'it''s vendored'
+pkg:golang/example.com/lib@v1.0.0:
+  empty:
+
+Worker: {}
+`
+	m, err := Load(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := Metadata{
+		"api": {
+			"owner":                     "team-payments",
+			"bom-dagger:deploy-command": `kubectl apply -f 'api.yaml' && echo "done"`,
+			"slack":                     "#payments",
+		},
+		"pkg:npm/left-pad@1.3.0":            {"note": "it's vendored"},
+		"pkg:golang/example.com/lib@v1.0.0": {"empty": ""},
+		"Worker":                            {},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Unexpected metadata\nGot:  %v\nWant: %v", m, want)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	m, err := Load(strings.NewReader(`{"api": {"owner": "team-payments"}}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if m["api"]["owner"] != "team-payments" {
+		t.Errorf("Expected owner team-payments, got %v", m)
+	}
+
+	m, err = Load(strings.NewReader(""))
+	if err != nil || len(m) != 0 {
+		t.Errorf("Expected empty metadata for an empty file, got %v, %v", m, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"scalar entry", "api: team-payments\n", `line 1: entry "api" must be a mapping`},
+		{"orphan property", "  owner: x\n", `line 1: property "owner" is not inside an entry`},
+		{"tab indentation", "api:\n\towner: x\n", "line 2: tabs are not allowed"},
+		{"inconsistent indentation", "api:\n  owner: x\n    team: y\n", "line 3: inconsistent indentation"},
+		{"duplicate entry", "api:\n  a: b\napi:\n", `line 3: duplicate entry "api"`},
+		{"duplicate property", "api:\n  a: b\n  a: c\n", `line 3: duplicate property "a"`},
+		{"missing separator", "api:\n  owner\n", "line 2: expected 'key: value'"},
+		{"block scalar", "api:\n  cmd: |\n", "line 2: unsupported YAML value"},
+		{"unterminated quote", "api:\n  cmd: 'oops\n", "line 2: unterminated single-quoted string"},
+		{"text after quote", "api:\n  cmd: \"a\" b\n", "line 2: unexpected text after quoted value"},
+		{"invalid JSON", `{"api": "x"}`, "failed to decode metadata"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tt.doc))
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func property(properties []sbom.Property, name string) string {
+	for _, p := range properties {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func TestApply(t *testing.T) {
+	bom := &sbom.CycloneDX{
+		Metadata: &sbom.Metadata{Component: &sbom.Component{BOMRef: "app", Name: "App"}},
+		Components: []sbom.Component{
+			{BOMRef: "db", Name: "Database", Purl: "pkg:docker/postgres@15", Properties: []sbom.Property{
+				{Name: "owner", Value: "from-sbom"},
+				{Name: "tier", Value: "data"},
+			}},
+			{BOMRef: "bundle", Name: "Bundle", Components: []sbom.Component{
+				{BOMRef: "plugin", Name: "Plugin"},
+			}},
+		},
+		Services: []sbom.Service{{BOMRef: "auth", Name: "Auth"}},
+	}
+	m := Metadata{
+		"Database":               {"owner": "by-name", "slack": "#db", "oncall": "by-name"},
+		"pkg:docker/postgres@15": {"owner": "by-purl", "oncall": "by-purl"},
+		"db":                     {"owner": "by-ref"},
+		"plugin":                 {"owner": "plugins"},
+		"Auth":                   {"owner": "identity"},
+		"app":                    {"owner": "product"},
+		"missing":                {"owner": "nobody"},
+		"Missing Name":           {},
+	}
+
+	warnings := m.Apply(bom)
+
+	db := bom.Components[0].Properties
+	for name, want := range map[string]string{"owner": "by-ref", "oncall": "by-purl", "slack": "#db", "tier": "data"} {
+		if got := property(db, name); got != want {
+			t.Errorf("Expected db %s=%q, got %q", name, want, got)
+		}
+	}
+	if len(db) != 4 {
+		t.Errorf("Expected the owner property to be replaced rather than duplicated, got %v", db)
+	}
+	if got := property(bom.Components[1].Components[0].Properties, "owner"); got != "plugins" {
+		t.Errorf("Expected nested plugin owner plugins, got %q", got)
+	}
+	if got := property(bom.Services[0].Properties, "owner"); got != "identity" {
+		t.Errorf("Expected service owner identity, got %q", got)
+	}
+	if got := property(bom.Metadata.Component.Properties, "owner"); got != "product" {
+		t.Errorf("Expected metadata component owner product, got %q", got)
+	}
+
+	want := []warning.Warning{
+		warning.New(warning.UnmatchedMetadata, `metadata entry "Missing Name" matches no bom-ref, purl or name`),
+		warning.New(warning.UnmatchedMetadata, `metadata entry "missing" matches no bom-ref, purl or name`),
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("Unexpected warnings\nGot:  %v\nWant: %v", warnings, want)
+	}
+}
@@ -0,0 +1,59 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/yamlsubset"
+)
+
+// parseYAML reads the YAML form of metadata files (see yamlsubset): a
+// mapping of keys to mappings of scalar values
+func parseYAML(data []byte) (Metadata, error) {
+	lines, err := yamlsubset.Lines(data)
+	if err != nil {
+		return nil, err
+	}
+
+	m := Metadata{}
+	var (
+		entry       map[string]string
+		entryKey    string
+		childIndent int
+	)
+	for _, line := range lines {
+		key, value, err := yamlsubset.Pair(line.Text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.No, err)
+		}
+
+		if line.Indent == 0 {
+			if _, exists := m[key]; exists {
+				return nil, fmt.Errorf("line %d: duplicate entry %q", line.No, key)
+			}
+			if value != "" && value != "{}" && !strings.HasPrefix(value, "#") {
+				return nil, fmt.Errorf("line %d: entry %q must be a mapping of properties", line.No, key)
+			}
+			entry, entryKey, childIndent = map[string]string{}, key, 0
+			m[key] = entry
+			continue
+		}
+
+		if entry == nil {
+			return nil, fmt.Errorf("line %d: property %q is not inside an entry", line.No, key)
+		}
+		if childIndent == 0 {
+			childIndent = line.Indent
+		} else if line.Indent != childIndent {
+			return nil, fmt.Errorf("line %d: inconsistent indentation in entry %q", line.No, entryKey)
+		}
+		if _, exists := entry[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate property %q in entry %q", line.No, key, entryKey)
+		}
+		if value, err = yamlsubset.Scalar(value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.No, err)
+		}
+		entry[key] = value
+	}
+	return m, nil
+}
@@ -0,0 +1,49 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/plural"
+)
+
+// WriteImages writes the container images of the plan, one per line under a
+// comment per group, or each once with Options.Flat
+func WriteImages(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	var b strings.Builder
+	var seen []string
+	omitted := 0
+	for _, step := range p.Steps {
+		var images []string
+		for _, entry := range step.Components {
+			var image string
+			if node, ok := g.Node(entry.BOMRef); ok {
+				image = node.Image()
+			}
+			switch {
+			case image == "":
+				omitted++
+			case opts.Flat && !slices.Contains(seen, image):
+				seen = append(seen, image)
+				fmt.Fprintln(&b, image)
+			case !opts.Flat && !slices.Contains(images, image):
+				images = append(images, image)
+			}
+		}
+		if len(images) > 0 {
+			fmt.Fprintf(&b, "# Group %d\n", step.Step)
+			for _, image := range images {
+				fmt.Fprintln(&b, image)
+			}
+		}
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&b, "# %d %s without an image omitted\n", omitted, plural.Noun(omitted, "component"))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
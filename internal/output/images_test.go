@@ -0,0 +1,78 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// imagesBOM has two groups: a database and a cache sharing the registry's
+// postgres image, then an API pinned by digest and a library without an
+// image
+func imagesBOM() *sbom.CycloneDX {
+	return &sbom.CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.6",
+		Components: []sbom.Component{
+			{Type: "container", BOMRef: "db", Name: "Database", Purl: "pkg:docker/infra/postgres@15?repository_url=registry.local:5000"},
+			{Type: "container", BOMRef: "replica", Name: "Replica", Purl: "pkg:docker/infra/postgres@15?repository_url=registry.local:5000"},
+			{Type: "container", BOMRef: "api", Name: "API", Purl: "pkg:oci/api@sha256%3Aabc123?repository_url=ghcr.io/acme/api&tag=2.0"},
+			{Type: "library", BOMRef: "lib", Name: "Lib", Purl: "pkg:npm/lib@1.0"},
+			{Type: "application", BOMRef: "worker", Name: "Worker", Properties: []sbom.Property{
+				{Name: dag.PropertyImage, Value: "registry.local:5000/infra/postgres:15"},
+			}},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "api", DependsOn: []string{"db", "replica", "lib"}},
+			{Ref: "worker", DependsOn: []string{"db"}},
+		},
+	}
+}
+
+func renderImages(t *testing.T, opts Options) string {
+	t.Helper()
+	bom := imagesBOM()
+	componentMap := make(map[string]*sbom.Component)
+	for i := range bom.Components {
+		componentMap[bom.Components[i].BOMRef] = &bom.Components[i]
+	}
+	g := dag.New()
+	if err := g.BuildFromSBOM(bom, componentMap); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	p, err := plan.New(g)
+	if err != nil {
+		t.Fatalf("plan.New failed: %v", err)
+	}
+	w, err := Lookup("images")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := w.Write(&buf, p, g, opts); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestImagesWriter(t *testing.T) {
+	want := "# Group 1\n" +
+		"registry.local:5000/infra/postgres:15\n" +
+		"# Group 2\n" +
+		"ghcr.io/acme/api:2.0@sha256:abc123\n" +
+		"registry.local:5000/infra/postgres:15\n" +
+		"# 1 component without an image omitted\n"
+	if got := renderImages(t, Options{}); got != want {
+		t.Errorf("Expected images per group:\n%s\nGot:\n%s", want, got)
+	}
+
+	want = "registry.local:5000/infra/postgres:15\n" +
+		"ghcr.io/acme/api:2.0@sha256:abc123\n" +
+		"# 1 component without an image omitted\n"
+	if got := renderImages(t, Options{Flat: true}); got != want {
+		t.Errorf("Expected each image once:\n%s\nGot:\n%s", want, got)
+	}
+}
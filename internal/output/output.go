@@ -0,0 +1,164 @@
+// Package output holds the registry of -o output formats. Every format is a
+// Writer rendering the deployment plan or the graph it was computed from;
+// programs embedding bom-dagger can Register their own.
+package output
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/logging"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// Writer renders one output format
+type Writer interface {
+	// Name is the value selecting the format with -o
+	Name() string
+	// Description is a one-line summary shown by -o list
+	Description() string
+	// Write renders the format from the sorted and filtered plan p, or from
+	// g for writers that draw the whole graph
+	Write(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error
+}
+
+// Options carries the settings that shape individual formats
+type Options struct {
+	Label            *dag.LabelTemplate     // Node labels for dot, mermaid and tree
+	ClusterBy        dag.ClusterBy          // Clusters for dot and mermaid
+	Direction        dag.AdjacencyDirection // Edges written by adjacency
+	TreeFrom         string                 // Root of the tree, or "" for every root
+	TreeDepth        int                    // Tree depth limit (0 = unlimited)
+	ServiceKind      string                 // Backstage entity kind for services
+	K8sAnnotation    string                 // Annotation set by k8s-patch
+	DefaultDuration  time.Duration          // Schedule duration without a bom-dagger:duration
+	GanttMaxTasks    int                    // Tasks written by gantt (0 = unlimited)
+	Describe         bool                   // Print descriptions under the order and groups entries
+	DescribeWidth    int                    // Line width descriptions are wrapped at (0 = DefaultDescribeWidth)
+	DescribeMax      int                    // Length descriptions are truncated at (0 = unlimited)
+	MaxLabelLen      int                    // Length names and refs are shortened to in output for people (0 = never; see ident.Shorten)
+	Numbering        Numbering              // Numbers shown by order (zero = NumberGroup)
+	ShowBlockers     bool                   // List what each entry of the order waits for
+	ShowTrust        bool                   // Mark services crossing a trust boundary in order, groups and dot
+	ByOwner          bool                   // Partition each group of the groups output by owner
+	Flat             bool                   // List each image of images once instead of per group
+	DOTLegend        bool                   // Append a legend of the node and edge styles to dot
+	Teardown         bool                   // Write the teardown order or commands (--reverse)
+	Banner           bool                   // Start the order with the completeness warnings
+	Style            Style                  // Colors of the order and groups formats (zero = plain)
+	BOM              *sbom.CycloneDX        // The merged SBOM, for graph (nil = none, as with --redact)
+	Baseline         *sbom.CycloneDX        // The SBOM release-report compares with (nil = none)
+	ParseWarnings    []warning.Warning      // Warnings parsing the SBOMs, for graph
+	MetadataWarnings []warning.Warning      // Warnings applying --metadata, for graph
+	Logger           *logging.Logger        // Receives warnings such as skipped components (nil = discard)
+	Pause            func(step int) error   // Called by order and groups before each step (nil = none)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Writer)
+	names      []string // Registration order, for List
+)
+
+// Register makes a format available under its name. It panics when the name
+// is empty or already registered.
+func Register(w Writer) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name := w.Name()
+	if name == "" {
+		panic("output: Register with an empty name")
+	}
+	if _, dup := registry[name]; dup {
+		panic("output: Register called twice for " + name)
+	}
+	registry[name] = w
+	names = append(names, name)
+}
+
+// Lookup returns the format registered under name. The error for an unknown
+// name lists the available ones.
+func Lookup(name string) (Writer, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if w, ok := registry[name]; ok {
+		return w, nil
+	}
+	return nil, fmt.Errorf("unknown output mode %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// extensions maps output file extensions to the format inferred for them
+var extensions = map[string]string{
+	".dot":  "dot",
+	".json": "json",
+	".md":   "markdown",
+	".mmd":  "mermaid",
+	".prom": "prometheus",
+}
+
+// ModeForFile infers the format to write to path from its extension, for an
+// output file given without a mode. The error for an unknown extension lists
+// the known ones.
+func ModeForFile(path string) (string, error) {
+	if mode, ok := extensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return mode, nil
+	}
+	known := make([]string, 0, len(extensions))
+	for ext := range extensions {
+		known = append(known, ext)
+	}
+	sort.Strings(known)
+	return "", fmt.Errorf("cannot infer the output mode from the extension of %q (known: %s); choose one with -o", path, strings.Join(known, ", "))
+}
+
+// Names returns the registered format names in registration order
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]string(nil), names...)
+}
+
+// List writes every registered format with its description, one per line
+func List(w io.Writer) error {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%-*s  %s\n", width, name, registry[name].Description())
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Func returns a Writer that calls write
+func Func(name, description string, write func(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error) Writer {
+	return funcWriter{name: name, description: description, write: write}
+}
+
+type funcWriter struct {
+	name        string
+	description string
+	write       func(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error
+}
+
+func (f funcWriter) Name() string        { return f.name }
+func (f funcWriter) Description() string { return f.description }
+
+func (f funcWriter) Write(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	return f.write(w, p, g, opts)
+}
@@ -0,0 +1,111 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+)
+
+func TestLookup(t *testing.T) {
+	w, err := Lookup("dot")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if w.Name() != "dot" {
+		t.Errorf("Expected the dot writer, got %s", w.Name())
+	}
+
+	_, err = Lookup("yaml")
+	if err == nil {
+		t.Fatal("Expected an error for an unknown mode")
+	}
+	if !strings.Contains(err.Error(), `unknown output mode "yaml"`) || !strings.Contains(err.Error(), "order, groups, dot") {
+		t.Errorf("Expected the error to list the available modes, got: %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	write := func(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+		_, err := io.WriteString(w, "custom\n")
+		return err
+	}
+	Register(Func("test-custom", "A format registered by a test", write))
+
+	w, err := Lookup("test-custom")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := w.Write(&buf, nil, nil, Options{}); err != nil || buf.String() != "custom\n" {
+		t.Errorf("Expected the registered function to run, got %q, %v", buf.String(), err)
+	}
+	if names := Names(); names[len(names)-1] != "test-custom" {
+		t.Errorf("Expected names in registration order, got %v", names)
+	}
+
+	for _, name := range []string{"test-custom", ""} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected Register(%q) to panic", name)
+				}
+			}()
+			Register(Func(name, "", write))
+		}()
+	}
+}
+
+func TestList(t *testing.T) {
+	var buf bytes.Buffer
+	if err := List(&buf); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(Names()) {
+		t.Fatalf("Expected one line per format, got:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "order                Deployment") {
+		t.Errorf("Expected names padded to the longest, got %q", lines[0])
+	}
+	for _, name := range Names() {
+		if !strings.Contains(buf.String(), "\n"+name+" ") && !strings.HasPrefix(buf.String(), name+" ") {
+			t.Errorf("Expected %s to be listed", name)
+		}
+	}
+}
+
+func TestModeForFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"graph.dot", "dot"},
+		{"out/plan.json", "json"},
+		{"PLAN.MD", "markdown"},
+		{"docs/deps.mmd", "mermaid"},
+		{"/var/lib/node_exporter/bom.prom", "prometheus"},
+	}
+	for _, tt := range tests {
+		got, err := ModeForFile(tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("ModeForFile(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+		if _, err := Lookup(got); err != nil {
+			t.Errorf("ModeForFile(%q) inferred an unregistered mode: %v", tt.path, err)
+		}
+	}
+
+	for _, path := range []string{"plan.txt", "plan"} {
+		_, err := ModeForFile(path)
+		if err == nil {
+			t.Fatalf("Expected an error for %q", path)
+		}
+		if !strings.Contains(err.Error(), "known: .dot, .json, .md, .mmd, .prom") {
+			t.Errorf("Expected the error to list the known extensions, got: %v", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+)
+
+// PrometheusLabels identify the SBOM the metrics of WritePrometheus describe
+type PrometheusLabels struct {
+	SerialNumber string
+	SpecVersion  string
+}
+
+// prometheusMetrics are the metrics WritePrometheus writes, in order
+var prometheusMetrics = []struct {
+	name  string
+	help  string
+	value func(dag.Metrics) int
+}{
+	{"bom_dagger_components_total", "Components and services in the dependency graph.", func(m dag.Metrics) int { return m.Components }},
+	{"bom_dagger_edges_total", "Dependency edges in the graph.", func(m dag.Metrics) int { return m.Edges }},
+	{"bom_dagger_groups_total", "Deployment groups, 0 when the graph has a cycle.", func(m dag.Metrics) int { return m.Groups }},
+	{"bom_dagger_max_depth", "Edges on the longest dependency chain, 0 when the graph has a cycle.", func(m dag.Metrics) int { return m.MaxDepth }},
+	{"bom_dagger_dangling_refs_total", "dependsOn targets naming no component or service.", func(m dag.Metrics) int { return m.DanglingRefs }},
+	{"bom_dagger_cycles_detected", "1 when the dependency graph has a cycle, else 0.", func(m dag.Metrics) int {
+		if m.Cycles {
+			return 1
+		}
+		return 0
+	}},
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes the metrics as gauges in the Prometheus text
+// exposition format, ready for the node exporter's textfile collector
+func WritePrometheus(w io.Writer, m dag.Metrics, labels PrometheusLabels) error {
+	labelSet := fmt.Sprintf(`{serial_number="%s",spec_version="%s"}`,
+		prometheusLabelEscaper.Replace(labels.SerialNumber), prometheusLabelEscaper.Replace(labels.SpecVersion))
+
+	var b strings.Builder
+	for _, metric := range prometheusMetrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", metric.name)
+		fmt.Fprintf(&b, "%s%s %d\n", metric.name, labelSet, metric.value(m))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,221 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+)
+
+// promSample is one sample line of the text exposition format
+type promSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+var (
+	promMetricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	promLabelName  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// parseExposition validates text against the Prometheus text exposition
+// format, as far as the textfile collector relies on it: HELP and TYPE at
+// most once per metric and before its samples, valid metric and label names,
+// label values quoted with only \\, \" and \n escapes, numeric values and a
+// final newline
+func parseExposition(text string) ([]promSample, error) {
+	if !strings.HasSuffix(text, "\n") {
+		return nil, fmt.Errorf("missing final newline")
+	}
+	help, types := make(map[string]bool), make(map[string]string)
+	seen := make(map[string]bool)
+	var samples []promSample
+	for i, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s: %q", i+1, fmt.Sprintf(format, args...), line)
+		}
+		if strings.HasPrefix(line, "#") {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) < 3 || (fields[1] != "HELP" && fields[1] != "TYPE") {
+				continue // A comment
+			}
+			name := fields[2]
+			if !promMetricName.MatchString(name) {
+				return nil, fail("invalid metric name %q", name)
+			}
+			if seen[name] {
+				return nil, fail("%s after the samples of %s", fields[1], name)
+			}
+			if fields[1] == "HELP" {
+				if help[name] {
+					return nil, fail("second HELP for %s", name)
+				}
+				help[name] = true
+				continue
+			}
+			if _, ok := types[name]; ok || len(fields) != 4 {
+				return nil, fail("second or incomplete TYPE for %s", name)
+			}
+			switch fields[3] {
+			case "counter", "gauge", "histogram", "summary", "untyped":
+			default:
+				return nil, fail("unknown type %q", fields[3])
+			}
+			types[name] = fields[3]
+			continue
+		}
+
+		sample := promSample{labels: make(map[string]string)}
+		rest := line
+		end := strings.IndexAny(rest, "{ ")
+		if end < 0 {
+			return nil, fail("no value")
+		}
+		sample.name, rest = rest[:end], rest[end:]
+		if !promMetricName.MatchString(sample.name) {
+			return nil, fail("invalid metric name %q", sample.name)
+		}
+		if strings.HasPrefix(rest, "{") {
+			rest = rest[1:]
+			for !strings.HasPrefix(rest, "}") {
+				eq := strings.Index(rest, `="`)
+				if eq < 0 {
+					return nil, fail("malformed label")
+				}
+				name := rest[:eq]
+				if !promLabelName.MatchString(name) {
+					return nil, fail("invalid label name %q", name)
+				}
+				var value strings.Builder
+				j := eq + 2
+				for ; j < len(rest) && rest[j] != '"'; j++ {
+					if rest[j] == '\\' {
+						j++
+						switch {
+						case j == len(rest):
+							return nil, fail("unterminated escape")
+						case rest[j] == 'n':
+							value.WriteByte('\n')
+						case rest[j] == '\\' || rest[j] == '"':
+							value.WriteByte(rest[j])
+						default:
+							return nil, fail("invalid escape \\%c", rest[j])
+						}
+						continue
+					}
+					if rest[j] == '\n' {
+						return nil, fail("raw newline in label value")
+					}
+					value.WriteByte(rest[j])
+				}
+				if j == len(rest) {
+					return nil, fail("unterminated label value")
+				}
+				if _, dup := sample.labels[name]; dup {
+					return nil, fail("duplicate label %q", name)
+				}
+				sample.labels[name] = value.String()
+				rest = strings.TrimPrefix(rest[j+1:], ",")
+			}
+			rest = rest[1:]
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 1 || len(fields) > 2 || !strings.HasPrefix(rest, " ") {
+			return nil, fail("expected a value and an optional timestamp")
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fail("invalid value %q", fields[0])
+		}
+		sample.value = value
+		if _, ok := types[sample.name]; !ok {
+			return nil, fail("sample of %s without a TYPE", sample.name)
+		}
+		seen[sample.name] = true
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+func TestParseExposition(t *testing.T) {
+	for _, bad := range []string{
+		"no_newline 1",
+		"# TYPE m gauge\nm{a=\"b\"} x\n",
+		"# TYPE m gauge\nm{a=\"b\\t\"} 1\n",
+		"# TYPE m gauge\nm{a=\"b} 1\n",
+		"m 1\n",
+		"# TYPE 1m gauge\n1m 1\n",
+		"# TYPE m gauge\nm 1\n# HELP m late\n",
+	} {
+		if _, err := parseExposition(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	metrics := dag.Metrics{Components: 23, Edges: 30, Groups: 6, MaxDepth: 5, DanglingRefs: 2, Cycles: true}
+	labels := PrometheusLabels{SerialNumber: "urn:uuid:1234", SpecVersion: "1.6"}
+
+	var buf bytes.Buffer
+	if err := WritePrometheus(&buf, metrics, labels); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+	samples, err := parseExposition(buf.String())
+	if err != nil {
+		t.Fatalf("Invalid exposition format: %v\n%s", err, buf.String())
+	}
+
+	want := map[string]float64{
+		"bom_dagger_components_total":    23,
+		"bom_dagger_edges_total":         30,
+		"bom_dagger_groups_total":        6,
+		"bom_dagger_max_depth":           5,
+		"bom_dagger_dangling_refs_total": 2,
+		"bom_dagger_cycles_detected":     1,
+	}
+	if len(samples) != len(want) {
+		t.Fatalf("Expected %d samples, got %d", len(want), len(samples))
+	}
+	for _, sample := range samples {
+		if value, ok := want[sample.name]; !ok || sample.value != value {
+			t.Errorf("Unexpected sample %s = %v", sample.name, sample.value)
+		}
+		if sample.labels["serial_number"] != "urn:uuid:1234" || sample.labels["spec_version"] != "1.6" {
+			t.Errorf("Unexpected labels on %s: %v", sample.name, sample.labels)
+		}
+	}
+}
+
+func TestWritePrometheusEscapesLabels(t *testing.T) {
+	serial := "urn:\"odd\"\\serial\nnumber"
+
+	var buf bytes.Buffer
+	if err := WritePrometheus(&buf, dag.Metrics{}, PrometheusLabels{SerialNumber: serial}); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+	samples, err := parseExposition(buf.String())
+	if err != nil {
+		t.Fatalf("Invalid exposition format: %v\n%s", err, buf.String())
+	}
+	for _, sample := range samples {
+		if sample.labels["serial_number"] != serial {
+			t.Errorf("Expected the serial number to survive escaping, got %q", sample.labels["serial_number"])
+		}
+	}
+}
+
+func TestPrometheusWriter(t *testing.T) {
+	samples, err := parseExposition(render(t, "prometheus", Options{}))
+	if err != nil {
+		t.Fatalf("Invalid exposition format: %v", err)
+	}
+	if len(samples) != 6 || samples[0].name != "bom_dagger_components_total" || samples[0].value != 3 {
+		t.Errorf("Expected 6 samples starting with 3 components, got %+v", samples)
+	}
+}
@@ -0,0 +1,98 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nprimmer/bom-dagger/internal/plan"
+)
+
+// ANSI SGR sequences used by Style
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// Style colors the order and groups formats for a terminal. The zero Style
+// writes plain text.
+type Style struct {
+	Color bool // Wrap text in ANSI escape sequences
+}
+
+func (s Style) wrap(code, text string) string {
+	if !s.Color || text == "" {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// Header styles step and group headings (bold)
+func (s Style) Header(text string) string { return s.wrap(ansiBold, text) }
+
+// Service styles the names of services (cyan)
+func (s Style) Service(text string) string { return s.wrap(ansiCyan, text) }
+
+// Warning styles warnings (yellow)
+func (s Style) Warning(text string) string { return s.wrap(ansiYellow, text) }
+
+// Dim styles secondary text such as placeholders for external refs
+func (s Style) Dim(text string) string { return s.wrap(ansiDim, text) }
+
+// entry styles the name of a plan entry: external placeholders dim, services
+// cyan and components as they are
+func (s Style) entry(entry plan.Entry, text string) string {
+	switch {
+	case entry.Synthetic:
+		return s.Dim(text)
+	case entry.Kind == "service":
+		return s.Service(text)
+	}
+	return text
+}
+
+// ColorMode is the --color setting
+type ColorMode string
+
+// Supported values for the --color flag
+const (
+	ColorAuto   ColorMode = "auto"   // Color terminals unless NO_COLOR is set
+	ColorAlways ColorMode = "always" // Color even when piped
+	ColorNever  ColorMode = "never"
+)
+
+// ParseColorMode validates a --color value
+func ParseColorMode(s string) (ColorMode, error) {
+	switch ColorMode(s) {
+	case ColorAuto, ColorAlways, ColorNever:
+		return ColorMode(s), nil
+	}
+	return "", fmt.Errorf("unknown color mode %q (expected auto, always or never)", s)
+}
+
+// Enabled reports whether output written to w is colored: always with
+// ColorAlways, never with ColorNever, and with ColorAuto when w is a terminal
+// and the NO_COLOR environment variable (https://no-color.org) is empty
+func (m ColorMode) Enabled(w io.Writer) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && isTerminal(w)
+}
+
+// isTerminal reports whether w is a file on a character device, such as a
+// terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/plan"
+)
+
+func styledPlan() *plan.Plan {
+	return &plan.Plan{SchemaVersion: plan.SchemaVersion, Steps: []plan.Step{
+		{Step: 1, Components: []plan.Entry{
+			{BOMRef: "db", Name: "Database", Version: "15", Kind: "component"},
+			{BOMRef: "pkg:npm/left-pad", Name: "pkg:npm/left-pad", Kind: "component", Synthetic: true},
+		}},
+		{Step: 2, Components: []plan.Entry{{BOMRef: "api", Name: "API", Kind: "service"}}},
+	}}
+}
+
+func TestStyle(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(*bytes.Buffer, Options) error
+		plain string
+		color string
+	}{
+		{
+			"order",
+			func(buf *bytes.Buffer, opts Options) error { return WriteOrder(buf, styledPlan(), nil, opts) },
+			"=== Deployment Order ===\nDeploy components in this sequence:\n\n" +
+				"Step 1:\n  - Database (ref: db)\n  - pkg:npm/left-pad (ref: pkg:npm/left-pad) (external)\n\n" +
+				"Step 2:\n  - API (ref: api)\n",
+			"\x1b[1m=== Deployment Order ===\x1b[0m\nDeploy components in this sequence:\n\n" +
+				"\x1b[1mStep 1:\x1b[0m\n  - Database (ref: db)\n  - \x1b[2mpkg:npm/left-pad\x1b[0m (ref: pkg:npm/left-pad) (external)\n\n" +
+				"\x1b[1mStep 2:\x1b[0m\n  - \x1b[36mAPI\x1b[0m (ref: api)\n",
+		},
+		{
+			"groups",
+			func(buf *bytes.Buffer, opts Options) error { return WriteGroups(buf, styledPlan(), opts) },
+			"=== Deployment Groups ===\nComponents in the same group can be deployed in parallel:\n\n" +
+				"Group 1 (can deploy in parallel):\n  - Database (15)\n  - pkg:npm/left-pad (external)\n    ↓\n" +
+				"Group 2 (can deploy in parallel):\n  - API\n",
+			"\x1b[1m=== Deployment Groups ===\x1b[0m\nComponents in the same group can be deployed in parallel:\n\n" +
+				"\x1b[1mGroup 1 (can deploy in parallel):\x1b[0m\n  - Database (15)\n  - \x1b[2mpkg:npm/left-pad\x1b[0m (external)\n    ↓\n" +
+				"\x1b[1mGroup 2 (can deploy in parallel):\x1b[0m\n  - \x1b[36mAPI\x1b[0m\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.write(&buf, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.plain {
+				t.Errorf("Plain:\n%q\nWant:\n%q", buf.String(), tt.plain)
+			}
+
+			buf.Reset()
+			if err := tt.write(&buf, Options{Style: Style{Color: true}}); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.color {
+				t.Errorf("Colored:\n%q\nWant:\n%q", buf.String(), tt.color)
+			}
+		})
+	}
+}
+
+func TestColorMode(t *testing.T) {
+	for _, s := range []string{"auto", "always", "never"} {
+		if m, err := ParseColorMode(s); err != nil || string(m) != s {
+			t.Errorf("ParseColorMode(%q) = %q, %v", s, m, err)
+		}
+	}
+	if _, err := ParseColorMode("sometimes"); err == nil {
+		t.Error("Expected an error for an unknown color mode")
+	}
+
+	// A regular file stands in for a pipe or redirect
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if ColorAuto.Enabled(f) || ColorNever.Enabled(f) {
+		t.Error("Expected no color when not writing to a terminal")
+	}
+	if !ColorAlways.Enabled(f) {
+		t.Error("Expected ColorAlways to color any output")
+	}
+
+	// So does a writer that is not a file at all
+	var buf bytes.Buffer
+	if ColorAuto.Enabled(&buf) || !ColorAlways.Enabled(&buf) {
+		t.Error("Expected a buffer to be colored only with ColorAlways")
+	}
+}
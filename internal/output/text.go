@@ -0,0 +1,352 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/ident"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+)
+
+const (
+	// DefaultDescribeWidth is the line width descriptions are wrapped at when
+	// the terminal width is unknown
+	DefaultDescribeWidth = 100
+	// DefaultDescribeMax is the length descriptions are truncated at
+	DefaultDescribeMax = 300
+
+	// descriptionIndent lines descriptions up under the entry they belong to
+	descriptionIndent = "      "
+	// minDescribeWidth keeps very narrow terminals from wrapping every word
+	minDescribeWidth = 20
+)
+
+// Numbering is the --numbering setting: the numbers the order format shows
+type Numbering string
+
+// Supported values for the --numbering flag
+const (
+	NumberGroup    Numbering = "group"    // Steps numbered by deployment group
+	NumberSequence Numbering = "sequence" // Components numbered 1..N, with their group
+)
+
+// ParseNumbering validates a --numbering value
+func ParseNumbering(s string) (Numbering, error) {
+	switch Numbering(s) {
+	case NumberGroup, NumberSequence:
+		return Numbering(s), nil
+	}
+	return "", fmt.Errorf("unknown numbering %q (expected sequence or group)", s)
+}
+
+// WriteOrder renders the plan as the deployment order shown by default. g is
+// the graph the plan was computed from; it is only read for
+// Options.ShowBlockers and may be nil otherwise.
+func WriteOrder(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	blockers := func(node *dag.Node) []*dag.Node { return node.Dependencies }
+	return writeSteps(w, p, g, opts, blockers, "=== Deployment Order ===", "Deploy components in this sequence:")
+}
+
+// WriteTeardown renders a teardown plan (see plan.NewTeardown) as a
+// teardown order; the components of a step can be removed in parallel. With Options.ShowBlockers, the blockers of a component are
+// its dependents, which have to be removed first.
+func WriteTeardown(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	blockers := func(node *dag.Node) []*dag.Node { return node.Dependents }
+	return writeSteps(w, p, g, opts, blockers, "=== Teardown Order ===", "Remove/stop components in this sequence:")
+}
+
+func writeSteps(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options, blockers func(*dag.Node) []*dag.Node, title, intro string) error {
+	sequence := opts.Numbering == NumberSequence
+	steps := make(map[string]int)
+	for _, step := range p.Steps {
+		for _, entry := range step.Components {
+			steps[entry.BOMRef] = step.Step
+			if sequence {
+				steps[entry.BOMRef] = entry.Sequence
+			}
+		}
+	}
+	position := "step %d"
+	if sequence {
+		position = "#%d"
+	}
+
+	style := opts.Style
+	var b strings.Builder
+	b.WriteString(style.Header(title) + "\n" + intro + "\n\n")
+	if sequence {
+		// One line per component, so the number identifies it
+		for _, step := range p.Steps {
+			if err := pause(w, &b, step.Step, opts); err != nil {
+				return err
+			}
+			writeWindow(&b, p, step.Step, style)
+			for _, entry := range step.Components {
+				fmt.Fprintf(&b, "  %s %s (ref: %s%s, group %d)%s%s\n", style.Header(fmt.Sprintf("%d.", entry.Sequence)), style.entry(entry, opts.shorten(entry.Name)),
+					opts.shorten(entry.BOMRef), aliasNote(entry, opts), entry.Group, externalMarker(entry), trustMarker(entry, opts))
+				if opts.ShowBlockers && g != nil {
+					writeBlockers(&b, g, entry.BOMRef, blockers, steps, position, opts)
+				}
+				writeDescription(&b, entry, opts)
+			}
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	for i, step := range p.Steps {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if err := pause(w, &b, step.Step, opts); err != nil {
+			return err
+		}
+		writeWindow(&b, p, step.Step, style)
+		b.WriteString(style.Header(fmt.Sprintf("Step %d:", step.Step)) + "\n")
+		for _, entry := range step.Components {
+			fmt.Fprintf(&b, "  - %s (ref: %s%s)%s%s\n", style.entry(entry, opts.shorten(entry.Name)), opts.shorten(entry.BOMRef), aliasNote(entry, opts),
+				externalMarker(entry), trustMarker(entry, opts))
+			if opts.ShowBlockers && g != nil {
+				writeBlockers(&b, g, entry.BOMRef, blockers, steps, position, opts)
+			}
+			writeDescription(&b, entry, opts)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeWindow writes a heading when one of the plan's maintenance windows
+// (see plan.Plan.SplitWindows) starts at step
+func writeWindow(b *strings.Builder, p *plan.Plan, step int, style Style) {
+	for _, window := range p.Windows {
+		if len(window.Steps) == 0 || window.Steps[0] != step {
+			continue
+		}
+		steps := fmt.Sprintf("step %d", step)
+		if last := window.Steps[len(window.Steps)-1]; last != step {
+			steps = fmt.Sprintf("steps %d-%d", step, last)
+		}
+		if window.Oversized {
+			b.WriteString(style.Warning(fmt.Sprintf("--- Window %d: %s, %s, longer than a window ---", window.Window, steps, window.Duration)) + "\n")
+			continue
+		}
+		b.WriteString(style.Header(fmt.Sprintf("--- Window %d: %s, %s ---", window.Window, steps, window.Duration)) + "\n")
+	}
+}
+
+// WriteGroups renders the plan as deployment groups of parallel components,
+// or with Options.Teardown a teardown plan as teardown groups
+func WriteGroups(w io.Writer, p *plan.Plan, opts Options) error {
+	style := opts.Style
+	title, verb, done := "Deployment", "deploy", "deployed"
+	if opts.Teardown {
+		title, verb, done = "Teardown", "remove", "removed"
+	}
+	var b strings.Builder
+	b.WriteString(style.Header(fmt.Sprintf("=== %s Groups ===", title)) + "\n")
+	fmt.Fprintf(&b, "Components in the same group can be %s in parallel:\n\n", done)
+
+	for i, step := range p.Steps {
+		if err := pause(w, &b, step.Step, opts); err != nil {
+			return err
+		}
+		b.WriteString(style.Header(fmt.Sprintf("Group %d (can %s in parallel):", step.Step, verb)) + "\n")
+		if opts.ByOwner {
+			for _, owned := range byOwner(step.Components) {
+				fmt.Fprintf(&b, "  %s:\n", owned.owner)
+				for _, entry := range owned.entries {
+					writeGroupEntry(&b, "    ", entry, opts)
+				}
+			}
+		} else {
+			for _, entry := range step.Components {
+				writeGroupEntry(&b, "  ", entry, opts)
+			}
+		}
+		if i < len(p.Steps)-1 {
+			b.WriteString("    ↓\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// pause writes what b holds so far to w and calls Options.Pause before step
+func pause(w io.Writer, b *strings.Builder, step int, opts Options) error {
+	if opts.Pause == nil {
+		return nil
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	b.Reset()
+	return opts.Pause(step)
+}
+
+// writeGroupEntry writes one component of a deployment group
+func writeGroupEntry(b *strings.Builder, indent string, entry plan.Entry, opts Options) {
+	name := opts.Style.entry(entry, opts.shorten(entry.Name))
+	if entry.Version != "" {
+		fmt.Fprintf(b, "%s- %s (%s)%s%s\n", indent, name, opts.shorten(entry.Version), externalMarker(entry), trustMarker(entry, opts))
+	} else {
+		fmt.Fprintf(b, "%s- %s%s%s\n", indent, name, externalMarker(entry), trustMarker(entry, opts))
+	}
+	writeDescription(b, entry, opts)
+}
+
+// ownedEntries are the entries of one group owned by one team
+type ownedEntries struct {
+	owner   string
+	entries []plan.Entry
+}
+
+// byOwner partitions the entries of a group by owner, keeping their order.
+// Owners are sorted by name, with dag.Unowned last.
+func byOwner(entries []plan.Entry) []ownedEntries {
+	index := make(map[string]int)
+	var partitions []ownedEntries
+	for _, entry := range entries {
+		i, ok := index[entry.Owner]
+		if !ok {
+			i = len(partitions)
+			index[entry.Owner] = i
+			partitions = append(partitions, ownedEntries{owner: entry.Owner})
+		}
+		partitions[i].entries = append(partitions[i].entries, entry)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if unownedI, unownedJ := partitions[i].owner == dag.Unowned, partitions[j].owner == dag.Unowned; unownedI != unownedJ {
+			return unownedJ
+		}
+		return partitions[i].owner < partitions[j].owner
+	})
+	return partitions
+}
+
+// writeBlockers lists the components that have to be done before ref, the
+// closest step first; those left out of the printed plan have no step
+func writeBlockers(b *strings.Builder, g *dag.Graph, ref string, blockers func(*dag.Node) []*dag.Node, steps map[string]int, position string, opts Options) {
+	node, ok := g.Node(ref)
+	if !ok || len(blockers(node)) == 0 {
+		return
+	}
+	gating := append([]*dag.Node(nil), blockers(node)...)
+	sort.Slice(gating, func(i, j int) bool {
+		if si, sj := steps[gating[i].ID], steps[gating[j].ID]; si != sj {
+			return si > sj
+		}
+		return gating[i].ID < gating[j].ID
+	})
+	names := make([]string, len(gating))
+	for i, blocker := range gating {
+		if step, ok := steps[blocker.ID]; ok {
+			names[i] = fmt.Sprintf("%s ("+position+")", opts.shorten(blocker.Name()), step)
+		} else {
+			names[i] = fmt.Sprintf("%s (ref: %s)", opts.shorten(blocker.Name()), opts.shorten(blocker.ID))
+		}
+	}
+	b.WriteString(descriptionIndent + "blocked by: " + strings.Join(names, ", ") + "\n")
+}
+
+// writeDescription writes the entry's publisher and its description,
+// truncated and wrapped, under it when Options.Describe is set
+func writeDescription(b *strings.Builder, entry plan.Entry, opts Options) {
+	if !opts.Describe {
+		return
+	}
+	if entry.Publisher != "" {
+		b.WriteString(descriptionIndent + "published by: " + entry.Publisher + "\n")
+	}
+	if entry.Description == "" {
+		return
+	}
+	width := opts.DescribeWidth
+	if width <= 0 {
+		width = DefaultDescribeWidth
+	}
+	text := TruncateDescription(entry.Description, opts.DescribeMax)
+	for _, line := range WrapText(text, max(width-len(descriptionIndent), minDescribeWidth)) {
+		b.WriteString(descriptionIndent + line + "\n")
+	}
+}
+
+// TruncateDescription collapses the whitespace in s, including line breaks,
+// and shortens it to at most limit characters, ending in an ellipsis when it
+// was cut. A limit of zero or less keeps the whole text.
+func TruncateDescription(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if limit <= 0 || utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimRight(string(runes[:max(limit-1, 0)]), " ") + "…"
+}
+
+// WrapText breaks s into lines of at most width characters at spaces. Words
+// longer than width get a line of their own.
+func WrapText(s string, width int) []string {
+	var lines []string
+	var line strings.Builder
+	lineLen := 0
+	for _, word := range strings.Fields(s) {
+		wordLen := utf8.RuneCountInString(word)
+		if lineLen > 0 && lineLen+1+wordLen > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			line.WriteByte(' ')
+			lineLen++
+		}
+		line.WriteString(word)
+		lineLen += wordLen
+	}
+	if lineLen > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// aliasNote lists the other refs of an entry unified by purl
+func aliasNote(entry plan.Entry, opts Options) string {
+	if len(entry.Aliases) == 0 {
+		return ""
+	}
+	aliases := make([]string, len(entry.Aliases))
+	for i, alias := range entry.Aliases {
+		aliases[i] = opts.shorten(alias)
+	}
+	return ", also known as " + strings.Join(aliases, ", ")
+}
+
+// shorten shortens a name or ref for display (see ident.Shorten)
+func (o Options) shorten(s string) string {
+	return ident.Shorten(s, o.MaxLabelLen)
+}
+
+// externalMarker flags placeholder entries for refs the SBOM does not declare
+func externalMarker(entry plan.Entry) string {
+	if entry.Synthetic {
+		return " (external)"
+	}
+	return ""
+}
+
+// trustMarker flags services crossing a trust boundary with
+// Options.ShowTrust, noting when they declare no authentication
+func trustMarker(entry plan.Entry, opts Options) string {
+	if !opts.ShowTrust || entry.XTrustBoundary == nil || !*entry.XTrustBoundary {
+		return ""
+	}
+	if entry.Authenticated != nil && !*entry.Authenticated {
+		return " [trust boundary, unauthenticated]"
+	}
+	return " [trust boundary]"
+}
@@ -0,0 +1,327 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+)
+
+func TestTruncateDescription(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{"short", "Primary database", 20, "Primary database"},
+		{"exact", "Primary database", 16, "Primary database"},
+		{"cut", "Primary database", 10, "Primary d…"},
+		{"no trailing space before the ellipsis", "Primary database", 9, "Primary…"},
+		{"unlimited", "Primary database", 0, "Primary database"},
+		{"whitespace collapsed", "  Primary\n\tdatabase  ", 0, "Primary database"},
+		{"counts characters, not bytes", "Größe über alles", 6, "Größe…"},
+		{"limit of one", "Primary", 1, "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TruncateDescription(tt.text, tt.limit); got != tt.want {
+				t.Errorf("TruncateDescription(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{"fits", "one two three", 20, []string{"one two three"}},
+		{"breaks at spaces", "one two three four", 9, []string{"one two", "three", "four"}},
+		{"exact width", "one two", 7, []string{"one two"}},
+		{"long word on its own line", "a supercalifragilistic word", 10, []string{"a", "supercalifragilistic", "word"}},
+		{"counts characters, not bytes", "ääää ööö", 8, []string{"ääää ööö"}},
+		{"empty", "   ", 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WrapText(tt.text, tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+// describedPlan has one component with a long description and one without
+func describedPlan() *plan.Plan {
+	return &plan.Plan{SchemaVersion: plan.SchemaVersion, Steps: []plan.Step{
+		{Step: 1, Components: []plan.Entry{{BOMRef: "db", Name: "Database", Version: "15.0", Kind: "component",
+			Description: "Primary relational store for orders, customers and the audit log, replicated across zones"}}},
+		{Step: 2, Components: []plan.Entry{{BOMRef: "api", Name: "API", Kind: "component"}}},
+	}}
+}
+
+func TestWriteOrderDescribe(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteOrder(&buf, describedPlan(), nil, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "Primary") {
+		t.Errorf("Expected no descriptions without Describe, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteOrder(&buf, describedPlan(), nil, Options{Describe: true, DescribeWidth: 40, DescribeMax: 60}); err != nil {
+		t.Fatal(err)
+	}
+	want := "Step 1:\n" +
+		"  - Database (ref: db)\n" +
+		"      Primary relational store for\n" +
+		"      orders, customers and the audi…\n" +
+		"\n" +
+		"Step 2:\n" +
+		"  - API (ref: api)\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("Expected wrapped, truncated descriptions:\n%s\nGot:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteGroupsDescribe(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGroups(&buf, describedPlan(), Options{Describe: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := "  - Database (15.0)\n" +
+		"      Primary relational store for orders, customers and the audit log, replicated across zones\n" +
+		"    ↓\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected the description under its group entry:\n%s\nGot:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteOrderDescribePublisher(t *testing.T) {
+	p := describedPlan()
+	p.Steps[1].Components[0].Publisher = "Acme Payments"
+
+	var buf bytes.Buffer
+	if err := WriteOrder(&buf, p, nil, Options{Describe: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := "Step 2:\n" +
+		"  - API (ref: api)\n" +
+		"      published by: Acme Payments\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("Expected the publisher under its entry:\n%s\nGot:\n%s", want, buf.String())
+	}
+	if strings.Count(buf.String(), "published by") != 1 {
+		t.Errorf("Expected no publisher line for the entry without one, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteOrder(&buf, p, nil, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "published by") {
+		t.Errorf("Expected no publisher without Describe, got:\n%s", buf.String())
+	}
+}
+
+func TestMarkdownWriterTruncatesDescriptions(t *testing.T) {
+	w, err := Lookup("markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := describedPlan()
+	var buf bytes.Buffer
+	if err := w.Write(&buf, p, nil, Options{DescribeMax: 16}); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, buf.String(), "| Database | 15.0 | component | `db` | Primary relatio… |")
+	if p.Steps[0].Components[0].Description == "Primary relatio…" {
+		t.Error("Expected the plan passed in to be left unchanged")
+	}
+}
+
+func TestWriteOrderShowBlockers(t *testing.T) {
+	out := render(t, "order", Options{ShowBlockers: true})
+	assertContains(t, out,
+		"  - Database (ref: db)\n\nStep 2:",
+		"  - API (ref: api)\n      blocked by: Database (step 1)\n",
+		"  - Gateway (ref: gateway)\n      blocked by: API (step 2)\n")
+
+	out = render(t, "order", Options{ShowBlockers: true, Teardown: true})
+	assertContains(t, out,
+		"  - Gateway (ref: gateway)\n\n",
+		"  - Database (ref: db)\n      blocked by: API (step 2)\n")
+
+	if out := render(t, "order", Options{}); strings.Contains(out, "blocked by") {
+		t.Errorf("Expected no blockers without ShowBlockers, got:\n%s", out)
+	}
+}
+
+func TestWriteOrderNumberingSequence(t *testing.T) {
+	out := render(t, "order", Options{Numbering: NumberSequence, ShowBlockers: true})
+	assertContains(t, out,
+		"  1. Database (ref: db, group 1)\n  2. API (ref: api, group 2)\n      blocked by: Database (#1)\n",
+		"  3. Gateway (ref: gateway, group 3)\n      blocked by: API (#2)\n")
+	if strings.Contains(out, "Step ") {
+		t.Errorf("Expected no step headings, got:\n%s", out)
+	}
+
+	out = render(t, "order", Options{Numbering: NumberSequence, Teardown: true})
+	assertContains(t, out, "  1. Gateway (ref: gateway, group 1)\n", "  3. Database (ref: db, group 3)\n")
+}
+
+func TestWriteOrderWindows(t *testing.T) {
+	p := describedPlan()
+	p.Windows = []plan.Window{
+		{Window: 1, Steps: []int{1}, Duration: "3h0m0s", Oversized: true},
+		{Window: 2, Steps: []int{2}, Duration: "1h0m0s"},
+	}
+	p.Steps[0].Components[0].Sequence, p.Steps[0].Components[0].Group = 1, 1
+	p.Steps[1].Components[0].Sequence, p.Steps[1].Components[0].Group = 2, 2
+	var buf bytes.Buffer
+	if err := WriteOrder(&buf, p, nil, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, buf.String(),
+		"--- Window 1: step 1, 3h0m0s, longer than a window ---\nStep 1:\n",
+		"\n--- Window 2: step 2, 1h0m0s ---\nStep 2:\n")
+	if strings.Count(buf.String(), "--- Window") != 2 {
+		t.Errorf("Expected two window headings, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteOrder(&buf, p, nil, Options{Numbering: NumberSequence}); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, buf.String(), "--- Window 2: step 2, 1h0m0s ---\n  2. API (ref: api, group 2)\n")
+}
+
+func TestParseNumbering(t *testing.T) {
+	for _, s := range []string{"group", "sequence"} {
+		if n, err := ParseNumbering(s); err != nil || string(n) != s {
+			t.Errorf("ParseNumbering(%q) = %q, %v", s, n, err)
+		}
+	}
+	if _, err := ParseNumbering("level"); err == nil {
+		t.Error("Expected an error for an unknown numbering")
+	}
+}
+
+func TestWritePause(t *testing.T) {
+	// Each step is written before the pause for the next, so an operator
+	// sees a group before being asked to continue. The first pause comes
+	// after the header, so group 1 can be logged as shown.
+	var buf bytes.Buffer
+	var seen []string
+	pause := func(step int) error {
+		seen = append(seen, buf.String())
+		return nil
+	}
+	if err := WriteGroups(&buf, describedPlan(), Options{Pause: pause}); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 2 || !strings.HasSuffix(seen[0], "deployed in parallel:\n\n") || !strings.HasSuffix(seen[1], "  - Database (15.0)\n    ↓\n") {
+		t.Errorf("Expected a pause before group 1 and one after it, got %q", seen)
+	}
+	if !strings.HasSuffix(buf.String(), "Group 2 (can deploy in parallel):\n  - API\n") {
+		t.Errorf("Expected group 2 after the pause, got:\n%s", buf.String())
+	}
+
+	stop := errors.New("stop")
+	for _, numbering := range []Numbering{NumberGroup, NumberSequence} {
+		buf.Reset()
+		stopAfterFirst := func(step int) error {
+			if step > 1 {
+				return stop
+			}
+			return nil
+		}
+		err := WriteOrder(&buf, describedPlan(), nil, Options{Numbering: numbering, Pause: stopAfterFirst})
+		if !errors.Is(err, stop) {
+			t.Errorf("%s: expected the pause error, got %v", numbering, err)
+		}
+		if !strings.Contains(buf.String(), "Database") || strings.Contains(buf.String(), "API") {
+			t.Errorf("%s: expected only step 1, got:\n%s", numbering, buf.String())
+		}
+	}
+}
+
+func TestShowTrust(t *testing.T) {
+	yes, no := true, false
+	p := &plan.Plan{SchemaVersion: plan.SchemaVersion, Steps: []plan.Step{
+		{Step: 1, Components: []plan.Entry{
+			{BOMRef: "geoip", Name: "GeoIP", Kind: "service", Authenticated: &no, XTrustBoundary: &yes},
+			{BOMRef: "orders", Name: "Orders", Version: "3.2", Kind: "service", Authenticated: &no, XTrustBoundary: &no},
+			{BOMRef: "payments", Name: "Payments", Version: "2024-06", Kind: "service", Authenticated: &yes, XTrustBoundary: &yes},
+			{BOMRef: "stock", Name: "Stock", Kind: "service"},
+		}},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteOrder(&buf, p, nil, Options{ShowTrust: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, buf.String(),
+		"  - GeoIP (ref: geoip) [trust boundary, unauthenticated]\n",
+		"  - Orders (ref: orders)\n",
+		"  - Payments (ref: payments) [trust boundary]\n",
+		"  - Stock (ref: stock)\n")
+
+	buf.Reset()
+	if err := WriteGroups(&buf, p, Options{ShowTrust: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, buf.String(),
+		"  - GeoIP [trust boundary, unauthenticated]\n",
+		"  - Orders (3.2)\n",
+		"  - Payments (2024-06) [trust boundary]\n")
+
+	buf.Reset()
+	if err := WriteOrder(&buf, p, nil, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "trust boundary") {
+		t.Errorf("Expected no markers without ShowTrust, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteGroupsByOwner(t *testing.T) {
+	p := &plan.Plan{SchemaVersion: plan.SchemaVersion, Steps: []plan.Step{
+		{Step: 1, Components: []plan.Entry{
+			{BOMRef: "log", Name: "Log Shipper", Owner: dag.Unowned},
+			{BOMRef: "db", Name: "Database", Version: "15.4", Owner: "payments-team"},
+			{BOMRef: "cache", Name: "Cache", Owner: "Acme"},
+			{BOMRef: "ledger", Name: "Ledger", Owner: "payments-team"},
+		}},
+		{Step: 2, Components: []plan.Entry{{BOMRef: "api", Name: "API", Owner: "payments-team"}}},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteGroups(&buf, p, Options{ByOwner: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := "Group 1 (can deploy in parallel):\n" +
+		"  Acme:\n" +
+		"    - Cache\n" +
+		"  payments-team:\n" +
+		"    - Database (15.4)\n" +
+		"    - Ledger\n" +
+		"  unowned:\n" +
+		"    - Log Shipper\n" +
+		"    ↓\n" +
+		"Group 2 (can deploy in parallel):\n" +
+		"  payments-team:\n" +
+		"    - API\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("Expected groups split by owner:\n%s\nGot:\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,294 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/plural"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// The built-in formats, in the order -o list shows them
+func init() {
+	Register(Func("order", "Deployment (or with --reverse, teardown) order, one step at a time", writeOrder))
+	Register(Func("groups", "Deployment groups of components that can be deployed in parallel", writeGroups))
+	Register(Func("dot", "Graphviz DOT graph of the dependencies", writeDOT))
+	Register(Func("json", "Deployment plan as JSON, with provenance", writeJSON))
+	Register(Func("markdown", "Deployment plan as a Markdown change-ticket attachment, with provenance", writeMarkdown))
+	Register(Func("terraform", "Terraform module blocks with depends_on from bom-dagger:tf-module", writeTerraform))
+	Register(Func("tree", "Dependency tree from the roots, or from --from", writeTree))
+	Register(Func("mermaid", "Mermaid flowchart of the dependencies", writeMermaid))
+	Register(Func("backstage", "Backstage catalog entities with dependsOn relations", writeBackstage))
+	Register(Func("schedule", "Earliest start and finish per component from bom-dagger:duration", writeSchedule))
+	Register(Func("schedule-json", "The schedule as JSON", writeScheduleJSON))
+	Register(Func("gantt", "The schedule as a Mermaid gantt chart with the critical path marked", writeGantt))
+	Register(Func("shell", "Shell script running every bom-dagger:deploy-command in plan order", writeShell))
+	Register(Func("adjacency", "Adjacency lists of dependencies and dependents as JSON", writeAdjacency))
+	Register(Func("k8s-patch", "JSON patches annotating Kubernetes manifests with their deployment wave", writeK8sPatch))
+	Register(Func("images", "Container images to pull before each deployment group, or once each with --flat", WriteImages))
+	Register(Func("spdx", "The graph as an SPDX 2.3 JSON document with DEPENDS_ON relationships", writeSPDX))
+	Register(Func("prometheus", "Graph metrics in the Prometheus textfile collector format", writePrometheus))
+	Register(Func("release-report", "What changed since --baseline and the sub-plan redeploying it and its dependents, as Markdown", writeReleaseReport))
+	Register(Func("release-report-json", "The release report as JSON", writeReleaseReportJSON))
+}
+
+// teardownFormats render the teardown plan with Options.Teardown; the others
+// draw the graph or only make sense for a deployment
+var teardownFormats = []string{"order", "groups", "json", "markdown", "shell"}
+
+// SupportsTeardown reports whether the format named name renders the
+// teardown plan (--reverse)
+func SupportsTeardown(name string) bool {
+	return slices.Contains(teardownFormats, name)
+}
+
+func writeOrder(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	if opts.Banner && WriteCompletenessBanner(w, g.Completeness(), opts.Style) {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	if opts.Teardown {
+		return WriteTeardown(w, p, g, opts)
+	}
+	return WriteOrder(w, p, g, opts)
+}
+
+// WriteCompletenessBanner warns about the components whose dependencies the
+// SBOM declares incomplete or unknown, and reports whether it wrote anything
+func WriteCompletenessBanner(w io.Writer, completeness dag.Completeness, style Style) bool {
+	if n := len(completeness.Incomplete); n > 0 {
+		fmt.Fprintln(w, style.Warning(fmt.Sprintf("WARNING: dependencies declared incomplete for %d %s", n, plural.Noun(n, "component"))))
+	}
+	if n := len(completeness.Unknown); n > 0 {
+		fmt.Fprintln(w, style.Warning(fmt.Sprintf("WARNING: dependency completeness unknown for %d %s", n, plural.Noun(n, "component"))))
+	}
+	return len(completeness.Incomplete)+len(completeness.Unknown) > 0
+}
+
+func writeGroups(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	return WriteGroups(w, p, opts)
+}
+
+func writeDOT(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	return dag.WriteDOTWithOptions(w, g, dag.DOTOptions{
+		Label:       opts.Label,
+		ClusterBy:   opts.ClusterBy,
+		ShowTrust:   opts.ShowTrust,
+		Legend:      opts.DOTLegend,
+		MaxLabelLen: opts.MaxLabelLen,
+	})
+}
+
+func writeJSON(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	return p.Write(w)
+}
+
+// writeMarkdown always includes descriptions, truncated at Options.DescribeMax
+// so long ones do not swamp the tables; names and refs are shortened to
+// Options.MaxLabelLen
+func writeMarkdown(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	truncated := *p
+	truncated.Steps = make([]plan.Step, len(p.Steps))
+	for i, step := range p.Steps {
+		truncated.Steps[i] = plan.Step{Step: step.Step, Components: make([]plan.Entry, len(step.Components))}
+		for j, entry := range step.Components {
+			entry.Description = TruncateDescription(entry.Description, opts.DescribeMax)
+			entry.Name, entry.BOMRef, entry.Version = opts.shorten(entry.Name), opts.shorten(entry.BOMRef), opts.shorten(entry.Version)
+			truncated.Steps[i].Components[j] = entry
+		}
+	}
+	if opts.Teardown {
+		return truncated.WriteTeardownMarkdown(w)
+	}
+	return truncated.WriteMarkdown(w)
+}
+
+func writeReleaseReport(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	report, err := releaseReport(p, g, opts)
+	if err != nil {
+		return err
+	}
+	for i := range report.Changes {
+		change := &report.Changes[i]
+		change.Name, change.Ref = opts.shorten(change.Name), opts.shorten(change.Ref)
+		change.Baseline, change.Current = opts.shorten(change.Baseline), opts.shorten(change.Current)
+	}
+	for _, step := range report.Steps {
+		for i := range step.Components {
+			entry := &step.Components[i]
+			entry.Name, entry.BOMRef, entry.Version = opts.shorten(entry.Name), opts.shorten(entry.BOMRef), opts.shorten(entry.Version)
+			for j := range entry.Needs {
+				entry.Needs[j] = opts.shorten(entry.Needs[j])
+			}
+		}
+	}
+	return report.WriteMarkdown(w)
+}
+
+func writeReleaseReportJSON(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	report, err := releaseReport(p, g, opts)
+	if err != nil {
+		return err
+	}
+	return report.Write(w)
+}
+
+func releaseReport(p *plan.Plan, g *dag.Graph, opts Options) (*plan.ReleaseReport, error) {
+	if opts.Baseline == nil {
+		return nil, fmt.Errorf("a release report needs a baseline SBOM (--baseline)")
+	}
+	return plan.NewReleaseReport(p, g, opts.Baseline)
+}
+
+func writeTerraform(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	skipped, err := dag.WriteTerraform(w, g)
+	if err != nil {
+		return err
+	}
+	if skipped > 0 {
+		opts.Logger.Warn(warning.New(warning.SkippedComponents, "Skipped %d components without the %s property", skipped, dag.PropertyTFModule))
+	}
+	return nil
+}
+
+func writeTree(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	return dag.WriteTree(w, g, dag.TreeOptions{From: opts.TreeFrom, MaxDepth: opts.TreeDepth, Label: opts.Label, MaxLabelLen: opts.MaxLabelLen})
+}
+
+func writeMermaid(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	return dag.WriteMermaid(w, g, dag.MermaidOptions{Label: opts.Label, ClusterBy: opts.ClusterBy, MaxLabelLen: opts.MaxLabelLen})
+}
+
+func writeBackstage(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	return dag.WriteBackstage(w, g, dag.BackstageOptions{ServiceKind: opts.ServiceKind})
+}
+
+func writeSchedule(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	items, err := schedule(g, opts)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "=== Deployment Schedule ===\nEarliest start and finish assuming unlimited parallelism:\n\n"); err != nil {
+		return err
+	}
+	return dag.WriteSchedule(w, shortNames(items, opts))
+}
+
+func writeScheduleJSON(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	items, err := schedule(g, opts)
+	if err != nil {
+		return err
+	}
+	return dag.WriteScheduleJSON(w, items)
+}
+
+func writeGantt(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	items, err := schedule(g, opts)
+	if err != nil {
+		return err
+	}
+	omitted, err := dag.WriteGantt(w, g, shortNames(items, opts), dag.GanttOptions{MaxTasks: opts.GanttMaxTasks})
+	if err != nil {
+		return err
+	}
+	if omitted > 0 {
+		opts.Logger.Warn(warning.New(warning.TruncatedOutput, "gantt chart shows the first %d of %d tasks; raise --gantt-max-tasks to see them all",
+			len(items)-omitted, len(items)))
+	}
+	return nil
+}
+
+// shortNames shortens the names of schedule items to Options.MaxLabelLen for
+// the text and gantt formats
+func shortNames(items []dag.ScheduledItem, opts Options) []dag.ScheduledItem {
+	short := make([]dag.ScheduledItem, len(items))
+	for i, item := range items {
+		item.Name = opts.shorten(item.Name)
+		short[i] = item
+	}
+	return short
+}
+
+func schedule(g *dag.Graph, opts Options) ([]dag.ScheduledItem, error) {
+	durations, err := dag.PropertyDurations(g, opts.DefaultDuration)
+	if err != nil {
+		return nil, fmt.Errorf("reading durations: %w", err)
+	}
+	items, err := g.Schedule(durations)
+	if err != nil {
+		return nil, fmt.Errorf("computing schedule: %w", err)
+	}
+	return items, nil
+}
+
+func writeShell(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	property := dag.PropertyDeployCommand
+	if opts.Teardown {
+		property = dag.PropertyTeardownCommand
+	}
+	return p.WriteShell(w, plan.ShellOptions{Commands: plan.ShellCommands(g, property), Teardown: opts.Teardown})
+}
+
+func writeAdjacency(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	return dag.WriteAdjacency(w, g, dag.AdjacencyOptions{Direction: opts.Direction})
+}
+
+func writeK8sPatch(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	skipped, err := dag.WriteK8sPatches(w, g, dag.K8sPatchOptions{Annotation: opts.K8sAnnotation})
+	if err != nil {
+		return err
+	}
+	if len(skipped) > 0 {
+		opts.Logger.Warn(warning.New(warning.SkippedComponents, "Skipped %d components without the %s and %s properties: %s",
+			len(skipped), dag.PropertyK8sKind, dag.PropertyK8sName, strings.Join(skipped, ", ")))
+	}
+	return nil
+}
+
+// writeSPDX names the document after the input SBOMs and takes its creation
+// info from the plan's provenance
+func writeSPDX(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	var spdx dag.SPDXOptions
+	if prov := p.Provenance; prov != nil {
+		spdx.Created = prov.GeneratedAt
+		if prov.Tool != "" {
+			spdx.Creators = []string{"Tool: " + strings.TrimSuffix(prov.Tool+"-"+prov.ToolVersion, "-")}
+		}
+		var names []string
+		for _, input := range prov.Inputs {
+			names = append(names, strings.TrimSuffix(filepath.Base(input.Path), filepath.Ext(input.Path)))
+		}
+		spdx.Name = strings.Join(names, "+")
+	}
+	return dag.WriteSPDX(w, g, spdx)
+}
+
+// writePrometheus labels the metrics with the serial numbers and spec
+// versions of the input SBOMs, comma-separated when there are several
+func writePrometheus(w io.Writer, p *plan.Plan, g *dag.Graph, opts Options) error {
+	var serials, versions []string
+	if p != nil && p.Provenance != nil {
+		for _, input := range p.Provenance.Inputs {
+			serials = appendNew(serials, input.SerialNumber)
+			versions = appendNew(versions, input.SpecVersion)
+		}
+	}
+	return WritePrometheus(w, g.Metrics(), PrometheusLabels{
+		SerialNumber: strings.Join(serials, ","),
+		SpecVersion:  strings.Join(versions, ","),
+	})
+}
+
+// appendNew appends value to values unless it is empty or already there
+func appendNew(values []string, value string) []string {
+	if value == "" || slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
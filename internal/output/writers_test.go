@@ -0,0 +1,204 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/logging"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// testBOM is a database, an API that depends on it and a service in front
+// of the API, with the properties the formats below read
+func testBOM() *sbom.CycloneDX {
+	return &sbom.CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.6",
+		Components: []sbom.Component{
+			{Type: "data", BOMRef: "db", Name: "Database", Version: "15.0", Properties: []sbom.Property{
+				{Name: dag.PropertyTFModule, Value: "./modules/db"},
+				{Name: dag.PropertyK8sKind, Value: "StatefulSet"},
+				{Name: dag.PropertyK8sName, Value: "db"},
+				{Name: dag.PropertyDeployCommand, Value: "helm install db"},
+				{Name: dag.PropertyTeardownCommand, Value: "helm uninstall db"},
+				{Name: dag.PropertyDuration, Value: "2m"},
+			}},
+			{Type: "application", BOMRef: "api", Name: "API", Version: "2.0", Properties: []sbom.Property{
+				{Name: dag.PropertyDeployCommand, Value: "helm install api"},
+			}},
+		},
+		Services: []sbom.Service{
+			{BOMRef: "gateway", Name: "Gateway", Endpoints: []string{"https://gateway.example.com/healthz"}},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "api", DependsOn: []string{"db"}},
+			{Ref: "gateway", DependsOn: []string{"api"}},
+		},
+	}
+}
+
+// render writes the named format for the test BOM
+func render(t *testing.T, name string, opts Options) string {
+	t.Helper()
+
+	bom := testBOM()
+	componentMap := make(map[string]*sbom.Component)
+	for i := range bom.Components {
+		componentMap[bom.Components[i].BOMRef] = &bom.Components[i]
+	}
+	g := dag.New()
+	if err := g.BuildFromSBOM(bom, componentMap); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	build := plan.New
+	if opts.Teardown && SupportsTeardown(name) {
+		build = plan.NewTeardown
+	}
+	p, err := build(g)
+	if err != nil {
+		t.Fatalf("plan.New failed: %v", err)
+	}
+
+	w, err := Lookup(name)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := w.Write(&buf, p, g, opts); err != nil {
+		t.Fatalf("Write %s failed: %v", name, err)
+	}
+	return buf.String()
+}
+
+func assertContains(t *testing.T, output string, want ...string) {
+	t.Helper()
+	for _, s := range want {
+		if !strings.Contains(output, s) {
+			t.Errorf("Expected output to contain %q, got:\n%s", s, output)
+		}
+	}
+}
+
+func TestOrderWriter(t *testing.T) {
+	out := render(t, "order", Options{})
+	assertContains(t, out, "=== Deployment Order ===", "Step 1:\n  - Database (ref: db)", "Step 3:\n  - Gateway (ref: gateway)")
+
+	out = render(t, "order", Options{Teardown: true})
+	assertContains(t, out, "=== Teardown Order ===", "Step 1:\n  - Gateway (ref: gateway)")
+}
+
+func TestOrderWriterBanner(t *testing.T) {
+	var buf bytes.Buffer
+	if !WriteCompletenessBanner(&buf, dag.Completeness{Incomplete: []string{"a", "b"}, Unknown: []string{"c"}}, Style{}) {
+		t.Error("Expected the banner to report that it wrote warnings")
+	}
+	want := "WARNING: dependencies declared incomplete for 2 components\nWARNING: dependency completeness unknown for 1 component\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+	if WriteCompletenessBanner(&buf, dag.Completeness{Complete: []string{"a"}}, Style{}) {
+		t.Error("Expected no banner for complete dependencies")
+	}
+}
+
+func TestGroupsWriter(t *testing.T) {
+	assertContains(t, render(t, "groups", Options{}), "=== Deployment Groups ===", "Group 1", "Group 3")
+}
+
+func TestDOTWriter(t *testing.T) {
+	assertContains(t, render(t, "dot", Options{}), "digraph", `"api" -> "db"`)
+}
+
+func TestJSONWriter(t *testing.T) {
+	var p plan.Plan
+	if err := json.Unmarshal([]byte(render(t, "json", Options{})), &p); err != nil {
+		t.Fatalf("Expected JSON output: %v", err)
+	}
+	if len(p.Steps) != 3 || p.Steps[2].Components[0].BOMRef != "gateway" {
+		t.Errorf("Expected three steps ending with the gateway, got %+v", p.Steps)
+	}
+}
+
+func TestMarkdownWriter(t *testing.T) {
+	assertContains(t, render(t, "markdown", Options{}), "## Step 3", "| Gateway |  | service | `gateway` |")
+}
+
+func TestTerraformWriter(t *testing.T) {
+	var logs bytes.Buffer
+	out := render(t, "terraform", Options{Logger: logging.New(&logs, logging.LevelWarn, logging.FormatText)})
+	assertContains(t, out, `source = "./modules/db"`)
+	assertContains(t, logs.String(), "Skipped 2 components without the bom-dagger:tf-module property")
+}
+
+func TestTreeWriter(t *testing.T) {
+	out := render(t, "tree", Options{TreeFrom: "api"})
+	assertContains(t, out, "API (ref: api)\n└── Gateway (ref: gateway)")
+	if strings.Contains(out, "Database") {
+		t.Errorf("Expected the tree to start at api, got:\n%s", out)
+	}
+}
+
+func TestMermaidWriter(t *testing.T) {
+	assertContains(t, render(t, "mermaid", Options{}), "flowchart")
+}
+
+func TestBackstageWriter(t *testing.T) {
+	assertContains(t, render(t, "backstage", Options{ServiceKind: "api"}), "kind: API", "name: gateway")
+}
+
+func TestScheduleWriters(t *testing.T) {
+	out := render(t, "schedule", Options{DefaultDuration: time.Minute})
+	assertContains(t, out, "=== Deployment Schedule ===", "Database")
+
+	var items []map[string]any
+	if err := json.Unmarshal([]byte(render(t, "schedule-json", Options{DefaultDuration: time.Minute})), &items); err != nil {
+		t.Fatalf("Expected JSON schedule: %v", err)
+	}
+	if len(items) != 3 {
+		t.Errorf("Expected 3 scheduled items, got %d", len(items))
+	}
+}
+
+func TestGanttWriter(t *testing.T) {
+	var logs bytes.Buffer
+	out := render(t, "gantt", Options{DefaultDuration: time.Minute, GanttMaxTasks: 2, Logger: logging.New(&logs, logging.LevelWarn, logging.FormatText)})
+	assertContains(t, out, "gantt\n", "section Step 1\n    Database :crit, t1, 0, 120s", "section Step 2")
+	assertContains(t, logs.String(), "gantt chart shows the first 2 of 3 tasks")
+}
+
+func TestShellWriter(t *testing.T) {
+	assertContains(t, render(t, "shell", Options{}), "helm install db", "helm install api")
+	assertContains(t, render(t, "shell", Options{Teardown: true}), "helm uninstall db")
+}
+
+func TestAdjacencyWriter(t *testing.T) {
+	var adjacency map[string]any
+	if err := json.Unmarshal([]byte(render(t, "adjacency", Options{Direction: dag.AdjacencyBoth})), &adjacency); err != nil {
+		t.Fatalf("Expected JSON adjacency: %v", err)
+	}
+	if len(adjacency) == 0 {
+		t.Error("Expected adjacency lists")
+	}
+}
+
+func TestK8sPatchWriter(t *testing.T) {
+	var logs bytes.Buffer
+	out := render(t, "k8s-patch", Options{K8sAnnotation: dag.DefaultK8sAnnotation, Logger: logging.New(&logs, logging.LevelWarn, logging.FormatText)})
+	assertContains(t, out, "StatefulSet", "bom-dagger.io~1wave")
+	assertContains(t, logs.String(), "Skipped 2 components", "api, gateway")
+}
+
+func TestSPDXWriter(t *testing.T) {
+	var doc dag.SPDXDocument
+	if err := json.Unmarshal([]byte(render(t, "spdx", Options{})), &doc); err != nil {
+		t.Fatalf("Expected an SPDX JSON document: %v", err)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || doc.Name != "bom-dagger" || len(doc.Packages) != 3 {
+		t.Errorf("Expected an SPDX 2.3 document with 3 packages, got %+v", doc)
+	}
+}
@@ -0,0 +1,184 @@
+package overlay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// Overlay holds local ordering tweaks applied to an SBOM before the graph is
+// built; excluding a component also excludes the components nested in it
+type Overlay struct {
+	AddDependencies    []sbom.Dependency `json:"addDependencies,omitempty"`
+	RemoveDependencies []sbom.Dependency `json:"removeDependencies,omitempty"`
+	ExcludeRefs        []string          `json:"excludeRefs,omitempty"`
+}
+
+// Load reads an overlay from a reader
+func Load(r io.Reader) (*Overlay, error) {
+	var o Overlay
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&o); err != nil {
+		return nil, fmt.Errorf("failed to decode overlay: %w", err)
+	}
+	return &o, nil
+}
+
+// LoadFile reads an overlay from a file path
+func LoadFile(filePath string) (*Overlay, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open overlay: %w", err)
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// Write serializes the overlay as indented JSON
+func (o *Overlay) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(o)
+}
+
+// Validate checks the overlay for internal conflicts
+func (o *Overlay) Validate() error {
+	excluded := o.excluded()
+	for _, dep := range o.AddDependencies {
+		if dep.Ref == "" {
+			return fmt.Errorf("overlay addDependencies entry is missing a ref")
+		}
+		if excluded[dep.Ref] {
+			return fmt.Errorf("overlay conflict: %s is excluded but addDependencies declares dependencies for it", dep.Ref)
+		}
+		for _, target := range dep.DependsOn {
+			if excluded[target] {
+				return fmt.Errorf("overlay conflict: %s is excluded but addDependencies makes %s depend on it", target, dep.Ref)
+			}
+		}
+	}
+	for _, dep := range o.RemoveDependencies {
+		if dep.Ref == "" {
+			return fmt.Errorf("overlay removeDependencies entry is missing a ref")
+		}
+	}
+	return nil
+}
+
+// Apply validates the overlay and applies it to the SBOM in place: removals
+// first, then additions, then exclusions
+func (o *Overlay) Apply(bom *sbom.CycloneDX) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	for _, removal := range o.RemoveDependencies {
+		for i := range bom.Dependencies {
+			dep := &bom.Dependencies[i]
+			if dep.Ref != removal.Ref {
+				continue
+			}
+			if len(removal.DependsOn) == 0 {
+				dep.DependsOn = nil
+				continue
+			}
+			dep.DependsOn = without(dep.DependsOn, toSet(removal.DependsOn))
+		}
+	}
+
+	for _, addition := range o.AddDependencies {
+		found := false
+		for i := range bom.Dependencies {
+			dep := &bom.Dependencies[i]
+			if dep.Ref != addition.Ref {
+				continue
+			}
+			found = true
+			for _, target := range addition.DependsOn {
+				if !contains(dep.DependsOn, target) {
+					dep.DependsOn = append(dep.DependsOn, target)
+				}
+			}
+			break
+		}
+		if !found {
+			bom.Dependencies = append(bom.Dependencies, sbom.Dependency{
+				Ref:       addition.Ref,
+				DependsOn: append([]string{}, addition.DependsOn...),
+			})
+		}
+	}
+
+	excluded := o.excluded()
+	if len(excluded) > 0 {
+		bom.Components = excludeComponents(bom.Components, excluded)
+		services := bom.Services[:0]
+		for _, service := range bom.Services {
+			if !excluded[service.BOMRef] {
+				services = append(services, service)
+			}
+		}
+		bom.Services = services
+
+		dependencies := bom.Dependencies[:0]
+		for _, dep := range bom.Dependencies {
+			if excluded[dep.Ref] {
+				continue
+			}
+			dep.DependsOn = without(dep.DependsOn, excluded)
+			dependencies = append(dependencies, dep)
+		}
+		bom.Dependencies = dependencies
+	}
+
+	return nil
+}
+
+func (o *Overlay) excluded() map[string]bool {
+	return toSet(o.ExcludeRefs)
+}
+
+// excludeComponents recursively drops excluded components and their subtrees
+func excludeComponents(components []sbom.Component, excluded map[string]bool) []sbom.Component {
+	result := components[:0]
+	for _, component := range components {
+		if component.BOMRef != "" && excluded[component.BOMRef] {
+			continue
+		}
+		component.Components = excludeComponents(component.Components, excluded)
+		result = append(result, component)
+	}
+	return result
+}
+
+func toSet(refs []string) map[string]bool {
+	set := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		set[ref] = true
+	}
+	return set
+}
+
+func without(refs []string, remove map[string]bool) []string {
+	result := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if !remove[ref] {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+func contains(refs []string, ref string) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
+}
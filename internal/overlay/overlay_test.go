@@ -0,0 +1,177 @@
+package overlay
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func testBOM() *sbom.CycloneDX {
+	return &sbom.CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.6",
+		Components: []sbom.Component{
+			{BOMRef: "frontend", Name: "Frontend"},
+			{BOMRef: "bundle", Name: "Bundle", Components: []sbom.Component{
+				{BOMRef: "plugin", Name: "Plugin"},
+			}},
+			{BOMRef: "cdn-job", Name: "CDN Config Job"},
+		},
+		Services: []sbom.Service{
+			{BOMRef: "api", Name: "API"},
+			{BOMRef: "legacy-queue", Name: "Legacy Queue"},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "frontend", DependsOn: []string{"api"}},
+			{Ref: "api", DependsOn: []string{"legacy-queue", "plugin"}},
+			{Ref: "legacy-queue"},
+		},
+	}
+}
+
+func dependsOn(bom *sbom.CycloneDX, ref string) []string {
+	for _, dep := range bom.Dependencies {
+		if dep.Ref == ref {
+			return dep.DependsOn
+		}
+	}
+	return nil
+}
+
+func TestApply(t *testing.T) {
+	bom := testBOM()
+	o := &Overlay{
+		AddDependencies: []sbom.Dependency{
+			{Ref: "frontend", DependsOn: []string{"cdn-job", "api"}},
+			{Ref: "cdn-job", DependsOn: []string{"api"}},
+		},
+		RemoveDependencies: []sbom.Dependency{
+			{Ref: "api", DependsOn: []string{"plugin"}},
+		},
+		ExcludeRefs: []string{"legacy-queue"},
+	}
+
+	if err := o.Apply(bom); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if got := dependsOn(bom, "frontend"); !reflect.DeepEqual(got, []string{"api", "cdn-job"}) {
+		t.Errorf("Expected frontend to gain cdn-job without duplicating api, got %v", got)
+	}
+	if got := dependsOn(bom, "cdn-job"); !reflect.DeepEqual(got, []string{"api"}) {
+		t.Errorf("Expected new dependency entry for cdn-job, got %v", got)
+	}
+	if got := dependsOn(bom, "api"); len(got) != 0 {
+		t.Errorf("Expected api to lose plugin and excluded legacy-queue, got %v", got)
+	}
+	if len(bom.Services) != 1 || bom.Services[0].BOMRef != "api" {
+		t.Errorf("Expected legacy-queue service to be excluded, got %v", bom.Services)
+	}
+	for _, dep := range bom.Dependencies {
+		if dep.Ref == "legacy-queue" {
+			t.Error("Expected dependency entry for excluded ref to be dropped")
+		}
+	}
+}
+
+func TestApplyRemoveAllAndExcludeNested(t *testing.T) {
+	bom := testBOM()
+	o := &Overlay{
+		RemoveDependencies: []sbom.Dependency{{Ref: "api"}},
+		ExcludeRefs:        []string{"bundle"},
+	}
+
+	if err := o.Apply(bom); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := dependsOn(bom, "api"); len(got) != 0 {
+		t.Errorf("Expected all api dependencies removed, got %v", got)
+	}
+	if len(bom.Components) != 2 {
+		t.Errorf("Expected bundle (and its nested plugin) excluded, got %d components", len(bom.Components))
+	}
+}
+
+func TestValidateConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		overlay Overlay
+		wantErr string
+	}{
+		{
+			name: "excluded dependency target",
+			overlay: Overlay{
+				AddDependencies: []sbom.Dependency{{Ref: "frontend", DependsOn: []string{"cdn-job"}}},
+				ExcludeRefs:     []string{"cdn-job"},
+			},
+			wantErr: "cdn-job is excluded but addDependencies makes frontend depend on it",
+		},
+		{
+			name: "excluded dependency source",
+			overlay: Overlay{
+				AddDependencies: []sbom.Dependency{{Ref: "frontend", DependsOn: []string{"api"}}},
+				ExcludeRefs:     []string{"frontend"},
+			},
+			wantErr: "frontend is excluded",
+		},
+		{
+			name:    "missing ref",
+			overlay: Overlay{AddDependencies: []sbom.Dependency{{DependsOn: []string{"api"}}}},
+			wantErr: "missing a ref",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bom := testBOM()
+			err := tt.overlay.Apply(bom)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(bom, testBOM()) {
+				t.Error("A conflicting overlay must not modify the SBOM")
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	original := &Overlay{
+		AddDependencies:    []sbom.Dependency{{Ref: "frontend", DependsOn: []string{"cdn-job"}}},
+		RemoveDependencies: []sbom.Dependency{{Ref: "api", DependsOn: []string{"legacy-queue"}}},
+		ExcludeRefs:        []string{"load-test"},
+	}
+
+	var buf bytes.Buffer
+	if err := original.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(original, loaded) {
+		t.Errorf("Round trip mismatch:\n%+v\n%+v", original, loaded)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	o, err := LoadFile(filepath.Join("..", "..", "testdata", "overlays", "services-overlay.json"))
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if len(o.AddDependencies) != 1 || len(o.RemoveDependencies) != 1 || len(o.ExcludeRefs) != 1 {
+		t.Errorf("Unexpected overlay contents: %+v", o)
+	}
+
+	if _, err := Load(strings.NewReader(`{"addDependency": []}`)); err == nil {
+		t.Error("Expected misspelled field to be rejected")
+	}
+	if _, err := LoadFile("/non/existent/overlay.json"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
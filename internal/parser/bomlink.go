@@ -0,0 +1,372 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// BOMLinkPrefix starts every BOM-Link
+const BOMLinkPrefix = "urn:cdx:"
+
+// serialPrefix starts the serialNumber of a CycloneDX document
+const serialPrefix = "urn:uuid:"
+
+// BOMLink is a reference into another CycloneDX document (CycloneDX 1.5+),
+// written urn:cdx:<serial number>/<version>#<bom-ref>. Without the #<bom-ref>
+// fragment it names the document itself.
+type BOMLink struct {
+	SerialNumber string // The document's serialNumber without urn:uuid:
+	Version      int
+	Ref          string // Empty for the document itself
+}
+
+// ParseBOMLink parses a BOM-Link. The bom-ref fragment is percent-decoded.
+func ParseBOMLink(s string) (BOMLink, error) {
+	rest, ok := strings.CutPrefix(s, BOMLinkPrefix)
+	if !ok {
+		return BOMLink{}, fmt.Errorf("invalid BOM-Link %q: does not start with %s", s, BOMLinkPrefix)
+	}
+	document, fragment, hasRef := strings.Cut(rest, "#")
+	slash := strings.LastIndex(document, "/")
+	if slash <= 0 {
+		return BOMLink{}, fmt.Errorf("invalid BOM-Link %q: expected %s<serial number>/<version>", s, BOMLinkPrefix)
+	}
+	version, err := strconv.Atoi(document[slash+1:])
+	if err != nil || version < 1 {
+		return BOMLink{}, fmt.Errorf("invalid BOM-Link %q: version must be a positive integer", s)
+	}
+	link := BOMLink{SerialNumber: strings.ToLower(document[:slash]), Version: version}
+	if hasRef {
+		if link.Ref, err = url.PathUnescape(fragment); err != nil || link.Ref == "" {
+			return BOMLink{}, fmt.Errorf("invalid BOM-Link %q: invalid bom-ref", s)
+		}
+	}
+	return link, nil
+}
+
+func (l BOMLink) String() string {
+	s := fmt.Sprintf("%s%s/%d", BOMLinkPrefix, l.SerialNumber, l.Version)
+	if l.Ref != "" {
+		s += "#" + url.PathEscape(l.Ref)
+	}
+	return s
+}
+
+// Registry holds the documents BOM-Links can point into, by serial number
+// and version
+type Registry struct {
+	docs map[string]map[int]*sbom.CycloneDX
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{docs: make(map[string]map[int]*sbom.CycloneDX)}
+}
+
+// Add registers a document under its serialNumber and version
+func (r *Registry) Add(bom *sbom.CycloneDX) error {
+	serial, ok := strings.CutPrefix(strings.ToLower(bom.SerialNumber), serialPrefix)
+	if !ok || serial == "" {
+		return fmt.Errorf("no %s serialNumber", serialPrefix)
+	}
+	if r.docs[serial] == nil {
+		r.docs[serial] = make(map[int]*sbom.CycloneDX)
+	}
+	if _, exists := r.docs[serial][bom.Version]; exists {
+		return fmt.Errorf("%s%s version %d is already registered", serialPrefix, serial, bom.Version)
+	}
+	r.docs[serial][bom.Version] = bom
+	return nil
+}
+
+// Len returns the number of registered documents
+func (r *Registry) Len() int {
+	n := 0
+	for _, versions := range r.docs {
+		n += len(versions)
+	}
+	return n
+}
+
+// LoadRegistry parses every *.json file of dir into a registry. Documents
+// without a serialNumber cannot be linked to and are skipped.
+func (p *Parser) LoadRegistry(dir string) (*Registry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(paths)
+	r := NewRegistry()
+	for _, path := range paths {
+		bom, err := p.ParseFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if bom.SerialNumber == "" {
+			p.Logger.Infof("skipping %s: no serialNumber to link to", path)
+			continue
+		}
+		if err := r.Add(bom); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return r, nil
+}
+
+// ResolveBOMLinks imports the targets of BOM-Link dependsOn entries from r
+// and returns the links it could not resolve, each reported as a warning
+func ResolveBOMLinks(bom *sbom.CycloneDX, r *Registry) (unresolved map[string]bool, warnings []Warning) {
+	res := &linkResolver{bom: bom, registry: r, refs: declaredRefs(bom), imported: make(map[string]string)}
+	unresolved = make(map[string]bool)
+	for i := range bom.Dependencies {
+		dep := &bom.Dependencies[i]
+		for j, target := range dep.DependsOn {
+			if !strings.HasPrefix(target, BOMLinkPrefix) {
+				continue
+			}
+			ref, err := res.resolve(target)
+			if err != nil {
+				warnings = append(warnings, warning.New(WarnUnresolvedBOMLink, "cannot resolve %s (needed by %s): %v", target, dep.Ref, err))
+				unresolved[target] = true
+				continue
+			}
+			dep.DependsOn[j] = ref
+		}
+	}
+	return unresolved, warnings
+}
+
+// linkResolver imports linked components into bom
+type linkResolver struct {
+	bom      *sbom.CycloneDX
+	registry *Registry
+	refs     map[string]bool   // Refs declared in bom, including imported ones
+	imported map[string]string // BOM-Link of every imported ref to its ref in bom
+}
+
+func (res *linkResolver) resolve(target string) (string, error) {
+	link, err := ParseBOMLink(target)
+	if err != nil {
+		return "", err
+	}
+	doc := res.registry.docs[link.SerialNumber][link.Version]
+	if doc == nil {
+		return "", fmt.Errorf("no linked document with serial number %s%s and version %d", serialPrefix, link.SerialNumber, link.Version)
+	}
+	if link.Ref == "" {
+		if doc.Metadata == nil || doc.Metadata.Component == nil || doc.Metadata.Component.BOMRef == "" {
+			return "", fmt.Errorf("document %s%s has no metadata component to link to", serialPrefix, link.SerialNumber)
+		}
+		link.Ref = doc.Metadata.Component.BOMRef
+	}
+	if ref, ok := res.imported[link.String()]; ok {
+		return ref, nil
+	}
+
+	index := indexDocument(doc)
+	if !index.declares(link.Ref) {
+		return "", fmt.Errorf("document %s%s version %d declares no %s", serialPrefix, link.SerialNumber, link.Version, link.Ref)
+	}
+
+	// Everything the linked ref depends on in its document comes along
+	closure := []string{link.Ref}
+	reached := map[string]bool{link.Ref: true}
+	for i := 0; i < len(closure); i++ {
+		for _, dependency := range index.dependsOn[closure[i]] {
+			if !reached[dependency] && index.declares(dependency) {
+				reached[dependency] = true
+				closure = append(closure, dependency)
+			}
+		}
+	}
+
+	// Name the imported refs first so that dependencies between them can be
+	// rewritten
+	var components []sbom.Component
+	var services []sbom.Service
+	var names []string
+	for _, ref := range closure {
+		key := BOMLink{SerialNumber: link.SerialNumber, Version: link.Version, Ref: ref}.String()
+		if _, done := res.imported[key]; done || index.nestedIn(ref, reached) {
+			continue
+		}
+		if component, ok := index.components[ref]; ok {
+			copied := copyComponent(*component)
+			for _, nested := range componentRefs(&copied) {
+				names = append(names, nested)
+				res.name(link, nested)
+			}
+			components = append(components, copied)
+		} else if service, ok := index.services[ref]; ok {
+			names = append(names, ref)
+			res.name(link, ref)
+			services = append(services, *service)
+		}
+	}
+	rename := func(ref string) string {
+		if local, ok := res.imported[BOMLink{SerialNumber: link.SerialNumber, Version: link.Version, Ref: ref}.String()]; ok {
+			return local
+		}
+		return ref
+	}
+	for i := range components {
+		renameComponent(&components[i], rename)
+	}
+	for i := range services {
+		services[i].BOMRef = rename(services[i].BOMRef)
+	}
+	res.bom.Components = append(res.bom.Components, components...)
+	res.bom.Services = append(res.bom.Services, services...)
+
+	importedNames := make(map[string]bool, len(names))
+	for _, name := range names {
+		importedNames[name] = true
+	}
+	for _, dep := range doc.Dependencies {
+		if !importedNames[dep.Ref] {
+			continue
+		}
+		imported := sbom.Dependency{Ref: rename(dep.Ref)}
+		for _, dependency := range dep.DependsOn {
+			if reached[dependency] || importedNames[dependency] {
+				imported.DependsOn = append(imported.DependsOn, rename(dependency))
+			}
+		}
+		res.bom.Dependencies = append(res.bom.Dependencies, imported)
+	}
+	return res.imported[link.String()], nil
+}
+
+// name picks the ref an imported ref gets in bom: its own, unless bom
+// already declares it, in which case the full BOM-Link
+func (res *linkResolver) name(link BOMLink, ref string) {
+	key := BOMLink{SerialNumber: link.SerialNumber, Version: link.Version, Ref: ref}.String()
+	local := ref
+	if res.refs[ref] {
+		local = key
+	}
+	res.refs[local] = true
+	res.imported[key] = local
+}
+
+// documentIndex finds the components, services and dependencies of a
+// document by ref
+type documentIndex struct {
+	components map[string]*sbom.Component
+	services   map[string]*sbom.Service
+	dependsOn  map[string][]string
+	parents    map[string]string // Ref of every nested component to its parent's
+}
+
+func (i documentIndex) declares(ref string) bool {
+	_, component := i.components[ref]
+	_, service := i.services[ref]
+	return component || service
+}
+
+// nestedIn reports whether ref is nested in one of refs, and so comes along
+// with it
+func (i documentIndex) nestedIn(ref string, refs map[string]bool) bool {
+	for parent, ok := i.parents[ref]; ok; parent, ok = i.parents[parent] {
+		if refs[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+func indexDocument(doc *sbom.CycloneDX) documentIndex {
+	index := documentIndex{
+		components: make(map[string]*sbom.Component),
+		services:   make(map[string]*sbom.Service),
+		dependsOn:  make(map[string][]string),
+		parents:    make(map[string]string),
+	}
+	for i := range doc.Components {
+		addComponentToMap(&doc.Components[i], index.components)
+		addParents(&doc.Components[i], index.parents)
+	}
+	if doc.Metadata != nil && doc.Metadata.Component != nil {
+		addComponentToMap(doc.Metadata.Component, index.components)
+		addParents(doc.Metadata.Component, index.parents)
+	}
+	for i := range doc.Services {
+		if doc.Services[i].BOMRef != "" {
+			index.services[doc.Services[i].BOMRef] = &doc.Services[i]
+		}
+	}
+	for _, dep := range doc.Dependencies {
+		index.dependsOn[dep.Ref] = append(index.dependsOn[dep.Ref], dep.DependsOn...)
+	}
+	return index
+}
+
+func addParents(c *sbom.Component, parents map[string]string) {
+	for i := range c.Components {
+		if c.BOMRef != "" && c.Components[i].BOMRef != "" {
+			parents[c.Components[i].BOMRef] = c.BOMRef
+		}
+		addParents(&c.Components[i], parents)
+	}
+}
+
+// declaredRefs returns every bom-ref of the components, nested components,
+// metadata component and services of bom
+func declaredRefs(bom *sbom.CycloneDX) map[string]bool {
+	index := indexDocument(bom)
+	refs := make(map[string]bool, len(index.components)+len(index.services))
+	for ref := range index.components {
+		refs[ref] = true
+	}
+	for ref := range index.services {
+		refs[ref] = true
+	}
+	return refs
+}
+
+// componentRefs returns the refs of c and of the components nested in it
+func componentRefs(c *sbom.Component) []string {
+	var refs []string
+	if c.BOMRef != "" {
+		refs = append(refs, c.BOMRef)
+	}
+	for i := range c.Components {
+		refs = append(refs, componentRefs(&c.Components[i])...)
+	}
+	return refs
+}
+
+// copyComponent copies c and the components nested in it, so that renaming
+// the copy leaves the linked document alone
+func copyComponent(c sbom.Component) sbom.Component {
+	nested := make([]sbom.Component, len(c.Components))
+	for i := range c.Components {
+		nested[i] = copyComponent(c.Components[i])
+	}
+	if c.Components != nil {
+		c.Components = nested
+	}
+	return c
+}
+
+func renameComponent(c *sbom.Component, rename func(string) string) {
+	if c.BOMRef != "" {
+		c.BOMRef = rename(c.BOMRef)
+	}
+	for i := range c.Components {
+		renameComponent(&c.Components[i], rename)
+	}
+}
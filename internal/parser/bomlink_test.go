@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+const platformLink = "urn:cdx:6f1c2d4e-5a3b-4c7d-9e8f-0a1b2c3d4e5f/2"
+
+func TestParseBOMLink(t *testing.T) {
+	tests := []struct {
+		link    string
+		want    BOMLink
+		wantErr bool
+	}{
+		{"urn:cdx:6F1C2D4E-5A3B-4C7D-9E8F-0A1B2C3D4E5F/2", BOMLink{SerialNumber: "6f1c2d4e-5a3b-4c7d-9e8f-0a1b2c3d4e5f", Version: 2}, false},
+		{platformLink + "#kafka", BOMLink{SerialNumber: "6f1c2d4e-5a3b-4c7d-9e8f-0a1b2c3d4e5f", Version: 2, Ref: "kafka"}, false},
+		{platformLink + "#pkg%3Anpm%2Fleft-pad%401.3.0", BOMLink{SerialNumber: "6f1c2d4e-5a3b-4c7d-9e8f-0a1b2c3d4e5f", Version: 2, Ref: "pkg:npm/left-pad@1.3.0"}, false},
+		{"urn:uuid:6f1c2d4e-5a3b-4c7d-9e8f-0a1b2c3d4e5f", BOMLink{}, true},
+		{"urn:cdx:6f1c2d4e-5a3b-4c7d-9e8f-0a1b2c3d4e5f", BOMLink{}, true},
+		{"urn:cdx:6f1c2d4e-5a3b-4c7d-9e8f-0a1b2c3d4e5f/0#kafka", BOMLink{}, true},
+		{"urn:cdx:/1#kafka", BOMLink{}, true},
+		{platformLink + "#", BOMLink{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			got, err := ParseBOMLink(tt.link)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBOMLink failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+			if round, _ := ParseBOMLink(got.String()); round != got {
+				t.Errorf("Expected %s to round-trip, got %+v", got, round)
+			}
+		})
+	}
+}
+
+func TestResolveBOMLinks(t *testing.T) {
+	p := New()
+	registry, err := p.LoadRegistry(filepath.Join("..", "..", "testdata", "bomlink"))
+	if err != nil {
+		t.Fatalf("LoadRegistry failed: %v", err)
+	}
+	if registry.Len() != 1 {
+		t.Fatalf("Expected 1 registered document, got %d", registry.Len())
+	}
+	bom, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", "bomlink-app-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	unresolved, warnings := ResolveBOMLinks(bom, registry)
+	if len(warnings) != 1 || warnings[0].Code != WarnUnresolvedBOMLink ||
+		!strings.Contains(warnings[0].Message, "urn:cdx:0e2f4a6c-8b1d-4f3e-9a5c-7d9e1b3f5a7c/1#redis") {
+		t.Fatalf("Expected one unresolved link warning naming the URN, got %v", warnings)
+	}
+	if want := map[string]bool{"urn:cdx:0e2f4a6c-8b1d-4f3e-9a5c-7d9e1b3f5a7c/1#redis": true}; !reflect.DeepEqual(unresolved, want) {
+		t.Errorf("Expected the link to be returned as unresolved, got %v", unresolved)
+	}
+
+	// The linked components, what they depend on and what is nested in them
+	// come along; grafana, which nothing links to, stays behind. The
+	// platform's api collides with the shop's and keeps its URN.
+	var refs []string
+	for ref := range p.GetComponentMap(bom) {
+		refs = append(refs, ref)
+	}
+	for _, want := range []string{"postgres", "postgres-exporter", "kafka", "zookeeper", platformLink + "#api"} {
+		if !containsRef(refs, want) {
+			t.Errorf("Expected %s to be imported, got %v", want, refs)
+		}
+	}
+	if containsRef(refs, "grafana") || containsRef(refs, "platform") {
+		t.Errorf("Expected only linked components to be imported, got %v", refs)
+	}
+
+	dependsOn := make(map[string][]string)
+	for _, dep := range bom.Dependencies {
+		dependsOn[dep.Ref] = dep.DependsOn
+	}
+	want := map[string][]string{
+		"api":                 {"postgres", "kafka"},
+		"web":                 {"api", platformLink + "#api"},
+		"worker":              {"kafka", "urn:cdx:0e2f4a6c-8b1d-4f3e-9a5c-7d9e1b3f5a7c/1#redis"},
+		"kafka":               {"zookeeper"},
+		platformLink + "#api": {"postgres"},
+	}
+	for ref, targets := range want {
+		if !reflect.DeepEqual(dependsOn[ref], targets) {
+			t.Errorf("Expected %s to depend on %v, got %v", ref, targets, dependsOn[ref])
+		}
+	}
+}
+
+func TestResolveBOMLinksToDocument(t *testing.T) {
+	registry := NewRegistry()
+	linked := &sbom.CycloneDX{
+		SerialNumber: "urn:uuid:6F1C2D4E-5A3B-4C7D-9E8F-0A1B2C3D4E5F",
+		Version:      2,
+		Metadata:     &sbom.Metadata{Component: &sbom.Component{BOMRef: "platform", Name: "Platform"}},
+	}
+	if err := registry.Add(linked); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := registry.Add(linked); err == nil {
+		t.Error("Expected adding a document twice to fail")
+	}
+	if err := registry.Add(&sbom.CycloneDX{Version: 1}); err == nil {
+		t.Error("Expected a document without a serialNumber to be rejected")
+	}
+
+	bom := &sbom.CycloneDX{
+		Components:   []sbom.Component{{BOMRef: "app", Name: "App"}},
+		Dependencies: []sbom.Dependency{{Ref: "app", DependsOn: []string{platformLink, platformLink + "#missing", "urn:cdx:6f1c2d4e-5a3b-4c7d-9e8f-0a1b2c3d4e5f/3"}}},
+	}
+	unresolved, warnings := ResolveBOMLinks(bom, registry)
+	if len(warnings) != 2 || len(unresolved) != 2 {
+		t.Fatalf("Expected 2 warnings and unresolved links, got %v and %v", warnings, unresolved)
+	}
+	for i, want := range []string{"declares no missing", "no linked document with serial number"} {
+		if !strings.Contains(warnings[i].Message, want) {
+			t.Errorf("Expected warning %d to mention %q, got %q", i, want, warnings[i].Message)
+		}
+	}
+	if got := bom.Dependencies[0].DependsOn[0]; got != "platform" {
+		t.Errorf("Expected the document link to resolve to its metadata component, got %s", got)
+	}
+	if len(bom.Components) != 2 || bom.Components[1].BOMRef != "platform" {
+		t.Errorf("Expected the metadata component to be imported, got %+v", bom.Components)
+	}
+}
+
+func containsRef(refs []string, ref string) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
+}
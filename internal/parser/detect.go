@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Format identifies an SBOM encoding
+type Format string
+
+// Supported values for Parser.Format and the --format flag
+const (
+	FormatAuto              Format = "auto"
+	FormatCycloneDXJSON     Format = "cyclonedx-json"
+	FormatCycloneDXXML      Format = "cyclonedx-xml"
+	FormatCycloneDXProtobuf Format = "cyclonedx-protobuf"
+	FormatSPDXJSON          Format = "spdx-json"
+)
+
+// formats lists every concrete format in the order detectors run
+var formats = []Format{FormatCycloneDXJSON, FormatCycloneDXXML, FormatSPDXJSON, FormatCycloneDXProtobuf}
+
+// ErrUnknownFormat is returned by DetectFormat when no detector matches
+var ErrUnknownFormat = errors.New("unrecognized SBOM format")
+
+// sniffSize is how many leading bytes are inspected to detect the format
+const sniffSize = 8 << 10
+
+// ParseFormat validates a format name such as "cyclonedx-json" or "auto"
+func ParseFormat(s string) (Format, error) {
+	format := Format(strings.ToLower(s))
+	if format == "" || format == FormatAuto {
+		return FormatAuto, nil
+	}
+	for _, f := range formats {
+		if format == f {
+			return f, nil
+		}
+	}
+
+	names := []string{string(FormatAuto)}
+	for _, f := range formats {
+		names = append(names, string(f))
+	}
+	return "", fmt.Errorf("unknown format %q (expected one of %s)", s, strings.Join(names, ", "))
+}
+
+var (
+	utf8BOM         = []byte("\xef\xbb\xbf")
+	cycloneDXXMLNS  = []byte("http://cyclonedx.org/schema/bom")
+	protoSpecPrefix = regexp.MustCompile(`^1\.[0-9]+$`)
+)
+
+// DetectFormat identifies the encoding of an SBOM, JSON, XML or protobuf,
+// from its leading bytes
+func DetectFormat(prefix []byte) (Format, error) {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(prefix, utf8BOM), " \t\r\n")
+
+	switch {
+	case len(trimmed) == 0:
+	case trimmed[0] == '{':
+		if bytes.Contains(trimmed, []byte(`"bomFormat"`)) {
+			return FormatCycloneDXJSON, nil
+		}
+		if bytes.Contains(trimmed, []byte(`"spdxVersion"`)) {
+			return FormatSPDXJSON, nil
+		}
+	case trimmed[0] == '<':
+		if bytes.Contains(trimmed, cycloneDXXMLNS) {
+			return FormatCycloneDXXML, nil
+		}
+	case isProtobufBOM(prefix):
+		return FormatCycloneDXProtobuf, nil
+	}
+
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("%w (tried %s)", ErrUnknownFormat, strings.Join(names, ", "))
+}
+
+// isProtobufBOM reports whether b starts with field 1 (spec_version) of a
+// CycloneDX Bom message: tag 0x0a, a one-byte length and a value like "1.6"
+func isProtobufBOM(b []byte) bool {
+	if len(b) < 2 || b[0] != 0x0a {
+		return false
+	}
+	n := int(b[1])
+	if n < 3 || n > 8 || len(b) < 2+n {
+		return false
+	}
+	return protoSpecPrefix.Match(b[2 : 2+n])
+}
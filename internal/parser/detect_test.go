@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   Format
+	}{
+		{"cyclonedx json", `{"bomFormat": "CycloneDX", "specVersion": "1.6"}`, FormatCycloneDXJSON},
+		{"cyclonedx json with leading whitespace", "\n\t  {\n  \"$schema\": \"x\",\n  \"bomFormat\": \"CycloneDX\"", FormatCycloneDXJSON},
+		{"cyclonedx json with utf-8 bom", "\xef\xbb\xbf{\"bomFormat\": \"CycloneDX\"}", FormatCycloneDXJSON},
+		{"spdx json", `{"spdxVersion": "SPDX-2.3", "dataLicense": "CC0-1.0"}`, FormatSPDXJSON},
+		{"cyclonedx xml with prolog", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<bom xmlns="http://cyclonedx.org/schema/bom/1.6" version="1">`, FormatCycloneDXXML},
+		{"cyclonedx xml without prolog", `<bom xmlns="http://cyclonedx.org/schema/bom/1.5">`, FormatCycloneDXXML},
+		{"cyclonedx protobuf", "\x0a\x031.6\x10\x01", FormatCycloneDXProtobuf},
+		{"cyclonedx protobuf two-digit minor", "\x0a\x041.10\x10\x01", FormatCycloneDXProtobuf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat([]byte(tt.prefix))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectFormatUnknown(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{"empty", ""},
+		{"whitespace only", "  \n"},
+		{"json without known keys", `{"name": "not an sbom"}`},
+		{"xml in another namespace", `<?xml version="1.0"?><project xmlns="http://maven.apache.org/POM/4.0.0">`},
+		{"spdx tag-value", "SPDXVersion: SPDX-2.3\nDataLicense: CC0-1.0"},
+		{"protobuf tag with non-version value", "\x0a\x03abc"},
+		{"truncated protobuf", "\x0a\x05"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat([]byte(tt.prefix))
+			if !errors.Is(err, ErrUnknownFormat) {
+				t.Fatalf("Expected ErrUnknownFormat, got %s, %v", got, err)
+			}
+			for _, detector := range []string{"cyclonedx-json", "cyclonedx-xml", "spdx-json", "cyclonedx-protobuf"} {
+				if !strings.Contains(err.Error(), detector) {
+					t.Errorf("Expected error to name detector %s, got: %v", detector, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, in := range []string{"", "auto", "AUTO"} {
+		if got, err := ParseFormat(in); err != nil || got != FormatAuto {
+			t.Errorf("ParseFormat(%q) = %s, %v; want auto", in, got, err)
+		}
+	}
+	if got, err := ParseFormat("spdx-json"); err != nil || got != FormatSPDXJSON {
+		t.Errorf("ParseFormat(spdx-json) = %s, %v", got, err)
+	}
+	if _, err := ParseFormat("yaml"); err == nil || !strings.Contains(err.Error(), "cyclonedx-json") {
+		t.Errorf("Expected error listing valid formats, got %v", err)
+	}
+}
+
+func TestParseWithFormat(t *testing.T) {
+	t.Run("spdx is detected but not decoded", func(t *testing.T) {
+		_, err := New().Parse(strings.NewReader(`{"spdxVersion": "SPDX-2.3"}`))
+		if err == nil || !strings.Contains(err.Error(), "spdx-json input is not supported yet") {
+			t.Errorf("Expected unsupported format error, got %v", err)
+		}
+	})
+
+	t.Run("override skips detection", func(t *testing.T) {
+		p := New()
+		p.Format = FormatCycloneDXJSON
+		bom, err := p.Parse(strings.NewReader(`{"spdxVersion": "x", "bomFormat": "CycloneDX"}`))
+		if err != nil || bom.BOMFormat != "CycloneDX" {
+			t.Errorf("Expected forced CycloneDX JSON decode, got %v", err)
+		}
+	})
+
+	t.Run("undetected input reports detectors", func(t *testing.T) {
+		_, err := New().Parse(strings.NewReader("not an sbom"))
+		if err == nil || !strings.Contains(err.Error(), "failed to decode JSON") || !strings.Contains(err.Error(), "tried cyclonedx-json") {
+			t.Errorf("Expected decode error naming the detectors, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"runtime"
+	"strings"
+)
+
+// FilePath returns the local path of a file:// URL, or name unchanged; on
+// Windows a host names a UNC share
+func FilePath(name string) (string, error) {
+	return filePath(name, runtime.GOOS)
+}
+
+// filePath implements FilePath for the given GOOS, so the Windows rules can
+// be tested anywhere
+func filePath(name, goos string) (string, error) {
+	if len(name) < len("file:") || !strings.EqualFold(name[:len("file:")], "file:") {
+		return name, nil
+	}
+	// Some tools write Windows paths with backslashes even in URLs
+	u, err := url.Parse(strings.ReplaceAll(name, `\`, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL %q: %w", name, err)
+	}
+	path := u.Path
+	if u.Opaque != "" {
+		// file:C:/sboms/app.json and other URLs without //
+		path = u.Opaque
+	}
+	if path == "" {
+		return "", fmt.Errorf("invalid file URL %q: no path", name)
+	}
+
+	host := u.Host
+	if strings.EqualFold(host, "localhost") {
+		host = ""
+	}
+	if goos != "windows" {
+		if host != "" {
+			return "", fmt.Errorf("invalid file URL %q: host %s is not local", name, u.Host)
+		}
+		return path, nil
+	}
+
+	if host != "" {
+		return `\\` + host + strings.ReplaceAll(path, "/", `\`), nil
+	}
+	// /C:/sboms/app.json names the drive path C:\sboms\app.json
+	if len(path) >= 3 && path[0] == '/' && isDriveLetter(path[1]) && path[2] == ':' {
+		path = path[1:]
+	}
+	return strings.ReplaceAll(path, "/", `\`), nil
+}
+
+func isDriveLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
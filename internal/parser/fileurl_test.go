@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		goos    string
+		want    string
+		wantErr bool
+	}{
+		{"sbom.json", "linux", "sbom.json", false},
+		{`C:\sboms\app.json`, "windows", `C:\sboms\app.json`, false},
+		{"file:///home/me/sbom.json", "linux", "/home/me/sbom.json", false},
+		{"file://localhost/home/me/sbom.json", "linux", "/home/me/sbom.json", false},
+		{"FILE:///home/me/my%20sbom.json", "linux", "/home/me/my sbom.json", false},
+		{"file://build-host/sbom.json", "linux", "", true},
+		{"file://", "linux", "", true},
+		{"file:///C:/sboms/app.json", "windows", `C:\sboms\app.json`, false},
+		{"file:///c:/sboms/app.json", "windows", `c:\sboms\app.json`, false},
+		{`file:///C:/sboms\nested/app.json`, "windows", `C:\sboms\nested\app.json`, false},
+		{`file:///C:\sboms\app.json`, "windows", `C:\sboms\app.json`, false},
+		{"file:C:/sboms/app.json", "windows", `C:\sboms\app.json`, false},
+		{"file://localhost/C:/sboms/app.json", "windows", `C:\sboms\app.json`, false},
+		{"file://fileserver/share/app.json", "windows", `\\fileserver\share\app.json`, false},
+		{"file:///sboms/app.json", "windows", `\sboms\app.json`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos+" "+tt.name, func(t *testing.T) {
+			got, err := filePath(tt.name, tt.goos)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filePath failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseFileURL(t *testing.T) {
+	path, err := filepath.Abs(filepath.Join("..", "..", "testdata", "sboms", "simple-1.6.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// file:///C:/... on Windows, file:///home/... elsewhere
+	url := "file://" + filepath.ToSlash(path)
+	if !strings.HasPrefix(filepath.ToSlash(path), "/") {
+		url = "file:///" + filepath.ToSlash(path)
+	}
+	bom, err := New().ParseFile(url)
+	if err != nil {
+		t.Fatalf("ParseFile(%s) failed: %v", url, err)
+	}
+	if len(bom.Components) == 0 {
+		t.Error("Expected components from the file URL")
+	}
+}
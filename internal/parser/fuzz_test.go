@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// addSeedCorpus seeds f with every SBOM fixture in testdata/sboms
+func addSeedCorpus(f *testing.F) {
+	f.Helper()
+
+	paths, err := filepath.Glob(filepath.Join("..", "..", "testdata", "sboms", "*.json"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	addSeedCorpus(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p := New()
+		p.MaxSize = 1 << 20
+		bom, err := p.Parse(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		p.GetComponentMap(bom)
+		p.GetServiceMap(bom)
+	})
+}
@@ -0,0 +1,28 @@
+package parser
+
+import "github.com/nprimmer/bom-dagger/internal/sbom"
+
+// Merge concatenates several SBOMs under the header and metadata of the
+// first; later metadata components become top-level components
+func Merge(boms ...*sbom.CycloneDX) *sbom.CycloneDX {
+	if len(boms) == 0 {
+		return nil
+	}
+
+	merged := *boms[0]
+	merged.Components = append([]sbom.Component(nil), boms[0].Components...)
+	merged.Services = append([]sbom.Service(nil), boms[0].Services...)
+	merged.Dependencies = append([]sbom.Dependency(nil), boms[0].Dependencies...)
+	merged.Compositions = append([]sbom.Composition(nil), boms[0].Compositions...)
+
+	for _, bom := range boms[1:] {
+		if bom.Metadata != nil && bom.Metadata.Component != nil && bom.Metadata.Component.BOMRef != "" {
+			merged.Components = append(merged.Components, *bom.Metadata.Component)
+		}
+		merged.Components = append(merged.Components, bom.Components...)
+		merged.Services = append(merged.Services, bom.Services...)
+		merged.Dependencies = append(merged.Dependencies, bom.Dependencies...)
+		merged.Compositions = append(merged.Compositions, bom.Compositions...)
+	}
+	return &merged
+}
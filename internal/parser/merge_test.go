@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	p := New()
+	shop, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", "dedupe-shop-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	migrator, err := p.ParseFile(filepath.Join("..", "..", "testdata", "sboms", "dedupe-migrator-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	shopComponents := len(shop.Components)
+
+	merged := Merge(shop, migrator)
+
+	if merged.SerialNumber != shop.SerialNumber || merged.Metadata.Component.BOMRef != "shop" {
+		t.Errorf("Expected the header and metadata of the first SBOM, got %s / %s", merged.SerialNumber, merged.Metadata.Component.BOMRef)
+	}
+	// 2 + 2 components plus the metadata component of the second SBOM
+	if len(merged.Components) != 5 {
+		t.Errorf("Expected 5 components, got %d", len(merged.Components))
+	}
+	if merged.Components[2].BOMRef != "migrator" {
+		t.Errorf("Expected the second metadata component after the first SBOM's components, got %s", merged.Components[2].BOMRef)
+	}
+	if len(merged.Dependencies) != 3 {
+		t.Errorf("Expected 3 dependency entries, got %d", len(merged.Dependencies))
+	}
+	if len(shop.Components) != shopComponents {
+		t.Errorf("Merge modified its first input")
+	}
+
+	if Merge() != nil {
+		t.Error("Expected nil when merging nothing")
+	}
+}
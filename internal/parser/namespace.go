@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// PropertyNamespace records the namespace of a component or service rewritten
+// by Namespace; graphs show it in node names (see dag.PropertyNamespace)
+const PropertyNamespace = "bom-dagger:namespace"
+
+// NamespaceSeparator separates the namespace from the original bom-ref
+const NamespaceSeparator = "/"
+
+// ValidateNamespace checks that a namespace can prefix refs unambiguously
+func ValidateNamespace(namespace string) error {
+	if namespace == "" {
+		return fmt.Errorf("empty namespace")
+	}
+	if strings.Contains(namespace, NamespaceSeparator) || strings.TrimSpace(namespace) != namespace {
+		return fmt.Errorf("invalid namespace %q (must not contain %q or surrounding spaces)", namespace, NamespaceSeparator)
+	}
+	return nil
+}
+
+// Namespace prefixes every ref of bom with namespace/ and tags its
+// components and services with PropertyNamespace
+func Namespace(bom *sbom.CycloneDX, namespace string) {
+	prefix := func(ref string) string {
+		// BOM-Links name a component of another document, whatever the input
+		if ref == "" || strings.HasPrefix(ref, BOMLinkPrefix) {
+			return ref
+		}
+		return namespace + NamespaceSeparator + ref
+	}
+	prefixAll := func(refs []string) {
+		for i := range refs {
+			refs[i] = prefix(refs[i])
+		}
+	}
+	property := sbom.Property{Name: PropertyNamespace, Value: namespace}
+
+	var walk func(components []sbom.Component)
+	walk = func(components []sbom.Component) {
+		for i := range components {
+			c := &components[i]
+			c.BOMRef = prefix(c.BOMRef)
+			c.Properties = append(c.Properties, property)
+			walk(c.Components)
+		}
+	}
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		components := []sbom.Component{*bom.Metadata.Component}
+		walk(components)
+		bom.Metadata.Component = &components[0]
+	}
+	walk(bom.Components)
+	for i := range bom.Services {
+		s := &bom.Services[i]
+		s.BOMRef = prefix(s.BOMRef)
+		s.Properties = append(s.Properties, property)
+	}
+	for i := range bom.Dependencies {
+		bom.Dependencies[i].Ref = prefix(bom.Dependencies[i].Ref)
+		prefixAll(bom.Dependencies[i].DependsOn)
+	}
+	for i := range bom.Compositions {
+		prefixAll(bom.Compositions[i].Assemblies)
+		prefixAll(bom.Compositions[i].Dependencies)
+	}
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestNamespace(t *testing.T) {
+	bom, err := New().ParseFile(filepath.Join("..", "..", "testdata", "sboms", "team-orders-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	bom.Services = []sbom.Service{{BOMRef: "queue", Name: "Queue"}}
+	bom.Compositions = []sbom.Composition{{Aggregate: "complete", Assemblies: []string{"app"}, Dependencies: []string{"api"}}}
+
+	Namespace(bom, "orders")
+
+	if got := bom.Metadata.Component.BOMRef; got != "orders/app" {
+		t.Errorf("Expected the metadata component to be namespaced, got %s", got)
+	}
+	var refs []string
+	for _, c := range bom.Components {
+		refs = append(refs, c.BOMRef)
+		if want := (sbom.Property{Name: PropertyNamespace, Value: "orders"}); len(c.Properties) != 1 || c.Properties[0] != want {
+			t.Errorf("Expected %s to record its namespace, got %v", c.BOMRef, c.Properties)
+		}
+	}
+	if want := []string{"orders/api", "orders/database", "orders/cache"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("Expected component refs %v, got %v", want, refs)
+	}
+	if bom.Services[0].BOMRef != "orders/queue" {
+		t.Errorf("Expected the service to be namespaced, got %s", bom.Services[0].BOMRef)
+	}
+	if want := (sbom.Dependency{Ref: "orders/api", DependsOn: []string{"orders/database", "orders/cache"}}); !reflect.DeepEqual(bom.Dependencies[1], want) {
+		t.Errorf("Expected %+v, got %+v", want, bom.Dependencies[1])
+	}
+	if c := bom.Compositions[0]; c.Assemblies[0] != "orders/app" || c.Dependencies[0] != "orders/api" {
+		t.Errorf("Expected composition refs to be namespaced, got %+v", c)
+	}
+}
+
+func TestValidateNamespace(t *testing.T) {
+	for _, namespace := range []string{"payments", "team-1.6", "Orders_EU"} {
+		if err := ValidateNamespace(namespace); err != nil {
+			t.Errorf("ValidateNamespace(%q) = %v", namespace, err)
+		}
+	}
+	for _, namespace := range []string{"", "a/b", " padded"} {
+		if err := ValidateNamespace(namespace); err == nil {
+			t.Errorf("Expected an error for %q", namespace)
+		}
+	}
+}
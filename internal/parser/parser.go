@@ -1,25 +1,52 @@
 package parser
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 
+	"github.com/nprimmer/bom-dagger/internal/logging"
 	"github.com/nprimmer/bom-dagger/internal/sbom"
 )
 
+// Default limits applied by New
+const (
+	DefaultMaxSize  = 256 << 20 // 256 MiB
+	DefaultMaxDepth = 64
+)
+
 // Parser handles parsing of CycloneDX SBOM files
-type Parser struct{}
+type Parser struct {
+	// MaxSize is the largest document, in bytes, that Parse accepts (0 = unlimited)
+	MaxSize int64
+	// MaxDepth is the deepest component nesting that Parse accepts (0 = unlimited)
+	MaxDepth int
+	// Format forces a decoder instead of detecting the encoding (default FormatAuto)
+	Format Format
+	// Strict rejects documents breaking the parts of CycloneDX bom-dagger
+	// relies on, returning every problem as StrictErrors
+	Strict bool
+	// Logger receives progress messages (nil = discard)
+	Logger *logging.Logger
+}
 
-// New creates a new Parser instance
+// New creates a new Parser instance with the default limits
 func New() *Parser {
-	return &Parser{}
+	return &Parser{
+		MaxSize:  DefaultMaxSize,
+		MaxDepth: DefaultMaxDepth,
+	}
 }
 
-// ParseFile parses a CycloneDX SBOM from a file path
+// ParseFile parses a CycloneDX SBOM from a file path or a file:// URL
 func (p *Parser) ParseFile(filePath string) (*sbom.CycloneDX, error) {
-	file, err := os.Open(filePath)
+	path, err := FilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
@@ -28,20 +55,81 @@ func (p *Parser) ParseFile(filePath string) (*sbom.CycloneDX, error) {
 	return p.Parse(file)
 }
 
-// Parse parses a CycloneDX SBOM from a reader
+// Parse parses an SBOM from a reader. With Format unset or FormatAuto the
+// encoding is detected from the leading bytes; input no detector recognizes
+// is decoded as CycloneDX JSON.
 func (p *Parser) Parse(reader io.Reader) (*sbom.CycloneDX, error) {
-	var bom sbom.CycloneDX
+	return p.parse(reader, nil)
+}
 
-	decoder := json.NewDecoder(reader)
-	if err := decoder.Decode(&bom); err != nil {
+// parse implements Parse. When raw is non-nil the document is decoded through
+// it so callers can inspect fields the sbom types do not model.
+func (p *Parser) parse(reader io.Reader, raw *json.RawMessage) (*sbom.CycloneDX, error) {
+	var limited *io.LimitedReader
+	if p.MaxSize > 0 {
+		// Read one byte past the limit so an oversized document is detectable
+		limited = &io.LimitedReader{R: reader, N: p.MaxSize + 1}
+		reader = limited
+	}
+	buffered := bufio.NewReaderSize(reader, sniffSize)
+
+	format := p.Format
+	var detectErr error
+	if format == "" || format == FormatAuto {
+		prefix, _ := buffered.Peek(sniffSize)
+		if format, detectErr = DetectFormat(prefix); detectErr != nil {
+			format = FormatCycloneDXJSON
+		}
+	}
+	if format != FormatCycloneDXJSON {
+		return nil, fmt.Errorf("%s input is not supported yet", format)
+	}
+
+	var bom sbom.CycloneDX
+	if p.Strict && raw == nil {
+		raw = new(json.RawMessage)
+	}
+	var target any = &bom
+	if raw != nil {
+		target = raw
+	}
+	lines := &lineIndex{r: buffered}
+	decoder := json.NewDecoder(lines)
+	if err := decoder.Decode(target); err != nil {
+		if limited != nil && limited.N == 0 {
+			return nil, fmt.Errorf("document exceeds maximum size of %d bytes", p.MaxSize)
+		}
+		err = lines.locate(err, 0)
+		if detectErr != nil {
+			return nil, fmt.Errorf("failed to decode JSON: %w (%v)", err, detectErr)
+		}
 		return nil, fmt.Errorf("failed to decode JSON: %w", err)
 	}
+	if p.Strict {
+		if errs := validateStrict(*raw); len(errs) > 0 {
+			return nil, errs
+		}
+	}
+	if raw != nil {
+		if err := json.Unmarshal(*raw, &bom); err != nil {
+			// The raw value starts where the decoder stopped, less its length
+			return nil, fmt.Errorf("failed to decode JSON: %w", lines.locate(err, decoder.InputOffset()-int64(len(*raw))))
+		}
+	}
 
 	// Validate the BOM format
 	if bom.BOMFormat != "CycloneDX" {
 		return nil, fmt.Errorf("invalid BOM format: %s (expected CycloneDX)", bom.BOMFormat)
 	}
 
+	if p.MaxDepth > 0 {
+		if err := checkDepth(&bom, p.MaxDepth); err != nil {
+			return nil, err
+		}
+	}
+
+	p.Logger.Infof("parsed %d components and %d services (%s %s, %s)",
+		countComponents(&bom), len(bom.Services), bom.BOMFormat, bom.SpecVersion, format)
 	return &bom, nil
 }
 
@@ -75,14 +163,74 @@ func (p *Parser) GetServiceMap(bom *sbom.CycloneDX) map[string]*sbom.Service {
 	return serviceMap
 }
 
-// addComponentToMap recursively adds components to the map
+// addComponentToMap adds a component and everything nested inside it to the
+// map. It walks with an explicit stack so deeply nested input cannot exhaust
+// the goroutine stack.
 func addComponentToMap(component *sbom.Component, componentMap map[string]*sbom.Component) {
-	if component.BOMRef != "" {
-		componentMap[component.BOMRef] = component
+	stack := []*sbom.Component{component}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current.BOMRef != "" {
+			componentMap[current.BOMRef] = current
+		}
+		// Push in reverse so children are visited in document order
+		for i := len(current.Components) - 1; i >= 0; i-- {
+			stack = append(stack, &current.Components[i])
+		}
+	}
+}
+
+// countComponents counts every component in the document, including nested
+// components and the metadata component
+func countComponents(bom *sbom.CycloneDX) int {
+	stack := make([]*sbom.Component, 0, len(bom.Components)+1)
+	for i := range bom.Components {
+		stack = append(stack, &bom.Components[i])
+	}
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		stack = append(stack, bom.Metadata.Component)
 	}
 
-	// Recursively add nested components
-	for i := range component.Components {
-		addComponentToMap(&component.Components[i], componentMap)
+	count := 0
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		count++
+		for i := range current.Components {
+			stack = append(stack, &current.Components[i])
+		}
+	}
+	return count
+}
+
+// checkDepth rejects documents whose component nesting is deeper than maxDepth.
+// Top-level components are at depth 1.
+func checkDepth(bom *sbom.CycloneDX, maxDepth int) error {
+	type frame struct {
+		components []sbom.Component
+		depth      int
 	}
+
+	stack := []frame{{components: bom.Components, depth: 1}}
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		stack = append(stack, frame{components: []sbom.Component{*bom.Metadata.Component}, depth: 1})
+	}
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for i := range current.components {
+			if current.depth > maxDepth {
+				return fmt.Errorf("component nesting exceeds maximum depth of %d", maxDepth)
+			}
+			if children := current.components[i].Components; len(children) > 0 {
+				stack = append(stack, frame{components: children, depth: current.depth + 1})
+			}
+		}
+	}
+
+	return nil
 }
@@ -2,8 +2,10 @@ package parser
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -49,7 +51,29 @@ func TestParse(t *testing.T) {
 			name:    "invalid JSON",
 			json:    `{"invalid json`,
 			wantErr: true,
-			errMsg:  "failed to decode JSON",
+			errMsg:  "failed to decode JSON: line 1, column 15: unexpected EOF",
+		},
+		{
+			name: "invalid character",
+			json: `{
+				"bomFormat": "CycloneDX",
+				"specVersion" "1.6"
+			}`,
+			wantErr: true,
+			errMsg:  "failed to decode JSON: line 3, column 19: invalid character '\"' after object key",
+		},
+		{
+			name: "mistyped field",
+			json: `{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.6",
+				"components": [
+					{"type": "library", "name": "a"},
+					{"type": "library", "name": "b", "version": 2}
+				]
+			}`,
+			wantErr: true,
+			errMsg:  "failed to decode JSON: line 6, column 50: $.components[1].version: expected string, got number",
 		},
 		{
 			name:    "empty input",
@@ -83,6 +107,72 @@ func TestParse(t *testing.T) {
 	}
 }
 
+// nestedSBOM returns an SBOM whose single top-level component is nested depth levels deep
+func nestedSBOM(depth int) string {
+	component := `{"type": "library", "bom-ref": "leaf", "name": "Leaf"}`
+	for i := depth - 1; i > 0; i-- {
+		component = fmt.Sprintf(`{"type": "library", "bom-ref": "c%d", "name": "C", "components": [%s]}`, i, component)
+	}
+	return `{"bomFormat": "CycloneDX", "specVersion": "1.6", "components": [` + component + `]}`
+}
+
+func TestParseLimits(t *testing.T) {
+	t.Run("size within limit", func(t *testing.T) {
+		doc := nestedSBOM(3)
+		p := &Parser{MaxSize: int64(len(doc))}
+		if _, err := p.Parse(strings.NewReader(doc)); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("size over limit", func(t *testing.T) {
+		doc := nestedSBOM(3)
+		p := &Parser{MaxSize: int64(len(doc)) - 10}
+		_, err := p.Parse(strings.NewReader(doc))
+		if err == nil || !strings.Contains(err.Error(), "exceeds maximum size") {
+			t.Errorf("Expected size limit error, got %v", err)
+		}
+	})
+
+	t.Run("depth at limit", func(t *testing.T) {
+		p := &Parser{MaxDepth: 5}
+		bom, err := p.Parse(strings.NewReader(nestedSBOM(5)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := len(p.GetComponentMap(bom)); got != 5 {
+			t.Errorf("Expected 5 components, got %d", got)
+		}
+	})
+
+	t.Run("depth over limit", func(t *testing.T) {
+		p := &Parser{MaxDepth: 5}
+		_, err := p.Parse(strings.NewReader(nestedSBOM(6)))
+		if err == nil || !strings.Contains(err.Error(), "maximum depth of 5") {
+			t.Errorf("Expected depth limit error, got %v", err)
+		}
+	})
+
+	t.Run("metadata component depth", func(t *testing.T) {
+		p := &Parser{MaxDepth: 1}
+		doc := `{"bomFormat": "CycloneDX", "metadata": {"component": {"bom-ref": "app", "components": [{"bom-ref": "lib"}]}}}`
+		_, err := p.Parse(strings.NewReader(doc))
+		if err == nil || !strings.Contains(err.Error(), "maximum depth") {
+			t.Errorf("Expected depth limit error, got %v", err)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		p := New()
+		if p.MaxSize != DefaultMaxSize || p.MaxDepth != DefaultMaxDepth {
+			t.Errorf("Expected default limits, got size %d depth %d", p.MaxSize, p.MaxDepth)
+		}
+		if _, err := p.Parse(strings.NewReader(nestedSBOM(DefaultMaxDepth + 1))); err == nil {
+			t.Error("Expected default depth limit to reject deep nesting")
+		}
+	})
+}
+
 func TestParseFile(t *testing.T) {
 	// Test with valid file
 	t.Run("valid file", func(t *testing.T) {
@@ -115,6 +205,27 @@ func TestParseFile(t *testing.T) {
 	})
 }
 
+func TestParsePublisherAndReleaseNotes(t *testing.T) {
+	bom, err := New().ParseFile(filepath.Join("..", "..", "testdata", "sboms", "release-notes-1.6.json"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	storefront := bom.Components[0]
+	if storefront.Publisher != "Acme Retail" || storefront.Author != "Storefront Team" {
+		t.Errorf("Expected publisher and author, got %q and %q", storefront.Publisher, storefront.Author)
+	}
+	want := &sbom.ReleaseNotes{Type: "minor", Title: "Spring sale banners", Description: "Adds configurable banners to the landing page"}
+	if !reflect.DeepEqual(storefront.ReleaseNotes, want) {
+		t.Errorf("ReleaseNotes = %+v, want %+v", storefront.ReleaseNotes, want)
+	}
+
+	db := bom.Components[2]
+	if db.Publisher != "" || db.Author != "" || db.ReleaseNotes != nil {
+		t.Errorf("Expected no publisher, author or release notes on %s, got %+v", db.BOMRef, db)
+	}
+}
+
 func TestGetComponentMap(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -374,6 +485,34 @@ func TestParseCycloneDX16Features(t *testing.T) {
 	}
 }
 
+func TestParseToolsForms(t *testing.T) {
+	want := []string{"Anchore, Inc. syft 1.4.1", "org.cyclonedx cyclonedx-maven-plugin 2.8.0", "Acme Corp sbom-enrichment 3"}
+	for _, name := range []string{"tools-array-1.4.json", "tools-object-1.5.json"} {
+		t.Run(name, func(t *testing.T) {
+			bom, err := New().ParseFile(filepath.Join("..", "..", "testdata", "sboms", name))
+			if err != nil {
+				t.Fatalf("ParseFile failed: %v", err)
+			}
+			var got []string
+			for _, tool := range bom.Metadata.Tools {
+				got = append(got, tool.String())
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected tools %q, got %q", want, got)
+			}
+		})
+	}
+
+	bom, err := New().ParseFile(filepath.Join("..", "..", "testdata", "sboms", "tools-object-1.5.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lifecycles := bom.Metadata.Lifecycles
+	if len(lifecycles) != 2 || lifecycles[0].Phase != "build" || lifecycles[1].Name != "staging-verification" {
+		t.Errorf("Expected the build phase and a custom lifecycle, got %+v", lifecycles)
+	}
+}
+
 func TestParseAllTestFiles(t *testing.T) {
 	testDir := filepath.Join("..", "..", "testdata", "sboms")
 	files, err := os.ReadDir(testDir)
@@ -410,13 +549,40 @@ func TestParseAllTestFiles(t *testing.T) {
 			if bom.BOMFormat != "CycloneDX" {
 				t.Errorf("Invalid BOMFormat in %s: %s", file.Name(), bom.BOMFormat)
 			}
-			if bom.SpecVersion != "1.6" {
-				t.Errorf("Expected SpecVersion 1.6 in %s, got %s", file.Name(), bom.SpecVersion)
+			// Fixtures are named after their spec version, e.g. simple-1.6.json
+			name := strings.TrimSuffix(file.Name(), ".json")
+			want := name[strings.LastIndex(name, "-")+1:]
+			if bom.SpecVersion != want {
+				t.Errorf("Expected SpecVersion %s in %s, got %s", want, file.Name(), bom.SpecVersion)
 			}
 		})
 	}
 }
 
+// TestParseCRLF checks documents with Windows line endings, including the
+// position reported for an error in one
+func TestParseCRLF(t *testing.T) {
+	doc := strings.Join([]string{
+		`{`,
+		`  "bomFormat": "CycloneDX",`,
+		`  "specVersion": "1.6",`,
+		`  "components": [{"type": "library", "bom-ref": "a", "name": "a"}]`,
+		`}`,
+	}, "\r\n")
+	bom, err := New().Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(bom.Components) != 1 {
+		t.Errorf("Expected 1 component, got %d", len(bom.Components))
+	}
+
+	_, err = New().Parse(strings.NewReader(strings.Replace(doc, `"name": "a"`, `"name": 1`, 1)))
+	if err == nil || !strings.Contains(err.Error(), "line 4, column 62: $.components[0].name") {
+		t.Errorf("Expected the error on line 4, got %v", err)
+	}
+}
+
 func BenchmarkParse(b *testing.B) {
 	json := bytes.Repeat([]byte(`{
 		"bomFormat": "CycloneDX",
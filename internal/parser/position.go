@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DecodeError is a JSON decoding failure located in the input: a syntax
+// error at a line and column, or a value of the wrong type at a JSON path
+// such as $.components[41].version
+type DecodeError struct {
+	Line   int
+	Column int
+	Path   string // Set for type errors
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(e.Err, &typeErr) {
+		return fmt.Sprintf("line %d, column %d: %s: expected %s, got %s", e.Line, e.Column, e.Path, jsonKind(typeErr.Type), typeErr.Value)
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %v", e.Line, e.Column, e.Path, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// lineIndex passes reads through and remembers where each line of the input
+// starts, so a decoder offset can be turned into a line and column without
+// keeping the document
+type lineIndex struct {
+	r      io.Reader
+	read   int64
+	breaks []int64 // Offsets of the newlines
+}
+
+func (l *lineIndex) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			l.breaks = append(l.breaks, l.read+int64(i))
+		}
+	}
+	l.read += int64(n)
+	return n, err
+}
+
+// position returns the 1-based line and column of the byte at offset
+func (l *lineIndex) position(offset int64) (line, column int) {
+	i := sort.Search(len(l.breaks), func(i int) bool { return l.breaks[i] >= offset })
+	start := int64(0)
+	if i > 0 {
+		start = l.breaks[i-1] + 1
+	}
+	return i + 1, int(offset-start) + 1
+}
+
+// locate adds the position of err to it. base is the offset within the
+// input of the bytes err's offset counts from. Errors without a position,
+// such as an empty input, are returned as they are.
+func (l *lineIndex) locate(err error, base int64) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var offset int64
+	path := ""
+	switch {
+	case errors.As(err, &syntaxErr):
+		// Offset counts the bytes read up to and including the bad one
+		offset = base + syntaxErr.Offset - 1
+	case errors.As(err, &typeErr):
+		// Offset is just past the mistyped value, or past its opening
+		// delimiter for objects and arrays
+		offset = base + typeErr.Offset - 1
+		path = fieldPath(typeErr.Field)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		offset = l.read
+	default:
+		return err
+	}
+	line, column := l.position(max(offset, 0))
+	return &DecodeError{Line: line, Column: column, Path: path, Err: err}
+}
+
+// fieldPath turns the dotted field of a type error, such as
+// components.41.version, into a JSON path
+func fieldPath(field string) string {
+	var path strings.Builder
+	path.WriteString("$")
+	if field == "" {
+		return path.String()
+	}
+	for _, part := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(part); err == nil {
+			path.WriteString("[" + part + "]")
+		} else {
+			path.WriteString("." + part)
+		}
+	}
+	return path.String()
+}
+
+// jsonKind names the JSON type a Go type decodes from
+func jsonKind(t reflect.Type) string {
+	if t == nil {
+		return "value"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Pointer:
+		return jsonKind(t.Elem())
+	}
+	return t.String()
+}
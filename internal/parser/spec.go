@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// Fields and values of the CycloneDX schema by the spec version that
+// introduced them, for those introduced after 1.2
+var (
+	topLevelSince = map[string]sbom.SpecVersion{
+		"compositions":    sbom.Spec1_3,
+		"vulnerabilities": sbom.Spec1_4,
+		"annotations":     sbom.Spec1_5,
+		"formulation":     sbom.Spec1_5,
+		"properties":      sbom.Spec1_5,
+		"declarations":    sbom.Spec1_6,
+		"definitions":     sbom.Spec1_6,
+	}
+	metadataSince = map[string]sbom.SpecVersion{
+		"properties":   sbom.Spec1_3,
+		"lifecycles":   sbom.Spec1_5,
+		"manufacturer": sbom.Spec1_6,
+	}
+	componentSince = map[string]sbom.SpecVersion{
+		"properties":       sbom.Spec1_3,
+		"evidence":         sbom.Spec1_3,
+		"signature":        sbom.Spec1_4,
+		"releaseNotes":     sbom.Spec1_4,
+		"modelCard":        sbom.Spec1_5,
+		"data":             sbom.Spec1_5,
+		"authors":          sbom.Spec1_6,
+		"manufacturer":     sbom.Spec1_6,
+		"omniborId":        sbom.Spec1_6,
+		"swhid":            sbom.Spec1_6,
+		"cryptoProperties": sbom.Spec1_6,
+	}
+	componentTypeSince = map[string]sbom.SpecVersion{
+		"file":                   sbom.Spec1_2,
+		"container":              sbom.Spec1_2,
+		"firmware":               sbom.Spec1_2,
+		"platform":               sbom.Spec1_5,
+		"device-driver":          sbom.Spec1_5,
+		"machine-learning-model": sbom.Spec1_5,
+		"data":                   sbom.Spec1_5,
+		"cryptographic-asset":    sbom.Spec1_6,
+	}
+)
+
+// checkSpecFeatures reports fields and component types newer than the
+// document's declared spec version
+func checkSpecFeatures(bom *sbom.CycloneDX, fields map[string]json.RawMessage) []Warning {
+	declared := bom.Spec()
+	if declared == sbom.SpecUnknown {
+		return nil
+	}
+
+	var warnings []Warning
+	report := func(feature string, since sbom.SpecVersion, count int) {
+		if since <= declared {
+			return
+		}
+		usedBy := ""
+		if count > 1 {
+			usedBy = fmt.Sprintf(" (%d components)", count)
+		}
+		warnings = append(warnings, Warning{
+			Code:    WarnSpecVersionFeature,
+			Message: fmt.Sprintf("%s%s requires CycloneDX %s but specVersion is %s", feature, usedBy, since, declared),
+		})
+	}
+
+	for _, name := range sortedKeys(fields) {
+		report(name, topLevelSince[name], 1)
+	}
+
+	var metadata map[string]json.RawMessage
+	_ = json.Unmarshal(fields["metadata"], &metadata)
+	for _, name := range sortedKeys(metadata) {
+		report("metadata."+name, metadataSince[name], 1)
+	}
+	if tools := metadata["tools"]; len(tools) > 0 && tools[0] == '{' {
+		report("metadata.tools as an object", sbom.Spec1_5, 1)
+	}
+
+	componentFields := make(map[string]int)
+	countComponentFields(fields["components"], componentFields)
+	for _, name := range sortedKeys(componentFields) {
+		report("components[]."+name, componentSince[name], componentFields[name])
+	}
+
+	types := make(map[string]int)
+	countComponentTypes(bom.Components, types)
+	for _, typ := range sortedKeys(types) {
+		report(fmt.Sprintf("component type %q", typ), componentTypeSince[typ], types[typ])
+	}
+
+	return warnings
+}
+
+// countComponentFields counts the components, nested ones included, using
+// each field
+func countComponentFields(raw json.RawMessage, counts map[string]int) {
+	var components []map[string]json.RawMessage
+	_ = json.Unmarshal(raw, &components)
+	for _, component := range components {
+		for name := range component {
+			counts[name]++
+		}
+		countComponentFields(component["components"], counts)
+	}
+}
+
+// countComponentTypes counts the components, nested ones included, of each
+// type
+func countComponentTypes(components []sbom.Component, counts map[string]int) {
+	for i := range components {
+		counts[components[i].Type]++
+		countComponentTypes(components[i].Components, counts)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,231 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/plural"
+)
+
+// Fields CycloneDX 1.6 defines on the objects strict parsing inspects. Objects
+// below these (licenses, hashes, pedigree, ...) are not checked.
+var (
+	componentFields = fieldSet("type", "mime-type", "bom-ref", "supplier", "manufacturer", "authors", "author",
+		"publisher", "group", "name", "version", "description", "scope", "hashes", "licenses", "copyright", "cpe",
+		"purl", "omniborId", "swhid", "swid", "modified", "pedigree", "externalReferences", "properties",
+		"components", "evidence", "releaseNotes", "modelCard", "data", "cryptoProperties", "tags", "signature")
+	serviceFields = fieldSet("bom-ref", "provider", "group", "name", "version", "description", "endpoints",
+		"authenticated", "x-trust-boundary", "trustZone", "data", "licenses", "externalReferences", "properties",
+		"services", "releaseNotes", "tags", "signature")
+	metadataFields = fieldSet("timestamp", "lifecycles", "tools", "manufacturer", "manufacture", "authors",
+		"component", "supplier", "licenses", "properties")
+	dependencyFields  = fieldSet("ref", "dependsOn", "provides")
+	compositionFields = fieldSet("bom-ref", "aggregate", "assemblies", "dependencies", "vulnerabilities", "signature")
+	propertyFields    = fieldSet("name", "value")
+)
+
+func fieldSet(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// StrictError is a problem found by strict parsing, located by a JSON path
+// such as $.dependencies[3].dependsOn
+type StrictError struct {
+	Path    string
+	Message string
+}
+
+func (e StrictError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// StrictErrors is every problem found in a document by strict parsing
+type StrictErrors []StrictError
+
+func (errs StrictErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = "  " + err.Error()
+	}
+	return fmt.Sprintf("strict parsing found %d %s:\n%s", len(errs), plural.Noun(len(errs), "problem"), strings.Join(lines, "\n"))
+}
+
+// validateStrict checks a raw document against the parts of CycloneDX that
+// bom-dagger relies on
+func validateStrict(raw json.RawMessage) StrictErrors {
+	var v strictValidator
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		v.fail("$", "document must be a JSON object")
+		return v.errs
+	}
+
+	v.fields("$", doc, topLevelFields)
+	if metadata, ok := v.object("$.metadata", doc["metadata"], false); ok {
+		v.fields("$.metadata", metadata, metadataFields)
+		if component, ok := v.object("$.metadata.component", metadata["component"], false); ok {
+			v.component("$.metadata.component", component)
+		}
+		v.properties("$.metadata.properties", metadata["properties"])
+	}
+	for i, item := range v.array("$.components", doc["components"]) {
+		path := fmt.Sprintf("$.components[%d]", i)
+		if component, ok := v.object(path, item, true); ok {
+			v.component(path, component)
+		}
+	}
+	for i, item := range v.array("$.services", doc["services"]) {
+		path := fmt.Sprintf("$.services[%d]", i)
+		if service, ok := v.object(path, item, true); ok {
+			v.service(path, service)
+		}
+	}
+	for i, item := range v.array("$.dependencies", doc["dependencies"]) {
+		path := fmt.Sprintf("$.dependencies[%d]", i)
+		dependency, ok := v.object(path, item, true)
+		if !ok {
+			continue
+		}
+		v.fields(path, dependency, dependencyFields)
+		if ref, _ := dependency["ref"].(string); ref == "" {
+			v.fail(path+".ref", "must be a non-empty string")
+		}
+		v.strings(path+".dependsOn", dependency["dependsOn"])
+		v.strings(path+".provides", dependency["provides"])
+	}
+	for i, item := range v.array("$.compositions", doc["compositions"]) {
+		path := fmt.Sprintf("$.compositions[%d]", i)
+		if composition, ok := v.object(path, item, true); ok {
+			v.fields(path, composition, compositionFields)
+			v.strings(path+".assemblies", composition["assemblies"])
+			v.strings(path+".dependencies", composition["dependencies"])
+		}
+	}
+	return v.errs
+}
+
+// strictValidator accumulates StrictErrors while walking a decoded document
+type strictValidator struct {
+	errs StrictErrors
+}
+
+func (v *strictValidator) fail(path, format string, args ...any) {
+	v.errs = append(v.errs, StrictError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// fields reports the fields of obj that CycloneDX does not define, sorted
+func (v *strictValidator) fields(path string, obj map[string]any, known map[string]bool) {
+	var unknown []string
+	for name := range obj {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		v.fail(path+"."+name, "unknown field")
+	}
+}
+
+// object returns value as an object. A missing value is only reported when
+// required, since optional sections may be absent.
+func (v *strictValidator) object(path string, value any, required bool) (map[string]any, bool) {
+	if value == nil && !required {
+		return nil, false
+	}
+	obj, ok := value.(map[string]any)
+	if !ok {
+		v.fail(path, "must be an object, got %s", jsonType(value))
+	}
+	return obj, ok
+}
+
+// array returns value as an array; absent values are an empty array
+func (v *strictValidator) array(path string, value any) []any {
+	if value == nil {
+		return nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		v.fail(path, "must be an array, got %s", jsonType(value))
+	}
+	return items
+}
+
+// strings checks that value, when present, is an array of strings
+func (v *strictValidator) strings(path string, value any) {
+	for i, item := range v.array(path, value) {
+		if _, ok := item.(string); !ok {
+			v.fail(fmt.Sprintf("%s[%d]", path, i), "must be a string, got %s", jsonType(item))
+		}
+	}
+}
+
+func (v *strictValidator) component(path string, component map[string]any) {
+	v.fields(path, component, componentFields)
+	v.name(path, component)
+	if _, ok := component["bom-ref"]; ok {
+		if _, isString := component["bom-ref"].(string); !isString {
+			v.fail(path+".bom-ref", "must be a string, got %s", jsonType(component["bom-ref"]))
+		}
+	}
+	v.properties(path+".properties", component["properties"])
+	for i, item := range v.array(path+".components", component["components"]) {
+		nested := fmt.Sprintf("%s.components[%d]", path, i)
+		if child, ok := v.object(nested, item, true); ok {
+			v.component(nested, child)
+		}
+	}
+}
+
+func (v *strictValidator) service(path string, service map[string]any) {
+	v.fields(path, service, serviceFields)
+	v.name(path, service)
+	v.strings(path+".endpoints", service["endpoints"])
+	v.properties(path+".properties", service["properties"])
+	for i, item := range v.array(path+".services", service["services"]) {
+		nested := fmt.Sprintf("%s.services[%d]", path, i)
+		if child, ok := v.object(nested, item, true); ok {
+			v.service(nested, child)
+		}
+	}
+}
+
+func (v *strictValidator) name(path string, obj map[string]any) {
+	if name, _ := obj["name"].(string); name == "" {
+		v.fail(path+".name", "must be a non-empty string")
+	}
+}
+
+func (v *strictValidator) properties(path string, value any) {
+	for i, item := range v.array(path, value) {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if property, ok := v.object(itemPath, item, true); ok {
+			v.fields(itemPath, property, propertyFields)
+		}
+	}
+}
+
+// jsonType names the JSON type of a decoded value for error messages
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStrictAcceptsFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "testdata", "sboms", "*.json"))
+	if err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+
+	p := New()
+	p.Strict = true
+	for _, file := range files {
+		if _, err := p.ParseFile(file); err != nil {
+			t.Errorf("%s: strict parsing failed: %v", file, err)
+		}
+	}
+}
+
+func TestStrictMistypedDependsOn(t *testing.T) {
+	path := filepath.Join("..", "..", "testdata", "malformed", "mistyped-dependson-1.6.json")
+
+	// Lenient parsing reads the string as a single dependency and warns
+	bom, warnings, err := New().ParseFileWithWarnings(path)
+	if err != nil {
+		t.Fatalf("Lenient parsing failed: %v", err)
+	}
+	if got := bom.Dependencies[0].DependsOn; !reflect.DeepEqual(got, []string{"db"}) {
+		t.Errorf("Expected dependsOn [db], got %v", got)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarnMistypedField {
+		t.Errorf("Expected a mistyped-field warning, got %v", warnings)
+	}
+
+	p := New()
+	p.Strict = true
+	_, err = p.ParseFile(path)
+	var errs StrictErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected StrictErrors, got %v", err)
+	}
+	want := StrictErrors{{Path: "$.dependencies[0].dependsOn", Message: "must be an array, got string"}}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("Expected %v, got %v", want, errs)
+	}
+}
+
+func TestStrictUnknownFields(t *testing.T) {
+	path := filepath.Join("..", "..", "testdata", "malformed", "unknown-fields-1.6.json")
+
+	if _, err := New().ParseFile(path); err != nil {
+		t.Fatalf("Lenient parsing failed: %v", err)
+	}
+
+	p := New()
+	p.Strict = true
+	_, err := p.ParseFile(path)
+	var errs StrictErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected StrictErrors, got %v", err)
+	}
+	want := StrictErrors{
+		{Path: "$.deployTarget", Message: "unknown field"},
+		{Path: "$.components[0].owner", Message: "unknown field"},
+		{Path: "$.components[1].name", Message: "must be a non-empty string"},
+		{Path: "$.dependencies[1].ref", Message: "must be a non-empty string"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("Unexpected errors\nGot:  %v\nWant: %v", errs, want)
+	}
+	if got := err.Error(); got[:33] != "strict parsing found 4 problems:\n" {
+		t.Errorf("Unexpected error summary: %q", got)
+	}
+}
+
+func TestStrictValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{"not an object", `[]`, []string{"$: document must be a JSON object"}},
+		{"components not an array", `{"bomFormat": "CycloneDX", "components": {}}`, []string{"$.components: must be an array, got object"}},
+		{"nested component", `{"bomFormat": "CycloneDX", "components": [{"name": "a", "components": [{"bom-ref": 1}]}]}`,
+			[]string{"$.components[0].components[0].name: must be a non-empty string", "$.components[0].components[0].bom-ref: must be a string, got number"}},
+		{"service endpoints", `{"bomFormat": "CycloneDX", "services": [{"name": "s", "endpoints": "https://x"}]}`,
+			[]string{"$.services[0].endpoints: must be an array, got string"}},
+		{"metadata component", `{"bomFormat": "CycloneDX", "metadata": {"component": {"name": "app", "extra": true}}}`,
+			[]string{"$.metadata.component.extra: unknown field"}},
+		{"property", `{"bomFormat": "CycloneDX", "components": [{"name": "a", "properties": [{"name": "k", "val": "v"}]}]}`,
+			[]string{"$.components[0].properties[0].val: unknown field"}},
+		{"dependsOn item", `{"bomFormat": "CycloneDX", "dependencies": [{"ref": "a", "dependsOn": ["b", 7]}]}`,
+			[]string{"$.dependencies[0].dependsOn[1]: must be a string, got number"}},
+		{"composition refs", `{"bomFormat": "CycloneDX", "compositions": [{"aggregate": "complete", "assemblies": [null]}]}`,
+			[]string{"$.compositions[0].assemblies[0]: must be a string, got null"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range validateStrict([]byte(tt.doc)) {
+				got = append(got, err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unexpected errors\nGot:  %v\nWant: %v", got, tt.want)
+			}
+		})
+	}
+}
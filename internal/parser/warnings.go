@@ -0,0 +1,235 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// WarningCode identifies the kind of problem a Warning reports
+type WarningCode = warning.Code
+
+// Warning codes returned by ParseWithWarnings and ResolveBOMLinks
+const (
+	WarnUnsupportedSpecVersion = warning.UnsupportedSpecVersion
+	WarnServicesSpecVersion    = warning.ServicesSpecVersion
+	WarnSpecVersionFeature     = warning.SpecVersionFeature
+	WarnMissingVersion         = warning.MissingVersion
+	WarnEmptyComponents        = warning.EmptyComponents
+	WarnUnknownField           = warning.UnknownField
+	WarnMistypedField          = warning.MistypedField
+	WarnMetadataComponentRef   = warning.MetadataComponentRef
+	WarnDuplicateBOMRef        = warning.DuplicateBOMRef
+	WarnUnresolvedBOMLink      = warning.UnresolvedBOMLink
+)
+
+// Warning is a problem with a document that does not prevent parsing it
+type Warning = warning.Warning
+
+// SupportedSpecVersions lists the CycloneDX spec versions the parser knows
+var SupportedSpecVersions = []string{"1.2", "1.3", "1.4", "1.5", "1.6"}
+
+// topLevelFields are the properties CycloneDX 1.6 defines on a BOM
+var topLevelFields = map[string]bool{
+	"$schema":            true,
+	"bomFormat":          true,
+	"specVersion":        true,
+	"serialNumber":       true,
+	"version":            true,
+	"metadata":           true,
+	"components":         true,
+	"services":           true,
+	"externalReferences": true,
+	"dependencies":       true,
+	"compositions":       true,
+	"properties":         true,
+	"vulnerabilities":    true,
+	"annotations":        true,
+	"formulation":        true,
+	"declarations":       true,
+	"definitions":        true,
+	"signature":          true,
+}
+
+// ParseWithWarnings parses an SBOM like Parse and also reports problems the
+// decoder would silently accept
+func (p *Parser) ParseWithWarnings(reader io.Reader) (*sbom.CycloneDX, []Warning, error) {
+	var raw json.RawMessage
+	bom, err := p.parse(reader, &raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return bom, checkWarnings(bom, fields), nil
+}
+
+// ParseFileWithWarnings is ParseWithWarnings for a file path
+func (p *Parser) ParseFileWithWarnings(filePath string) (*sbom.CycloneDX, []Warning, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return p.ParseWithWarnings(file)
+}
+
+func checkWarnings(bom *sbom.CycloneDX, fields map[string]json.RawMessage) []Warning {
+	var warnings []Warning
+
+	if bom.Spec() == sbom.SpecUnknown {
+		warnings = append(warnings, Warning{
+			Code: WarnUnsupportedSpecVersion,
+			Message: fmt.Sprintf("specVersion %q is not supported (expected one of %s)",
+				bom.SpecVersion, strings.Join(SupportedSpecVersions, ", ")),
+		})
+	} else if len(bom.Services) > 0 && bom.Spec() < sbom.Spec1_5 {
+		warnings = append(warnings, Warning{
+			Code:    WarnServicesSpecVersion,
+			Message: fmt.Sprintf("services are declared but specVersion is %s; services handling assumes 1.5 or later", bom.SpecVersion),
+		})
+	}
+	warnings = append(warnings, checkSpecFeatures(bom, fields)...)
+
+	if _, ok := fields["version"]; !ok {
+		warnings = append(warnings, Warning{Code: WarnMissingVersion, Message: "document has no version field"})
+	}
+	if len(bom.Components) == 0 {
+		warnings = append(warnings, Warning{Code: WarnEmptyComponents, Message: "document declares no components"})
+	}
+
+	var unknown []string
+	for name := range fields {
+		if !topLevelFields[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		warnings = append(warnings, Warning{
+			Code:    WarnUnknownField,
+			Message: fmt.Sprintf("unknown top-level field %q is ignored", name),
+		})
+	}
+
+	var dependencies []map[string]json.RawMessage
+	_ = json.Unmarshal(fields["dependencies"], &dependencies)
+	for i, dep := range dependencies {
+		if dependsOn := dep["dependsOn"]; len(dependsOn) > 0 && dependsOn[0] == '"' {
+			warnings = append(warnings, Warning{
+				Code:    WarnMistypedField,
+				Message: fmt.Sprintf("$.dependencies[%d].dependsOn is a string rather than a list; treated as a single dependency", i),
+			})
+		}
+	}
+
+	warnings = append(warnings, checkDuplicateRefs(bom)...)
+
+	if warning, ok := checkMetadataComponentRef(bom); ok {
+		warnings = append(warnings, warning)
+	}
+
+	return warnings
+}
+
+// checkDuplicateRefs reports bom-refs declared more than once, leaving out
+// the metadata component, which tools often list among the components
+func checkDuplicateRefs(bom *sbom.CycloneDX) []Warning {
+	counts := make(map[string]int)
+	stack := make([]*sbom.Component, 0, len(bom.Components))
+	for i := range bom.Components {
+		stack = append(stack, &bom.Components[i])
+	}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if current.BOMRef != "" {
+			counts[current.BOMRef]++
+		}
+		for i := range current.Components {
+			stack = append(stack, &current.Components[i])
+		}
+	}
+	for _, service := range bom.Services {
+		if service.BOMRef != "" {
+			counts[service.BOMRef]++
+		}
+	}
+
+	var warnings []Warning
+	for _, ref := range sortedKeys(counts) {
+		if n := counts[ref]; n > 1 {
+			warnings = append(warnings, warning.New(WarnDuplicateBOMRef,
+				"bom-ref %q is declared by %d components or services; only one of them is planned", ref, n))
+		}
+	}
+	return warnings
+}
+
+// checkMetadataComponentRef catches a metadata component that the
+// dependencies cannot refer to
+func checkMetadataComponentRef(bom *sbom.CycloneDX) (Warning, bool) {
+	if bom.Metadata == nil || bom.Metadata.Component == nil || len(bom.Dependencies) == 0 {
+		return Warning{}, false
+	}
+	subject := bom.Metadata.Component
+
+	identities := map[string]bool{subject.Name: subject.Name != ""}
+	if subject.Name != "" && subject.Version != "" {
+		identities[subject.Name+"@"+subject.Version] = true
+	}
+	if subject.Purl != "" {
+		identities[subject.Purl] = true
+	}
+	known := New().GetComponentMap(bom)
+	for i := range bom.Services {
+		known[bom.Services[i].BOMRef] = nil
+	}
+	seen := make(map[string]bool)
+	var matches []string
+	for _, dep := range bom.Dependencies {
+		for _, ref := range append([]string{dep.Ref}, dep.DependsOn...) {
+			if _, ok := known[ref]; ok || !identities[ref] || seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			matches = append(matches, ref)
+		}
+	}
+
+	var message string
+	switch {
+	case len(matches) > 0 && subject.BOMRef == "":
+		message = fmt.Sprintf("dependencies refer to %s, which looks like the metadata component %q but does not resolve because it has no bom-ref; set metadata.component.bom-ref to %q",
+			quoteAll(matches), subject.Name, matches[0])
+	case len(matches) > 0:
+		message = fmt.Sprintf("dependencies refer to %s, which looks like the metadata component %q but does not resolve; refer to it by its bom-ref %q",
+			quoteAll(matches), subject.Name, subject.BOMRef)
+	case subject.BOMRef == "":
+		message = fmt.Sprintf("metadata component %q has no bom-ref, so it is left out of the graph; add a bom-ref and a dependencies entry for it",
+			subject.Name)
+	default:
+		return Warning{}, false
+	}
+	return Warning{Code: WarnMetadataComponentRef, Message: message}, true
+}
+
+// quoteAll quotes and joins strings
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,221 @@
+package parser
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseWithWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []WarningCode
+	}{
+		{
+			name: "clean document",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+				"components": [{"bom-ref": "a", "name": "A", "type": "library"}]}`,
+		},
+		{
+			name: "unsupported spec version",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "2.0", "version": 1,
+				"components": [{"bom-ref": "a", "name": "A", "type": "library"}]}`,
+			want: []WarningCode{WarnUnsupportedSpecVersion},
+		},
+		{
+			name: "missing spec version",
+			json: `{"bomFormat": "CycloneDX", "version": 1,
+				"components": [{"bom-ref": "a", "name": "A", "type": "library"}]}`,
+			want: []WarningCode{WarnUnsupportedSpecVersion},
+		},
+		{
+			name: "services before 1.5",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.4", "version": 1,
+				"components": [{"bom-ref": "a", "name": "A", "type": "library"}],
+				"services": [{"bom-ref": "s", "name": "S"}]}`,
+			want: []WarningCode{WarnServicesSpecVersion},
+		},
+		{
+			name: "services in 1.5",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.5", "version": 1,
+				"components": [{"bom-ref": "a", "name": "A", "type": "library"}],
+				"services": [{"bom-ref": "s", "name": "S"}]}`,
+		},
+		{
+			name: "missing version and components",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.6", "components": []}`,
+			want: []WarningCode{WarnMissingVersion, WarnEmptyComponents},
+		},
+		{
+			name: "unknown fields",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+				"components": [{"bom-ref": "a", "name": "A", "type": "library"}],
+				"zeta": true, "alpha": {}, "$schema": "http://cyclonedx.org/schema/bom-1.6.schema.json"}`,
+			want: []WarningCode{WarnUnknownField, WarnUnknownField},
+		},
+		{
+			name: "metadata component referred to by name",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+				"metadata": {"component": {"name": "app", "version": "1.0", "type": "application"}},
+				"components": [{"bom-ref": "a", "name": "A", "type": "library"}],
+				"dependencies": [{"ref": "app", "dependsOn": ["a"]}]}`,
+			want: []WarningCode{WarnMetadataComponentRef},
+		},
+		{
+			name: "metadata component referred to by purl instead of its ref",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+				"metadata": {"component": {"bom-ref": "app", "name": "app", "purl": "pkg:npm/app@1.0", "type": "application"}},
+				"components": [{"bom-ref": "a", "name": "A", "type": "library"}],
+				"dependencies": [{"ref": "pkg:npm/app@1.0", "dependsOn": ["a"]}]}`,
+			want: []WarningCode{WarnMetadataComponentRef},
+		},
+		{
+			name: "metadata component without a ref",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+				"metadata": {"component": {"name": "app", "type": "application"}},
+				"components": [{"bom-ref": "a", "name": "A", "type": "library"}, {"bom-ref": "b", "name": "B", "type": "library"}],
+				"dependencies": [{"ref": "b", "dependsOn": ["a"]}]}`,
+			want: []WarningCode{WarnMetadataComponentRef},
+		},
+		{
+			name: "metadata component without a ref and no dependencies",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+				"metadata": {"component": {"name": "app", "type": "application"}},
+				"components": [{"bom-ref": "a", "name": "A", "type": "library"}]}`,
+		},
+		{
+			name: "component named like the metadata component",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+				"metadata": {"component": {"bom-ref": "root", "name": "app", "type": "application"}},
+				"components": [{"bom-ref": "app", "name": "app", "type": "library"}],
+				"dependencies": [{"ref": "root", "dependsOn": ["app"]}]}`,
+		},
+		{
+			name: "1.6 fields in a 1.4 document",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.4", "version": 1,
+				"metadata": {"lifecycles": [{"phase": "build"}], "tools": {"components": [{"name": "syft"}]}},
+				"components": [{"bom-ref": "a", "name": "A", "type": "library", "omniborId": ["gitoid:blob:sha1:a"]}],
+				"declarations": {}}`,
+			want: []WarningCode{WarnSpecVersionFeature, WarnSpecVersionFeature, WarnSpecVersionFeature, WarnSpecVersionFeature},
+		},
+		{
+			name: "1.5 fields in a 1.5 document",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.5", "version": 1,
+				"metadata": {"lifecycles": [{"phase": "build"}], "tools": {"components": [{"name": "syft"}]}},
+				"components": [{"bom-ref": "m", "name": "M", "type": "machine-learning-model", "modelCard": {}}],
+				"formulation": []}`,
+		},
+		{
+			name: "1.6 component type in a 1.5 document",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.5", "version": 1,
+				"components": [{"bom-ref": "c", "name": "C", "type": "library",
+					"components": [{"bom-ref": "k", "name": "K", "type": "cryptographic-asset"}]}]}`,
+			want: []WarningCode{WarnSpecVersionFeature},
+		},
+		{
+			name: "bom-ref declared twice",
+			json: `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": 1,
+				"components": [{"bom-ref": "a", "name": "A", "type": "library",
+					"components": [{"bom-ref": "a", "name": "A2", "type": "library"}]}],
+				"services": [{"bom-ref": "s", "name": "S"}, {"bom-ref": "s", "name": "S2"}]}`,
+			want: []WarningCode{WarnDuplicateBOMRef, WarnDuplicateBOMRef},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New()
+			bom, warnings, err := p.ParseWithWarnings(strings.NewReader(tt.json))
+			if err != nil {
+				t.Fatalf("ParseWithWarnings failed: %v", err)
+			}
+			if bom == nil {
+				t.Fatal("Expected a BOM")
+			}
+
+			var codes []WarningCode
+			for _, w := range warnings {
+				codes = append(codes, w.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.want) {
+				t.Errorf("Expected warning codes %v, got %v (%v)", tt.want, codes, warnings)
+			}
+		})
+	}
+}
+
+func TestSpecVersionFeatureMessages(t *testing.T) {
+	p := New()
+	_, warnings, err := p.ParseWithWarnings(strings.NewReader(`{"bomFormat": "CycloneDX", "specVersion": "1.4",
+		"version": 1, "metadata": {"tools": {"components": []}},
+		"components": [{"name": "A", "authors": []}, {"name": "B", "authors": []}], "annotations": []}`))
+	if err != nil {
+		t.Fatalf("ParseWithWarnings failed: %v", err)
+	}
+	var messages []string
+	for _, w := range warnings {
+		if !w.Code.SpecVersionMismatch() {
+			t.Errorf("Expected only spec version warnings, got %v", w)
+		}
+		messages = append(messages, w.Message)
+	}
+	want := []string{
+		"annotations requires CycloneDX 1.5 but specVersion is 1.4",
+		"metadata.tools as an object requires CycloneDX 1.5 but specVersion is 1.4",
+		"components[].authors (2 components) requires CycloneDX 1.6 but specVersion is 1.4",
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("Messages =\n%q\nwant\n%q", messages, want)
+	}
+}
+
+func TestParseWithWarningsUnknownFieldOrder(t *testing.T) {
+	p := New()
+	_, warnings, err := p.ParseWithWarnings(strings.NewReader(`{"bomFormat": "CycloneDX", "specVersion": "1.6",
+		"version": 1, "components": [{"name": "A"}], "zeta": 1, "alpha": 2}`))
+	if err != nil {
+		t.Fatalf("ParseWithWarnings failed: %v", err)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0].Message, `"alpha"`) || !strings.Contains(warnings[1].Message, `"zeta"`) {
+		t.Errorf("Expected unknown fields sorted by name, got %v", warnings)
+	}
+}
+
+func TestParseWithWarningsErrors(t *testing.T) {
+	p := New()
+	if _, _, err := p.ParseWithWarnings(strings.NewReader(`{"bomFormat": "SPDX"}`)); err == nil {
+		t.Error("Expected error for non-CycloneDX document")
+	}
+	if _, _, err := p.ParseWithWarnings(strings.NewReader(`{"bomFormat": `)); err == nil {
+		t.Error("Expected error for malformed JSON")
+	}
+	// The document is decoded twice here; the second pass must still count
+	// lines from the start of the input
+	_, _, err := p.ParseWithWarnings(strings.NewReader("\n\n{\"bomFormat\": \"CycloneDX\",\n\"components\": [{\"name\": 7}]}"))
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Line != 4 || decodeErr.Column != 25 || decodeErr.Path != "$.components[0].name" {
+		t.Errorf("Expected a type error at line 4, column 25, got %v", err)
+	}
+}
+
+func TestParseFileWithWarningsFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "testdata", "sboms", "*.json"))
+	if err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+
+	p := New()
+	for _, file := range files {
+		_, warnings, err := p.ParseFileWithWarnings(file)
+		if err != nil {
+			t.Errorf("%s: ParseFileWithWarnings failed: %v", file, err)
+			continue
+		}
+		if len(warnings) > 0 {
+			t.Errorf("%s: expected no warnings, got %v", file, warnings)
+		}
+	}
+}
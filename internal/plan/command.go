@@ -0,0 +1,144 @@
+package plan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// Defaults for CommandOptions
+const (
+	DefaultHealthTimeout  = 5 * time.Minute
+	DefaultHealthInterval = 2 * time.Second
+)
+
+// CommandGracePeriod is how long a cancelled command gets to exit after
+// SIGTERM before it is killed
+const CommandGracePeriod = 10 * time.Second
+
+// Command is what exec mode runs for one component
+type Command struct {
+	Run     string // Deploys (or tears down) the component
+	Health  string // Exits 0 once the component is healthy; empty skips the check
+	Retries int    // Further attempts after a failed one
+}
+
+// Commands returns the commands of every node with the given property, by
+// bom-ref; invalid retry counts are reported and use defaultRetries
+func Commands(g *dag.Graph, property string, withHealth bool, defaultRetries int) (map[string]Command, []warning.Warning) {
+	commands := make(map[string]Command)
+	var warnings []warning.Warning
+	for _, node := range g.NodesSorted() {
+		id := node.ID
+		run, ok := node.Property(property)
+		if !ok || run == "" {
+			continue
+		}
+		c := Command{Run: run, Retries: defaultRetries}
+		if withHealth {
+			c.Health, _ = node.Property(dag.PropertyHealthCommand)
+		}
+		if value, ok := node.Property(dag.PropertyRetries); ok {
+			if retries, err := strconv.Atoi(value); err == nil && retries >= 0 {
+				c.Retries = retries
+			} else {
+				warnings = append(warnings, warning.New(warning.InvalidRetries, "invalid %s %q on %s (expected a non-negative integer); using %d",
+					dag.PropertyRetries, value, id, defaultRetries))
+			}
+		}
+		commands[id] = c
+	}
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Message < warnings[j].Message })
+	return commands, warnings
+}
+
+// CommandOptions configures RunCommand
+type CommandOptions struct {
+	RetryDelay     time.Duration // Wait between attempts
+	HealthTimeout  time.Duration // How long the health command may keep failing (0 = DefaultHealthTimeout)
+	HealthInterval time.Duration // Wait between health checks (0 = DefaultHealthInterval)
+	// OnRetry is called before every further attempt with the number of the
+	// attempt that failed, its error and output
+	OnRetry func(attempt int, err error, output []byte)
+}
+
+// ShellCommand runs command with sh -c. Cancelling ctx, e.g. on an interrupt,
+// sends the shell SIGTERM so the deploy tool can clean up, and kills it once
+// CommandGracePeriod has passed.
+func ShellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = CommandGracePeriod
+	return cmd
+}
+
+// RunCommand runs c, with its health check, until an attempt succeeds or
+// 1+c.Retries fail, and returns the output of the last attempt
+func RunCommand(ctx context.Context, c Command, opts CommandOptions) ([]byte, error) {
+	for attempt := 1; ; attempt++ {
+		output, err := runAttempt(ctx, c, opts)
+		if err == nil || attempt > c.Retries || ctx.Err() != nil {
+			return output, err
+		}
+		if opts.OnRetry != nil {
+			opts.OnRetry(attempt, err, output)
+		}
+		if err := sleep(ctx, opts.RetryDelay); err != nil {
+			return output, err
+		}
+	}
+}
+
+func runAttempt(ctx context.Context, c Command, opts CommandOptions) ([]byte, error) {
+	output, err := ShellCommand(ctx, c.Run).CombinedOutput()
+	if err != nil || c.Health == "" {
+		return output, err
+	}
+
+	timeout := opts.HealthTimeout
+	if timeout <= 0 {
+		timeout = DefaultHealthTimeout
+	}
+	interval := opts.HealthInterval
+	if interval <= 0 {
+		interval = DefaultHealthInterval
+	}
+	healthCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		health, err := ShellCommand(healthCtx, c.Health).CombinedOutput()
+		if err == nil {
+			return output, nil
+		}
+		if err := sleep(healthCtx, interval); err != nil {
+			if ctx.Err() != nil {
+				return output, ctx.Err()
+			}
+			output = append(append(output, "health check output:\n"...), bytes.TrimRight(health, "\n")...)
+			return output, fmt.Errorf("health check did not pass within %s", timeout)
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done, returning ctx.Err() in that case
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,155 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+	"github.com/nprimmer/bom-dagger/internal/warning"
+)
+
+// succeedOnAttempt returns a shell command that fails until it has run n
+// times, counting in a file under dir
+func succeedOnAttempt(dir string, n int) string {
+	counter := filepath.Join(dir, "attempts")
+	return `n=$(cat '` + counter + `' 2>/dev/null || echo 0); n=$((n+1)); echo $n > '` + counter + `'; echo "attempt $n"; [ $n -ge ` +
+		strconv.Itoa(n) + ` ]`
+}
+
+func TestRunCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   func(dir string) Command
+		wantErr   string
+		wantTries int // OnRetry calls + 1
+	}{
+		{"success", func(string) Command { return Command{Run: "true"} }, "", 1},
+		{"failure without retries", func(string) Command { return Command{Run: "false"} }, "exit status 1", 1},
+		{"flaky command within retries", func(dir string) Command {
+			return Command{Run: succeedOnAttempt(dir, 3), Retries: 2}
+		}, "", 3},
+		{"flaky command beyond retries", func(dir string) Command {
+			return Command{Run: succeedOnAttempt(dir, 3), Retries: 1}
+		}, "exit status 1", 2},
+		{"health check passes eventually", func(dir string) Command {
+			return Command{Run: "true", Health: succeedOnAttempt(dir, 3)}
+		}, "", 1},
+		{"health check never passes", func(string) Command {
+			return Command{Run: "true", Health: "false"}
+		}, "health check did not pass within 100ms", 1},
+		{"failed deploy skips the health check", func(string) Command {
+			return Command{Run: "false", Health: "true"}
+		}, "exit status 1", 1},
+		{"health failures are retried", func(string) Command {
+			return Command{Run: "true", Health: "false", Retries: 1}
+		}, "health check did not pass", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tries := 1
+			opts := CommandOptions{
+				RetryDelay:     time.Millisecond,
+				HealthTimeout:  100 * time.Millisecond,
+				HealthInterval: 10 * time.Millisecond,
+				OnRetry:        func(int, error, []byte) { tries++ },
+			}
+			_, err := RunCommand(context.Background(), tt.command(t.TempDir()), opts)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if tries != tt.wantTries {
+				t.Errorf("Expected %d attempts, got %d", tt.wantTries, tries)
+			}
+		})
+	}
+}
+
+func TestRunCommandOutput(t *testing.T) {
+	output, err := RunCommand(context.Background(), Command{Run: succeedOnAttempt(t.TempDir(), 2), Retries: 1},
+		CommandOptions{RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatalf("RunCommand failed: %v", err)
+	}
+	if string(output) != "attempt 2\n" {
+		t.Errorf("Expected the output of the last attempt, got %q", output)
+	}
+
+	output, _ = RunCommand(context.Background(), Command{Run: "echo deployed", Health: "echo not ready; false"},
+		CommandOptions{HealthTimeout: 50 * time.Millisecond, HealthInterval: 10 * time.Millisecond})
+	if want := "deployed\nhealth check output:\nnot ready"; string(output) != want {
+		t.Errorf("Expected %q, got %q", want, output)
+	}
+}
+
+func TestRunCommandCancel(t *testing.T) {
+	tests := []struct {
+		name    string
+		command Command
+		opts    CommandOptions
+	}{
+		{"while running", Command{Run: "exec sleep 10"}, CommandOptions{}},
+		{"between attempts", Command{Run: "false", Retries: 3}, CommandOptions{RetryDelay: time.Hour}},
+		{"while checking health", Command{Run: "true", Health: "false"}, CommandOptions{HealthInterval: time.Hour}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			start := time.Now()
+			_, err := RunCommand(ctx, tt.command, tt.opts)
+			if err == nil {
+				t.Fatal("Expected an error after cancelling")
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Expected cancelling to stop the command, took %s", elapsed)
+			}
+			if tt.name != "while running" && !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCommands(t *testing.T) {
+	bom := &sbom.CycloneDX{Components: []sbom.Component{
+		{BOMRef: "api", Name: "API", Properties: []sbom.Property{
+			{Name: dag.PropertyDeployCommand, Value: "deploy api"},
+			{Name: dag.PropertyHealthCommand, Value: "curl -f api/healthz"},
+			{Name: dag.PropertyRetries, Value: "3"},
+		}},
+		{BOMRef: "db", Name: "DB", Properties: []sbom.Property{
+			{Name: dag.PropertyDeployCommand, Value: "deploy db"},
+			{Name: dag.PropertyRetries, Value: "many"},
+		}},
+		{BOMRef: "docs", Name: "Docs"},
+	}}
+	g := buildGraph(t, bom)
+
+	commands, warnings := Commands(g, dag.PropertyDeployCommand, true, 1)
+	want := map[string]Command{
+		"api": {Run: "deploy api", Health: "curl -f api/healthz", Retries: 3},
+		"db":  {Run: "deploy db", Retries: 1},
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("Commands() = %+v, want %+v", commands, want)
+	}
+	if len(warnings) != 1 || warnings[0].Code != warning.InvalidRetries || !strings.Contains(warnings[0].Message, `invalid bom-dagger:retries "many" on db`) {
+		t.Errorf("Expected a warning about db, got %q", warnings)
+	}
+
+	commands, _ = Commands(g, dag.PropertyDeployCommand, false, 0)
+	if commands["api"].Health != "" {
+		t.Errorf("Expected no health command without withHealth, got %+v", commands["api"])
+	}
+}
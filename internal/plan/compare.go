@@ -0,0 +1,167 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// StepChange records a component that moved to a different step
+type StepChange struct {
+	BOMRef  string
+	OldStep int
+	NewStep int
+}
+
+// VersionChange records a component whose version differs between plans
+type VersionChange struct {
+	BOMRef     string
+	OldVersion string
+	NewVersion string
+}
+
+// Diff describes the differences between an expected and an actual plan
+type Diff struct {
+	Added          []string
+	Removed        []string
+	StepChanges    []StepChange
+	VersionChanges []VersionChange
+}
+
+// Compare reports how the actual plan differs from the expected one.
+// Ordering of components within a step is not significant.
+func Compare(expected, actual *Plan) *Diff {
+	oldEntries := expected.index()
+	newEntries := actual.index()
+	diff := &Diff{}
+
+	for ref, oldEntry := range oldEntries {
+		newEntry, ok := newEntries[ref]
+		if !ok {
+			diff.Removed = append(diff.Removed, ref)
+			continue
+		}
+		if oldEntry.Step != newEntry.Step {
+			diff.StepChanges = append(diff.StepChanges, StepChange{BOMRef: ref, OldStep: oldEntry.Step, NewStep: newEntry.Step})
+		}
+		if oldEntry.Version != newEntry.Version {
+			diff.VersionChanges = append(diff.VersionChanges, VersionChange{BOMRef: ref, OldVersion: oldEntry.Version, NewVersion: newEntry.Version})
+		}
+	}
+	for ref := range newEntries {
+		if _, ok := oldEntries[ref]; !ok {
+			diff.Added = append(diff.Added, ref)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.StepChanges, func(i, j int) bool {
+		return diff.StepChanges[i].BOMRef < diff.StepChanges[j].BOMRef
+	})
+	sort.Slice(diff.VersionChanges, func(i, j int) bool {
+		return diff.VersionChanges[i].BOMRef < diff.VersionChanges[j].BOMRef
+	})
+
+	return diff
+}
+
+// Empty reports whether the plans matched
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.StepChanges) == 0 && len(d.VersionChanges) == 0
+}
+
+// Write prints a human-readable report of the differences
+func (d *Diff) Write(w io.Writer) {
+	if d.Empty() {
+		fmt.Fprintln(w, "Plan matches.")
+		return
+	}
+
+	for _, ref := range d.Added {
+		fmt.Fprintf(w, "  + %s (added)\n", ref)
+	}
+	for _, ref := range d.Removed {
+		fmt.Fprintf(w, "  - %s (removed)\n", ref)
+	}
+	for _, change := range d.StepChanges {
+		fmt.Fprintf(w, "  ~ %s: step %d -> %d\n", change.BOMRef, change.OldStep, change.NewStep)
+	}
+	for _, change := range d.VersionChanges {
+		fmt.Fprintf(w, "  ~ %s: version %s -> %s\n", change.BOMRef, change.OldVersion, change.NewVersion)
+	}
+}
+
+// GroupMove records a component whose deployment group differs between
+// plans. OldGroup is 0 for a component only in the actual plan, NewGroup 0
+// for one only in the expected plan.
+type GroupMove struct {
+	BOMRef   string
+	OldGroup int
+	NewGroup int
+}
+
+// CompareGroups reports components that moved into or out of groups 1 to
+// through (all when <= 0), and how many later moves were ignored
+func CompareGroups(expected, actual *Plan, through int) ([]GroupMove, int) {
+	oldGroups, newGroups := expected.groups(), actual.groups()
+	protected := func(group int) bool {
+		return group > 0 && (through <= 0 || group <= through)
+	}
+
+	moves := []GroupMove{}
+	ignored := 0
+	record := func(move GroupMove) {
+		if protected(move.OldGroup) || protected(move.NewGroup) {
+			moves = append(moves, move)
+		} else {
+			ignored++
+		}
+	}
+	for ref, oldGroup := range oldGroups {
+		if newGroup := newGroups[ref]; newGroup != oldGroup {
+			record(GroupMove{BOMRef: ref, OldGroup: oldGroup, NewGroup: newGroup})
+		}
+	}
+	for ref, newGroup := range newGroups {
+		if _, ok := oldGroups[ref]; !ok {
+			record(GroupMove{BOMRef: ref, NewGroup: newGroup})
+		}
+	}
+
+	sort.Slice(moves, func(i, j int) bool {
+		a, b := moves[i], moves[j]
+		if a.OldGroup != b.OldGroup {
+			return a.OldGroup < b.OldGroup
+		}
+		if a.NewGroup != b.NewGroup {
+			return a.NewGroup < b.NewGroup
+		}
+		return a.BOMRef < b.BOMRef
+	})
+	return moves, ignored
+}
+
+// groups maps each ref to its deployment group. Plans written before
+// entries carried their group fall back to the step.
+func (p *Plan) groups() map[string]int {
+	groups := make(map[string]int)
+	for ref, entry := range p.index() {
+		if entry.Group > 0 {
+			groups[ref] = entry.Group
+		} else {
+			groups[ref] = entry.Step
+		}
+	}
+	return groups
+}
+
+func (m GroupMove) String() string {
+	switch {
+	case m.OldGroup == 0:
+		return fmt.Sprintf("+ %s: added in group %d", m.BOMRef, m.NewGroup)
+	case m.NewGroup == 0:
+		return fmt.Sprintf("- %s: removed from group %d", m.BOMRef, m.OldGroup)
+	}
+	return fmt.Sprintf("~ %s: group %d -> %d", m.BOMRef, m.OldGroup, m.NewGroup)
+}
@@ -0,0 +1,119 @@
+package plan
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Reply is an answer to a Prompter
+type Reply string
+
+// Replies accepted by Prompter.Confirm
+const (
+	ReplyYes  Reply = "yes"
+	ReplyNo   Reply = "no"
+	ReplySkip Reply = "skip"
+)
+
+// ErrNoReply reports input that ended before a prompt was answered
+var ErrNoReply = errors.New("no reply: input ended")
+
+// Prompter paces a rollout one group at a time: it asks before each group
+// and records every answer, with its time, in a session log so the rollout
+// can be audited afterwards
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// Log receives one line per answer (nil = none)
+	Log io.Writer
+	// AssumeYes answers every prompt with yes without reading input
+	AssumeYes bool
+	// Now is the clock of the log (nil = time.Now)
+	Now func() time.Time
+
+	paused bool
+}
+
+// NewPrompter returns a Prompter reading answers from in and writing
+// prompts to out
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+// Pause waits for Enter before group is shown. The first group is shown
+// without waiting, but still logged. It returns ErrNoReply when the input
+// ends first.
+func (p *Prompter) Pause(group int) error {
+	if !p.paused {
+		p.paused = true
+		return p.log(group, "shown")
+	}
+	if p.AssumeYes {
+		return p.log(group, "shown (assumed)")
+	}
+	fmt.Fprintf(p.out, "Press Enter to show group %d... ", group)
+	if _, err := p.readLine(); err != nil {
+		return err
+	}
+	return p.log(group, "shown")
+}
+
+// Confirm asks question about group until it is answered with y(es), n(o)
+// or s(kip), in any case. It returns ErrNoReply when the input ends first.
+func (p *Prompter) Confirm(group int, question string) (Reply, error) {
+	if p.AssumeYes {
+		return ReplyYes, p.log(group, string(ReplyYes)+" (assumed)")
+	}
+	for {
+		fmt.Fprintf(p.out, "%s [y/n/skip] ", question)
+		line, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+		var reply Reply
+		switch strings.ToLower(line) {
+		case "y", "yes":
+			reply = ReplyYes
+		case "n", "no":
+			reply = ReplyNo
+		case "s", "skip":
+			reply = ReplySkip
+		default:
+			fmt.Fprintln(p.out, "Please answer y, n or skip.")
+			continue
+		}
+		return reply, p.log(group, string(reply))
+	}
+}
+
+// readLine reads one line without its line ending. A last line without a
+// newline still counts; no line at all is ErrNoReply.
+func (p *Prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			fmt.Fprintln(p.out)
+			return "", ErrNoReply
+		}
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// log writes "<RFC 3339 time> group <n>: <event>" to Log
+func (p *Prompter) log(group int, event string) error {
+	if p.Log == nil {
+		return nil
+	}
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+	_, err := fmt.Fprintf(p.Log, "%s group %d: %s\n", now().UTC().Format(time.RFC3339), group, event)
+	return err
+}
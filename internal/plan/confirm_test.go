@@ -0,0 +1,83 @@
+package plan
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedPrompter answers prompts with script, logging at a fixed time
+func scriptedPrompter(script string) (*Prompter, *strings.Builder, *strings.Builder) {
+	var out, log strings.Builder
+	p := NewPrompter(strings.NewReader(script), &out)
+	p.Log = &log
+	p.Now = func() time.Time { return time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC) }
+	return p, &out, &log
+}
+
+func TestPrompterConfirm(t *testing.T) {
+	p, out, log := scriptedPrompter("maybe\nY\n skip \nn")
+	for _, want := range []Reply{ReplyYes, ReplySkip, ReplyNo} {
+		reply, err := p.Confirm(2, "Deploy group 2?")
+		if err != nil {
+			t.Fatalf("Confirm failed: %v", err)
+		}
+		if reply != want {
+			t.Errorf("Expected %s, got %s", want, reply)
+		}
+	}
+	if _, err := p.Confirm(3, "Deploy group 3?"); !errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected ErrNoReply once the script ends, got %v", err)
+	}
+
+	if got := strings.Count(out.String(), "Deploy group 2? [y/n/skip] "); got != 4 {
+		t.Errorf("Expected the invalid answer to be asked again, got %d prompts:\n%s", got, out)
+	}
+	if !strings.Contains(out.String(), "Please answer y, n or skip.") {
+		t.Errorf("Expected a hint after the invalid answer, got:\n%s", out)
+	}
+	want := "2024-01-15T10:00:00Z group 2: yes\n2024-01-15T10:00:00Z group 2: skip\n2024-01-15T10:00:00Z group 2: no\n"
+	if log.String() != want {
+		t.Errorf("Unexpected session log:\n%s", log)
+	}
+}
+
+func TestPrompterPause(t *testing.T) {
+	p, out, log := scriptedPrompter("\n")
+	for _, group := range []int{1, 2} {
+		if err := p.Pause(group); err != nil {
+			t.Fatalf("Pause failed: %v", err)
+		}
+	}
+	if err := p.Pause(3); !errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected ErrNoReply, got %v", err)
+	}
+	// The first group is shown without a prompt
+	if !strings.HasPrefix(out.String(), "Press Enter to show group 2... ") {
+		t.Errorf("Unexpected prompt %q", out)
+	}
+	if log.String() != "2024-01-15T10:00:00Z group 1: shown\n2024-01-15T10:00:00Z group 2: shown\n" {
+		t.Errorf("Unexpected session log %q", log)
+	}
+}
+
+func TestPrompterAssumeYes(t *testing.T) {
+	p, out, log := scriptedPrompter("")
+	p.AssumeYes = true
+	if reply, err := p.Confirm(1, "Deploy group 1?"); err != nil || reply != ReplyYes {
+		t.Errorf("Expected yes without input, got %s, %v", reply, err)
+	}
+	for _, group := range []int{1, 2} {
+		if err := p.Pause(group); err != nil {
+			t.Errorf("Pause failed: %v", err)
+		}
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no prompts, got %q", out)
+	}
+	want := "2024-01-15T10:00:00Z group 1: yes (assumed)\n2024-01-15T10:00:00Z group 1: shown\n2024-01-15T10:00:00Z group 2: shown (assumed)\n"
+	if log.String() != want {
+		t.Errorf("Unexpected session log %q", log)
+	}
+}
@@ -0,0 +1,200 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ExecuteOptions configures Execute. Every callback is optional.
+type ExecuteOptions struct {
+	// OnGroupStart is called before a step starts; an error fails the step,
+	// ErrSkipGroup skips it and ErrStop ends the run
+	OnGroupStart func(ctx context.Context, step Step) error
+	// OnComponent deploys a single component. Calls for components of the
+	// same step may run concurrently.
+	OnComponent func(ctx context.Context, step Step, entry Entry) error
+	// OnGroupEnd is called once a step has finished, with the errors of the
+	// step joined (nil when every component succeeded)
+	OnGroupEnd func(ctx context.Context, step Step, err error)
+
+	// MaxParallel limits how many components of a step run at once
+	// (0 = all of them)
+	MaxParallel int
+	// ContinueOnError runs the rest of the plan after a failure, dependents of
+	// the failed component included
+	ContinueOnError bool
+}
+
+var (
+	// ErrSkipGroup is returned by OnGroupStart to skip a step
+	ErrSkipGroup = errors.New("skip group")
+	// ErrStop is wrapped by errors of OnGroupStart that end the run
+	ErrStop = errors.New("stopped")
+)
+
+// ComponentError is a failure of OnComponent for one component
+type ComponentError struct {
+	Step   int
+	BOMRef string
+	Err    error
+}
+
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("step %d: %s: %v", e.Step, e.BOMRef, e.Err)
+}
+
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
+
+// Execute runs the components of each step in parallel, step by step, and
+// returns every failure joined; component failures are *ComponentError
+func (p *Plan) Execute(ctx context.Context, opts ExecuteOptions) error {
+	var errs []error
+	for _, step := range p.Steps {
+		if err := ctx.Err(); err != nil {
+			// A step cut short by the cancellation has already reported it
+			if !errors.Is(errors.Join(errs...), err) {
+				errs = append(errs, err)
+			}
+			break
+		}
+
+		stepErr := p.executeStep(ctx, step, opts)
+		if opts.OnGroupEnd != nil {
+			opts.OnGroupEnd(ctx, step, stepErr)
+		}
+		if stepErr != nil {
+			errs = append(errs, stepErr)
+			if !opts.ContinueOnError || errors.Is(stepErr, ErrStop) {
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// executeStep runs the components of one step and returns their joined
+// errors, followed by ctx.Err() when the cancellation left components of the
+// step unstarted
+func (p *Plan) executeStep(ctx context.Context, step Step, opts ExecuteOptions) error {
+	if opts.OnGroupStart != nil {
+		if err := opts.OnGroupStart(ctx, step); errors.Is(err, ErrSkipGroup) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("step %d: %w", step.Step, err)
+		}
+	}
+	if opts.OnComponent == nil {
+		return nil
+	}
+
+	limit := opts.MaxParallel
+	if limit <= 0 || limit > len(step.Components) {
+		limit = len(step.Components)
+	}
+	slots := make(chan struct{}, limit)
+
+	var (
+		mu        sync.Mutex
+		errs      []error
+		failed    bool
+		cancelled error
+		wg        sync.WaitGroup
+	)
+	for _, entry := range step.Components {
+		slots <- struct{}{}
+
+		mu.Lock()
+		stop := failed && !opts.ContinueOnError
+		mu.Unlock()
+		if stop || ctx.Err() != nil {
+			cancelled = ctx.Err()
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(entry Entry) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := opts.OnComponent(ctx, step, entry); err != nil {
+				mu.Lock()
+				failed = true
+				errs = append(errs, &ComponentError{Step: step.Step, BOMRef: entry.BOMRef, Err: err})
+				mu.Unlock()
+			}
+		}(entry)
+	}
+	wg.Wait()
+
+	// Report failures in plan order rather than completion order
+	order := make(map[string]int, len(step.Components))
+	for i, entry := range step.Components {
+		order[entry.BOMRef] = i
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return order[errs[i].(*ComponentError).BOMRef] < order[errs[j].(*ComponentError).BOMRef]
+	})
+	if cancelled != nil {
+		errs = append(errs, cancelled)
+	}
+	return errors.Join(errs...)
+}
+
+// ComponentErrors returns every *ComponentError in an error returned by
+// Execute, in the order the components appear in the plan
+func ComponentErrors(err error) []*ComponentError {
+	var found []*ComponentError
+	var walk func(error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case *ComponentError:
+			found = append(found, e)
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				walk(inner)
+			}
+		}
+	}
+	if err != nil {
+		walk(err)
+	}
+	return found
+}
+
+// ExecuteSummary sorts the components of a plan by what became of them in
+// Execute, each in plan order
+type ExecuteSummary struct {
+	Completed  []string
+	Failed     []string
+	NotStarted []string
+}
+
+// Summarize builds the summary of an Execute run from the refs whose
+// OnComponent returned nil and the error Execute returned
+func (p *Plan) Summarize(completed map[string]bool, err error) ExecuteSummary {
+	failed := make(map[string]bool)
+	for _, componentErr := range ComponentErrors(err) {
+		failed[componentErr.BOMRef] = true
+	}
+
+	var s ExecuteSummary
+	for _, step := range p.Steps {
+		for _, entry := range step.Components {
+			switch {
+			case completed[entry.BOMRef]:
+				s.Completed = append(s.Completed, entry.BOMRef)
+			case failed[entry.BOMRef]:
+				s.Failed = append(s.Failed, entry.BOMRef)
+			default:
+				s.NotStarted = append(s.NotStarted, entry.BOMRef)
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,288 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// executeRecorder records the callbacks Execute makes
+type executeRecorder struct {
+	mu     sync.Mutex
+	events []string
+	fail   map[string]bool
+}
+
+func (r *executeRecorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *executeRecorder) options() ExecuteOptions {
+	return ExecuteOptions{
+		OnGroupStart: func(ctx context.Context, step Step) error {
+			r.record(fmt.Sprintf("start %d", step.Step))
+			return nil
+		},
+		OnComponent: func(ctx context.Context, step Step, entry Entry) error {
+			r.record(entry.BOMRef)
+			if r.fail[entry.BOMRef] {
+				return errors.New("boom")
+			}
+			return nil
+		},
+		OnGroupEnd: func(ctx context.Context, step Step, err error) {
+			r.record(fmt.Sprintf("end %d ok=%t", step.Step, err == nil))
+		},
+		// One at a time keeps the recorded order deterministic
+		MaxParallel: 1,
+	}
+}
+
+func executePlan(t *testing.T) *Plan {
+	t.Helper()
+
+	p, err := New(buildGraph(t, testBOM()))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return p
+}
+
+func TestExecute(t *testing.T) {
+	r := &executeRecorder{}
+	if err := executePlan(t).Execute(context.Background(), r.options()); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	want := "start 1,auth,cache,db,end 1 ok=true,start 2,app,end 2 ok=true"
+	if got := strings.Join(r.events, ","); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestExecuteStopsOnError(t *testing.T) {
+	r := &executeRecorder{fail: map[string]bool{"auth": true}}
+	err := executePlan(t).Execute(context.Background(), r.options())
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	// cache and db never start, and neither does step 2
+	want := "start 1,auth,end 1 ok=false"
+	if got := strings.Join(r.events, ","); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	var componentErr *ComponentError
+	if !errors.As(err, &componentErr) {
+		t.Fatalf("Expected a *ComponentError, got %T", err)
+	}
+	if componentErr.BOMRef != "auth" || componentErr.Step != 1 {
+		t.Errorf("Expected auth in step 1 to fail, got %s in step %d", componentErr.BOMRef, componentErr.Step)
+	}
+	if err.Error() != "step 1: auth: boom" {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
+
+func TestExecuteContinueOnError(t *testing.T) {
+	r := &executeRecorder{fail: map[string]bool{"cache": true, "app": true}}
+	opts := r.options()
+	opts.ContinueOnError = true
+
+	err := executePlan(t).Execute(context.Background(), opts)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	want := "start 1,auth,cache,db,end 1 ok=false,start 2,app,end 2 ok=false"
+	if got := strings.Join(r.events, ","); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	failed := ComponentErrors(err)
+	if len(failed) != 2 || failed[0].BOMRef != "cache" || failed[1].BOMRef != "app" {
+		t.Errorf("Expected cache and app to fail, got %v", failed)
+	}
+}
+
+func TestExecuteGroupStartError(t *testing.T) {
+	r := &executeRecorder{}
+	opts := r.options()
+	opts.OnGroupStart = func(ctx context.Context, step Step) error {
+		return errors.New("no capacity")
+	}
+
+	err := executePlan(t).Execute(context.Background(), opts)
+	if err == nil || err.Error() != "step 1: no capacity" {
+		t.Fatalf("Expected the group start error, got %v", err)
+	}
+	if got := strings.Join(r.events, ","); got != "end 1 ok=false" {
+		t.Errorf("Expected no components to run, got %s", got)
+	}
+	if failed := ComponentErrors(err); len(failed) != 0 {
+		t.Errorf("Expected no component errors, got %v", failed)
+	}
+}
+
+func TestExecuteSkipAndStop(t *testing.T) {
+	r := &executeRecorder{}
+	opts := r.options()
+	opts.ContinueOnError = true
+	opts.OnGroupStart = func(ctx context.Context, step Step) error {
+		if step.Step == 1 {
+			return ErrSkipGroup
+		}
+		return fmt.Errorf("operator said no: %w", ErrStop)
+	}
+	steps := executePlan(t)
+	steps.Steps = append(steps.Steps, Step{Step: 3, Components: []Entry{{BOMRef: "late"}}})
+
+	err := steps.Execute(context.Background(), opts)
+	if !errors.Is(err, ErrStop) {
+		t.Fatalf("Expected ErrStop, got %v", err)
+	}
+	// Step 1 is skipped without failing; step 2 stops the run despite
+	// ContinueOnError, so step 3 never starts
+	if got := strings.Join(r.events, ","); got != "end 1 ok=true,end 2 ok=false" {
+		t.Errorf("Unexpected events %s", got)
+	}
+	if summary := steps.Summarize(nil, err); len(summary.NotStarted) != 5 {
+		t.Errorf("Expected every component not started, got %+v", summary)
+	}
+}
+
+func TestExecuteCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &executeRecorder{}
+	opts := r.options()
+	opts.OnComponent = func(ctx context.Context, step Step, entry Entry) error {
+		r.record(entry.BOMRef)
+		cancel()
+		return nil
+	}
+
+	err := executePlan(t).Execute(ctx, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	want := "start 1,auth,end 1 ok=false"
+	if got := strings.Join(r.events, ","); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestExecuteCancelledInLastStep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &executeRecorder{}
+	opts := r.options()
+	completed := make(map[string]bool)
+	opts.OnComponent = func(ctx context.Context, step Step, entry Entry) error {
+		r.record(entry.BOMRef)
+		completed[entry.BOMRef] = true
+		cancel()
+		return nil
+	}
+
+	// With no later step to notice the cancellation, the step itself must
+	// report that its other components never ran
+	p := &Plan{Steps: executePlan(t).Steps[:1]}
+	err := p.Execute(ctx, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if strings.Count(err.Error(), context.Canceled.Error()) != 1 {
+		t.Errorf("Expected the cancellation reported once, got %v", err)
+	}
+	if summary := p.Summarize(completed, err); !reflect.DeepEqual(summary.NotStarted, []string{"cache", "db"}) {
+		t.Errorf("Expected cache and db not started, got %+v", summary)
+	}
+}
+
+func TestExecuteMaxParallel(t *testing.T) {
+	tests := []struct {
+		maxParallel int
+		want        int
+	}{
+		{0, 3},
+		{2, 2},
+		{1, 1},
+	}
+
+	for _, tt := range tests {
+		var (
+			mu        sync.Mutex
+			running   int
+			peak      int
+			allQueued = make(chan struct{})
+			started   int
+		)
+		opts := ExecuteOptions{
+			MaxParallel: tt.maxParallel,
+			OnComponent: func(ctx context.Context, step Step, entry Entry) error {
+				mu.Lock()
+				running++
+				started++
+				if running > peak {
+					peak = running
+				}
+				// Hold every slot until the limit is reached or the step is
+				// fully started, so the peak is the real limit
+				if running == tt.want || started == len(step.Components) {
+					select {
+					case <-allQueued:
+					default:
+						close(allQueued)
+					}
+				}
+				mu.Unlock()
+
+				<-allQueued
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			},
+		}
+
+		p := &Plan{Steps: executePlan(t).Steps[:1]}
+		if err := p.Execute(context.Background(), opts); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if peak != tt.want {
+			t.Errorf("MaxParallel %d: expected %d components at once, got %d", tt.maxParallel, tt.want, peak)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	p := executePlan(t)
+	r := &executeRecorder{fail: map[string]bool{"cache": true}}
+	completed := make(map[string]bool)
+	opts := r.options()
+	onComponent := opts.OnComponent
+	opts.OnComponent = func(ctx context.Context, step Step, entry Entry) error {
+		if err := onComponent(ctx, step, entry); err != nil {
+			return err
+		}
+		completed[entry.BOMRef] = true
+		return nil
+	}
+
+	err := p.Execute(context.Background(), opts)
+	got := p.Summarize(completed, err)
+	want := ExecuteSummary{Completed: []string{"auth"}, Failed: []string{"cache"}, NotStarted: []string{"db", "app"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	if got := p.Summarize(map[string]bool{"auth": true, "cache": true, "db": true, "app": true}, nil); len(got.Failed)+len(got.NotStarted) != 0 {
+		t.Errorf("Expected every component to complete, got %+v", got)
+	}
+}
@@ -0,0 +1,152 @@
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LockVersion is the version of the lock format written by this build.
+// Locks with a higher version are rejected by LoadLock.
+const LockVersion = 1
+
+// Lock is a frozen plan: the JSON plan with the fingerprints of what it was
+// computed from, so it can be approved ahead of time and verified against
+// the SBOM before it is executed. A lock is also a valid plan for Load.
+type Lock struct {
+	LockVersion      int    `json:"lockVersion"`
+	GraphFingerprint string `json:"graphFingerprint"` // dag.Graph.Fingerprint of the planned graph
+	OptionsHash      string `json:"optionsHash"`      // OptionsHash of the options that shape the plan
+	Plan
+}
+
+// NewLock freezes p, computed from a graph with the given fingerprint and
+// options hash
+func NewLock(p *Plan, graphFingerprint, optionsHash string) *Lock {
+	return &Lock{LockVersion: LockVersion, GraphFingerprint: graphFingerprint, OptionsHash: optionsHash, Plan: *p}
+}
+
+// OptionsHash returns a digest of options, each a canonical description of a
+// setting that shapes the plan, as "sha256:" and the hex digest
+func OptionsHash(options ...string) string {
+	h := sha256.New()
+	for _, option := range options {
+		fmt.Fprintf(h, "%d:%s", len(option), option)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Write serializes the lock as indented JSON
+func (l *Lock) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(l)
+}
+
+// LoadLock reads a lock previously written by Write
+func LoadLock(r io.Reader) (*Lock, error) {
+	var l Lock
+	if err := json.NewDecoder(r).Decode(&l); err != nil {
+		return nil, fmt.Errorf("failed to decode lock: %w", err)
+	}
+
+	if l.LockVersion == 0 {
+		return nil, fmt.Errorf("missing lockVersion (not a bom-dagger lock?)")
+	}
+	if l.LockVersion > LockVersion {
+		return nil, fmt.Errorf("lockVersion %d is newer than supported version %d", l.LockVersion, LockVersion)
+	}
+	if l.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("plan schemaVersion %d is newer than supported version %d", l.SchemaVersion, SchemaVersion)
+	}
+	if l.GraphFingerprint == "" || l.OptionsHash == "" {
+		return nil, fmt.Errorf("lock has no fingerprints")
+	}
+
+	return &l, nil
+}
+
+// LoadLockFile reads a lock from a file path
+func LoadLockFile(filePath string) (*Lock, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock: %w", err)
+	}
+	defer file.Close()
+
+	return LoadLock(file)
+}
+
+// Drift lists how current differs from the frozen lock, one line per
+// difference, or nil when nothing drifted
+func (l *Lock) Drift(current *Lock) []string {
+	var lines []string
+	if l.OptionsHash != current.OptionsHash {
+		lines = append(lines, fmt.Sprintf("! options changed: %s -> %s", l.OptionsHash, current.OptionsHash))
+	}
+	if l.GraphFingerprint != current.GraphFingerprint {
+		lines = append(lines, fmt.Sprintf("! graph changed: %s -> %s", l.GraphFingerprint, current.GraphFingerprint))
+	}
+	lines = append(lines, l.inputDrift(current)...)
+
+	if diff := Compare(&l.Plan, &current.Plan); !diff.Empty() {
+		var b strings.Builder
+		diff.Write(&b)
+		for _, line := range strings.Split(strings.TrimRight(b.String(), "\n"), "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+
+	frozen, now := l.dependencies(), current.dependencies()
+	var edges []string
+	for edge := range now {
+		if !frozen[edge] {
+			edges = append(edges, "+ "+edge+" (dependency added)")
+		}
+	}
+	for edge := range frozen {
+		if !now[edge] {
+			edges = append(edges, "- "+edge+" (dependency removed)")
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i][2:] < edges[j][2:] })
+	return append(lines, edges...)
+}
+
+// inputDrift lists the input SBOMs whose digest differs from the one recorded
+// in the lock's provenance, matched in order. Inputs read without a digest
+// are not compared.
+func (l *Lock) inputDrift(current *Lock) []string {
+	if l.Provenance == nil || current.Provenance == nil {
+		return nil
+	}
+	frozen, now := l.Provenance.Inputs, current.Provenance.Inputs
+	if len(frozen) != len(now) {
+		return []string{fmt.Sprintf("! inputs changed: %d SBOMs -> %d", len(frozen), len(now))}
+	}
+	var lines []string
+	for i := range frozen {
+		if frozen[i].SHA256 != "" && now[i].SHA256 != "" && frozen[i].SHA256 != now[i].SHA256 {
+			lines = append(lines, fmt.Sprintf("! input %s changed: sha256:%s -> sha256:%s", now[i].Path, frozen[i].SHA256, now[i].SHA256))
+		}
+	}
+	return lines
+}
+
+// dependencies returns the edges of the frozen plan as "ref -> dependency"
+func (l *Lock) dependencies() map[string]bool {
+	edges := make(map[string]bool)
+	for _, step := range l.Steps {
+		for _, entry := range step.Components {
+			for _, dep := range entry.BlockedBy {
+				edges[entry.BOMRef+" -> "+dep] = true
+			}
+		}
+	}
+	return edges
+}
@@ -0,0 +1,121 @@
+package plan
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLockRoundTrip(t *testing.T) {
+	g := buildGraph(t, testBOM())
+	p, err := New(g)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	lock := NewLock(p, g.Fingerprint(), OptionsHash("runtime-only=false", "env="))
+
+	var buf bytes.Buffer
+	if err := lock.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	for _, want := range []string{`"lockVersion": 1`, `"graphFingerprint": "sha256:`, `"schemaVersion": 1`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %s in output, got: %s", want, buf.String())
+		}
+	}
+
+	// A lock is also a plan
+	asPlan, err := Load(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if diff := Compare(p, asPlan); !diff.Empty() {
+		t.Errorf("Expected the lock to load as the plan, got %+v", diff)
+	}
+
+	loaded, err := LoadLock(&buf)
+	if err != nil {
+		t.Fatalf("LoadLock failed: %v", err)
+	}
+	if loaded.GraphFingerprint != lock.GraphFingerprint || loaded.OptionsHash != lock.OptionsHash {
+		t.Errorf("Expected the fingerprints to round-trip, got %+v", loaded)
+	}
+	if drift := loaded.Drift(lock); drift != nil {
+		t.Errorf("Expected no drift, got %q", drift)
+	}
+}
+
+func TestLoadLock(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{"plan without fingerprints", `{"schemaVersion": 1, "steps": []}`, "missing lockVersion"},
+		{"newer lock version", `{"lockVersion": 2, "schemaVersion": 1, "steps": []}`, "lockVersion 2 is newer"},
+		{"newer schema version", `{"lockVersion": 1, "graphFingerprint": "sha256:a", "optionsHash": "sha256:b", "schemaVersion": 99}`, "schemaVersion 99 is newer"},
+		{"missing fingerprint", `{"lockVersion": 1, "optionsHash": "sha256:b", "schemaVersion": 1}`, "no fingerprints"},
+		{"malformed JSON", `{"lockVersion": `, "failed to decode lock"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadLock(strings.NewReader(tt.json)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLockDrift(t *testing.T) {
+	frozen := NewLock(&Plan{SchemaVersion: 1, Steps: []Step{
+		{Step: 1, Components: []Entry{{BOMRef: "db", Version: "15"}, {BOMRef: "cache", Version: "7"}}},
+		{Step: 2, Components: []Entry{{BOMRef: "app", Version: "1.0", BlockedBy: []string{"db", "cache"}}}},
+	}}, "sha256:graph", "sha256:options")
+
+	current := NewLock(&Plan{SchemaVersion: 1, Steps: []Step{
+		{Step: 1, Components: []Entry{{BOMRef: "db", Version: "16"}}},
+		{Step: 2, Components: []Entry{{BOMRef: "cache", Version: "7", BlockedBy: []string{"db"}}}},
+		{Step: 3, Components: []Entry{{BOMRef: "app", Version: "1.0", BlockedBy: []string{"cache"}}}},
+	}}, "sha256:other", "sha256:options")
+
+	want := []string{
+		"! graph changed: sha256:graph -> sha256:other",
+		"~ app: step 2 -> 3",
+		"~ cache: step 1 -> 2",
+		"~ db: version 15 -> 16",
+		"- app -> db (dependency removed)",
+		"+ cache -> db (dependency added)",
+	}
+	if got := frozen.Drift(current); !reflect.DeepEqual(got, want) {
+		t.Errorf("Drift() =\n%q\nwant\n%q", got, want)
+	}
+
+	current.OptionsHash = "sha256:changed"
+	if got := frozen.Drift(current); got[0] != "! options changed: sha256:options -> sha256:changed" {
+		t.Errorf("Expected the options change first, got %q", got)
+	}
+}
+
+func TestLockDriftInputs(t *testing.T) {
+	lock := func(digests ...string) *Lock {
+		provenance := &Provenance{Tool: "bom-dagger"}
+		for _, digest := range digests {
+			provenance.Inputs = append(provenance.Inputs, ProvenanceInput{Path: "sbom.json", SHA256: digest})
+		}
+		return NewLock(&Plan{SchemaVersion: 1, Provenance: provenance, Steps: []Step{}}, "sha256:graph", "sha256:options")
+	}
+
+	// The same plan from a different file is still drift
+	want := []string{"! input sbom.json changed: sha256:aaa -> sha256:bbb"}
+	if got := lock("aaa").Drift(lock("bbb")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Drift() = %q, want %q", got, want)
+	}
+	want = []string{"! inputs changed: 1 SBOMs -> 2"}
+	if got := lock("aaa").Drift(lock("aaa", "bbb")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Drift() = %q, want %q", got, want)
+	}
+	if got := lock("aaa").Drift(lock("")); got != nil {
+		t.Errorf("Expected inputs without a digest to be skipped, got %q", got)
+	}
+}
@@ -0,0 +1,89 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown renders the plan as a Markdown document with one section per
+// step, listing the smoke-test endpoints and release notes titles of its
+// entries, followed by the provenance when the plan has one
+func (p *Plan) WriteMarkdown(w io.Writer) error {
+	return p.writeMarkdown(w, "Deployment Plan")
+}
+
+// WriteTeardownMarkdown renders a teardown plan (see NewTeardown) like
+// WriteMarkdown, titled as one
+func (p *Plan) WriteTeardownMarkdown(w io.Writer) error {
+	return p.writeMarkdown(w, "Teardown Plan")
+}
+
+func (p *Plan) writeMarkdown(w io.Writer, title string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+
+	for _, step := range p.Steps {
+		fmt.Fprintf(&b, "\n## Step %d\n\n", step.Step)
+		b.WriteString("| Component | Version | Kind | Ref | Description |\n")
+		b.WriteString("|-----------|---------|------|-----|-------------|\n")
+		for _, entry := range step.Components {
+			fmt.Fprintf(&b, "| %s | %s | %s | `%s` | %s |\n",
+				markdownCell(entry.Name)+externalMarker(entry)+markdownLinks(entry), markdownCell(entry.Version), entry.Kind, entry.BOMRef,
+				markdownCell(entry.Description))
+		}
+
+		var endpoints []string
+		for _, entry := range step.Components {
+			for _, endpoint := range entry.Endpoints {
+				endpoints = append(endpoints, fmt.Sprintf("- %s: <%s>", markdownCell(entry.Name), endpoint))
+			}
+		}
+		if len(endpoints) > 0 {
+			b.WriteString("\nSmoke-test endpoints:\n\n")
+			b.WriteString(strings.Join(endpoints, "\n"))
+			b.WriteString("\n")
+		}
+
+		var releaseNotes []string
+		for _, entry := range step.Components {
+			if notes := entry.ReleaseNotes; notes != nil && notes.Title != "" {
+				releaseNotes = append(releaseNotes, fmt.Sprintf("- %s: %s (%s)", markdownCell(entry.Name), markdownCell(notes.Title), notes.Type))
+			}
+		}
+		if len(releaseNotes) > 0 {
+			b.WriteString("\nRelease notes:\n\n")
+			b.WriteString(strings.Join(releaseNotes, "\n"))
+			b.WriteString("\n")
+		}
+	}
+
+	if p.Provenance != nil {
+		p.Provenance.writeMarkdown(&b)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapes text for use inside a Markdown table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ", "\r", "").Replace(s)
+}
+
+// markdownLinks lists the entry's links after its name as autolinks
+func markdownLinks(entry Entry) string {
+	var b strings.Builder
+	for _, link := range entry.Links {
+		fmt.Fprintf(&b, " <%s>", markdownCell(link))
+	}
+	return b.String()
+}
+
+// externalMarker flags placeholder entries for refs the SBOM does not declare
+func externalMarker(entry Entry) string {
+	if entry.Synthetic {
+		return " (external)"
+	}
+	return ""
+}
@@ -0,0 +1,191 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// SchemaVersion is the version of the JSON plan format written by this build.
+// Plans with a higher schema version are rejected by Load.
+const SchemaVersion = 1
+
+// Plan is the serializable form of a deployment plan
+type Plan struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	Provenance    *Provenance `json:"provenance,omitempty"` // Set by the caller; ignored by comparisons
+	Stats         *Stats      `json:"stats,omitempty"`      // Set by the caller; ignored by comparisons
+	Steps         []Step      `json:"steps"`
+	Windows       []Window    `json:"windows,omitempty"` // Set by the caller (see SplitWindows); ignored by comparisons
+}
+
+// Step is a set of components that can be deployed in parallel
+type Step struct {
+	Step       int     `json:"step"`
+	Components []Entry `json:"components"`
+}
+
+// Entry is a single component or service within a step
+type Entry struct {
+	BOMRef  string `json:"ref"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind"`
+	Type    string `json:"type,omitempty"` // CycloneDX component type; empty for services
+
+	Sequence int `json:"sequence"` // 1-based position in the plan, distinct per component
+	Group    int `json:"group"`    // Deployment group (Kahn level), or teardown group in a NewTeardown plan; the step, unless the plan is Reversed
+
+	Description    string             `json:"description,omitempty"`      // Component or service description
+	Endpoints      []string           `json:"endpoints,omitempty"`        // Service endpoints for post-deploy smoke tests
+	Provider       *sbom.Supplier     `json:"provider,omitempty"`         // Organization providing the service
+	Publisher      string             `json:"publisher,omitempty"`        // Person or organization that published the component
+	ReleaseNotes   *sbom.ReleaseNotes `json:"releaseNotes,omitempty"`     // Notes on the release of this component version
+	Authenticated  *bool              `json:"authenticated,omitempty"`    // Whether the service requires authentication, when declared
+	XTrustBoundary *bool              `json:"x-trust-boundary,omitempty"` // Whether calling the service crosses a trust boundary, when declared
+	Completeness   string             `json:"completeness,omitempty"`     // Composition aggregate declared for the component
+	Synthetic      bool               `json:"synthetic,omitempty"`        // Placeholder for a ref the SBOM does not declare
+	Aliases        []string           `json:"aliases,omitempty"`          // Refs unified into this component by purl
+	Owner          string             `json:"owner,omitempty"`            // Team owning the component, or dag.Unowned
+	Priority       int                `json:"priority,omitempty"`         // bom-dagger:priority; lower is listed first within its step
+	BlockedBy      []string           `json:"blockedBy,omitempty"`        // Refs of the direct dependencies that must be deployed first
+	Links          []string           `json:"links,omitempty"`            // URLs of the external references of the graph's LinkType, e.g. runbooks
+	Duration       string             `json:"duration,omitempty"`         // Deploy duration, e.g. 5m0s, set by SetDurations for time-boxed plans
+}
+
+// New computes the deployment plan for a graph
+func New(g *dag.Graph) (*Plan, error) {
+	groups, err := g.GetDeploymentGroupNodes()
+	if err != nil {
+		return nil, err
+	}
+	return newPlan(g, groups), nil
+}
+
+// NewTeardown computes the teardown plan for a graph, one step per teardown
+// group; unlike New(g).Reverse(), nodes torn down together share a step
+func NewTeardown(g *dag.Graph) (*Plan, error) {
+	groups, err := g.GetTeardownGroupNodes()
+	if err != nil {
+		return nil, err
+	}
+	return newPlan(g, groups), nil
+}
+
+// newPlan makes a step of each group of nodes of g, keeping the order of
+// each group (see Graph.LessWithinLevel)
+func newPlan(g *dag.Graph, groups [][]*dag.Node) *Plan {
+	p := &Plan{SchemaVersion: SchemaVersion, Steps: make([]Step, 0, len(groups))}
+	for i, group := range groups {
+		step := Step{Step: i + 1, Components: make([]Entry, 0, len(group))}
+		for _, node := range group {
+			entry := newEntry(g, node)
+			entry.Group = step.Step
+			step.Components = append(step.Components, entry)
+		}
+		p.Steps = append(p.Steps, step)
+	}
+	p.number()
+	return p
+}
+
+// number sets the Sequence of every entry to its position in the plan
+func (p *Plan) number() {
+	sequence := 0
+	for i := range p.Steps {
+		for j := range p.Steps[i].Components {
+			sequence++
+			p.Steps[i].Components[j].Sequence = sequence
+		}
+	}
+}
+
+// newEntry describes node, a node of g, for a plan step
+func newEntry(g *dag.Graph, node *dag.Node) Entry {
+	entry := Entry{
+		BOMRef:       node.ID,
+		Name:         node.Name(),
+		Version:      node.Version(),
+		Kind:         node.Kind(),
+		Description:  node.Description(),
+		Completeness: node.Completeness,
+		Synthetic:    node.Synthetic,
+		Aliases:      node.Aliases,
+		Priority:     node.Priority(),
+		Owner:        node.Owner(g.OwnerProperty),
+		Links:        node.Links(g.LinkType),
+	}
+	if node.Component != nil {
+		entry.Type = node.Component.Type
+		entry.Publisher = node.Component.Publisher
+		entry.ReleaseNotes = node.Component.ReleaseNotes
+	}
+	if node.Service != nil {
+		entry.Endpoints = node.Service.Endpoints
+		entry.Provider = node.Service.Provider
+		entry.Authenticated = node.Service.Authenticated
+		entry.XTrustBoundary = node.Service.XTrustBoundary
+	}
+	for _, dep := range node.Dependencies {
+		entry.BlockedBy = append(entry.BlockedBy, dep.ID)
+	}
+	sort.Strings(entry.BlockedBy)
+	return entry
+}
+
+// Write serializes the plan as indented JSON
+func (p *Plan) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// Load reads a plan previously written by Write. Unknown fields are ignored so
+// plans written by newer releases with additive changes can still be read.
+func Load(r io.Reader) (*Plan, error) {
+	var p Plan
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to decode plan: %w", err)
+	}
+
+	if p.SchemaVersion == 0 {
+		return nil, fmt.Errorf("missing schemaVersion (not a bom-dagger plan?)")
+	}
+	if p.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("plan schemaVersion %d is newer than supported version %d", p.SchemaVersion, SchemaVersion)
+	}
+
+	return &p, nil
+}
+
+// LoadFile reads a plan from a file path
+func LoadFile(filePath string) (*Plan, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plan: %w", err)
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// index returns the entries of the plan keyed by bom-ref along with their step
+func (p *Plan) index() map[string]indexedEntry {
+	entries := make(map[string]indexedEntry)
+	for _, step := range p.Steps {
+		for _, entry := range step.Components {
+			entries[entry.BOMRef] = indexedEntry{Entry: entry, Step: step.Step}
+		}
+	}
+	return entries
+}
+
+type indexedEntry struct {
+	Entry
+	Step int
+}
@@ -0,0 +1,527 @@
+package plan
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func buildGraph(t *testing.T, bom *sbom.CycloneDX) *dag.Graph {
+	t.Helper()
+
+	componentMap := make(map[string]*sbom.Component)
+	for i := range bom.Components {
+		componentMap[bom.Components[i].BOMRef] = &bom.Components[i]
+	}
+
+	g := dag.New()
+	if err := g.BuildFromSBOM(bom, componentMap); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	return g
+}
+
+func testBOM() *sbom.CycloneDX {
+	return &sbom.CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.6",
+		Components: []sbom.Component{
+			{BOMRef: "app", Name: "App", Version: "1.0", Description: "Customer-facing web app"},
+			{BOMRef: "db", Name: "Database", Version: "15.0"},
+			{BOMRef: "cache", Name: "Cache", Version: "7.0"},
+		},
+		Services: []sbom.Service{
+			{BOMRef: "auth", Name: "Auth", Endpoints: []string{"https://auth.example.com/healthz"}},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "app", DependsOn: []string{"db", "cache", "auth"}},
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	p, err := New(buildGraph(t, testBOM()))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if p.SchemaVersion != SchemaVersion {
+		t.Errorf("Expected schemaVersion %d, got %d", SchemaVersion, p.SchemaVersion)
+	}
+	if len(p.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(p.Steps))
+	}
+
+	var refs []string
+	for _, entry := range p.Steps[0].Components {
+		refs = append(refs, entry.BOMRef)
+	}
+	if strings.Join(refs, ",") != "auth,cache,db" {
+		t.Errorf("Expected step 1 sorted by ref, got %v", refs)
+	}
+	if p.Steps[0].Components[0].Kind != "service" {
+		t.Errorf("Expected auth to be a service, got %s", p.Steps[0].Components[0].Kind)
+	}
+	if endpoints := p.Steps[0].Components[0].Endpoints; len(endpoints) != 1 || endpoints[0] != "https://auth.example.com/healthz" {
+		t.Errorf("Expected service endpoints in plan, got %v", endpoints)
+	}
+	if p.Steps[0].Components[1].Endpoints != nil {
+		t.Errorf("Expected no endpoints for components, got %v", p.Steps[0].Components[1].Endpoints)
+	}
+	if p.Steps[1].Components[0].Version != "1.0" {
+		t.Errorf("Expected app version 1.0, got %s", p.Steps[1].Components[0].Version)
+	}
+	if blockers := strings.Join(p.Steps[1].Components[0].BlockedBy, ","); blockers != "auth,cache,db" {
+		t.Errorf("Expected app to be blocked by its dependencies, got %s", blockers)
+	}
+	if p.Steps[0].Components[0].BlockedBy != nil {
+		t.Errorf("Expected no blockers for a root, got %v", p.Steps[0].Components[0].BlockedBy)
+	}
+}
+
+// assertSequence checks that the Sequence numbers of p are 1..N in plan
+// order and come after those of each entry's blockers
+func assertSequence(t *testing.T, p *Plan) {
+	t.Helper()
+	sequence := make(map[string]int)
+	n := 0
+	for _, step := range p.Steps {
+		for _, entry := range step.Components {
+			n++
+			if entry.Sequence != n {
+				t.Errorf("Expected %s to have sequence %d, got %d", entry.BOMRef, n, entry.Sequence)
+			}
+			sequence[entry.BOMRef] = entry.Sequence
+		}
+	}
+	for _, step := range p.Steps {
+		for _, entry := range step.Components {
+			for _, blocker := range entry.BlockedBy {
+				if sequence[blocker] >= entry.Sequence {
+					t.Errorf("Expected %s (%d) after its dependency %s (%d)", entry.BOMRef, entry.Sequence, blocker, sequence[blocker])
+				}
+			}
+		}
+	}
+}
+
+func TestNewNumbering(t *testing.T) {
+	p, err := New(buildGraph(t, testBOM()))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	assertSequence(t, p)
+	for _, step := range p.Steps {
+		for _, entry := range step.Components {
+			if entry.Group != step.Step {
+				t.Errorf("Expected %s in group %d, got %d", entry.BOMRef, step.Step, entry.Group)
+			}
+		}
+	}
+
+	// Sorting renumbers in the new order
+	assertSequence(t, p.SortWithinSteps(SortByName, nil))
+
+	// Teardown groups count from the first one torn down
+	reversed := p.Reverse()
+	var got []string
+	for _, step := range reversed.Steps {
+		entry := step.Components[0]
+		got = append(got, fmt.Sprintf("%d:%s:%d", entry.Sequence, entry.BOMRef, entry.Group))
+	}
+	if want := "1:app:1 2:auth:2 3:cache:2 4:db:2"; strings.Join(got, " ") != want {
+		t.Errorf("Expected teardown numbering %q, got %q", want, strings.Join(got, " "))
+	}
+
+	groups := p.ReverseGroups()
+	if first := groups.Steps[0].Components[0]; first.Sequence != 1 || first.Group != 2 {
+		t.Errorf("Expected app first with its group kept, got sequence %d, group %d", first.Sequence, first.Group)
+	}
+	if p.Steps[0].Components[0].Sequence != 1 {
+		t.Error("ReverseGroups modified the original plan")
+	}
+}
+
+func TestNewTeardown(t *testing.T) {
+	// worker deploys with api/v1 but nothing depends on it, so it goes in the
+	// first teardown group with web; reversing the plan would put it second
+	g := buildGraph(t, shellBOM())
+	p, err := NewTeardown(g)
+	if err != nil {
+		t.Fatalf("NewTeardown failed: %v", err)
+	}
+	var got []string
+	for _, step := range p.Steps {
+		for _, entry := range step.Components {
+			got = append(got, fmt.Sprintf("%d:%s:%d:%d", step.Step, entry.BOMRef, entry.Sequence, entry.Group))
+		}
+	}
+	if want := "1:web:1:1 1:worker:2:1 2:api/v1:3:2 3:db:4:3"; strings.Join(got, " ") != want {
+		t.Errorf("Expected teardown plan %q, got %q", want, strings.Join(got, " "))
+	}
+}
+
+func TestNewOrdersByPriority(t *testing.T) {
+	bom := testBOM()
+	bom.Components[1].Properties = []sbom.Property{{Name: dag.PropertyPriority, Value: "3"}}
+	bom.Components[2].Properties = []sbom.Property{{Name: dag.PropertyPriority, Value: "-1"}}
+
+	p, err := New(buildGraph(t, bom))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got := stepRefs(p.Steps[0]); got != "cache auth db" {
+		t.Errorf("Expected step 1 ordered by priority then name, got %s", got)
+	}
+	if p.Steps[0].Components[0].Priority != -1 {
+		t.Errorf("Expected cache to carry priority -1, got %d", p.Steps[0].Components[0].Priority)
+	}
+
+	// Priority ranks before the selected sort
+	if got := stepRefs(p.SortWithinSteps(SortByName, nil).Steps[0]); got != "cache auth db" {
+		t.Errorf("Expected priority to rank before name, got %s", got)
+	}
+}
+
+func TestNewKeepsGraphOrder(t *testing.T) {
+	bom := testBOM()
+	bom.Components[1].Name = "Alpha Database"
+
+	// By default ties are broken by name, not ref
+	p, err := New(buildGraph(t, bom))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got := stepRefs(p.Steps[0]); got != "db auth cache" {
+		t.Errorf("Expected step 1 ordered by name, got %s", got)
+	}
+
+	// A custom comparator orders the plan too
+	g := buildGraph(t, bom)
+	g.LessWithinLevel = func(a, b *dag.Node) bool { return a.ID > b.ID }
+	if p, err = New(g); err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got := stepRefs(p.Steps[0]); got != "db cache auth" {
+		t.Errorf("Expected step 1 ordered by the graph's comparator, got %s", got)
+	}
+	if p.Steps[0].Components[0].Sequence != 1 || p.Steps[1].Components[0].Sequence != 4 {
+		t.Errorf("Expected sequence numbers to follow the order, got %+v", p.Steps)
+	}
+}
+
+func TestWriteLoadRoundTrip(t *testing.T) {
+	p, err := New(buildGraph(t, testBOM()))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"schemaVersion": 1`) {
+		t.Errorf("Expected schemaVersion in output, got: %s", buf.String())
+	}
+
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if diff := Compare(p, loaded); !diff.Empty() {
+		t.Errorf("Expected round-tripped plan to match, got %+v", diff)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{
+			name: "unknown fields are ignored",
+			json: `{"schemaVersion": 1, "generator": "future", "steps": [
+				{"step": 1, "risk": "low", "components": [{"ref": "a", "name": "A", "kind": "component", "owner": "x"}]}
+			]}`,
+		},
+		{
+			name:    "missing schema version",
+			json:    `{"steps": []}`,
+			wantErr: "missing schemaVersion",
+		},
+		{
+			name:    "newer schema version",
+			json:    `{"schemaVersion": 99, "steps": []}`,
+			wantErr: "newer than supported",
+		},
+		{
+			name:    "malformed JSON",
+			json:    `{"schemaVersion": `,
+			wantErr: "failed to decode plan",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Load(strings.NewReader(tt.json))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(p.Steps) != 1 || p.Steps[0].Components[0].BOMRef != "a" {
+				t.Errorf("Unexpected plan: %+v", p)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := &Plan{SchemaVersion: 1, Steps: []Step{
+		{Step: 1, Components: []Entry{{BOMRef: "db", Version: "15"}, {BOMRef: "cache", Version: "7"}}},
+		{Step: 2, Components: []Entry{{BOMRef: "app", Version: "1.0"}}},
+	}}
+
+	t.Run("identical plans", func(t *testing.T) {
+		if diff := Compare(base, base); !diff.Empty() {
+			t.Errorf("Expected no differences, got %+v", diff)
+		}
+	})
+
+	t.Run("reordered within a step", func(t *testing.T) {
+		reordered := &Plan{SchemaVersion: 1, Steps: []Step{
+			{Step: 1, Components: []Entry{{BOMRef: "cache", Version: "7"}, {BOMRef: "db", Version: "15"}}},
+			{Step: 2, Components: []Entry{{BOMRef: "app", Version: "1.0"}}},
+		}}
+		if diff := Compare(base, reordered); !diff.Empty() {
+			t.Errorf("Expected intra-step reordering to match, got %+v", diff)
+		}
+	})
+
+	t.Run("step changes", func(t *testing.T) {
+		moved := &Plan{SchemaVersion: 1, Steps: []Step{
+			{Step: 1, Components: []Entry{{BOMRef: "db", Version: "15"}}},
+			{Step: 2, Components: []Entry{{BOMRef: "cache", Version: "7"}}},
+			{Step: 3, Components: []Entry{{BOMRef: "app", Version: "1.0"}}},
+		}}
+		diff := Compare(base, moved)
+		if len(diff.StepChanges) != 2 {
+			t.Fatalf("Expected 2 step changes, got %+v", diff.StepChanges)
+		}
+		if diff.StepChanges[0] != (StepChange{BOMRef: "app", OldStep: 2, NewStep: 3}) {
+			t.Errorf("Unexpected step change: %+v", diff.StepChanges[0])
+		}
+	})
+
+	t.Run("version drift", func(t *testing.T) {
+		drifted := &Plan{SchemaVersion: 1, Steps: []Step{
+			{Step: 1, Components: []Entry{{BOMRef: "db", Version: "16"}, {BOMRef: "cache", Version: "7"}}},
+			{Step: 2, Components: []Entry{{BOMRef: "app", Version: "1.0"}}},
+		}}
+		diff := Compare(base, drifted)
+		if diff.Empty() {
+			t.Fatal("Expected version drift to be reported")
+		}
+		if len(diff.VersionChanges) != 1 || diff.VersionChanges[0] != (VersionChange{BOMRef: "db", OldVersion: "15", NewVersion: "16"}) {
+			t.Errorf("Unexpected version changes: %+v", diff.VersionChanges)
+		}
+
+		var buf bytes.Buffer
+		diff.Write(&buf)
+		if !strings.Contains(buf.String(), "db: version 15 -> 16") {
+			t.Errorf("Expected version drift in report, got: %s", buf.String())
+		}
+	})
+
+	t.Run("added and removed", func(t *testing.T) {
+		changed := &Plan{SchemaVersion: 1, Steps: []Step{
+			{Step: 1, Components: []Entry{{BOMRef: "db", Version: "15"}, {BOMRef: "queue", Version: "3"}}},
+			{Step: 2, Components: []Entry{{BOMRef: "app", Version: "1.0"}}},
+		}}
+		diff := Compare(base, changed)
+		if len(diff.Added) != 1 || diff.Added[0] != "queue" {
+			t.Errorf("Expected queue added, got %v", diff.Added)
+		}
+		if len(diff.Removed) != 1 || diff.Removed[0] != "cache" {
+			t.Errorf("Expected cache removed, got %v", diff.Removed)
+		}
+	})
+}
+
+func TestCompareGroups(t *testing.T) {
+	base := &Plan{SchemaVersion: 1, Steps: []Step{
+		{Step: 1, Components: []Entry{{BOMRef: "db", Group: 1}, {BOMRef: "cache", Group: 1}}},
+		{Step: 2, Components: []Entry{{BOMRef: "api", Group: 2}, {BOMRef: "worker", Group: 2}}},
+		{Step: 3, Components: []Entry{{BOMRef: "app", Group: 3}, {BOMRef: "cron", Group: 3}}},
+	}}
+	changed := &Plan{SchemaVersion: 1, Steps: []Step{
+		{Step: 1, Components: []Entry{{BOMRef: "cache", Group: 1}, {BOMRef: "db", Group: 1}, {BOMRef: "queue", Group: 1}}},
+		{Step: 2, Components: []Entry{{BOMRef: "api", Group: 2}}},
+		{Step: 3, Components: []Entry{{BOMRef: "worker", Group: 3}, {BOMRef: "app", Group: 3}}},
+		{Step: 4, Components: []Entry{{BOMRef: "cron", Group: 4}, {BOMRef: "report", Group: 4}}},
+	}}
+
+	tests := []struct {
+		name    string
+		through int
+		want    []GroupMove
+		ignored int
+	}{
+		{
+			name:    "first group",
+			through: 1,
+			want:    []GroupMove{{BOMRef: "queue", NewGroup: 1}},
+			ignored: 3,
+		},
+		{
+			name:    "first two groups",
+			through: 2,
+			want:    []GroupMove{{BOMRef: "queue", NewGroup: 1}, {BOMRef: "worker", OldGroup: 2, NewGroup: 3}},
+			ignored: 2,
+		},
+		{
+			name: "every group",
+			want: []GroupMove{
+				{BOMRef: "queue", NewGroup: 1}, {BOMRef: "report", NewGroup: 4},
+				{BOMRef: "worker", OldGroup: 2, NewGroup: 3}, {BOMRef: "cron", OldGroup: 3, NewGroup: 4},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moves, ignored := CompareGroups(base, changed, tt.through)
+			if !reflect.DeepEqual(moves, tt.want) || ignored != tt.ignored {
+				t.Errorf("Expected %+v and %d ignored, got %+v and %d", tt.want, tt.ignored, moves, ignored)
+			}
+		})
+	}
+
+	// Plans without groups compare by step; order within a group is free
+	reordered := &Plan{SchemaVersion: 1, Steps: []Step{
+		{Step: 1, Components: []Entry{{BOMRef: "cache"}, {BOMRef: "db"}}},
+		{Step: 2, Components: []Entry{{BOMRef: "worker"}, {BOMRef: "api"}}},
+		{Step: 3, Components: []Entry{{BOMRef: "cron"}, {BOMRef: "app"}}},
+	}}
+	if moves, _ := CompareGroups(base, reordered, 0); len(moves) != 0 {
+		t.Errorf("Expected no moves, got %+v", moves)
+	}
+
+	for move, want := range map[GroupMove]string{
+		{BOMRef: "a", OldGroup: 1, NewGroup: 2}: "~ a: group 1 -> 2",
+		{BOMRef: "a", NewGroup: 2}:              "+ a: added in group 2",
+		{BOMRef: "a", OldGroup: 1}:              "- a: removed from group 1",
+	} {
+		if move.String() != want {
+			t.Errorf("Expected %q, got %q", want, move.String())
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	p, err := New(buildGraph(t, testBOM()))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	p.Steps[1].Components[0].Name = "App | Web"
+
+	var buf bytes.Buffer
+	if err := p.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"# Deployment Plan",
+		"## Step 1",
+		"| Auth |  | service | `auth` |",
+		"- Auth: <https://auth.example.com/healthz>",
+		"## Step 2",
+		`| App \| Web | 1.0 | component | ` + "`app`" + ` | Customer-facing web app |`,
+		"| Database | 15.0 | component | `db` |  |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown missing %q\nGot:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownReleaseNotes(t *testing.T) {
+	bom := testBOM()
+	bom.Components[0].ReleaseNotes = &sbom.ReleaseNotes{Type: "minor", Title: "Checkout | redesign", Description: "Not rendered"}
+	bom.Components[1].ReleaseNotes = &sbom.ReleaseNotes{Type: "patch"} // No title to show
+	p, err := New(buildGraph(t, bom))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := p.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "Release notes:\n\n- App: Checkout \\| redesign (minor)\n") {
+		t.Errorf("Expected the app's release notes title, got:\n%s", out)
+	}
+	if strings.Count(out, "Release notes:") != 1 || strings.Contains(out, "Not rendered") {
+		t.Errorf("Expected only titled release notes to be listed, got:\n%s", out)
+	}
+
+	p, err = New(buildGraph(t, testBOM()))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	buf.Reset()
+	if err := p.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "Release notes") {
+		t.Errorf("Expected no release notes section without release notes, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteMarkdownLinks(t *testing.T) {
+	bom := testBOM()
+	bom.Components[0].ExternalReferences = []sbom.ExternalReference{
+		{Type: "documentation", URL: "https://wiki.example.com/runbooks/app"},
+		{Type: "vcs", URL: "https://git.example.com/app"},
+		{Type: "website", URL: "https://app.example.com"},
+		{Type: "documentation", URL: "https://wiki.example.com/app/README"},
+	}
+	g := buildGraph(t, bom)
+
+	tests := []struct {
+		linkType string
+		want     string
+	}{
+		{"", "| App <https://wiki.example.com/runbooks/app> <https://wiki.example.com/app/README> | 1.0 |"},
+		{"vcs", "| App <https://git.example.com/app> | 1.0 |"},
+		{"website", "| App <https://app.example.com> | 1.0 |"},
+		{"issue-tracker", "| App | 1.0 |"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.linkType, func(t *testing.T) {
+			g.LinkType = tt.linkType
+			p, err := New(g)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := p.WriteMarkdown(&buf); err != nil {
+				t.Fatalf("WriteMarkdown failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("Markdown missing %q\nGot:\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
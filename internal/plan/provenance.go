@@ -0,0 +1,98 @@
+package plan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// Provenance records which tool produced a plan, from which SBOMs and when,
+// so a plan attached to a change ticket can be traced back to its inputs
+type Provenance struct {
+	Tool        string            `json:"tool"`
+	ToolVersion string            `json:"toolVersion"`
+	GeneratedAt string            `json:"generatedAt"` // RFC 3339, UTC
+	Inputs      []ProvenanceInput `json:"inputs"`
+	Env         string            `json:"env,omitempty"` // Deployment environment planned for (--env)
+}
+
+// ProvenanceInput describes one SBOM the plan was computed from
+type ProvenanceInput struct {
+	Path         string   `json:"path"`             // File path, or "stdin"
+	SHA256       string   `json:"sha256,omitempty"` // Hex digest of the file as read
+	SerialNumber string   `json:"serialNumber,omitempty"`
+	SpecVersion  string   `json:"specVersion,omitempty"`
+	Timestamp    string   `json:"timestamp,omitempty"`  // SBOM metadata timestamp
+	Tools        []string `json:"tools,omitempty"`      // SBOM generators as "vendor name version"
+	Lifecycles   []string `json:"lifecycles,omitempty"` // Lifecycle phases the SBOM describes, e.g. build
+}
+
+// NewProvenanceInput describes an SBOM read from path
+func NewProvenanceInput(path string, bom *sbom.CycloneDX) ProvenanceInput {
+	input := ProvenanceInput{Path: path, SerialNumber: bom.SerialNumber, SpecVersion: bom.SpecVersion}
+	if bom.Metadata != nil {
+		input.Timestamp = bom.Metadata.Timestamp
+		for _, tool := range bom.Metadata.Tools {
+			input.Tools = append(input.Tools, tool.String())
+		}
+		for _, lifecycle := range bom.Metadata.Lifecycles {
+			input.Lifecycles = append(input.Lifecycles, lifecycle.String())
+		}
+	}
+	return input
+}
+
+// GenerationTime returns override (RFC 3339), else sourceDateEpoch (Unix
+// seconds), else now, in UTC truncated to seconds
+func GenerationTime(override, sourceDateEpoch string, now time.Time) (time.Time, error) {
+	switch {
+	case override != "":
+		t, err := time.Parse(time.RFC3339, override)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q (expected RFC 3339, e.g. 2024-01-15T10:00:00Z)", override)
+		}
+		now = t
+	case sourceDateEpoch != "":
+		seconds, err := strconv.ParseInt(sourceDateEpoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q (expected Unix seconds)", sourceDateEpoch)
+		}
+		now = time.Unix(seconds, 0)
+	}
+	return now.UTC().Truncate(time.Second), nil
+}
+
+// writeMarkdown renders the provenance as a Markdown section
+func (pr *Provenance) writeMarkdown(b *strings.Builder) {
+	b.WriteString("\n## Provenance\n\n")
+	fmt.Fprintf(b, "- Generated by %s %s at %s\n", pr.Tool, pr.ToolVersion, pr.GeneratedAt)
+	if pr.Env != "" {
+		fmt.Fprintf(b, "- Planned for environment `%s`\n", pr.Env)
+	}
+	for _, input := range pr.Inputs {
+		fmt.Fprintf(b, "- Input `%s`", input.Path)
+		var details []string
+		if input.SerialNumber != "" {
+			details = append(details, "serial number "+input.SerialNumber)
+		}
+		if input.SpecVersion != "" {
+			details = append(details, "CycloneDX "+input.SpecVersion)
+		}
+		if input.Timestamp != "" {
+			details = append(details, "created "+input.Timestamp)
+		}
+		if len(input.Lifecycles) > 0 {
+			details = append(details, "lifecycle "+markdownCell(strings.Join(input.Lifecycles, " and ")))
+		}
+		if len(input.Tools) > 0 {
+			details = append(details, "by "+markdownCell(strings.Join(input.Tools, ", ")))
+		}
+		if len(details) > 0 {
+			b.WriteString(": " + strings.Join(details, ", "))
+		}
+		b.WriteString("\n")
+	}
+}
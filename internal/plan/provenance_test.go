@@ -0,0 +1,120 @@
+package plan
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestNewProvenanceInput(t *testing.T) {
+	bom := &sbom.CycloneDX{
+		SerialNumber: "urn:uuid:1234",
+		SpecVersion:  "1.6",
+		Metadata: &sbom.Metadata{
+			Timestamp:  "2024-01-15T10:00:00Z",
+			Tools:      []sbom.Tool{{Vendor: "acme", Name: "scanner", Version: "2.1"}, {Name: "syft"}},
+			Lifecycles: []sbom.Lifecycle{{Phase: "build"}, {Name: "staging"}},
+		},
+	}
+	got := NewProvenanceInput("sbom.json", bom)
+	want := ProvenanceInput{
+		Path:         "sbom.json",
+		SerialNumber: "urn:uuid:1234",
+		SpecVersion:  "1.6",
+		Timestamp:    "2024-01-15T10:00:00Z",
+		Tools:        []string{"acme scanner 2.1", "syft"},
+		Lifecycles:   []string{"build", "staging"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	if got := NewProvenanceInput("stdin", &sbom.CycloneDX{}); got.Path != "stdin" || got.Tools != nil {
+		t.Errorf("Expected a bare input without metadata, got %+v", got)
+	}
+}
+
+func TestGenerationTime(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 30, 15, 999, time.FixedZone("CET", 3600))
+	tests := []struct {
+		name     string
+		override string
+		epoch    string
+		want     string
+		wantErr  string
+	}{
+		{name: "clock", want: "2025-03-01T07:30:15Z"},
+		{name: "source date epoch", epoch: "1700000000", want: "2023-11-14T22:13:20Z"},
+		{name: "override wins", override: "2024-02-01T12:00:00+01:00", epoch: "1700000000", want: "2024-02-01T11:00:00Z"},
+		{name: "invalid override", override: "yesterday", wantErr: `invalid timestamp "yesterday"`},
+		{name: "invalid epoch", epoch: "soon", wantErr: `invalid SOURCE_DATE_EPOCH "soon"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerationTime(tt.override, tt.epoch, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerationTime failed: %v", err)
+			}
+			if got.Format(time.RFC3339Nano) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got.Format(time.RFC3339Nano))
+			}
+		})
+	}
+}
+
+func TestProvenanceOutputs(t *testing.T) {
+	p, err := New(buildGraph(t, testBOM()))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	p.Provenance = &Provenance{
+		Tool:        "bom-dagger",
+		ToolVersion: "1.2.3",
+		GeneratedAt: "2024-01-15T10:00:00Z",
+		Inputs:      []ProvenanceInput{{Path: "sbom.json", SerialNumber: "urn:uuid:1234", SpecVersion: "1.6"}},
+		Env:         "staging",
+	}
+
+	// Copies keep the provenance
+	if reversed := p.Reverse(); reversed.Provenance != p.Provenance {
+		t.Error("Expected Reverse to keep the provenance")
+	}
+
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Provenance, p.Provenance) {
+		t.Errorf("Expected provenance to round-trip, got %+v", loaded.Provenance)
+	}
+
+	buf.Reset()
+	if err := p.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	for _, want := range []string{
+		"## Provenance",
+		"- Generated by bom-dagger 1.2.3 at 2024-01-15T10:00:00Z",
+		"- Planned for environment `staging`\n",
+		"- Input `sbom.json`: serial number urn:uuid:1234, CycloneDX 1.6\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Markdown missing %q\nGot:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/plural"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// Kinds of change listed by a release report
+const (
+	ChangeAdded   = "added"
+	ChangeChanged = "changed"
+	ChangeRemoved = "removed"
+)
+
+// Change is a component or service that differs between the baseline SBOM
+// of a release report and the current graph
+type Change struct {
+	Ref      string `json:"ref"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Change   string `json:"change"`             // ChangeAdded, ChangeChanged or ChangeRemoved
+	Baseline string `json:"baseline,omitempty"` // Version in the baseline
+	Current  string `json:"current,omitempty"`  // Version now
+}
+
+// ReleaseReport is what changed since a baseline SBOM and what deploying it
+// takes: the changed and added components, and everything depending on them,
+// in plan order
+type ReleaseReport struct {
+	Changes    []Change      `json:"changes"`
+	Dependents int           `json:"dependents"` // Unchanged components redeployed for a change they depend on
+	Steps      []ReleaseStep `json:"steps"`
+}
+
+// ReleaseStep is a step of a release report's sub-plan
+type ReleaseStep struct {
+	Step       int            `json:"step"`
+	Components []ReleaseEntry `json:"components"`
+}
+
+// ReleaseEntry is a plan entry of a release report: a change, or a dependent
+// of one
+type ReleaseEntry struct {
+	Entry
+	Change string   `json:"change,omitempty"` // ChangeAdded or ChangeChanged; empty for dependents
+	Needs  []string `json:"needs,omitempty"`  // Refs of the changes a dependent depends on, directly or not
+}
+
+// NewReleaseReport compares baseline with g by bom-ref and keeps the steps of
+// p that deploy added or changed components, or their dependents
+func NewReleaseReport(p *Plan, g *dag.Graph, baseline *sbom.CycloneDX) (*ReleaseReport, error) {
+	before := baselineEntries(baseline)
+	report := &ReleaseReport{Changes: []Change{}, Steps: []ReleaseStep{}}
+	changed := make(map[string]string)
+	for _, node := range g.NodesSorted() {
+		if node.Synthetic {
+			continue
+		}
+		previous, existed := before[node.ID]
+		switch {
+		case !existed:
+			changed[node.ID] = ChangeAdded
+			report.Changes = append(report.Changes, Change{Ref: node.ID, Name: node.Name(), Kind: node.Kind(), Change: ChangeAdded, Current: node.Version()})
+		case previous.Baseline != node.Version():
+			changed[node.ID] = ChangeChanged
+			report.Changes = append(report.Changes, Change{Ref: node.ID, Name: node.Name(), Kind: node.Kind(), Change: ChangeChanged,
+				Baseline: previous.Baseline, Current: node.Version()})
+		}
+	}
+	for _, ref := range sortedKeys(before) {
+		if _, ok := g.Node(ref); !ok {
+			removed := before[ref]
+			removed.Change = ChangeRemoved
+			report.Changes = append(report.Changes, removed)
+		}
+	}
+
+	needs := make(map[string][]string)
+	for _, ref := range sortedKeys(changed) {
+		dependents, err := g.Neighborhood(ref, -1, dag.DirectionDependents)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range dependents[1:] {
+			if _, ok := changed[node.ID]; !ok {
+				needs[node.ID] = append(needs[node.ID], ref)
+			}
+		}
+	}
+	report.Dependents = len(needs)
+
+	for _, step := range p.Steps {
+		var entries []ReleaseEntry
+		for _, entry := range step.Components {
+			change, isChange := changed[entry.BOMRef]
+			if !isChange && needs[entry.BOMRef] == nil {
+				continue
+			}
+			entries = append(entries, ReleaseEntry{Entry: entry, Change: change, Needs: needs[entry.BOMRef]})
+		}
+		if len(entries) > 0 {
+			report.Steps = append(report.Steps, ReleaseStep{Step: len(report.Steps) + 1, Components: entries})
+		}
+	}
+	sequence := 0
+	for i := range report.Steps {
+		for j := range report.Steps[i].Components {
+			sequence++
+			report.Steps[i].Components[j].Sequence = sequence
+		}
+	}
+	return report, nil
+}
+
+// baselineEntries indexes the components, nested ones and the metadata
+// component included, and services of the baseline by bom-ref
+func baselineEntries(bom *sbom.CycloneDX) map[string]Change {
+	entries := make(map[string]Change)
+	var walk func(components []sbom.Component)
+	walk = func(components []sbom.Component) {
+		for _, c := range components {
+			if c.BOMRef != "" {
+				entries[c.BOMRef] = Change{Ref: c.BOMRef, Name: c.Name, Kind: "component", Baseline: c.Version}
+			}
+			walk(c.Components)
+		}
+	}
+	walk(bom.Components)
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		walk([]sbom.Component{*bom.Metadata.Component})
+	}
+	for _, s := range bom.Services {
+		if s.BOMRef != "" {
+			entries[s.BOMRef] = Change{Ref: s.BOMRef, Name: s.Name, Kind: "service", Baseline: s.Version}
+		}
+	}
+	return entries
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Write writes the report as indented JSON
+func (r *ReleaseReport) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown renders the report as release notes for operations: a
+// table of the changes, then the steps deploying them
+func (r *ReleaseReport) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Release Report\n\n")
+	if len(r.Changes) == 0 {
+		b.WriteString("No components changed since the baseline.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	b.WriteString("| Change | Component | Kind | Ref | Baseline | Current |\n")
+	b.WriteString("|--------|-----------|------|-----|----------|---------|\n")
+	deployed := 0
+	for _, change := range r.Changes {
+		fmt.Fprintf(&b, "| %s | %s | %s | `%s` | %s | %s |\n",
+			change.Change, markdownCell(change.Name), change.Kind, change.Ref, markdownCell(change.Baseline), markdownCell(change.Current))
+		if change.Change != ChangeRemoved {
+			deployed++
+		}
+	}
+
+	them := "them"
+	if deployed == 1 {
+		them = "it"
+	}
+	fmt.Fprintf(&b, "\n%d %s changed; deploying %s requires redeploying %d %s in this order.\n",
+		len(r.Changes), plural.Noun(len(r.Changes), "component"), them, r.Dependents, plural.Noun(r.Dependents, "dependent"))
+	for _, step := range r.Steps {
+		fmt.Fprintf(&b, "\n## Step %d\n\n", step.Step)
+		b.WriteString("| Component | Version | Kind | Ref | Reason |\n")
+		b.WriteString("|-----------|---------|------|-----|--------|\n")
+		for _, entry := range step.Components {
+			reason := entry.Change
+			if reason == "" {
+				reason = "depends on " + strings.Join(entry.Needs, ", ")
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | `%s` | %s |\n",
+				markdownCell(entry.Name)+externalMarker(entry.Entry), markdownCell(entry.Version), entry.Kind, entry.BOMRef, markdownCell(reason))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,150 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func releaseBOM() *sbom.CycloneDX {
+	return &sbom.CycloneDX{
+		Components: []sbom.Component{
+			{BOMRef: "db", Name: "Database", Version: "15.0"},
+			{BOMRef: "cache", Name: "Cache", Version: "7.0"},
+			{BOMRef: "api", Name: "API", Version: "2.0"},
+			{BOMRef: "worker", Name: "Worker", Version: "2.0"},
+			{BOMRef: "web", Name: "Web", Version: "2.0"},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "api", DependsOn: []string{"db", "cache"}},
+			{Ref: "worker", DependsOn: []string{"db"}},
+			{Ref: "web", DependsOn: []string{"api"}},
+		},
+	}
+}
+
+// releaseSteps builds the report of current against releaseBOM and returns
+// the refs of each step
+func releaseSteps(t *testing.T, current *sbom.CycloneDX) (*ReleaseReport, [][]string) {
+	t.Helper()
+	g := buildGraph(t, current)
+	p, err := New(g)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	report, err := NewReleaseReport(p, g, releaseBOM())
+	if err != nil {
+		t.Fatalf("NewReleaseReport failed: %v", err)
+	}
+	var steps [][]string
+	for i, step := range report.Steps {
+		if step.Step != i+1 {
+			t.Errorf("Expected step %d to be numbered %d, got %d", i, i+1, step.Step)
+		}
+		var refs []string
+		for _, entry := range step.Components {
+			refs = append(refs, entry.BOMRef)
+		}
+		steps = append(steps, refs)
+	}
+	return report, steps
+}
+
+func TestReleaseReportLeafChange(t *testing.T) {
+	current := releaseBOM()
+	current.Components[4].Version = "2.1"
+	report, steps := releaseSteps(t, current)
+
+	want := []Change{{Ref: "web", Name: "Web", Kind: "component", Change: ChangeChanged, Baseline: "2.0", Current: "2.1"}}
+	if !reflect.DeepEqual(report.Changes, want) {
+		t.Errorf("Expected changes %+v, got %+v", want, report.Changes)
+	}
+	if !reflect.DeepEqual(steps, [][]string{{"web"}}) || report.Dependents != 0 {
+		t.Errorf("Expected a single step deploying web, got %v with %d dependents", steps, report.Dependents)
+	}
+}
+
+func TestReleaseReportRootChange(t *testing.T) {
+	current := releaseBOM()
+	current.Components[0].Version = "16.0"
+	report, steps := releaseSteps(t, current)
+
+	// Everything but the cache depends on the database
+	want := [][]string{{"db"}, {"api", "worker"}, {"web"}}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("Expected steps %v, got %v", want, steps)
+	}
+	if report.Dependents != 3 {
+		t.Errorf("Expected 3 dependents, got %d", report.Dependents)
+	}
+	web := report.Steps[2].Components[0]
+	if web.Change != "" || !reflect.DeepEqual(web.Needs, []string{"db"}) || web.Sequence != 4 {
+		t.Errorf("Expected web to be the fourth entry, needing db, got %+v", web)
+	}
+}
+
+func TestReleaseReportAddedAndRemoved(t *testing.T) {
+	current := releaseBOM()
+	current.Components = append(current.Components[:1], current.Components[2:]...) // No cache
+	current.Components = append(current.Components, sbom.Component{BOMRef: "queue", Name: "Queue", Version: "3.0"})
+	current.Dependencies[0].DependsOn = []string{"db"}
+	current.Dependencies[1].DependsOn = []string{"db", "queue"}
+	report, steps := releaseSteps(t, current)
+
+	want := []Change{
+		{Ref: "queue", Name: "Queue", Kind: "component", Change: ChangeAdded, Current: "3.0"},
+		{Ref: "cache", Name: "Cache", Kind: "component", Change: ChangeRemoved, Baseline: "7.0"},
+	}
+	if !reflect.DeepEqual(report.Changes, want) {
+		t.Errorf("Expected changes %+v, got %+v", want, report.Changes)
+	}
+	if !reflect.DeepEqual(steps, [][]string{{"queue"}, {"worker"}}) {
+		t.Errorf("Expected the queue and the worker, got %v", steps)
+	}
+}
+
+func TestReleaseReportWrite(t *testing.T) {
+	current := releaseBOM()
+	current.Components[1].Version = "7.2"
+	report, _ := releaseSteps(t, current)
+
+	var md bytes.Buffer
+	if err := report.WriteMarkdown(&md); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	for _, want := range []string{
+		"| changed | Cache | component | `cache` | 7.0 | 7.2 |",
+		"1 component changed; deploying it requires redeploying 2 dependents in this order.",
+		"## Step 3\n",
+		"| Web | 2.0 | component | `web` | depends on cache |",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("Expected the Markdown report to contain %q, got:\n%s", want, md.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var decoded ReleaseReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected JSON: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, report) {
+		t.Errorf("Expected the JSON report to round-trip, got %+v", decoded)
+	}
+
+	unchanged, _ := releaseSteps(t, releaseBOM())
+	md.Reset()
+	if err := unchanged.WriteMarkdown(&md); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	if !strings.Contains(md.String(), "No components changed since the baseline.") {
+		t.Errorf("Expected an empty report, got:\n%s", md.String())
+	}
+}
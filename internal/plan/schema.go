@@ -0,0 +1,179 @@
+package plan
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema is the JSON Schema (draft 2020-12) of the plan format written by
+// Write, for consumers validating plans. It changes with SchemaVersion.
+//
+//go:embed plan.schema.json
+var Schema []byte
+
+// SchemaError is a place where a plan does not match Schema, located by a
+// JSON path such as $.steps[0].components[2].kind
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// SchemaErrors is every mismatch found by Validate
+type SchemaErrors []SchemaError
+
+func (errs SchemaErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = "  " + err.Error()
+	}
+	noun := "problems"
+	if len(errs) == 1 {
+		noun = "problem"
+	}
+	return fmt.Sprintf("plan does not match the schema, %d %s:\n%s", len(errs), noun, strings.Join(lines, "\n"))
+}
+
+// schema is the subset of JSON Schema that Schema uses: types, enums,
+// minimums, required and additional properties, arrays and local $refs
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Enum                 []any              `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"` // false or a schema
+	Items                *schema            `json:"items"`
+	Defs                 map[string]*schema `json:"$defs"`
+}
+
+var planSchema = func() *schema {
+	var s schema
+	if err := json.Unmarshal(Schema, &s); err != nil {
+		panic("plan: invalid embedded schema: " + err.Error())
+	}
+	return &s
+}()
+
+// Validate checks a JSON plan against Schema. It returns SchemaErrors listing
+// every mismatch, or an error when data is not JSON.
+func Validate(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("failed to decode plan: %w", err)
+	}
+
+	var errs SchemaErrors
+	planSchema.validate("$", value, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *schema) validate(path string, value any, errs *SchemaErrors) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Ref != "" {
+		def := planSchema.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		if def == nil {
+			fail("unresolved $ref %s", s.Ref)
+			return
+		}
+		s = def
+	}
+
+	if len(s.Enum) > 0 {
+		for _, allowed := range s.Enum {
+			if allowed == value {
+				return
+			}
+		}
+		fail("must be one of %v, got %v", s.Enum, value)
+		return
+	}
+
+	if s.Type != "" && jsonType(value) != s.Type && !(s.Type == "number" && jsonType(value) == "integer") {
+		fail("must be %s, got %s", s.Type, jsonType(value))
+		return
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Float64(); err == nil && s.Minimum != nil && n < *s.Minimum {
+			fail("must be at least %v, got %v", *s.Minimum, v)
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required field %q", name)
+			}
+		}
+		var additional *schema
+		closed := string(s.AdditionalProperties) == "false"
+		if len(s.AdditionalProperties) > 0 && !closed {
+			additional = new(schema)
+			if err := json.Unmarshal(s.AdditionalProperties, additional); err != nil {
+				fail("invalid additionalProperties in the schema: %v", err)
+				return
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fieldPath := path + "." + name
+			switch property, ok := s.Properties[name]; {
+			case ok:
+				property.validate(fieldPath, v[name], errs)
+			case closed:
+				*errs = append(*errs, SchemaError{Path: fieldPath, Message: "is not defined by the schema"})
+			case additional != nil:
+				additional.validate(fieldPath, v[name], errs)
+			}
+		}
+	}
+}
+
+// jsonType names the JSON Schema type of a value decoded with UseNumber
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
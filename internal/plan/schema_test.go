@@ -0,0 +1,197 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// fullPlan sets every field of the plan types, so a field added to them
+// without a schema change fails TestSchemaCoversPlan
+func fullPlan() *Plan {
+	yes, no := true, false
+	return &Plan{
+		SchemaVersion: SchemaVersion,
+		Provenance: &Provenance{
+			Tool:        "bom-dagger",
+			ToolVersion: "1.0.0",
+			GeneratedAt: "2024-06-01T12:00:00Z",
+			Inputs: []ProvenanceInput{{
+				Path:         "sbom.json",
+				SHA256:       "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+				SerialNumber: "urn:uuid:1",
+				SpecVersion:  "1.6",
+				Timestamp:    "2024-06-01T11:00:00Z",
+				Tools:        []string{"Anchore syft 1.4.1"},
+				Lifecycles:   []string{"build"},
+			}},
+			Env: "prod",
+		},
+		Stats: &Stats{
+			Components:        1,
+			Services:          1,
+			Nested:            1,
+			DuplicateServices: 1,
+			Dependencies:      1,
+			Inferred:          1,
+			Roots:             1,
+			Synthetic:         1,
+			Excluded:          1,
+			OtherEnvironment:  1,
+			Unreachable:       1,
+			Pruned:            1,
+			VersionConflicts:  1,
+			NoDependencies:    true,
+			Levels: []LevelStats{{
+				Step:       1,
+				Total:      2,
+				Components: 1,
+				Services:   1,
+				Ecosystems: map[string]int{"npm": 1, NoEcosystem: 1},
+			}},
+			Chokepoints: []dag.ChokepointReport{{Ref: "db", Name: "DB", Step: 1, Blocked: 1}},
+		},
+		Steps: []Step{{
+			Step: 1,
+			Components: []Entry{{
+				BOMRef:         "api",
+				Name:           "API",
+				Version:        "1.0",
+				Kind:           "service",
+				Type:           "application",
+				Sequence:       1,
+				Group:          1,
+				Description:    "The API",
+				Endpoints:      []string{"https://api.example.com"},
+				Provider:       &sbom.Supplier{Name: "Example", URL: []string{"https://example.com"}},
+				Publisher:      "Example Inc.",
+				ReleaseNotes:   &sbom.ReleaseNotes{Type: "minor", Title: "Spring release", Description: "Adds refunds"},
+				Authenticated:  &no,
+				XTrustBoundary: &yes,
+				Completeness:   "complete",
+				Synthetic:      true,
+				Aliases:        []string{"pkg:npm/api@1.0"},
+				Owner:          "payments-team",
+				Priority:       1,
+				BlockedBy:      []string{"db"},
+				Links:          []string{"https://wiki.example.com/runbooks/api"},
+				Duration:       "5m0s",
+			}},
+		}},
+		Windows: []Window{{Window: 1, Steps: []int{1}, Duration: "5m0s", Components: []string{"api"}, Oversized: true}},
+	}
+}
+
+// assertPopulated fails for every zero field reachable from v
+func assertPopulated(t *testing.T, path string, v reflect.Value) {
+	t.Helper()
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			t.Errorf("%s is nil", path)
+			return
+		}
+		assertPopulated(t, path, v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			assertPopulated(t, path+"."+v.Type().Field(i).Name, v.Field(i))
+		}
+	case reflect.Slice:
+		if v.Len() == 0 {
+			t.Errorf("%s is empty", path)
+		}
+		for i := 0; i < v.Len(); i++ {
+			assertPopulated(t, path, v.Index(i))
+		}
+	default:
+		if v.IsZero() && v.Kind() != reflect.Bool {
+			t.Errorf("%s is zero", path)
+		}
+	}
+}
+
+func TestSchemaCoversPlan(t *testing.T) {
+	p := fullPlan()
+	assertPopulated(t, "Plan", reflect.ValueOf(p))
+
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := Validate(buf.Bytes()); err != nil {
+		t.Errorf("Expected a fully populated plan to match the schema: %v", err)
+	}
+
+	// A minimal plan matches as well
+	g := buildGraph(t, testBOM())
+	minimal, err := New(g)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	buf.Reset()
+	if err := minimal.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := Validate(buf.Bytes()); err != nil {
+		t.Errorf("Expected the plan of the test BOM to match the schema: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want SchemaErrors
+	}{
+		{"empty plan", `{"schemaVersion": 1, "steps": []}`, nil},
+		{"missing steps", `{"schemaVersion": 1}`, SchemaErrors{{"$", `missing required field "steps"`}}},
+		{"unknown field", `{"schemaVersion": 1, "steps": [], "extra": true}`, SchemaErrors{{"$.extra", "is not defined by the schema"}}},
+		{"wrong type", `{"schemaVersion": "1", "steps": []}`, SchemaErrors{{"$.schemaVersion", "must be integer, got string"}}},
+		{"below minimum", `{"schemaVersion": 0, "steps": []}`, SchemaErrors{{"$.schemaVersion", "must be at least 1, got 0"}}},
+		{"bad entry", `{"schemaVersion": 1, "steps": [{"step": 1, "components": [{"ref": "a", "kind": "widget", "sequence": 1, "group": 1}]}]}`, SchemaErrors{
+			{"$.steps[0].components[0]", `missing required field "name"`},
+			{"$.steps[0].components[0].kind", "must be one of [component service], got widget"},
+		}},
+		{"bad ecosystem count", `{"schemaVersion": 1, "steps": [], "stats": {"components": 1, "dependencies": 0, "roots": 1,
+			"levels": [{"step": 1, "total": 1, "components": 1, "services": 0, "ecosystems": {"npm": "one"}}]}}`,
+			SchemaErrors{{"$.stats.levels[0].ecosystems.npm", "must be integer, got string"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(tt.json))
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			var errs SchemaErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Expected SchemaErrors, got %v", err)
+			}
+			if !reflect.DeepEqual(errs, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, errs)
+			}
+		})
+	}
+
+	if err := Validate([]byte(`{"schemaVersion": `)); err == nil || !strings.Contains(err.Error(), "failed to decode plan") {
+		t.Errorf("Expected a decode error, got %v", err)
+	}
+}
+
+func TestSchemaIsValidJSON(t *testing.T) {
+	var s map[string]any
+	if err := json.Unmarshal(Schema, &s); err != nil {
+		t.Fatalf("Schema is not JSON: %v", err)
+	}
+	if s["$schema"] != "https://json-schema.org/draft/2020-12/schema" {
+		t.Errorf("Unexpected $schema %v", s["$schema"])
+	}
+}
@@ -0,0 +1,161 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StepRange selects a contiguous range of steps. Last is 0 for an open-ended
+// range; the zero value selects every step.
+type StepRange struct {
+	First int
+	Last  int
+}
+
+// ParseStepRange parses "N", "N-M" or "N-" into a StepRange
+func ParseStepRange(s string) (StepRange, error) {
+	first, last, isRange := strings.Cut(strings.TrimSpace(s), "-")
+
+	var r StepRange
+	var err error
+	if r.First, err = strconv.Atoi(first); err != nil || r.First < 1 {
+		return StepRange{}, fmt.Errorf("invalid step range %q: start must be a positive integer", s)
+	}
+	switch {
+	case !isRange:
+		r.Last = r.First
+	case last == "":
+		// Open-ended
+	default:
+		if r.Last, err = strconv.Atoi(last); err != nil || r.Last < r.First {
+			return StepRange{}, fmt.Errorf("invalid step range %q: end must be an integer no smaller than the start", s)
+		}
+	}
+
+	return r, nil
+}
+
+// String formats the range in the syntax accepted by ParseStepRange
+func (r StepRange) String() string {
+	switch {
+	case r.Last == 0:
+		return fmt.Sprintf("%d-", r.First)
+	case r.First == r.Last:
+		return strconv.Itoa(r.First)
+	default:
+		return fmt.Sprintf("%d-%d", r.First, r.Last)
+	}
+}
+
+// Select returns a copy of the plan restricted to the steps in r and then to
+// the first top components (0 = no limit). Step numbers are preserved. The
+// zero StepRange selects every step, even of an empty plan.
+func (p *Plan) Select(r StepRange, top int) (*Plan, error) {
+	if top < 0 {
+		return nil, fmt.Errorf("invalid top %d: must not be negative", top)
+	}
+	if last := p.lastStep(); r != (StepRange{}) && last > 0 && (r.First > last || r.Last > last) {
+		return nil, fmt.Errorf("step range %s is beyond the %d steps in the plan", r, last)
+	}
+	if r.First == 0 {
+		r.First = 1
+	}
+
+	selected := &Plan{SchemaVersion: p.SchemaVersion, Provenance: p.Provenance, Stats: p.Stats, Steps: []Step{}}
+	remaining := top
+	for _, step := range p.Steps {
+		if step.Step < r.First || (r.Last != 0 && step.Step > r.Last) {
+			continue
+		}
+		components := step.Components
+		if top > 0 {
+			if remaining == 0 {
+				break
+			}
+			if len(components) > remaining {
+				components = components[:remaining]
+			}
+			remaining -= len(components)
+		}
+		selected.Steps = append(selected.Steps, Step{Step: step.Step, Components: components})
+	}
+
+	return selected, nil
+}
+
+// lastStep returns the number of the last step, or 0 for an empty plan.
+// Steps are numbered from 1, but ForOwner can leave gaps.
+func (p *Plan) lastStep() int {
+	if len(p.Steps) == 0 {
+		return 0
+	}
+	return p.Steps[len(p.Steps)-1].Step
+}
+
+// ForOwner returns a copy of the plan with only the components owned by owner
+// and the steps holding any of them. Step numbers are preserved, so a team
+// still sees when its turn comes.
+func (p *Plan) ForOwner(owner string) *Plan {
+	owned := &Plan{SchemaVersion: p.SchemaVersion, Provenance: p.Provenance, Stats: p.Stats, Steps: []Step{}}
+	for _, step := range p.Steps {
+		var components []Entry
+		for _, entry := range step.Components {
+			if entry.Owner == owner {
+				components = append(components, entry)
+			}
+		}
+		if len(components) > 0 {
+			owned.Steps = append(owned.Steps, Step{Step: step.Step, Components: components})
+		}
+	}
+	return owned
+}
+
+// Owners returns the owners of the components in the plan, sorted
+func (p *Plan) Owners() []string {
+	seen := make(map[string]bool)
+	var owners []string
+	for _, step := range p.Steps {
+		for _, entry := range step.Components {
+			if !seen[entry.Owner] {
+				seen[entry.Owner] = true
+				owners = append(owners, entry.Owner)
+			}
+		}
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// Reverse returns the teardown order of the plan: steps run last to first and
+// every component gets a step of its own. Groups are renumbered so that the
+// first group torn down is 1.
+func (p *Plan) Reverse() *Plan {
+	reversed := &Plan{SchemaVersion: p.SchemaVersion, Provenance: p.Provenance, Stats: p.Stats, Steps: []Step{}}
+	last := p.lastStep()
+	for i := len(p.Steps) - 1; i >= 0; i-- {
+		for _, entry := range p.Steps[i].Components {
+			entry.Group = last - p.Steps[i].Step + 1
+			reversed.Steps = append(reversed.Steps, Step{
+				Step:       len(reversed.Steps) + 1,
+				Components: []Entry{entry},
+			})
+		}
+	}
+	reversed.number()
+	return reversed
+}
+
+// ReverseGroups returns the plan with its steps in reverse order. Unlike
+// Reverse, the components of a step stay together so they can still be torn
+// down in parallel; step and group numbers are kept.
+func (p *Plan) ReverseGroups() *Plan {
+	reversed := &Plan{SchemaVersion: p.SchemaVersion, Provenance: p.Provenance, Stats: p.Stats, Steps: make([]Step, len(p.Steps))}
+	for i, step := range p.Steps {
+		reversed.Steps[len(p.Steps)-1-i] = Step{Step: step.Step, Components: append([]Entry(nil), step.Components...)}
+	}
+	reversed.number()
+	return reversed
+}
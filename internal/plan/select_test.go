@@ -0,0 +1,168 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStepRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    StepRange
+		wantErr bool
+	}{
+		{in: "2", want: StepRange{First: 2, Last: 2}},
+		{in: "1-3", want: StepRange{First: 1, Last: 3}},
+		{in: "4-", want: StepRange{First: 4}},
+		{in: "0", wantErr: true},
+		{in: "3-1", wantErr: true},
+		{in: "-2", wantErr: true},
+		{in: "a-b", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseStepRange(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %+v", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+			if got.String() != tt.in {
+				t.Errorf("Expected String() %q, got %q", tt.in, got.String())
+			}
+		})
+	}
+}
+
+func selectTestPlan() *Plan {
+	return &Plan{SchemaVersion: 1, Steps: []Step{
+		{Step: 1, Components: []Entry{{BOMRef: "cache"}, {BOMRef: "db"}}},
+		{Step: 2, Components: []Entry{{BOMRef: "api"}, {BOMRef: "worker"}}},
+		{Step: 3, Components: []Entry{{BOMRef: "web"}}},
+		{Step: 4, Components: []Entry{{BOMRef: "cdn"}}},
+	}}
+}
+
+func planRefs(p *Plan) string {
+	var steps []string
+	for _, step := range p.Steps {
+		var refs []string
+		for _, entry := range step.Components {
+			refs = append(refs, entry.BOMRef)
+		}
+		steps = append(steps, strings.Join(refs, ","))
+	}
+	return strings.Join(steps, " | ")
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   StepRange
+		top     int
+		want    string
+		wantErr string
+	}{
+		{name: "everything", want: "cache,db | api,worker | web | cdn"},
+		{name: "single step", steps: StepRange{First: 2, Last: 2}, want: "api,worker"},
+		{name: "closed range", steps: StepRange{First: 1, Last: 3}, want: "cache,db | api,worker | web"},
+		{name: "open-ended range", steps: StepRange{First: 3}, want: "web | cdn"},
+		{name: "top", top: 3, want: "cache,db | api"},
+		{name: "top within range", steps: StepRange{First: 2}, top: 1, want: "api"},
+		{name: "start beyond steps", steps: StepRange{First: 5}, wantErr: "beyond the 4 steps"},
+		{name: "end beyond steps", steps: StepRange{First: 2, Last: 9}, wantErr: "beyond the 4 steps"},
+		{name: "negative top", top: -1, wantErr: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectTestPlan().Select(tt.steps, tt.top)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if planRefs(got) != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, planRefs(got))
+			}
+		})
+	}
+
+	selected, _ := selectTestPlan().Select(StepRange{First: 3}, 0)
+	if selected.Steps[0].Step != 3 {
+		t.Errorf("Expected step numbers to be preserved, got %d", selected.Steps[0].Step)
+	}
+
+	// An empty plan has no steps to be beyond
+	empty := &Plan{SchemaVersion: 1, Steps: []Step{}}
+	for _, r := range []StepRange{{}, {First: 2}} {
+		if got, err := empty.Select(r, 0); err != nil || len(got.Steps) != 0 {
+			t.Errorf("Select(%v) of an empty plan = %v, %v; want no steps", r, got, err)
+		}
+	}
+}
+
+func TestForOwner(t *testing.T) {
+	p := &Plan{SchemaVersion: 1, Steps: []Step{
+		{Step: 1, Components: []Entry{{BOMRef: "cache", Owner: "web-team"}, {BOMRef: "db", Owner: "data-team"}}},
+		{Step: 2, Components: []Entry{{BOMRef: "api", Owner: "data-team"}, {BOMRef: "worker", Owner: "unowned"}}},
+		{Step: 3, Components: []Entry{{BOMRef: "web", Owner: "web-team"}}},
+	}}
+
+	owned := p.ForOwner("web-team")
+	if got := planRefs(owned); got != "cache | web" {
+		t.Errorf("Expected the web team's components, got %q", got)
+	}
+	if owned.Steps[1].Step != 3 {
+		t.Errorf("Expected step numbers to be preserved, got %d", owned.Steps[1].Step)
+	}
+	if got := planRefs(p); got != "cache,db | api,worker | web" {
+		t.Errorf("Expected the plan to be left unchanged, got %q", got)
+	}
+	if got := p.ForOwner("ops-team"); len(got.Steps) != 0 {
+		t.Errorf("Expected no steps for an unknown owner, got %q", planRefs(got))
+	}
+	if got := strings.Join(p.Owners(), ","); got != "data-team,unowned,web-team" {
+		t.Errorf("Expected the sorted owners, got %q", got)
+	}
+
+	// Ranges refer to the preserved step numbers
+	selected, err := owned.Select(StepRange{First: 3}, 0)
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if got := planRefs(selected); got != "web" {
+		t.Errorf("Expected step 3 of the owned plan, got %q", got)
+	}
+	if _, err := owned.Select(StepRange{First: 4}, 0); err == nil || !strings.Contains(err.Error(), "beyond the 3 steps") {
+		t.Errorf("Expected a range error, got %v", err)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	reversed := selectTestPlan().Reverse()
+	if got := planRefs(reversed); got != "cdn | web | api | worker | cache | db" {
+		t.Errorf("Unexpected teardown order: %q", got)
+	}
+
+	selected, err := reversed.Select(StepRange{First: 2, Last: 3}, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := planRefs(selected); got != "web | api" {
+		t.Errorf("Expected teardown steps 2-3, got %q", got)
+	}
+}
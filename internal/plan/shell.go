@@ -0,0 +1,105 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/ident"
+)
+
+// ShellOptions controls WriteShell
+type ShellOptions struct {
+	// Commands maps bom-refs to the shell command that deploys (or, with
+	// Teardown, removes) the component. Components without a command get a
+	// placeholder echo.
+	Commands map[string]string
+	// Teardown writes a teardown script; the plan is then a teardown plan
+	// (see NewTeardown), whose steps already run in teardown order
+	Teardown bool
+}
+
+// ShellCommands collects the value of a command property, such as
+// dag.PropertyDeployCommand, for every node that has one
+func ShellCommands(g *dag.Graph, property string) map[string]string {
+	commands := make(map[string]string)
+	for _, node := range g.NodesSorted() {
+		if command, ok := node.Property(property); ok && command != "" {
+			commands[node.ID] = command
+		}
+	}
+	return commands
+}
+
+const shellPrelude = `set -euo pipefail
+
+LOG_DIR="${LOG_DIR:-bom-dagger-logs}"
+mkdir -p "$LOG_DIR"
+
+# wait_group waits for every given job and fails if any of them failed
+wait_group() {
+  failed=0
+  for pid in "$@"; do
+    wait "$pid" || failed=1
+  done
+  return "$failed"
+}
+`
+
+// WriteShell renders the plan as a bash script running each step as
+// background jobs, logging each to its own file under $LOG_DIR
+func (p *Plan) WriteShell(w io.Writer, opts ShellOptions) error {
+	verb, placeholder := "Deploying", "deploy"
+	if opts.Teardown {
+		verb, placeholder = "Tearing down", "teardown"
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/usr/bin/env bash\n")
+	if opts.Teardown {
+		b.WriteString("# Teardown script generated by bom-dagger. Groups run in order;\n")
+	} else {
+		b.WriteString("# Deployment script generated by bom-dagger. Groups run in order;\n")
+	}
+	b.WriteString("# components within a group run in parallel.\n")
+	b.WriteString(shellPrelude)
+
+	logNames := ident.NewNamer(ident.Shell)
+	for _, step := range p.Steps {
+		fmt.Fprintf(&b, "\n# Group %d\n", step.Step)
+		fmt.Fprintf(&b, "echo %s\n", shellQuote(fmt.Sprintf("=== Group %d ===", step.Step)))
+		b.WriteString("pids=\"\"\n")
+		for _, entry := range step.Components {
+			label := singleLine(fmt.Sprintf("%s (ref: %s)", entry.Name, entry.BOMRef))
+			logFile := logNames.Name(entry.BOMRef) + ".log"
+
+			fmt.Fprintf(&b, "# %s\n", label)
+			fmt.Fprintf(&b, "echo %s\n", shellQuote(verb+" "+label))
+			command, ok := opts.Commands[entry.BOMRef]
+			if ok {
+				fmt.Fprintf(&b, "bash -c %s", shellQuote(command))
+			} else {
+				fmt.Fprintf(&b, "echo %s", shellQuote(fmt.Sprintf("TODO: %s %s", placeholder, label)))
+			}
+			fmt.Fprintf(&b, " >\"$LOG_DIR\"/%s 2>&1 &\n", shellQuote(logFile))
+			b.WriteString("pids=\"$pids $!\"\n")
+		}
+		b.WriteString("# shellcheck disable=SC2086\n")
+		fmt.Fprintf(&b, "wait_group $pids || { echo %s >&2; exit 1; }\n",
+			shellQuote(fmt.Sprintf("Group %d failed; see the logs in ", step.Step))+`"$LOG_DIR"`)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// shellQuote quotes s as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// singleLine replaces line breaks so s fits on one comment or output line
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,189 @@
+package plan
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+var update = flag.Bool("update", false, "Update golden files")
+
+// assertGolden compares got against testdata/golden/<name>, ignoring the
+// difference between LF and CRLF line endings, rewriting the file when
+// -update is set
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("..", "..", "testdata", "golden", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	// Checkouts with core.autocrlf turn the golden files' line endings into CRLF
+	if normalizeNewlines(string(got)) != normalizeNewlines(string(want)) {
+		t.Errorf("Output does not match %s\nGot:\n%s\nWant:\n%s", name, got, want)
+	}
+}
+
+// shellBOM has names that need quoting and a mix of components with and
+// without deploy commands
+func shellBOM() *sbom.CycloneDX {
+	return &sbom.CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.6",
+		Components: []sbom.Component{
+			{BOMRef: "db", Name: "Postgres DB", Properties: []sbom.Property{
+				{Name: dag.PropertyDeployCommand, Value: `echo "starting db" && echo 'db ready'`},
+				{Name: dag.PropertyTeardownCommand, Value: "echo stopping db"},
+			}},
+			{BOMRef: "api/v1", Name: `It's "the" API`},
+			{BOMRef: "worker", Name: "Worker $(rm -rf /)\nnext line"},
+			{BOMRef: "web", Name: "Web; exit 1"},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "api/v1", DependsOn: []string{"db"}},
+			{Ref: "worker", DependsOn: []string{"db"}},
+			{Ref: "web", DependsOn: []string{"api/v1"}},
+		},
+	}
+}
+
+func writeShell(t *testing.T, teardown bool) []byte {
+	t.Helper()
+
+	g := buildGraph(t, shellBOM())
+	build := New
+	if teardown {
+		build = NewTeardown
+	}
+	p, err := build(g)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	property := dag.PropertyDeployCommand
+	if teardown {
+		property = dag.PropertyTeardownCommand
+	}
+	var buf bytes.Buffer
+	if err := p.WriteShell(&buf, ShellOptions{Commands: ShellCommands(g, property), Teardown: teardown}); err != nil {
+		t.Fatalf("WriteShell failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteShell(t *testing.T) {
+	assertGolden(t, "shell-deploy.sh", writeShell(t, false))
+	assertGolden(t, "shell-teardown.sh", writeShell(t, true))
+}
+
+func TestWriteShellSyntax(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	for _, teardown := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "plan.sh")
+		if err := os.WriteFile(path, writeShell(t, teardown), 0o755); err != nil {
+			t.Fatalf("Failed to write script: %v", err)
+		}
+		if out, err := exec.Command(sh, "-n", path).CombinedOutput(); err != nil {
+			t.Errorf("sh -n failed (teardown=%v): %v\n%s", teardown, err, out)
+		}
+	}
+}
+
+func TestWriteShellRun(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deploy.sh")
+	if err := os.WriteFile(path, writeShell(t, false), 0o755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	logDir := filepath.Join(dir, "logs")
+	cmd := exec.Command(bash, path)
+	cmd.Env = append(os.Environ(), "LOG_DIR="+logDir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Script failed: %v\n%s", err, out)
+	}
+
+	// Groups run in dependency order
+	order := []string{"Deploying Postgres DB", `Deploying It's "the" API`, "Deploying Web; exit 1"}
+	last := -1
+	for _, want := range order {
+		i := strings.Index(string(out), want)
+		if i < 0 || i < last {
+			t.Errorf("Expected %q after the previous group, got:\n%s", want, out)
+		}
+		last = i
+	}
+
+	logs := map[string]string{
+		"db.log":     "starting db\ndb ready\n",
+		"api_v1.log": "TODO: deploy It's \"the\" API (ref: api/v1)\n",
+	}
+	for name, want := range logs {
+		got, err := os.ReadFile(filepath.Join(logDir, name))
+		if err != nil {
+			t.Errorf("Failed to read %s: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestWriteShellFailingGroup(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	p := &Plan{Steps: []Step{
+		{Step: 1, Components: []Entry{{BOMRef: "a", Name: "A"}, {BOMRef: "b", Name: "B"}}},
+		{Step: 2, Components: []Entry{{BOMRef: "c", Name: "C"}}},
+	}}
+	var buf bytes.Buffer
+	if err := p.WriteShell(&buf, ShellOptions{Commands: map[string]string{"a": "exit 3"}}); err != nil {
+		t.Fatalf("WriteShell failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	cmd := exec.Command(bash, "-c", buf.String())
+	cmd.Env = append(os.Environ(), "LOG_DIR="+dir)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("Expected the script to fail, got:\n%s", out)
+	}
+	if !strings.Contains(string(out), "Group 1 failed") || strings.Contains(string(out), "Deploying C") {
+		t.Errorf("Expected the script to stop after group 1, got:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.log")); err != nil {
+		t.Errorf("Expected the rest of group 1 to run: %v", err)
+	}
+}
+
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
@@ -0,0 +1,116 @@
+package plan
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// SortBy selects the presentation order of components within a step
+type SortBy string
+
+// Supported values for the --sort-within-group flag
+const (
+	SortByRef  SortBy = "ref"
+	SortByName SortBy = "name"
+	SortByType SortBy = "type"
+)
+
+// DefaultTypeOrder lists data stores first, then platform services, then
+// applications, which is the order operators usually want to watch them come up
+var DefaultTypeOrder = []string{"data", "platform", "container", "application", "library"}
+
+// ParseSortBy validates a --sort-within-group value
+func ParseSortBy(s string) (SortBy, error) {
+	switch SortBy(s) {
+	case SortByRef, SortByName, SortByType:
+		return SortBy(s), nil
+	}
+	return "", fmt.Errorf("unknown sort %q (expected type, name or ref)", s)
+}
+
+// ParseTypeOrder parses a comma-separated precedence list of component types
+// such as "data,platform,application". Services can be placed with "service".
+func ParseTypeOrder(s string) ([]string, error) {
+	var types []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			return nil, fmt.Errorf("invalid type order %q: empty type", s)
+		}
+		if seen[t] {
+			return nil, fmt.Errorf("invalid type order %q: %s is listed twice", s, t)
+		}
+		seen[t] = true
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// SortWithinSteps returns a copy of the plan with each step reordered by
+// priority, then by type rank in typeOrder with SortByType, then bom-ref
+func (p *Plan) SortWithinSteps(by SortBy, typeOrder []string) *Plan {
+	rank := make(map[string]int, len(typeOrder))
+	for i, t := range typeOrder {
+		rank[t] = i
+	}
+	typeRank := func(e Entry) int {
+		t := e.Type
+		if e.Kind == "service" {
+			t = "service"
+		}
+		if r, ok := rank[t]; ok {
+			return r
+		}
+		return len(typeOrder)
+	}
+
+	less := func(a, b Entry) bool {
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		switch by {
+		case SortByType:
+			if ra, rb := typeRank(a), typeRank(b); ra != rb {
+				return ra < rb
+			}
+		case SortByName:
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+		}
+		return a.BOMRef < b.BOMRef
+	}
+
+	sorted := &Plan{SchemaVersion: p.SchemaVersion, Provenance: p.Provenance, Stats: p.Stats, Steps: make([]Step, len(p.Steps))}
+	for i, step := range p.Steps {
+		components := append([]Entry(nil), step.Components...)
+		sort.SliceStable(components, func(a, b int) bool {
+			return less(components[a], components[b])
+		})
+		sorted.Steps[i] = Step{Step: step.Step, Components: components}
+	}
+	sorted.number()
+	return sorted
+}
+
+// ShuffleWithinSteps returns a copy of the plan with each step in a random
+// order drawn from seed; lower priorities still come first
+func (p *Plan) ShuffleWithinSteps(seed int64) *Plan {
+	r := rand.New(rand.NewSource(seed))
+	shuffled := &Plan{SchemaVersion: p.SchemaVersion, Provenance: p.Provenance, Stats: p.Stats, Steps: make([]Step, len(p.Steps))}
+	for i, step := range p.Steps {
+		components := append([]Entry(nil), step.Components...)
+		r.Shuffle(len(components), func(a, b int) {
+			components[a], components[b] = components[b], components[a]
+		})
+		sort.SliceStable(components, func(a, b int) bool {
+			return components[a].Priority < components[b].Priority
+		})
+		shuffled.Steps[i] = Step{Step: step.Step, Components: components}
+	}
+	shuffled.number()
+	return shuffled
+}
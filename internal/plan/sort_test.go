@@ -0,0 +1,134 @@
+package plan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mixedStepPlan() *Plan {
+	return &Plan{SchemaVersion: SchemaVersion, Steps: []Step{
+		{Step: 1, Components: []Entry{
+			{BOMRef: "a-lib", Name: "Zeta Lib", Kind: "component", Type: "library"},
+			{BOMRef: "b-app", Name: "Beta App", Kind: "component", Type: "application"},
+			{BOMRef: "c-db", Name: "Gamma DB", Kind: "component", Type: "data"},
+			{BOMRef: "d-svc", Name: "Alpha Service", Kind: "service"},
+			{BOMRef: "e-fw", Name: "Epsilon FW", Kind: "component", Type: "framework"},
+			{BOMRef: "f-db", Name: "Delta DB", Kind: "component", Type: "data"},
+		}},
+		{Step: 2, Components: []Entry{
+			{BOMRef: "g-app", Name: "App", Kind: "component", Type: "application"},
+		}},
+	}}
+}
+
+func stepRefs(step Step) string {
+	refs := make([]string, len(step.Components))
+	for i, entry := range step.Components {
+		refs[i] = entry.BOMRef
+	}
+	return strings.Join(refs, " ")
+}
+
+func TestSortWithinSteps(t *testing.T) {
+	tests := []struct {
+		name      string
+		by        SortBy
+		typeOrder []string
+		want      string
+	}{
+		{
+			name:      "default type order",
+			by:        SortByType,
+			typeOrder: DefaultTypeOrder,
+			want:      "c-db f-db b-app a-lib d-svc e-fw",
+		},
+		{
+			name:      "custom type order with services",
+			by:        SortByType,
+			typeOrder: []string{"service", "application"},
+			want:      "d-svc b-app a-lib c-db e-fw f-db",
+		},
+		{
+			name: "name",
+			by:   SortByName,
+			want: "d-svc b-app f-db e-fw c-db a-lib",
+		},
+		{
+			name: "ref",
+			by:   SortByRef,
+			want: "a-lib b-app c-db d-svc e-fw f-db",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := mixedStepPlan()
+			sorted := original.SortWithinSteps(tt.by, tt.typeOrder)
+
+			if got := stepRefs(sorted.Steps[0]); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+			if len(sorted.Steps) != 2 || sorted.Steps[1].Step != 2 || stepRefs(sorted.Steps[1]) != "g-app" {
+				t.Errorf("Expected step membership to be unchanged, got %+v", sorted.Steps)
+			}
+			if !reflect.DeepEqual(original, mixedStepPlan()) {
+				t.Error("SortWithinSteps modified the original plan")
+			}
+		})
+	}
+}
+
+func TestParseSortByAndTypeOrder(t *testing.T) {
+	for _, s := range []string{"type", "name", "ref"} {
+		if _, err := ParseSortBy(s); err != nil {
+			t.Errorf("ParseSortBy(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseSortBy("size"); err == nil {
+		t.Error("Expected error for unknown sort")
+	}
+
+	types, err := ParseTypeOrder("data, service,application")
+	if err != nil {
+		t.Fatalf("ParseTypeOrder failed: %v", err)
+	}
+	if !reflect.DeepEqual(types, []string{"data", "service", "application"}) {
+		t.Errorf("Unexpected types: %v", types)
+	}
+	for _, bad := range []string{"", "data,,library", "data,data"} {
+		if _, err := ParseTypeOrder(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestShuffleWithinSteps(t *testing.T) {
+	original := mixedStepPlan()
+	original.Steps[0].Components[5].Priority = -1 // f-db
+
+	orders := make(map[string]bool)
+	for seed := int64(0); seed < 20; seed++ {
+		shuffled := original.ShuffleWithinSteps(seed)
+		got := stepRefs(shuffled.Steps[0])
+		if again := stepRefs(original.ShuffleWithinSteps(seed).Steps[0]); again != got {
+			t.Errorf("Seed %d: expected the same order twice, got %q and %q", seed, got, again)
+		}
+		if !strings.HasPrefix(got, "f-db ") {
+			t.Errorf("Seed %d: expected the lower priority first, got %q", seed, got)
+		}
+		if len(shuffled.Steps) != 2 || stepRefs(shuffled.Steps[1]) != "g-app" || shuffled.Steps[1].Components[0].Sequence != 7 {
+			t.Errorf("Seed %d: expected step membership to be unchanged, got %+v", seed, shuffled.Steps)
+		}
+		orders[got] = true
+	}
+	if len(orders) < 5 {
+		t.Errorf("Expected seeds to give different orders, got %v", orders)
+	}
+
+	want := mixedStepPlan()
+	want.Steps[0].Components[5].Priority = -1
+	if !reflect.DeepEqual(original, want) {
+		t.Error("ShuffleWithinSteps modified the original plan")
+	}
+}
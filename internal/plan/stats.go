@@ -0,0 +1,82 @@
+package plan
+
+import (
+	"github.com/nprimmer/bom-dagger/internal/dag"
+)
+
+// Stats summarizes the graph a plan was computed from, for sizing the
+// deployment runners of each step. It is written by -o json with --stats.
+type Stats struct {
+	Components        int          `json:"components"` // Component nodes; services are counted apart
+	Services          int          `json:"services"`
+	Nested            int          `json:"nested,omitempty"`            // Components declared inside another component
+	DuplicateServices int          `json:"duplicateServices,omitempty"` // Services sharing a bom-ref with a component or another service, planned once
+	Dependencies      int          `json:"dependencies"`
+	Inferred          int          `json:"inferred,omitempty"` // Dependencies inferred from nesting or compositions
+	Roots             int          `json:"roots"`
+	Synthetic         int          `json:"synthetic,omitempty"`        // External placeholders for unresolved refs
+	Excluded          int          `json:"excluded,omitempty"`         // Components of scope excluded, see --include-excluded
+	OtherEnvironment  int          `json:"otherEnvironment,omitempty"` // Components whose bom-dagger:envs does not list the --env
+	Unreachable       int          `json:"unreachable,omitempty"`      // Components dropped by --reachable-only
+	Pruned            int          `json:"pruned,omitempty"`           // Leaves dropped by --prune-leaves-of-type
+	VersionConflicts  int          `json:"versionConflicts,omitempty"` // Components present at several versions
+	NoDependencies    bool         `json:"noDependencies,omitempty"`   // Several components but no edge: the SBOM lacks dependency data
+	Levels            []LevelStats `json:"levels"`
+
+	// Chokepoints are the nodes blocking the most of the plan, set with
+	// --chokepoints
+	Chokepoints []dag.ChokepointReport `json:"chokepoints,omitempty"`
+}
+
+// LevelStats is the width of one deployment step
+type LevelStats struct {
+	Step       int            `json:"step"`
+	Total      int            `json:"total"`
+	Components int            `json:"components"`
+	Services   int            `json:"services"`
+	Ecosystems map[string]int `json:"ecosystems,omitempty"` // Nodes per purl type; "none" counts nodes without a purl
+}
+
+// NoEcosystem is the ecosystem reported for nodes without a purl
+const NoEcosystem = "none"
+
+// NewStats computes the statistics of a graph. Ecosystems are only broken
+// down when byEcosystem is set.
+func NewStats(g *dag.Graph, byEcosystem bool) (*Stats, error) {
+	levels, err := g.LevelBreakdown()
+	if err != nil {
+		return nil, err
+	}
+	counts := g.NodeCounts()
+	stats := &Stats{
+		Components:        counts.Components,
+		Services:          counts.Services,
+		Nested:            counts.Nested,
+		DuplicateServices: counts.DuplicateServices,
+		Dependencies:      g.GetEdgeCount(),
+		Inferred:          g.GetInferredEdgeCount(),
+		Roots:             len(g.Roots),
+		Synthetic:         g.SyntheticCount(),
+		Excluded:          g.ExcludedCount(),
+		OtherEnvironment:  g.EnvExcludedCount(),
+		Levels:            make([]LevelStats, len(levels)),
+	}
+	for i, level := range levels {
+		stats.Levels[i] = LevelStats{
+			Step:       level.Level,
+			Total:      level.Total(),
+			Components: level.Components,
+			Services:   level.Services,
+		}
+		if byEcosystem {
+			stats.Levels[i].Ecosystems = make(map[string]int, len(level.Ecosystems))
+			for ecosystem, count := range level.Ecosystems {
+				if ecosystem == "" {
+					ecosystem = NoEcosystem
+				}
+				stats.Levels[i].Ecosystems[ecosystem] += count
+			}
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,47 @@
+package plan
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+func TestNewStats(t *testing.T) {
+	bom := testBOM()
+	bom.Components[1].Purl = "pkg:docker/library/postgres@15"
+	g := buildGraph(t, bom)
+
+	stats, err := NewStats(g, false)
+	if err != nil {
+		t.Fatalf("NewStats failed: %v", err)
+	}
+	want := &Stats{Components: 3, Services: 1, Dependencies: 3, Roots: 3, Levels: []LevelStats{
+		{Step: 1, Total: 3, Components: 2, Services: 1},
+		{Step: 2, Total: 1, Components: 1},
+	}}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("NewStats() = %+v, want %+v", stats, want)
+	}
+
+	stats, err = NewStats(g, true)
+	if err != nil {
+		t.Fatalf("NewStats failed: %v", err)
+	}
+	if got, want := stats.Levels[0].Ecosystems, map[string]int{"docker": 1, NoEcosystem: 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Step 1 ecosystems = %v, want %v", got, want)
+	}
+	if got, want := stats.Levels[1].Ecosystems, map[string]int{NoEcosystem: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Step 2 ecosystems = %v, want %v", got, want)
+	}
+}
+
+func TestNewStatsEmpty(t *testing.T) {
+	stats, err := NewStats(buildGraph(t, &sbom.CycloneDX{BOMFormat: "CycloneDX", SpecVersion: "1.6"}), true)
+	if err != nil {
+		t.Fatalf("NewStats failed: %v", err)
+	}
+	if stats.Components != 0 || len(stats.Levels) != 0 {
+		t.Errorf("Expected empty statistics, got %+v", stats)
+	}
+}
@@ -0,0 +1,75 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Window is a maintenance window of a time-boxed plan: consecutive steps
+// whose components fit in one window. It is written by -o json with --window.
+type Window struct {
+	Window     int      `json:"window"`
+	Steps      []int    `json:"steps"`
+	Duration   string   `json:"duration"`            // Sum of the costs of its steps (see SplitWindows)
+	Components []string `json:"components"`          // Refs deployed in the window, in plan order
+	Oversized  bool     `json:"oversized,omitempty"` // A single step longer than a window, which cannot fit
+}
+
+// SetDurations sets the Duration of every entry to durations(ref), so the
+// plan can be split into windows
+func (p *Plan) SetDurations(durations func(ref string) time.Duration) {
+	for i := range p.Steps {
+		for j := range p.Steps[i].Components {
+			entry := &p.Steps[i].Components[j]
+			entry.Duration = durations(entry.BOMRef).String()
+		}
+	}
+}
+
+// StepCost returns how long step takes when its components deploy in
+// parallel: the longest Duration among them. Entries without a duration take
+// no time.
+func StepCost(step Step) (time.Duration, error) {
+	var cost time.Duration
+	for _, entry := range step.Components {
+		if entry.Duration == "" {
+			continue
+		}
+		d, err := time.ParseDuration(entry.Duration)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q of %s: %w", entry.Duration, entry.BOMRef, err)
+		}
+		cost = max(cost, d)
+	}
+	return cost, nil
+}
+
+// SplitWindows packs the steps in order into as few windows of length d as
+// possible; a step longer than d gets its own, marked Oversized
+func (p *Plan) SplitWindows(d time.Duration) ([]Window, error) {
+	if d <= 0 {
+		return nil, errors.New("the window length must be positive")
+	}
+	var windows []Window
+	var used time.Duration
+	for _, step := range p.Steps {
+		cost, err := StepCost(step)
+		if err != nil {
+			return nil, err
+		}
+		if len(windows) == 0 || used+cost > d || windows[len(windows)-1].Oversized || cost > d {
+			windows = append(windows, Window{Window: len(windows) + 1, Components: []string{}})
+			used = 0
+		}
+		window := &windows[len(windows)-1]
+		used += cost
+		window.Steps = append(window.Steps, step.Step)
+		window.Duration = used.String()
+		window.Oversized = cost > d
+		for _, entry := range step.Components {
+			window.Components = append(window.Components, entry.BOMRef)
+		}
+	}
+	return windows, nil
+}
@@ -0,0 +1,93 @@
+package plan
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// timedPlan returns a plan with one step per list of durations, with
+// components named after their step and position
+func timedPlan(steps ...[]string) *Plan {
+	p := &Plan{SchemaVersion: SchemaVersion}
+	for i, durations := range steps {
+		step := Step{Step: i + 1}
+		for j, d := range durations {
+			ref := string(rune('a'+i)) + string(rune('1'+j))
+			step.Components = append(step.Components, Entry{BOMRef: ref, Name: ref, Duration: d})
+		}
+		p.Steps = append(p.Steps, step)
+	}
+	return p
+}
+
+func TestSplitWindows(t *testing.T) {
+	tests := []struct {
+		name string
+		plan *Plan
+		want []Window
+	}{
+		{
+			name: "exact fit",
+			plan: timedPlan([]string{"30m", "1h"}, []string{"1h"}, []string{"2h"}),
+			want: []Window{
+				{Window: 1, Steps: []int{1, 2}, Duration: "2h0m0s", Components: []string{"a1", "a2", "b1"}},
+				{Window: 2, Steps: []int{3}, Duration: "2h0m0s", Components: []string{"c1"}},
+			},
+		},
+		{
+			name: "overflow by one group",
+			plan: timedPlan([]string{"1h"}, []string{"45m"}, []string{"30m", "20m"}, []string{"10m"}),
+			want: []Window{
+				{Window: 1, Steps: []int{1, 2}, Duration: "1h45m0s", Components: []string{"a1", "b1"}},
+				{Window: 2, Steps: []int{3, 4}, Duration: "40m0s", Components: []string{"c1", "c2", "d1"}},
+			},
+		},
+		{
+			name: "oversized group",
+			plan: timedPlan([]string{"30m"}, []string{"1h", "3h"}, []string{"15m"}, []string{""}),
+			want: []Window{
+				{Window: 1, Steps: []int{1}, Duration: "30m0s", Components: []string{"a1"}},
+				{Window: 2, Steps: []int{2}, Duration: "3h0m0s", Components: []string{"b1", "b2"}, Oversized: true},
+				{Window: 3, Steps: []int{3, 4}, Duration: "15m0s", Components: []string{"c1", "d1"}},
+			},
+		},
+		{
+			name: "empty plan",
+			plan: timedPlan(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows, err := tt.plan.SplitWindows(2 * time.Hour)
+			if err != nil {
+				t.Fatalf("SplitWindows failed: %v", err)
+			}
+			if !reflect.DeepEqual(windows, tt.want) {
+				t.Errorf("SplitWindows() = %+v, want %+v", windows, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitWindowsErrors(t *testing.T) {
+	if _, err := timedPlan([]string{"1h"}).SplitWindows(0); err == nil {
+		t.Error("Expected an error for a zero window")
+	}
+	if _, err := timedPlan([]string{"soon"}).SplitWindows(time.Hour); err == nil {
+		t.Error("Expected an error for an invalid duration")
+	}
+}
+
+func TestSetDurations(t *testing.T) {
+	p := timedPlan([]string{"", ""})
+	p.SetDurations(func(ref string) time.Duration {
+		if ref == "a1" {
+			return 90 * time.Second
+		}
+		return time.Minute
+	})
+	if got := []string{p.Steps[0].Components[0].Duration, p.Steps[0].Components[1].Duration}; !reflect.DeepEqual(got, []string{"1m30s", "1m0s"}) {
+		t.Errorf("Expected durations 1m30s and 1m0s, got %v", got)
+	}
+}
@@ -0,0 +1,10 @@
+// Package plural words the counts in messages and summaries
+package plural
+
+// Noun returns noun, with an s unless n is 1
+func Noun(n int, noun string) string {
+	if n == 1 {
+		return noun
+	}
+	return noun + "s"
+}
@@ -0,0 +1,19 @@
+package plural
+
+import "testing"
+
+func TestNoun(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "components"},
+		{1, "component"},
+		{2, "components"},
+	}
+	for _, tt := range tests {
+		if got := Noun(tt.n, "component"); got != tt.want {
+			t.Errorf("Noun(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
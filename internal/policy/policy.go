@@ -0,0 +1,241 @@
+// Package policy checks deny rules, such as required edges or group size
+// limits, against a dependency graph and its deployment groups
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/plural"
+)
+
+// Rule kinds
+const (
+	RequireEdge          = "require-edge"            // from must depend directly on to
+	ForbidEdge           = "forbid-edge"             // from must not depend directly on to
+	MaxGroupSize         = "max-group-size"          // No deployment group may hold more than max nodes
+	MustPrecede          = "must-precede"            // a must deploy in an earlier group than b
+	ForbiddenTypeInGroup = "forbidden-type-in-group" // No node of type may deploy in group
+)
+
+// Kinds lists every rule kind, in the order of the README
+var Kinds = []string{RequireEdge, ForbidEdge, MaxGroupSize, MustPrecede, ForbiddenTypeInGroup}
+
+// ruleFields are the fields of a rule besides its kind, as written in policy
+// files
+var ruleFields = []string{"from", "to", "a", "b", "type", "group", "max"}
+
+// Rule is one deny rule. Which fields apply depends on the kind; see
+// Validate.
+type Rule struct {
+	Rule  string `json:"rule"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+	A     string `json:"a,omitempty"`
+	B     string `json:"b,omitempty"`
+	Type  string `json:"type,omitempty"`  // CycloneDX component type, or "service"
+	Group int    `json:"group,omitempty"` // 1-based deployment group
+	Max   int    `json:"max,omitempty"`
+}
+
+// Policy is a list of rules kept next to the SBOM, so CI can reject plans
+// that break them
+type Policy struct {
+	Rules []Rule
+}
+
+// Finding is a violated rule
+type Finding struct {
+	Rule    int    // 1-based index of the rule in the policy
+	Kind    string // Rule kind, e.g. require-edge
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("rule %d (%s): %s", f.Rule, f.Kind, f.Message)
+}
+
+// Load reads a policy from a reader. Documents starting with "[" are
+// decoded as JSON, anything else as YAML. Every rule is validated.
+func Load(r io.Reader) (*Policy, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var rules []Rule
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&rules); err != nil {
+			return nil, fmt.Errorf("failed to decode policy: %w", err)
+		}
+	} else if rules, err = parseYAML(data); err != nil {
+		return nil, fmt.Errorf("failed to decode policy: %w", err)
+	}
+
+	p := &Policy{Rules: rules}
+	for i, rule := range p.Rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("policy rule %d: %w", i+1, err)
+		}
+	}
+	return p, nil
+}
+
+// LoadFile reads a policy from a file path
+func LoadFile(filePath string) (*Policy, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open policy: %w", err)
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// Validate checks that the rule is of a known kind and sets exactly the
+// fields its kind uses
+func (r Rule) Validate() error {
+	set := map[string]bool{
+		"from": r.From != "", "to": r.To != "", "a": r.A != "", "b": r.B != "",
+		"type": r.Type != "", "group": r.Group != 0, "max": r.Max != 0,
+	}
+	var fields []string
+	switch r.Rule {
+	case RequireEdge, ForbidEdge:
+		fields = []string{"from", "to"}
+	case MustPrecede:
+		fields = []string{"a", "b"}
+	case MaxGroupSize:
+		fields = []string{"max"}
+	case ForbiddenTypeInGroup:
+		fields = []string{"type", "group"}
+	case "":
+		return fmt.Errorf("missing rule kind (expected %s)", strings.Join(Kinds, ", "))
+	default:
+		return fmt.Errorf("unknown rule %q (expected %s)", r.Rule, strings.Join(Kinds, ", "))
+	}
+	for _, field := range fields {
+		if !set[field] {
+			return fmt.Errorf("%s needs %s", r.Rule, strings.Join(fields, " and "))
+		}
+		delete(set, field)
+	}
+	for _, field := range ruleFields {
+		if set[field] {
+			return fmt.Errorf("%s does not take %s", r.Rule, field)
+		}
+	}
+	if r.Group < 0 || r.Max < 0 {
+		return fmt.Errorf("%s needs a positive number", r.Rule)
+	}
+	return nil
+}
+
+// Evaluate checks every rule against g and its deployment groups and returns
+// the violations, in rule order
+func (p *Policy) Evaluate(g *dag.Graph) ([]Finding, error) {
+	levels, err := g.GetDeploymentGroupNodes()
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]int, len(g.Nodes))
+	for i, level := range levels {
+		for _, node := range level {
+			groups[node.ID] = i + 1
+		}
+	}
+
+	var findings []Finding
+	for i, rule := range p.Rules {
+		for _, message := range rule.evaluate(g, levels, groups) {
+			findings = append(findings, Finding{Rule: i + 1, Kind: rule.Rule, Message: message})
+		}
+	}
+	return findings, nil
+}
+
+// evaluate returns a message per violation of the rule
+func (r Rule) evaluate(g *dag.Graph, levels [][]*dag.Node, groups map[string]int) []string {
+	switch r.Rule {
+	case RequireEdge:
+		if missing := missingRefs(g, r.From, r.To); len(missing) > 0 {
+			return missing
+		}
+		if node, _ := g.Node(r.From); !dependsOn(node, r.To) {
+			return []string{fmt.Sprintf("%s does not depend on %s", r.From, r.To)}
+		}
+	case ForbidEdge:
+		if node, ok := g.Node(r.From); ok && dependsOn(node, r.To) {
+			return []string{fmt.Sprintf("%s depends on %s", r.From, r.To)}
+		}
+	case MustPrecede:
+		if missing := missingRefs(g, r.A, r.B); len(missing) > 0 {
+			return missing
+		}
+		if groups[r.A] >= groups[r.B] {
+			return []string{fmt.Sprintf("%s deploys in group %d, not before %s in group %d", r.A, groups[r.A], r.B, groups[r.B])}
+		}
+	case MaxGroupSize:
+		var messages []string
+		for i, level := range levels {
+			if len(level) > r.Max {
+				messages = append(messages, fmt.Sprintf("group %d has %d components, more than %d", i+1, len(level), r.Max))
+			}
+		}
+		return messages
+	case ForbiddenTypeInGroup:
+		if r.Group > len(levels) {
+			return nil
+		}
+		var refs []string
+		for _, node := range levels[r.Group-1] {
+			if nodeType(node) == r.Type {
+				refs = append(refs, node.ID)
+			}
+		}
+		if len(refs) > 0 {
+			sort.Strings(refs)
+			return []string{fmt.Sprintf("group %d has %s %s: %s", r.Group, r.Type, plural.Noun(len(refs), "component"), strings.Join(refs, ", "))}
+		}
+	}
+	return nil
+}
+
+// missingRefs reports the refs not in the graph, which a rule naming them
+// cannot hold for
+func missingRefs(g *dag.Graph, refs ...string) []string {
+	var messages []string
+	for _, ref := range refs {
+		if _, ok := g.Node(ref); !ok {
+			messages = append(messages, fmt.Sprintf("%s is not in the graph", ref))
+		}
+	}
+	return messages
+}
+
+// dependsOn reports whether node depends directly on ref
+func dependsOn(node *dag.Node, ref string) bool {
+	for _, dep := range node.Dependencies {
+		if dep.ID == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeType returns the CycloneDX type of a component node and "service" for
+// service nodes
+func nodeType(node *dag.Node) string {
+	if node.Component != nil {
+		return node.Component.Type
+	}
+	return node.Kind()
+}
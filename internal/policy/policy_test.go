@@ -0,0 +1,152 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// testGraph deploys db, migrate and the auth service in group 1, api in
+// group 2 and web in group 3
+func testGraph(t *testing.T) *dag.Graph {
+	t.Helper()
+	bom := &sbom.CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.6",
+		Components: []sbom.Component{
+			{BOMRef: "db", Name: "Database", Type: "data"},
+			{BOMRef: "migrate", Name: "Migrations", Type: "application"},
+			{BOMRef: "api", Name: "API", Type: "application"},
+			{BOMRef: "web", Name: "Web", Type: "application"},
+		},
+		Services: []sbom.Service{{BOMRef: "auth", Name: "Auth"}},
+		Dependencies: []sbom.Dependency{
+			{Ref: "api", DependsOn: []string{"db", "migrate"}},
+			{Ref: "web", DependsOn: []string{"api"}},
+		},
+	}
+	componentMap := make(map[string]*sbom.Component)
+	for i := range bom.Components {
+		componentMap[bom.Components[i].BOMRef] = &bom.Components[i]
+	}
+	g := dag.New()
+	if err := g.BuildFromSBOM(bom, componentMap); err != nil {
+		t.Fatalf("BuildFromSBOM failed: %v", err)
+	}
+	return g
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want []string
+	}{
+		{name: "require-edge holds", rule: Rule{Rule: RequireEdge, From: "api", To: "migrate"}},
+		{name: "require-edge is direct", rule: Rule{Rule: RequireEdge, From: "web", To: "migrate"}, want: []string{"web does not depend on migrate"}},
+		{name: "require-edge on unknown refs", rule: Rule{Rule: RequireEdge, From: "cdn", To: "migrate"}, want: []string{"cdn is not in the graph"}},
+		{name: "forbid-edge holds", rule: Rule{Rule: ForbidEdge, From: "web", To: "db"}},
+		{name: "forbid-edge violated", rule: Rule{Rule: ForbidEdge, From: "api", To: "db"}, want: []string{"api depends on db"}},
+		{name: "forbid-edge on unknown refs", rule: Rule{Rule: ForbidEdge, From: "cdn", To: "db"}},
+		{name: "max-group-size holds", rule: Rule{Rule: MaxGroupSize, Max: 3}},
+		{name: "max-group-size violated", rule: Rule{Rule: MaxGroupSize, Max: 2}, want: []string{"group 1 has 3 components, more than 2"}},
+		{name: "must-precede holds", rule: Rule{Rule: MustPrecede, A: "migrate", B: "web"}},
+		{name: "must-precede in the same group", rule: Rule{Rule: MustPrecede, A: "db", B: "migrate"}, want: []string{"db deploys in group 1, not before migrate in group 1"}},
+		{name: "must-precede reversed", rule: Rule{Rule: MustPrecede, A: "web", B: "api"}, want: []string{"web deploys in group 3, not before api in group 2"}},
+		{name: "must-precede on unknown refs", rule: Rule{Rule: MustPrecede, A: "x", B: "y"}, want: []string{"x is not in the graph", "y is not in the graph"}},
+		{name: "forbidden-type-in-group violated", rule: Rule{Rule: ForbiddenTypeInGroup, Type: "application", Group: 2}, want: []string{"group 2 has application component: api"}},
+		{name: "forbidden-type-in-group holds", rule: Rule{Rule: ForbiddenTypeInGroup, Type: "data", Group: 2}},
+		{name: "forbidden-type-in-group on services", rule: Rule{Rule: ForbiddenTypeInGroup, Type: "service", Group: 1}, want: []string{"group 1 has service component: auth"}},
+		{name: "forbidden-type-in-group past the last group", rule: Rule{Rule: ForbiddenTypeInGroup, Type: "application", Group: 9}},
+	}
+	g := testGraph(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+			findings, err := (&Policy{Rules: []Rule{tt.rule}}).Evaluate(g)
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+			var got []string
+			for _, finding := range findings {
+				if finding.Rule != 1 || finding.Kind != tt.rule.Rule {
+					t.Errorf("Expected findings of rule 1 (%s), got %+v", tt.rule.Rule, finding)
+				}
+				got = append(got, finding.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	yaml := `# CI policy
+- rule: require-edge
+  from: api
+  to: "migrate" # quoted
+-
+  rule: forbidden-type-in-group
+  type: 'application'
+  group: 1
+- rule: max-group-size
+  max: 10
+`
+	want := []Rule{
+		{Rule: RequireEdge, From: "api", To: "migrate"},
+		{Rule: ForbiddenTypeInGroup, Type: "application", Group: 1},
+		{Rule: MaxGroupSize, Max: 10},
+	}
+	p, err := Load(strings.NewReader(yaml))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(p.Rules, want) {
+		t.Errorf("Expected %+v, got %+v", want, p.Rules)
+	}
+
+	json := `[{"rule": "require-edge", "from": "api", "to": "migrate"},
+		{"rule": "forbidden-type-in-group", "type": "application", "group": 1},
+		{"rule": "max-group-size", "max": 10}]`
+	if p, err = Load(strings.NewReader(json)); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(p.Rules, want) {
+		t.Errorf("Expected %+v from JSON, got %+v", want, p.Rules)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{name: "unknown rule", policy: "- rule: allow-all\n", want: `policy rule 1: unknown rule "allow-all"`},
+		{name: "missing kind", policy: "- from: a\n  to: b\n", want: "policy rule 1: missing rule kind"},
+		{name: "missing field", policy: "- rule: must-precede\n  a: db\n", want: "policy rule 1: must-precede needs a and b"},
+		{name: "extra field", policy: "- rule: max-group-size\n  max: 3\n  from: db\n", want: "policy rule 1: max-group-size does not take from"},
+		{name: "unknown field", policy: "- rule: max-group-size\n  size: 3\n", want: `line 2: unknown field "size"`},
+		{name: "not a number", policy: "- rule: max-group-size\n  max: many\n", want: `line 2: max must be a number, got "many"`},
+		{name: "negative number", policy: "- rule: max-group-size\n  max: -1\n", want: "max-group-size needs a positive number"},
+		{name: "not a list", policy: "rule: max-group-size\n", want: "line 1: expected a list of rules"},
+		{name: "inconsistent indentation", policy: "- rule: forbid-edge\n    from: a\n  to: b\n", want: "line 2: inconsistent indentation in rule 1"},
+		{name: "duplicate field", policy: "- rule: forbid-edge\n  from: a\n  from: b\n", want: `line 3: duplicate field "from" in rule 1`},
+		{name: "flow mapping", policy: "- rule: forbid-edge\n  from: {a: b}\n", want: "unsupported YAML value"},
+		{name: "unknown JSON field", policy: `[{"rule": "max-group-size", "size": 3}]`, want: `unknown field "size"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tt.policy))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
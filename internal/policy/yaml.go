@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/yamlsubset"
+)
+
+// parseYAML reads the YAML form of policy files (see yamlsubset): a
+// sequence of mappings of scalar values, each started by "- "
+func parseYAML(data []byte) ([]Rule, error) {
+	lines, err := yamlsubset.Lines(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		rules       []Rule
+		rule        *Rule
+		seen        map[string]bool
+		childIndent int
+	)
+	for _, line := range lines {
+		text := line.Text
+		if item, ok := strings.CutPrefix(text+" ", "- "); ok {
+			if line.Indent != 0 {
+				return nil, fmt.Errorf("line %d: rules must start at the beginning of the line", line.No)
+			}
+			rules = append(rules, Rule{})
+			rule, seen = &rules[len(rules)-1], map[string]bool{}
+			// Further fields line up with the first one, after the dash
+			childIndent = len(text) - len(strings.TrimLeft(text[1:], " "))
+			text = strings.TrimSpace(item)
+			if text == "" {
+				childIndent = 0
+				continue
+			}
+		} else if rule == nil {
+			return nil, fmt.Errorf("line %d: expected a list of rules, each starting with '- '", line.No)
+		} else if childIndent == 0 {
+			childIndent = line.Indent
+		} else if line.Indent != childIndent {
+			return nil, fmt.Errorf("line %d: inconsistent indentation in rule %d", line.No, len(rules))
+		}
+
+		key, value, err := yamlsubset.Pair(text)
+		if err == nil {
+			value, err = yamlsubset.Scalar(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.No, err)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("line %d: duplicate field %q in rule %d", line.No, key, len(rules))
+		}
+		seen[key] = true
+		if err := rule.set(key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.No, err)
+		}
+	}
+	return rules, nil
+}
+
+// set assigns the field named key
+func (r *Rule) set(key, value string) error {
+	if key == "group" || key == "max" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", key, value)
+		}
+		if key == "group" {
+			r.Group = n
+		} else {
+			r.Max = n
+		}
+		return nil
+	}
+	fields := map[string]*string{"rule": &r.Rule, "from": &r.From, "to": &r.To, "a": &r.A, "b": &r.B, "type": &r.Type}
+	field, ok := fields[key]
+	if !ok {
+		return fmt.Errorf("unknown field %q (expected rule, %s)", key, strings.Join(ruleFields, ", "))
+	}
+	*field = value
+	return nil
+}
@@ -0,0 +1,133 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Target is a single service endpoint to probe
+type Target struct {
+	Step     int
+	BOMRef   string
+	Service  string
+	Endpoint string
+}
+
+// Result is the outcome of probing one target
+type Result struct {
+	Target
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// OK reports whether the endpoint answered with a non-error status
+func (r Result) OK() bool {
+	return r.Err == nil && r.StatusCode < 400
+}
+
+// Options controls how endpoints are probed
+type Options struct {
+	Timeout     time.Duration // Per-request timeout (0 = no timeout)
+	Concurrency int           // Maximum requests in flight (0 = 8)
+	Client      *http.Client  // Defaults to http.DefaultClient
+}
+
+// Run issues an HTTP GET to every target concurrently, bounded by
+// opts.Concurrency, and returns the results in the order of targets
+func Run(ctx context.Context, targets []Target, opts Options) []Result {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+
+	results := make([]Result, len(targets))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = probeOne(ctx, client, target, opts.Timeout)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func probeOne(ctx context.Context, client *http.Client, target Target, timeout time.Duration) Result {
+	result := Result{Target: target}
+
+	u, err := url.Parse(target.Endpoint)
+	if err != nil {
+		result.Err = fmt.Errorf("invalid endpoint: %w", err)
+		return result
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		result.Err = fmt.Errorf("unsupported scheme %q", u.Scheme)
+		return result
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.Endpoint, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	result.StatusCode = resp.StatusCode
+	return result
+}
+
+// WriteReport prints the results grouped by deployment step
+func WriteReport(w io.Writer, results []Result) {
+	currentStep := 0
+	for _, result := range results {
+		if result.Step != currentStep {
+			if currentStep > 0 {
+				fmt.Fprintln(w)
+			}
+			currentStep = result.Step
+			fmt.Fprintf(w, "Step %d:\n", currentStep)
+		}
+
+		status := "OK"
+		if !result.OK() {
+			status = "FAIL"
+		}
+		detail := fmt.Sprintf("%d %s", result.StatusCode, http.StatusText(result.StatusCode))
+		if result.Err != nil {
+			detail = result.Err.Error()
+		}
+		fmt.Fprintf(w, "  [%s] %s (ref: %s) %s - %s (%s)\n",
+			status, result.Service, result.BOMRef, result.Endpoint, detail, result.Duration.Round(time.Millisecond))
+	}
+}
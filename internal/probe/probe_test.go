@@ -0,0 +1,100 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ok.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	targets := []Target{
+		{Step: 1, BOMRef: "ok", Service: "OK Service", Endpoint: ok.URL},
+		{Step: 1, BOMRef: "broken", Service: "Broken Service", Endpoint: broken.URL},
+		{Step: 2, BOMRef: "slow", Service: "Slow Service", Endpoint: slow.URL},
+		{Step: 2, BOMRef: "grpc", Service: "gRPC Service", Endpoint: "grpc://internal:9090"},
+	}
+
+	results := Run(context.Background(), targets, Options{Timeout: 100 * time.Millisecond})
+	if len(results) != len(targets) {
+		t.Fatalf("Expected %d results, got %d", len(targets), len(results))
+	}
+
+	if !results[0].OK() || results[0].StatusCode != http.StatusOK {
+		t.Errorf("Expected ok endpoint to pass, got %+v", results[0])
+	}
+	if results[1].OK() || results[1].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected broken endpoint to fail with 503, got %+v", results[1])
+	}
+	if results[2].OK() || results[2].Err == nil {
+		t.Errorf("Expected slow endpoint to time out, got %+v", results[2])
+	}
+	if results[3].OK() || results[3].Err == nil || !strings.Contains(results[3].Err.Error(), "unsupported scheme") {
+		t.Errorf("Expected unsupported scheme error, got %+v", results[3])
+	}
+
+	var buf bytes.Buffer
+	WriteReport(&buf, results)
+	out := buf.String()
+	for _, want := range []string{"Step 1:", "[OK] OK Service (ref: ok)", "[FAIL] Broken Service", "503 Service Unavailable", "Step 2:"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Report missing %q\nGot:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunConcurrencyLimit(t *testing.T) {
+	var inFlight, maxInFlight int32
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		mu.Lock()
+		if current > maxInFlight {
+			maxInFlight = current
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+	}))
+	defer server.Close()
+
+	var targets []Target
+	for i := 0; i < 12; i++ {
+		targets = append(targets, Target{Step: 1, BOMRef: "svc", Service: "Service", Endpoint: server.URL})
+	}
+
+	results := Run(context.Background(), targets, Options{Concurrency: 3, Timeout: time.Second})
+	for _, result := range results {
+		if !result.OK() {
+			t.Errorf("Unexpected failure: %+v", result)
+		}
+	}
+	if maxInFlight > 3 {
+		t.Errorf("Expected at most 3 concurrent requests, saw %d", maxInFlight)
+	}
+}
@@ -0,0 +1,181 @@
+package sbom
+
+import (
+	"reflect"
+	"slices"
+)
+
+// BOMDelta describes how one SBOM differs from another, by bom-ref. It is
+// applied to a graph built from the old SBOM to obtain the graph of the new
+// one without rebuilding it (see dag.Graph.ApplyDelta).
+type BOMDelta struct {
+	Components        []Component // Components that are new or changed
+	RemovedComponents []string
+	Services          []Service // Services that are new or changed
+	RemovedServices   []string
+
+	// Dependencies are complete entries, one per ref, that replace the ref's
+	// outgoing edges: refs whose dependsOn changed, and refs that are new or
+	// name a new target, since their entries resolve differently now
+	Dependencies        []Dependency
+	RemovedDependencies []string // Refs whose dependency entry is gone
+
+	// Compositions replace all compositions when CompositionsChanged is set
+	Compositions        []Composition
+	CompositionsChanged bool
+}
+
+// Empty reports whether the delta changes nothing
+func (d BOMDelta) Empty() bool {
+	return len(d.Components) == 0 && len(d.RemovedComponents) == 0 &&
+		len(d.Services) == 0 && len(d.RemovedServices) == 0 &&
+		len(d.Dependencies) == 0 && len(d.RemovedDependencies) == 0 &&
+		!d.CompositionsChanged
+}
+
+// ComputeDelta returns the delta from old to new, comparing components by
+// bom-ref at every nesting level, in document order
+func ComputeDelta(old, new *CycloneDX) BOMDelta {
+	var delta BOMDelta
+
+	oldComponents, oldOrder := componentsByRef(old)
+	newComponents, newOrder := componentsByRef(new)
+	added := make(map[string]bool)
+	for _, ref := range newOrder {
+		component := newComponents[ref]
+		previous, existed := oldComponents[ref]
+		if !existed {
+			added[ref] = true
+		}
+		if !existed || !sameComponent(previous, component) {
+			delta.Components = append(delta.Components, *component)
+		}
+	}
+	for _, ref := range oldOrder {
+		if _, ok := newComponents[ref]; !ok {
+			delta.RemovedComponents = append(delta.RemovedComponents, ref)
+		}
+	}
+
+	oldServices, oldServiceOrder := servicesByRef(old)
+	newServices, newServiceOrder := servicesByRef(new)
+	for _, ref := range newServiceOrder {
+		previous, existed := oldServices[ref]
+		if !existed {
+			added[ref] = true
+		}
+		if !existed || !reflect.DeepEqual(previous, newServices[ref]) {
+			delta.Services = append(delta.Services, *newServices[ref])
+		}
+	}
+	for _, ref := range oldServiceOrder {
+		if _, ok := newServices[ref]; !ok {
+			delta.RemovedServices = append(delta.RemovedServices, ref)
+		}
+	}
+
+	oldDeps, oldDepOrder := dependenciesByRef(old.Dependencies)
+	newDeps, newDepOrder := dependenciesByRef(new.Dependencies)
+	for _, ref := range newDepOrder {
+		targets := newDeps[ref]
+		previous, existed := oldDeps[ref]
+		changed := !existed || !slices.Equal(previous, targets) || added[ref]
+		for _, target := range targets {
+			changed = changed || added[target]
+		}
+		if changed {
+			delta.Dependencies = append(delta.Dependencies, Dependency{Ref: ref, DependsOn: targets})
+		}
+	}
+	for _, ref := range oldDepOrder {
+		if _, ok := newDeps[ref]; !ok {
+			delta.RemovedDependencies = append(delta.RemovedDependencies, ref)
+		}
+	}
+
+	if !reflect.DeepEqual(old.Compositions, new.Compositions) {
+		delta.Compositions = new.Compositions
+		delta.CompositionsChanged = true
+	}
+	return delta
+}
+
+// componentsByRef indexes every component with a bom-ref, nested ones and
+// the metadata component included, and returns the refs in document order
+func componentsByRef(bom *CycloneDX) (map[string]*Component, []string) {
+	byRef := make(map[string]*Component)
+	var order []string
+	var walk func(components []Component)
+	walk = func(components []Component) {
+		for i := range components {
+			if ref := components[i].BOMRef; ref != "" {
+				if _, seen := byRef[ref]; !seen {
+					order = append(order, ref)
+				}
+				byRef[ref] = &components[i]
+			}
+			walk(components[i].Components)
+		}
+	}
+	walk(bom.Components)
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		walk([]Component{*bom.Metadata.Component})
+	}
+	return byRef, order
+}
+
+// sameComponent compares two components without their nested components
+func sameComponent(a, b *Component) bool {
+	x, y := *a, *b
+	x.Components, y.Components = nil, nil
+	return reflect.DeepEqual(x, y)
+}
+
+func servicesByRef(bom *CycloneDX) (map[string]*Service, []string) {
+	byRef := make(map[string]*Service)
+	var order []string
+	for i := range bom.Services {
+		if ref := bom.Services[i].BOMRef; ref != "" {
+			if _, seen := byRef[ref]; !seen {
+				order = append(order, ref)
+			}
+			byRef[ref] = &bom.Services[i]
+		}
+	}
+	return byRef, order
+}
+
+// dependenciesByRef merges the entries for each ref, dropping repeated
+// targets, and returns the refs in document order
+func dependenciesByRef(dependencies []Dependency) (map[string][]string, []string) {
+	byRef := make(map[string][]string, len(dependencies))
+	order := make([]string, 0, len(dependencies))
+	large := make(map[string]map[string]bool) // Seen targets of refs with many of them
+	for _, dep := range dependencies {
+		targets, ok := byRef[dep.Ref]
+		if !ok {
+			order = append(order, dep.Ref)
+			targets = make([]string, 0, len(dep.DependsOn))
+		}
+		for _, target := range dep.DependsOn {
+			if seen := large[dep.Ref]; seen != nil {
+				if seen[target] {
+					continue
+				}
+				seen[target] = true
+			} else if slices.Contains(targets, target) {
+				continue
+			} else if len(targets) >= 32 {
+				seen := make(map[string]bool, 2*len(targets))
+				for _, t := range targets {
+					seen[t] = true
+				}
+				seen[target] = true
+				large[dep.Ref] = seen
+			}
+			targets = append(targets, target)
+		}
+		byRef[dep.Ref] = targets
+	}
+	return byRef, order
+}
@@ -0,0 +1,40 @@
+package sbom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeDelta(t *testing.T) {
+	old := &CycloneDX{
+		Components: []Component{
+			{BOMRef: "app", Name: "App", Version: "1", Components: []Component{{BOMRef: "lib", Name: "Lib", Version: "1"}}},
+			{BOMRef: "gone", Name: "Gone"},
+		},
+		Services:     []Service{{BOMRef: "auth", Name: "Auth"}},
+		Dependencies: []Dependency{{Ref: "app", DependsOn: []string{"lib"}}, {Ref: "gone"}, {Ref: "auth"}},
+	}
+	updated := &CycloneDX{
+		Components: []Component{
+			{BOMRef: "app", Name: "App", Version: "1", Components: []Component{{BOMRef: "lib", Name: "Lib", Version: "2"}}},
+			{BOMRef: "db", Name: "Database"},
+		},
+		Dependencies: []Dependency{{Ref: "app", DependsOn: []string{"lib"}}, {Ref: "app", DependsOn: []string{"lib", "db"}}, {Ref: "auth"}},
+	}
+
+	delta := ComputeDelta(old, updated)
+	want := BOMDelta{
+		Components:          []Component{{BOMRef: "lib", Name: "Lib", Version: "2"}, {BOMRef: "db", Name: "Database"}},
+		RemovedComponents:   []string{"gone"},
+		RemovedServices:     []string{"auth"},
+		Dependencies:        []Dependency{{Ref: "app", DependsOn: []string{"lib", "db"}}},
+		RemovedDependencies: []string{"gone"},
+	}
+	if !reflect.DeepEqual(delta, want) {
+		t.Errorf("Expected %+v, got %+v", want, delta)
+	}
+
+	if !ComputeDelta(old, old).Empty() {
+		t.Error("Expected no delta between an SBOM and itself")
+	}
+}
@@ -0,0 +1,43 @@
+package sbom
+
+import "fmt"
+
+// SpecVersion is a CycloneDX specification version the parser knows. The
+// versions are ordered, so features can be checked with a comparison.
+type SpecVersion int
+
+// Known spec versions; SpecUnknown is anything else, including a missing
+// specVersion
+const (
+	SpecUnknown SpecVersion = iota
+	Spec1_2
+	Spec1_3
+	Spec1_4
+	Spec1_5
+	Spec1_6
+)
+
+var specVersionNames = []string{"unknown", "1.2", "1.3", "1.4", "1.5", "1.6"}
+
+// ParseSpecVersion parses a specVersion string such as "1.5"
+func ParseSpecVersion(s string) (SpecVersion, error) {
+	for v := Spec1_2; v <= Spec1_6; v++ {
+		if specVersionNames[v] == s {
+			return v, nil
+		}
+	}
+	return SpecUnknown, fmt.Errorf("unknown CycloneDX spec version %q", s)
+}
+
+func (v SpecVersion) String() string {
+	if v < SpecUnknown || int(v) >= len(specVersionNames) {
+		return fmt.Sprintf("SpecVersion(%d)", int(v))
+	}
+	return specVersionNames[v]
+}
+
+// Spec returns the parsed specVersion of the document, or SpecUnknown
+func (c *CycloneDX) Spec() SpecVersion {
+	v, _ := ParseSpecVersion(c.SpecVersion)
+	return v
+}
@@ -0,0 +1,35 @@
+package sbom
+
+import "testing"
+
+func TestParseSpecVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SpecVersion
+		wantErr bool
+	}{
+		{"1.2", Spec1_2, false},
+		{"1.4", Spec1_4, false},
+		{"1.5", Spec1_5, false},
+		{"1.6", Spec1_6, false},
+		{"1.7", SpecUnknown, true},
+		{"1.4.0", SpecUnknown, true},
+		{"", SpecUnknown, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSpecVersion(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseSpecVersion(%q) = %v, %v; want %v, error %t", tt.in, got, err, tt.want, tt.wantErr)
+		}
+		if err == nil && got.String() != tt.in {
+			t.Errorf("Expected %v to print as %q", got, tt.in)
+		}
+	}
+
+	if !(Spec1_4 < Spec1_5 && Spec1_5 < Spec1_6) {
+		t.Error("Expected spec versions to be ordered")
+	}
+	if got := (&CycloneDX{SpecVersion: "1.5"}).Spec(); got != Spec1_5 {
+		t.Errorf("Spec() = %v, want 1.5", got)
+	}
+}
@@ -1,5 +1,12 @@
 package sbom
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 // CycloneDX represents a CycloneDX SBOM document (supports 1.6)
 type CycloneDX struct {
 	BOMFormat    string        `json:"bomFormat"`
@@ -15,11 +22,29 @@ type CycloneDX struct {
 
 // Metadata contains metadata about the BOM
 type Metadata struct {
-	Timestamp string     `json:"timestamp"`
-	Authors   []Author   `json:"authors,omitempty"`
-	Component *Component `json:"component,omitempty"`
-	Supplier  *Supplier  `json:"supplier,omitempty"`
-	Tools     []Tool     `json:"tools,omitempty"`
+	Timestamp  string      `json:"timestamp"`
+	Lifecycles []Lifecycle `json:"lifecycles,omitempty"`
+	Authors    []Author    `json:"authors,omitempty"`
+	Component  *Component  `json:"component,omitempty"`
+	Supplier   *Supplier   `json:"supplier,omitempty"`
+	Tools      Tools       `json:"tools,omitempty"`
+}
+
+// Lifecycle is a stage of the product lifecycle the BOM describes (CycloneDX
+// 1.5+): either one of the spec's phases, such as build or operations, or a
+// custom name
+type Lifecycle struct {
+	Phase       string `json:"phase,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// String returns the phase, or the name of a custom lifecycle
+func (l Lifecycle) String() string {
+	if l.Phase != "" {
+		return l.Phase
+	}
+	return l.Name
 }
 
 // Author represents an author
@@ -41,10 +66,85 @@ type Tool struct {
 	Version string `json:"version,omitempty"`
 }
 
+// String returns "vendor name version", leaving out empty parts
+func (t Tool) String() string {
+	return strings.Join(strings.Fields(t.Vendor+" "+t.Name+" "+t.Version), " ")
+}
+
+// Tools are the tools that created the BOM. CycloneDX 1.5 replaced the legacy
+// array of tools with an object listing them as components and services;
+// both forms decode into the same list and encode as the array.
+type Tools []Tool
+
+// UnmarshalJSON decodes either form of metadata.tools. Tools listed as
+// components take their vendor from the publisher, supplier, manufacturer
+// or group, in that order; tools listed as services from the provider.
+func (t *Tools) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		var legacy []Tool
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return fmt.Errorf("tools: %w", err)
+		}
+		*t = legacy
+		return nil
+	}
+
+	type organization struct {
+		Name string `json:"name"`
+	}
+	var tools struct {
+		Components []struct {
+			Group        string        `json:"group"`
+			Name         string        `json:"name"`
+			Version      string        `json:"version"`
+			Publisher    string        `json:"publisher"`
+			Supplier     *organization `json:"supplier"`
+			Manufacturer *organization `json:"manufacturer"`
+		} `json:"components"`
+		Services []struct {
+			Provider *organization `json:"provider"`
+			Group    string        `json:"group"`
+			Name     string        `json:"name"`
+			Version  string        `json:"version"`
+		} `json:"services"`
+	}
+	if err := json.Unmarshal(data, &tools); err != nil {
+		return fmt.Errorf("tools: %w", err)
+	}
+
+	result := make(Tools, 0, len(tools.Components)+len(tools.Services))
+	for _, component := range tools.Components {
+		vendor := component.Publisher
+		if vendor == "" && component.Supplier != nil {
+			vendor = component.Supplier.Name
+		}
+		if vendor == "" && component.Manufacturer != nil {
+			vendor = component.Manufacturer.Name
+		}
+		if vendor == "" {
+			vendor = component.Group
+		}
+		result = append(result, Tool{Vendor: vendor, Name: component.Name, Version: component.Version})
+	}
+	for _, service := range tools.Services {
+		vendor := service.Group
+		if service.Provider != nil && service.Provider.Name != "" {
+			vendor = service.Provider.Name
+		}
+		result = append(result, Tool{Vendor: vendor, Name: service.Name, Version: service.Version})
+	}
+	*t = result
+	return nil
+}
+
 // Component represents a component in the BOM
 type Component struct {
 	Type        string      `json:"type"`
 	BOMRef      string      `json:"bom-ref"`
+	Supplier    *Supplier   `json:"supplier,omitempty"`
+	Author      string      `json:"author,omitempty"`    // Deprecated in CycloneDX 1.6 in favor of authors
+	Publisher   string      `json:"publisher,omitempty"` // Person or organization that published the component
 	Name        string      `json:"name"`
 	Version     string      `json:"version"`
 	Description string      `json:"description,omitempty"`
@@ -53,16 +153,40 @@ type Component struct {
 	Purl        string      `json:"purl,omitempty"`
 	Components  []Component `json:"components,omitempty"`
 	Properties  []Property  `json:"properties,omitempty"`
+
+	ExternalReferences []ExternalReference `json:"externalReferences,omitempty"`
+	ReleaseNotes       *ReleaseNotes       `json:"releaseNotes,omitempty"`
+}
+
+// ReleaseNotes describes the release of a component version
+type ReleaseNotes struct {
+	Type        string `json:"type"` // major, minor, patch, pre-release or internal
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 // Service represents a service in CycloneDX 1.6
 type Service struct {
-	BOMRef      string     `json:"bom-ref"`
-	Name        string     `json:"name"`
-	Version     string     `json:"version,omitempty"`
-	Description string     `json:"description,omitempty"`
-	Endpoints   []string   `json:"endpoints,omitempty"`
-	Properties  []Property `json:"properties,omitempty"`
+	BOMRef         string     `json:"bom-ref"`
+	Provider       *Supplier  `json:"provider,omitempty"`
+	Group          string     `json:"group,omitempty"`
+	Name           string     `json:"name"`
+	Version        string     `json:"version,omitempty"`
+	Description    string     `json:"description,omitempty"`
+	Endpoints      []string   `json:"endpoints,omitempty"`
+	Authenticated  *bool      `json:"authenticated,omitempty"`
+	XTrustBoundary *bool      `json:"x-trust-boundary,omitempty"`
+	Properties     []Property `json:"properties,omitempty"`
+
+	ExternalReferences []ExternalReference `json:"externalReferences,omitempty"`
+}
+
+// ExternalReference points to a resource about a component or service, such
+// as its documentation, VCS repository or website
+type ExternalReference struct {
+	Type    string `json:"type"`
+	URL     string `json:"url"`
+	Comment string `json:"comment,omitempty"`
 }
 
 // Property represents a key-value property
@@ -77,6 +201,37 @@ type Dependency struct {
 	DependsOn []string `json:"dependsOn,omitempty"`
 }
 
+// UnmarshalJSON decodes a dependency, accepting a single string for dependsOn
+// as a one-element list. Some generators emit that; decoding it strictly
+// would fail the whole document. The parser reports it as a warning.
+func (d *Dependency) UnmarshalJSON(data []byte) error {
+	type plain Dependency
+	var aux struct {
+		plain
+		DependsOn json.RawMessage `json:"dependsOn"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*d = Dependency(aux.plain)
+
+	if len(aux.DependsOn) == 0 || string(aux.DependsOn) == "null" {
+		return nil
+	}
+	if aux.DependsOn[0] == '"' {
+		var single string
+		if err := json.Unmarshal(aux.DependsOn, &single); err != nil {
+			return err
+		}
+		d.DependsOn = []string{single}
+		return nil
+	}
+	if err := json.Unmarshal(aux.DependsOn, &d.DependsOn); err != nil {
+		return fmt.Errorf("dependsOn of %q: %w", d.Ref, err)
+	}
+	return nil
+}
+
 // Composition represents component composition in CycloneDX 1.6
 type Composition struct {
 	Aggregate    string   `json:"aggregate"`
@@ -0,0 +1,92 @@
+package sbom
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToolsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want Tools
+	}{
+		{
+			name: "legacy array",
+			json: `[{"vendor": "anchore", "name": "syft", "version": "1.4.1"}, {"name": "custom"}]`,
+			want: Tools{{Vendor: "anchore", Name: "syft", Version: "1.4.1"}, {Name: "custom"}},
+		},
+		{
+			name: "object",
+			json: `{
+				"components": [
+					{"type": "application", "publisher": "Anchore", "group": "io.anchore", "name": "syft", "version": "1.4.1"},
+					{"type": "application", "supplier": {"name": "CycloneDX"}, "name": "cdxgen"},
+					{"type": "application", "manufacturer": {"name": "Acme"}, "name": "scanner"},
+					{"type": "application", "group": "org.cyclonedx", "name": "cyclonedx-maven-plugin", "version": "2.8.0"}
+				],
+				"services": [
+					{"provider": {"name": "Acme Corp"}, "name": "enrichment", "version": "3"},
+					{"group": "internal", "name": "signer"}
+				]
+			}`,
+			want: Tools{
+				{Vendor: "Anchore", Name: "syft", Version: "1.4.1"},
+				{Vendor: "CycloneDX", Name: "cdxgen"},
+				{Vendor: "Acme", Name: "scanner"},
+				{Vendor: "org.cyclonedx", Name: "cyclonedx-maven-plugin", Version: "2.8.0"},
+				{Vendor: "Acme Corp", Name: "enrichment", Version: "3"},
+				{Vendor: "internal", Name: "signer"},
+			},
+		},
+		{name: "empty object", json: `{}`, want: Tools{}},
+		{name: "null", json: `null`, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tools Tools
+			if err := json.Unmarshal([]byte(tt.json), &tools); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(tools, tt.want) {
+				t.Errorf("Got %+v, want %+v", tools, tt.want)
+			}
+		})
+	}
+
+	var tools Tools
+	if err := json.Unmarshal([]byte(`"syft"`), &tools); err == nil || !strings.Contains(err.Error(), "tools") {
+		t.Errorf("Expected an error naming tools, got %v", err)
+	}
+}
+
+func TestToolsMarshalAsArray(t *testing.T) {
+	var metadata Metadata
+	if err := json.Unmarshal([]byte(`{"tools": {"components": [{"name": "syft", "version": "1.0"}]}}`), &metadata); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(metadata.Tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `[{"name":"syft","version":"1.0"}]`; got != want {
+		t.Errorf("Expected the legacy array form %s, got %s", want, got)
+	}
+}
+
+func TestToolString(t *testing.T) {
+	for _, tt := range []struct {
+		tool Tool
+		want string
+	}{
+		{Tool{Vendor: "anchore", Name: "syft", Version: "1.4.1"}, "anchore syft 1.4.1"},
+		{Tool{Name: "syft"}, "syft"},
+		{Tool{Name: "syft", Version: "1.0"}, "syft 1.0"},
+	} {
+		if got := tt.tool.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
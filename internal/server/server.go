@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nprimmer/bom-dagger/internal/dag"
+	"github.com/nprimmer/bom-dagger/internal/output"
+	"github.com/nprimmer/bom-dagger/internal/parser"
+	"github.com/nprimmer/bom-dagger/internal/plan"
+)
+
+// Default limits applied when Options leaves them unset
+const (
+	DefaultMaxBodyBytes = 10 << 20 // 10 MiB
+	DefaultTimeout      = 30 * time.Second
+)
+
+// Options configures the HTTP API
+type Options struct {
+	MaxBodyBytes int64         // Largest accepted SBOM body (0 = DefaultMaxBodyBytes)
+	Timeout      time.Duration // Per-request processing timeout (0 = DefaultTimeout)
+}
+
+// ErrorResponse is the JSON body returned for failed requests
+type ErrorResponse struct {
+	Error      string   `json:"error"`
+	Cycle      []string `json:"cycle,omitempty"`      // Set when the SBOM contains a dependency cycle
+	Unresolved []string `json:"unresolved,omitempty"` // Set when strict mode finds unknown refs
+}
+
+// contentTypes maps each rendering mode to its response content type
+var contentTypes = map[string]string{
+	"order":  "text/plain; charset=utf-8",
+	"groups": "text/plain; charset=utf-8",
+	"dot":    "text/vnd.graphviz; charset=utf-8",
+	"json":   "application/json",
+}
+
+// New returns the HTTP handler serving:
+//
+//	POST /plan     CycloneDX JSON body; query mode=order|groups|dot|json, reverse, strict
+//	GET  /healthz  liveness check
+func New(opts Options) http.Handler {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("POST /plan", http.TimeoutHandler(planHandler(opts.MaxBodyBytes), opts.Timeout, "request timed out\n"))
+	return mux
+}
+
+func planHandler(maxBodyBytes int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		mode := query.Get("mode")
+		if mode == "" {
+			mode = "order"
+		}
+		if _, ok := contentTypes[mode]; !ok {
+			writeError(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("unsupported mode %q (expected order, groups, dot or json)", mode)})
+			return
+		}
+		reverse, err := boolParam(query, "reverse")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid reverse parameter: %v", err)})
+			return
+		}
+		strict, err := boolParam(query, "strict")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid strict parameter: %v", err)})
+			return
+		}
+
+		p := parser.New()
+		p.MaxSize = maxBodyBytes
+		bom, err := p.Parse(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) || strings.Contains(err.Error(), "exceeds maximum size") {
+				writeError(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: fmt.Sprintf("request body exceeds %d bytes", maxBodyBytes)})
+				return
+			}
+			writeError(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		// Past the deadline TimeoutHandler has answered already; stop
+		// between phases rather than build a plan nobody reads
+		if r.Context().Err() != nil {
+			return
+		}
+		graph := dag.New()
+		if err := graph.BuildFromSBOM(bom, p.GetComponentMap(bom)); err != nil {
+			if cycle := graph.FindCycle(); cycle != nil {
+				writeError(w, http.StatusUnprocessableEntity, ErrorResponse{
+					Error: fmt.Sprintf("dependency cycle: %s", strings.Join(cycle, " -> ")),
+					Cycle: cycle,
+				})
+				return
+			}
+			writeError(w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		if strict {
+			if unresolved := graph.UnresolvedRefs(bom); len(unresolved) > 0 {
+				writeError(w, http.StatusUnprocessableEntity, ErrorResponse{
+					Error:      fmt.Sprintf("dependencies reference %d unknown refs", len(unresolved)),
+					Unresolved: unresolved,
+				})
+				return
+			}
+		}
+
+		if r.Context().Err() != nil {
+			return
+		}
+		var body bytes.Buffer
+		if err := render(&body, graph, mode, reverse); err != nil {
+			writeError(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", contentTypes[mode])
+		_, _ = w.Write(body.Bytes())
+	}
+}
+
+// render writes the graph in the requested mode. reverse only applies to the
+// order mode, matching the CLI.
+func render(buf *bytes.Buffer, graph *dag.Graph, mode string, reverse bool) error {
+	if mode == "dot" {
+		return dag.WriteDOT(buf, graph)
+	}
+
+	p, err := plan.New(graph)
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case "groups":
+		return output.WriteGroups(buf, p, output.Options{})
+	case "json":
+		return p.Write(buf)
+	default:
+		if reverse {
+			teardown, err := plan.NewTeardown(graph)
+			if err != nil {
+				return err
+			}
+			return output.WriteTeardown(buf, teardown, graph, output.Options{})
+		}
+		return output.WriteOrder(buf, p, graph, output.Options{})
+	}
+}
+
+// boolParam parses an optional boolean query parameter; a bare "?reverse"
+// counts as true
+func boolParam(query url.Values, name string) (bool, error) {
+	if !query.Has(name) {
+		return false, nil
+	}
+	if value := query.Get(name); value != "" {
+		return strconv.ParseBool(value)
+	}
+	return true, nil
+}
+
+func writeError(w http.ResponseWriter, status int, resp ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(resp)
+}
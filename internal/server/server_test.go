@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", "sboms", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func post(t *testing.T, h http.Handler, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	return resp
+}
+
+func TestHealthz(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "ok" {
+		t.Errorf("Expected 200 ok, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPlan(t *testing.T) {
+	h := New(Options{})
+	diamond := fixture(t, "diamond-1.6.json")
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		want        []string
+	}{
+		{
+			name:        "default order",
+			target:      "/plan",
+			contentType: "text/plain",
+			want:        []string{"=== Deployment Order ===", "Step 1:\n  - Database (ref: db)", "Step 3:\n  - Application (ref: app)"},
+		},
+		{
+			name:        "reverse",
+			target:      "/plan?mode=order&reverse",
+			contentType: "text/plain",
+			want:        []string{"=== Teardown Order ===", "Step 1:\n  - Application (ref: app)"},
+		},
+		{
+			name:        "groups",
+			target:      "/plan?mode=groups",
+			contentType: "text/plain",
+			want:        []string{"Group 2 (can deploy in parallel):", "API B (2.0.0)"},
+		},
+		{
+			name:        "dot",
+			target:      "/plan?mode=dot",
+			contentType: "text/vnd.graphviz",
+			want:        []string{"digraph dependencies {", `"app" -> "api-a";`},
+		},
+		{
+			name:        "json",
+			target:      "/plan?mode=json",
+			contentType: "application/json",
+			want:        []string{`"schemaVersion": 1`, `"ref": "db"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(t, h, tt.target, diamond)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("Expected content type %s, got %s", tt.contentType, ct)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("Expected body to contain %q\nGot:\n%s", want, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPlanErrors(t *testing.T) {
+	h := New(Options{MaxBodyBytes: 4096})
+
+	t.Run("cycle", func(t *testing.T) {
+		rec := post(t, h, "/plan", fixture(t, "cycle-1.6.json"))
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected 422, got %d: %s", rec.Code, rec.Body.String())
+		}
+		resp := decodeError(t, rec)
+		if strings.Join(resp.Cycle, " -> ") != "comp-x -> comp-y -> comp-z -> comp-x" {
+			t.Errorf("Unexpected cycle path: %v", resp.Cycle)
+		}
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		rec := post(t, h, "/plan", `{"bomFormat": `)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("Expected 400, got %d", rec.Code)
+		}
+		if resp := decodeError(t, rec); !strings.Contains(resp.Error, "failed to decode JSON") {
+			t.Errorf("Expected parse error message, got %q", resp.Error)
+		}
+	})
+
+	t.Run("not CycloneDX", func(t *testing.T) {
+		rec := post(t, h, "/plan", `{"bomFormat": "SPDX"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("Expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("unsupported mode", func(t *testing.T) {
+		rec := post(t, h, "/plan?mode=terraform", fixture(t, "diamond-1.6.json"))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("Expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		rec := post(t, h, "/plan", `{"bomFormat": "CycloneDX", "components": [`+strings.Repeat(`{"bom-ref": "x"},`, 500)+`{}]}`)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("Expected 413, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("strict unresolved refs", func(t *testing.T) {
+		body := `{"bomFormat": "CycloneDX", "components": [{"bom-ref": "a", "name": "A"}],
+			"dependencies": [{"ref": "a", "dependsOn": ["missing"]}]}`
+		if rec := post(t, h, "/plan", body); rec.Code != http.StatusOK {
+			t.Errorf("Expected 200 without strict, got %d", rec.Code)
+		}
+		rec := post(t, h, "/plan?strict=true", body)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected 422 in strict mode, got %d", rec.Code)
+		}
+		if resp := decodeError(t, rec); len(resp.Unresolved) != 1 || resp.Unresolved[0] != "missing" {
+			t.Errorf("Unexpected unresolved refs: %v", resp.Unresolved)
+		}
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/plan", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected 405, got %d", rec.Code)
+		}
+	})
+}
+
+// slowReader stalls before reporting an empty body
+type slowReader struct{ delay time.Duration }
+
+func (r slowReader) Read(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	return 0, io.EOF
+}
+
+func TestPlanTimeout(t *testing.T) {
+	h := New(Options{Timeout: 20 * time.Millisecond})
+	req := httptest.NewRequest(http.MethodPost, "/plan", slowReader{delay: 200 * time.Millisecond})
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(rec, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handler did not return after the timeout")
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 on timeout, got %d", rec.Code)
+	}
+}
+
+func TestPlanStopsPastDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/plan", strings.NewReader(fixture(t, "diamond-1.6.json"))).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	planHandler(DefaultMaxBodyBytes)(rec, req)
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected no plan once the request context is done, got: %s", rec.Body.String())
+	}
+}
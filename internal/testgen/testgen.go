@@ -0,0 +1,191 @@
+// Package testgen generates synthetic CycloneDX SBOMs for property tests,
+// benchmarks and fuzz seeds. Documents are deterministic for a given seed.
+package testgen
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// Options configures GenerateBOM
+type Options struct {
+	// Nodes is the number of components and services
+	Nodes int
+	// EdgeDensity is the average number of dependencies per node. Every
+	// dependency points at an earlier node, so the graph is acyclic unless
+	// Cycles is set.
+	EdgeDensity float64
+	// Cycles is the number of cycles injected, each by adding a back edge
+	// from a dependency to its dependent
+	Cycles int
+	// ServiceRatio is the fraction of nodes emitted as services (0-1)
+	ServiceRatio float64
+	// NestingDepth is the deepest level at which components are nested
+	// inside earlier components (0 = all top-level)
+	NestingDepth int
+	// Seed makes the output reproducible
+	Seed int64
+}
+
+// componentTypes are assigned to components in rotation
+var componentTypes = []string{"library", "application", "container", "framework", "data", "platform"}
+
+// Ref returns the bom-ref GenerateBOM gives the i-th node
+func Ref(i int) string {
+	return fmt.Sprintf("node-%d", i)
+}
+
+// GenerateBOM returns a synthetic SBOM. Nodes are named node-0 to node-<n-1>
+// and only depend on nodes with a lower index, except for injected cycles.
+func GenerateBOM(opts Options) *sbom.CycloneDX {
+	r := rand.New(rand.NewSource(opts.Seed))
+	bom := &sbom.CycloneDX{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.6",
+		SerialNumber: fmt.Sprintf("urn:uuid:00000000-0000-4000-8000-%012x", uint64(opts.Seed)&0xffffffffffff),
+		Version:      1,
+		Metadata:     &sbom.Metadata{Timestamp: "2024-01-01T00:00:00Z", Tools: []sbom.Tool{{Vendor: "bom-dagger", Name: "testgen"}}},
+		Components:   []sbom.Component{},
+	}
+
+	// Decide the kind and nesting parent of every node first; components are
+	// assembled into their parents afterwards
+	isService := make([]bool, opts.Nodes)
+	parent := make([]int, opts.Nodes)
+	depth := make([]int, opts.Nodes)
+	var components []int
+	for i := 0; i < opts.Nodes; i++ {
+		parent[i] = -1
+		if r.Float64() < opts.ServiceRatio {
+			isService[i] = true
+			continue
+		}
+		if opts.NestingDepth > 0 && len(components) > 0 && r.Intn(2) == 0 {
+			if candidate := components[r.Intn(len(components))]; depth[candidate] < opts.NestingDepth {
+				parent[i] = candidate
+				depth[i] = depth[candidate] + 1
+			}
+		}
+		components = append(components, i)
+	}
+
+	children := make(map[int][]int)
+	var roots []int
+	for _, i := range components {
+		if parent[i] >= 0 {
+			children[parent[i]] = append(children[parent[i]], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+	var build func(i int) sbom.Component
+	build = func(i int) sbom.Component {
+		component := sbom.Component{
+			Type:    componentTypes[i%len(componentTypes)],
+			BOMRef:  Ref(i),
+			Name:    fmt.Sprintf("component-%d", i),
+			Version: fmt.Sprintf("1.0.%d", i),
+			Purl:    fmt.Sprintf("pkg:generic/component-%d@1.0.%d", i, i),
+		}
+		for _, child := range children[i] {
+			component.Components = append(component.Components, build(child))
+		}
+		return component
+	}
+	for _, i := range roots {
+		bom.Components = append(bom.Components, build(i))
+	}
+	for i := 0; i < opts.Nodes; i++ {
+		if isService[i] {
+			bom.Services = append(bom.Services, sbom.Service{
+				BOMRef:    Ref(i),
+				Name:      fmt.Sprintf("service-%d", i),
+				Version:   fmt.Sprintf("1.0.%d", i),
+				Endpoints: []string{fmt.Sprintf("https://service-%d.example.com/health", i)},
+			})
+		}
+	}
+
+	// Each node gets floor(density) dependencies plus one more with the
+	// probability of the fractional part, drawn without repeats
+	dependsOn := make([][]int, opts.Nodes)
+	whole, fraction := int(opts.EdgeDensity), opts.EdgeDensity-float64(int(opts.EdgeDensity))
+	for i := 1; i < opts.Nodes; i++ {
+		count := whole
+		if r.Float64() < fraction {
+			count++
+		}
+		count = min(count, i)
+		seen := make(map[int]bool, count)
+		for len(dependsOn[i]) < count {
+			if target := r.Intn(i); !seen[target] {
+				seen[target] = true
+				dependsOn[i] = append(dependsOn[i], target)
+			}
+		}
+	}
+
+	for c := 0; c < opts.Cycles && opts.Nodes > 1; c++ {
+		from := 1 + r.Intn(opts.Nodes-1)
+		if len(dependsOn[from]) == 0 {
+			dependsOn[from] = append(dependsOn[from], r.Intn(from))
+		}
+		to := dependsOn[from][r.Intn(len(dependsOn[from]))]
+		dependsOn[to] = append(dependsOn[to], from)
+	}
+
+	for i, targets := range dependsOn {
+		dep := sbom.Dependency{Ref: Ref(i)}
+		for _, target := range targets {
+			dep.DependsOn = append(dep.DependsOn, Ref(target))
+		}
+		bom.Dependencies = append(bom.Dependencies, dep)
+	}
+	return bom
+}
+
+// ParseOptions reads comma-separated key=value options, such as
+// "nodes=1000,density=2.5,seed=7"
+func ParseOptions(spec string) (Options, error) {
+	opts := Options{Nodes: 100, EdgeDensity: 2}
+	if strings.TrimSpace(spec) == "" {
+		return opts, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return Options{}, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		var err error
+		switch key {
+		case "nodes":
+			opts.Nodes, err = strconv.Atoi(value)
+		case "density":
+			opts.EdgeDensity, err = strconv.ParseFloat(value, 64)
+		case "cycles":
+			opts.Cycles, err = strconv.Atoi(value)
+		case "services":
+			opts.ServiceRatio, err = strconv.ParseFloat(value, 64)
+		case "depth":
+			opts.NestingDepth, err = strconv.Atoi(value)
+		case "seed":
+			opts.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return Options{}, fmt.Errorf("unknown option %q (expected nodes, density, cycles, services, depth or seed)", key)
+		}
+		if err != nil {
+			return Options{}, fmt.Errorf("invalid %s %q", key, value)
+		}
+	}
+	if opts.Nodes < 0 || opts.EdgeDensity < 0 || opts.Cycles < 0 || opts.NestingDepth < 0 {
+		return Options{}, fmt.Errorf("nodes, density, cycles and depth must not be negative")
+	}
+	if opts.ServiceRatio < 0 || opts.ServiceRatio > 1 {
+		return Options{}, fmt.Errorf("services must be between 0 and 1")
+	}
+	return opts, nil
+}
@@ -0,0 +1,114 @@
+package testgen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nprimmer/bom-dagger/internal/sbom"
+)
+
+// depthOf returns the deepest nesting level below the given components
+func depthOf(components []sbom.Component) int {
+	deepest := 0
+	for _, component := range components {
+		if len(component.Components) > 0 {
+			deepest = max(deepest, 1+depthOf(component.Components))
+		}
+	}
+	return deepest
+}
+
+func countComponents(components []sbom.Component) int {
+	count := len(components)
+	for _, component := range components {
+		count += countComponents(component.Components)
+	}
+	return count
+}
+
+func TestGenerateBOM(t *testing.T) {
+	opts := Options{Nodes: 500, EdgeDensity: 2.5, ServiceRatio: 0.2, NestingDepth: 3, Seed: 7}
+	bom := GenerateBOM(opts)
+
+	if got := countComponents(bom.Components) + len(bom.Services); got != opts.Nodes {
+		t.Errorf("Expected %d nodes, got %d", opts.Nodes, got)
+	}
+	if len(bom.Services) < 50 || len(bom.Services) > 150 {
+		t.Errorf("Expected about 100 services, got %d", len(bom.Services))
+	}
+	if depth := depthOf(bom.Components); depth == 0 || depth > opts.NestingDepth {
+		t.Errorf("Expected nesting up to depth %d, got %d", opts.NestingDepth, depth)
+	}
+
+	index := make(map[string]int, opts.Nodes)
+	for i := 0; i < opts.Nodes; i++ {
+		index[Ref(i)] = i
+	}
+	edges := 0
+	for _, dep := range bom.Dependencies {
+		edges += len(dep.DependsOn)
+		for _, target := range dep.DependsOn {
+			if index[target] >= index[dep.Ref] {
+				t.Fatalf("Expected %s to depend only on earlier nodes, got %s", dep.Ref, target)
+			}
+		}
+	}
+	if density := float64(edges) / float64(opts.Nodes); density < 2 || density > 3 {
+		t.Errorf("Expected about 2.5 edges per node, got %.2f", density)
+	}
+
+	if !reflect.DeepEqual(bom, GenerateBOM(opts)) {
+		t.Error("Expected the same seed to produce the same document")
+	}
+	opts.Seed++
+	if reflect.DeepEqual(bom.Dependencies, GenerateBOM(opts).Dependencies) {
+		t.Error("Expected a different seed to produce different dependencies")
+	}
+}
+
+func TestGenerateBOMCycles(t *testing.T) {
+	bom := GenerateBOM(Options{Nodes: 20, EdgeDensity: 1, Cycles: 1, Seed: 3})
+
+	// Exactly one node gains a dependency on a later node
+	backEdges := 0
+	for i, dep := range bom.Dependencies {
+		for _, target := range dep.DependsOn {
+			var j int
+			if _, err := fmt.Sscanf(target, "node-%d", &j); err == nil && j > i {
+				backEdges++
+			}
+		}
+	}
+	if backEdges != 1 {
+		t.Errorf("Expected 1 back edge, got %d", backEdges)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions("nodes=1000, density=0.5,cycles=2,services=0.25,depth=4,seed=-9")
+	if err != nil {
+		t.Fatalf("ParseOptions failed: %v", err)
+	}
+	want := Options{Nodes: 1000, EdgeDensity: 0.5, Cycles: 2, ServiceRatio: 0.25, NestingDepth: 4, Seed: -9}
+	if opts != want {
+		t.Errorf("Expected %+v, got %+v", want, opts)
+	}
+
+	if opts, err := ParseOptions(""); err != nil || opts.Nodes != 100 || opts.EdgeDensity != 2 {
+		t.Errorf("Expected defaults for an empty spec, got %+v, %v", opts, err)
+	}
+
+	for spec, wantErr := range map[string]string{
+		"nodes":        "expected key=value",
+		"edges=3":      `unknown option "edges"`,
+		"nodes=many":   `invalid nodes "many"`,
+		"nodes=-1":     "must not be negative",
+		"services=1.5": "between 0 and 1",
+	} {
+		if _, err := ParseOptions(spec); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("ParseOptions(%q): expected error containing %q, got %v", spec, wantErr, err)
+		}
+	}
+}
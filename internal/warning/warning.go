@@ -0,0 +1,125 @@
+// Package warning gives every problem bom-dagger reports a stable code for
+// --suppress and --fail-on
+package warning
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Code identifies the kind of problem a Warning reports. Codes are never
+// renumbered or reused; a retired code stays reserved.
+type Code string
+
+// Codes of the graph and plan
+const (
+	DanglingRef         Code = "W001_DANGLING_REF"         // A dependsOn target names no component or service
+	DuplicateDependency Code = "W002_DUPLICATE_DEPENDENCY" // Dependency entries or dependsOn targets listed more than once
+	DuplicateBOMRef     Code = "W003_DUPLICATE_BOMREF"     // Several components or services declare the same bom-ref
+	VersionConflict     Code = "W004_VERSION_CONFLICT"     // The same component at several versions
+	FilteredEdge        Code = "W005_FILTERED_EDGE"        // An edge dropped by --runtime-only
+	InvalidPriority     Code = "W006_INVALID_PRIORITY"     // A bom-dagger:priority that is not an integer
+	NoDependencies      Code = "W007_NO_DEPENDENCIES"      // Several components but no dependency between them
+	UnmatchedMetadata   Code = "W008_UNMATCHED_METADATA"   // A --metadata entry matching no component
+	InvalidRetries      Code = "W009_INVALID_RETRIES"      // A bom-dagger:retries that is not a non-negative integer
+	Cycle               Code = "W018_CYCLE"                // A dependency cycle tolerated by -o prometheus
+	SkippedComponents   Code = "W019_SKIPPED_COMPONENTS"   // Components an output format leaves out for lack of a property
+	TruncatedOutput     Code = "W020_TRUNCATED_OUTPUT"     // An output format showing only part of the graph
+	OversizedStep       Code = "W021_OVERSIZED_STEP"       // A step longer than a --window
+	InvalidPhase        Code = "W022_INVALID_PHASE"        // A bom-dagger:phase that is not early, normal or late
+	InferredEdges       Code = "W023_INFERRED_EDGES"       // Edges --infer-from added, per source
+	SkippedInput        Code = "W024_SKIPPED_INPUT"        // An SBOM of an input directory that failed to parse
+	UnresolvedBOMLink   Code = "W025_UNRESOLVED_BOM_LINK"  // A BOM-Link dependsOn target --bom-dir cannot resolve
+)
+
+// Codes of the SBOM document, reported by the parser
+const (
+	UnsupportedSpecVersion Code = "W010_UNSUPPORTED_SPEC_VERSION" // A specVersion the parser does not know
+	ServicesSpecVersion    Code = "W011_SERVICES_SPEC_VERSION"    // Services in a document before CycloneDX 1.5
+	SpecVersionFeature     Code = "W012_SPEC_VERSION_FEATURE"     // A field newer than the declared specVersion
+	MissingVersion         Code = "W013_MISSING_VERSION"          // No version field
+	EmptyComponents        Code = "W014_EMPTY_COMPONENTS"         // No components
+	UnknownField           Code = "W015_UNKNOWN_FIELD"            // A top-level field CycloneDX does not define
+	MistypedField          Code = "W016_MISTYPED_FIELD"           // A string where dependsOn should be a list
+	MetadataComponentRef   Code = "W017_METADATA_COMPONENT_REF"   // A metadata component dependencies cannot refer to
+)
+
+// Codes lists every code, in order
+var Codes = []Code{
+	DanglingRef, DuplicateDependency, DuplicateBOMRef, VersionConflict, FilteredEdge, InvalidPriority,
+	NoDependencies, UnmatchedMetadata, InvalidRetries, UnsupportedSpecVersion, ServicesSpecVersion,
+	SpecVersionFeature, MissingVersion, EmptyComponents, UnknownField, MistypedField, MetadataComponentRef,
+	Cycle, SkippedComponents, TruncatedOutput, OversizedStep, InvalidPhase,
+	InferredEdges, SkippedInput, UnresolvedBOMLink,
+}
+
+// SpecVersionMismatch reports whether the code flags a document using
+// features its declared spec version does not have
+func (c Code) SpecVersionMismatch() bool {
+	return c == ServicesSpecVersion || c == SpecVersionFeature
+}
+
+// Warning is a problem that does not prevent planning
+type Warning struct {
+	Code    Code
+	Message string
+}
+
+// New returns a warning with a formatted message
+func New(code Code, format string, args ...any) Warning {
+	return Warning{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("[%s] %s", w.Code, w.Message)
+}
+
+// ParseCodes parses a comma-separated list of codes. Each code may be given
+// in full or by its number alone, in any case: W001_DANGLING_REF, W001 and
+// w001 are the same code.
+func ParseCodes(s string) ([]Code, error) {
+	var codes []Code
+	for _, field := range strings.Split(s, ",") {
+		field = strings.ToUpper(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		i := slices.IndexFunc(Codes, func(c Code) bool {
+			number, _, _ := strings.Cut(string(c), "_")
+			return field == string(c) || field == number
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("unknown warning code %q", field)
+		}
+		codes = append(codes, Codes[i])
+	}
+	return codes, nil
+}
+
+// Policy decides what happens to warnings of each code
+type Policy struct {
+	Suppress []Code // Codes acknowledged: not reported and never failing a run
+	FailOn   []Code // Codes that fail the run once reported
+}
+
+// Suppressed reports whether warnings of code are acknowledged
+func (p Policy) Suppressed(code Code) bool {
+	return slices.Contains(p.Suppress, code)
+}
+
+// Fails reports whether a warning of code fails the run
+func (p Policy) Fails(code Code) bool {
+	return !p.Suppressed(code) && slices.Contains(p.FailOn, code)
+}
+
+// Unsuppressed returns the warnings the policy does not suppress
+func (p Policy) Unsuppressed(warnings []Warning) []Warning {
+	var kept []Warning
+	for _, w := range warnings {
+		if !p.Suppressed(w.Code) {
+			kept = append(kept, w)
+		}
+	}
+	return kept
+}
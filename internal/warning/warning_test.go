@@ -0,0 +1,50 @@
+package warning
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCodes(t *testing.T) {
+	codes, err := ParseCodes("W001_DANGLING_REF, w003,W020")
+	if err != nil {
+		t.Fatalf("ParseCodes failed: %v", err)
+	}
+	if want := []Code{DanglingRef, DuplicateBOMRef, TruncatedOutput}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("Expected %v, got %v", want, codes)
+	}
+	if codes, err := ParseCodes(""); err != nil || len(codes) != 0 {
+		t.Errorf("Expected no codes, got %v, %v", codes, err)
+	}
+	for _, s := range []string{"W999", "W001_DANGLING", "DANGLING_REF"} {
+		if _, err := ParseCodes(s); err == nil || !strings.Contains(err.Error(), "unknown warning code") {
+			t.Errorf("ParseCodes(%q): expected an unknown code error, got %v", s, err)
+		}
+	}
+}
+
+func TestCodesAreUnique(t *testing.T) {
+	numbers := make(map[string]Code)
+	for _, c := range Codes {
+		number, _, _ := strings.Cut(string(c), "_")
+		if other, ok := numbers[number]; ok {
+			t.Errorf("%s and %s share the number %s", c, other, number)
+		}
+		numbers[number] = c
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p := Policy{Suppress: []Code{DanglingRef}, FailOn: []Code{DanglingRef, VersionConflict}}
+	if !p.Suppressed(DanglingRef) || p.Suppressed(VersionConflict) {
+		t.Error("Expected only W001 suppressed")
+	}
+	if p.Fails(DanglingRef) || !p.Fails(VersionConflict) || p.Fails(Cycle) {
+		t.Error("Expected only W004 to fail; suppression wins")
+	}
+	kept := p.Unsuppressed([]Warning{New(DanglingRef, "a"), New(Cycle, "b")})
+	if len(kept) != 1 || kept[0].String() != "[W018_CYCLE] b" {
+		t.Errorf("Unexpected unsuppressed warnings: %v", kept)
+	}
+}
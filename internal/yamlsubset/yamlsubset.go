@@ -0,0 +1,118 @@
+// Package yamlsubset reads the subset of YAML used by the policy and
+// metadata files, rejecting what it cannot read rather than misreading it
+package yamlsubset
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Line is a line of a document holding content
+type Line struct {
+	No     int    // 1-based line number
+	Indent int    // Leading spaces
+	Text   string // The line without its indentation and trailing space
+}
+
+// Lines returns the lines of data that hold content, skipping blank lines,
+// comments and a leading "---" document marker. Indenting with tabs is an
+// error.
+func Lines(data []byte) ([]Line, error) {
+	var lines []Line
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || (trimmed == "---" && len(lines) == 0) {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.HasPrefix(line[indent:], "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, Line{No: i + 1, Indent: indent, Text: trimmed})
+	}
+	return lines, nil
+}
+
+// Pair splits "key: value" into the unquoted key and the raw value, which
+// Scalar unquotes
+func Pair(s string) (string, string, error) {
+	var key, rest string
+	switch s[0] {
+	case '"', '\'':
+		quoted, err := quotedPrefix(s)
+		if err != nil {
+			return "", "", err
+		}
+		if key, err = Scalar(quoted); err != nil {
+			return "", "", err
+		}
+		rest = strings.TrimLeft(s[len(quoted):], " ")
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", fmt.Errorf("expected ':' after key %s", quoted)
+		}
+		rest = rest[1:]
+	default:
+		// Plain keys may contain colons (purls, namespaced property names),
+		// so the separator is the first ": " or a trailing ':'
+		if i := strings.Index(s, ": "); i >= 0 {
+			key, rest = s[:i], s[i+2:]
+		} else if strings.HasSuffix(s, ":") {
+			key = s[:len(s)-1]
+		} else {
+			return "", "", fmt.Errorf("expected 'key: value', got %q", s)
+		}
+	}
+	return key, strings.TrimSpace(rest), nil
+}
+
+// Scalar unquotes a scalar value and strips trailing comments
+func Scalar(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	switch s[0] {
+	case '"', '\'':
+		quoted, err := quotedPrefix(s)
+		if err != nil {
+			return "", err
+		}
+		if rest := strings.TrimSpace(s[len(quoted):]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected text after quoted value: %q", rest)
+		}
+		if quoted[0] == '\'' {
+			return strings.ReplaceAll(quoted[1:len(quoted)-1], "''", "'"), nil
+		}
+		return strconv.Unquote(quoted)
+	case '|', '>', '[', '{', '&', '*', '!':
+		return "", fmt.Errorf("unsupported YAML value %q (quote it to use it as a string)", s)
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// quotedPrefix returns the quoted string at the start of s, quotes included
+func quotedPrefix(s string) (string, error) {
+	if s[0] == '"' {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", fmt.Errorf("unterminated or invalid double-quoted string %q", s)
+		}
+		return quoted, nil
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] != '\'' {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\'' {
+			i++
+			continue
+		}
+		return s[:i+1], nil
+	}
+	return "", fmt.Errorf("unterminated single-quoted string %q", s)
+}
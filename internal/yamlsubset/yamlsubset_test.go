@@ -0,0 +1,87 @@
+package yamlsubset
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	doc := "---\r\n# comment\n\napi:\n  owner: x   \n- a: b\n---\n"
+	lines, err := Lines([]byte(doc))
+	if err != nil {
+		t.Fatalf("Lines failed: %v", err)
+	}
+
+	want := []Line{
+		{No: 4, Indent: 0, Text: "api:"},
+		{No: 5, Indent: 2, Text: "owner: x"},
+		{No: 6, Indent: 0, Text: "- a: b"},
+		{No: 7, Indent: 0, Text: "---"},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Unexpected lines\nGot:  %v\nWant: %v", lines, want)
+	}
+
+	if _, err := Lines([]byte("api:\n \towner: x\n")); err == nil || !strings.Contains(err.Error(), "line 2: tabs are not allowed") {
+		t.Errorf("Expected a tab error on line 2, got: %v", err)
+	}
+}
+
+func TestPair(t *testing.T) {
+	tests := []struct {
+		in, key, value string
+	}{
+		{"owner: team", "owner", "team"},
+		{"owner:", "owner", ""},
+		{"bom-dagger:deploy-command: make", "bom-dagger:deploy-command", "make"},
+		{"pkg:npm/a@1:", "pkg:npm/a@1", ""},
+		{`"pkg:npm/a@1": x`, "pkg:npm/a@1", "x"},
+		{`'it''s' : "quoted"  # c`, "it's", `"quoted"  # c`},
+	}
+	for _, tt := range tests {
+		key, value, err := Pair(tt.in)
+		if err != nil || key != tt.key || value != tt.value {
+			t.Errorf("Pair(%q) = %q, %q, %v; want %q, %q", tt.in, key, value, err, tt.key, tt.value)
+		}
+	}
+
+	for _, in := range []string{"owner", `"a" b: c`, `'a: b`} {
+		if _, _, err := Pair(in); err == nil {
+			t.Errorf("Expected an error for %q", in)
+		}
+	}
+}
+
+func TestScalar(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"plain value  # comment", "plain value"},
+		{"a#b", "a#b"},
+		{`"say \"hi\"" # comment`, `say "hi"`},
+		{`'it''s'`, "it's"},
+		{`'#channel'`, "#channel"},
+	}
+	for _, tt := range tests {
+		got, err := Scalar(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Scalar(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	errors := map[string]string{
+		"|":      "unsupported YAML value",
+		"{a: b}": "unsupported YAML value",
+		"[a]":    "unsupported YAML value",
+		"'oops":  "unterminated single-quoted string",
+		`"oops`:  "unterminated or invalid double-quoted string",
+		`"a" b`:  "unexpected text after quoted value",
+	}
+	for in, want := range errors {
+		if _, err := Scalar(in); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Scalar(%q): expected error containing %q, got: %v", in, want, err)
+		}
+	}
+}
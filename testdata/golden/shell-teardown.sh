@@ -0,0 +1,50 @@
+#!/usr/bin/env bash
+# Teardown script generated by bom-dagger. Groups run in order;
+# components within a group run in parallel.
+set -euo pipefail
+
+LOG_DIR="${LOG_DIR:-bom-dagger-logs}"
+mkdir -p "$LOG_DIR"
+
+# wait_group waits for every given job and fails if any of them failed
+wait_group() {
+  failed=0
+  for pid in "$@"; do
+    wait "$pid" || failed=1
+  done
+  return "$failed"
+}
+
+# Group 1
+echo '=== Group 1 ==='
+pids=""
+# Web; exit 1 (ref: web)
+echo 'Tearing down Web; exit 1 (ref: web)'
+echo 'TODO: teardown Web; exit 1 (ref: web)' >"$LOG_DIR"/'web.log' 2>&1 &
+pids="$pids $!"
+# Worker $(rm -rf /) next line (ref: worker)
+echo 'Tearing down Worker $(rm -rf /) next line (ref: worker)'
+echo 'TODO: teardown Worker $(rm -rf /) next line (ref: worker)' >"$LOG_DIR"/'worker.log' 2>&1 &
+pids="$pids $!"
+# shellcheck disable=SC2086
+wait_group $pids || { echo 'Group 1 failed; see the logs in '"$LOG_DIR" >&2; exit 1; }
+
+# Group 2
+echo '=== Group 2 ==='
+pids=""
+# It's "the" API (ref: api/v1)
+echo 'Tearing down It'\''s "the" API (ref: api/v1)'
+echo 'TODO: teardown It'\''s "the" API (ref: api/v1)' >"$LOG_DIR"/'api_v1.log' 2>&1 &
+pids="$pids $!"
+# shellcheck disable=SC2086
+wait_group $pids || { echo 'Group 2 failed; see the logs in '"$LOG_DIR" >&2; exit 1; }
+
+# Group 3
+echo '=== Group 3 ==='
+pids=""
+# Postgres DB (ref: db)
+echo 'Tearing down Postgres DB (ref: db)'
+bash -c 'echo stopping db' >"$LOG_DIR"/'db.log' 2>&1 &
+pids="$pids $!"
+# shellcheck disable=SC2086
+wait_group $pids || { echo 'Group 3 failed; see the logs in '"$LOG_DIR" >&2; exit 1; }
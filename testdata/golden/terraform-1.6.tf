@@ -0,0 +1,25 @@
+# API "Core" Service (ref: api)
+module "api_core_service" {
+  source = "git::https://example.com/infra.git//api?ref=$${version}"
+
+  depends_on = [module._3rd-party_db, module.vpc_network]
+}
+
+# 3rd-party DB (ref: db)
+module "_3rd-party_db" {
+  source = "terraform-aws-modules/rds/aws"
+
+  depends_on = [module.vpc_network]
+}
+
+# VPC / Network (ref: network)
+module "vpc_network" {
+  source = "./modules/network"
+}
+
+# Web Frontend (ref: web)
+module "web_frontend" {
+  source = "./modules/web"
+
+  depends_on = [module.api_core_service]
+}